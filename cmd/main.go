@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"shipman/internal/auth"
 	"shipman/internal/config"
 	"shipman/internal/db"
 	"shipman/internal/email"
+	"shipman/internal/metrics"
 	"shipman/internal/router"
+	"shipman/internal/router/groups/marketplace"
+	"shipman/internal/router/middleware"
+	"shipman/internal/router/render"
+	"shipman/internal/routes"
 	"shipman/internal/storage"
 )
 
@@ -26,8 +39,37 @@ func main() {
 		log.Fatalf("ping database: %v", err)
 	}
 	log.Println("Connected to PostgreSQL")
+	if cfg.DBWarmup {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		warmed, err := db.Warmup(ctx, pool, db.DBMaxIdleConns)
+		cancel()
+		if err != nil {
+			log.Printf("Warmed %d of %d database connections: %v", warmed, db.DBMaxIdleConns, err)
+		} else {
+			log.Printf("Warmed %d database connections", warmed)
+		}
+	}
 
 	db.SetPool(pool)
+	auth.SetBcryptCost(cfg.BcryptCost)
+	db.SetCacheTTL(cfg.CacheTTL)
+	db.SetMaxListOffset(cfg.MaxListOffset)
+	middleware.SetMaxListLimit(cfg.MaxListLimit)
+	render.SetDefaultEnvelope(cfg.ResponseEnvelope != "none")
+	db.SetMaxVoyagePorts(cfg.MaxVoyagePorts)
+	db.SetCoordinatePrecision(cfg.CoordinatePrecision)
+	db.SetMaxNotesLength(cfg.MaxNotesLength)
+	db.SetCurrencyOrder(cfg.CurrencyOrder)
+	db.SetMaxCharterDuration(cfg.MaxCharterDuration)
+	middleware.SetLongRunningTimeout(cfg.LongRunningTimeout)
+	marketplace.SetSensitiveVesselFields(cfg.SensitiveVesselFields)
+	if cfg.CacheTTL > 0 {
+		log.Printf("Reference cache enabled (ttl %s)", cfg.CacheTTL)
+	}
+	if cfg.MetricsEnabled {
+		metrics.Enable()
+		log.Println("Metrics enabled at /metrics")
+	}
 
 	store, err := storage.NewLocalStorage(cfg.StoragePath)
 	if err != nil {
@@ -43,7 +85,7 @@ func main() {
 	}
 
 	r := router.Setup(
-		cfg.JWTSecret, store,
+		cfg.JWTSecret, cfg.TokenDuration, store,
 		cfg.AIProvider, cfg.OpenAIAPIKey, cfg.AIModel, cfg.AIBaseURL,
 		emailCfg, cfg.AppURL, cfg.MarineAPIKey,
 		cfg.CoinsubKey, cfg.CoinsubMerchantID, cfg.CoinsubSecret,
@@ -54,8 +96,52 @@ func main() {
 		},
 	)
 
-	log.Printf("Starting server on %s", cfg.HTTPAddress)
-	if err := r.Run(cfg.HTTPAddress); err != nil {
-		log.Fatalf("start http server: %v", err)
+	srv := routes.New(r, cfg.HTTPAddress, routes.Timeouts{
+		ReadHeader: cfg.HTTPReadHeaderTimeout,
+		Read:       cfg.HTTPReadTimeout,
+		Write:      cfg.HTTPWriteTimeout,
+		Idle:       cfg.HTTPIdleTimeout,
+	})
+
+	if cfg.TLSCertFile != "" {
+		if err := srv.EnableTLS(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			log.Fatalf("enable TLS: %v", err)
+		}
+		log.Println("TLS enabled")
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on %s", cfg.HTTPAddress)
+		errCh <- srv.Start()
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("start http server: %v", err)
+		}
+	case sig := <-sigCh:
+		log.Printf("Received %s, shutting down", sig)
+		shutdown(srv, cfg.ShutdownTimeout)
+	}
+}
+
+// shutdown stops accepting connections and waits up to timeout for in-flight
+// requests to finish.
+func shutdown(srv *routes.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Stop(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Shutdown timed out after %s with %d requests still in flight", timeout, srv.InFlight())
+			return
+		}
+		log.Printf("shutdown http server: %v", err)
+		return
 	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockingServer serves /slow, which blocks until release is closed, on a
+// loopback port. started receives once per request that reached the handler.
+func blockingServer(t *testing.T, release <-chan struct{}) (srv *routes.Server, addr string, started <-chan struct{}, served <-chan error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	startedCh := make(chan struct{}, 1)
+	r.GET("/slow", func(c *gin.Context) {
+		startedCh <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+	srv = routes.New(r, "", routes.Timeouts{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servedCh := make(chan error, 1)
+	go func() { servedCh <- srv.Serve(ln) }()
+	return srv, ln.Addr().String(), startedCh, servedCh
+}
+
+type response struct {
+	status int
+	body   string
+	err    error
+}
+
+func get(url string) <-chan response {
+	ch := make(chan response, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			ch <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		ch <- response{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	return ch
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	srv, addr, started, served := blockingServer(t, release)
+	respCh := get("http://" + addr + "/slow")
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		shutdown(srv, 5*time.Second)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("shutdown returned while a request was still in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := srv.InFlight(); n != 1 {
+		t.Errorf("in flight = %d, want 1", n)
+	}
+
+	close(release)
+	resp := <-respCh
+	if resp.err != nil || resp.status != http.StatusOK || resp.body != "done" {
+		t.Errorf("in-flight response = %+v, want 200 done", resp)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not return after the request finished")
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve = %v, want http.ErrServerClosed", err)
+	}
+	if n := srv.InFlight(); n != 0 {
+		t.Errorf("in flight after shutdown = %d, want 0", n)
+	}
+}
+
+func TestShutdownHonorsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv, addr, started, _ := blockingServer(t, release)
+	get("http://" + addr + "/slow")
+	<-started
+
+	const timeout = 100 * time.Millisecond
+	begin := time.Now()
+	shutdown(srv, timeout)
+	elapsed := time.Since(begin)
+
+	if elapsed < timeout {
+		t.Errorf("shutdown returned after %s, before the %s timeout", elapsed, timeout)
+	}
+	if elapsed > timeout+2*time.Second {
+		t.Errorf("shutdown took %s, want about %s", elapsed, timeout)
+	}
+	if n := srv.InFlight(); n != 1 {
+		t.Errorf("in flight at timeout = %d, want 1", n)
+	}
+}
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS shipman.charter_laytime_terms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    charter_detail_id UUID NOT NULL REFERENCES shipman.charter_details(id) ON DELETE CASCADE,
+    port_role TEXT, -- load | discharge
+    port_name TEXT NOT NULL,
+    allowance_hours NUMERIC(10,2) NOT NULL,
+    reversible BOOLEAN NOT NULL DEFAULT false,
+    notes TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT charter_laytime_terms_port_role_check CHECK (port_role IS NULL OR port_role IN ('load', 'discharge'))
+);
+
+CREATE INDEX idx_charter_laytime_terms_charter_detail_id ON shipman.charter_laytime_terms(charter_detail_id);
+
+CREATE TRIGGER trg_charter_laytime_terms_updated_at
+    BEFORE UPDATE ON shipman.charter_laytime_terms
+    FOR EACH ROW
+    EXECUTE FUNCTION shipman.set_updated_at();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS trg_charter_laytime_terms_updated_at ON shipman.charter_laytime_terms;
+DROP TABLE IF EXISTS shipman.charter_laytime_terms;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE shipman.charter_details
+    ADD COLUMN IF NOT EXISTS laytime_reversible BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE shipman.charter_details
+    DROP COLUMN IF EXISTS laytime_reversible;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE shipman.disputes
+    ADD COLUMN IF NOT EXISTS settled_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE shipman.disputes
+    DROP COLUMN IF EXISTS settled_at;
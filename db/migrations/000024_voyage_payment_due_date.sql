@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE shipman.voyage_payments ADD COLUMN IF NOT EXISTS due_date DATE;
+
+CREATE INDEX IF NOT EXISTS idx_voyage_payments_due_date ON shipman.voyage_payments(due_date);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_voyage_payments_due_date;
+ALTER TABLE shipman.voyage_payments DROP COLUMN IF EXISTS due_date;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS shipman.charter_laytime_totals (
+    charter_detail_id UUID PRIMARY KEY REFERENCES shipman.charter_details(id) ON DELETE CASCADE,
+    total_hours_used NUMERIC(12,2) NOT NULL DEFAULT 0,
+    total_hours_allowed NUMERIC(12,2) NOT NULL DEFAULT 0,
+    balance_hours NUMERIC(12,2) NOT NULL DEFAULT 0, -- negative = demurrage
+    demurrage_hours NUMERIC(12,2) NOT NULL DEFAULT 0,
+    despatch_hours NUMERIC(12,2) NOT NULL DEFAULT 0,
+    demurrage_amount NUMERIC(14,2),
+    currency TEXT NOT NULL DEFAULT 'USD',
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipman.charter_laytime_totals;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS shipman.demurrage_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    demurrage_record_id UUID NOT NULL REFERENCES shipman.demurrage_records(id) ON DELETE CASCADE,
+    uri TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'other', -- sof | nor | invoice | other ...
+    checksum TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_demurrage_documents_record_id ON shipman.demurrage_documents(demurrage_record_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipman.demurrage_documents;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Backs CharterDetailRepository.ListActive. Only active rows are indexed, so
+-- the index stays small as closed charters accumulate and the list becomes an
+-- index scan that stops after LIMIT rows instead of a sort over the table.
+CREATE INDEX IF NOT EXISTS idx_charter_details_active_created_at
+    ON shipman.charter_details(created_at DESC, id DESC)
+    WHERE status = 'active';
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_charter_details_active_created_at;
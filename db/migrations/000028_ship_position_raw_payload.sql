@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE shipman.ship_positions
+    ADD COLUMN IF NOT EXISTS raw_payload JSONB;
+
+-- +goose Down
+ALTER TABLE shipman.ship_positions
+    DROP COLUMN IF EXISTS raw_payload;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE shipman.voyage_payments
+    DROP CONSTRAINT IF EXISTS voyage_payments_status_check;
+ALTER TABLE shipman.voyage_payments
+    ADD CONSTRAINT voyage_payments_status_check
+    CHECK (status IN ('draft', 'pending', 'completed', 'failed', 'cancelled', 'disputed'));
+
+-- +goose Down
+UPDATE shipman.voyage_payments SET status = 'pending' WHERE status = 'disputed';
+ALTER TABLE shipman.voyage_payments
+    DROP CONSTRAINT IF EXISTS voyage_payments_status_check;
+ALTER TABLE shipman.voyage_payments
+    ADD CONSTRAINT voyage_payments_status_check
+    CHECK (status IN ('draft', 'pending', 'completed', 'failed', 'cancelled'));
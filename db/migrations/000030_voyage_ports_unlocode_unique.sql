@@ -0,0 +1,16 @@
+-- +goose Up
+-- One row per UN/LOCODE per voyage so re-imported itineraries update ports in
+-- place (VoyagePortRepository.Upsert). Existing duplicates are collapsed onto
+-- the most recently updated row first. Ports without a code are unaffected
+-- because NULLs never conflict.
+DELETE FROM shipman.voyage_ports vp
+USING shipman.voyage_ports newer
+WHERE vp.voyage_id = newer.voyage_id
+  AND vp.port_unlocode = newer.port_unlocode
+  AND (vp.updated_at, vp.id) < (newer.updated_at, newer.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_voyage_ports_voyage_unlocode
+    ON shipman.voyage_ports(voyage_id, port_unlocode);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_voyage_ports_voyage_unlocode;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- changes holds one {"before": ..., "after": ...} object per modified field,
+-- keyed by the field's JSON name.
+CREATE TABLE IF NOT EXISTS shipman.audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type TEXT NOT NULL,
+    entity_id UUID NOT NULL,
+    user_id UUID REFERENCES shipman.users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_entity ON shipman.audit_log(entity_type, entity_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipman.audit_log;
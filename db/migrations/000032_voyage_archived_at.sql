@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE shipman.voyages
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE shipman.voyages
+    DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Currency inherited by payments and demurrage records created under the
+-- charter without one. NULL falls back to USD.
+ALTER TABLE shipman.charter_details
+    ADD COLUMN IF NOT EXISTS default_currency CHAR(3);
+
+-- +goose Down
+ALTER TABLE shipman.charter_details
+    DROP COLUMN IF EXISTS default_currency;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Per-day despatch rate owed to the charterer for laytime saved. NULL means
+-- half the demurrage rate.
+ALTER TABLE shipman.charter_details
+    ADD COLUMN IF NOT EXISTS despatch_rate NUMERIC(12,2);
+
+-- +goose Down
+ALTER TABLE shipman.charter_details
+    DROP COLUMN IF EXISTS despatch_rate;
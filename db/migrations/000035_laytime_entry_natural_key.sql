@@ -0,0 +1,78 @@
+-- +goose Up
+-- One laytime entry per charter, port, activity and start time so re-imported
+-- noon reports update entries in place (LaytimeEntryRepository.CreateBatch).
+-- Existing duplicates are collapsed onto the most recently updated row first.
+-- Removed rows are kept in laytime_entries_dedup_archive, and disputes and
+-- demurrage records pointing at them are moved to the kept row with their
+-- original link saved in laytime_entries_dedup_links, so Down can put both
+-- back. Drop the two tables once the cleanup has been checked.
+CREATE TABLE IF NOT EXISTS shipman.laytime_entries_dedup_archive (
+    id UUID PRIMARY KEY,
+    kept_id UUID NOT NULL,
+    entry JSONB NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS shipman.laytime_entries_dedup_links (
+    source_table TEXT NOT NULL,
+    row_id UUID NOT NULL,
+    laytime_entry_id UUID NOT NULL,
+    PRIMARY KEY (source_table, row_id)
+);
+
+INSERT INTO shipman.laytime_entries_dedup_archive (id, kept_id, entry)
+SELECT le.id, keeper.id, to_jsonb(le)
+FROM shipman.laytime_entries le
+JOIN LATERAL (
+    SELECT k.id
+    FROM shipman.laytime_entries k
+    WHERE k.charter_detail_id = le.charter_detail_id
+      AND k.port_name = le.port_name
+      AND k.activity = le.activity
+      AND k.started_at = le.started_at
+    ORDER BY k.updated_at DESC, k.id DESC
+    LIMIT 1
+) keeper ON keeper.id <> le.id;
+
+INSERT INTO shipman.laytime_entries_dedup_links (source_table, row_id, laytime_entry_id)
+SELECT 'disputes', d.id, d.laytime_entry_id
+FROM shipman.disputes d
+JOIN shipman.laytime_entries_dedup_archive a ON a.id = d.laytime_entry_id
+UNION ALL
+SELECT 'demurrage_records', r.id, r.laytime_entry_id
+FROM shipman.demurrage_records r
+JOIN shipman.laytime_entries_dedup_archive a ON a.id = r.laytime_entry_id;
+
+UPDATE shipman.disputes d SET laytime_entry_id = a.kept_id
+FROM shipman.laytime_entries_dedup_archive a
+WHERE d.laytime_entry_id = a.id;
+
+UPDATE shipman.demurrage_records r SET laytime_entry_id = a.kept_id
+FROM shipman.laytime_entries_dedup_archive a
+WHERE r.laytime_entry_id = a.id;
+
+DELETE FROM shipman.laytime_entries le
+USING shipman.laytime_entries_dedup_archive a
+WHERE le.id = a.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_laytime_entries_natural_key
+    ON shipman.laytime_entries(charter_detail_id, port_name, activity, started_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_laytime_entries_natural_key;
+
+INSERT INTO shipman.laytime_entries
+SELECT (jsonb_populate_record(NULL::shipman.laytime_entries, a.entry)).*
+FROM shipman.laytime_entries_dedup_archive a
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE shipman.disputes d SET laytime_entry_id = l.laytime_entry_id
+FROM shipman.laytime_entries_dedup_links l
+WHERE l.source_table = 'disputes' AND d.id = l.row_id;
+
+UPDATE shipman.demurrage_records r SET laytime_entry_id = l.laytime_entry_id
+FROM shipman.laytime_entries_dedup_links l
+WHERE l.source_table = 'demurrage_records' AND r.id = l.row_id;
+
+DROP TABLE IF EXISTS shipman.laytime_entries_dedup_links;
+DROP TABLE IF EXISTS shipman.laytime_entries_dedup_archive;
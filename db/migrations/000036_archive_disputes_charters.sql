@@ -0,0 +1,14 @@
+-- +goose Up
+-- Soft-archive markers for data hygiene. Archived rows stay in place so
+-- payments, laytime entries and demurrage records that reference them keep
+-- working.
+ALTER TABLE shipman.disputes
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+ALTER TABLE shipman.charter_details
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE shipman.charter_details
+    DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE shipman.disputes
+    DROP COLUMN IF EXISTS archived_at;
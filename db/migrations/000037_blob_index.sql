@@ -0,0 +1,14 @@
+-- +goose Up
+-- Content-addressed index over stored blobs. Identical uploads share one
+-- object; ref_count tracks how many records point at it so the object is only
+-- removed when the last one goes.
+CREATE TABLE IF NOT EXISTS shipman.blob_index (
+    checksum TEXT PRIMARY KEY,
+    uri TEXT NOT NULL UNIQUE,
+    ref_count INTEGER NOT NULL DEFAULT 1 CHECK (ref_count >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipman.blob_index;
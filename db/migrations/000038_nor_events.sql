@@ -0,0 +1,21 @@
+-- +goose Up
+-- Notice of Readiness tendered at each port. Laytime may start from
+-- acceptance plus the agreed turn time instead of from the first entry.
+CREATE TABLE IF NOT EXISTS shipman.nor_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    voyage_id UUID NOT NULL REFERENCES shipman.voyages(id) ON DELETE CASCADE,
+    charter_detail_id UUID REFERENCES shipman.charter_details(id) ON DELETE SET NULL,
+    port_name TEXT NOT NULL,
+    tendered_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    turn_time_hours NUMERIC(6,2) NOT NULL DEFAULT 0 CHECK (turn_time_hours >= 0),
+    remarks TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (accepted_at IS NULL OR accepted_at >= tendered_at)
+);
+
+CREATE INDEX idx_nor_events_voyage_id ON shipman.nor_events(voyage_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipman.nor_events;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Weather and other excepted periods deducted from an entry's gross
+-- duration when hours_counted is derived from its start and end.
+ALTER TABLE shipman.laytime_entries
+    ADD COLUMN IF NOT EXISTS excluded_hours NUMERIC(10,2) NOT NULL DEFAULT 0
+        CHECK (excluded_hours >= 0);
+
+-- +goose Down
+ALTER TABLE shipman.laytime_entries
+    DROP COLUMN IF EXISTS excluded_hours;
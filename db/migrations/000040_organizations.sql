@@ -0,0 +1,41 @@
+-- +goose Up
+-- Tenants. Users, charters and voyages belong to exactly one organization;
+-- everything that existed before this migration goes to the default one.
+CREATE TABLE IF NOT EXISTS shipman.organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO shipman.organizations (id, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE shipman.users
+    ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+        DEFAULT '00000000-0000-0000-0000-000000000001'
+        REFERENCES shipman.organizations(id);
+
+ALTER TABLE shipman.charter_details
+    ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+        DEFAULT '00000000-0000-0000-0000-000000000001'
+        REFERENCES shipman.organizations(id);
+
+ALTER TABLE shipman.voyages
+    ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+        DEFAULT '00000000-0000-0000-0000-000000000001'
+        REFERENCES shipman.organizations(id);
+
+CREATE INDEX idx_users_org_id ON shipman.users(org_id);
+CREATE INDEX idx_charter_details_org_id_created_at ON shipman.charter_details(org_id, created_at DESC);
+CREATE INDEX idx_voyages_org_id ON shipman.voyages(org_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_voyages_org_id;
+DROP INDEX IF EXISTS shipman.idx_charter_details_org_id_created_at;
+DROP INDEX IF EXISTS shipman.idx_users_org_id;
+ALTER TABLE shipman.voyages DROP COLUMN IF EXISTS org_id;
+ALTER TABLE shipman.charter_details DROP COLUMN IF EXISTS org_id;
+ALTER TABLE shipman.users DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS shipman.organizations;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Tombstones for hard-deleted rows, so incremental sync consumers can see
+-- deletions that updated_at cannot. entity_type uses the audit_log values.
+CREATE TABLE IF NOT EXISTS shipman.deletions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type TEXT NOT NULL,
+    entity_id UUID NOT NULL,
+    org_id UUID REFERENCES shipman.organizations(id) ON DELETE CASCADE,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deletions_entity_type_deleted_at ON shipman.deletions(entity_type, deleted_at);
+CREATE INDEX idx_charter_details_updated_at ON shipman.charter_details(updated_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_charter_details_updated_at;
+DROP TABLE IF EXISTS shipman.deletions;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- disputes.payment_id points at the legacy payments table; disputes raised
+-- against a voyage payment reference it here instead.
+ALTER TABLE shipman.disputes
+    ADD COLUMN IF NOT EXISTS voyage_payment_id UUID REFERENCES shipman.voyage_payments(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_disputes_voyage_payment_id ON shipman.disputes(voyage_payment_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_disputes_voyage_payment_id;
+ALTER TABLE shipman.disputes DROP COLUMN IF EXISTS voyage_payment_id;
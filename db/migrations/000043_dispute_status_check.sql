@@ -0,0 +1,29 @@
+-- +goose Up
+-- Fold statuses outside the documented vocabulary into closed, then enforce
+-- open | under_review | resolved | closed. The original statuses are kept in
+-- disputes_status_rewrites so Down can put them back.
+CREATE TABLE IF NOT EXISTS shipman.disputes_status_rewrites (
+    dispute_id UUID PRIMARY KEY REFERENCES shipman.disputes(id) ON DELETE CASCADE,
+    old_status TEXT NOT NULL
+);
+
+INSERT INTO shipman.disputes_status_rewrites (dispute_id, old_status)
+SELECT id, status FROM shipman.disputes WHERE status IN ('rejected', 'withdrawn');
+
+UPDATE shipman.disputes SET status = 'closed' WHERE status IN ('rejected', 'withdrawn');
+
+ALTER TABLE shipman.disputes
+    ADD CONSTRAINT disputes_status_check
+    CHECK (status IN ('open', 'under_review', 'resolved', 'closed'));
+
+-- +goose Down
+ALTER TABLE shipman.disputes DROP CONSTRAINT IF EXISTS disputes_status_check;
+
+-- Restore only disputes still closed; anything reopened since keeps its
+-- current status.
+UPDATE shipman.disputes d
+SET status = r.old_status
+FROM shipman.disputes_status_rewrites r
+WHERE r.dispute_id = d.id AND d.status = 'closed';
+
+DROP TABLE IF EXISTS shipman.disputes_status_rewrites;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- The charter change feed orders rows by the id of the transaction that last
+-- wrote them. Readers only return rows from transactions older than every
+-- one still running, so a long transaction committing late is never skipped
+-- the way an updated_at cursor skips it.
+ALTER TABLE shipman.charter_details
+    ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE shipman.deletions
+    ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION shipman.set_change_xid()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.change_xid = pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER trg_charter_details_change_xid
+    BEFORE UPDATE ON shipman.charter_details
+    FOR EACH ROW
+    EXECUTE FUNCTION shipman.set_change_xid();
+
+CREATE INDEX idx_charter_details_change_xid ON shipman.charter_details(change_xid, id);
+CREATE INDEX idx_deletions_entity_type_change_xid ON shipman.deletions(entity_type, change_xid, entity_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS shipman.idx_deletions_entity_type_change_xid;
+DROP INDEX IF EXISTS shipman.idx_charter_details_change_xid;
+DROP TRIGGER IF EXISTS trg_charter_details_change_xid ON shipman.charter_details;
+DROP FUNCTION IF EXISTS shipman.set_change_xid();
+ALTER TABLE shipman.deletions DROP COLUMN IF EXISTS change_xid;
+ALTER TABLE shipman.charter_details DROP COLUMN IF EXISTS change_xid;
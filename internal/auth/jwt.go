@@ -15,6 +15,7 @@ var (
 
 type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
+	OrgID    uuid.UUID `json:"org_id"`
 	Email    string    `json:"email"`
 	Role     string    `json:"role"`
 	FullName string    `json:"full_name"`
@@ -33,9 +34,10 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	}
 }
 
-func (m *JWTManager) Generate(userID uuid.UUID, email, role, fullName string) (string, error) {
+func (m *JWTManager) Generate(userID, orgID uuid.UUID, email, role, fullName string) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
+		OrgID:    orgID,
 		Email:    email,
 		Role:     role,
 		FullName: fullName,
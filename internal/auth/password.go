@@ -1,10 +1,24 @@
 package auth
 
 import (
+	"sync"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
-const bcryptCost = 12
+// DefaultBcryptCost is the bcrypt work factor used unless overridden.
+const DefaultBcryptCost = 12
+
+var bcryptCost = DefaultBcryptCost
+
+// SetBcryptCost overrides the work factor for new hashes. Values outside
+// bcrypt's supported range restore the default.
+func SetBcryptCost(cost int) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefaultBcryptCost
+	}
+	bcryptCost = cost
+}
 
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
@@ -15,3 +29,32 @@ func CheckPassword(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// CompareDummy spends as long as CheckPassword would against a real hash.
+// Sign-in calls it for unknown emails so response time does not reveal
+// whether an account exists.
+func CompareDummy(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("shipman-dummy-password"), bcryptCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// IsHash reports whether hash is a bcrypt hash, as opposed to a blank or
+// plaintext value.
+func IsHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// NeedsRehash reports whether hash was produced at a lower cost than
+// HashPassword uses today, so a verified password should be hashed again.
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < bcryptCost
+}
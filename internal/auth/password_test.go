@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	SetBcryptCost(bcrypt.MinCost)
+	defer SetBcryptCost(DefaultBcryptCost)
+
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{"good password", "correct horse battery", hash, true},
+		{"bad password", "correct horse battery!", hash, false},
+		{"empty password", "", hash, false},
+		{"plaintext stored", "correct horse battery", "correct horse battery", false},
+		{"blank hash", "correct horse battery", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(tt.password, tt.hash); got != tt.want {
+				t.Errorf("CheckPassword = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHash(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{string(hash), true},
+		{"", false},
+		{"secret123", false},
+		{"$2a$", false},
+	}
+	for _, tt := range tests {
+		if got := IsHash(tt.hash); got != tt.want {
+			t.Errorf("IsHash(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestSetBcryptCost(t *testing.T) {
+	defer SetBcryptCost(DefaultBcryptCost)
+
+	tests := []struct {
+		cost, want int
+	}{
+		{bcrypt.MinCost, bcrypt.MinCost},
+		{10, 10},
+		{bcrypt.MinCost - 1, DefaultBcryptCost},
+		{bcrypt.MaxCost + 1, DefaultBcryptCost},
+	}
+	for _, tt := range tests {
+		SetBcryptCost(tt.cost)
+		if bcryptCost != tt.want {
+			t.Errorf("SetBcryptCost(%d): cost = %d, want %d", tt.cost, bcryptCost, tt.want)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	SetBcryptCost(bcrypt.MinCost + 1)
+	defer SetBcryptCost(DefaultBcryptCost)
+
+	low, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	current, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost+1)
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"lower cost", string(low), true},
+		{"current cost", string(current), false},
+		{"not a hash", "secret123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,6 +14,10 @@ type Config struct {
 	HTTPAddress   string
 	DatabaseDSN   string
 	JWTSecret     string
+	// BcryptCost is the work factor for new password hashes.
+	BcryptCost    int
+	// TokenDuration is how long issued JWTs stay valid.
+	TokenDuration time.Duration
 	StoragePath   string
 	OpenAIAPIKey  string
 	AIProvider    string
@@ -26,6 +33,55 @@ type Config struct {
 	AppURL        string
 	Email         EmailConfig
 	MarineAPIKey  string
+	// CacheTTL fronts vessel and charter detail lookups with an in-memory
+	// cache. Zero disables caching.
+	CacheTTL time.Duration
+	// MaxListOffset caps the offset accepted by list endpoints; deeper pages
+	// are rejected with a 400 asking for cursor pagination.
+	MaxListOffset int
+	// MaxListLimit is the absolute limit a list request may ask for; larger
+	// values get a 413 pointing at the streaming export.
+	MaxListLimit int
+	// MaxNotesLength caps notes, remarks and description fields, in
+	// characters.
+	MaxNotesLength int
+	// MaxVoyagePorts caps the number of ports a voyage may hold.
+	MaxVoyagePorts int
+	// CoordinatePrecision is the number of decimal places latitude and
+	// longitude are rounded to in API responses.
+	CoordinatePrecision int
+	// MaxCharterDuration rejects charters whose dates span longer. Zero
+	// disables the check.
+	MaxCharterDuration time.Duration
+	// CurrencyOrder lists currencies to show first in payment totals; the
+	// rest are alphabetical.
+	CurrencyOrder []string
+	// SensitiveVesselFields lists vessel JSON fields hidden from non-admin
+	// users.
+	SensitiveVesselFields []string
+	// HTTP server timeouts. LongRunningTimeout replaces the read and write
+	// timeouts on routes that move large bodies or wait on AI extraction.
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+	LongRunningTimeout    time.Duration
+	// TLSCertFile and TLSKeyFile switch the server to HTTPS when both are
+	// set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ResponseEnvelope is the default list response shape, "data" for
+	// {"data": [...]} or "none" for a bare array. Clients can override it
+	// per request with X-Response-Envelope.
+	ResponseEnvelope string
+	// ShutdownTimeout bounds how long in-flight requests may drain after a
+	// termination signal.
+	ShutdownTimeout time.Duration
+	// MetricsEnabled turns on the domain event counters and the /metrics
+	// endpoint.
+	MetricsEnabled bool
+	// DBWarmup pre-opens the pool's idle connections at startup.
+	DBWarmup bool
 }
 
 type EmailConfig struct {
@@ -37,7 +93,16 @@ type EmailConfig struct {
 
 type yamlConfig struct {
 	Server struct {
-		HTTPAddr string `yaml:"http_addr"`
+		HTTPAddr           string `yaml:"http_addr"`
+		ReadHeaderTimeout  string `yaml:"read_header_timeout"` // Go durations, e.g. "10s"
+		ReadTimeout        string `yaml:"read_timeout"`
+		WriteTimeout       string `yaml:"write_timeout"`
+		IdleTimeout        string `yaml:"idle_timeout"`
+		LongRunningTimeout string `yaml:"long_running_timeout"`
+		ShutdownTimeout    string `yaml:"shutdown_timeout"`
+		TLSCertFile        string `yaml:"tls_cert_file"`
+		TLSKeyFile         string `yaml:"tls_key_file"`
+		ResponseEnvelope   string `yaml:"response_envelope"`
 	} `yaml:"server"`
 
 	Database struct {
@@ -47,10 +112,13 @@ type yamlConfig struct {
 		Password string `yaml:"password"`
 		Name     string `yaml:"name"`
 		SSLMode  string `yaml:"sslmode"`
+		Warmup   *bool  `yaml:"warmup"`
 	} `yaml:"database"`
 
 	Auth struct {
-		JWTSecret string `yaml:"jwt_secret"`
+		JWTSecret          string `yaml:"jwt_secret"`
+		TokenDurationHours int    `yaml:"token_duration_hours"`
+		BcryptCost         int    `yaml:"bcrypt_cost"`
 	} `yaml:"auth"`
 
 	Storage struct {
@@ -83,6 +151,40 @@ type yamlConfig struct {
 		FromName       string `yaml:"from_name"`
 	} `yaml:"email"`
 
+	Cache struct {
+		TTL string `yaml:"ttl"` // e.g. "30s"; empty disables caching
+	} `yaml:"cache"`
+
+	Pagination struct {
+		MaxOffset int `yaml:"max_offset"`
+		MaxLimit  int `yaml:"max_limit"`
+	} `yaml:"pagination"`
+
+	Charters struct {
+		MaxDurationDays *int `yaml:"max_duration_days"` // pointer so 0 (disabled) is distinguishable from unset
+	} `yaml:"charters"`
+
+	Voyages struct {
+		MaxPorts            int  `yaml:"max_ports"`
+		CoordinatePrecision *int `yaml:"coordinate_precision"` // pointer so 0 is distinguishable from unset
+	} `yaml:"voyages"`
+
+	Notes struct {
+		MaxLength int `yaml:"max_length"`
+	} `yaml:"notes"`
+
+	Metrics struct {
+		Enabled *bool `yaml:"enabled"`
+	} `yaml:"metrics"`
+
+	Payments struct {
+		CurrencyOrder string `yaml:"currency_order"` // comma-separated, e.g. "USD,EUR"
+	} `yaml:"payments"`
+
+	Vessels struct {
+		SensitiveFields string `yaml:"sensitive_fields"` // comma-separated JSON names, e.g. "owner,manager"
+	} `yaml:"vessels"`
+
 	AppURL       string `yaml:"app_url"`
 	MarineAPIKey string `yaml:"marine_traffic_api_key"`
 }
@@ -93,6 +195,22 @@ func Load() (*Config, error) {
 	// Environment variables always take priority over YAML
 	httpAddr := envOr("HTTP_ADDR", yc.Server.HTTPAddr, "0.0.0.0:8080")
 	jwtSecret := envOr("JWT_SECRET", yc.Auth.JWTSecret, "shipman-dev-secret-change-in-production")
+	yamlBcryptCost := ""
+	if yc.Auth.BcryptCost > 0 {
+		yamlBcryptCost = strconv.Itoa(yc.Auth.BcryptCost)
+	}
+	bcryptCost, err := strconv.Atoi(envOr("BCRYPT_COST", yamlBcryptCost, "12"))
+	if err != nil {
+		return nil, fmt.Errorf("parse BCRYPT_COST: %w", err)
+	}
+	yamlTokenHours := ""
+	if yc.Auth.TokenDurationHours > 0 {
+		yamlTokenHours = strconv.Itoa(yc.Auth.TokenDurationHours)
+	}
+	tokenHours, err := strconv.Atoi(envOr("TOKEN_DURATION_HOURS", yamlTokenHours, "24"))
+	if err != nil {
+		return nil, fmt.Errorf("parse TOKEN_DURATION_HOURS: %w", err)
+	}
 	storagePath := envOr("STORAGE_PATH", yc.Storage.Path, "./uploads")
 	openAIKey := envOr("OPENAI_API_KEY", yc.AI.OpenAIAPIKey, "")
 	aiProvider := envOr("AI_PROVIDER", yc.AI.Provider, "openai")
@@ -152,10 +270,133 @@ func Load() (*Config, error) {
 	appURL := envOr("APP_URL", yc.AppURL, "http://localhost:3000")
 	marineAPIKey := envOr("MARINE_TRAFFIC_API_KEY", yc.MarineAPIKey, "")
 
+	var cacheTTL time.Duration
+	if raw := envOr("CACHE_TTL", yc.Cache.TTL, ""); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse CACHE_TTL: %w", err)
+		}
+		cacheTTL = parsed
+	}
+
+	yamlMaxOffset := ""
+	if yc.Pagination.MaxOffset > 0 {
+		yamlMaxOffset = strconv.Itoa(yc.Pagination.MaxOffset)
+	}
+	maxListOffset, err := strconv.Atoi(envOr("MAX_LIST_OFFSET", yamlMaxOffset, "10000"))
+	if err != nil {
+		return nil, fmt.Errorf("parse MAX_LIST_OFFSET: %w", err)
+	}
+
+	yamlMaxLimit := ""
+	if yc.Pagination.MaxLimit > 0 {
+		yamlMaxLimit = strconv.Itoa(yc.Pagination.MaxLimit)
+	}
+	maxListLimit, err := strconv.Atoi(envOr("MAX_LIST_LIMIT", yamlMaxLimit, "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("parse MAX_LIST_LIMIT: %w", err)
+	}
+
+	yamlMaxPorts := ""
+	if yc.Voyages.MaxPorts > 0 {
+		yamlMaxPorts = strconv.Itoa(yc.Voyages.MaxPorts)
+	}
+	maxVoyagePorts, err := strconv.Atoi(envOr("MAX_VOYAGE_PORTS", yamlMaxPorts, "100"))
+	if err != nil {
+		return nil, fmt.Errorf("parse MAX_VOYAGE_PORTS: %w", err)
+	}
+
+	yamlCoordPrecision := ""
+	if yc.Voyages.CoordinatePrecision != nil {
+		yamlCoordPrecision = strconv.Itoa(*yc.Voyages.CoordinatePrecision)
+	}
+	coordinatePrecision, err := strconv.Atoi(envOr("COORDINATE_PRECISION", yamlCoordPrecision, "6"))
+	if err != nil {
+		return nil, fmt.Errorf("parse COORDINATE_PRECISION: %w", err)
+	}
+
+	yamlMaxNotes := ""
+	if yc.Notes.MaxLength > 0 {
+		yamlMaxNotes = strconv.Itoa(yc.Notes.MaxLength)
+	}
+	maxNotesLength, err := strconv.Atoi(envOr("MAX_NOTES_LENGTH", yamlMaxNotes, "4000"))
+	if err != nil {
+		return nil, fmt.Errorf("parse MAX_NOTES_LENGTH: %w", err)
+	}
+
+	yamlMaxCharterDays := ""
+	if yc.Charters.MaxDurationDays != nil {
+		yamlMaxCharterDays = strconv.Itoa(*yc.Charters.MaxDurationDays)
+	}
+	maxCharterDays, err := strconv.Atoi(envOr("CHARTER_MAX_DURATION_DAYS", yamlMaxCharterDays, "3650"))
+	if err != nil {
+		return nil, fmt.Errorf("parse CHARTER_MAX_DURATION_DAYS: %w", err)
+	}
+
+	readHeaderTimeout, err := durationOr("HTTP_READ_HEADER_TIMEOUT", yc.Server.ReadHeaderTimeout, "10s")
+	if err != nil {
+		return nil, err
+	}
+	readTimeout, err := durationOr("HTTP_READ_TIMEOUT", yc.Server.ReadTimeout, "30s")
+	if err != nil {
+		return nil, err
+	}
+	writeTimeout, err := durationOr("HTTP_WRITE_TIMEOUT", yc.Server.WriteTimeout, "30s")
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := durationOr("HTTP_IDLE_TIMEOUT", yc.Server.IdleTimeout, "60s")
+	if err != nil {
+		return nil, err
+	}
+	longRunningTimeout, err := durationOr("HTTP_LONG_RUNNING_TIMEOUT", yc.Server.LongRunningTimeout, "5m")
+	if err != nil {
+		return nil, err
+	}
+	shutdownTimeout, err := durationOr("SHUTDOWN_TIMEOUT", yc.Server.ShutdownTimeout, "10s")
+	if err != nil {
+		return nil, err
+	}
+	tlsCertFile := envOr("TLS_CERT_FILE", yc.Server.TLSCertFile, "")
+	tlsKeyFile := envOr("TLS_KEY_FILE", yc.Server.TLSKeyFile, "")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	responseEnvelope := envOr("RESPONSE_ENVELOPE", yc.Server.ResponseEnvelope, "data")
+	if responseEnvelope != "data" && responseEnvelope != "none" {
+		return nil, fmt.Errorf("RESPONSE_ENVELOPE must be data or none, got %q", responseEnvelope)
+	}
+
+	var currencyOrder []string
+	if raw := envOr("PAYMENT_CURRENCY_ORDER", yc.Payments.CurrencyOrder, ""); raw != "" {
+		currencyOrder = strings.Split(raw, ",")
+	}
+
+	var sensitiveVesselFields []string
+	if raw := envOr("VESSEL_SENSITIVE_FIELDS", yc.Vessels.SensitiveFields, ""); raw != "" {
+		sensitiveVesselFields = strings.Split(raw, ",")
+	}
+
+	metricsEnabled := false
+	if v := os.Getenv("METRICS_ENABLED"); v != "" {
+		metricsEnabled = v == "true" || v == "1"
+	} else if yc.Metrics.Enabled != nil {
+		metricsEnabled = *yc.Metrics.Enabled
+	}
+
+	dbWarmup := false
+	if v := os.Getenv("DB_WARMUP"); v != "" {
+		dbWarmup = v == "true" || v == "1"
+	} else if yc.Database.Warmup != nil {
+		dbWarmup = *yc.Database.Warmup
+	}
+
 	return &Config{
 		HTTPAddress:   httpAddr,
 		DatabaseDSN:   dsn,
 		JWTSecret:     jwtSecret,
+		BcryptCost:    bcryptCost,
+		TokenDuration: time.Duration(tokenHours) * time.Hour,
 		StoragePath:   storagePath,
 		OpenAIAPIKey:  openAIKey,
 		AIProvider:    aiProvider,
@@ -169,6 +410,26 @@ func Load() (*Config, error) {
 		RocketRampTestMode:   rocketRampTestMode,
 		AppURL:        appURL,
 		MarineAPIKey:  marineAPIKey,
+		CacheTTL:      cacheTTL,
+		MaxListOffset: maxListOffset,
+		MaxListLimit:  maxListLimit,
+		MaxVoyagePorts: maxVoyagePorts,
+		CoordinatePrecision: coordinatePrecision,
+		MaxNotesLength: maxNotesLength,
+		CurrencyOrder:  currencyOrder,
+		SensitiveVesselFields: sensitiveVesselFields,
+		MaxCharterDuration: time.Duration(maxCharterDays) * 24 * time.Hour,
+		MetricsEnabled: metricsEnabled,
+		DBWarmup:       dbWarmup,
+		HTTPReadHeaderTimeout: readHeaderTimeout,
+		HTTPReadTimeout:       readTimeout,
+		HTTPWriteTimeout:      writeTimeout,
+		HTTPIdleTimeout:       idleTimeout,
+		LongRunningTimeout:    longRunningTimeout,
+		ShutdownTimeout:       shutdownTimeout,
+		TLSCertFile:           tlsCertFile,
+		TLSKeyFile:            tlsKeyFile,
+		ResponseEnvelope:      responseEnvelope,
 		Email: EmailConfig{
 			SendGridAPIKey: envOr("SENDGRID_API_KEY", yc.Email.SendGridAPIKey, ""),
 			TemplateID:     envOr("SENDGRID_TEMPLATE_ID", yc.Email.TemplateID, ""),
@@ -200,6 +461,16 @@ func loadYAML() yamlConfig {
 	return yc
 }
 
+// durationOr resolves a setting like envOr and parses it as a Go duration.
+func durationOr(envKey, yamlValue, fallback string) (time.Duration, error) {
+	raw := envOr(envKey, yamlValue, fallback)
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", envKey, err)
+	}
+	return d, nil
+}
+
 // envOr returns the env var if set, otherwise yamlValue, otherwise fallback.
 func envOr(envKey, yamlValue, fallback string) string {
 	if v := os.Getenv(envKey); v != "" {
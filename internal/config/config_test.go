@@ -0,0 +1,248 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadServerTimeouts(t *testing.T) {
+	envs := []string{
+		"HTTP_READ_HEADER_TIMEOUT", "HTTP_READ_TIMEOUT", "HTTP_WRITE_TIMEOUT",
+		"HTTP_IDLE_TIMEOUT", "HTTP_LONG_RUNNING_TIMEOUT", "SHUTDOWN_TIMEOUT",
+	}
+	type timeouts struct {
+		readHeader, read, write, idle, longRunning, shutdown time.Duration
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    timeouts
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: timeouts{10 * time.Second, 30 * time.Second, 30 * time.Second, 60 * time.Second, 5 * time.Minute, 10 * time.Second},
+		},
+		{
+			name: "env overrides",
+			env: map[string]string{
+				"HTTP_READ_HEADER_TIMEOUT":  "2s",
+				"HTTP_READ_TIMEOUT":         "1m",
+				"HTTP_WRITE_TIMEOUT":        "90s",
+				"HTTP_IDLE_TIMEOUT":         "2m",
+				"HTTP_LONG_RUNNING_TIMEOUT": "15m",
+				"SHUTDOWN_TIMEOUT":          "45s",
+			},
+			want: timeouts{2 * time.Second, time.Minute, 90 * time.Second, 2 * time.Minute, 15 * time.Minute, 45 * time.Second},
+		},
+		{
+			name:    "invalid duration",
+			env:     map[string]string{"HTTP_WRITE_TIMEOUT": "soon"},
+			wantErr: "parse HTTP_WRITE_TIMEOUT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range envs {
+				t.Setenv(k, tt.env[k])
+			}
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := timeouts{
+				cfg.HTTPReadHeaderTimeout, cfg.HTTPReadTimeout, cfg.HTTPWriteTimeout,
+				cfg.HTTPIdleTimeout, cfg.LongRunningTimeout, cfg.ShutdownTimeout,
+			}
+			if got != tt.want {
+				t.Errorf("timeouts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCurrencyOrder(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{"", nil},
+		{"USD,EUR", []string{"USD", "EUR"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("PAYMENT_CURRENCY_ORDER", tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.CurrencyOrder, tt.want) {
+				t.Errorf("currency order = %q, want %q", cfg.CurrencyOrder, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMaxCharterDuration(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", 3650 * 24 * time.Hour},
+		{"30", 30 * 24 * time.Hour},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("CHARTER_MAX_DURATION_DAYS", tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MaxCharterDuration != tt.want {
+				t.Errorf("max charter duration = %s, want %s", cfg.MaxCharterDuration, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("CHARTER_MAX_DURATION_DAYS", "ten")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted a non-numeric CHARTER_MAX_DURATION_DAYS")
+	}
+}
+
+func TestLoadMaxListLimit(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"", 1000},
+		{"250", 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("MAX_LIST_LIMIT", tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MaxListLimit != tt.want {
+				t.Errorf("max list limit = %d, want %d", cfg.MaxListLimit, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("MAX_LIST_LIMIT", "all")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted a non-numeric MAX_LIST_LIMIT")
+	}
+}
+
+func TestLoadMaxNotesLength(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"", 4000},
+		{"500", 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("MAX_NOTES_LENGTH", tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MaxNotesLength != tt.want {
+				t.Errorf("max notes length = %d, want %d", cfg.MaxNotesLength, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("MAX_NOTES_LENGTH", "long")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted a non-numeric MAX_NOTES_LENGTH")
+	}
+}
+
+func TestLoadResponseEnvelope(t *testing.T) {
+	for _, env := range []string{"", "data", "none"} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv("RESPONSE_ENVELOPE", env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := env
+			if want == "" {
+				want = "data"
+			}
+			if cfg.ResponseEnvelope != want {
+				t.Errorf("response envelope = %q, want %q", cfg.ResponseEnvelope, want)
+			}
+		})
+	}
+
+	t.Setenv("RESPONSE_ENVELOPE", "bare")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted RESPONSE_ENVELOPE=bare")
+	}
+}
+
+func TestLoadSensitiveVesselFields(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{"", nil},
+		{"owner,manager,documentation_uri", []string{"owner", "manager", "documentation_uri"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("VESSEL_SENSITIVE_FIELDS", tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.SensitiveVesselFields, tt.want) {
+				t.Errorf("sensitive vessel fields = %q, want %q", cfg.SensitiveVesselFields, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCoordinatePrecision(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"", 6},
+		{"4", 4},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("COORDINATE_PRECISION", tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.CoordinatePrecision != tt.want {
+				t.Errorf("coordinate precision = %d, want %d", cfg.CoordinatePrecision, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("COORDINATE_PRECISION", "fine")
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted a non-numeric COORDINATE_PRECISION")
+	}
+}
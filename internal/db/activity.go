@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActivityItem is one row of the cross-entity activity feed.
+type ActivityItem struct {
+	Kind      string     `json:"kind"` // charter | voyage | payment | dispute
+	ID        uuid.UUID  `json:"id"`
+	CharterID *uuid.UUID `json:"charter_id,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Summary   string     `json:"summary"`
+}
+
+// ActivityRepository reads the activity feed.
+type ActivityRepository struct{}
+
+// NewActivityRepository returns a repository.
+func NewActivityRepository() *ActivityRepository {
+	return &ActivityRepository{}
+}
+
+// Recent returns the limit most recently updated charters, voyages, payments
+// and disputes, newest first. Each source is limited before the merge so the
+// union stays small regardless of table size.
+func (repo *ActivityRepository) Recent(ctx context.Context, limit int) ([]ActivityItem, error) {
+	const query = `
+		SELECT kind, id, charter_id, updated_at, summary
+		FROM (
+			(SELECT 'charter' AS kind, id, id AS charter_id, updated_at,
+			        title || ' (' || COALESCE(status, 'draft') || ')' AS summary
+			 FROM shipman.charter_details
+			 ORDER BY updated_at DESC LIMIT $1)
+			UNION ALL
+			(SELECT 'voyage', id, charter_detail_id, updated_at,
+			        COALESCE(voyage_number, vessel_name, 'Voyage') || ' (' || status || ')'
+			 FROM shipman.voyages
+			 ORDER BY updated_at DESC LIMIT $1)
+			UNION ALL
+			(SELECT 'payment', p.id, v.charter_detail_id, p.updated_at,
+			        p.payment_type || ' ' || p.amount::text || ' ' || p.currency || ' (' || p.status || ')'
+			 FROM shipman.voyage_payments p
+			 JOIN shipman.voyages v ON v.id = p.voyage_id
+			 ORDER BY p.updated_at DESC LIMIT $1)
+			UNION ALL
+			(SELECT 'dispute', id, charter_detail_id, updated_at,
+			        subject || ' (' || COALESCE(status, 'open') || ')'
+			 FROM shipman.disputes
+			 ORDER BY updated_at DESC LIMIT $1)
+		) feed
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $1
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ActivityItem
+	for rows.Next() {
+		var (
+			item      ActivityItem
+			charterID sql.NullString
+		)
+		if err := rows.Scan(&item.Kind, &item.ID, &charterID, &item.UpdatedAt, &item.Summary); err != nil {
+			return nil, err
+		}
+		item.CharterID = uuidPtrNullable(charterID)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const activityQuery = "SELECT kind, id, charter_id, updated_at, summary FROM ("
+
+func TestActivityRecentQueryLimitsEverySource(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return(activityQuery, dbtest.Rows([]string{"kind", "id", "charter_id", "updated_at", "summary"}))
+
+	if _, err := NewActivityRepository().Recent(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls(activityQuery)
+	if len(calls) != 1 {
+		t.Fatalf("feed queries = %d, want 1", len(calls))
+	}
+	if got := calls[0].Arg(1); got != int64(7) {
+		t.Errorf("limit = %v, want 7", got)
+	}
+
+	query := calls[0].Query
+	for _, table := range []string{"charter_details", "voyages", "voyage_payments", "disputes"} {
+		source := regexp.MustCompile(`FROM shipman\.` + table + `\b[^()]*ORDER BY (\w+\.)?updated_at DESC LIMIT \$1\)`)
+		if !source.MatchString(query) {
+			t.Errorf("%s is not limited to its newest rows before the merge", table)
+		}
+	}
+	if !strings.HasSuffix(strings.TrimSpace(query), ") feed ORDER BY updated_at DESC, id DESC LIMIT $1") {
+		t.Errorf("merged feed is not ordered newest first: %s", query)
+	}
+}
+
+func TestActivityRecentScansMergedRows(t *testing.T) {
+	fake := newFakeDB(t)
+	charterID := uuid.New()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	want := []ActivityItem{
+		{Kind: "dispute", ID: uuid.New(), CharterID: &charterID, UpdatedAt: base, Summary: "Short shipment (open)"},
+		{Kind: "payment", ID: uuid.New(), CharterID: &charterID, UpdatedAt: base.Add(-time.Minute), Summary: "hire 1000.00 USD (paid)"},
+		{Kind: "voyage", ID: uuid.New(), UpdatedAt: base.Add(-time.Hour), Summary: "V-1 (planned)"},
+		{Kind: "charter", ID: charterID, CharterID: &charterID, UpdatedAt: base.Add(-24 * time.Hour), Summary: "Grain charter (draft)"},
+	}
+	var rows [][]any
+	for _, item := range want {
+		var charter any
+		if item.CharterID != nil {
+			charter = item.CharterID.String()
+		}
+		rows = append(rows, []any{item.Kind, item.ID, charter, item.UpdatedAt, item.Summary})
+	}
+	fake.Return(activityQuery, dbtest.Rows([]string{"kind", "id", "charter_id", "updated_at", "summary"}, rows...))
+
+	got, err := NewActivityRepository().Recent(context.Background(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("items = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Kind != w.Kind || g.ID != w.ID || !g.UpdatedAt.Equal(w.UpdatedAt) || g.Summary != w.Summary {
+			t.Errorf("item %d = %+v, want %+v", i, g, w)
+		}
+		if (g.CharterID == nil) != (w.CharterID == nil) || (g.CharterID != nil && *g.CharterID != *w.CharterID) {
+			t.Errorf("item %d charter = %v, want %v", i, g.CharterID, w.CharterID)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var (
+	statusIn    = regexp.MustCompile(`\bstatus IN \(([^)]*)\)`)
+	statusNotIn = regexp.MustCompile(`\bstatus NOT IN \(([^)]*)\)`)
+)
+
+// quotedList returns the values of a SQL list like 'a', 'b' matched by re.
+func quotedList(t *testing.T, re *regexp.Regexp, query string) []string {
+	t.Helper()
+	m := re.FindStringSubmatch(query)
+	if m == nil {
+		t.Fatalf("query %q has no %s", query, re)
+	}
+	var out []string
+	for _, v := range strings.Split(m[1], ",") {
+		out = append(out, strings.Trim(strings.TrimSpace(v), "'"))
+	}
+	return out
+}
+
+// archiveRow is a dispute or charter row as the archive statements see it.
+type archiveRow struct {
+	id         uuid.UUID
+	charterID  uuid.UUID // disputes only
+	status     string
+	settledAt  *time.Time
+	updatedAt  time.Time
+	archivedAt *time.Time
+}
+
+// fakeArchiveBefore answers both archive UPDATEs against disputes and
+// charters, applying the statuses named in each statement so the test
+// checks the SQL's own status lists.
+type fakeArchiveBefore struct {
+	mu       sync.Mutex
+	disputes []*archiveRow
+	charters []*archiveRow
+}
+
+func (f *fakeArchiveBefore) install(t *testing.T, fake *dbtest.Fake) {
+	fake.On("UPDATE shipman.disputes SET archived_at = NOW()", func(call dbtest.Call) dbtest.Result {
+		final := quotedList(t, statusIn, call.Query)
+		before := call.Arg(1).(time.Time)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var n int64
+		for _, d := range f.disputes {
+			at := d.updatedAt
+			if d.settledAt != nil {
+				at = *d.settledAt
+			}
+			if d.archivedAt == nil && slices.Contains(final, d.status) && at.Before(before) {
+				now := time.Now()
+				d.archivedAt = &now
+				n++
+			}
+		}
+		return dbtest.Affected(n)
+	})
+	fake.On("UPDATE shipman.charter_details cd SET archived_at = NOW()", func(call dbtest.Call) dbtest.Result {
+		closed := quotedList(t, statusIn, call.Query)
+		settled := quotedList(t, statusNotIn, call.Query)
+		before := call.Arg(1).(time.Time)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var n int64
+		for _, c := range f.charters {
+			if c.archivedAt != nil || !slices.Contains(closed, c.status) || !c.updatedAt.Before(before) {
+				continue
+			}
+			if slices.ContainsFunc(f.disputes, func(d *archiveRow) bool {
+				return d.charterID == c.id && !slices.Contains(settled, d.status)
+			}) {
+				continue
+			}
+			now := time.Now()
+			c.archivedAt = &now
+			n++
+		}
+		return dbtest.Affected(n)
+	})
+}
+
+func archivedIDs(rows []*archiveRow) []uuid.UUID {
+	var out []uuid.UUID
+	for _, r := range rows {
+		if r.archivedAt != nil {
+			out = append(out, r.id)
+		}
+	}
+	return out
+}
+
+func TestDisputeArchiveResolvedBefore(t *testing.T) {
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	old, recent := cutoff.AddDate(0, -6, 0), cutoff.AddDate(0, 1, 0)
+	earlier := cutoff.AddDate(-1, 0, 0)
+	row := func(status string, settled *time.Time, updated time.Time) *archiveRow {
+		return &archiveRow{id: uuid.New(), status: status, settledAt: settled, updatedAt: updated}
+	}
+
+	fake := newFakeDB(t)
+	table := &fakeArchiveBefore{disputes: []*archiveRow{
+		row("resolved", &old, recent),  // settled long ago, touched since
+		row("closed", nil, old),        // no settled_at, falls back to updated_at
+		row("resolved", &recent, old),  // settled after the cutoff
+		row("open", nil, old),          // still in progress
+		row("under_review", &old, old), // still in progress
+		{id: uuid.New(), status: "closed", updatedAt: old, archivedAt: &earlier},
+	}}
+	table.install(t, fake)
+
+	n, err := NewDisputeRepository().ArchiveResolvedBefore(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("archived = %d, want 2", n)
+	}
+	want := []uuid.UUID{table.disputes[0].id, table.disputes[1].id, table.disputes[5].id}
+	if got := archivedIDs(table.disputes); !slices.Equal(got, want) {
+		t.Errorf("archived disputes = %v, want %v", got, want)
+	}
+	if !table.disputes[5].archivedAt.Equal(earlier) {
+		t.Error("an already archived dispute was re-stamped")
+	}
+	if calls := fake.Calls(""); len(calls) != 1 || !strings.HasPrefix(calls[0].Query, "UPDATE") {
+		t.Errorf("statements = %+v, want a single UPDATE leaving referencing rows alone", calls)
+	}
+}
+
+func TestCharterArchiveClosedBefore(t *testing.T) {
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	old, recent := cutoff.AddDate(0, -6, 0), cutoff.AddDate(0, 1, 0)
+	charter := func(status string, updated time.Time) *archiveRow {
+		return &archiveRow{id: uuid.New(), status: status, updatedAt: updated}
+	}
+
+	fake := newFakeDB(t)
+	table := &fakeArchiveBefore{charters: []*archiveRow{
+		charter("closed", old),
+		charter("completed", old),
+		charter("closed", recent),
+		charter("active", old),
+		charter("draft", old),
+		charter("closed", old), // has an open dispute
+		charter("closed", old), // its disputes are settled
+	}}
+	table.disputes = []*archiveRow{
+		{id: uuid.New(), charterID: table.charters[5].id, status: "under_review", updatedAt: old},
+		{id: uuid.New(), charterID: table.charters[6].id, status: "resolved", updatedAt: old},
+		{id: uuid.New(), charterID: table.charters[6].id, status: "closed", updatedAt: old},
+	}
+	table.install(t, fake)
+
+	n, err := NewCharterDetailRepository().ArchiveClosedBefore(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("archived = %d, want 3", n)
+	}
+	want := []uuid.UUID{table.charters[0].id, table.charters[1].id, table.charters[6].id}
+	if got := archivedIDs(table.charters); !slices.Equal(got, want) {
+		t.Errorf("archived charters = %v, want %v", got, want)
+	}
+	if got := archivedIDs(table.disputes); len(got) != 0 {
+		t.Errorf("archiving charters touched disputes %v", got)
+	}
+	if calls := fake.Calls(""); len(calls) != 1 || !strings.HasPrefix(calls[0].Query, "UPDATE") {
+		t.Errorf("statements = %+v, want a single UPDATE leaving referencing rows alone", calls)
+	}
+}
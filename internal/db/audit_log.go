@@ -0,0 +1,154 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry mirrors shipman.audit_log. Changes maps each modified field's
+// JSON name to its before and after values.
+type AuditEntry struct {
+	ID         uuid.UUID              `json:"id"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   uuid.UUID              `json:"entity_id"`
+	UserID     *uuid.UUID             `json:"user_id,omitempty"`
+	Changes    map[string]FieldChange `json:"changes"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// FieldChange is one field's value before and after an edit. ChangedAt and
+// ChangedBy are filled in by FieldHistory.
+type FieldChange struct {
+	ChangedAt time.Time       `json:"changed_at,omitempty"`
+	ChangedBy *uuid.UUID      `json:"changed_by,omitempty"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+}
+
+// AuditEntityCharter is the entity_type recorded for charter details.
+const AuditEntityCharter = "charter"
+
+// AuditLogService describes audit log behaviour.
+type AuditLogService interface {
+	Record(ctx context.Context, entry *AuditEntry) error
+	FieldHistory(ctx context.Context, entityType string, entityID uuid.UUID, field string) ([]FieldChange, error)
+}
+
+// AuditLogRepository implements AuditLogService using Pool.
+type AuditLogRepository struct{}
+
+// NewAuditLogRepository returns a repository.
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{}
+}
+
+// DiffFields compares the JSON encodings of before and after and returns the
+// top-level fields whose values differ. updated_at is ignored.
+func DiffFields(before, after any) (map[string]FieldChange, error) {
+	var b, a map[string]json.RawMessage
+	if err := remarshal(before, &b); err != nil {
+		return nil, err
+	}
+	if err := remarshal(after, &a); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]FieldChange)
+	for key, av := range a {
+		bv := b[key]
+		if !bytes.Equal(bv, av) {
+			changes[key] = FieldChange{Before: jsonOrNull(bv), After: av}
+		}
+	}
+	for key, bv := range b {
+		if _, ok := a[key]; !ok {
+			changes[key] = FieldChange{Before: bv, After: jsonOrNull(nil)}
+		}
+	}
+	delete(changes, "updated_at")
+	return changes, nil
+}
+
+func remarshal(v any, out *map[string]json.RawMessage) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func jsonOrNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// Record stores an audit entry. Entries with no changes are skipped.
+func (repo *AuditLogRepository) Record(ctx context.Context, entry *AuditEntry) error {
+	if len(entry.Changes) == 0 {
+		return nil
+	}
+	clearServerFields(&entry.ID, &entry.CreatedAt)
+
+	type storedChange struct {
+		Before json.RawMessage `json:"before"`
+		After  json.RawMessage `json:"after"`
+	}
+	changes := make(map[string]storedChange, len(entry.Changes))
+	for field, ch := range entry.Changes {
+		changes[field] = storedChange{Before: ch.Before, After: ch.After}
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO shipman.audit_log (entity_type, entity_id, user_id, changes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return Conn(ctx).QueryRowContext(ctx, query,
+		entry.EntityType, entry.EntityID, nullableUUID(entry.UserID), raw,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// FieldHistory returns every recorded change to one field of an entity,
+// oldest first.
+func (repo *AuditLogRepository) FieldHistory(ctx context.Context, entityType string, entityID uuid.UUID, field string) ([]FieldChange, error) {
+	const query = `
+		SELECT created_at, user_id, changes -> $3 -> 'before', changes -> $3 -> 'after'
+		FROM shipman.audit_log
+		WHERE entity_type = $1 AND entity_id = $2 AND changes -> $3 IS NOT NULL
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, entityType, entityID, field)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FieldChange
+	for rows.Next() {
+		var (
+			ch            FieldChange
+			userID        sql.NullString
+			before, after []byte
+		)
+		if err := rows.Scan(&ch.ChangedAt, &userID, &before, &after); err != nil {
+			return nil, err
+		}
+		ch.ChangedBy = uuidPtrNullable(userID)
+		ch.Before = jsonOrNull(before)
+		ch.After = jsonOrNull(after)
+		out = append(out, ch)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,28 @@
+package db
+
+import "context"
+
+// BatchErrors maps the index of each batch item that was not written to the
+// error that stopped it.
+type BatchErrors map[int]error
+
+// createEach runs create for every item with no surrounding transaction, so
+// one failure does not undo the others. It returns how many succeeded and
+// the errors of those that did not; the map is nil when all succeeded.
+func createEach[T any](ctx context.Context, items []T, create func(context.Context, T) error) (int, BatchErrors) {
+	var (
+		created int
+		errs    BatchErrors
+	)
+	for i, item := range items {
+		if err := create(ctx, item); err != nil {
+			if errs == nil {
+				errs = make(BatchErrors)
+			}
+			errs[i] = err
+			continue
+		}
+		created++
+	}
+	return created, errs
+}
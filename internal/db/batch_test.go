@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateEach(t *testing.T) {
+	boom := errors.New("boom")
+	var seen []int
+	created, errs := createEach(context.Background(), []int{1, 2, 3, 4}, func(_ context.Context, n int) error {
+		seen = append(seen, n)
+		if n%2 == 0 {
+			return boom
+		}
+		return nil
+	})
+	if created != 2 {
+		t.Errorf("created = %d, want 2", created)
+	}
+	if len(errs) != 2 || !errors.Is(errs[1], boom) || !errors.Is(errs[3], boom) {
+		t.Errorf("errs = %v, want boom at 1 and 3", errs)
+	}
+	if !slices.Equal(seen, []int{1, 2, 3, 4}) {
+		t.Errorf("visited %v, want every item after a failure", seen)
+	}
+
+	created, errs = createEach(context.Background(), []int{1, 3}, func(context.Context, int) error { return nil })
+	if created != 2 || errs != nil {
+		t.Errorf("all good: created %d, errs %v; want 2 and nil", created, errs)
+	}
+}
+
+func positionAt(voyageID uuid.UUID, lat, lon float64) *ShipPosition {
+	return &ShipPosition{VoyageID: voyageID, RecordedAt: time.Now(), Latitude: lat, Longitude: lon}
+}
+
+func TestShipPositionCreateBatchPartial(t *testing.T) {
+	fake := newFakeDB(t)
+	var table fakePositionTable
+	table.install(fake)
+	voyageID := uuid.New()
+	long := strings.Repeat("x", MaxNotesLength+1)
+	tooLong := positionAt(voyageID, 10, 10)
+	tooLong.Remarks = &long
+
+	positions := []*ShipPosition{
+		positionAt(voyageID, 51.9, 4.1),
+		positionAt(voyageID, 91, 4.1),
+		positionAt(voyageID, 1.3, 103.8),
+		tooLong,
+		positionAt(voyageID, 0, 181),
+	}
+	created, errs := NewShipPositionRepository().CreateBatchPartial(context.Background(), positions)
+	if created != 2 {
+		t.Errorf("created = %d, want 2", created)
+	}
+	var verr *ValidationError
+	if len(errs) != 3 || !errors.As(errs[1], &verr) || !errors.Is(errs[3], ErrNotesTooLong) || !errors.As(errs[4], &verr) {
+		t.Fatalf("errs = %v, want coordinates at 1 and 4, remarks at 3", errs)
+	}
+	for _, i := range []int{0, 2} {
+		got, err := NewShipPositionRepository().Retrieve(context.Background(), positions[i].ID)
+		if err != nil || got.Latitude != positions[i].Latitude {
+			t.Errorf("position %d not persisted: %+v, %v", i, got, err)
+		}
+	}
+	if n := len(fake.Calls("INSERT INTO shipman.ship_positions")); n != 2 {
+		t.Errorf("inserts = %d, want only the valid positions", n)
+	}
+	if fake.Commits() != 0 || fake.Rollbacks() != 0 {
+		t.Errorf("commits %d, rollbacks %d; want no transaction", fake.Commits(), fake.Rollbacks())
+	}
+}
+
+func TestShipPositionCreateBatchAtomic(t *testing.T) {
+	voyageID := uuid.New()
+	tests := []struct {
+		name          string
+		positions     []*ShipPosition
+		wantErr       string
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{"all valid", []*ShipPosition{positionAt(voyageID, 51.9, 4.1), positionAt(voyageID, 1.3, 103.8)}, "", 1, 0},
+		{"one invalid", []*ShipPosition{positionAt(voyageID, 51.9, 4.1), positionAt(voyageID, -91, 0)}, "position 1: ", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			var table fakePositionTable
+			table.install(fake)
+
+			err := NewShipPositionRepository().CreateBatch(context.Background(), tt.positions, false)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want it to start with %q", err, tt.wantErr)
+			}
+			if fake.Commits() != tt.wantCommits || fake.Rollbacks() != tt.wantRollbacks {
+				t.Errorf("commits %d, rollbacks %d; want %d and %d", fake.Commits(), fake.Rollbacks(), tt.wantCommits, tt.wantRollbacks)
+			}
+		})
+	}
+}
+
+func TestVoyagePortCreateBatchPartial(t *testing.T) {
+	SetMaxVoyagePorts(2)
+	t.Cleanup(func() { SetMaxVoyagePorts(0) })
+	fake := newFakeDB(t)
+	var table fakePortTable
+	table.install(fake)
+	voyageID := uuid.New()
+
+	badLat := 95.0
+	invalid := newPort(voyageID, "Nowhere")
+	invalid.Latitude, invalid.Longitude = &badLat, &badLat
+	ports := []*VoyagePort{newPort(voyageID, "Santos"), invalid, newPort(voyageID, "Rotterdam"), newPort(voyageID, "Houston")}
+
+	created, errs := NewVoyagePortRepository().CreateBatchPartial(context.Background(), ports)
+	if created != 2 {
+		t.Errorf("created = %d, want 2", created)
+	}
+	var verr *ValidationError
+	if len(errs) != 2 || !errors.As(errs[1], &verr) || !errors.Is(errs[3], ErrTooManyPorts) {
+		t.Fatalf("errs = %v, want coordinates at 1 and the port cap at 3", errs)
+	}
+	if got := table.count(voyageID); got != 2 {
+		t.Errorf("ports = %d, want the two valid ones kept", got)
+	}
+}
+
+func TestShipPositionCreateBatchOrdering(t *testing.T) {
+	voyageID := uuid.New()
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int, lat float64) *ShipPosition {
+		p := positionAt(voyageID, lat, 4.1)
+		p.RecordedAt = base.Add(time.Duration(hour) * time.Hour)
+		return p
+	}
+	// Latitudes tag each position so the insert order can be read back; the
+	// two fixes at hour 2 must keep their relative order.
+	outOfOrder := func() []*ShipPosition {
+		return []*ShipPosition{at(3, 1), at(1, 2), at(2, 3), at(0, 4), at(2, 5)}
+	}
+
+	tests := []struct {
+		name      string
+		positions []*ShipPosition
+		strict    bool
+		wantErr   string
+		wantLats  []float64
+	}{
+		{"sorted by default", outOfOrder(), false, "", []float64{4, 2, 3, 5, 1}},
+		{"strict rejects", outOfOrder(), true, "position 1: ", nil},
+		{"strict accepts ordered", []*ShipPosition{at(0, 1), at(1, 2), at(1, 3)}, true, "", []float64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			var table fakePositionTable
+			table.install(fake)
+
+			err := NewShipPositionRepository().CreateBatch(context.Background(), tt.positions, tt.strict)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrNonMonotonicBatch) || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want ErrNonMonotonicBatch starting %q", err, tt.wantErr)
+				}
+				if n := len(fake.Calls("")); n != 0 {
+					t.Errorf("rejected batch ran %d statements, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var lats []float64
+			var last time.Time
+			for _, call := range fake.Calls("INSERT INTO shipman.ship_positions") {
+				recorded := call.Arg(2).(time.Time)
+				if recorded.Before(last) {
+					t.Errorf("inserted %s after %s", recorded, last)
+				}
+				last = recorded
+				lats = append(lats, call.Arg(3).(float64))
+			}
+			if !slices.Equal(lats, tt.wantLats) {
+				t.Errorf("insert order = %v, want %v", lats, tt.wantLats)
+			}
+			for i := 1; i < len(tt.positions); i++ {
+				if tt.positions[i].RecordedAt.Before(tt.positions[i-1].RecordedAt) {
+					t.Errorf("batch left out of order at %d", i)
+				}
+			}
+			if fake.Commits() != 1 {
+				t.Errorf("commits = %d, want 1", fake.Commits())
+			}
+		})
+	}
+}
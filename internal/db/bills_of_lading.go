@@ -48,6 +48,10 @@ func NewBillOfLadingRepository() *BillOfLadingRepository {
 
 // Create inserts a bill of lading.
 func (repo *BillOfLadingRepository) Create(ctx context.Context, bl *BillOfLading) error {
+	clearServerFields(&bl.ID, &bl.CreatedAt, &bl.UpdatedAt)
+	if err := sanitizeNotes(notesField{"cargo_description", bl.CargoDescription}, notesField{"notes", bl.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.bills_of_lading (
 			charter_detail_id,
@@ -70,7 +74,7 @@ func (repo *BillOfLadingRepository) Create(ctx context.Context, bl *BillOfLading
 		RETURNING id, created_at, updated_at
 	`
 
-	return Pool.QueryRowContext(
+	return Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		bl.CharterDetailID,
@@ -90,7 +94,8 @@ func (repo *BillOfLadingRepository) Create(ctx context.Context, bl *BillOfLading
 	).Scan(&bl.ID, &bl.CreatedAt, &bl.UpdatedAt)
 }
 
-// Retrieve fetches a bill of lading by id.
+// Retrieve fetches a bill of lading by id. Rows whose charter is outside
+// the org ctx is scoped to are reported as sql.ErrNoRows.
 func (repo *BillOfLadingRepository) Retrieve(ctx context.Context, id uuid.UUID) (BillOfLading, error) {
 	const query = `
 		SELECT
@@ -113,6 +118,9 @@ func (repo *BillOfLadingRepository) Retrieve(ctx context.Context, id uuid.UUID)
 			updated_at
 		FROM shipman.bills_of_lading
 		WHERE id = $1
+		  AND ($2::uuid IS NULL OR EXISTS (
+		      SELECT 1 FROM shipman.charter_details cd
+		      WHERE cd.id = charter_detail_id AND cd.org_id = $2))
 	`
 
 	var (
@@ -131,7 +139,7 @@ func (repo *BillOfLadingRepository) Retrieve(ctx context.Context, id uuid.UUID)
 		notes     sql.NullString
 	)
 
-	err := Pool.QueryRowContext(ctx, query, id).Scan(
+	err := Pool.QueryRowContext(ctx, query, id, orgFilter(ctx)).Scan(
 		&bl.ID,
 		&bl.CharterDetailID,
 		&voyage,
@@ -170,14 +178,14 @@ func (repo *BillOfLadingRepository) Retrieve(ctx context.Context, id uuid.UUID)
 	return bl, nil
 }
 
-// ListByCharter returns bills for a charter.
+// ListByCharter returns bills for a charter by issue date, undated bills
+// last and newest first among themselves.
 func (repo *BillOfLadingRepository) ListByCharter(ctx context.Context, charterID uuid.UUID) ([]BillOfLading, error) {
-	const query = `
+	query := `
 		SELECT id, charter_detail_id, document_number, issue_date, created_at, updated_at
 		FROM shipman.bills_of_lading
 		WHERE charter_detail_id = $1
-		ORDER BY issue_date NULLS LAST, created_at DESC
-	`
+		ORDER BY ` + orderBy(asc("issue_date"), desc("created_at"), desc("id"))
 
 	rows, err := Pool.QueryContext(ctx, query, charterID)
 	if err != nil {
@@ -209,6 +217,9 @@ func (repo *BillOfLadingRepository) ListByCharter(ctx context.Context, charterID
 
 // Update modifies bill of lading fields.
 func (repo *BillOfLadingRepository) Update(ctx context.Context, bl *BillOfLading) error {
+	if err := sanitizeNotes(notesField{"cargo_description", bl.CargoDescription}, notesField{"notes", bl.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.bills_of_lading
 		SET
@@ -230,7 +241,7 @@ func (repo *BillOfLadingRepository) Update(ctx context.Context, bl *BillOfLading
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		bl.ID,
@@ -248,6 +259,7 @@ func (repo *BillOfLadingRepository) Update(ctx context.Context, bl *BillOfLading
 		nullableBytes(bl.EncryptedKey),
 		nullableString(bl.Notes),
 	).Scan(&bl.UpdatedAt)
+	return notFound(err)
 }
 
 // Delete removes a bill of lading.
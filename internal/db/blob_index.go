@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// BlobIndexService describes reference-counted blob lookups by checksum.
+type BlobIndexService interface {
+	Acquire(ctx context.Context, checksum string) (string, bool, error)
+	Register(ctx context.Context, checksum, uri string) (string, error)
+	Release(ctx context.Context, uri string) (bool, error)
+}
+
+// BlobIndexRepository implements BlobIndexService using Pool.
+type BlobIndexRepository struct{}
+
+// NewBlobIndexRepository returns a repository.
+func NewBlobIndexRepository() *BlobIndexRepository {
+	return &BlobIndexRepository{}
+}
+
+// Acquire takes a reference on the blob with the given SHA-256 checksum and
+// returns its uri. ok is false when no such blob is indexed.
+func (repo *BlobIndexRepository) Acquire(ctx context.Context, checksum string) (string, bool, error) {
+	const query = `
+		UPDATE shipman.blob_index
+		SET ref_count = ref_count + 1, updated_at = NOW()
+		WHERE checksum = $1
+		RETURNING uri
+	`
+
+	var uri string
+	err := Conn(ctx).QueryRowContext(ctx, query, checksum).Scan(&uri)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return uri, true, nil
+}
+
+// Register indexes a freshly stored blob with one reference. When a
+// concurrent upload of the same content got there first, the existing entry
+// gains the reference instead and its uri is returned; the caller should then
+// discard the object it stored.
+func (repo *BlobIndexRepository) Register(ctx context.Context, checksum, uri string) (string, error) {
+	const query = `
+		INSERT INTO shipman.blob_index (checksum, uri)
+		VALUES ($1, $2)
+		ON CONFLICT (checksum) DO UPDATE
+		SET ref_count = shipman.blob_index.ref_count + 1, updated_at = NOW()
+		RETURNING uri
+	`
+
+	var stored string
+	err := Conn(ctx).QueryRowContext(ctx, query, checksum, uri).Scan(&stored)
+	return stored, err
+}
+
+// Release drops one reference on the blob at uri. It reports true when the
+// caller should delete the underlying object: either the last reference was
+// released or the uri was never indexed.
+func (repo *BlobIndexRepository) Release(ctx context.Context, uri string) (bool, error) {
+	var last bool
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var refs int
+		err := Conn(ctx).QueryRowContext(ctx, `
+			UPDATE shipman.blob_index
+			SET ref_count = ref_count - 1, updated_at = NOW()
+			WHERE uri = $1
+			RETURNING ref_count
+		`, uri).Scan(&refs)
+		if errors.Is(err, sql.ErrNoRows) {
+			last = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return nil
+		}
+		last = true
+		_, err = Conn(ctx).ExecContext(ctx, `DELETE FROM shipman.blob_index WHERE uri = $1`, uri)
+		return err
+	})
+	return last, err
+}
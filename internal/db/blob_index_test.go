@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+)
+
+// fakeBlobIndex keeps blob_index rows keyed by checksum and answers the
+// reference-counting statements against them.
+type fakeBlobIndex struct {
+	mu   sync.Mutex
+	uris map[string]string // checksum -> uri
+	refs map[string]int    // checksum -> ref_count
+}
+
+func newFakeBlobIndex(fake *dbtest.Fake) *fakeBlobIndex {
+	b := &fakeBlobIndex{uris: map[string]string{}, refs: map[string]int{}}
+	fake.On("SET ref_count = ref_count + 1, updated_at = NOW() WHERE checksum = $1", func(call dbtest.Call) dbtest.Result {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		sum := call.Arg(1).(string)
+		uri, ok := b.uris[sum]
+		if !ok {
+			return dbtest.Rows([]string{"uri"})
+		}
+		b.refs[sum]++
+		return dbtest.Rows([]string{"uri"}, []any{uri})
+	})
+	fake.On("INSERT INTO shipman.blob_index", func(call dbtest.Call) dbtest.Result {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		sum := call.Arg(1).(string)
+		if _, ok := b.uris[sum]; !ok {
+			b.uris[sum] = call.Arg(2).(string)
+		}
+		b.refs[sum]++
+		return dbtest.Rows([]string{"uri"}, []any{b.uris[sum]})
+	})
+	fake.On("SET ref_count = ref_count - 1", func(call dbtest.Call) dbtest.Result {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for sum, uri := range b.uris {
+			if uri == call.Arg(1) {
+				b.refs[sum]--
+				return dbtest.Rows([]string{"ref_count"}, []any{b.refs[sum]})
+			}
+		}
+		return dbtest.Rows([]string{"ref_count"})
+	})
+	fake.On("DELETE FROM shipman.blob_index WHERE uri = $1", func(call dbtest.Call) dbtest.Result {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for sum, uri := range b.uris {
+			if uri == call.Arg(1) {
+				delete(b.uris, sum)
+				delete(b.refs, sum)
+				return dbtest.Affected(1)
+			}
+		}
+		return dbtest.Affected(0)
+	})
+	return b
+}
+
+func TestBlobIndexSharesDuplicates(t *testing.T) {
+	fake := newFakeDB(t)
+	index := newFakeBlobIndex(fake)
+	repo := NewBlobIndexRepository()
+	ctx := context.Background()
+	const sum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	if _, ok, err := repo.Acquire(ctx, sum); err != nil || ok {
+		t.Fatalf("Acquire on an empty index = %v, %v; want a miss", ok, err)
+	}
+	stored, err := repo.Register(ctx, sum, "first.pdf")
+	if err != nil || stored != "first.pdf" {
+		t.Fatalf("Register = %q, %v; want first.pdf", stored, err)
+	}
+	uri, ok, err := repo.Acquire(ctx, sum)
+	if err != nil || !ok || uri != "first.pdf" {
+		t.Fatalf("Acquire duplicate = %q, %v, %v; want the existing first.pdf", uri, ok, err)
+	}
+	// A concurrent upload that stored its own copy is pointed at the winner.
+	if stored, err := repo.Register(ctx, sum, "second.pdf"); err != nil || stored != "first.pdf" {
+		t.Fatalf("racing Register = %q, %v; want first.pdf", stored, err)
+	}
+	if index.refs[sum] != 3 {
+		t.Fatalf("refs = %d, want 3", index.refs[sum])
+	}
+
+	for i, wantLast := range []bool{false, false, true} {
+		last, err := repo.Release(ctx, "first.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last != wantLast {
+			t.Errorf("release %d: last = %v, want %v", i+1, last, wantLast)
+		}
+	}
+	if _, ok := index.uris[sum]; ok {
+		t.Error("the index entry outlived its last reference")
+	}
+	if fake.Commits() != 3 || fake.Rollbacks() != 0 {
+		t.Errorf("commits/rollbacks = %d/%d, want 3/0", fake.Commits(), fake.Rollbacks())
+	}
+}
+
+func TestBlobIndexReleaseUnindexed(t *testing.T) {
+	fake := newFakeDB(t)
+	newFakeBlobIndex(fake)
+
+	last, err := NewBlobIndexRepository().Release(context.Background(), "legacy.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last {
+		t.Error("a blob stored before the index was kept")
+	}
+	if len(fake.Calls("DELETE FROM shipman.blob_index")) != 0 {
+		t.Error("deleted an index row that did not exist")
+	}
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ttlCache is a small concurrency-safe map keyed by row id whose entries
+// expire after ttl. A nil *ttlCache is a valid, always-missing cache so
+// repositories can call it unconditionally when caching is disabled.
+//
+// Values are copied with clone on the way in and out, so callers that edit
+// a returned row (Normalize, sanitizeNotes) never write through shared
+// pointers into the cached one.
+type ttlCache[T any] struct {
+	mu    sync.RWMutex
+	ttl   time.Duration
+	clone func(T) T
+	items map[uuid.UUID]cacheEntry[T]
+}
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func newTTLCache[T any](ttl time.Duration, clone func(T) T) *ttlCache[T] {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ttlCache[T]{ttl: ttl, clone: clone, items: make(map[uuid.UUID]cacheEntry[T])}
+}
+
+func (c *ttlCache[T]) get(id uuid.UUID) (T, bool) {
+	var zero T
+	if c == nil {
+		return zero, false
+	}
+	c.mu.RLock()
+	entry, ok := c.items[id]
+	c.mu.RUnlock()
+	if !ok {
+		return zero, false
+	}
+	if now().After(entry.expiresAt) {
+		c.invalidate(id)
+		return zero, false
+	}
+	return c.clone(entry.value), true
+}
+
+func (c *ttlCache[T]) set(id uuid.UUID, value T) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.items[id] = cacheEntry[T]{value: c.clone(value), expiresAt: now().Add(c.ttl)}
+	c.mu.Unlock()
+}
+
+func (c *ttlCache[T]) invalidate(id uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.items, id)
+	c.mu.Unlock()
+}
+
+// clear drops every entry.
+func (c *ttlCache[T]) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.items = make(map[uuid.UUID]cacheEntry[T])
+	c.mu.Unlock()
+}
+
+var (
+	vesselCache  *ttlCache[Vessel]
+	charterCache *ttlCache[CharterDetail]
+)
+
+// SetCacheTTL enables the read-through cache for vessel and charter detail
+// lookups. A ttl of zero or less disables it.
+func SetCacheTTL(ttl time.Duration) {
+	vesselCache = newTTLCache(ttl, cloneVessel)
+	charterCache = newTTLCache(ttl, cloneCharterDetail)
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func cloneCharterDetail(d CharterDetail) CharterDetail {
+	d.CreatedByUserID = clonePtr(d.CreatedByUserID)
+	d.CharterReferenceCode = clonePtr(d.CharterReferenceCode)
+	d.VesselName = clonePtr(d.VesselName)
+	d.CounterpartyName = clonePtr(d.CounterpartyName)
+	d.StartDate = clonePtr(d.StartDate)
+	d.EndDate = clonePtr(d.EndDate)
+	d.LaytimeAllowanceHours = clonePtr(d.LaytimeAllowanceHours)
+	d.DemurrageRate = clonePtr(d.DemurrageRate)
+	d.DemurrageCurrency = clonePtr(d.DemurrageCurrency)
+	d.DespatchRate = clonePtr(d.DespatchRate)
+	d.DefaultCurrency = clonePtr(d.DefaultCurrency)
+	d.FuelClause = clonePtr(d.FuelClause)
+	d.PaymentTerms = clonePtr(d.PaymentTerms)
+	d.AIDocumentPath = clonePtr(d.AIDocumentPath)
+	d.AIExtractedTerms = bytes.Clone(d.AIExtractedTerms)
+	d.LastReviewedAt = clonePtr(d.LastReviewedAt)
+	d.ArchivedAt = clonePtr(d.ArchivedAt)
+	d.Notes = clonePtr(d.Notes)
+	return d
+}
+
+func cloneVessel(v Vessel) Vessel {
+	v.IMONumber = clonePtr(v.IMONumber)
+	v.FlagState = clonePtr(v.FlagState)
+	v.VesselType = clonePtr(v.VesselType)
+	v.CallSign = clonePtr(v.CallSign)
+	v.DeadweightTonnage = clonePtr(v.DeadweightTonnage)
+	v.GrossTonnage = clonePtr(v.GrossTonnage)
+	v.NetTonnage = clonePtr(v.NetTonnage)
+	v.Capacity = bytes.Clone(v.Capacity)
+	v.BuildYear = clonePtr(v.BuildYear)
+	v.ClassSociety = clonePtr(v.ClassSociety)
+	v.Owner = clonePtr(v.Owner)
+	v.Manager = clonePtr(v.Manager)
+	v.DocumentationURI = clonePtr(v.DocumentationURI)
+	v.Notes = clonePtr(v.Notes)
+	return v
+}
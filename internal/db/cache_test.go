@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestTTLCache(t *testing.T) {
+	start := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	freezeClock(t, start)
+	c := newTTLCache(time.Minute, func(s string) string { return s })
+	a, b := uuid.New(), uuid.New()
+
+	if _, ok := c.get(a); ok {
+		t.Fatal("empty cache hit")
+	}
+	c.set(a, "first")
+	c.set(b, "second")
+	if v, ok := c.get(a); !ok || v != "first" {
+		t.Fatalf("get = %q, %v; want a hit", v, ok)
+	}
+
+	c.invalidate(a)
+	if _, ok := c.get(a); ok {
+		t.Error("hit after invalidate")
+	}
+	if _, ok := c.get(b); !ok {
+		t.Error("invalidate dropped another entry")
+	}
+
+	freezeClock(t, start.Add(time.Minute))
+	if _, ok := c.get(b); !ok {
+		t.Error("entry expired at exactly its ttl")
+	}
+	freezeClock(t, start.Add(time.Minute+time.Second))
+	if _, ok := c.get(b); ok {
+		t.Error("hit after ttl")
+	}
+
+	c.set(a, "again")
+	c.clear()
+	if _, ok := c.get(a); ok {
+		t.Error("hit after clear")
+	}
+}
+
+func TestTTLCacheDisabled(t *testing.T) {
+	c := newTTLCache(0, func(s string) string { return s })
+	if c != nil {
+		t.Fatal("zero ttl built a cache")
+	}
+	id := uuid.New()
+	c.set(id, "x")
+	if _, ok := c.get(id); ok {
+		t.Error("disabled cache hit")
+	}
+	c.invalidate(id)
+	c.clear()
+}
+
+// setAllPointers points every nil pointer field of the struct at v to a new
+// zero value and gives every byte slice content.
+func setAllPointers(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Pointer:
+			f.Set(reflect.New(f.Type().Elem()))
+		case reflect.Slice:
+			f.Set(reflect.ValueOf([]byte(`{}`)).Convert(f.Type()))
+		}
+	}
+}
+
+// assertNoSharedPointers fails for any pointer or slice field a and b share.
+func assertNoSharedPointers(t *testing.T, a, b reflect.Value) {
+	t.Helper()
+	for i := 0; i < a.NumField(); i++ {
+		fa, fb := a.Field(i), b.Field(i)
+		switch fa.Kind() {
+		case reflect.Pointer, reflect.Slice:
+			if fa.Pointer() == fb.Pointer() {
+				t.Errorf("%s.%s is shared with the copy", a.Type().Name(), a.Type().Field(i).Name)
+			}
+		}
+	}
+}
+
+func TestCacheClonesShareNothing(t *testing.T) {
+	var d CharterDetail
+	setAllPointers(reflect.ValueOf(&d).Elem())
+	c := cloneCharterDetail(d)
+	assertNoSharedPointers(t, reflect.ValueOf(d), reflect.ValueOf(c))
+
+	var v Vessel
+	setAllPointers(reflect.ValueOf(&v).Elem())
+	cv := cloneVessel(v)
+	assertNoSharedPointers(t, reflect.ValueOf(v), reflect.ValueOf(cv))
+}
+
+func TestCharterCacheCopiesValues(t *testing.T) {
+	c := newTTLCache(time.Minute, cloneCharterDetail)
+	notes := "original"
+	d := CharterDetail{ID: uuid.New(), Notes: &notes}
+
+	c.set(d.ID, d)
+	*d.Notes = "edited after set"
+	got, _ := c.get(d.ID)
+	if *got.Notes != "original" {
+		t.Fatalf("cached notes = %q, want the value at set", *got.Notes)
+	}
+
+	*got.Notes = "edited after get"
+	again, _ := c.get(d.ID)
+	if *again.Notes != "original" {
+		t.Errorf("cached notes = %q after editing a returned copy", *again.Notes)
+	}
+}
+
+func TestCharterRetrieveCache(t *testing.T) {
+	fake := newFakeDB(t)
+	SetCacheTTL(time.Minute)
+	t.Cleanup(func() { SetCacheTTL(0) })
+
+	id := uuid.New()
+	created := time.Now()
+	fake.Return("updated_at FROM shipman.charter_details WHERE id = $1", dbtest.Rows(charterColumns, dbtest.Row(charterColumns, map[string]any{
+		"id": id, "org_id": DefaultOrgID, "title": "Cached", "status": "draft", "ai_status": "pending",
+		"notes": "  padded  ", "laytime_reversible": false, "created_at": created, "updated_at": created,
+	})))
+	fake.Return("UPDATE shipman.charter_details SET title = $2", dbtest.Rows([]string{"updated_at"}, []any{time.Now()}))
+	reads := func() int { return len(fake.Calls("updated_at FROM shipman.charter_details WHERE id = $1")) }
+	repo := NewCharterDetailRepository()
+	ctx := context.Background()
+
+	first, err := repo.Retrieve(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*first.Notes = "changed by the caller"
+	second, err := repo.Retrieve(ctx, id)
+	if err != nil || reads() != 1 {
+		t.Fatalf("second read: err %v, %d queries; want a cache hit", err, reads())
+	}
+	if *second.Notes != "  padded  " {
+		t.Errorf("cached notes = %q, want the stored value", *second.Notes)
+	}
+
+	// Update normalizes the caller's copy in place; the cached row must not
+	// change with it.
+	if err := repo.Update(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	if reads() != 1 {
+		t.Fatalf("update queried the charter %d times", reads()-1)
+	}
+	after, err := repo.Retrieve(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads() != 2 {
+		t.Errorf("read after update made %d queries, want the cache invalidated", reads())
+	}
+	if after.Title != "Cached" {
+		t.Errorf("title = %q", after.Title)
+	}
+
+	other := WithOrg(ctx, uuid.New())
+	if _, err := repo.Retrieve(other, id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("other org cache hit: err = %v, want sql.ErrNoRows", err)
+	}
+	if reads() != 2 {
+		t.Errorf("other-org read bypassed the cache")
+	}
+}
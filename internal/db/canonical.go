@@ -0,0 +1,72 @@
+package db
+
+import "strings"
+
+// Canonicalization runs before a row is written so the same port, vessel or
+// reference typed with different spacing or casing is stored once. Names are
+// trimmed with runs of internal whitespace collapsed to one space; codes
+// (currencies, IMO numbers) are additionally uppercased. Free-text notes are
+// only trimmed, by sanitizeNotes. UN/LOCODEs go through normalizeUNLocode.
+
+// collapseSpace trims s and collapses internal whitespace to single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// canonicalNames applies collapseSpace to each non-nil value in place.
+func canonicalNames(values ...*string) {
+	for _, v := range values {
+		if v != nil {
+			*v = collapseSpace(*v)
+		}
+	}
+}
+
+// canonicalCodes collapses and uppercases each non-nil value in place.
+func canonicalCodes(values ...*string) {
+	for _, v := range values {
+		if v != nil {
+			*v = strings.ToUpper(collapseSpace(*v))
+		}
+	}
+}
+
+// Normalize canonicalizes the charter's names, reference and currencies.
+func (d *CharterDetail) Normalize() {
+	canonicalNames(&d.Title, d.CharterReferenceCode, d.VesselName, d.CounterpartyName)
+	canonicalCodes(d.DemurrageCurrency, d.DefaultCurrency)
+}
+
+// Normalize canonicalizes the voyage's names, ports, IMO number and
+// currency. The counterparty email is only trimmed.
+func (v *Voyage) Normalize() {
+	canonicalNames(v.VoyageNumber, v.VesselName, v.VesselType, v.FlagState,
+		v.DeparturePort, v.ArrivalPort, v.CargoType, v.CounterpartyName)
+	canonicalCodes(v.IMONumber, &v.DemurrageCurrency)
+	if v.CounterpartyEmail != nil {
+		*v.CounterpartyEmail = strings.TrimSpace(*v.CounterpartyEmail)
+	}
+}
+
+// Normalize canonicalizes the port's name and country.
+func (vp *VoyagePort) Normalize() {
+	canonicalNames(&vp.PortName, vp.PortCountry)
+}
+
+// Normalize canonicalizes the vessel's names, flag and IMO number.
+func (vessel *Vessel) Normalize() {
+	canonicalNames(&vessel.Name, vessel.FlagState, vessel.VesselType,
+		vessel.ClassSociety, vessel.Owner, vessel.Manager)
+	canonicalCodes(vessel.IMONumber, vessel.CallSign)
+}
+
+// Normalize canonicalizes the payment's currency. The recipient fields are
+// only trimmed.
+func (p *VoyagePayment) Normalize() {
+	canonicalCodes(&p.Currency)
+	for _, v := range []*string{p.RecipientEmail, p.RecipientWallet} {
+		if v != nil {
+			*v = strings.TrimSpace(*v)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package db
+
+import "testing"
+
+func TestCollapseSpace(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"Rotterdam", "Rotterdam"},
+		{"  Rotterdam  ", "Rotterdam"},
+		{"Port \t of\n\nSpain", "Port of Spain"},
+		{"MV  Ever   Given", "MV Ever Given"},
+	}
+	for _, tt := range tests {
+		if got := collapseSpace(tt.in); got != tt.want {
+			t.Errorf("collapseSpace(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCharterDetailNormalize(t *testing.T) {
+	str := func(s string) *string { return &s }
+	d := CharterDetail{
+		Title:                "  Spring \t grain   charter ",
+		CharterReferenceCode: str(" GX-2026\n"),
+		VesselName:           str("MV   Ever  Given"),
+		CounterpartyName:     nil,
+		DemurrageCurrency:    str(" usd "),
+		DefaultCurrency:      str("eur"),
+		Notes:                str("  keep   my spacing  "),
+	}
+	d.Normalize()
+
+	tests := []struct {
+		field string
+		got   *string
+		want  string
+	}{
+		{"Title", &d.Title, "Spring grain charter"},
+		{"CharterReferenceCode", d.CharterReferenceCode, "GX-2026"},
+		{"VesselName", d.VesselName, "MV Ever Given"},
+		{"DemurrageCurrency", d.DemurrageCurrency, "USD"},
+		{"DefaultCurrency", d.DefaultCurrency, "EUR"},
+		{"Notes", d.Notes, "  keep   my spacing  "},
+	}
+	for _, tt := range tests {
+		if *tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, *tt.got, tt.want)
+		}
+	}
+	if d.CounterpartyName != nil {
+		t.Errorf("CounterpartyName = %q, want nil", *d.CounterpartyName)
+	}
+}
+
+func TestVoyageNormalize(t *testing.T) {
+	str := func(s string) *string { return &s }
+	v := Voyage{
+		VoyageNumber:      str(" V  001 "),
+		VesselName:        str("ever\tgiven"),
+		IMONumber:         str(" imo 9811000 "),
+		DeparturePort:     str("  Port  Said "),
+		ArrivalPort:       str("Rotterdam"),
+		DemurrageCurrency: " usd\n",
+		CounterpartyEmail: str("  Ops@Example.com  "),
+	}
+	v.Normalize()
+
+	tests := []struct {
+		field string
+		got   *string
+		want  string
+	}{
+		{"VoyageNumber", v.VoyageNumber, "V 001"},
+		{"VesselName", v.VesselName, "ever given"},
+		{"IMONumber", v.IMONumber, "IMO 9811000"},
+		{"DeparturePort", v.DeparturePort, "Port Said"},
+		{"ArrivalPort", v.ArrivalPort, "Rotterdam"},
+		{"DemurrageCurrency", &v.DemurrageCurrency, "USD"},
+		{"CounterpartyEmail", v.CounterpartyEmail, "Ops@Example.com"},
+	}
+	for _, tt := range tests {
+		if *tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, *tt.got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,18 +11,18 @@ import (
 
 // CargoLoad mirrors shipman.cargo_loads rows.
 type CargoLoad struct {
-	ID            uuid.UUID `json:"id"`
-	VoyageID      uuid.UUID `json:"voyage_id"`
-	LoadPort      *string   `json:"load_port,omitempty"`
-	DischargePort *string   `json:"discharge_port,omitempty"`
-	Commodity     *string   `json:"commodity,omitempty"`
-	Quantity      *float64  `json:"quantity,omitempty"`
-	Unit          *string   `json:"unit,omitempty"`
-	StowagePlan   []byte    `json:"stowage_plan,omitempty"`
-	Hazardous     *bool     `json:"hazardous,omitempty"`
-	Notes         *string   `json:"notes,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID       `json:"id"`
+	VoyageID      uuid.UUID       `json:"voyage_id"`
+	LoadPort      *string         `json:"load_port,omitempty"`
+	DischargePort *string         `json:"discharge_port,omitempty"`
+	Commodity     *string         `json:"commodity,omitempty"`
+	Quantity      *float64        `json:"quantity,omitempty"`
+	Unit          *string         `json:"unit,omitempty"`
+	StowagePlan   json.RawMessage `json:"stowage_plan,omitempty"`
+	Hazardous     *bool           `json:"hazardous,omitempty"`
+	Notes         *string         `json:"notes,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
 // CargoLoadService exposes CRUD behaviour.
@@ -29,6 +30,7 @@ type CargoLoadService interface {
 	Create(ctx context.Context, load *CargoLoad) error
 	Retrieve(ctx context.Context, id uuid.UUID) (CargoLoad, error)
 	ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]CargoLoad, error)
+	Search(ctx context.Context, filter CargoLoadFilter, page Page) ([]CargoLoad, error)
 	Update(ctx context.Context, load *CargoLoad) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -43,6 +45,13 @@ func NewCargoLoadRepository() *CargoLoadRepository {
 
 // Create inserts a cargo load row.
 func (repo *CargoLoadRepository) Create(ctx context.Context, load *CargoLoad) error {
+	clearServerFields(&load.ID, &load.CreatedAt, &load.UpdatedAt)
+	if err := sanitizeNotes(notesField{"notes", load.Notes}); err != nil {
+		return err
+	}
+	if err := checkJSON("stowage_plan", load.StowagePlan); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.cargo_loads (
 			voyage_id,
@@ -69,7 +78,7 @@ func (repo *CargoLoadRepository) Create(ctx context.Context, load *CargoLoad) er
 		nullableString(load.Commodity),
 		nullableFloat(load.Quantity),
 		nullableString(load.Unit),
-		nullableBytes(load.StowagePlan),
+		nullableJSON(load.StowagePlan),
 		nullableBool(load.Hazardous),
 		nullableString(load.Notes),
 	).Scan(&load.ID, &load.CreatedAt, &load.UpdatedAt)
@@ -130,7 +139,7 @@ func (repo *CargoLoadRepository) Retrieve(ctx context.Context, id uuid.UUID) (Ca
 	load.Commodity = stringPtr(commodity)
 	load.Quantity = floatPtr(quantity)
 	load.Unit = stringPtr(unit)
-	load.StowagePlan = bytesOrNil(stowage)
+	load.StowagePlan = jsonOrNil(stowage)
 	if hazardous.Valid {
 		val := hazardous.Bool
 		load.Hazardous = &val
@@ -182,8 +191,78 @@ func (repo *CargoLoadRepository) ListByVoyage(ctx context.Context, voyageID uuid
 	return loads, rows.Err()
 }
 
+// CargoLoadFilter narrows Search. Nil fields match everything.
+type CargoLoadFilter struct {
+	VoyageID  *uuid.UUID
+	Hazardous *bool
+}
+
+// Search returns cargo loads matching filter, newest first.
+func (repo *CargoLoadRepository) Search(ctx context.Context, filter CargoLoadFilter, page Page) ([]CargoLoad, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	var where conditions
+	if filter.VoyageID != nil {
+		where.add("voyage_id = $%d", *filter.VoyageID)
+	}
+	where.addBool("hazardous", filter.Hazardous)
+	where.page("created_at DESC, id DESC", page.limit(), page.Offset)
+
+	query := `
+		SELECT id, voyage_id, commodity, quantity, unit, hazardous, created_at, updated_at
+		FROM shipman.cargo_loads
+		WHERE TRUE
+	` + where.sql
+
+	rows, err := Pool.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loads []CargoLoad
+	for rows.Next() {
+		var (
+			load      CargoLoad
+			commodity sql.NullString
+			quantity  sql.NullFloat64
+			unit      sql.NullString
+			hazardous sql.NullBool
+		)
+		if err := rows.Scan(
+			&load.ID,
+			&load.VoyageID,
+			&commodity,
+			&quantity,
+			&unit,
+			&hazardous,
+			&load.CreatedAt,
+			&load.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		load.Commodity = stringPtr(commodity)
+		load.Quantity = floatPtr(quantity)
+		load.Unit = stringPtr(unit)
+		if hazardous.Valid {
+			val := hazardous.Bool
+			load.Hazardous = &val
+		}
+		loads = append(loads, load)
+	}
+	return loads, rows.Err()
+}
+
 // Update modifies a cargo load.
 func (repo *CargoLoadRepository) Update(ctx context.Context, load *CargoLoad) error {
+	if err := sanitizeNotes(notesField{"notes", load.Notes}); err != nil {
+		return err
+	}
+	if err := checkJSON("stowage_plan", load.StowagePlan); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.cargo_loads
 		SET
@@ -200,7 +279,7 @@ func (repo *CargoLoadRepository) Update(ctx context.Context, load *CargoLoad) er
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		load.ID,
@@ -209,10 +288,11 @@ func (repo *CargoLoadRepository) Update(ctx context.Context, load *CargoLoad) er
 		nullableString(load.Commodity),
 		nullableFloat(load.Quantity),
 		nullableString(load.Unit),
-		nullableBytes(load.StowagePlan),
+		nullableJSON(load.StowagePlan),
 		nullableBool(load.Hazardous),
 		nullableString(load.Notes),
 	).Scan(&load.UpdatedAt)
+	return notFound(err)
 }
 
 // Delete removes a cargo load.
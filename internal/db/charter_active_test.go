@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var activePredicate = regexp.MustCompile(`WHERE status = '(\w+)'`)
+
+// stubCharterTable answers both charter list queries from rows, honouring a
+// status predicate when the query has one.
+func stubCharterTable(fake *dbtest.Fake, rows []CharterDetail) {
+	fake.On("FROM shipman.charter_details", func(call dbtest.Call) dbtest.Result {
+		cols := []string{"id", "title", "status", "created_at", "updated_at"}
+		m := activePredicate.FindStringSubmatch(call.Query)
+		if m == nil {
+			cols = append(cols, "total")
+		}
+		var out [][]any
+		for _, c := range rows {
+			if m != nil && c.Status != m[1] {
+				continue
+			}
+			row := []any{c.ID, c.Title, c.Status, c.CreatedAt, c.UpdatedAt}
+			if m == nil {
+				row = append(row, int64(len(rows)))
+			}
+			out = append(out, row)
+		}
+		return dbtest.Rows(cols, out...)
+	})
+}
+
+func TestCharterListActive(t *testing.T) {
+	fake := newFakeDB(t)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var rows []CharterDetail
+	for i, status := range []string{"active", "draft", "active", "closed", "cancelled", "active"} {
+		at := base.Add(-time.Duration(i) * time.Hour)
+		rows = append(rows, CharterDetail{ID: uuid.New(), Title: status, Status: status, CreatedAt: at, UpdatedAt: at})
+	}
+	stubCharterTable(fake, rows)
+
+	got, err := NewCharterDetailRepository().ListActive(context.Background(), Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uuid.UUID{rows[0].ID, rows[2].ID, rows[5].ID}
+	if len(got) != len(want) {
+		t.Fatalf("active charters = %d, want %d", len(got), len(want))
+	}
+	for i, c := range got {
+		if c.ID != want[i] || c.Status != "active" {
+			t.Errorf("charter %d = %s (%s), want active %s", i, c.ID, c.Status, want[i])
+		}
+	}
+
+	call := fake.Calls("FROM shipman.charter_details")[0]
+	if call.Arg(1) != int64(10) || call.Arg(2) != int64(0) {
+		t.Errorf("limit/offset = %v/%v, want 10/0", call.Arg(1), call.Arg(2))
+	}
+
+	if _, err := NewCharterDetailRepository().ListActive(context.Background(), Page{Offset: MaxListOffset + 1}); !errors.Is(err, ErrOffsetTooLarge) {
+		t.Errorf("deep offset: err = %v, want ErrOffsetTooLarge", err)
+	}
+}
+
+// TestCharterListActiveMatchesPartialIndex keeps ListActive's filter and
+// ordering in step with the partial index, which the planner only uses when
+// both match.
+func TestCharterListActiveMatchesPartialIndex(t *testing.T) {
+	raw, err := os.ReadFile("../../db/migrations/000027_charter_details_active_index.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`ON shipman\.charter_details\(([^)]*)\)\s+WHERE (status = '\w+')`).FindSubmatch(raw)
+	if m == nil {
+		t.Fatal("partial index definition not found")
+	}
+	order, predicate := string(m[1]), string(m[2])
+
+	fake := newFakeDB(t)
+	stubCharterTable(fake, nil)
+	if _, err := NewCharterDetailRepository().ListActive(context.Background(), Page{}); err != nil {
+		t.Fatal(err)
+	}
+	query := fake.Calls("FROM shipman.charter_details")[0].Query
+	if !strings.Contains(query, "WHERE "+predicate+" ") || !strings.Contains(query, "ORDER BY "+order+" LIMIT") {
+		t.Errorf("query %q does not match index predicate %q ordered by %q", query, predicate, order)
+	}
+}
+
+// stubCharterVoyages answers ListWithoutVoyages from rows, skipping charters
+// in withVoyages and applying the statement's order and paging.
+func stubCharterVoyages(fake *dbtest.Fake, rows []CharterDetail, withVoyages map[uuid.UUID]bool) {
+	fake.On("WHERE NOT EXISTS ( SELECT 1 FROM shipman.voyages v WHERE v.charter_detail_id = cd.id )", func(call dbtest.Call) dbtest.Result {
+		var matched []CharterDetail
+		for _, c := range rows {
+			if !withVoyages[c.ID] {
+				matched = append(matched, c)
+			}
+		}
+		sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+		limit, offset := int(call.Arg(1).(int64)), int(call.Arg(2).(int64))
+		matched = matched[min(offset, len(matched)):min(offset+limit, len(matched))]
+		var out [][]any
+		for _, c := range matched {
+			out = append(out, []any{c.ID, c.Title, c.Status, c.CreatedAt, c.UpdatedAt})
+		}
+		return dbtest.Rows([]string{"id", "title", "status", "created_at", "updated_at"}, out...)
+	})
+}
+
+func TestCharterListWithoutVoyages(t *testing.T) {
+	fake := newFakeDB(t)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var rows []CharterDetail
+	for i, status := range []string{"draft", "active", "closed", "draft", "active"} {
+		at := base.Add(time.Duration(i) * time.Hour)
+		rows = append(rows, CharterDetail{ID: uuid.New(), Title: status, Status: status, CreatedAt: at, UpdatedAt: at})
+	}
+	withVoyages := map[uuid.UUID]bool{rows[1].ID: true, rows[3].ID: true}
+	stubCharterVoyages(fake, rows, withVoyages)
+	repo := NewCharterDetailRepository()
+
+	got, err := repo.ListWithoutVoyages(context.Background(), Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uuid.UUID, len(got))
+	for i, c := range got {
+		ids[i] = c.ID
+	}
+	if want := []uuid.UUID{rows[4].ID, rows[2].ID, rows[0].ID}; !slices.Equal(ids, want) {
+		t.Errorf("charters = %v, want %v newest first", ids, want)
+	}
+	if got[0].Title != "active" || got[0].Status != "active" {
+		t.Errorf("first charter = %+v, want summary columns scanned", got[0])
+	}
+
+	page, err := repo.ListWithoutVoyages(context.Background(), Page{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != rows[0].ID {
+		t.Errorf("second page = %+v, want only the oldest charter", page)
+	}
+
+	query := fake.Calls("FROM shipman.charter_details")[0].Query
+	if !strings.Contains(query, "ORDER BY cd.created_at DESC") {
+		t.Errorf("query %q is not ordered by created_at DESC", query)
+	}
+	if _, err := repo.ListWithoutVoyages(context.Background(), Page{Offset: MaxListOffset + 1}); !errors.Is(err, ErrOffsetTooLarge) {
+		t.Errorf("deep offset: err = %v, want ErrOffsetTooLarge", err)
+	}
+}
+
+// BenchmarkCharterList compares the active list with the unfiltered one
+// against TEST_DATABASE_URL. With the partial index the active list is an
+// index scan that stops after the page; List sorts every visible charter.
+func BenchmarkCharterList(b *testing.B) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		b.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := Open(dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	prev := Pool
+	SetPool(conn)
+	b.Cleanup(func() {
+		SetPool(prev)
+		conn.Close()
+	})
+
+	repo := NewCharterDetailRepository()
+	ctx := context.Background()
+	b.Run("all", func(b *testing.B) {
+		for b.Loop() {
+			if _, _, err := repo.List(ctx, 20, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("active", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := repo.ListActive(ctx, Page{Limit: 20}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
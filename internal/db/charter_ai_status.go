@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// charterAITransitions lists the AI statuses each status may move to. A
+// failed run can be retried directly or reset to pending; an applied result
+// can be sent back to pending when a new document is extracted.
+var charterAITransitions = map[string][]string{
+	"pending":    {"processing"},
+	"processing": {"applied", "failed"},
+	"failed":     {"pending", "processing"},
+	"applied":    {"pending"},
+}
+
+// charterAIStatusDone reports whether status ends an extraction run.
+func charterAIStatusDone(status string) bool {
+	return status == "applied" || status == "failed"
+}
+
+// SetAIStatus moves a charter's AI extraction status along the allowed graph,
+// locking the row so concurrent workers are serialized. docPath, when given,
+// replaces ai_document_path; finishing a run (applied or failed) stamps
+// last_reviewed_at. It returns sql.ErrNoRows for an unknown charter,
+// ErrInvalidStatus for a status outside the graph and ErrInvalidTransition
+// when the move is not allowed from the current status.
+func (repo *CharterDetailRepository) SetAIStatus(ctx context.Context, id uuid.UUID, status string, docPath *string) error {
+	if _, ok := charterAITransitions[status]; !ok {
+		return ErrInvalidStatus
+	}
+
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var current string
+		const lockQuery = `SELECT COALESCE(ai_status, 'pending') FROM shipman.charter_details WHERE id = $1 FOR UPDATE`
+		if err := Conn(ctx).QueryRowContext(ctx, lockQuery, id).Scan(&current); err != nil {
+			return err
+		}
+		if !slices.Contains(charterAITransitions[current], status) {
+			return ErrInvalidTransition
+		}
+
+		const updateQuery = `
+			UPDATE shipman.charter_details
+			SET ai_status = $2,
+				ai_document_path = COALESCE($3, ai_document_path),
+				last_reviewed_at = CASE WHEN $4 THEN NOW() ELSE last_reviewed_at END,
+				updated_at = NOW()
+			WHERE id = $1
+		`
+		_, err := Conn(ctx).ExecContext(ctx, updateQuery, id, status, nullableString(docPath), charterAIStatusDone(status))
+		return err
+	})
+	charterCache.invalidate(id)
+	return err
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterRetrieveByVoyage(t *testing.T) {
+	charterID, voyageID, orphanID, danglingID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	// voyage id -> charter_detail_id; the orphan has none and the dangling
+	// one points at a charter deleted between the join and the read.
+	voyages := map[string]uuid.UUID{voyageID.String(): charterID, danglingID.String(): uuid.New()}
+	charters := map[string]map[string]any{charterID.String(): {
+		"id": charterID, "org_id": DefaultOrgID, "title": "Grain charter", "status": "active",
+		"ai_status": "pending", "laytime_reversible": false, "created_at": time.Now(), "updated_at": time.Now(),
+	}}
+
+	fake := newFakeDB(t)
+	fake.On("JOIN shipman.charter_details cd ON cd.id = v.charter_detail_id WHERE v.id = $1", func(call dbtest.Call) dbtest.Result {
+		id, ok := voyages[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows([]string{"id"})
+		}
+		return dbtest.Rows([]string{"id"}, []any{id})
+	})
+	fake.On("updated_at FROM shipman.charter_details WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		c, ok := charters[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows(charterColumns)
+		}
+		return dbtest.Rows(charterColumns, dbtest.Row(charterColumns, c))
+	})
+	repo := NewCharterDetailRepository()
+
+	got, err := repo.RetrieveByVoyage(context.Background(), voyageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != charterID || got.Title != "Grain charter" {
+		t.Errorf("charter = %s %q, want %s %q", got.ID, got.Title, charterID, "Grain charter")
+	}
+
+	for name, id := range map[string]uuid.UUID{"missing voyage": uuid.New(), "voyage without charter": orphanID, "charter deleted": danglingID} {
+		if _, err := repo.RetrieveByVoyage(context.Background(), id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// feedRow is a charter_details row or a deletions tombstone with the
+// transaction that wrote it.
+type feedRow struct {
+	id, org string
+	xid     uint64
+	title   string
+	deleted bool
+}
+
+// fakeChangeFeed keeps charters and tombstones the way Postgres would for the
+// change feed: each transaction gets the next xid, its writes only land on
+// commit, and Changes hides writes at or after the oldest running xid.
+type fakeChangeFeed struct {
+	nextXID uint64
+	running []uint64
+	txXID   map[*dbtest.Tx]uint64
+	rows    []feedRow
+}
+
+func newFakeChangeFeed() *fakeChangeFeed {
+	return &fakeChangeFeed{nextXID: 1, txXID: map[*dbtest.Tx]uint64{}}
+}
+
+// begin starts a transaction and returns its xid; end it with finish.
+func (f *fakeChangeFeed) begin() uint64 {
+	xid := f.nextXID
+	f.nextXID++
+	f.running = append(f.running, xid)
+	return xid
+}
+
+func (f *fakeChangeFeed) finish(xid uint64, writes ...feedRow) {
+	f.running = slices.DeleteFunc(f.running, func(x uint64) bool { return x == xid })
+	for _, w := range writes {
+		w.xid = xid
+		f.rows = slices.DeleteFunc(f.rows, func(r feedRow) bool { return r.id == w.id && r.deleted == w.deleted })
+		f.rows = append(f.rows, w)
+	}
+}
+
+// write commits a charter insert or update in a transaction of its own.
+func (f *fakeChangeFeed) write(org uuid.UUID, title string) uuid.UUID {
+	id := uuid.New()
+	f.finish(f.begin(), feedRow{id: id.String(), org: org.String(), title: title})
+	return id
+}
+
+func (f *fakeChangeFeed) xidOf(tx *dbtest.Tx) uint64 {
+	xid, ok := f.txXID[tx]
+	if !ok {
+		xid = f.begin()
+		f.txXID[tx] = xid
+	}
+	return xid
+}
+
+func (f *fakeChangeFeed) install(fake *dbtest.Fake) {
+	var pending []feedRow
+	fake.On("DELETE FROM shipman.charter_details WHERE id = $1 RETURNING org_id", func(call dbtest.Call) dbtest.Result {
+		i := slices.IndexFunc(f.rows, func(r feedRow) bool { return r.id == call.Arg(1) && !r.deleted })
+		if i < 0 {
+			return dbtest.Rows([]string{"org_id"})
+		}
+		xid, row := f.xidOf(call.Tx), f.rows[i]
+		call.Tx.OnEnd(func(committed bool) {
+			if committed {
+				f.rows = slices.DeleteFunc(f.rows, func(r feedRow) bool { return r == row })
+				f.finish(xid, pending...)
+			} else {
+				f.finish(xid)
+			}
+			pending = nil
+		})
+		return dbtest.Rows([]string{"org_id"}, []any{row.org})
+	})
+	fake.On("INSERT INTO shipman.deletions", func(call dbtest.Call) dbtest.Result {
+		f.xidOf(call.Tx)
+		pending = append(pending, feedRow{id: call.Arg(2).(string), org: call.Arg(3).(string), deleted: true})
+		return dbtest.Affected(1)
+	})
+	fake.On("SELECT deleted, entity_id, change_xid::text", func(call dbtest.Call) dbtest.Result {
+		after, _ := strconv.ParseUint(call.Arg(1).(string), 10, 64)
+		afterID, org := call.Arg(2).(string), orgArg(call)
+		if call.Arg(4) != AuditEntityCharter {
+			return dbtest.Fail(errors.New("unexpected entity type"))
+		}
+		xmin := f.nextXID
+		if len(f.running) > 0 {
+			xmin = slices.Min(f.running)
+		}
+		var visible []feedRow
+		for _, r := range f.rows {
+			if (org != "" && r.org != org) || r.xid >= xmin {
+				continue
+			}
+			if r.xid > after || (r.xid == after && r.id > afterID) {
+				visible = append(visible, r)
+			}
+		}
+		slices.SortFunc(visible, func(a, b feedRow) int {
+			if a.xid != b.xid {
+				return int(a.xid) - int(b.xid)
+			}
+			return strings.Compare(a.id, b.id)
+		})
+		visible = visible[:min(int(call.Arg(5).(int64)), len(visible))]
+		stamp := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+		out := make([][]any, len(visible))
+		for i, r := range visible {
+			xid := strconv.FormatUint(r.xid, 10)
+			if r.deleted {
+				out[i] = []any{true, r.id, xid, nil, nil, nil, nil, stamp}
+			} else {
+				out[i] = []any{false, r.id, xid, r.title, "draft", stamp, stamp, nil}
+			}
+		}
+		return dbtest.Rows([]string{"deleted", "entity_id", "change_xid", "title", "status", "created_at", "updated_at", "deleted_at"}, out...)
+	})
+}
+
+func TestCharterDeleteLeavesTombstone(t *testing.T) {
+	fake := newFakeDB(t)
+	feed := newFakeChangeFeed()
+	feed.install(fake)
+	id := feed.write(DefaultOrgID, "Grain charter")
+	repo := NewCharterDetailRepository()
+
+	if err := repo.Delete(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	inserts := fake.Calls("INSERT INTO shipman.deletions")
+	if len(inserts) != 1 {
+		t.Fatalf("tombstones = %d, want 1", len(inserts))
+	}
+	if got := inserts[0]; got.Arg(1) != AuditEntityCharter || got.Arg(2) != id.String() || got.Arg(3) != DefaultOrgID.String() {
+		t.Errorf("tombstone = %v, want charter %s in %s", got.Args, id, DefaultOrgID)
+	}
+	if deletes := fake.Calls("DELETE FROM shipman.charter_details"); deletes[0].Tx == nil || deletes[0].Tx != inserts[0].Tx {
+		t.Error("delete and tombstone ran in different transactions")
+	}
+	if fake.Commits() != 1 {
+		t.Errorf("commits = %d, want 1", fake.Commits())
+	}
+
+	// A second delete finds nothing and leaves no second tombstone.
+	if err := repo.Delete(context.Background(), id); err != nil {
+		t.Errorf("deleting a missing charter: %v", err)
+	}
+	if n := len(fake.Calls("INSERT INTO shipman.deletions")); n != 1 {
+		t.Errorf("tombstones = %d after deleting a missing charter, want 1", n)
+	}
+}
+
+func TestCharterDeleteRollsBackWithoutTombstone(t *testing.T) {
+	fake := newFakeDB(t)
+	feed := newFakeChangeFeed()
+	feed.install(fake)
+	id := feed.write(DefaultOrgID, "Grain charter")
+	fake.Return("INSERT INTO shipman.deletions", dbtest.Fail(errors.New("connection reset")))
+
+	if err := NewCharterDetailRepository().Delete(context.Background(), id); err == nil {
+		t.Fatal("delete succeeded without its tombstone")
+	}
+	if fake.Commits() != 0 || fake.Rollbacks() != 1 {
+		t.Errorf("commits %d, rollbacks %d; want the delete rolled back", fake.Commits(), fake.Rollbacks())
+	}
+	set, err := NewCharterDetailRepository().Changes(context.Background(), ChangeCursor{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Upserts) != 1 || set.Upserts[0].ID != id || len(set.Deletions) != 0 {
+		t.Errorf("changes = %+v, want the charter still there", set)
+	}
+}
+
+func TestCharterChangesSync(t *testing.T) {
+	fake := newFakeDB(t)
+	feed := newFakeChangeFeed()
+	feed.install(fake)
+	repo := NewCharterDetailRepository()
+	ctx := WithOrg(context.Background(), DefaultOrgID)
+
+	first := feed.write(DefaultOrgID, "First")
+	second := feed.write(DefaultOrgID, "Second")
+	feed.write(uuid.New(), "Other org")
+	third := feed.write(DefaultOrgID, "Third")
+
+	sync := func(cursor string, limit int) ChangeSet {
+		t.Helper()
+		var c ChangeCursor
+		if cursor != "" {
+			var err error
+			if c, err = DecodeChangeCursor(cursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		set, err := repo.Changes(ctx, c, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set
+	}
+	ids := func(set ChangeSet) []uuid.UUID {
+		out := make([]uuid.UUID, 0, len(set.Upserts))
+		for _, c := range set.Upserts {
+			out = append(out, c.ID)
+		}
+		return out
+	}
+
+	page := sync("", 2)
+	if got := ids(page); !slices.Equal(got, []uuid.UUID{first, second}) || !page.More {
+		t.Fatalf("first page = %v more %v, want the first two charters and more", got, page.More)
+	}
+	page = sync(page.Next, 2)
+	if got := ids(page); !slices.Equal(got, []uuid.UUID{third}) || page.More {
+		t.Fatalf("second page = %v more %v, want the third charter and no more", got, page.More)
+	}
+
+	// A delete after the client caught up arrives as a tombstone only.
+	if err := repo.Delete(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	caughtUp := page.Next
+	page = sync(caughtUp, 0)
+	if len(page.Upserts) != 0 || len(page.Deletions) != 1 || page.Deletions[0].EntityID != second {
+		t.Fatalf("after delete = %+v, want a tombstone for %s", page, second)
+	}
+	if page.Deletions[0].EntityType != AuditEntityCharter {
+		t.Errorf("tombstone entity = %q, want %q", page.Deletions[0].EntityType, AuditEntityCharter)
+	}
+
+	// Nothing new: the cursor stays put.
+	idle := sync(page.Next, 0)
+	if len(idle.Upserts)+len(idle.Deletions) != 0 || idle.Next != page.Next {
+		t.Errorf("idle sync = %+v, want no changes and the same cursor", idle)
+	}
+
+	// A write committed after a slower, still-open transaction began is held
+	// back until that one commits, so neither is skipped.
+	slow := feed.begin()
+	fast := feed.write(DefaultOrgID, "Fast")
+	if held := sync(page.Next, 0); len(held.Upserts) != 0 {
+		t.Errorf("saw %v while an older transaction was running, want nothing", ids(held))
+	}
+	slowID := uuid.New()
+	feed.finish(slow, feedRow{id: slowID.String(), org: DefaultOrgID.String(), title: "Slow"})
+	if got := ids(sync(page.Next, 0)); !slices.Equal(got, []uuid.UUID{slowID, fast}) {
+		t.Errorf("after the slow commit = %v, want %v", got, []uuid.UUID{slowID, fast})
+	}
+}
+
+func TestCharterChangesLimit(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int64
+	}{
+		{0, DefaultPageSize + 1},
+		{10, 11},
+		{MaxChangesPage * 10, MaxChangesPage + 1},
+	}
+	for _, tt := range tests {
+		fake := newFakeDB(t)
+		fake.Return("SELECT deleted, entity_id, change_xid::text", dbtest.Rows([]string{"deleted"}))
+		set, err := NewCharterDetailRepository().Changes(context.Background(), ChangeCursor{}, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fake.Calls("SELECT deleted")[0].Arg(5); got != tt.want {
+			t.Errorf("limit %d fetched %v rows, want %d", tt.limit, got, tt.want)
+		}
+		if set.Upserts == nil || set.Deletions == nil || set.Next != (ChangeCursor{}).Encode() {
+			t.Errorf("empty set = %+v, want empty lists and the starting cursor", set)
+		}
+	}
+}
+
+func TestChangeCursorEncoding(t *testing.T) {
+	c := ChangeCursor{XID: 1<<40 + 7, ID: uuid.New()}
+	got, err := DecodeChangeCursor(c.Encode())
+	if err != nil || got != c {
+		t.Errorf("round trip = %+v, %v; want %+v", got, err, c)
+	}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	for _, token := range []string{
+		"not base64!",
+		encode("no separator"),
+		encode("x|" + uuid.NewString()),
+		encode("-1|" + uuid.NewString()),
+		encode("1|not-a-uuid"),
+	} {
+		if _, err := DecodeChangeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeChangeCursor(%q) = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
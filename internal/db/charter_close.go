@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CloseResult summarizes a charter close. OpenDisputes block the close
+// unless forced; OutstandingPayments never block and are reported so they
+// can be chased.
+type CloseResult struct {
+	CharterID           uuid.UUID         `json:"charter_id"`
+	Closed              bool              `json:"closed"`
+	ArchivedVoyages     int64             `json:"archived_voyages"`
+	OpenDisputes        []ActiveDependent `json:"open_disputes"`
+	OutstandingPayments []ActiveDependent `json:"outstanding_payments"`
+}
+
+// Close marks a charter closed and archives its completed voyages in one
+// transaction, with the charter row locked. While any dispute on the charter
+// is unresolved the close is refused with ErrHasOpenDisputes and nothing is
+// changed, unless force is set. Payments still draft, pending or disputed are
+// listed in the result either way. It returns sql.ErrNoRows for an unknown
+// charter.
+func (repo *CharterDetailRepository) Close(ctx context.Context, charterID uuid.UUID, force bool) (CloseResult, error) {
+	res := CloseResult{
+		CharterID:           charterID,
+		OpenDisputes:        []ActiveDependent{},
+		OutstandingPayments: []ActiveDependent{},
+	}
+
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var locked bool
+		const lockQuery = `SELECT true FROM shipman.charter_details WHERE id = $1 FOR UPDATE`
+		if err := Conn(ctx).QueryRowContext(ctx, lockQuery, charterID).Scan(&locked); err != nil {
+			return err
+		}
+
+		const disputesQuery = `
+			SELECT 'dispute', id, status FROM shipman.disputes
+			WHERE charter_detail_id = $1
+			  AND status NOT IN ('resolved', 'closed')
+			ORDER BY created_at, id
+		`
+		disputes, err := scanDependents(ctx, disputesQuery, charterID)
+		if err != nil {
+			return err
+		}
+		res.OpenDisputes = disputes
+
+		const paymentsQuery = `
+			SELECT 'payment', p.id, p.status
+			FROM shipman.voyage_payments p
+			JOIN shipman.voyages v ON v.id = p.voyage_id
+			WHERE v.charter_detail_id = $1
+			  AND p.status IN ('draft', 'pending', 'disputed')
+			ORDER BY p.created_at, p.id
+		`
+		payments, err := scanDependents(ctx, paymentsQuery, charterID)
+		if err != nil {
+			return err
+		}
+		res.OutstandingPayments = payments
+
+		if len(disputes) > 0 && !force {
+			return ErrHasOpenDisputes
+		}
+
+		const updateQuery = `UPDATE shipman.charter_details SET status = 'closed', updated_at = NOW() WHERE id = $1`
+		if _, err := Conn(ctx).ExecContext(ctx, updateQuery, charterID); err != nil {
+			return err
+		}
+		res.ArchivedVoyages, err = NewVoyageRepository().ArchiveCompletedByCharter(ctx, charterID)
+		return err
+	})
+	charterCache.invalidate(charterID)
+	if err != nil {
+		res.ArchivedVoyages = 0
+		return res, err
+	}
+	res.Closed = true
+	return res, nil
+}
+
+// scanDependents runs a query selecting (kind, id, status) rows.
+func scanDependents(ctx context.Context, query string, args ...any) ([]ActiveDependent, error) {
+	rows, err := Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ActiveDependent{}
+	for rows.Next() {
+		var d ActiveDependent
+		if err := rows.Scan(&d.Type, &d.ID, &d.Status); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
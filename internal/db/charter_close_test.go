@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterClose(t *testing.T) {
+	dependent := []string{"kind", "id", "status"}
+	openDispute := dbtest.Rows(dependent, []any{"dispute", uuid.New(), "open"})
+	pendingPayment := dbtest.Rows(dependent, []any{"payment", uuid.New(), "pending"})
+	none := dbtest.Rows(dependent)
+
+	tests := []struct {
+		name         string
+		exists       bool
+		force        bool
+		disputes     dbtest.Result
+		payments     dbtest.Result
+		wantErr      error
+		wantClosed   bool
+		wantArchived int64
+		wantPayments int
+	}{
+		{"clean close", true, false, none, none, nil, true, 2, 0},
+		{"outstanding payments do not block", true, false, none, pendingPayment, nil, true, 2, 1},
+		{"open dispute blocks", true, false, openDispute, pendingPayment, ErrHasOpenDisputes, false, 0, 1},
+		{"forced past open dispute", true, true, openDispute, none, nil, true, 2, 0},
+		{"unknown charter", false, false, none, none, sql.ErrNoRows, false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			lock := dbtest.Rows([]string{"locked"})
+			if tt.exists {
+				lock = dbtest.Rows([]string{"locked"}, []any{true})
+			}
+			fake.Return("SELECT true FROM shipman.charter_details WHERE id = $1 FOR UPDATE", lock)
+			fake.Return("SELECT 'dispute', id, status FROM shipman.disputes", tt.disputes)
+			fake.Return("SELECT 'payment', p.id, p.status", tt.payments)
+			fake.Return("UPDATE shipman.charter_details SET status = 'closed'", dbtest.Affected(1))
+			fake.Return("SELECT status FROM shipman.charter_details WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"status"}, []any{"closed"}))
+			fake.Return("SET archived_at = NOW(), updated_at = NOW() WHERE charter_detail_id = $1", dbtest.Affected(2))
+
+			res, err := NewCharterDetailRepository().Close(context.Background(), uuid.New(), tt.force)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if res.Closed != tt.wantClosed {
+				t.Errorf("closed = %v, want %v", res.Closed, tt.wantClosed)
+			}
+			if res.ArchivedVoyages != tt.wantArchived {
+				t.Errorf("archived = %d, want %d", res.ArchivedVoyages, tt.wantArchived)
+			}
+			if len(res.OutstandingPayments) != tt.wantPayments {
+				t.Errorf("outstanding payments = %v, want %d", res.OutstandingPayments, tt.wantPayments)
+			}
+			updated := len(fake.Calls("UPDATE shipman.charter_details SET status = 'closed'")) == 1
+			if updated != tt.wantClosed {
+				t.Errorf("status updated = %v, want %v", updated, tt.wantClosed)
+			}
+			if tt.wantClosed {
+				if fake.Commits() != 1 {
+					t.Errorf("commits = %d, want 1", fake.Commits())
+				}
+			} else if fake.Rollbacks() != 1 {
+				t.Errorf("rollbacks = %d, want 1", fake.Rollbacks())
+			}
+		})
+	}
+}
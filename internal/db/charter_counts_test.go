@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// seededVoyage is a voyage of a seeded charter: departed and arrived say
+// whether the actual departure and arrival times are set.
+type seededVoyage struct {
+	charter           int
+	departed, arrived bool
+}
+
+// voyageCountsSeed is three charters, newest first: one with a voyage in each
+// state, one with a single sailing voyage and one with none.
+var voyageCountsSeed = []seededVoyage{
+	{0, false, false},
+	{0, true, false},
+	{0, true, true},
+	{0, true, false},
+	{1, true, false},
+}
+
+var wantVoyageCounts = []struct{ total, inProgress int }{{4, 2}, {1, 1}, {0, 0}}
+
+func TestCharterListWithVoyageCounts(t *testing.T) {
+	fake := newFakeDB(t)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	// The fake aggregates the seed the way the LEFT JOIN ... GROUP BY does:
+	// every voyage counts, and departed-but-not-arrived ones are in progress.
+	fake.On("LEFT JOIN shipman.voyages v ON v.charter_detail_id = cd.id", func(call dbtest.Call) dbtest.Result {
+		var rows [][]any
+		for i, id := range ids {
+			var total, inProgress int64
+			for _, v := range voyageCountsSeed {
+				if v.charter != i {
+					continue
+				}
+				total++
+				if v.departed && !v.arrived {
+					inProgress++
+				}
+			}
+			at := base.Add(-time.Duration(i) * time.Hour)
+			rows = append(rows, []any{id, "Charter", "active", total, inProgress, at, at})
+		}
+		return dbtest.Rows([]string{"id", "title", "status", "voyage_count", "in_progress", "created_at", "updated_at"}, rows...)
+	})
+
+	got, err := NewCharterDetailRepository().ListWithVoyageCounts(context.Background(), Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(ids) {
+		t.Fatalf("charters = %d, want %d", len(got), len(ids))
+	}
+	for i, c := range got {
+		if c.ID != ids[i] || c.VoyageCount != wantVoyageCounts[i].total || c.InProgressVoyageCount != wantVoyageCounts[i].inProgress {
+			t.Errorf("charter %d = %+v, want %d voyages, %d in progress", i, c, wantVoyageCounts[i].total, wantVoyageCounts[i].inProgress)
+		}
+	}
+
+	query := fake.Calls("LEFT JOIN shipman.voyages")[0].Query
+	for _, want := range []string{
+		"COUNT(v.id) FILTER (WHERE v.actual_departure_at IS NOT NULL AND v.actual_arrival_at IS NULL)",
+		"GROUP BY cd.id",
+		"ORDER BY cd.created_at DESC, cd.id DESC",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query is missing %q", want)
+		}
+	}
+}
+
+// TestCharterListWithVoyageCountsPostgres seeds voyageCountsSeed into
+// TEST_DATABASE_URL and checks the counts the database computes.
+func TestCharterListWithVoyageCountsPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := Pool
+	SetPool(conn)
+	ctx := context.Background()
+
+	// Seed far in the future so the three charters are the first page.
+	future := time.Now().AddDate(100, 0, 0)
+	ids := make([]uuid.UUID, len(wantVoyageCounts))
+	for i := range ids {
+		if err := conn.QueryRowContext(ctx,
+			`INSERT INTO shipman.charter_details (title, created_at) VALUES ('voyage counts test', $1) RETURNING id`,
+			future.Add(-time.Duration(i)*time.Hour),
+		).Scan(&ids[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		idStrings := make([]string, len(ids))
+		for i, id := range ids {
+			idStrings[i] = id.String()
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM shipman.charter_details WHERE id = ANY($1::uuid[])`, idStrings); err != nil {
+			t.Error(err)
+		}
+		SetPool(prev)
+		conn.Close()
+	})
+	for _, v := range voyageCountsSeed {
+		var departed, arrived any
+		if v.departed {
+			departed = time.Now()
+		}
+		if v.arrived {
+			arrived = time.Now()
+		}
+		if _, err := conn.ExecContext(ctx,
+			`INSERT INTO shipman.voyages (charter_detail_id, actual_departure_at, actual_arrival_at) VALUES ($1, $2, $3)`,
+			ids[v.charter], departed, arrived,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := NewCharterDetailRepository().ListWithVoyageCounts(ctx, Page{Limit: len(ids)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(ids) {
+		t.Fatalf("charters = %d, want %d", len(got), len(ids))
+	}
+	for i, c := range got {
+		if c.ID != ids[i] || c.VoyageCount != wantVoyageCounts[i].total || c.InProgressVoyageCount != wantVoyageCounts[i].inProgress {
+			t.Errorf("charter %d = %+v, want %d voyages, %d in progress", i, c, wantVoyageCounts[i].total, wantVoyageCounts[i].inProgress)
+		}
+	}
+}
@@ -3,43 +3,79 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"shipman/internal/metrics"
+
 	"github.com/google/uuid"
 )
 
 // CharterDetail mirrors a row in shipman.charter_details.
 type CharterDetail struct {
-	ID                    uuid.UUID  `json:"id"`
-	CreatedByUserID       *uuid.UUID `json:"created_by_user_id,omitempty"`
-	Title                 string     `json:"title"`
-	CharterReferenceCode  *string    `json:"charter_reference_code,omitempty"`
-	VesselName            *string    `json:"vessel_name,omitempty"`
-	CounterpartyName      *string    `json:"counterparty_name,omitempty"`
-	Status                string     `json:"status"`
-	StartDate             *time.Time `json:"start_date,omitempty"`
-	EndDate               *time.Time `json:"end_date,omitempty"`
-	LaytimeAllowanceHours *float64   `json:"laytime_allowance_hours,omitempty"`
-	DemurrageRate         *float64   `json:"demurrage_rate,omitempty"`
-	DemurrageCurrency     *string    `json:"demurrage_currency,omitempty"`
-	FuelClause            *string    `json:"fuel_clause,omitempty"`
-	PaymentTerms          *string    `json:"payment_terms,omitempty"`
-	AIStatus              string     `json:"ai_status"`
-	AIDocumentPath        *string    `json:"ai_document_path,omitempty"`
-	AIExtractedTerms      []byte     `json:"ai_extracted_terms,omitempty"`
-	LastReviewedAt        *time.Time `json:"last_reviewed_at,omitempty"`
-	Notes                 *string    `json:"notes,omitempty"`
-	CreatedAt             time.Time  `json:"created_at"`
-	UpdatedAt             time.Time  `json:"updated_at"`
+	ID                    uuid.UUID       `json:"id"`
+	OrgID                 uuid.UUID       `json:"org_id"`
+	CreatedByUserID       *uuid.UUID      `json:"created_by_user_id,omitempty"`
+	Title                 string          `json:"title"`
+	CharterReferenceCode  *string         `json:"charter_reference_code,omitempty"`
+	VesselName            *string         `json:"vessel_name,omitempty"`
+	CounterpartyName      *string         `json:"counterparty_name,omitempty"`
+	Status                string          `json:"status"`
+	StartDate             *time.Time      `json:"start_date,omitempty"`
+	EndDate               *time.Time      `json:"end_date,omitempty"`
+	LaytimeAllowanceHours *float64        `json:"laytime_allowance_hours,omitempty"`
+	DemurrageRate         *float64        `json:"demurrage_rate,omitempty"`
+	DemurrageCurrency     *string         `json:"demurrage_currency,omitempty"`
+	DespatchRate          *float64        `json:"despatch_rate,omitempty"`    // per day; nil means half the demurrage rate
+	DefaultCurrency       *string         `json:"default_currency,omitempty"` // inherited by payments and demurrage created without a currency
+	LaytimeReversible     bool            `json:"laytime_reversible"`
+	FuelClause            *string         `json:"fuel_clause,omitempty"`
+	PaymentTerms          *string         `json:"payment_terms,omitempty"`
+	AIStatus              string          `json:"ai_status"`
+	AIDocumentPath        *string         `json:"ai_document_path,omitempty"`
+	AIExtractedTerms      json.RawMessage `json:"ai_extracted_terms,omitempty"`
+	LastReviewedAt        *time.Time      `json:"last_reviewed_at,omitempty"`
+	ArchivedAt            *time.Time      `json:"archived_at,omitempty"`
+	Notes                 *string         `json:"notes,omitempty"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+// CharterWithCounts is a charter list row carrying voyage aggregates.
+// InProgressVoyageCount counts voyages that have departed but not arrived.
+type CharterWithCounts struct {
+	ID                    uuid.UUID `json:"id"`
+	Title                 string    `json:"title"`
+	Status                string    `json:"status"`
+	VoyageCount           int       `json:"voyage_count"`
+	InProgressVoyageCount int       `json:"in_progress_voyage_count"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 // CharterDetailService defines CRUD behaviour.
 type CharterDetailService interface {
 	Create(ctx context.Context, detail *CharterDetail) error
 	Retrieve(ctx context.Context, id uuid.UUID) (CharterDetail, error)
-	List(ctx context.Context, limit, offset int) ([]CharterDetail, error)
+	RetrieveByVoyage(ctx context.Context, voyageID uuid.UUID) (CharterDetail, error)
+	List(ctx context.Context, limit, offset int) ([]CharterDetail, int, error)
+	ListByVesselName(ctx context.Context, vesselName string, page Page) ([]CharterDetail, error)
+	Search(ctx context.Context, q string, limit, offset int) ([]CharterDetail, error)
+	ListFiltered(ctx context.Context, filter CharterListFilter, limit, offset int) ([]CharterDetail, error)
+	ListActive(ctx context.Context, page Page) ([]CharterDetail, error)
+	ListWithVoyageCounts(ctx context.Context, page Page) ([]CharterWithCounts, error)
+	ListWithoutVoyages(ctx context.Context, page Page) ([]CharterDetail, error)
 	Update(ctx context.Context, detail *CharterDetail) error
+	SetAIStatus(ctx context.Context, id uuid.UUID, status string, docPath *string) error
+	SetStatus(ctx context.Context, id uuid.UUID, status string, force bool) ([]ActiveDependent, error)
+	Close(ctx context.Context, charterID uuid.UUID, force bool) (CloseResult, error)
+	CreateCharterWithVoyage(ctx context.Context, charter *CharterDetail, voyage *Voyage) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	Changes(ctx context.Context, cursor ChangeCursor, limit int) (ChangeSet, error)
 }
 
 // CharterDetailRepository implements CharterDetailService using the package Pool.
@@ -52,6 +88,17 @@ func NewCharterDetailRepository() *CharterDetailRepository {
 
 // Create inserts a charter detail row.
 func (repo *CharterDetailRepository) Create(ctx context.Context, detail *CharterDetail) error {
+	clearServerFields(&detail.ID, &detail.CreatedAt, &detail.UpdatedAt)
+	detail.Normalize()
+	if err := sanitizeNotes(notesField{"notes", detail.Notes}); err != nil {
+		return err
+	}
+	if err := checkCharterDates(*detail); err != nil {
+		return err
+	}
+	if err := checkJSON("ai_extracted_terms", detail.AIExtractedTerms); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.charter_details (
 			created_by_user_id,
@@ -71,13 +118,17 @@ func (repo *CharterDetailRepository) Create(ctx context.Context, detail *Charter
 			ai_document_path,
 			ai_extracted_terms,
 			last_reviewed_at,
-			notes
+			notes,
+			laytime_reversible,
+			default_currency,
+			despatch_rate,
+			org_id
 		) VALUES (
 			$1, $2, $3, $4, $5,
 			COALESCE($6, 'draft'),
 			$7, $8, $9, $10, $11,
 			$12, $13, COALESCE($14, 'pending'),
-			$15, $16, $17, $18
+			$15, $16, $17, $18, $19, $20, $21, $22
 		)
 		RETURNING id, status, ai_status, created_at, updated_at
 	`
@@ -90,8 +141,9 @@ func (repo *CharterDetailRepository) Create(ctx context.Context, detail *Charter
 	if aiStatus == "" {
 		aiStatus = "pending"
 	}
+	detail.OrgID = orgForCreate(ctx, detail.OrgID)
 
-	return Pool.QueryRowContext(
+	err := Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		nullableUUID(detail.CreatedByUserID),
@@ -109,17 +161,56 @@ func (repo *CharterDetailRepository) Create(ctx context.Context, detail *Charter
 		nullableString(detail.PaymentTerms),
 		aiStatus,
 		nullableString(detail.AIDocumentPath),
-		nullableBytes(detail.AIExtractedTerms),
+		nullableJSON(detail.AIExtractedTerms),
 		nullableTime(detail.LastReviewedAt),
 		nullableString(detail.Notes),
+		detail.LaytimeReversible,
+		nullableString(detail.DefaultCurrency),
+		nullableFloat(detail.DespatchRate),
+		detail.OrgID,
 	).Scan(&detail.ID, &detail.Status, &detail.AIStatus, &detail.CreatedAt, &detail.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	AfterCommit(ctx, metrics.CharterCreated)
+	return nil
 }
 
-// Retrieve fetches a single charter detail.
+// CreateCharterWithVoyage inserts a charter and its first voyage in one
+// transaction. The voyage is attached to the new charter and inherits its org;
+// if either insert fails nothing is written and both IDs are left zero.
+func (repo *CharterDetailRepository) CreateCharterWithVoyage(ctx context.Context, charter *CharterDetail, voyage *Voyage) error {
+	err := WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, charter); err != nil {
+			return err
+		}
+		voyage.CharterDetailID = &charter.ID
+		voyage.OrgID = charter.OrgID
+		return NewVoyageRepository().Create(ctx, voyage)
+	})
+	if err != nil {
+		charter.ID = uuid.Nil
+		voyage.ID = uuid.Nil
+		voyage.CharterDetailID = nil
+		return err
+	}
+	return nil
+}
+
+// Retrieve fetches a single charter detail. A charter outside the org ctx is
+// scoped to is reported as sql.ErrNoRows.
 func (repo *CharterDetailRepository) Retrieve(ctx context.Context, id uuid.UUID) (CharterDetail, error) {
+	if cached, ok := charterCache.get(id); ok {
+		if !inOrg(ctx, cached.OrgID) {
+			return CharterDetail{}, sql.ErrNoRows
+		}
+		return cached, nil
+	}
+
 	const query = `
 		SELECT
 			id,
+			org_id,
 			created_by_user_id,
 			title,
 			charter_reference_code,
@@ -138,6 +229,10 @@ func (repo *CharterDetailRepository) Retrieve(ctx context.Context, id uuid.UUID)
 			ai_extracted_terms,
 			last_reviewed_at,
 			notes,
+			laytime_reversible,
+			default_currency,
+			despatch_rate,
+			archived_at,
 			created_at,
 			updated_at
 		FROM shipman.charter_details
@@ -163,10 +258,14 @@ func (repo *CharterDetailRepository) Retrieve(ctx context.Context, id uuid.UUID)
 		aiTerms    []byte
 		lastRev    sql.NullTime
 		notes      sql.NullString
+		defCurr    sql.NullString
+		despRate   sql.NullFloat64
+		archived   sql.NullTime
 	)
 
 	err := Pool.QueryRowContext(ctx, query, id).Scan(
 		&detail.ID,
+		&detail.OrgID,
 		&rawUserID,
 		&detail.Title,
 		&rawRef,
@@ -185,6 +284,10 @@ func (repo *CharterDetailRepository) Retrieve(ctx context.Context, id uuid.UUID)
 		&aiTerms,
 		&lastRev,
 		&notes,
+		&detail.LaytimeReversible,
+		&defCurr,
+		&despRate,
+		&archived,
 		&detail.CreatedAt,
 		&detail.UpdatedAt,
 	)
@@ -208,27 +311,372 @@ func (repo *CharterDetailRepository) Retrieve(ctx context.Context, id uuid.UUID)
 	detail.LaytimeAllowanceHours = floatPtr(laytime)
 	detail.DemurrageRate = floatPtr(demRate)
 	detail.DemurrageCurrency = stringPtr(demCurr)
+	detail.DefaultCurrency = stringPtr(defCurr)
+	detail.DespatchRate = floatPtr(despRate)
+	detail.ArchivedAt = timePtr(archived)
 	detail.FuelClause = stringPtr(fuel)
 	detail.PaymentTerms = stringPtr(payment)
 	detail.AIStatus = defaultString(aiStatus, "pending")
 	detail.AIDocumentPath = stringPtr(aiDoc)
-	detail.AIExtractedTerms = bytesOrNil(aiTerms)
+	detail.AIExtractedTerms = jsonOrNil(aiTerms)
 	detail.LastReviewedAt = timePtr(lastRev)
 	detail.Notes = stringPtr(notes)
 
+	charterCache.set(id, detail)
+	if !inOrg(ctx, detail.OrgID) {
+		return CharterDetail{}, sql.ErrNoRows
+	}
+	return detail, nil
+}
+
+// RetrieveByVoyage fetches the charter a voyage belongs to. It returns
+// ErrNotFound when the voyage does not exist or has no charter.
+func (repo *CharterDetailRepository) RetrieveByVoyage(ctx context.Context, voyageID uuid.UUID) (CharterDetail, error) {
+	const query = `
+		SELECT cd.id
+		FROM shipman.voyages v
+		JOIN shipman.charter_details cd ON cd.id = v.charter_detail_id
+		WHERE v.id = $1
+	`
+
+	var charterID uuid.UUID
+	if err := Pool.QueryRowContext(ctx, query, voyageID).Scan(&charterID); err != nil {
+		return CharterDetail{}, notFound(err)
+	}
+	detail, err := repo.Retrieve(ctx, charterID)
+	if err != nil {
+		return CharterDetail{}, notFound(err)
+	}
 	return detail, nil
 }
 
-// List returns charter details ordered by most recent.
-func (repo *CharterDetailRepository) List(ctx context.Context, limit, offset int) ([]CharterDetail, error) {
+// DistinctCounterparties returns counterparty names used on charters that
+// start with prefix, case-insensitively, in alphabetical order. Names
+// differing only in case or surrounding whitespace are returned once.
+func (repo *CharterDetailRepository) DistinctCounterparties(ctx context.Context, prefix string, limit int) ([]string, error) {
+	const query = `
+		SELECT name FROM (
+			SELECT DISTINCT ON (lower(name)) name
+			FROM (
+				SELECT trim(counterparty_name) AS name
+				FROM shipman.charter_details
+				WHERE counterparty_name IS NOT NULL
+				  AND ($3::uuid IS NULL OR org_id = $3)
+			) names
+			WHERE name <> ''
+			  AND name ILIKE $1 ESCAPE '\'
+			ORDER BY lower(name), name
+		) deduped
+		ORDER BY lower(name)
+		LIMIT $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, escapeLike(prefix)+"%", limit, orgFilter(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// List returns one page of charter details ordered by most recent, along
+// with how many charters there are in total regardless of limit and offset.
+// The total comes from the same query; only a page past the end, which has no
+// rows to carry it, costs a second COUNT.
+func (repo *CharterDetailRepository) List(ctx context.Context, limit, offset int) ([]CharterDetail, int, error) {
+	if err := checkOffset(offset); err != nil {
+		return nil, 0, err
+	}
+
+	const query = `
+		SELECT id, title, status, created_at, updated_at, COUNT(*) OVER () AS total
+		FROM shipman.charter_details
+		WHERE ($3::uuid IS NULL OR org_id = $3)
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, limit, offset, orgFilter(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		out   []CharterDetail
+		total int
+	)
+	for rows.Next() {
+		var detail CharterDetail
+		if err := rows.Scan(&detail.ID, &detail.Title, &detail.Status, &detail.CreatedAt, &detail.UpdatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, detail)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if len(out) == 0 && offset > 0 {
+		const countQuery = `
+			SELECT COUNT(*) FROM shipman.charter_details
+			WHERE ($1::uuid IS NULL OR org_id = $1)
+		`
+		if err := Pool.QueryRowContext(ctx, countQuery, orgFilter(ctx)).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+	return out, total, nil
+}
+
+// Each calls fn for every charter, newest first, with the same columns as
+// List. Rows are read from a single query rather than page by page, so it is
+// not subject to MaxListOffset; iteration stops at the first error fn returns.
+func (repo *CharterDetailRepository) Each(ctx context.Context, fn func(CharterDetail) error) error {
 	const query = `
 		SELECT id, title, status, created_at, updated_at
 		FROM shipman.charter_details
+		WHERE ($1::uuid IS NULL OR org_id = $1)
 		ORDER BY created_at DESC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, orgFilter(ctx))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var detail CharterDetail
+		if err := rows.Scan(&detail.ID, &detail.Title, &detail.Status, &detail.CreatedAt, &detail.UpdatedAt); err != nil {
+			return err
+		}
+		if err := fn(detail); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListByVesselName returns charters naming the vessel directly or through one
+// of their voyages, newest first. Names are compared case-insensitively.
+func (repo *CharterDetailRepository) ListByVesselName(ctx context.Context, vesselName string, page Page) ([]CharterDetail, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT cd.id, cd.title, cd.vessel_name, cd.status, cd.created_at, cd.updated_at
+		FROM shipman.charter_details cd
+		WHERE (lower(trim(cd.vessel_name)) = lower(trim($1))
+		   OR EXISTS (
+				SELECT 1 FROM shipman.voyages v
+				WHERE v.charter_detail_id = cd.id
+				  AND lower(trim(v.vessel_name)) = lower(trim($1))
+		   ))
+		  AND ($4::uuid IS NULL OR cd.org_id = $4)
+		ORDER BY cd.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, vesselName, page.Limit, page.Offset, orgFilter(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterDetail
+	for rows.Next() {
+		var (
+			detail CharterDetail
+			vessel sql.NullString
+		)
+		if err := rows.Scan(&detail.ID, &detail.Title, &vessel, &detail.Status, &detail.CreatedAt, &detail.UpdatedAt); err != nil {
+			return nil, err
+		}
+		detail.VesselName = stringPtr(vessel)
+		out = append(out, detail)
+	}
+	return out, rows.Err()
+}
+
+// CharterListFilter narrows ListFiltered results. Nil fields are ignored.
+// StartAfter is inclusive and StartBefore exclusive, so a quarter is
+// StartAfter = its first day, StartBefore = the next quarter's first day.
+// Charters with no start date never match a date bound.
+type CharterListFilter struct {
+	Status      *string
+	StartAfter  *time.Time
+	StartBefore *time.Time
+}
+
+// ListFiltered returns charters matching filter, newest first. With no
+// fields set it behaves like List.
+func (repo *CharterDetailRepository) ListFiltered(ctx context.Context, filter CharterListFilter, limit, offset int) ([]CharterDetail, error) {
+	if filter == (CharterListFilter{}) {
+		charters, _, err := repo.List(ctx, limit, offset)
+		return charters, err
+	}
+	if err := checkOffset(offset); err != nil {
+		return nil, err
+	}
+
+	var where conditions
+	where.add("($%[1]d::uuid IS NULL OR org_id = $%[1]d)", orgFilter(ctx))
+	if filter.Status != nil {
+		where.add("status = $%d", *filter.Status)
+	}
+	if filter.StartAfter != nil {
+		where.add("start_date >= $%d", *filter.StartAfter)
+	}
+	if filter.StartBefore != nil {
+		where.add("start_date < $%d", *filter.StartBefore)
+	}
+	where.page(orderBy(desc("created_at"), desc("id")), limit, offset)
+
+	query := `
+		SELECT id, title, status, start_date, created_at, updated_at
+		FROM shipman.charter_details
+		WHERE TRUE
+	` + where.sql
+
+	rows, err := Pool.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterDetail
+	for rows.Next() {
+		var (
+			detail CharterDetail
+			start  sql.NullTime
+		)
+		if err := rows.Scan(&detail.ID, &detail.Title, &detail.Status, &start, &detail.CreatedAt, &detail.UpdatedAt); err != nil {
+			return nil, err
+		}
+		detail.StartDate = timePtr(start)
+		out = append(out, detail)
+	}
+	return out, rows.Err()
+}
+
+// Search returns charters whose title, vessel name or counterparty contains
+// q, case-insensitively. Title matches rank first (prefix before anywhere in
+// the title), then newest first. A blank q behaves like List.
+func (repo *CharterDetailRepository) Search(ctx context.Context, q string, limit, offset int) ([]CharterDetail, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		charters, _, err := repo.List(ctx, limit, offset)
+		return charters, err
+	}
+	if err := checkOffset(offset); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, title, vessel_name, counterparty_name, status, created_at, updated_at
+		FROM shipman.charter_details
+		WHERE (title ILIKE $1 ESCAPE '\'
+		    OR vessel_name ILIKE $1 ESCAPE '\'
+		    OR counterparty_name ILIKE $1 ESCAPE '\')
+		  AND ($5::uuid IS NULL OR org_id = $5)
+		ORDER BY ` + orderBy(
+		asc(`CASE WHEN title ILIKE $2 ESCAPE '\' THEN 0 WHEN title ILIKE $1 ESCAPE '\' THEN 1 ELSE 2 END`),
+		desc("created_at"),
+		desc("id"),
+	) + `
+		LIMIT $3 OFFSET $4
+	`
+
+	escaped := escapeLike(q)
+	rows, err := Pool.QueryContext(ctx, query, "%"+escaped+"%", escaped+"%", limit, offset, orgFilter(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterDetail
+	for rows.Next() {
+		var (
+			detail       CharterDetail
+			vessel       sql.NullString
+			counterparty sql.NullString
+		)
+		if err := rows.Scan(&detail.ID, &detail.Title, &vessel, &counterparty, &detail.Status, &detail.CreatedAt, &detail.UpdatedAt); err != nil {
+			return nil, err
+		}
+		detail.VesselName = stringPtr(vessel)
+		detail.CounterpartyName = stringPtr(counterparty)
+		out = append(out, detail)
+	}
+	return out, rows.Err()
+}
+
+// ListActive returns active charters, newest first. The filter and ordering
+// match the partial index idx_charter_details_active_created_at, so Postgres
+// walks the index and stops after the page rather than sorting every charter
+// the way List does.
+func (repo *CharterDetailRepository) ListActive(ctx context.Context, page Page) ([]CharterDetail, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT id, title, status, created_at, updated_at
+		FROM shipman.charter_details
+		WHERE status = 'active'
+		  AND ($3::uuid IS NULL OR org_id = $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, page.limit(), page.Offset, orgFilter(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterDetail
+	for rows.Next() {
+		var detail CharterDetail
+		if err := rows.Scan(&detail.ID, &detail.Title, &detail.Status, &detail.CreatedAt, &detail.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, detail)
+	}
+	return out, rows.Err()
+}
+
+// ListWithoutVoyages returns charters that have never had a voyage, archived
+// ones included, newest first. Like ListActive it fills only the summary
+// columns.
+func (repo *CharterDetailRepository) ListWithoutVoyages(ctx context.Context, page Page) ([]CharterDetail, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT cd.id, cd.title, cd.status, cd.created_at, cd.updated_at
+		FROM shipman.charter_details cd
+		WHERE NOT EXISTS (
+			SELECT 1 FROM shipman.voyages v WHERE v.charter_detail_id = cd.id
+		)
+		  AND ($3::uuid IS NULL OR cd.org_id = $3)
+		ORDER BY cd.created_at DESC, cd.id DESC
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := Pool.QueryContext(ctx, query, limit, offset)
+	rows, err := Pool.QueryContext(ctx, query, page.limit(), page.Offset, orgFilter(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -245,8 +693,105 @@ func (repo *CharterDetailRepository) List(ctx context.Context, limit, offset int
 	return out, rows.Err()
 }
 
+// ListWithVoyageCounts returns charters ordered like List, each with its
+// voyage totals computed in the same query.
+func (repo *CharterDetailRepository) ListWithVoyageCounts(ctx context.Context, page Page) ([]CharterWithCounts, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT
+			cd.id,
+			cd.title,
+			cd.status,
+			COUNT(v.id),
+			COUNT(v.id) FILTER (WHERE v.actual_departure_at IS NOT NULL AND v.actual_arrival_at IS NULL),
+			cd.created_at,
+			cd.updated_at
+		FROM shipman.charter_details cd
+		LEFT JOIN shipman.voyages v ON v.charter_detail_id = cd.id
+		WHERE ($3::uuid IS NULL OR cd.org_id = $3)
+		GROUP BY cd.id
+		ORDER BY cd.created_at DESC, cd.id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, page.limit(), page.Offset, orgFilter(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterWithCounts
+	for rows.Next() {
+		var row CharterWithCounts
+		if err := rows.Scan(
+			&row.ID,
+			&row.Title,
+			&row.Status,
+			&row.VoyageCount,
+			&row.InProgressVoyageCount,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ListExpiring returns charters that are not finished and whose end date
+// falls between today (UTC, per the package clock) and withinDays days from
+// today inclusive, soonest first. end_date is a DATE, so the window is
+// compared as dates and the session time zone does not matter.
+func (repo *CharterDetailRepository) ListExpiring(ctx context.Context, withinDays int) ([]CharterDetail, error) {
+	const query = `
+		SELECT id, title, status, end_date, created_at, updated_at
+		FROM shipman.charter_details
+		WHERE end_date IS NOT NULL
+		  AND end_date >= $1::date
+		  AND end_date <= $1::date + $2::int
+		  AND status NOT IN ('completed', 'cancelled', 'closed')
+		  AND ($3::uuid IS NULL OR org_id = $3)
+		ORDER BY end_date ASC
+	`
+
+	today := now().UTC().Format(time.DateOnly)
+	rows, err := Pool.QueryContext(ctx, query, today, withinDays, orgFilter(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterDetail
+	for rows.Next() {
+		var (
+			detail CharterDetail
+			end    sql.NullTime
+		)
+		if err := rows.Scan(&detail.ID, &detail.Title, &detail.Status, &end, &detail.CreatedAt, &detail.UpdatedAt); err != nil {
+			return nil, err
+		}
+		detail.EndDate = timePtr(end)
+		out = append(out, detail)
+	}
+	return out, rows.Err()
+}
+
 // Update modifies editable fields of a charter detail.
 func (repo *CharterDetailRepository) Update(ctx context.Context, detail *CharterDetail) error {
+	detail.Normalize()
+	if err := sanitizeNotes(notesField{"notes", detail.Notes}); err != nil {
+		return err
+	}
+	if err := checkCharterDates(*detail); err != nil {
+		return err
+	}
+	if err := checkJSON("ai_extracted_terms", detail.AIExtractedTerms); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.charter_details
 		SET
@@ -267,12 +812,15 @@ func (repo *CharterDetailRepository) Update(ctx context.Context, detail *Charter
 			ai_extracted_terms = $16,
 			last_reviewed_at = $17,
 			notes = $18,
+			laytime_reversible = $19,
+			default_currency = $20,
+			despatch_rate = $21,
 			updated_at = NOW()
 		WHERE id = $1
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		detail.ID,
@@ -290,15 +838,184 @@ func (repo *CharterDetailRepository) Update(ctx context.Context, detail *Charter
 		nullableString(detail.PaymentTerms),
 		detail.AIStatus,
 		nullableString(detail.AIDocumentPath),
-		nullableBytes(detail.AIExtractedTerms),
+		nullableJSON(detail.AIExtractedTerms),
 		nullableTime(detail.LastReviewedAt),
 		nullableString(detail.Notes),
+		detail.LaytimeReversible,
+		nullableString(detail.DefaultCurrency),
+		nullableFloat(detail.DespatchRate),
 	).Scan(&detail.UpdatedAt)
+	charterCache.invalidate(detail.ID)
+	return notFound(err)
 }
 
-// Delete removes a charter detail.
+// ArchiveClosedBefore archives charters whose status is closed or completed
+// and that were last updated before the given time, skipping any with a
+// dispute still in progress. It returns how many were archived.
+func (repo *CharterDetailRepository) ArchiveClosedBefore(ctx context.Context, before time.Time) (int64, error) {
+	const query = `
+		UPDATE shipman.charter_details cd
+		SET archived_at = NOW()
+		WHERE cd.archived_at IS NULL
+		  AND cd.status IN ('closed', 'completed')
+		  AND cd.updated_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM shipman.disputes d
+			WHERE d.charter_detail_id = cd.id
+			  AND d.status NOT IN ('resolved', 'closed')
+		  )
+	`
+	res, err := Pool.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if n > 0 {
+		charterCache.clear()
+	}
+	return n, err
+}
+
+// Delete removes a charter detail and leaves a tombstone for Changes.
+// Deleting a charter that does not exist is not an error.
 func (repo *CharterDetailRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	const query = `DELETE FROM shipman.charter_details WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id)
+	const query = `DELETE FROM shipman.charter_details WHERE id = $1 RETURNING org_id`
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var orgID uuid.UUID
+		if err := Conn(ctx).QueryRowContext(ctx, query, id).Scan(&orgID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		return recordDeletion(ctx, AuditEntityCharter, id, orgID)
+	})
+	charterCache.invalidate(id)
 	return err
 }
+
+// MaxChangesPage caps how many changes Changes returns per call.
+const MaxChangesPage = 500
+
+// ChangeCursor marks a place in the charter change feed for keyset
+// pagination over (change_xid, id): the transaction that last wrote a row and
+// the row's id. The zero cursor starts from the beginning.
+type ChangeCursor struct {
+	XID uint64
+	ID  uuid.UUID
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c ChangeCursor) Encode() string {
+	raw := strconv.FormatUint(c.XID, 10) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeChangeCursor parses a token produced by ChangeCursor.Encode.
+func DecodeChangeCursor(token string) (ChangeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ChangeCursor{}, ErrInvalidCursor
+	}
+	xid, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return ChangeCursor{}, ErrInvalidCursor
+	}
+	c := ChangeCursor{}
+	if c.XID, err = strconv.ParseUint(xid, 10, 64); err != nil {
+		return ChangeCursor{}, ErrInvalidCursor
+	}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return ChangeCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ChangeSet is one page of the change feed: rows created or updated, and
+// tombstones for rows deleted. Next is the cursor for the following call; it
+// stays at the requested one when nothing changed. More reports that another
+// page is already available.
+type ChangeSet struct {
+	Upserts   []CharterDetail `json:"upserts"`
+	Deletions []Deletion      `json:"deletions"`
+	Next      string          `json:"next"`
+	More      bool            `json:"more"`
+}
+
+// Changes returns up to limit charter upserts and deletions after cursor,
+// in commit-safe order, with the same columns as List. Only writes from
+// transactions older than every one still running are returned, so a slow
+// transaction is picked up by a later call rather than skipped. limit <= 0
+// uses DefaultPageSize and is capped at MaxChangesPage.
+func (repo *CharterDetailRepository) Changes(ctx context.Context, cursor ChangeCursor, limit int) (ChangeSet, error) {
+	limit = Page{Limit: limit}.limit()
+	if limit > MaxChangesPage {
+		limit = MaxChangesPage
+	}
+	set := ChangeSet{Upserts: []CharterDetail{}, Deletions: []Deletion{}, Next: cursor.Encode()}
+
+	// Fetch one extra row to learn whether another page follows.
+	query := `
+		SELECT deleted, entity_id, change_xid::text, title, status, created_at, updated_at, deleted_at
+		FROM (
+			SELECT FALSE AS deleted, id AS entity_id, change_xid,
+			       title, status, created_at, updated_at, NULL::timestamptz AS deleted_at
+			FROM shipman.charter_details
+			WHERE ($3::uuid IS NULL OR org_id = $3)
+			UNION ALL
+			SELECT TRUE, entity_id, change_xid,
+			       NULL, NULL, NULL, NULL, deleted_at
+			FROM shipman.deletions
+			WHERE entity_type = $4
+			  AND ($3::uuid IS NULL OR org_id = $3)
+		) changes
+		WHERE (change_xid, entity_id) > ($1::text::xid8, $2::uuid)
+		  AND change_xid < pg_snapshot_xmin(pg_current_snapshot())
+		ORDER BY change_xid, entity_id
+		LIMIT $5
+	`
+	rows, err := Pool.QueryContext(ctx, query,
+		strconv.FormatUint(cursor.XID, 10), cursor.ID, orgFilter(ctx), AuditEntityCharter, limit+1)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var (
+			deleted   bool
+			id        uuid.UUID
+			xid       string
+			title     sql.NullString
+			status    sql.NullString
+			createdAt sql.NullTime
+			updatedAt sql.NullTime
+			deletedAt sql.NullTime
+		)
+		if err := rows.Scan(&deleted, &id, &xid, &title, &status, &createdAt, &updatedAt, &deletedAt); err != nil {
+			return ChangeSet{}, err
+		}
+		if n++; n > limit {
+			set.More = true
+			break
+		}
+		if deleted {
+			set.Deletions = append(set.Deletions, Deletion{EntityType: AuditEntityCharter, EntityID: id, DeletedAt: deletedAt.Time})
+		} else {
+			set.Upserts = append(set.Upserts, CharterDetail{
+				ID: id, Title: title.String, Status: status.String,
+				CreatedAt: createdAt.Time, UpdatedAt: updatedAt.Time,
+			})
+		}
+		next := ChangeCursor{ID: id}
+		if next.XID, err = strconv.ParseUint(xid, 10, 64); err != nil {
+			return ChangeSet{}, err
+		}
+		set.Next = next.Encode()
+	}
+	if err := rows.Err(); err != nil {
+		return ChangeSet{}, err
+	}
+	return set, nil
+}
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CharterExport is a self-contained snapshot of a charter and everything
+// hanging off it, used for backup and handoff between accounts.
+type CharterExport struct {
+	ExportedAt       time.Time            `json:"exported_at"`
+	Charter          CharterDetail        `json:"charter"`
+	LaytimeTerms     []CharterLaytimeTerm `json:"laytime_terms"`
+	Voyages          []VoyageExport       `json:"voyages"`
+	LaytimeEntries   []LaytimeEntry       `json:"laytime_entries"`
+	Disputes         []Dispute            `json:"disputes"`
+	BillsOfLading    []BillOfLading       `json:"bills_of_lading"`
+	DemurrageRecords []DemurrageRecord    `json:"demurrage_records"`
+}
+
+// VoyageExport nests a voyage with its ports and payments.
+type VoyageExport struct {
+	Voyage   Voyage          `json:"voyage"`
+	Ports    []VoyagePort    `json:"ports"`
+	Payments []VoyagePayment `json:"payments"`
+}
+
+// CharterExport assembles the full export for a charter. Independent sections
+// are fetched concurrently.
+func (repo *CharterDetailRepository) CharterExport(ctx context.Context, charterID uuid.UUID) (CharterExport, error) {
+	charter, err := repo.Retrieve(ctx, charterID)
+	if err != nil {
+		return CharterExport{}, err
+	}
+
+	out := CharterExport{
+		ExportedAt: now().UTC(),
+		Charter:    charter,
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	run := func(fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	run(func() (err error) {
+		out.LaytimeTerms, err = NewCharterLaytimeTermRepository().ListByCharter(ctx, charterID)
+		return err
+	})
+	run(func() (err error) {
+		out.LaytimeEntries, err = NewLaytimeEntryRepository().ListByCharter(ctx, charterID)
+		return err
+	})
+	run(func() error {
+		voyages, err := NewVoyageRepository().ListByCharter(ctx, charterID, true)
+		if err != nil {
+			return err
+		}
+		portRepo := NewVoyagePortRepository()
+		paymentRepo := NewPaymentRepository()
+		out.Voyages = make([]VoyageExport, 0, len(voyages))
+		for _, v := range voyages {
+			ports, err := portRepo.ListByVoyage(ctx, v.ID)
+			if err != nil {
+				return err
+			}
+			payments, err := paymentRepo.ListByVoyage(ctx, v.ID)
+			if err != nil {
+				return err
+			}
+			out.Voyages = append(out.Voyages, VoyageExport{Voyage: v, Ports: ports, Payments: payments})
+		}
+		return nil
+	})
+	run(func() error {
+		disputeRepo := NewDisputeRepository()
+		list, err := listAll(func(p Page) ([]Dispute, error) {
+			return disputeRepo.ListByCharter(ctx, charterID, p)
+		})
+		if err != nil {
+			return err
+		}
+		out.Disputes = make([]Dispute, 0, len(list))
+		for _, d := range list {
+			full, err := disputeRepo.Retrieve(ctx, d.ID)
+			if err != nil {
+				return err
+			}
+			out.Disputes = append(out.Disputes, full)
+		}
+		return nil
+	})
+	run(func() error {
+		billRepo := NewBillOfLadingRepository()
+		list, err := billRepo.ListByCharter(ctx, charterID)
+		if err != nil {
+			return err
+		}
+		out.BillsOfLading = make([]BillOfLading, 0, len(list))
+		for _, bl := range list {
+			full, err := billRepo.Retrieve(ctx, bl.ID)
+			if err != nil {
+				return err
+			}
+			out.BillsOfLading = append(out.BillsOfLading, full)
+		}
+		return nil
+	})
+	run(func() error {
+		recordRepo := NewDemurrageRecordRepository()
+		list, err := listAll(func(p Page) ([]DemurrageRecord, error) {
+			return recordRepo.ListByCharter(ctx, charterID, p)
+		})
+		if err != nil {
+			return err
+		}
+		out.DemurrageRecords = make([]DemurrageRecord, 0, len(list))
+		for _, r := range list {
+			full, err := recordRepo.Retrieve(ctx, r.ID)
+			if err != nil {
+				return err
+			}
+			out.DemurrageRecords = append(out.DemurrageRecords, full)
+		}
+		return nil
+	})
+
+	wg.Wait()
+	if firstErr != nil {
+		return CharterExport{}, firstErr
+	}
+	return out, nil
+}
+
+// CharterImport recreates an exported charter graph inside a single
+// transaction. Every row gets a new id and references between rows are
+// remapped; the importing user becomes the owner of the charter, its voyages
+// and its payments, and the copy belongs to the organization ctx is scoped to
+// rather than the exporter's. It returns the new charter.
+func (repo *CharterDetailRepository) CharterImport(ctx context.Context, export CharterExport, userID uuid.UUID) (CharterDetail, error) {
+	charter := export.Charter
+
+	err := WithTx(ctx, func(ctx context.Context) error {
+		charter.CreatedByUserID = &userID
+		charter.OrgID = uuid.Nil
+		if err := repo.Create(ctx, &charter); err != nil {
+			return err
+		}
+
+		termRepo := NewCharterLaytimeTermRepository()
+		for _, t := range export.LaytimeTerms {
+			t.CharterDetailID = charter.ID
+			if err := termRepo.Create(ctx, &t); err != nil {
+				return err
+			}
+		}
+
+		voyageRepo := NewVoyageRepository()
+		portRepo := NewVoyagePortRepository()
+		paymentRepo := NewPaymentRepository()
+		voyageIDs := make(map[uuid.UUID]uuid.UUID)
+		for _, ve := range export.Voyages {
+			v := ve.Voyage
+			oldID := v.ID
+			v.CharterDetailID = &charter.ID
+			v.OrgID = charter.OrgID
+			v.OwnerUserID = &userID
+			v.DealID = nil
+			v.DocumentID = nil
+			v.CounterpartyUserID = nil
+			v.BrokerUserID = nil
+			if err := voyageRepo.Create(ctx, &v); err != nil {
+				return err
+			}
+			// Create only covers the planning columns; restore the rest.
+			if err := voyageRepo.Update(ctx, &v); err != nil {
+				return err
+			}
+			voyageIDs[oldID] = v.ID
+
+			for _, p := range ve.Ports {
+				p.VoyageID = v.ID
+				if err := portRepo.Create(ctx, &p); err != nil {
+					return err
+				}
+			}
+			for _, p := range ve.Payments {
+				p.VoyageID = v.ID
+				p.CreatedBy = userID
+				if err := paymentRepo.Create(ctx, &p); err != nil {
+					return err
+				}
+			}
+		}
+
+		remapVoyage := func(id *uuid.UUID) *uuid.UUID {
+			if id == nil {
+				return nil
+			}
+			if mapped, ok := voyageIDs[*id]; ok {
+				return &mapped
+			}
+			return nil
+		}
+
+		entryRepo := NewLaytimeEntryRepository()
+		entryIDs := make(map[uuid.UUID]uuid.UUID)
+		for _, e := range export.LaytimeEntries {
+			oldID := e.ID
+			e.CharterDetailID = charter.ID
+			e.VoyageID = remapVoyage(e.VoyageID)
+			if err := entryRepo.Create(ctx, &e); err != nil {
+				return err
+			}
+			entryIDs[oldID] = e.ID
+		}
+
+		remapEntry := func(id *uuid.UUID) *uuid.UUID {
+			if id == nil {
+				return nil
+			}
+			if mapped, ok := entryIDs[*id]; ok {
+				return &mapped
+			}
+			return nil
+		}
+
+		disputeRepo := NewDisputeRepository()
+		for _, d := range export.Disputes {
+			d.CharterDetailID = charter.ID
+			d.VoyageID = remapVoyage(d.VoyageID)
+			d.LaytimeEntryID = remapEntry(d.LaytimeEntryID)
+			d.PaymentID = nil
+			d.VoyagePaymentID = nil
+			if err := disputeRepo.Create(ctx, &d); err != nil {
+				return err
+			}
+		}
+
+		billRepo := NewBillOfLadingRepository()
+		for _, bl := range export.BillsOfLading {
+			bl.CharterDetailID = charter.ID
+			bl.VoyageID = remapVoyage(bl.VoyageID)
+			if err := billRepo.Create(ctx, &bl); err != nil {
+				return err
+			}
+		}
+
+		recordRepo := NewDemurrageRecordRepository()
+		for _, r := range export.DemurrageRecords {
+			r.CharterDetailID = charter.ID
+			r.VoyageID = remapVoyage(r.VoyageID)
+			r.LaytimeEntryID = remapEntry(r.LaytimeEntryID)
+			if err := recordRepo.Create(ctx, &r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return CharterDetail{}, err
+	}
+	return charter, nil
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+	"shipman/internal/metrics"
+
+	"github.com/google/uuid"
+)
+
+// exportFixture answers the export queries for a charter with one voyage, one
+// laytime entry on it and one demurrage claim against that entry. The other
+// sections are empty.
+type exportFixture struct {
+	charter, voyage, entry, record uuid.UUID
+	owner                          uuid.UUID
+	started                        time.Time
+}
+
+func newExportFixture(fake *dbtest.Fake) exportFixture {
+	fx := exportFixture{
+		charter: uuid.New(), voyage: uuid.New(), entry: uuid.New(), record: uuid.New(),
+		owner:   uuid.New(),
+		started: time.Date(2026, 2, 1, 6, 0, 0, 0, time.UTC),
+	}
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	none := dbtest.Rows(nil)
+
+	fake.Return("updated_at FROM shipman.charter_details WHERE id = $1", dbtest.Rows(charterColumns, dbtest.Row(charterColumns, map[string]any{
+		"id": fx.charter, "org_id": DefaultOrgID, "created_by_user_id": fx.owner,
+		"title": "Grain charter", "status": "active", "demurrage_rate": 24000.0,
+		"demurrage_currency": "USD", "ai_status": "pending", "laytime_reversible": true,
+		"created_at": created, "updated_at": created,
+	})))
+	fake.Return("FROM shipman.charter_laytime_terms WHERE charter_detail_id = $1", none)
+	fake.Return("SELECT id FROM shipman.voyages WHERE charter_detail_id = $1", dbtest.Rows([]string{"id"}, []any{fx.voyage}))
+	fake.Return("updated_at FROM shipman.voyages WHERE id = $1", dbtest.Rows(voyageColumns, dbtest.Row(voyageColumns, map[string]any{
+		"id": fx.voyage, "org_id": DefaultOrgID, "charter_detail_id": fx.charter, "owner_user_id": fx.owner,
+		"vessel_name": "MV Example", "demurrage_currency": "USD", "status": "completed",
+		"created_at": created, "updated_at": created,
+	})))
+	fake.Return("FROM shipman.voyage_ports WHERE voyage_id = $1", none)
+	fake.Return("FROM shipman.voyage_payments WHERE voyage_id = $1", none)
+	fake.Return("FROM shipman.laytime_entries WHERE charter_detail_id = $1 ORDER BY started_at", dbtest.Rows(
+		[]string{"id", "charter_detail_id", "voyage_id", "port_name", "activity", "started_at", "ended_at",
+			"hours_counted", "excluded_hours", "remarks", "created_at", "updated_at"},
+		[]any{fx.entry, fx.charter, fx.voyage.String(), "Santos", "loading", fx.started, fx.started.Add(60 * time.Hour),
+			60.0, 0.0, nil, created, created}))
+	fake.Return("FROM shipman.disputes WHERE charter_detail_id = $1", none)
+	fake.Return("FROM shipman.bills_of_lading WHERE charter_detail_id = $1", none)
+	fake.Return("FROM shipman.demurrage_records WHERE charter_detail_id = $1", dbtest.Rows(
+		[]string{"id", "charter_detail_id", "voyage_id", "claimed_amount", "status", "created_at", "updated_at"},
+		[]any{fx.record, fx.charter, fx.voyage.String(), 12000.0, "submitted", created, created}))
+	fake.Return("updated_at FROM shipman.demurrage_records WHERE id = $1", dbtest.Rows(
+		[]string{"id", "charter_detail_id", "voyage_id", "laytime_entry_id", "claimed_hours", "claimed_amount",
+			"currency", "status", "reference", "supporting_doc_uri", "notes", "created_at", "updated_at"},
+		[]any{fx.record, fx.charter, fx.voyage.String(), fx.entry.String(), 12.0, 12000.0,
+			"USD", "submitted", "DEM-1", nil, nil, created, created}))
+	return fx
+}
+
+// importIDs answers the import inserts with fresh ids and returns them.
+type importIDs struct {
+	charter, voyage, entry, record uuid.UUID
+}
+
+func stubImport(fake *dbtest.Fake) importIDs {
+	ids := importIDs{charter: uuid.New(), voyage: uuid.New(), entry: uuid.New(), record: uuid.New()}
+	now := time.Now()
+	fake.Return("INSERT INTO shipman.charter_details", dbtest.Rows(
+		[]string{"id", "status", "ai_status", "created_at", "updated_at"},
+		[]any{ids.charter, "active", "pending", now, now}))
+	fake.Return("INSERT INTO shipman.voyages", dbtest.Rows(
+		[]string{"id", "org_id", "status", "demurrage_currency", "created_at", "updated_at"},
+		[]any{ids.voyage, DefaultOrgID, "completed", "USD", now, now}))
+	fake.Return("UPDATE shipman.voyages SET voyage_number = $2", dbtest.Rows([]string{"updated_at"}, []any{now}))
+	fake.Return("INSERT INTO shipman.laytime_entries", dbtest.Rows(
+		[]string{"id", "created_at", "updated_at"}, []any{ids.entry, now, now}))
+	fake.Return("INSERT INTO shipman.demurrage_records", dbtest.Rows(
+		[]string{"id", "currency", "status", "created_at", "updated_at"},
+		[]any{ids.record, "USD", "submitted", now, now}))
+	return ids
+}
+
+func TestCharterExportImportRoundTrip(t *testing.T) {
+	fake := newFakeDB(t)
+	fx := newExportFixture(fake)
+	repo := NewCharterDetailRepository()
+
+	export, err := repo.CharterExport(context.Background(), fx.charter)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(export.Voyages) != 1 || len(export.LaytimeEntries) != 1 || len(export.DemurrageRecords) != 1 {
+		t.Fatalf("export = %d voyages, %d entries, %d demurrage; want one each",
+			len(export.Voyages), len(export.LaytimeEntries), len(export.DemurrageRecords))
+	}
+
+	// The import endpoint receives the export as JSON.
+	raw, err := json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded CharterExport
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	importer := uuid.New()
+	ids := stubImport(fake)
+	imported, err := repo.CharterImport(context.Background(), decoded, importer)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if imported.ID != ids.charter || imported.Title != "Grain charter" || !imported.LaytimeReversible {
+		t.Errorf("imported charter = %+v", imported)
+	}
+	if imported.CreatedByUserID == nil || *imported.CreatedByUserID != importer {
+		t.Errorf("imported charter owner = %v, want the importer", imported.CreatedByUserID)
+	}
+	if fake.Commits() != 1 {
+		t.Errorf("commits = %d, want 1", fake.Commits())
+	}
+
+	charterInsert := fake.Calls("INSERT INTO shipman.charter_details")[0]
+	if got := charterInsert.Arg(1); got != importer.String() {
+		t.Errorf("charter created_by = %v, want the importer", got)
+	}
+	voyageInsert := fake.Calls("INSERT INTO shipman.voyages")[0]
+	if voyageInsert.Arg(1) != ids.charter.String() || voyageInsert.Arg(3) != importer.String() || voyageInsert.Arg(5) != "MV Example" {
+		t.Errorf("voyage insert args = %v", voyageInsert.Args)
+	}
+	entryInsert := fake.Calls("INSERT INTO shipman.laytime_entries")[0]
+	if entryInsert.Arg(1) != ids.charter.String() || entryInsert.Arg(2) != ids.voyage.String() || entryInsert.Arg(3) != "Santos" {
+		t.Errorf("laytime entry insert args = %v", entryInsert.Args)
+	}
+	if at, _ := entryInsert.Arg(5).(time.Time); !at.Equal(fx.started) {
+		t.Errorf("laytime entry started_at = %v, want %v", entryInsert.Arg(5), fx.started)
+	}
+	recordInsert := fake.Calls("INSERT INTO shipman.demurrage_records")[0]
+	if recordInsert.Arg(1) != ids.charter.String() || recordInsert.Arg(2) != ids.voyage.String() ||
+		recordInsert.Arg(3) != ids.entry.String() || recordInsert.Arg(5) != 12000.0 || recordInsert.Arg(8) != "DEM-1" {
+		t.Errorf("demurrage insert args = %v", recordInsert.Args)
+	}
+	for _, c := range fake.Calls("INSERT INTO") {
+		for _, a := range c.Args {
+			if a == fx.charter.String() || a == fx.voyage.String() || a == fx.entry.String() {
+				t.Errorf("import reused an exported id %v in %s", a, c.Query)
+			}
+		}
+	}
+}
+
+// demurrageClaims scrapes the claims counter for currency.
+func demurrageClaims(t *testing.T, currency string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	prefix := `shipman_demurrage_claims_total{currency="` + currency + `"} `
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return "0"
+}
+
+func TestCharterImportCountsClaimsAfterCommit(t *testing.T) {
+	fake := newFakeDB(t)
+	newExportFixture(fake)
+	stubImport(fake)
+	export, err := NewCharterDetailRepository().CharterExport(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics.Enable()
+
+	errRollback := errors.New("roll back")
+	err = WithTx(context.Background(), func(ctx context.Context) error {
+		if _, err := NewCharterDetailRepository().CharterImport(ctx, export, uuid.New()); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want the outer error", err)
+	}
+	if got := demurrageClaims(t, "USD"); got != "0" {
+		t.Errorf("claims after rollback = %s, want 0", got)
+	}
+
+	if _, err := NewCharterDetailRepository().CharterImport(context.Background(), export, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if got := demurrageClaims(t, "USD"); got != "1" {
+		t.Errorf("claims after commit = %s, want 1", got)
+	}
+}
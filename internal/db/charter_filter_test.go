@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var (
+	statusClause      = regexp.MustCompile(`AND status = \$(\d+)`)
+	startAfterClause  = regexp.MustCompile(`AND start_date >= \$(\d+)`)
+	startBeforeClause = regexp.MustCompile(`AND start_date < \$(\d+)`)
+)
+
+var filteredColumns = []string{"id", "title", "status", "start_date", "created_at", "updated_at"}
+
+// stubListFiltered answers ListFiltered from charters, applying only the
+// clauses the statement carries, in its order and page.
+func stubListFiltered(t *testing.T, fake *dbtest.Fake, charters []map[string]any) {
+	fake.On("SELECT id, title, status, start_date, created_at, updated_at FROM shipman.charter_details WHERE TRUE", func(call dbtest.Call) dbtest.Result {
+		arg := func(re *regexp.Regexp) any {
+			m := re.FindStringSubmatch(call.Query)
+			if m == nil {
+				return nil
+			}
+			n, _ := strconv.Atoi(m[1])
+			return call.Arg(n)
+		}
+		status, after, before, org := arg(statusClause), arg(startAfterClause), arg(startBeforeClause), orgArg(call)
+		var out [][]any
+		for _, c := range charters {
+			start, dated := c["start_date"].(time.Time)
+			switch {
+			case org != "" && c["org_id"] != org,
+				status != nil && c["status"] != status,
+				after != nil && (!dated || start.Before(after.(time.Time))),
+				before != nil && (!dated || !start.Before(before.(time.Time))):
+				continue
+			}
+			out = append(out, dbtest.Row(filteredColumns, c))
+		}
+		sortLikeQuery(t, call.Query, filteredColumns, out)
+		m := limitOffset.FindStringSubmatch(call.Query)
+		ln, _ := strconv.Atoi(m[1])
+		on, _ := strconv.Atoi(m[2])
+		if len(call.Args) != max(ln, on) {
+			t.Errorf("%d args for %d placeholders: %s", len(call.Args), max(ln, on), call.Query)
+		}
+		limit, offset := int(call.Arg(ln).(int64)), int(call.Arg(on).(int64))
+		out = out[min(offset, len(out)):]
+		return dbtest.Rows(filteredColumns, out[:min(limit, len(out))]...)
+	})
+}
+
+func TestCharterListFiltered(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	charter := func(title, status string, start any, org uuid.UUID, created time.Time) map[string]any {
+		return map[string]any{
+			"id": uuid.NewString(), "org_id": org.String(), "title": title, "status": status,
+			"start_date": start, "created_at": created, "updated_at": created,
+		}
+	}
+	charters := []map[string]any{
+		charter("Q1 active", "active", day(time.January, 1), DefaultOrgID, day(time.January, 1)),
+		charter("Q1 draft", "draft", day(time.March, 31), DefaultOrgID, day(time.January, 2)),
+		charter("Q2 active", "active", day(time.April, 1), DefaultOrgID, day(time.January, 3)),
+		charter("Undated active", "active", nil, DefaultOrgID, day(time.January, 4)),
+		charter("Last year active", "active", day(time.December, 31).AddDate(-1, 0, 0), DefaultOrgID, day(time.January, 5)),
+		charter("Other org Q1 active", "active", day(time.February, 1), uuid.New(), day(time.January, 6)),
+	}
+	active := "active"
+	q1, q2 := day(time.January, 1), day(time.April, 1)
+
+	tests := []struct {
+		name   string
+		filter CharterListFilter
+		page   Page
+		want   []string
+	}{
+		{"status", CharterListFilter{Status: &active}, Page{Limit: 10},
+			[]string{"Last year active", "Undated active", "Q2 active", "Q1 active"}},
+		{"start after, inclusive", CharterListFilter{StartAfter: &q2}, Page{Limit: 10}, []string{"Q2 active"}},
+		{"start before, exclusive", CharterListFilter{StartBefore: &q2}, Page{Limit: 10},
+			[]string{"Last year active", "Q1 draft", "Q1 active"}},
+		{"quarter", CharterListFilter{StartAfter: &q1, StartBefore: &q2}, Page{Limit: 10}, []string{"Q1 draft", "Q1 active"}},
+		{"active in the quarter", CharterListFilter{Status: &active, StartAfter: &q1, StartBefore: &q2}, Page{Limit: 10},
+			[]string{"Q1 active"}},
+		{"paged", CharterListFilter{Status: &active}, Page{Limit: 2, Offset: 1}, []string{"Undated active", "Q2 active"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubListFiltered(t, fake, charters)
+			got, err := NewCharterDetailRepository().ListFiltered(WithOrg(context.Background(), DefaultOrgID), tt.filter, tt.page.Limit, tt.page.Offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, c := range got {
+				titles = append(titles, c.Title)
+			}
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("titles = %v, want %v", titles, tt.want)
+			}
+			query := fake.Calls("WHERE TRUE")[0].Query
+			for _, clause := range []struct {
+				re  *regexp.Regexp
+				set bool
+			}{
+				{statusClause, tt.filter.Status != nil},
+				{startAfterClause, tt.filter.StartAfter != nil},
+				{startBeforeClause, tt.filter.StartBefore != nil},
+			} {
+				if clause.re.MatchString(query) != clause.set {
+					t.Errorf("clause %s present = %v, want %v: %s", clause.re, !clause.set, clause.set, query)
+				}
+			}
+		})
+	}
+}
+
+func TestCharterListFilteredWithoutFilterIsList(t *testing.T) {
+	fake := newFakeDB(t)
+	listed := uuid.New()
+	fake.Return("COUNT(*) OVER () AS total FROM shipman.charter_details", dbtest.Rows(
+		[]string{"id", "title", "status", "created_at", "updated_at", "total"},
+		[]any{listed, "Newest", "draft", time.Now(), time.Now(), int64(1)}))
+
+	got, err := NewCharterDetailRepository().ListFiltered(context.Background(), CharterListFilter{}, 20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != listed {
+		t.Errorf("unfiltered = %+v, want the List page", got)
+	}
+	if len(fake.Calls("WHERE TRUE")) != 0 {
+		t.Error("unfiltered list built a filtered query")
+	}
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CharterLaytimePort is one line of a charter laytime breakdown. Ports with a
+// laytime term are assessed against their own allowance; all other ports share
+// the charter-level allowance and are reported together on a single line.
+// Reversible is set when the line's time is netted with the other reversible
+// lines instead of being assessed on its own.
+type CharterLaytimePort struct {
+	Ports           []string `json:"ports"`
+	PortRole        *string  `json:"port_role,omitempty"`
+	AllowanceSource string   `json:"allowance_source"` // port | charter
+	HoursUsed       float64  `json:"hours_used"`
+	HoursAllowed    float64  `json:"hours_allowed"`
+	BalanceHours    float64  `json:"balance_hours"` // negative = demurrage
+	DemurrageHours  float64  `json:"demurrage_hours"`
+	DespatchHours   float64  `json:"despatch_hours"`
+	Reversible      bool     `json:"reversible"`
+}
+
+// CharterLaytimeSummary aggregates laytime usage across all ports of a charter.
+type CharterLaytimeSummary struct {
+	CharterDetailID   uuid.UUID            `json:"charter_detail_id"`
+	Reversible        bool                 `json:"reversible"`
+	Ports             []CharterLaytimePort `json:"ports"`
+	TotalHoursUsed    float64              `json:"total_hours_used"`
+	TotalHoursAllowed float64              `json:"total_hours_allowed"`
+	BalanceHours      float64              `json:"balance_hours"` // negative = demurrage
+	DemurrageHours    float64              `json:"demurrage_hours"`
+	DespatchHours     float64              `json:"despatch_hours"`
+	DemurrageAmount   *float64             `json:"demurrage_amount,omitempty"`
+	DespatchRate      *float64             `json:"despatch_rate,omitempty"`   // per day, as applied
+	DespatchAmount    *float64             `json:"despatch_amount,omitempty"` // owed to the charterer
+	Currency          string               `json:"currency"`
+}
+
+// charterPortUsage is the counted laytime for one port of a charter.
+type charterPortUsage struct {
+	PortName string
+	Hours    float64
+}
+
+// CalcLaytime computes laytime usage, demurrage and despatch for a charter,
+// consulting per-port laytime terms where present. When the charter is
+// reversible, time saved at one port offsets time used at another. Despatch
+// is charged at the charter's despatch rate, or half the demurrage rate when
+// none is set.
+func (repo *CharterDetailRepository) CalcLaytime(ctx context.Context, charterID uuid.UUID) (CharterLaytimeSummary, error) {
+	const termsQuery = `
+		SELECT COALESCE(laytime_allowance_hours, 0),
+		       COALESCE(demurrage_rate, 0),
+		       COALESCE(despatch_rate, COALESCE(demurrage_rate, 0) / 2),
+		       COALESCE(demurrage_currency, 'USD'),
+		       laytime_reversible
+		FROM shipman.charter_details WHERE id = $1
+	`
+	var allowed, demRate, despRate float64
+	var currency string
+	var reversible bool
+	if err := Pool.QueryRowContext(ctx, termsQuery, charterID).Scan(&allowed, &demRate, &despRate, &currency, &reversible); err != nil {
+		return CharterLaytimeSummary{}, err
+	}
+
+	const usageQuery = `
+		SELECT port_name, COALESCE(SUM(hours_counted), 0)
+		FROM shipman.laytime_entries
+		WHERE charter_detail_id = $1
+		  AND hours_counted IS NOT NULL
+		GROUP BY port_name
+	`
+	rows, err := Pool.QueryContext(ctx, usageQuery, charterID)
+	if err != nil {
+		return CharterLaytimeSummary{}, err
+	}
+	defer rows.Close()
+
+	var usage []charterPortUsage
+	for rows.Next() {
+		var u charterPortUsage
+		if err := rows.Scan(&u.PortName, &u.Hours); err != nil {
+			return CharterLaytimeSummary{}, err
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return CharterLaytimeSummary{}, err
+	}
+
+	terms, err := NewCharterLaytimeTermRepository().ListByCharter(ctx, charterID)
+	if err != nil {
+		return CharterLaytimeSummary{}, err
+	}
+
+	summary := assessCharterLaytime(usage, terms, allowed, demRate, despRate, reversible)
+	summary.CharterDetailID = charterID
+	summary.Currency = currency
+	return summary, nil
+}
+
+// assessCharterLaytime matches port usage to laytime terms by port name. Each
+// matched port is assessed on its own allowance; unmatched ports are pooled
+// against the charter-level allowance. In reversible mode the charter pool and
+// every term flagged reversible are netted against their combined allowance,
+// while non-reversible terms are still assessed independently.
+func assessCharterLaytime(usage []charterPortUsage, terms []CharterLaytimeTerm, charterAllowance, demRate, despRate float64, reversible bool) CharterLaytimeSummary {
+	used := make(map[string]float64)
+	names := make(map[string]string)
+	for _, u := range usage {
+		key := portKey(u.PortName)
+		used[key] += u.Hours
+		if _, ok := names[key]; !ok {
+			names[key] = strings.TrimSpace(u.PortName)
+		}
+	}
+
+	summary := CharterLaytimeSummary{Reversible: reversible}
+	matched := make(map[string]bool)
+	for _, t := range terms {
+		key := portKey(t.PortName)
+		if matched[key] {
+			continue
+		}
+		matched[key] = true
+		line := assessPort([]string{strings.TrimSpace(t.PortName)}, t.PortRole, "port", used[key], t.AllowanceHours)
+		line.Reversible = reversible && t.Reversible
+		summary.Ports = append(summary.Ports, line)
+	}
+
+	var pooled []string
+	var pooledHours float64
+	for key, hours := range used {
+		if matched[key] {
+			continue
+		}
+		pooled = append(pooled, names[key])
+		pooledHours += hours
+	}
+	if len(pooled) > 0 || len(terms) == 0 {
+		sort.Strings(pooled)
+		if pooled == nil {
+			pooled = []string{}
+		}
+		line := assessPort(pooled, nil, "charter", pooledHours, charterAllowance)
+		line.Reversible = reversible
+		summary.Ports = append(summary.Ports, line)
+	}
+
+	var netBalance float64
+	for _, p := range summary.Ports {
+		summary.TotalHoursUsed += p.HoursUsed
+		summary.TotalHoursAllowed += p.HoursAllowed
+		if p.Reversible {
+			netBalance += p.BalanceHours
+			continue
+		}
+		summary.DemurrageHours += p.DemurrageHours
+		summary.DespatchHours += p.DespatchHours
+	}
+	if netBalance < 0 {
+		summary.DemurrageHours += -netBalance
+	} else {
+		summary.DespatchHours += netBalance
+	}
+	summary.BalanceHours = summary.DespatchHours - summary.DemurrageHours
+	if summary.DemurrageHours > 0 && demRate > 0 {
+		amt := (summary.DemurrageHours / 24) * demRate
+		summary.DemurrageAmount = &amt
+	}
+	if summary.DespatchHours > 0 && despRate > 0 {
+		rate := despRate
+		amt := (summary.DespatchHours / 24) * despRate
+		summary.DespatchRate = &rate
+		summary.DespatchAmount = &amt
+	}
+
+	return summary
+}
+
+// assessPort balances used against allowed for one line. A line with no time
+// recorded has no balance: laytime there has not started, so no despatch is
+// earned yet.
+func assessPort(ports []string, role *string, source string, used, allowed float64) CharterLaytimePort {
+	line := CharterLaytimePort{
+		Ports:           ports,
+		PortRole:        role,
+		AllowanceSource: source,
+		HoursUsed:       used,
+		HoursAllowed:    allowed,
+	}
+	if used == 0 {
+		return line
+	}
+	line.BalanceHours = allowed - used
+	if line.BalanceHours < 0 {
+		line.DemurrageHours = -line.BalanceHours
+	} else {
+		line.DespatchHours = line.BalanceHours
+	}
+	return line
+}
+
+func portKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CharterLaytimeTerm mirrors shipman.charter_laytime_terms. Each row overrides
+// the charter-level laytime allowance for a single load or discharge port.
+type CharterLaytimeTerm struct {
+	ID              uuid.UUID `json:"id"`
+	CharterDetailID uuid.UUID `json:"charter_detail_id"`
+	PortRole        *string   `json:"port_role,omitempty"`
+	PortName        string    `json:"port_name"`
+	AllowanceHours  float64   `json:"allowance_hours"`
+	Reversible      bool      `json:"reversible"`
+	Notes           *string   `json:"notes,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CharterLaytimeTermService describes CRUD behaviour.
+type CharterLaytimeTermService interface {
+	Create(ctx context.Context, term *CharterLaytimeTerm) error
+	Retrieve(ctx context.Context, id uuid.UUID) (CharterLaytimeTerm, error)
+	ListByCharter(ctx context.Context, charterID uuid.UUID) ([]CharterLaytimeTerm, error)
+	Update(ctx context.Context, term *CharterLaytimeTerm) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// CharterLaytimeTermRepository implements CharterLaytimeTermService using Pool.
+type CharterLaytimeTermRepository struct{}
+
+// NewCharterLaytimeTermRepository returns a repository.
+func NewCharterLaytimeTermRepository() *CharterLaytimeTermRepository {
+	return &CharterLaytimeTermRepository{}
+}
+
+// Create inserts a laytime term.
+func (repo *CharterLaytimeTermRepository) Create(ctx context.Context, term *CharterLaytimeTerm) error {
+	clearServerFields(&term.ID, &term.CreatedAt, &term.UpdatedAt)
+	if err := sanitizeNotes(notesField{"notes", term.Notes}); err != nil {
+		return err
+	}
+	const query = `
+		INSERT INTO shipman.charter_laytime_terms (
+			charter_detail_id,
+			port_role,
+			port_name,
+			allowance_hours,
+			reversible,
+			notes
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+		RETURNING id, created_at, updated_at
+	`
+
+	return Conn(ctx).QueryRowContext(
+		ctx,
+		query,
+		term.CharterDetailID,
+		nullableString(term.PortRole),
+		term.PortName,
+		term.AllowanceHours,
+		term.Reversible,
+		nullableString(term.Notes),
+	).Scan(&term.ID, &term.CreatedAt, &term.UpdatedAt)
+}
+
+// Retrieve fetches a term by id.
+func (repo *CharterLaytimeTermRepository) Retrieve(ctx context.Context, id uuid.UUID) (CharterLaytimeTerm, error) {
+	const query = `
+		SELECT
+			id,
+			charter_detail_id,
+			port_role,
+			port_name,
+			allowance_hours,
+			reversible,
+			notes,
+			created_at,
+			updated_at
+		FROM shipman.charter_laytime_terms
+		WHERE id = $1
+	`
+
+	row := Pool.QueryRowContext(ctx, query, id)
+	term, err := scanCharterLaytimeTerm(row)
+	if err != nil {
+		return CharterLaytimeTerm{}, err
+	}
+	return term, nil
+}
+
+// ListByCharter returns all terms attached to a charter.
+func (repo *CharterLaytimeTermRepository) ListByCharter(ctx context.Context, charterID uuid.UUID) ([]CharterLaytimeTerm, error) {
+	const query = `
+		SELECT
+			id,
+			charter_detail_id,
+			port_role,
+			port_name,
+			allowance_hours,
+			reversible,
+			notes,
+			created_at,
+			updated_at
+		FROM shipman.charter_laytime_terms
+		WHERE charter_detail_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, charterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CharterLaytimeTerm
+	for rows.Next() {
+		term, err := scanCharterLaytimeTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, term)
+	}
+	return out, rows.Err()
+}
+
+// Update modifies a laytime term.
+func (repo *CharterLaytimeTermRepository) Update(ctx context.Context, term *CharterLaytimeTerm) error {
+	if err := sanitizeNotes(notesField{"notes", term.Notes}); err != nil {
+		return err
+	}
+	const query = `
+		UPDATE shipman.charter_laytime_terms
+		SET
+			port_role = $2,
+			port_name = $3,
+			allowance_hours = $4,
+			reversible = $5,
+			notes = $6,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := Pool.QueryRowContext(
+		ctx,
+		query,
+		term.ID,
+		nullableString(term.PortRole),
+		term.PortName,
+		term.AllowanceHours,
+		term.Reversible,
+		nullableString(term.Notes),
+	).Scan(&term.UpdatedAt)
+	return notFound(err)
+}
+
+// Delete removes a laytime term.
+func (repo *CharterLaytimeTermRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	const query = `DELETE FROM shipman.charter_laytime_terms WHERE id = $1`
+	_, err := Pool.ExecContext(ctx, query, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCharterLaytimeTerm(row rowScanner) (CharterLaytimeTerm, error) {
+	var (
+		term  CharterLaytimeTerm
+		role  sql.NullString
+		notes sql.NullString
+	)
+	if err := row.Scan(
+		&term.ID,
+		&term.CharterDetailID,
+		&role,
+		&term.PortName,
+		&term.AllowanceHours,
+		&term.Reversible,
+		&notes,
+		&term.CreatedAt,
+		&term.UpdatedAt,
+	); err != nil {
+		return CharterLaytimeTerm{}, err
+	}
+	term.PortRole = stringPtr(role)
+	term.Notes = stringPtr(notes)
+	return term, nil
+}
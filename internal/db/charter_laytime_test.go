@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func laytimeTerm(port string, allowance float64, reversible bool) CharterLaytimeTerm {
+	return CharterLaytimeTerm{PortName: port, AllowanceHours: allowance, Reversible: reversible}
+}
+
+// laytimeLine returns the breakdown line covering port, failing the test
+// when there is none.
+func laytimeLine(t *testing.T, s CharterLaytimeSummary, port string) CharterLaytimePort {
+	t.Helper()
+	for _, p := range s.Ports {
+		if slices.Contains(p.Ports, port) {
+			return p
+		}
+	}
+	t.Fatalf("no line for %s in %+v", port, s.Ports)
+	return CharterLaytimePort{}
+}
+
+func TestAssessCharterLaytimeAllowances(t *testing.T) {
+	tests := []struct {
+		name          string
+		usage         []charterPortUsage
+		terms         []CharterLaytimeTerm
+		port          string
+		wantSource    string
+		wantAllowed   float64
+		wantDemurrage float64
+		wantDespatch  float64
+	}{
+		{
+			name:       "charter allowance without terms",
+			usage:      []charterPortUsage{{"Santos", 60}},
+			port:       "Santos",
+			wantSource: "charter", wantAllowed: 48, wantDemurrage: 12,
+		},
+		{
+			name:       "port term overrides charter allowance",
+			usage:      []charterPortUsage{{"Santos", 60}},
+			terms:      []CharterLaytimeTerm{laytimeTerm("Santos", 72, false)},
+			port:       "Santos",
+			wantSource: "port", wantAllowed: 72, wantDespatch: 12,
+		},
+		{
+			name:       "term matched case- and space-insensitively",
+			usage:      []charterPortUsage{{" santos", 60}},
+			terms:      []CharterLaytimeTerm{laytimeTerm("Santos ", 72, false)},
+			port:       "Santos",
+			wantSource: "port", wantAllowed: 72, wantDespatch: 12,
+		},
+		{
+			name:       "ports without a term fall back to the charter",
+			usage:      []charterPortUsage{{"Santos", 60}, {"Rotterdam", 50}},
+			terms:      []CharterLaytimeTerm{laytimeTerm("Santos", 72, false)},
+			port:       "Rotterdam",
+			wantSource: "charter", wantAllowed: 48, wantDemurrage: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := assessCharterLaytime(tt.usage, tt.terms, 48, 24000, 12000, false)
+			line := laytimeLine(t, s, tt.port)
+			if line.AllowanceSource != tt.wantSource || line.HoursAllowed != tt.wantAllowed {
+				t.Errorf("allowance = %s %v, want %s %v", line.AllowanceSource, line.HoursAllowed, tt.wantSource, tt.wantAllowed)
+			}
+			if line.DemurrageHours != tt.wantDemurrage || line.DespatchHours != tt.wantDespatch {
+				t.Errorf("demurrage/despatch = %v/%v, want %v/%v",
+					line.DemurrageHours, line.DespatchHours, tt.wantDemurrage, tt.wantDespatch)
+			}
+		})
+	}
+}
+
+func TestAssessCharterLaytimeNothingUsed(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage []charterPortUsage
+		terms []CharterLaytimeTerm
+	}{
+		{"no entries or terms", nil, nil},
+		{"terms but no entries", nil, []CharterLaytimeTerm{laytimeTerm("Santos", 72, false)}},
+		{"entries with no counted time", []charterPortUsage{{"Santos", 0}}, nil},
+	}
+	for _, tt := range tests {
+		for _, reversible := range []bool{false, true} {
+			s := assessCharterLaytime(tt.usage, tt.terms, 48, 24000, 12000, reversible)
+			if s.DespatchHours != 0 || s.DespatchAmount != nil || s.DemurrageHours != 0 || s.BalanceHours != 0 {
+				t.Errorf("%s (reversible %v): despatch %v (%v), demurrage %v, balance %v; want all zero",
+					tt.name, reversible, s.DespatchHours, s.DespatchAmount, s.DemurrageHours, s.BalanceHours)
+			}
+		}
+	}
+}
+
+func TestAssessCharterLaytimeUnusedTermPort(t *testing.T) {
+	usage := []charterPortUsage{{"Santos", 60}}
+	terms := []CharterLaytimeTerm{laytimeTerm("Santos", 72, false), laytimeTerm("Rotterdam", 48, false)}
+	s := assessCharterLaytime(usage, terms, 0, 24000, 12000, false)
+	if got := laytimeLine(t, s, "Rotterdam"); got.DespatchHours != 0 || got.BalanceHours != 0 {
+		t.Errorf("unused port = %+v, want no balance", got)
+	}
+	if s.DespatchHours != 12 {
+		t.Errorf("despatch = %v, want 12 from Santos only", s.DespatchHours)
+	}
+}
+
+func TestAssessCharterLaytimeReversible(t *testing.T) {
+	// Santos saves 12 hours against its term, the un-termed ports use 18
+	// hours more than the charter allows.
+	usage := []charterPortUsage{{"Santos", 60}, {"Rotterdam", 40}, {"Hamburg", 26}}
+	tests := []struct {
+		name          string
+		reversible    bool
+		termRev       bool
+		wantDemurrage float64
+		wantDespatch  float64
+		wantRev       map[string]bool
+	}{
+		{"non-reversible assesses each line", false, true, 18, 12,
+			map[string]bool{"Santos": false, "Rotterdam": false}},
+		{"reversible nets all lines", true, true, 6, 0,
+			map[string]bool{"Santos": true, "Rotterdam": true}},
+		{"reversible keeps non-reversible terms apart", true, false, 18, 12,
+			map[string]bool{"Santos": false, "Rotterdam": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms := []CharterLaytimeTerm{laytimeTerm("Santos", 72, tt.termRev)}
+			s := assessCharterLaytime(usage, terms, 48, 24000, 12000, tt.reversible)
+			if s.Reversible != tt.reversible {
+				t.Errorf("reversible = %v, want %v", s.Reversible, tt.reversible)
+			}
+			if s.TotalHoursUsed != 126 || s.TotalHoursAllowed != 120 {
+				t.Errorf("totals = %v used / %v allowed, want 126 / 120", s.TotalHoursUsed, s.TotalHoursAllowed)
+			}
+			if s.DemurrageHours != tt.wantDemurrage || s.DespatchHours != tt.wantDespatch {
+				t.Errorf("demurrage/despatch = %v/%v, want %v/%v",
+					s.DemurrageHours, s.DespatchHours, tt.wantDemurrage, tt.wantDespatch)
+			}
+			if want := tt.wantDespatch - tt.wantDemurrage; s.BalanceHours != want {
+				t.Errorf("balance = %v, want %v", s.BalanceHours, want)
+			}
+			for port, want := range tt.wantRev {
+				if got := laytimeLine(t, s, port).Reversible; got != want {
+					t.Errorf("%s reversible = %v, want %v", port, got, want)
+				}
+			}
+			if pooled := laytimeLine(t, s, "Rotterdam").Ports; !slices.Equal(pooled, []string{"Hamburg", "Rotterdam"}) {
+				t.Errorf("charter line ports = %v, want Hamburg and Rotterdam", pooled)
+			}
+			wantAmount := tt.wantDemurrage / 24 * 24000
+			if s.DemurrageAmount == nil || *s.DemurrageAmount != wantAmount {
+				t.Errorf("demurrage amount = %v, want %v", s.DemurrageAmount, wantAmount)
+			}
+		})
+	}
+}
+
+func TestAssessCharterLaytimeDespatchAmount(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name       string
+		usage      []charterPortUsage
+		despRate   float64
+		wantRate   *float64
+		wantAmount *float64
+		wantDemurr bool
+	}{
+		{"under allowance owes despatch", []charterPortUsage{{"Santos", 36}}, 12000, f(12000.0), f(6000.0), false},
+		{"custom despatch rate", []charterPortUsage{{"Santos", 36}}, 9600, f(9600.0), f(4800.0), false},
+		{"no despatch rate", []charterPortUsage{{"Santos", 36}}, 0, nil, nil, false},
+		{"over allowance owes demurrage only", []charterPortUsage{{"Santos", 60}}, 12000, nil, nil, true},
+		{"exactly on allowance", []charterPortUsage{{"Santos", 48}}, 12000, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := assessCharterLaytime(tt.usage, nil, 48, 24000, tt.despRate, false)
+			checkPct(t, "despatch rate", s.DespatchRate, tt.wantRate)
+			checkPct(t, "despatch amount", s.DespatchAmount, tt.wantAmount)
+			if (s.DemurrageAmount != nil) != tt.wantDemurr {
+				t.Errorf("demurrage amount = %v, want set %v", s.DemurrageAmount, tt.wantDemurr)
+			}
+		})
+	}
+}
+
+func TestCharterCalcLaytimeDespatchDefault(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("FROM shipman.charter_details WHERE id = $1",
+		dbtest.Rows([]string{"allowed", "dem_rate", "desp_rate", "currency", "reversible"},
+			[]any{48.0, 24000.0, 12000.0, "USD", false}))
+	fake.Return("FROM shipman.laytime_entries", dbtest.Rows([]string{"port_name", "hours"}, []any{"Santos", 24.0}))
+	fake.Return("FROM shipman.charter_laytime_terms", dbtest.Rows([]string{"id", "charter_detail_id", "port_role",
+		"port_name", "allowance_hours", "reversible", "notes", "created_at", "updated_at"}))
+	charterID := uuid.New()
+
+	s, err := NewCharterDetailRepository().CalcLaytime(context.Background(), charterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DespatchHours != 24 || s.DespatchAmount == nil || *s.DespatchAmount != 12000 {
+		t.Errorf("despatch = %v hours, %v amount; want 24 hours at 12000/day", s.DespatchHours, s.DespatchAmount)
+	}
+	q := fake.Calls("FROM shipman.charter_details WHERE id = $1")[0].Query
+	if !strings.Contains(q, "COALESCE(despatch_rate, COALESCE(demurrage_rate, 0) / 2)") {
+		t.Errorf("terms query does not default despatch to half demurrage: %s", q)
+	}
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CharterLaytimeTotals mirrors shipman.charter_laytime_totals, the stored
+// snapshot of a charter's laytime summary used for fast dashboard reads.
+type CharterLaytimeTotals struct {
+	CharterDetailID   uuid.UUID `json:"charter_detail_id"`
+	TotalHoursUsed    float64   `json:"total_hours_used"`
+	TotalHoursAllowed float64   `json:"total_hours_allowed"`
+	BalanceHours      float64   `json:"balance_hours"` // negative = demurrage
+	DemurrageHours    float64   `json:"demurrage_hours"`
+	DespatchHours     float64   `json:"despatch_hours"`
+	DemurrageAmount   *float64  `json:"demurrage_amount,omitempty"`
+	Currency          string    `json:"currency"`
+	ComputedAt        time.Time `json:"computed_at"`
+}
+
+// RecomputeLaytime recalculates the charter's laytime summary from its
+// entries and terms and stores the totals, replacing any previous snapshot.
+// Running it again without changes to the inputs stores the same figures.
+func (repo *CharterDetailRepository) RecomputeLaytime(ctx context.Context, charterID uuid.UUID) (CharterLaytimeTotals, error) {
+	summary, err := repo.CalcLaytime(ctx, charterID)
+	if err != nil {
+		return CharterLaytimeTotals{}, err
+	}
+
+	const query = `
+		INSERT INTO shipman.charter_laytime_totals (
+			charter_detail_id,
+			total_hours_used,
+			total_hours_allowed,
+			balance_hours,
+			demurrage_hours,
+			despatch_hours,
+			demurrage_amount,
+			currency,
+			computed_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, NOW()
+		)
+		ON CONFLICT (charter_detail_id) DO UPDATE SET
+			total_hours_used = EXCLUDED.total_hours_used,
+			total_hours_allowed = EXCLUDED.total_hours_allowed,
+			balance_hours = EXCLUDED.balance_hours,
+			demurrage_hours = EXCLUDED.demurrage_hours,
+			despatch_hours = EXCLUDED.despatch_hours,
+			demurrage_amount = EXCLUDED.demurrage_amount,
+			currency = EXCLUDED.currency,
+			computed_at = EXCLUDED.computed_at
+		RETURNING computed_at
+	`
+
+	totals := CharterLaytimeTotals{
+		CharterDetailID:   charterID,
+		TotalHoursUsed:    summary.TotalHoursUsed,
+		TotalHoursAllowed: summary.TotalHoursAllowed,
+		BalanceHours:      summary.BalanceHours,
+		DemurrageHours:    summary.DemurrageHours,
+		DespatchHours:     summary.DespatchHours,
+		DemurrageAmount:   summary.DemurrageAmount,
+		Currency:          summary.Currency,
+	}
+	err = Conn(ctx).QueryRowContext(
+		ctx,
+		query,
+		totals.CharterDetailID,
+		totals.TotalHoursUsed,
+		totals.TotalHoursAllowed,
+		totals.BalanceHours,
+		totals.DemurrageHours,
+		totals.DespatchHours,
+		nullableFloat(totals.DemurrageAmount),
+		totals.Currency,
+	).Scan(&totals.ComputedAt)
+	if err != nil {
+		return CharterLaytimeTotals{}, err
+	}
+	return totals, nil
+}
+
+// LaytimeTotals returns the stored laytime totals for a charter. It returns
+// sql.ErrNoRows when the charter has never been recomputed.
+func (repo *CharterDetailRepository) LaytimeTotals(ctx context.Context, charterID uuid.UUID) (CharterLaytimeTotals, error) {
+	const query = `
+		SELECT
+			charter_detail_id,
+			total_hours_used,
+			total_hours_allowed,
+			balance_hours,
+			demurrage_hours,
+			despatch_hours,
+			demurrage_amount,
+			currency,
+			computed_at
+		FROM shipman.charter_laytime_totals
+		WHERE charter_detail_id = $1
+	`
+
+	var (
+		totals CharterLaytimeTotals
+		amount sql.NullFloat64
+	)
+	err := Pool.QueryRowContext(ctx, query, charterID).Scan(
+		&totals.CharterDetailID,
+		&totals.TotalHoursUsed,
+		&totals.TotalHoursAllowed,
+		&totals.BalanceHours,
+		&totals.DemurrageHours,
+		&totals.DespatchHours,
+		&amount,
+		&totals.Currency,
+		&totals.ComputedAt,
+	)
+	if err != nil {
+		return CharterLaytimeTotals{}, err
+	}
+	totals.DemurrageAmount = floatPtr(amount)
+	return totals, nil
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var searchColumns = []string{"id", "title", "vessel_name", "counterparty_name", "status", "created_at", "updated_at"}
+
+// stubSearch answers Search from charters: rows in the caller's org whose
+// title, vessel or counterparty match $1, title prefix ($2) matches first,
+// then title matches, then newest first, paged by $3 and $4.
+func stubSearch(t *testing.T, fake *dbtest.Fake, charters []map[string]any) {
+	const rank = `ORDER BY CASE WHEN title ILIKE $2 ESCAPE '\' THEN 0 WHEN title ILIKE $1 ESCAPE '\' THEN 1 ELSE 2 END ASC NULLS LAST, ` +
+		`created_at DESC NULLS LAST, id DESC NULLS LAST`
+	fake.On("FROM shipman.charter_details WHERE (title ILIKE $1", func(call dbtest.Call) dbtest.Result {
+		if !strings.Contains(call.Query, rank) {
+			t.Errorf("unexpected ordering: %s", call.Query)
+		}
+		anywhere, prefix := likePattern(call.Arg(1).(string)), likePattern(call.Arg(2).(string))
+		org := orgArg(call)
+		matches := func(v any) bool { s, ok := v.(string); return ok && anywhere.MatchString(s) }
+		ranked := func(c map[string]any) int {
+			switch {
+			case prefix.MatchString(c["title"].(string)):
+				return 0
+			case matches(c["title"]):
+				return 1
+			}
+			return 2
+		}
+		var hits []map[string]any
+		for _, c := range charters {
+			if org != "" && c["org_id"] != org {
+				continue
+			}
+			if matches(c["title"]) || matches(c["vessel_name"]) || matches(c["counterparty_name"]) {
+				hits = append(hits, c)
+			}
+		}
+		slices.SortStableFunc(hits, func(a, b map[string]any) int {
+			if r := ranked(a) - ranked(b); r != 0 {
+				return r
+			}
+			return b["created_at"].(time.Time).Compare(a["created_at"].(time.Time))
+		})
+		limit, offset := int(call.Arg(3).(int64)), int(call.Arg(4).(int64))
+		hits = hits[min(offset, len(hits)):]
+		hits = hits[:min(limit, len(hits))]
+		out := make([][]any, len(hits))
+		for i, c := range hits {
+			out[i] = dbtest.Row(searchColumns, c)
+		}
+		return dbtest.Rows(searchColumns, out...)
+	})
+}
+
+func TestCharterSearch(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 2, d, 0, 0, 0, 0, time.UTC) }
+	charter := func(title string, vessel, counterparty any, org uuid.UUID, created time.Time) map[string]any {
+		return map[string]any{
+			"id": uuid.NewString(), "org_id": org.String(), "title": title, "vessel_name": vessel,
+			"counterparty_name": counterparty, "status": "active", "created_at": created, "updated_at": created,
+		}
+	}
+	charters := []map[string]any{
+		charter("Grain to Santos", "MV Nordic Star", "Maersk Line", DefaultOrgID, day(1)),
+		charter("Coal charter", "Star of Eden", nil, DefaultOrgID, day(2)),
+		charter("Starboard trial", nil, "Cargill", DefaultOrgID, day(3)),
+		charter("Iron ore", "Pacific Dawn", "Vale", DefaultOrgID, day(4)),
+		charter("Discount 100% charter", nil, nil, DefaultOrgID, day(5)),
+		charter("Star fuel", "Other org vessel", nil, uuid.New(), day(6)),
+	}
+	fake := newFakeDB(t)
+	stubSearch(t, fake, charters)
+	repo := NewCharterDetailRepository()
+	ctx := WithOrg(context.Background(), DefaultOrgID)
+
+	tests := []struct {
+		name          string
+		q             string
+		limit, offset int
+		want          []string
+	}{
+		{"partial vessel, any case", "nordic", 10, 0, []string{"Grain to Santos"}},
+		{"partial counterparty", "CARG", 10, 0, []string{"Starboard trial"}},
+		{"title prefix ranks first", "star", 10, 0, []string{"Starboard trial", "Coal charter", "Grain to Santos"}},
+		{"paged", "star", 1, 1, []string{"Coal charter"}},
+		{"trimmed", "  vale ", 10, 0, []string{"Iron ore"}},
+		{"like characters are literal", "100%", 10, 0, []string{"Discount 100% charter"}},
+		{"underscore is literal", "star_of", 10, 0, nil},
+		{"no match", "bulk carrier", 10, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Search(ctx, tt.q, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, c := range got {
+				titles = append(titles, c.Title)
+			}
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.q, titles, tt.want)
+			}
+		})
+	}
+
+	got, err := repo.Search(ctx, "nordic", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].VesselName == nil || *got[0].VesselName != "MV Nordic Star" || got[0].CounterpartyName == nil || *got[0].CounterpartyName != "Maersk Line" {
+		t.Errorf("matched charter = %+v, want vessel and counterparty filled in", got[0])
+	}
+}
+
+func TestCharterSearchBlankFallsBackToList(t *testing.T) {
+	fake := newFakeDB(t)
+	listed := uuid.New()
+	fake.Return("COUNT(*) OVER () AS total FROM shipman.charter_details", dbtest.Rows(
+		[]string{"id", "title", "status", "created_at", "updated_at", "total"},
+		[]any{listed, "Newest", "draft", time.Now(), time.Now(), int64(1)}))
+
+	got, err := NewCharterDetailRepository().Search(context.Background(), "   ", 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != listed {
+		t.Errorf("blank search = %+v, want the List page", got)
+	}
+	if len(fake.Calls("ILIKE")) != 0 {
+		t.Error("blank search ran a pattern match")
+	}
+	if call := fake.Calls("COUNT(*) OVER ()")[0]; call.Arg(1) != int64(5) {
+		t.Errorf("List limit = %v, want 5", call.Arg(1))
+	}
+}
+
+func TestCharterSearchRejectsDeepOffset(t *testing.T) {
+	fake := newFakeDB(t)
+	_, err := NewCharterDetailRepository().Search(context.Background(), "star", 10, MaxListOffset+1)
+	if !errors.Is(err, ErrOffsetTooLarge) {
+		t.Errorf("err = %v, want ErrOffsetTooLarge", err)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("searched past the offset cap")
+	}
+}
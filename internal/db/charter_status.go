@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// charterStatusTransitions lists the statuses each charter status may move
+// to. An active charter can be sent back to draft and a cancelled one
+// revived as a draft; closed is final.
+var charterStatusTransitions = map[string][]string{
+	"draft":     {"active", "cancelled"},
+	"active":    {"draft", "completed", "closed", "cancelled"},
+	"completed": {"active", "closed"},
+	"closed":    {},
+	"cancelled": {"draft"},
+}
+
+// charterStatusNeedsIdle reports whether moving a charter to status is only
+// safe once nothing under it is still running.
+func charterStatusNeedsIdle(status string) bool {
+	return status == "draft" || status == "cancelled"
+}
+
+// ActiveDependent is a voyage, payment or dispute that blocks or holds up a
+// charter status change.
+type ActiveDependent struct {
+	Type   string    `json:"type"` // voyage, payment or dispute
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+}
+
+// SetStatus changes a charter's status, locking the row for the duration.
+// Moving to draft or cancelled is refused with ErrHasActiveDependents, along
+// with the offending rows, while any voyage is not completed or cancelled or
+// any payment on its voyages is still draft, pending or disputed; force skips
+// that check but not the transition graph. It returns sql.ErrNoRows for an
+// unknown charter, ErrInvalidStatus for a status outside
+// charterStatusTransitions and ErrInvalidTransition when the move is not
+// allowed from the current status.
+func (repo *CharterDetailRepository) SetStatus(ctx context.Context, id uuid.UUID, status string, force bool) ([]ActiveDependent, error) {
+	if _, ok := charterStatusTransitions[status]; !ok {
+		return nil, ErrInvalidStatus
+	}
+
+	var blocking []ActiveDependent
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var current string
+		const lockQuery = `SELECT COALESCE(status, 'draft') FROM shipman.charter_details WHERE id = $1 FOR UPDATE`
+		if err := Conn(ctx).QueryRowContext(ctx, lockQuery, id).Scan(&current); err != nil {
+			return err
+		}
+		if !slices.Contains(charterStatusTransitions[current], status) {
+			return ErrInvalidTransition
+		}
+
+		if charterStatusNeedsIdle(status) && !force {
+			deps, err := activeDependents(ctx, id)
+			if err != nil {
+				return err
+			}
+			if len(deps) > 0 {
+				blocking = deps
+				return ErrHasActiveDependents
+			}
+		}
+
+		const updateQuery = `UPDATE shipman.charter_details SET status = $2, updated_at = NOW() WHERE id = $1`
+		_, err := Conn(ctx).ExecContext(ctx, updateQuery, id, status)
+		return err
+	})
+	charterCache.invalidate(id)
+	return blocking, err
+}
+
+// activeDependents lists the charter's unfinished voyages and open payments.
+func activeDependents(ctx context.Context, charterID uuid.UUID) ([]ActiveDependent, error) {
+	const query = `
+		SELECT kind, id, status FROM (
+			SELECT 'voyage' AS kind, v.id, v.status, v.created_at
+			FROM shipman.voyages v
+			WHERE v.charter_detail_id = $1
+			  AND v.status NOT IN ('completed', 'cancelled')
+			UNION ALL
+			SELECT 'payment', p.id, p.status, p.created_at
+			FROM shipman.voyage_payments p
+			JOIN shipman.voyages v ON v.id = p.voyage_id
+			WHERE v.charter_detail_id = $1
+			  AND p.status IN ('draft', 'pending', 'disputed')
+		) deps
+		ORDER BY created_at, id
+	`
+
+	rows, err := Conn(ctx).QueryContext(ctx, query, charterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ActiveDependent
+	for rows.Next() {
+		var d ActiveDependent
+		if err := rows.Scan(&d.Type, &d.ID, &d.Status); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterStatusTransitions(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{"draft", "active"}:     true,
+		{"draft", "cancelled"}:  true,
+		{"active", "draft"}:     true,
+		{"active", "completed"}: true,
+		{"active", "closed"}:    true,
+		{"active", "cancelled"}: true,
+		{"completed", "active"}: true,
+		{"completed", "closed"}: true,
+		{"cancelled", "draft"}:  true,
+	}
+	for from := range charterStatusTransitions {
+		for to := range charterStatusTransitions {
+			want := allowed[[2]string{from, to}]
+			if got := slices.Contains(charterStatusTransitions[from], to); got != want {
+				t.Errorf("%s -> %s allowed = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestCharterSetStatus(t *testing.T) {
+	voyageID, paymentID := uuid.New(), uuid.New()
+	dependents := dbtest.Rows([]string{"kind", "id", "status"},
+		[]any{"voyage", voyageID, "sailing"},
+		[]any{"payment", paymentID, "pending"},
+	)
+	none := dbtest.Rows([]string{"kind", "id", "status"})
+
+	tests := []struct {
+		name       string
+		current    string // "" for an unknown charter
+		status     string
+		force      bool
+		deps       dbtest.Result
+		wantErr    error
+		wantDeps   int
+		wantUpdate bool
+	}{
+		{"activate draft", "draft", "active", false, none, nil, 0, true},
+		{"complete active", "active", "completed", false, none, nil, 0, true},
+		{"revert idle charter", "active", "draft", false, none, nil, 0, true},
+		{"revert blocked", "active", "draft", false, dependents, ErrHasActiveDependents, 2, false},
+		{"cancel blocked", "active", "cancelled", false, dependents, ErrHasActiveDependents, 2, false},
+		{"revert forced", "active", "draft", true, dependents, nil, 0, true},
+		{"cancel forced", "draft", "cancelled", true, dependents, nil, 0, true},
+		{"reopen closed", "closed", "active", false, none, ErrInvalidTransition, 0, false},
+		{"force does not skip the graph", "closed", "draft", true, none, ErrInvalidTransition, 0, false},
+		{"same status", "active", "active", false, none, ErrInvalidTransition, 0, false},
+		{"unknown status", "draft", "archived", false, none, ErrInvalidStatus, 0, false},
+		{"unknown charter", "", "active", false, none, sql.ErrNoRows, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			lock := dbtest.Rows([]string{"status"})
+			if tt.current != "" {
+				lock = dbtest.Rows([]string{"status"}, []any{tt.current})
+			}
+			fake.Return("FROM shipman.charter_details WHERE id = $1 FOR UPDATE", lock)
+			fake.Return("SELECT kind, id, status FROM", tt.deps)
+			fake.Return("UPDATE shipman.charter_details SET status = $2", dbtest.Affected(1))
+
+			deps, err := NewCharterDetailRepository().SetStatus(context.Background(), uuid.New(), tt.status, tt.force)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(deps) != tt.wantDeps {
+				t.Errorf("dependents = %v, want %d", deps, tt.wantDeps)
+			}
+			updated := len(fake.Calls("UPDATE shipman.charter_details SET status = $2")) == 1
+			if updated != tt.wantUpdate {
+				t.Errorf("updated = %v, want %v", updated, tt.wantUpdate)
+			}
+			if tt.force && len(fake.Calls("SELECT kind, id, status FROM")) != 0 {
+				t.Error("forced change still checked dependents")
+			}
+			if tt.wantUpdate && fake.Commits() != 1 {
+				t.Errorf("commits = %d, want 1", fake.Commits())
+			}
+		})
+	}
+}
+
+func TestCharterSetStatusReportsDependents(t *testing.T) {
+	voyageID := uuid.New()
+	fake := newFakeDB(t)
+	fake.Return("FROM shipman.charter_details WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"status"}, []any{"active"}))
+	fake.Return("SELECT kind, id, status FROM", dbtest.Rows([]string{"kind", "id", "status"}, []any{"voyage", voyageID, "sailing"}))
+
+	deps, err := NewCharterDetailRepository().SetStatus(context.Background(), uuid.New(), "draft", false)
+	if !errors.Is(err, ErrHasActiveDependents) {
+		t.Fatalf("err = %v, want ErrHasActiveDependents", err)
+	}
+	want := []ActiveDependent{{Type: "voyage", ID: voyageID, Status: "sailing"}}
+	if !slices.Equal(deps, want) {
+		t.Errorf("dependents = %v, want %v", deps, want)
+	}
+	if fake.Rollbacks() != 1 {
+		t.Errorf("rollbacks = %d, want 1", fake.Rollbacks())
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Term value sources reported by ResolveCharterTerms.
+const (
+	TermSourceSet     = "set"     // stored on the charter
+	TermSourceDefault = "default" // not stored; the value laytime and payments fall back to
+	TermSourcePort    = "port"    // a per-port laytime term
+)
+
+// TermValue is one resolved commercial term and where it came from.
+type TermValue[T any] struct {
+	Value  T      `json:"value"`
+	Source string `json:"source"`
+}
+
+// PortTerms is the effective laytime allowance at one port with its own term.
+type PortTerms struct {
+	PortName       string             `json:"port_name"`
+	PortRole       *string            `json:"port_role,omitempty"`
+	AllowanceHours TermValue[float64] `json:"allowance_hours"`
+	Reversible     bool               `json:"reversible"`
+	Notes          *string            `json:"notes,omitempty"`
+}
+
+// CharterTerms is the consolidated commercial terms of a charter. Ports
+// without an entry in Ports are assessed against LaytimeAllowanceHours.
+type CharterTerms struct {
+	CharterDetailID       uuid.UUID          `json:"charter_detail_id"`
+	LaytimeAllowanceHours TermValue[float64] `json:"laytime_allowance_hours"`
+	LaytimeReversible     bool               `json:"laytime_reversible"`
+	DemurrageRate         TermValue[float64] `json:"demurrage_rate"`
+	DespatchRate          TermValue[float64] `json:"despatch_rate"`
+	DemurrageCurrency     TermValue[string]  `json:"demurrage_currency"`
+	DefaultCurrency       TermValue[string]  `json:"default_currency"`
+	FuelClause            TermValue[*string] `json:"fuel_clause"`
+	PaymentTerms          TermValue[*string] `json:"payment_terms"`
+	Ports                 []PortTerms        `json:"ports"`
+}
+
+// ResolveCharterTerms merges a charter's own terms with its per-port laytime
+// terms, filling unset values with the defaults CalcLaytime and payment
+// creation apply: no allowance or demurrage, despatch at half the demurrage
+// rate and USD. When several terms name the same port the first one wins,
+// as in CalcLaytime.
+func ResolveCharterTerms(charter CharterDetail, terms []CharterLaytimeTerm) CharterTerms {
+	out := CharterTerms{
+		CharterDetailID:       charter.ID,
+		LaytimeAllowanceHours: resolveTerm(charter.LaytimeAllowanceHours, 0),
+		LaytimeReversible:     charter.LaytimeReversible,
+		DemurrageRate:         resolveTerm(charter.DemurrageRate, 0),
+		DemurrageCurrency:     resolveTerm(trimmedPtr(charter.DemurrageCurrency), "USD"),
+		DefaultCurrency:       resolveTerm(trimmedPtr(charter.DefaultCurrency), "USD"),
+		FuelClause:            optionalTerm(charter.FuelClause),
+		PaymentTerms:          optionalTerm(charter.PaymentTerms),
+		Ports:                 []PortTerms{},
+	}
+	out.DespatchRate = resolveTerm(charter.DespatchRate, out.DemurrageRate.Value/2)
+
+	seen := make(map[string]bool)
+	for _, t := range terms {
+		key := portKey(t.PortName)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out.Ports = append(out.Ports, PortTerms{
+			PortName:       strings.TrimSpace(t.PortName),
+			PortRole:       t.PortRole,
+			AllowanceHours: TermValue[float64]{Value: t.AllowanceHours, Source: TermSourcePort},
+			Reversible:     charter.LaytimeReversible && t.Reversible,
+			Notes:          t.Notes,
+		})
+	}
+	return out
+}
+
+func resolveTerm[T any](v *T, fallback T) TermValue[T] {
+	if v == nil {
+		return TermValue[T]{Value: fallback, Source: TermSourceDefault}
+	}
+	return TermValue[T]{Value: *v, Source: TermSourceSet}
+}
+
+// optionalTerm resolves a free-text term that has no default value.
+func optionalTerm(s *string) TermValue[*string] {
+	if s == nil {
+		return TermValue[*string]{Source: TermSourceDefault}
+	}
+	return TermValue[*string]{Value: s, Source: TermSourceSet}
+}
+
+func trimmedPtr(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
+}
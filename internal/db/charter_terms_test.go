@@ -0,0 +1,90 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveCharterTermsDefaults(t *testing.T) {
+	blank := "  "
+	got := ResolveCharterTerms(CharterDetail{ID: uuid.New(), DefaultCurrency: &blank}, nil)
+
+	if got.LaytimeAllowanceHours != (TermValue[float64]{0, TermSourceDefault}) ||
+		got.DemurrageRate != (TermValue[float64]{0, TermSourceDefault}) ||
+		got.DespatchRate != (TermValue[float64]{0, TermSourceDefault}) {
+		t.Errorf("numeric terms = %+v %+v %+v, want zero defaults",
+			got.LaytimeAllowanceHours, got.DemurrageRate, got.DespatchRate)
+	}
+	if got.DemurrageCurrency != (TermValue[string]{"USD", TermSourceDefault}) ||
+		got.DefaultCurrency != (TermValue[string]{"USD", TermSourceDefault}) {
+		t.Errorf("currencies = %+v %+v, want USD defaults", got.DemurrageCurrency, got.DefaultCurrency)
+	}
+	if got.FuelClause.Value != nil || got.FuelClause.Source != TermSourceDefault ||
+		got.PaymentTerms.Value != nil || got.PaymentTerms.Source != TermSourceDefault {
+		t.Errorf("free-text terms = %+v %+v, want unset defaults", got.FuelClause, got.PaymentTerms)
+	}
+	if got.Ports == nil || len(got.Ports) != 0 {
+		t.Errorf("ports = %v, want an empty list", got.Ports)
+	}
+}
+
+func TestResolveCharterTermsSet(t *testing.T) {
+	allowance, demurrage, despatch := 72.0, 24000.0, 9000.0
+	eur, fuel, payment := " EUR ", "Bunkers for owners", "30 days"
+	charter := CharterDetail{
+		ID: uuid.New(), LaytimeAllowanceHours: &allowance, DemurrageRate: &demurrage,
+		DespatchRate: &despatch, DemurrageCurrency: &eur, FuelClause: &fuel, PaymentTerms: &payment,
+	}
+
+	got := ResolveCharterTerms(charter, nil)
+	if got.CharterDetailID != charter.ID {
+		t.Errorf("charter id = %s, want %s", got.CharterDetailID, charter.ID)
+	}
+	if got.LaytimeAllowanceHours != (TermValue[float64]{72, TermSourceSet}) ||
+		got.DemurrageRate != (TermValue[float64]{24000, TermSourceSet}) ||
+		got.DespatchRate != (TermValue[float64]{9000, TermSourceSet}) {
+		t.Errorf("numeric terms = %+v %+v %+v", got.LaytimeAllowanceHours, got.DemurrageRate, got.DespatchRate)
+	}
+	if got.DemurrageCurrency != (TermValue[string]{"EUR", TermSourceSet}) {
+		t.Errorf("demurrage currency = %+v, want trimmed EUR", got.DemurrageCurrency)
+	}
+	if got.FuelClause.Source != TermSourceSet || *got.FuelClause.Value != fuel ||
+		got.PaymentTerms.Source != TermSourceSet || *got.PaymentTerms.Value != payment {
+		t.Errorf("free-text terms = %+v %+v", got.FuelClause, got.PaymentTerms)
+	}
+
+	charter.DespatchRate = nil
+	if got := ResolveCharterTerms(charter, nil).DespatchRate; got != (TermValue[float64]{12000, TermSourceDefault}) {
+		t.Errorf("despatch rate = %+v, want half the demurrage rate by default", got)
+	}
+}
+
+func TestResolveCharterTermsPorts(t *testing.T) {
+	load := "load"
+	terms := []CharterLaytimeTerm{
+		{PortName: " Santos ", PortRole: &load, AllowanceHours: 72, Reversible: true},
+		{PortName: "Rotterdam", AllowanceHours: 36, Reversible: false},
+		{PortName: "SANTOS", AllowanceHours: 10},
+	}
+	for _, reversible := range []bool{false, true} {
+		got := ResolveCharterTerms(CharterDetail{ID: uuid.New(), LaytimeReversible: reversible}, terms)
+		if len(got.Ports) != 2 {
+			t.Fatalf("ports = %+v, want Santos and Rotterdam once each", got.Ports)
+		}
+		santos, rotterdam := got.Ports[0], got.Ports[1]
+		if santos.PortName != "Santos" || santos.AllowanceHours != (TermValue[float64]{72, TermSourcePort}) ||
+			santos.PortRole == nil || *santos.PortRole != "load" {
+			t.Errorf("santos = %+v, want the first term with its allowance", santos)
+		}
+		if rotterdam.PortName != "Rotterdam" || rotterdam.AllowanceHours.Value != 36 {
+			t.Errorf("rotterdam = %+v", rotterdam)
+		}
+		if santos.Reversible != reversible || rotterdam.Reversible {
+			t.Errorf("reversible = %v/%v on a charter reversible %v", santos.Reversible, rotterdam.Reversible, reversible)
+		}
+		if got.LaytimeAllowanceHours.Source != TermSourceDefault {
+			t.Errorf("charter allowance source = %s, want default next to port terms", got.LaytimeAllowanceHours.Source)
+		}
+	}
+}
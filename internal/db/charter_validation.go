@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultMaxCharterDuration is the longest start-to-end span accepted unless
+// overridden with SetMaxCharterDuration.
+const DefaultMaxCharterDuration = 10 * 365 * 24 * time.Hour
+
+var maxCharterDuration = DefaultMaxCharterDuration
+
+// SetMaxCharterDuration overrides the charter duration limit. A value <= 0
+// disables the limit.
+func SetMaxCharterDuration(d time.Duration) {
+	maxCharterDuration = d
+}
+
+// charterDatesProblem describes what is wrong with the charter's dates, or
+// returns "" when they are acceptable. Either date may be nil.
+func charterDatesProblem(d CharterDetail) string {
+	if d.StartDate == nil || d.EndDate == nil {
+		return ""
+	}
+	if !d.EndDate.After(*d.StartDate) {
+		return "end_date must be after start_date"
+	}
+	if maxCharterDuration > 0 && d.EndDate.Sub(*d.StartDate) > maxCharterDuration {
+		return fmt.Sprintf("charter may not span more than %d days", int(maxCharterDuration.Hours()/24))
+	}
+	return ""
+}
+
+// checkCharterDates is the Create/Update guard for charterDatesProblem.
+func checkCharterDates(d CharterDetail) error {
+	if msg := charterDatesProblem(d); msg != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidCharterDates, msg)
+	}
+	return nil
+}
+
+// FieldError describes one problem with a field of a submitted record.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validate checks a charter detail for missing or inconsistent fields without
+// touching the database. It returns nil when the charter is valid.
+func (d CharterDetail) Validate() []FieldError {
+	var issues []FieldError
+	add := func(field, msg string) {
+		issues = append(issues, FieldError{Field: field, Message: msg})
+	}
+
+	if strings.TrimSpace(d.Title) == "" {
+		add("title", "title is required")
+	}
+	if msg := charterDatesProblem(d); msg != "" {
+		add("end_date", msg)
+	}
+	if d.LaytimeAllowanceHours != nil && *d.LaytimeAllowanceHours < 0 {
+		add("laytime_allowance_hours", "laytime_allowance_hours must not be negative")
+	}
+	if d.DemurrageRate != nil && *d.DemurrageRate < 0 {
+		add("demurrage_rate", "demurrage_rate must not be negative")
+	}
+	if d.DespatchRate != nil && *d.DespatchRate < 0 {
+		add("despatch_rate", "despatch_rate must not be negative")
+	}
+
+	currency := ""
+	if d.DemurrageCurrency != nil {
+		currency = strings.TrimSpace(*d.DemurrageCurrency)
+	}
+	switch {
+	case currency != "" && !isCurrencyCode(currency):
+		add("demurrage_currency", "demurrage_currency must be a three-letter ISO 4217 code")
+	case currency == "" && d.DemurrageRate != nil:
+		add("demurrage_currency", "demurrage_currency is required when demurrage_rate is set")
+	}
+
+	return issues
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
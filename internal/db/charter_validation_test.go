@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterDetailValidate(t *testing.T) {
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	after, before := start.AddDate(0, 1, 0), start.AddDate(0, 0, -1)
+	tooLong := start.Add(DefaultMaxCharterDuration + 24*time.Hour)
+	rate, negative := 12000.0, -1.0
+	usd, lower, blank := "USD", "usd", " "
+
+	tests := []struct {
+		name       string
+		charter    CharterDetail
+		wantFields []string
+	}{
+		{"valid", CharterDetail{Title: "Grain", StartDate: &start, EndDate: &after, DemurrageRate: &rate, DemurrageCurrency: &usd}, nil},
+		{"title only", CharterDetail{Title: "Grain"}, nil},
+		{"open-ended dates", CharterDetail{Title: "Grain", StartDate: &start}, nil},
+		{"blank title", CharterDetail{Title: "  "}, []string{"title"}},
+		{"end before start", CharterDetail{Title: "Grain", StartDate: &start, EndDate: &before}, []string{"end_date"}},
+		{"end equals start", CharterDetail{Title: "Grain", StartDate: &start, EndDate: &start}, []string{"end_date"}},
+		{"too long", CharterDetail{Title: "Grain", StartDate: &start, EndDate: &tooLong}, []string{"end_date"}},
+		{"rate without currency", CharterDetail{Title: "Grain", DemurrageRate: &rate}, []string{"demurrage_currency"}},
+		{"rate with blank currency", CharterDetail{Title: "Grain", DemurrageRate: &rate, DemurrageCurrency: &blank}, []string{"demurrage_currency"}},
+		{"lowercase currency", CharterDetail{Title: "Grain", DemurrageCurrency: &lower}, []string{"demurrage_currency"}},
+		{
+			"every problem at once",
+			CharterDetail{
+				StartDate: &start, EndDate: &before,
+				LaytimeAllowanceHours: &negative, DemurrageRate: &negative, DespatchRate: &negative,
+			},
+			[]string{"title", "end_date", "laytime_allowance_hours", "demurrage_rate", "despatch_rate", "demurrage_currency"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, issue := range tt.charter.Validate() {
+				if issue.Message == "" {
+					t.Errorf("%s has no message", issue.Field)
+				}
+				fields = append(fields, issue.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestSetMaxCharterDuration(t *testing.T) {
+	t.Cleanup(func() { SetMaxCharterDuration(DefaultMaxCharterDuration) })
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 3)
+	charter := CharterDetail{Title: "Grain", StartDate: &start, EndDate: &end}
+
+	SetMaxCharterDuration(48 * time.Hour)
+	if issues := charter.Validate(); len(issues) != 1 || issues[0].Field != "end_date" {
+		t.Errorf("issues = %v, want end_date over the 2 day limit", issues)
+	}
+	SetMaxCharterDuration(0)
+	if issues := charter.Validate(); issues != nil {
+		t.Errorf("issues = %v with the limit disabled, want none", issues)
+	}
+}
+
+func TestCharterCreateUpdateDates(t *testing.T) {
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	after, before := start.AddDate(0, 1, 0), start.AddDate(0, 0, -1)
+	tooLong := start.Add(DefaultMaxCharterDuration + 24*time.Hour)
+
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		wantErr    error
+	}{
+		{"valid range", &start, &after, nil},
+		{"no dates", nil, nil, nil},
+		{"start only", &start, nil, nil},
+		{"end only", nil, &before, nil},
+		{"inverted", &start, &before, ErrInvalidCharterDates},
+		{"same day", &start, &start, ErrInvalidCharterDates},
+		{"over the limit", &start, &tooLong, ErrInvalidCharterDates},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("INSERT INTO shipman.charter_details", dbtest.Rows(
+				[]string{"id", "status", "ai_status", "created_at", "updated_at"},
+				[]any{uuid.New(), "draft", "pending", time.Now(), time.Now()},
+			))
+			fake.Return("UPDATE shipman.charter_details", dbtest.Rows([]string{"updated_at"}, []any{time.Now()}))
+			repo := NewCharterDetailRepository()
+
+			created := CharterDetail{Title: "Grain", StartDate: tt.start, EndDate: tt.end}
+			if err := repo.Create(context.Background(), &created); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create err = %v, want %v", err, tt.wantErr)
+			}
+			updated := CharterDetail{ID: uuid.New(), Title: "Grain", Status: "draft", StartDate: tt.start, EndDate: tt.end}
+			if err := repo.Update(context.Background(), &updated); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update err = %v, want %v", err, tt.wantErr)
+			}
+			wantCalls := 2
+			if tt.wantErr != nil {
+				wantCalls = 0
+			}
+			if calls := fake.Calls(""); len(calls) != wantCalls {
+				t.Errorf("made %d statements, want %d", len(calls), wantCalls)
+			}
+		})
+	}
+}
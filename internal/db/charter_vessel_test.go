@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterListByVesselName(t *testing.T) {
+	fake := newFakeDB(t)
+	direct, viaVoyage := uuid.New(), uuid.New()
+	fake.Return("FROM shipman.charter_details cd WHERE (lower(trim(cd.vessel_name))", dbtest.Rows(
+		[]string{"id", "title", "vessel_name", "status", "created_at", "updated_at"},
+		[]any{direct, "Direct", "Ocean Star", "active", time.Now(), time.Now()},
+		[]any{viaVoyage, "Via voyage", nil, "draft", time.Now(), time.Now()},
+	))
+
+	got, err := NewCharterDetailRepository().ListByVesselName(context.Background(), "Ocean Star", Page{Limit: 20, Offset: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != direct || got[1].ID != viaVoyage {
+		t.Fatalf("charters = %+v, want direct then via voyage", got)
+	}
+	if got[0].VesselName == nil || *got[0].VesselName != "Ocean Star" || got[1].VesselName != nil {
+		t.Errorf("vessel names = %v, %v; want Ocean Star, nil", got[0].VesselName, got[1].VesselName)
+	}
+
+	call := fake.Calls("FROM shipman.charter_details cd")[0]
+	if call.Arg(1) != "Ocean Star" || call.Arg(2) != int64(20) || call.Arg(3) != int64(40) {
+		t.Errorf("args = %v, want name, limit 20, offset 40", call.Args)
+	}
+	// Voyages are matched with EXISTS rather than joined, so a charter
+	// reached both ways, or through several voyages, comes back once.
+	if !strings.Contains(call.Query, "EXISTS ( SELECT 1 FROM shipman.voyages v") || strings.Contains(call.Query, "JOIN") {
+		t.Errorf("query does not match voyages through EXISTS: %s", call.Query)
+	}
+	if !strings.Contains(call.Query, "ORDER BY cd.created_at DESC") {
+		t.Errorf("query is not newest first: %s", call.Query)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateCharterWithVoyage(t *testing.T) {
+	const (
+		charterInsert = "INSERT INTO shipman.charter_details"
+		voyageInsert  = "INSERT INTO shipman.voyages"
+	)
+	long := strings.Repeat("x", MaxNotesLength+1)
+	boom := errors.New("voyages_status_check")
+
+	tests := []struct {
+		name           string
+		notes          *string
+		failVoyage     bool
+		wantErr        error
+		wantVoyageRuns int
+	}{
+		{"both created", nil, false, nil, 1},
+		{"invalid voyage", &long, false, ErrNotesTooLong, 0},
+		{"voyage insert fails", nil, true, boom, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubReturning(fake, uuid.New(), time.Now())
+			if tt.failVoyage {
+				fake.Return(voyageInsert, dbtest.Fail(boom))
+			}
+
+			charter := &CharterDetail{Title: "Grain charter", OrgID: DefaultOrgID}
+			voyage := &Voyage{Notes: tt.notes}
+			err := NewCharterDetailRepository().CreateCharterWithVoyage(context.Background(), charter, voyage)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if n := len(fake.Calls(charterInsert)); n != 1 {
+				t.Errorf("charter inserts = %d, want 1", n)
+			}
+			voyageCalls := fake.Calls(voyageInsert)
+			if len(voyageCalls) != tt.wantVoyageRuns {
+				t.Errorf("voyage inserts = %d, want %d", len(voyageCalls), tt.wantVoyageRuns)
+			}
+
+			if tt.wantErr != nil {
+				if fake.Commits() != 0 || fake.Rollbacks() != 1 {
+					t.Errorf("commits %d, rollbacks %d; want the charter rolled back", fake.Commits(), fake.Rollbacks())
+				}
+				if charter.ID != uuid.Nil || voyage.ID != uuid.Nil || voyage.CharterDetailID != nil {
+					t.Errorf("ids left set after rollback: charter %s, voyage %s on %v", charter.ID, voyage.ID, voyage.CharterDetailID)
+				}
+				return
+			}
+			if fake.Commits() != 1 || fake.Rollbacks() != 0 {
+				t.Errorf("commits %d, rollbacks %d; want one commit", fake.Commits(), fake.Rollbacks())
+			}
+			if charter.ID == uuid.Nil || voyage.CharterDetailID == nil || *voyage.CharterDetailID != charter.ID {
+				t.Errorf("voyage charter = %v, want %s", voyage.CharterDetailID, charter.ID)
+			}
+			if got := voyageCalls[0].Arg(1); got != charter.ID.String() {
+				t.Errorf("voyage inserted under charter %v, want %s", got, charter.ID)
+			}
+			if voyage.OrgID != charter.OrgID {
+				t.Errorf("voyage org = %s, want the charter's %s", voyage.OrgID, charter.OrgID)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package db
+
+import "time"
+
+// Clock supplies the current time for application-level comparisons such as
+// overdue and expiry checks. Row timestamps (created_at/updated_at) still come
+// from the database's NOW().
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// FixedClock always reports the same instant.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+var clock Clock = realClock{}
+
+// SetClock replaces the package clock. Passing nil restores the real clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+}
+
+func now() time.Time {
+	return clock.Now()
+}
+
+// Now reports the current time from the package clock. Handlers use it for
+// the same application-level times so a test clock covers them too.
+func Now() time.Time {
+	return now()
+}
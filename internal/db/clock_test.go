@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+)
+
+// freezeClock sets the package clock to at for the rest of the test.
+func freezeClock(t *testing.T, at time.Time) {
+	t.Helper()
+	SetClock(FixedClock(at))
+	t.Cleanup(func() { SetClock(nil) })
+}
+
+func TestListOverdueUsesClock(t *testing.T) {
+	tests := []struct {
+		name  string
+		clock time.Time
+		today string
+	}{
+		{"utc", time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), "2026-03-01"},
+		{"evening west of utc is already tomorrow", time.Date(2026, 3, 1, 21, 0, 0, 0, time.FixedZone("EST", -5*3600)), "2026-03-02"},
+		{"morning east of utc is still yesterday", time.Date(2026, 3, 1, 2, 0, 0, 0, time.FixedZone("JST", 9*3600)), "2026-02-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			freezeClock(t, tt.clock)
+			fake.Return("FROM shipman.voyage_payments WHERE due_date < $1", dbtest.Rows(nil))
+
+			if _, err := NewPaymentRepository().ListOverdue(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			calls := fake.Calls("WHERE due_date < $1")
+			if len(calls) != 1 || calls[0].Arg(1) != tt.today {
+				t.Fatalf("overdue cutoff = %v, want %s", calls, tt.today)
+			}
+		})
+	}
+}
+
+func TestListExpiringUsesClock(t *testing.T) {
+	fake := newFakeDB(t)
+	freezeClock(t, time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC))
+	fake.Return("FROM shipman.charter_details WHERE end_date IS NOT NULL", dbtest.Rows(nil))
+
+	if _, err := NewCharterDetailRepository().ListExpiring(context.Background(), 14); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls("end_date >= $1::date")
+	if len(calls) != 1 {
+		t.Fatalf("expiring queried %d times, want 1", len(calls))
+	}
+	if got := calls[0].Arg(1); got != "2026-12-31" {
+		t.Errorf("window start = %v, want 2026-12-31", got)
+	}
+	if got := calls[0].Arg(2); got != int64(14) {
+		t.Errorf("window days = %v, want 14", got)
+	}
+}
+
+func TestSetClockNilRestoresRealClock(t *testing.T) {
+	freezeClock(t, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	if got := Now(); got.Year() != 2000 {
+		t.Fatalf("Now() = %v, want the frozen clock", got)
+	}
+	SetClock(nil)
+	if got := Now(); time.Since(got) > time.Minute {
+		t.Errorf("Now() = %v after SetClock(nil), want the real time", got)
+	}
+}
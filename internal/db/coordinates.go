@@ -0,0 +1,74 @@
+package db
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// DefaultCoordinatePrecision is the number of decimal places latitude and
+// longitude are rendered with in JSON unless overridden; 6 places is about
+// 0.1 m.
+const DefaultCoordinatePrecision = 6
+
+// CoordinatePrecision is the number of decimal places coordinates are
+// rounded to when marshalled. Stored values keep full precision.
+var CoordinatePrecision = DefaultCoordinatePrecision
+
+// SetCoordinatePrecision overrides the JSON coordinate precision. Values
+// outside 0-15 restore the default.
+func SetCoordinatePrecision(n int) {
+	if n < 0 || n > 15 {
+		n = DefaultCoordinatePrecision
+	}
+	CoordinatePrecision = n
+}
+
+// coordinate marshals a latitude or longitude rounded to CoordinatePrecision
+// decimal places, so float noise like 1.2300000000001 is not sent to clients.
+type coordinate float64
+
+func (v coordinate) MarshalJSON() ([]byte, error) {
+	scale := math.Pow10(CoordinatePrecision)
+	rounded := math.Round(float64(v)*scale) / scale
+	return strconv.AppendFloat(nil, rounded, 'f', -1, 64), nil
+}
+
+// coordinatePtr converts an optional coordinate for marshalling.
+func coordinatePtr(v *float64) *coordinate {
+	if v == nil {
+		return nil
+	}
+	c := coordinate(*v)
+	return &c
+}
+
+// MarshalJSON renders the position with rounded coordinates.
+func (pos ShipPosition) MarshalJSON() ([]byte, error) {
+	type plain ShipPosition
+	return json.Marshal(struct {
+		plain
+		Latitude  coordinate `json:"latitude"`
+		Longitude coordinate `json:"longitude"`
+	}{plain(pos), coordinate(pos.Latitude), coordinate(pos.Longitude)})
+}
+
+// MarshalJSON renders the port with rounded coordinates.
+func (port VoyagePort) MarshalJSON() ([]byte, error) {
+	type plain VoyagePort
+	return json.Marshal(struct {
+		plain
+		Latitude  *coordinate `json:"latitude,omitempty"`
+		Longitude *coordinate `json:"longitude,omitempty"`
+	}{plain(port), coordinatePtr(port.Latitude), coordinatePtr(port.Longitude)})
+}
+
+// MarshalJSON renders the fleet position with rounded coordinates.
+func (p FleetPosition) MarshalJSON() ([]byte, error) {
+	type plain FleetPosition
+	return json.Marshal(struct {
+		plain
+		Latitude  coordinate `json:"latitude"`
+		Longitude coordinate `json:"longitude"`
+	}{plain(p), coordinate(p.Latitude), coordinate(p.Longitude)})
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCoordinateMarshalJSON(t *testing.T) {
+	t.Cleanup(func() { SetCoordinatePrecision(DefaultCoordinatePrecision) })
+	tests := []struct {
+		precision int
+		in        float64
+		want      string
+	}{
+		{6, 1.2300000000001, "1.23"},
+		{6, 51.92250049999, "51.9225"},
+		{6, -4.1234567, "-4.123457"},
+		{6, 103.8, "103.8"},
+		{6, 0, "0"},
+		{2, 51.9225, "51.92"},
+		{0, 51.9225, "52"},
+		{-1, 1.23456789, "1.234568"},
+		{16, 1.23456789, "1.234568"},
+	}
+	for _, tt := range tests {
+		SetCoordinatePrecision(tt.precision)
+		got, err := json.Marshal(coordinate(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("precision %d: %v marshalled as %s, want %s", tt.precision, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCoordinatePrecisionInResponses(t *testing.T) {
+	lat, lon := 1.2300000000001, 103.85199999999
+	id := uuid.New()
+	recorded := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	pos := ShipPosition{ID: id, VoyageID: id, RecordedAt: recorded, Latitude: lat, Longitude: lon, Source: "ais"}
+	port := VoyagePort{ID: id, VoyageID: id, PortName: "Singapore", Latitude: &lat, Longitude: &lon}
+	fleet := FleetPosition{VoyageID: id, PositionID: id, RecordedAt: recorded, Latitude: lat, Longitude: lon}
+
+	for name, v := range map[string]any{"position": pos, "position pointer": &pos, "port": port, "fleet position": fleet} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := string(raw)
+		if !strings.Contains(body, `"latitude":1.23,`) || !strings.Contains(body, `"longitude":103.852`) {
+			t.Errorf("%s: %s, want coordinates rounded to 6 places", name, body)
+		}
+		if strings.Count(body, `"latitude"`) != 1 {
+			t.Errorf("%s: %s, want latitude once", name, body)
+		}
+	}
+	if pos.Latitude != lat || *port.Longitude != lon {
+		t.Error("marshalling changed the stored coordinates")
+	}
+
+	raw, err := json.Marshal(ShipPosition{ID: id, VoyageID: id, RecordedAt: recorded, Latitude: lat, Longitude: lon, Source: "ais"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back ShipPosition
+	if err := json.Unmarshal(raw, &back); err != nil || back.ID != id || back.Source != "ais" || !back.RecordedAt.Equal(recorded) {
+		t.Errorf("round trip = %+v, %v; want the other fields kept", back, err)
+	}
+
+	bare, err := json.Marshal(VoyagePort{ID: id, VoyageID: id, PortName: "Santos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bare), "latitude") || strings.Contains(string(bare), "longitude") {
+		t.Errorf("port without coordinates = %s, want them omitted", bare)
+	}
+}
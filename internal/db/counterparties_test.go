@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+)
+
+// likePattern turns an ILIKE pattern escaped with a backslash into an
+// anchored, case-insensitive regexp.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// stubCounterparties answers DistinctCounterparties from the counterparty
+// names stored on charters, nil meaning NULL.
+func stubCounterparties(fake *dbtest.Fake, stored []any) {
+	fake.On("SELECT DISTINCT ON (lower(name)) name", func(call dbtest.Call) dbtest.Result {
+		match := likePattern(call.Arg(1).(string))
+		seen := map[string]string{}
+		for _, v := range stored {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			name := strings.TrimSpace(s)
+			if name == "" || !match.MatchString(name) {
+				continue
+			}
+			key := strings.ToLower(name)
+			if prev, dup := seen[key]; !dup || name < prev {
+				seen[key] = name
+			}
+		}
+		keys := make([]string, 0, len(seen))
+		for k := range seen {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if limit := int(call.Arg(2).(int64)); len(keys) > limit {
+			keys = keys[:limit]
+		}
+		rows := make([][]any, len(keys))
+		for i, k := range keys {
+			rows[i] = []any{seen[k]}
+		}
+		return dbtest.Rows([]string{"name"}, rows...)
+	})
+}
+
+func TestDistinctCounterparties(t *testing.T) {
+	stored := []any{
+		"Maersk Line", "maersk line", "  Maersk Line  ", "Marubeni", "MSC", nil, "",
+		"Cargill", "100% Shipping", "100 Ships", "A_B Trading", "AxB Trading",
+	}
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"case-insensitive prefix", "ma", 10, []string{"Maersk Line", "Marubeni"}},
+		{"upper-case prefix", "MAR", 10, []string{"Marubeni"}},
+		{"percent matched literally", "100%", 10, []string{"100% Shipping"}},
+		{"underscore matched literally", "a_b", 10, []string{"A_B Trading"}},
+		{"limit", "m", 2, []string{"Maersk Line", "Marubeni"}},
+		{"no match", "zim", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCounterparties(fake, stored)
+
+			got, err := NewCharterDetailRepository().DistinctCounterparties(context.Background(), tt.prefix, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("names = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
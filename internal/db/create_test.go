@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var returningClause = regexp.MustCompile(`RETURNING ([\w, ]+?)\s*$`)
+
+// stubReturning answers every INSERT with one row for its RETURNING list:
+// id gets id, *_at columns get at, org_id the default org and anything else
+// a placeholder string.
+func stubReturning(fake *dbtest.Fake, id uuid.UUID, at time.Time) {
+	fake.On("INSERT INTO", func(call dbtest.Call) dbtest.Result {
+		m := returningClause.FindStringSubmatch(call.Query)
+		if m == nil {
+			return dbtest.Affected(1)
+		}
+		var columns []string
+		var row []any
+		for _, col := range strings.Split(m[1], ",") {
+			col = strings.TrimSpace(col)
+			columns = append(columns, col)
+			switch {
+			case col == "id":
+				row = append(row, id)
+			case col == "org_id":
+				row = append(row, DefaultOrgID)
+			case strings.HasSuffix(col, "_at"):
+				row = append(row, at)
+			default:
+				row = append(row, "server")
+			}
+		}
+		return dbtest.Rows(columns, row)
+	})
+}
+
+func TestCreateIgnoresClientIDsAndTimestamps(t *testing.T) {
+	clientID := uuid.New()
+	clientAt := time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// Each case creates a record whose ID and timestamps were set by the
+	// client and returns where the server values should have landed.
+	tests := []struct {
+		name   string
+		create func(t *testing.T) (id *uuid.UUID, stamps []*time.Time)
+	}{
+		{"charter", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			d := &CharterDetail{ID: clientID, Title: "Grain", CreatedAt: clientAt, UpdatedAt: clientAt}
+			mustCreate(t, NewCharterDetailRepository().Create(ctx, d))
+			return &d.ID, []*time.Time{&d.CreatedAt, &d.UpdatedAt}
+		}},
+		{"dispute", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			d := &Dispute{ID: clientID, CharterDetailID: uuid.New(), Subject: "Claim", SettledAt: &clientAt, CreatedAt: clientAt, UpdatedAt: clientAt}
+			mustCreate(t, NewDisputeRepository().Create(ctx, d))
+			if d.SettledAt != nil {
+				t.Errorf("settled_at = %v, want it cleared", d.SettledAt)
+			}
+			return &d.ID, []*time.Time{&d.CreatedAt, &d.UpdatedAt}
+		}},
+		{"demurrage record", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			r := &DemurrageRecord{ID: clientID, CharterDetailID: uuid.New(), CreatedAt: clientAt, UpdatedAt: clientAt}
+			mustCreate(t, NewDemurrageRecordRepository().Create(ctx, r))
+			return &r.ID, []*time.Time{&r.CreatedAt, &r.UpdatedAt}
+		}},
+		{"payment", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			session := "cs_client"
+			p := &VoyagePayment{ID: clientID, VoyageID: uuid.New(), PaymentType: "hire", Amount: 10, Currency: "USD",
+				PaidAt: &clientAt, CoinsubSessionID: &session, CreatedAt: clientAt, UpdatedAt: clientAt}
+			mustCreate(t, NewPaymentRepository().Create(ctx, p))
+			if p.PaidAt != nil || p.CoinsubSessionID != nil {
+				t.Errorf("paid_at/session = %v/%v, want them cleared", p.PaidAt, p.CoinsubSessionID)
+			}
+			return &p.ID, []*time.Time{&p.CreatedAt, &p.UpdatedAt}
+		}},
+		{"bill of lading", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			b := &BillOfLading{ID: clientID, CharterDetailID: uuid.New(), DocumentNumber: "BL-1", CreatedAt: clientAt, UpdatedAt: clientAt}
+			mustCreate(t, NewBillOfLadingRepository().Create(ctx, b))
+			return &b.ID, []*time.Time{&b.CreatedAt, &b.UpdatedAt}
+		}},
+		{"vessel", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			v := &Vessel{ID: clientID, Name: "Ever Given", CreatedAt: clientAt, UpdatedAt: clientAt}
+			mustCreate(t, NewVesselRepository().Create(ctx, v))
+			return &v.ID, []*time.Time{&v.CreatedAt, &v.UpdatedAt}
+		}},
+		{"demurrage document", func(t *testing.T) (*uuid.UUID, []*time.Time) {
+			d := &DemurrageDocument{ID: clientID, DemurrageRecordID: uuid.New(), URI: "s3://x", CreatedAt: clientAt}
+			mustCreate(t, NewDemurrageDocumentRepository().Create(ctx, d))
+			return &d.ID, []*time.Time{&d.CreatedAt}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			serverID, serverAt := uuid.New(), time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			stubReturning(fake, serverID, serverAt)
+
+			id, stamps := tt.create(t)
+			if *id != serverID {
+				t.Errorf("id = %s, want the server's %s", *id, serverID)
+			}
+			for i, at := range stamps {
+				if !at.Equal(serverAt) {
+					t.Errorf("timestamp %d = %s, want the server's %s", i, at, serverAt)
+				}
+			}
+			for _, call := range fake.Calls("INSERT INTO") {
+				for i, arg := range call.Args {
+					if arg == clientID.String() {
+						t.Errorf("client id bound as insert argument %d", i+1)
+					}
+					if at, ok := arg.(time.Time); ok && at.Equal(clientAt) {
+						t.Errorf("client timestamp bound as insert argument %d", i+1)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestClearServerFields(t *testing.T) {
+	id := uuid.New()
+	created, updated := time.Now(), time.Now()
+	clearServerFields(&id, &created, &updated)
+	if id != uuid.Nil || !created.IsZero() || !updated.IsZero() {
+		t.Errorf("id/created/updated = %s/%s/%s, want zero values", id, created, updated)
+	}
+}
+
+func mustCreate(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
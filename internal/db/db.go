@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"sync"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -10,14 +11,20 @@ import (
 
 var Pool *sql.DB
 
+// Pool limits applied by Open.
+const (
+	DBMaxOpenConns = 15
+	DBMaxIdleConns = 5
+)
+
 func Open(dsn string) (*sql.DB, error) {
 	conn, err := sql.Open("pgx", dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	conn.SetMaxOpenConns(15)
-	conn.SetMaxIdleConns(5)
+	conn.SetMaxOpenConns(DBMaxOpenConns)
+	conn.SetMaxIdleConns(DBMaxIdleConns)
 	conn.SetConnMaxLifetime(2 * time.Hour)
 
 	return conn, nil
@@ -32,3 +39,131 @@ func Ping(db *sql.DB) error {
 	defer cancel()
 	return db.PingContext(ctx)
 }
+
+// Warmup opens up to n connections concurrently, pings each, and returns
+// them to db's idle pool so the first requests do not pay connection setup.
+// All connections are held until every one is open; otherwise the pool would
+// hand the same connection back out. It returns how many were warmed and the
+// first error, if any.
+func Warmup(ctx context.Context, db *sql.DB, n int) (int, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		conns    []*sql.Conn
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err == nil {
+				if err = conn.PingContext(ctx); err != nil {
+					conn.Close()
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	return len(conns), firstErr
+}
+
+// Querier is satisfied by both *sql.DB and *sql.Tx.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// Tx is a transaction started by WithTx or BeginTx. Commit runs the
+// functions queued with AfterCommit once the commit succeeds.
+type Tx struct {
+	*sql.Tx
+
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+// Commit commits the transaction and then runs the AfterCommit functions.
+func (tx *Tx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	tx.mu.Lock()
+	fns := tx.afterCommit
+	tx.afterCommit = nil
+	tx.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the transaction bound to ctx commits, or right
+// away when ctx carries none. Side effects that must not outlive a rollback,
+// such as metrics, go through it.
+func AfterCommit(ctx context.Context, fn func()) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok {
+		fn()
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.afterCommit = append(tx.afterCommit, fn)
+}
+
+// WithTx runs fn inside a transaction. Repository calls made with the ctx
+// passed to fn join that transaction; it is committed when fn returns nil and
+// rolled back otherwise. Nested calls reuse the outer transaction.
+func WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, tx, err := BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BeginTx starts a transaction on Pool and returns a context carrying it, so
+// repository calls made with that context join the transaction. The caller
+// owns the *Tx and must commit or roll it back.
+func BeginTx(ctx context.Context) (context.Context, *Tx, error) {
+	sqlTx, err := Pool.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, nil, err
+	}
+	tx := &Tx{Tx: sqlTx}
+	return context.WithValue(ctx, txKey{}, tx), tx, nil
+}
+
+// Conn returns the transaction bound to ctx by WithTx or BeginTx, or Pool.
+func Conn(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return tx.Tx
+	}
+	return Pool
+}
@@ -0,0 +1,343 @@
+// Package dbtest provides a scripted database/sql driver so repository and
+// handler tests can run without a Postgres server. Tests register rules that
+// match statements by substring and return rows, a rows-affected count or an
+// error; every statement is recorded for later assertions.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Call is one statement the code under test sent to the database.
+type Call struct {
+	Query string
+	Args  []any
+	// Tx is the transaction the statement ran in, or nil outside one.
+	Tx *Tx
+}
+
+// Arg returns the nth (1-based, like $n) argument.
+func (c Call) Arg(n int) any {
+	return c.Args[n-1]
+}
+
+// Result is what a rule answers with. Queries read Columns and Rows; Execs
+// read RowsAffected. A non-nil Err fails the statement.
+type Result struct {
+	Columns      []string
+	Rows         [][]any
+	RowsAffected int64
+	Err          error
+}
+
+// Rows builds a query Result with the given columns and rows.
+func Rows(columns []string, rows ...[]any) Result {
+	return Result{Columns: columns, Rows: rows}
+}
+
+// Row lays values out in columns order, leaving columns without a value
+// NULL. It keeps wide rows readable when a test only cares about a few
+// columns.
+func Row(columns []string, values map[string]any) []any {
+	row := make([]any, len(columns))
+	for i, col := range columns {
+		row[i] = values[col]
+	}
+	return row
+}
+
+// Affected builds an exec Result reporting n affected rows.
+func Affected(n int64) Result {
+	return Result{RowsAffected: n}
+}
+
+// Fail builds a Result that fails the statement with err.
+func Fail(err error) Result {
+	return Result{Err: err}
+}
+
+type rule struct {
+	match string
+	fn    func(Call) Result
+}
+
+// Fake is a scripted database. The zero value is not usable; call New.
+type Fake struct {
+	mu        sync.Mutex
+	rules     []rule
+	calls     []Call
+	commits   int
+	rollbacks int
+	commitErr error
+	connErr   error
+}
+
+// New returns an empty Fake. Statements no rule matches fail.
+func New() *Fake {
+	return &Fake{}
+}
+
+// On answers statements containing match with fn. Whitespace in both the
+// statement and match is collapsed before comparing. Later rules take
+// precedence over earlier ones, so a test can override a shared setup.
+func (f *Fake) On(match string, fn func(Call) Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rule{match: collapse(match), fn: fn})
+}
+
+// Return answers statements containing match with res.
+func (f *Fake) Return(match string, res Result) {
+	f.On(match, func(Call) Result { return res })
+}
+
+// Open returns a *sql.DB backed by f.
+func (f *Fake) Open() *sql.DB {
+	return sql.OpenDB(connector{f})
+}
+
+// Calls returns the recorded statements containing match, in order. An
+// empty match returns every statement.
+func (f *Fake) Calls(match string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	match = collapse(match)
+	var out []Call
+	for _, c := range f.calls {
+		if strings.Contains(c.Query, match) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// FailCommits makes every later commit fail with err. The transaction is
+// counted as rolled back, as Postgres would abort it.
+func (f *Fake) FailCommits(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commitErr = err
+}
+
+// FailConnects makes every later new connection fail with err, as an
+// unreachable server would. Connections already pooled keep working.
+func (f *Fake) FailConnects(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connErr = err
+}
+
+// Commits reports how many transactions were committed.
+func (f *Fake) Commits() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commits
+}
+
+// Rollbacks reports how many transactions were rolled back.
+func (f *Fake) Rollbacks() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rollbacks
+}
+
+func (f *Fake) run(query string, args []driver.NamedValue, tx *Tx) (Result, error) {
+	call := Call{Query: collapse(query), Tx: tx}
+	for _, a := range args {
+		call.Args = append(call.Args, a.Value)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	var fn func(Call) Result
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		if strings.Contains(call.Query, f.rules[i].match) {
+			fn = f.rules[i].fn
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if fn == nil {
+		return Result{}, fmt.Errorf("dbtest: unexpected statement: %s", call.Query)
+	}
+	res := fn(call)
+	return res, res.Err
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Tx is a transaction opened on the fake.
+type Tx struct {
+	f     *Fake
+	mu    sync.Mutex
+	hooks []func(committed bool)
+}
+
+// OnEnd runs fn when the transaction commits or rolls back. Tests use it to
+// release simulated row locks.
+func (tx *Tx) OnEnd(fn func(committed bool)) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.hooks = append(tx.hooks, fn)
+}
+
+func (tx *Tx) end(committed bool) {
+	tx.f.mu.Lock()
+	if committed {
+		tx.f.commits++
+	} else {
+		tx.f.rollbacks++
+	}
+	tx.f.mu.Unlock()
+
+	tx.mu.Lock()
+	hooks := tx.hooks
+	tx.hooks = nil
+	tx.mu.Unlock()
+	for _, fn := range hooks {
+		fn(committed)
+	}
+}
+
+type connector struct{ f *Fake }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if c.f.connErr != nil {
+		return nil, c.f.connErr
+	}
+	return &conn{f: c.f}, nil
+}
+
+func (c connector) Driver() driver.Driver { return fakeDriver{c.f} }
+
+type fakeDriver struct{ f *Fake }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &conn{f: d.f}, nil }
+
+type conn struct {
+	f  *Fake
+	tx *Tx
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.tx = &Tx{f: c.f}
+	return connTx{c}, nil
+}
+
+func (c *conn) Ping(context.Context) error { return nil }
+
+// CheckNamedValue passes driver.Valuer results and basic types through and
+// keeps anything else (slices, for ANY($n)) as is, as pgx would accept them.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value); err == nil {
+		nv.Value = v
+	}
+	return nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.f.run(query, args, c.tx)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: res.Columns, values: res.Rows}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.f.run(query, args, c.tx)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+type connTx struct{ c *conn }
+
+func (t connTx) Commit() error {
+	tx := t.c.tx
+	t.c.tx = nil
+	t.c.f.mu.Lock()
+	err := t.c.f.commitErr
+	t.c.f.mu.Unlock()
+	tx.end(err == nil)
+	return err
+}
+
+func (t connTx) Rollback() error {
+	tx := t.c.tx
+	t.c.tx = nil
+	tx.end(false)
+	return nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return out
+}
+
+type rows struct {
+	columns []string
+	values  [][]any
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	row := r.values[0]
+	r.values = r.values[1:]
+	if len(row) != len(dest) {
+		return fmt.Errorf("dbtest: row has %d values, want %d", len(row), len(dest))
+	}
+	for i, v := range row {
+		cv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return fmt.Errorf("dbtest: column %s: %w", r.columns[i], err)
+		}
+		dest[i] = cv
+	}
+	return nil
+}
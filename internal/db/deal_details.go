@@ -57,6 +57,9 @@ func NewDealDetailsRepository() *DealDetailsRepository {
 }
 
 func (r *DealDetailsRepository) UpsertVesselDetails(ctx context.Context, d *DealVesselDetails) error {
+	if err := sanitizeNotes(notesField{"notes", d.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.deal_vessel_details (
 			deal_id, filled_by, vessel_name, imo_number, vessel_type, flag_state,
@@ -135,6 +138,9 @@ func (r *DealDetailsRepository) GetVesselDetails(ctx context.Context, dealID uui
 }
 
 func (r *DealDetailsRepository) UpsertCargoDetails(ctx context.Context, d *DealCargoDetails) error {
+	if err := sanitizeNotes(notesField{"notes", d.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.deal_cargo_details (
 			deal_id, filled_by, commodity, quantity, quantity_unit,
@@ -70,6 +70,10 @@ func NewDealRepository() *DealRepository {
 }
 
 func (repo *DealRepository) Create(ctx context.Context, d *Deal) error {
+	clearServerFields(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	if err := sanitizeNotes(notesField{"description", d.Description}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.deals (title, description, document_id, status, created_by)
 		VALUES ($1, $2, $3, $4, $5)
@@ -311,6 +315,7 @@ func (repo *DealRepository) IsParticipant(ctx context.Context, dealID, userID uu
 }
 
 func (repo *DealRepository) CreateInvite(ctx context.Context, i *DealInvite) error {
+	clearServerFields(&i.ID, &i.CreatedAt)
 	token := make([]byte, 32)
 	rand.Read(token)
 	i.Token = hex.EncodeToString(token)
@@ -365,8 +370,7 @@ func (repo *DealRepository) UseInvite(ctx context.Context, token string, userID
 
 func (repo *DealRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	const query = `UPDATE shipman.deals SET status = $2 WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, status)
-	return err
+	return requireRow(Conn(ctx).ExecContext(ctx, query, id, status))
 }
 
 // ListPendingInvites returns invites for a deal that haven't been used yet
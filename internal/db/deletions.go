@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Deletion mirrors shipman.deletions: a tombstone left when a row is hard
+// deleted. EntityType uses the audit log values, e.g. AuditEntityCharter.
+type Deletion struct {
+	EntityType string    `json:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
+
+// recordDeletion writes a tombstone for a row that was just deleted. Run it
+// in the same transaction as the delete so one is never kept without the
+// other.
+func recordDeletion(ctx context.Context, entityType string, entityID, orgID uuid.UUID) error {
+	const query = `
+		INSERT INTO shipman.deletions (entity_type, entity_id, org_id)
+		VALUES ($1, $2, $3)
+	`
+	_, err := Conn(ctx).ExecContext(ctx, query, entityType, entityID, orgID)
+	return err
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DemurrageDocument mirrors shipman.demurrage_documents. A demurrage record
+// keeps its primary document in SupportingDocURI; these rows hold the rest of
+// the claim bundle (statement of facts, NOR, invoices, ...).
+type DemurrageDocument struct {
+	ID                uuid.UUID `json:"id"`
+	DemurrageRecordID uuid.UUID `json:"demurrage_record_id"`
+	URI               string    `json:"uri"`
+	Kind              string    `json:"kind"`
+	Checksum          *string   `json:"checksum,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// DemurrageDocumentService describes document behaviour.
+type DemurrageDocumentService interface {
+	Create(ctx context.Context, doc *DemurrageDocument) error
+	Retrieve(ctx context.Context, id uuid.UUID) (DemurrageDocument, error)
+	ListByRecord(ctx context.Context, recordID uuid.UUID) ([]DemurrageDocument, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// DemurrageDocumentRepository implements DemurrageDocumentService using Pool.
+type DemurrageDocumentRepository struct{}
+
+// NewDemurrageDocumentRepository returns a repository.
+func NewDemurrageDocumentRepository() *DemurrageDocumentRepository {
+	return &DemurrageDocumentRepository{}
+}
+
+// Create attaches a document to a demurrage record.
+func (repo *DemurrageDocumentRepository) Create(ctx context.Context, doc *DemurrageDocument) error {
+	clearServerFields(&doc.ID, &doc.CreatedAt)
+	const query = `
+		INSERT INTO shipman.demurrage_documents (
+			demurrage_record_id,
+			uri,
+			kind,
+			checksum
+		) VALUES (
+			$1, $2, $3, $4
+		)
+		RETURNING id, kind, created_at
+	`
+
+	kind := doc.Kind
+	if kind == "" {
+		kind = "other"
+	}
+
+	return Conn(ctx).QueryRowContext(
+		ctx,
+		query,
+		doc.DemurrageRecordID,
+		doc.URI,
+		kind,
+		nullableString(doc.Checksum),
+	).Scan(&doc.ID, &doc.Kind, &doc.CreatedAt)
+}
+
+// Retrieve fetches a document by id.
+func (repo *DemurrageDocumentRepository) Retrieve(ctx context.Context, id uuid.UUID) (DemurrageDocument, error) {
+	const query = `
+		SELECT id, demurrage_record_id, uri, kind, checksum, created_at
+		FROM shipman.demurrage_documents
+		WHERE id = $1
+	`
+
+	return scanDemurrageDocument(Pool.QueryRowContext(ctx, query, id))
+}
+
+// ListByRecord returns the documents attached to a demurrage record, oldest first.
+func (repo *DemurrageDocumentRepository) ListByRecord(ctx context.Context, recordID uuid.UUID) ([]DemurrageDocument, error) {
+	const query = `
+		SELECT id, demurrage_record_id, uri, kind, checksum, created_at
+		FROM shipman.demurrage_documents
+		WHERE demurrage_record_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []DemurrageDocument
+	for rows.Next() {
+		doc, err := scanDemurrageDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// Delete removes a document.
+func (repo *DemurrageDocumentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	const query = `DELETE FROM shipman.demurrage_documents WHERE id = $1`
+	_, err := Pool.ExecContext(ctx, query, id)
+	return err
+}
+
+func scanDemurrageDocument(row rowScanner) (DemurrageDocument, error) {
+	var (
+		doc      DemurrageDocument
+		checksum sql.NullString
+	)
+	if err := row.Scan(
+		&doc.ID,
+		&doc.DemurrageRecordID,
+		&doc.URI,
+		&doc.Kind,
+		&checksum,
+		&doc.CreatedAt,
+	); err != nil {
+		return DemurrageDocument{}, err
+	}
+	doc.Checksum = stringPtr(checksum)
+	return doc, nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var demurrageDocumentColumns = []string{"id", "demurrage_record_id", "uri", "kind", "checksum", "created_at"}
+
+func TestDemurrageDocumentCreate(t *testing.T) {
+	checksum := "sha256:abc"
+	tests := []struct {
+		name         string
+		kind         string
+		checksum     *string
+		wantKind     string
+		wantChecksum any
+	}{
+		{"kind and checksum", "sof", &checksum, "sof", checksum},
+		{"defaults", "", nil, "other", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			serverID, createdAt := uuid.New(), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+			fake.On("INSERT INTO shipman.demurrage_documents", func(call dbtest.Call) dbtest.Result {
+				return dbtest.Rows([]string{"id", "kind", "created_at"}, []any{serverID, call.Arg(3), createdAt})
+			})
+
+			recordID := uuid.New()
+			doc := &DemurrageDocument{DemurrageRecordID: recordID, URI: "s3://claims/sof.pdf", Kind: tt.kind, Checksum: tt.checksum}
+			if err := NewDemurrageDocumentRepository().Create(context.Background(), doc); err != nil {
+				t.Fatal(err)
+			}
+
+			call := fake.Calls("INSERT INTO shipman.demurrage_documents")[0]
+			if call.Arg(1) != recordID.String() || call.Arg(2) != "s3://claims/sof.pdf" {
+				t.Errorf("inserted record/uri = %v/%v", call.Arg(1), call.Arg(2))
+			}
+			if call.Arg(3) != tt.wantKind || call.Arg(4) != tt.wantChecksum {
+				t.Errorf("inserted kind/checksum = %v/%v, want %v/%v", call.Arg(3), call.Arg(4), tt.wantKind, tt.wantChecksum)
+			}
+			if doc.ID != serverID || doc.Kind != tt.wantKind || !doc.CreatedAt.Equal(createdAt) {
+				t.Errorf("doc = %+v, want the returned id, kind and created_at", doc)
+			}
+		})
+	}
+}
+
+func TestDemurrageDocumentListByRecord(t *testing.T) {
+	fake := newFakeDB(t)
+	recordID := uuid.New()
+	checksum := "sha256:abc"
+	first, second := uuid.New(), uuid.New()
+	fake.Return("FROM shipman.demurrage_documents WHERE demurrage_record_id = $1", dbtest.Rows(demurrageDocumentColumns,
+		[]any{first, recordID, "s3://claims/sof.pdf", "sof", checksum, time.Now().Add(-time.Hour)},
+		[]any{second, recordID, "s3://claims/nor.pdf", "nor", nil, time.Now()},
+	))
+
+	docs, err := NewDemurrageDocumentRepository().ListByRecord(context.Background(), recordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].ID != first || docs[1].ID != second {
+		t.Fatalf("docs = %+v, want both in query order", docs)
+	}
+	if docs[0].Checksum == nil || *docs[0].Checksum != checksum || docs[1].Checksum != nil {
+		t.Errorf("checksums = %v, %v", docs[0].Checksum, docs[1].Checksum)
+	}
+
+	call := fake.Calls("FROM shipman.demurrage_documents")[0]
+	if call.Arg(1) != recordID.String() {
+		t.Errorf("listed record %v, want %s", call.Arg(1), recordID)
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// ReconcileToleranceHours is how far a record's claimed_hours may drift from
+// the laytime it references before ReconcileWithLaytime reports it.
+const ReconcileToleranceHours = 0.1
+
+// Discrepancy reasons reported by ReconcileWithLaytime.
+const (
+	DiscrepancyMismatch       = "mismatch"
+	DiscrepancyNoClaimedHours = "no_claimed_hours"
+	DiscrepancyNoLaytimeHours = "no_laytime_hours"
+)
+
+// Discrepancy is a demurrage record whose claim does not tie back to its
+// laytime entry. DifferenceHours is claimed minus counted and is nil when
+// either side is missing.
+type Discrepancy struct {
+	DemurrageRecordID uuid.UUID `json:"demurrage_record_id"`
+	LaytimeEntryID    uuid.UUID `json:"laytime_entry_id"`
+	ClaimedHours      *float64  `json:"claimed_hours,omitempty"`
+	CountedHours      *float64  `json:"counted_hours,omitempty"`
+	DifferenceHours   *float64  `json:"difference_hours,omitempty"`
+	Reason            string    `json:"reason"`
+}
+
+// ReconcileWithLaytime compares claimed_hours on the charter's demurrage
+// records against hours_counted on the laytime entry each one references and
+// returns those that differ by more than ReconcileToleranceHours, or where
+// either figure is missing. Records without a laytime entry are skipped.
+func (repo *DemurrageRecordRepository) ReconcileWithLaytime(ctx context.Context, charterID uuid.UUID) ([]Discrepancy, error) {
+	const query = `
+		SELECT dr.id, le.id, dr.claimed_hours, le.hours_counted
+		FROM shipman.demurrage_records dr
+		JOIN shipman.laytime_entries le ON le.id = dr.laytime_entry_id
+		WHERE dr.charter_detail_id = $1
+		ORDER BY dr.created_at ASC, dr.id ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, charterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Discrepancy
+	for rows.Next() {
+		var (
+			d       Discrepancy
+			claimed sql.NullFloat64
+			counted sql.NullFloat64
+		)
+		if err := rows.Scan(&d.DemurrageRecordID, &d.LaytimeEntryID, &claimed, &counted); err != nil {
+			return nil, err
+		}
+		d.ClaimedHours = floatPtr(claimed)
+		d.CountedHours = floatPtr(counted)
+
+		switch {
+		case !claimed.Valid:
+			d.Reason = DiscrepancyNoClaimedHours
+		case !counted.Valid:
+			d.Reason = DiscrepancyNoLaytimeHours
+		default:
+			diff := claimed.Float64 - counted.Float64
+			if math.Abs(diff) <= ReconcileToleranceHours {
+				continue
+			}
+			d.DifferenceHours = &diff
+			d.Reason = DiscrepancyMismatch
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestReconcileWithLaytime(t *testing.T) {
+	const reconcile = "JOIN shipman.laytime_entries le ON le.id = dr.laytime_entry_id"
+	cols := []string{"id", "laytime_id", "claimed_hours", "hours_counted"}
+	matching, withinTolerance, over, under, unclaimed, uncounted := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	fake := newFakeDB(t)
+	fake.Return(reconcile, dbtest.Rows(cols,
+		[]any{matching, uuid.New(), 48.0, 48.0},
+		[]any{withinTolerance, uuid.New(), 48.05, 48.0},
+		[]any{over, uuid.New(), 60.0, 48.0},
+		[]any{under, uuid.New(), 40.0, 48.5},
+		[]any{unclaimed, uuid.New(), nil, 12.0},
+		[]any{uncounted, uuid.New(), 12.0, nil},
+	))
+	charterID := uuid.New()
+	f := func(v float64) *float64 { return &v }
+
+	got, err := NewDemurrageRecordRepository().ReconcileWithLaytime(context.Background(), charterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id     uuid.UUID
+		reason string
+		diff   *float64
+	}{
+		{over, DiscrepancyMismatch, f(12)},
+		{under, DiscrepancyMismatch, f(-8.5)},
+		{unclaimed, DiscrepancyNoClaimedHours, nil},
+		{uncounted, DiscrepancyNoLaytimeHours, nil},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("discrepancies = %+v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].DemurrageRecordID != w.id || got[i].Reason != w.reason {
+			t.Errorf("discrepancy %d = %s %q, want %s %q", i, got[i].DemurrageRecordID, got[i].Reason, w.id, w.reason)
+		}
+		checkPct(t, "difference", got[i].DifferenceHours, w.diff)
+	}
+	if got[2].ClaimedHours != nil || got[2].CountedHours == nil || *got[2].CountedHours != 12 {
+		t.Errorf("unclaimed hours = %v/%v, want nil claimed and 12 counted", got[2].ClaimedHours, got[2].CountedHours)
+	}
+	if calls := fake.Calls(reconcile); len(calls) != 1 || calls[0].Arg(1) != charterID.String() {
+		t.Errorf("calls = %+v, want one for the charter", calls)
+	}
+}
+
+func TestReconcileWithLaytimeAllMatching(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("JOIN shipman.laytime_entries le ON le.id = dr.laytime_entry_id", dbtest.Rows(
+		[]string{"id", "laytime_id", "claimed_hours", "hours_counted"},
+		[]any{uuid.New(), uuid.New(), 24.0, 24.0},
+	))
+	got, err := NewDemurrageRecordRepository().ReconcileWithLaytime(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("discrepancies = %+v, want none", got)
+	}
+}
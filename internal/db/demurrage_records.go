@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"time"
 
+	"shipman/internal/metrics"
+
 	"github.com/google/uuid"
 )
 
@@ -29,7 +31,8 @@ type DemurrageRecord struct {
 type DemurrageRecordService interface {
 	Create(ctx context.Context, record *DemurrageRecord) error
 	Retrieve(ctx context.Context, id uuid.UUID) (DemurrageRecord, error)
-	ListByCharter(ctx context.Context, charterID uuid.UUID) ([]DemurrageRecord, error)
+	ListByCharter(ctx context.Context, charterID uuid.UUID, page Page) ([]DemurrageRecord, error)
+	ReconcileWithLaytime(ctx context.Context, charterID uuid.UUID) ([]Discrepancy, error)
 	Update(ctx context.Context, record *DemurrageRecord) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -42,8 +45,13 @@ func NewDemurrageRecordRepository() *DemurrageRecordRepository {
 	return &DemurrageRecordRepository{}
 }
 
-// Create inserts a demurrage record.
+// Create inserts a demurrage record. A blank currency is taken from the
+// charter's default_currency, falling back to USD.
 func (repo *DemurrageRecordRepository) Create(ctx context.Context, record *DemurrageRecord) error {
+	clearServerFields(&record.ID, &record.CreatedAt, &record.UpdatedAt)
+	if err := sanitizeNotes(notesField{"notes", record.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.demurrage_records (
 			charter_detail_id,
@@ -57,12 +65,18 @@ func (repo *DemurrageRecordRepository) Create(ctx context.Context, record *Demur
 			supporting_doc_uri,
 			notes
 		) VALUES (
-			$1, $2, $3, $4, $5, COALESCE($6, 'USD'), COALESCE($7, 'draft'), $8, $9, $10
+			$1, $2, $3, $4, $5,
+			COALESCE(
+				NULLIF($6, ''),
+				(SELECT default_currency FROM shipman.charter_details WHERE id = $1),
+				'USD'
+			),
+			COALESCE($7, 'draft'), $8, $9, $10
 		)
 		RETURNING id, currency, status, created_at, updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		record.CharterDetailID,
@@ -76,9 +90,16 @@ func (repo *DemurrageRecordRepository) Create(ctx context.Context, record *Demur
 		nullableString(record.SupportingDocURI),
 		nullableString(record.Notes),
 	).Scan(&record.ID, &record.Currency, &record.Status, &record.CreatedAt, &record.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	currency, amount := record.Currency, record.ClaimedAmount
+	AfterCommit(ctx, func() { metrics.DemurrageClaimed(currency, amount) })
+	return nil
 }
 
-// Retrieve fetches a demurrage record by id.
+// Retrieve fetches a demurrage record by id. Records whose charter is
+// outside the org ctx is scoped to are reported as sql.ErrNoRows.
 func (repo *DemurrageRecordRepository) Retrieve(ctx context.Context, id uuid.UUID) (DemurrageRecord, error) {
 	const query = `
 		SELECT
@@ -97,6 +118,9 @@ func (repo *DemurrageRecordRepository) Retrieve(ctx context.Context, id uuid.UUI
 			updated_at
 		FROM shipman.demurrage_records
 		WHERE id = $1
+		  AND ($2::uuid IS NULL OR EXISTS (
+		      SELECT 1 FROM shipman.charter_details cd
+		      WHERE cd.id = charter_detail_id AND cd.org_id = $2))
 	`
 
 	var (
@@ -112,7 +136,7 @@ func (repo *DemurrageRecordRepository) Retrieve(ctx context.Context, id uuid.UUI
 		notes    sql.NullString
 	)
 
-	err := Pool.QueryRowContext(ctx, query, id).Scan(
+	err := Pool.QueryRowContext(ctx, query, id, orgFilter(ctx)).Scan(
 		&record.ID,
 		&record.CharterDetailID,
 		&voyage,
@@ -144,16 +168,21 @@ func (repo *DemurrageRecordRepository) Retrieve(ctx context.Context, id uuid.UUI
 	return record, nil
 }
 
-// ListByCharter returns demurrage records for a charter.
-func (repo *DemurrageRecordRepository) ListByCharter(ctx context.Context, charterID uuid.UUID) ([]DemurrageRecord, error) {
+// ListByCharter returns a page of demurrage records for a charter, newest first.
+func (repo *DemurrageRecordRepository) ListByCharter(ctx context.Context, charterID uuid.UUID, page Page) ([]DemurrageRecord, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
 	const query = `
 		SELECT id, charter_detail_id, voyage_id, claimed_amount, status, created_at, updated_at
 		FROM shipman.demurrage_records
 		WHERE charter_detail_id = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := Pool.QueryContext(ctx, query, charterID)
+	rows, err := Pool.QueryContext(ctx, query, charterID, page.limit(), page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -186,8 +215,97 @@ func (repo *DemurrageRecordRepository) ListByCharter(ctx context.Context, charte
 	return records, rows.Err()
 }
 
+// UncollectedDemurrage is a demurrage record on a closed charter that was
+// never paid, with the charter title for context.
+type UncollectedDemurrage struct {
+	DemurrageRecord
+	CharterTitle string `json:"charter_title"`
+}
+
+// UnpaidForClosedCharters returns demurrage that is neither paid (settled)
+// nor rejected on charters that have closed (status 'closed' or 'completed'),
+// oldest first.
+func (repo *DemurrageRecordRepository) UnpaidForClosedCharters(ctx context.Context) ([]UncollectedDemurrage, error) {
+	const query = `
+		SELECT
+			dr.id,
+			dr.charter_detail_id,
+			dr.voyage_id,
+			dr.laytime_entry_id,
+			dr.claimed_hours,
+			dr.claimed_amount,
+			dr.currency,
+			dr.status,
+			dr.reference,
+			dr.supporting_doc_uri,
+			dr.notes,
+			dr.created_at,
+			dr.updated_at,
+			cd.title
+		FROM shipman.demurrage_records dr
+		JOIN shipman.charter_details cd ON cd.id = dr.charter_detail_id
+		WHERE cd.status IN ('closed', 'completed')
+		  AND dr.status NOT IN ('paid', 'settled', 'rejected')
+		ORDER BY dr.created_at ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UncollectedDemurrage
+	for rows.Next() {
+		var (
+			rec      UncollectedDemurrage
+			voyage   sql.NullString
+			laytime  sql.NullString
+			hours    sql.NullFloat64
+			amount   sql.NullFloat64
+			currency sql.NullString
+			status   sql.NullString
+			ref      sql.NullString
+			doc      sql.NullString
+			notes    sql.NullString
+		)
+		if err := rows.Scan(
+			&rec.ID,
+			&rec.CharterDetailID,
+			&voyage,
+			&laytime,
+			&hours,
+			&amount,
+			&currency,
+			&status,
+			&ref,
+			&doc,
+			&notes,
+			&rec.CreatedAt,
+			&rec.UpdatedAt,
+			&rec.CharterTitle,
+		); err != nil {
+			return nil, err
+		}
+		rec.VoyageID = uuidPtrNullable(voyage)
+		rec.LaytimeEntryID = uuidPtrNullable(laytime)
+		rec.ClaimedHours = floatPtr(hours)
+		rec.ClaimedAmount = floatPtr(amount)
+		rec.Currency = defaultString(currency, "USD")
+		rec.Status = defaultString(status, "draft")
+		rec.Reference = stringPtr(ref)
+		rec.SupportingDocURI = stringPtr(doc)
+		rec.Notes = stringPtr(notes)
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
 // Update modifies a demurrage record.
 func (repo *DemurrageRecordRepository) Update(ctx context.Context, record *DemurrageRecord) error {
+	if err := sanitizeNotes(notesField{"notes", record.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.demurrage_records
 		SET
@@ -205,7 +323,7 @@ func (repo *DemurrageRecordRepository) Update(ctx context.Context, record *Demur
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		record.ID,
@@ -219,6 +337,7 @@ func (repo *DemurrageRecordRepository) Update(ctx context.Context, record *Demur
 		nullableString(record.SupportingDocURI),
 		nullableString(record.Notes),
 	).Scan(&record.UpdatedAt)
+	return notFound(err)
 }
 
 // Delete removes a demurrage record.
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var uncollectedColumns = []string{
+	"id", "charter_detail_id", "voyage_id", "laytime_entry_id", "claimed_hours",
+	"claimed_amount", "currency", "status", "reference", "supporting_doc_uri",
+	"notes", "created_at", "updated_at", "title",
+}
+
+func TestUnpaidForClosedCharters(t *testing.T) {
+	fake := newFakeDB(t)
+	recordID, charterID, voyageID := uuid.New(), uuid.New(), uuid.New()
+	fake.Return("JOIN shipman.charter_details cd ON cd.id = dr.charter_detail_id", dbtest.Rows(uncollectedColumns,
+		dbtest.Row(uncollectedColumns, map[string]any{
+			"id": recordID, "charter_detail_id": charterID, "voyage_id": voyageID,
+			"claimed_amount": 12500.0, "currency": "EUR", "status": "submitted",
+			"created_at": time.Now(), "updated_at": time.Now(), "title": "Grain charter",
+		}),
+		dbtest.Row(uncollectedColumns, map[string]any{
+			"id": uuid.New(), "charter_detail_id": charterID,
+			"created_at": time.Now(), "updated_at": time.Now(), "title": "Grain charter",
+		}),
+	))
+
+	got, err := NewDemurrageRecordRepository().UnpaidForClosedCharters(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("records = %d, want 2", len(got))
+	}
+	first := got[0]
+	if first.ID != recordID || first.CharterTitle != "Grain charter" || first.VoyageID == nil || *first.VoyageID != voyageID {
+		t.Errorf("first = %+v, want the record with its charter title and voyage", first)
+	}
+	if first.ClaimedAmount == nil || *first.ClaimedAmount != 12500 || first.Currency != "EUR" || first.Status != "submitted" {
+		t.Errorf("first amount/currency/status = %v/%s/%s", first.ClaimedAmount, first.Currency, first.Status)
+	}
+	if got[1].Currency != "USD" || got[1].Status != "draft" || got[1].VoyageID != nil {
+		t.Errorf("second = %+v, want USD/draft defaults and no voyage", got[1])
+	}
+
+	q := fake.Calls("")[0].Query
+	for _, want := range []string{
+		"WHERE cd.status IN ('closed', 'completed')",
+		"AND dr.status NOT IN ('paid', 'settled', 'rejected')",
+		"ORDER BY dr.created_at ASC",
+	} {
+		if !strings.Contains(q, want) {
+			t.Errorf("query lacks %q: %s", want, q)
+		}
+	}
+}
+
+func TestDemurrageCreateInheritsCharterCurrency(t *testing.T) {
+	eurCharter, bareCharter := uuid.New(), uuid.New()
+	tests := []struct {
+		name      string
+		charterID uuid.UUID
+		currency  string
+		want      string
+	}{
+		{"charter default", eurCharter, "", "EUR"},
+		{"explicit currency wins", eurCharter, "SGD", "SGD"},
+		{"charter without a default", bareCharter, "", "USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fakeCurrencyDefault(fake, "INSERT INTO shipman.demurrage_records", map[string]string{eurCharter.String(): "EUR"},
+				[]string{"id", "currency", "status", "created_at", "updated_at"})
+
+			amount := 5000.0
+			record := &DemurrageRecord{CharterDetailID: tt.charterID, ClaimedAmount: &amount, Currency: tt.currency}
+			if err := NewDemurrageRecordRepository().Create(context.Background(), record); err != nil {
+				t.Fatal(err)
+			}
+			if record.Currency != tt.want {
+				t.Errorf("currency = %q, want %q", record.Currency, tt.want)
+			}
+			query := fake.Calls("INSERT INTO shipman.demurrage_records")[0].Query
+			for _, part := range []string{
+				"NULLIF($6, '')",
+				"(SELECT default_currency FROM shipman.charter_details WHERE id = $1)",
+				"RETURNING id, currency",
+			} {
+				if !strings.Contains(query, part) {
+					t.Errorf("insert is missing %q", part)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"time"
 
+	"shipman/internal/metrics"
+
 	"github.com/google/uuid"
 )
 
@@ -14,6 +16,7 @@ type Dispute struct {
 	CharterDetailID uuid.UUID  `json:"charter_detail_id"`
 	VoyageID        *uuid.UUID `json:"voyage_id,omitempty"`
 	PaymentID       *uuid.UUID `json:"payment_id,omitempty"`
+	VoyagePaymentID *uuid.UUID `json:"voyage_payment_id,omitempty"`
 	LaytimeEntryID  *uuid.UUID `json:"laytime_entry_id,omitempty"`
 	RaisedByOrgID   uuid.UUID  `json:"raised_by_org_id"`
 	AssignedToOrgID *uuid.UUID `json:"assigned_to_org_id,omitempty"`
@@ -23,6 +26,8 @@ type Dispute struct {
 	Currency        *string    `json:"currency,omitempty"`
 	Status          string     `json:"status"`
 	ResolutionNotes *string    `json:"resolution_notes,omitempty"`
+	SettledAt       *time.Time `json:"settled_at,omitempty"`
+	ArchivedAt      *time.Time `json:"archived_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
@@ -31,7 +36,8 @@ type Dispute struct {
 type DisputeService interface {
 	Create(ctx context.Context, d *Dispute) error
 	Retrieve(ctx context.Context, id uuid.UUID) (Dispute, error)
-	ListByCharter(ctx context.Context, charterID uuid.UUID) ([]Dispute, error)
+	ListByCharter(ctx context.Context, charterID uuid.UUID, page Page) ([]Dispute, error)
+	ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]Dispute, error)
 	Update(ctx context.Context, d *Dispute) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -46,6 +52,11 @@ func NewDisputeRepository() *DisputeRepository {
 
 // Create inserts dispute row.
 func (repo *DisputeRepository) Create(ctx context.Context, d *Dispute) error {
+	clearServerFields(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	if err := sanitizeNotes(notesField{"description", d.Description}, notesField{"resolution_notes", d.ResolutionNotes}); err != nil {
+		return err
+	}
+	d.SettledAt = nil
 	const query = `
 		INSERT INTO shipman.disputes (
 			charter_detail_id,
@@ -59,14 +70,15 @@ func (repo *DisputeRepository) Create(ctx context.Context, d *Dispute) error {
 			claimed_amount,
 			currency,
 			status,
-			resolution_notes
+			resolution_notes,
+			voyage_payment_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11, 'open'), $12
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11, 'open'), $12, $13
 		)
 		RETURNING id, status, created_at, updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		d.CharterDetailID,
@@ -81,10 +93,17 @@ func (repo *DisputeRepository) Create(ctx context.Context, d *Dispute) error {
 		nullableString(d.Currency),
 		nullableString(&d.Status),
 		nullableString(d.ResolutionNotes),
+		nullableUUID(d.VoyagePaymentID),
 	).Scan(&d.ID, &d.Status, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	AfterCommit(ctx, metrics.DisputeOpened)
+	return nil
 }
 
-// Retrieve fetches dispute by id.
+// Retrieve fetches dispute by id. Disputes whose charter is outside the org
+// ctx is scoped to are reported as sql.ErrNoRows.
 func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Dispute, error) {
 	const query = `
 		SELECT
@@ -92,6 +111,7 @@ func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Disp
 			charter_detail_id,
 			voyage_id,
 			payment_id,
+			voyage_payment_id,
 			laytime_entry_id,
 			raised_by_org_id,
 			assigned_to_org_id,
@@ -101,16 +121,22 @@ func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Disp
 			currency,
 			status,
 			resolution_notes,
+			settled_at,
+			archived_at,
 			created_at,
 			updated_at
 		FROM shipman.disputes
 		WHERE id = $1
+		  AND ($2::uuid IS NULL OR EXISTS (
+		      SELECT 1 FROM shipman.charter_details cd
+		      WHERE cd.id = charter_detail_id AND cd.org_id = $2))
 	`
 
 	var (
 		dispute  Dispute
 		voyage   sql.NullString
 		payment  sql.NullString
+		vpayment sql.NullString
 		laytime  sql.NullString
 		assigned sql.NullString
 		desc     sql.NullString
@@ -118,13 +144,16 @@ func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Disp
 		curr     sql.NullString
 		status   sql.NullString
 		notes    sql.NullString
+		settled  sql.NullTime
+		archived sql.NullTime
 	)
 
-	err := Pool.QueryRowContext(ctx, query, id).Scan(
+	err := Pool.QueryRowContext(ctx, query, id, orgFilter(ctx)).Scan(
 		&dispute.ID,
 		&dispute.CharterDetailID,
 		&voyage,
 		&payment,
+		&vpayment,
 		&laytime,
 		&dispute.RaisedByOrgID,
 		&assigned,
@@ -134,6 +163,8 @@ func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Disp
 		&curr,
 		&status,
 		&notes,
+		&settled,
+		&archived,
 		&dispute.CreatedAt,
 		&dispute.UpdatedAt,
 	)
@@ -143,6 +174,7 @@ func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Disp
 
 	dispute.VoyageID = uuidPtrNullable(voyage)
 	dispute.PaymentID = uuidPtrNullable(payment)
+	dispute.VoyagePaymentID = uuidPtrNullable(vpayment)
 	dispute.LaytimeEntryID = uuidPtrNullable(laytime)
 	dispute.AssignedToOrgID = uuidPtrNullable(assigned)
 	dispute.Description = stringPtr(desc)
@@ -150,20 +182,43 @@ func (repo *DisputeRepository) Retrieve(ctx context.Context, id uuid.UUID) (Disp
 	dispute.Currency = stringPtr(curr)
 	dispute.Status = defaultString(status, "open")
 	dispute.ResolutionNotes = stringPtr(notes)
+	dispute.SettledAt = timePtr(settled)
+	dispute.ArchivedAt = timePtr(archived)
 
 	return dispute, nil
 }
 
-// ListByCharter returns disputes for a charter.
-func (repo *DisputeRepository) ListByCharter(ctx context.Context, charterID uuid.UUID) ([]Dispute, error) {
+// ListByCharter returns a page of disputes for a charter, newest first.
+func (repo *DisputeRepository) ListByCharter(ctx context.Context, charterID uuid.UUID, page Page) ([]Dispute, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
 	const query = `
 		SELECT id, charter_detail_id, subject, status, claimed_amount, currency, created_at, updated_at
 		FROM shipman.disputes
 		WHERE charter_detail_id = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	return listDisputeSummaries(ctx, query, charterID, page.limit(), page.Offset)
+}
+
+// ListByPayment returns the disputes raised against a voyage payment, newest
+// first, with the same columns as ListByCharter.
+func (repo *DisputeRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]Dispute, error) {
+	const query = `
+		SELECT id, charter_detail_id, subject, status, claimed_amount, currency, created_at, updated_at
+		FROM shipman.disputes
+		WHERE voyage_payment_id = $1
+		ORDER BY created_at DESC, id DESC
 	`
+	return listDisputeSummaries(ctx, query, paymentID)
+}
 
-	rows, err := Pool.QueryContext(ctx, query, charterID)
+// listDisputeSummaries runs a query selecting the list columns of disputes.
+func listDisputeSummaries(ctx context.Context, query string, args ...any) ([]Dispute, error) {
+	rows, err := Pool.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -199,6 +254,9 @@ func (repo *DisputeRepository) ListByCharter(ctx context.Context, charterID uuid
 
 // Update modifies dispute fields.
 func (repo *DisputeRepository) Update(ctx context.Context, d *Dispute) error {
+	if err := sanitizeNotes(notesField{"description", d.Description}, notesField{"resolution_notes", d.ResolutionNotes}); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.disputes
 		SET
@@ -212,12 +270,13 @@ func (repo *DisputeRepository) Update(ctx context.Context, d *Dispute) error {
 			currency = $9,
 			status = $10,
 			resolution_notes = $11,
+			voyage_payment_id = $12,
 			updated_at = NOW()
 		WHERE id = $1
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		d.ID,
@@ -231,7 +290,9 @@ func (repo *DisputeRepository) Update(ctx context.Context, d *Dispute) error {
 		nullableString(d.Currency),
 		d.Status,
 		nullableString(d.ResolutionNotes),
+		nullableUUID(d.VoyagePaymentID),
 	).Scan(&d.UpdatedAt)
+	return notFound(err)
 }
 
 // Delete removes a dispute.
@@ -240,3 +301,123 @@ func (repo *DisputeRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := Pool.ExecContext(ctx, query, id)
 	return err
 }
+
+// ArchiveResolvedBefore archives disputes that reached a final status
+// (resolved or closed) before the given time, measured from
+// settled_at or, failing that, the last update. It returns how many were
+// archived.
+func (repo *DisputeRepository) ArchiveResolvedBefore(ctx context.Context, before time.Time) (int64, error) {
+	const query = `
+		UPDATE shipman.disputes
+		SET archived_at = NOW()
+		WHERE archived_at IS NULL
+		  AND status IN ('resolved', 'closed')
+		  AND COALESCE(settled_at, updated_at) < $1
+	`
+	res, err := Pool.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// disputeStatuses is the status vocabulary allowed by the disputes CHECK
+// constraint (migration 000043).
+var disputeStatuses = []string{"open", "under_review", "resolved", "closed"}
+
+// disputeTransitions lists the statuses each dispute status may move to.
+// Closed is terminal; resolved and closed disputes go back to open only
+// through Reopen.
+var disputeTransitions = map[string][]string{
+	"open":         {"under_review", "resolved", "closed"},
+	"under_review": {"open", "resolved", "closed"},
+	"resolved":     {"closed"},
+}
+
+// disputeTransitionAllowed reports whether a dispute may move from one status
+// to another through TransitionStatus.
+func disputeTransitionAllowed(from, to string) bool {
+	for _, next := range disputeTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionStatus moves a dispute to status inside a transaction that locks
+// the row, so concurrent transitions are serialized. When the move is not
+// allowed the current dispute is returned with ErrInvalidTransition.
+func (repo *DisputeRepository) TransitionStatus(ctx context.Context, id uuid.UUID, status string, resolutionNotes *string) (Dispute, error) {
+	if err := sanitizeNotes(notesField{"resolution_notes", resolutionNotes}); err != nil {
+		return Dispute{}, err
+	}
+	var allowed bool
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var current string
+		const lockQuery = `SELECT status FROM shipman.disputes WHERE id = $1 FOR UPDATE`
+		if err := Conn(ctx).QueryRowContext(ctx, lockQuery, id).Scan(&current); err != nil {
+			return err
+		}
+		if allowed = disputeTransitionAllowed(current, status); !allowed {
+			return nil
+		}
+
+		const updateQuery = `
+			UPDATE shipman.disputes
+			SET status = $2,
+				resolution_notes = COALESCE($3, resolution_notes),
+				settled_at = CASE WHEN $2 = 'resolved' THEN NOW() ELSE settled_at END,
+				updated_at = NOW()
+			WHERE id = $1
+		`
+		_, err := Conn(ctx).ExecContext(ctx, updateQuery, id, status, nullableString(resolutionNotes))
+		return err
+	})
+	if err != nil {
+		return Dispute{}, err
+	}
+
+	d, err := repo.Retrieve(ctx, id)
+	if err != nil {
+		return Dispute{}, err
+	}
+	if !allowed {
+		return d, ErrInvalidTransition
+	}
+	return d, nil
+}
+
+// Reopen moves a resolved or closed dispute back to open, appending reason
+// to the resolution notes and clearing settled_at. Disputes in any other
+// status are left untouched and ErrInvalidTransition is returned.
+func (repo *DisputeRepository) Reopen(ctx context.Context, id uuid.UUID, reason string) error {
+	if err := sanitizeNotes(notesField{"reason", &reason}); err != nil {
+		return err
+	}
+	return WithTx(ctx, func(ctx context.Context) error {
+		var current string
+		const lockQuery = `SELECT status FROM shipman.disputes WHERE id = $1 FOR UPDATE`
+		if err := Conn(ctx).QueryRowContext(ctx, lockQuery, id).Scan(&current); err != nil {
+			return err
+		}
+		if current != "resolved" && current != "closed" {
+			return ErrInvalidTransition
+		}
+
+		note := "Reopened " + now().UTC().Format(time.RFC3339) + ": " + reason
+		const updateQuery = `
+			UPDATE shipman.disputes
+			SET status = 'open',
+				resolution_notes = CASE
+					WHEN resolution_notes IS NULL OR resolution_notes = '' THEN $2
+					ELSE resolution_notes || E'\n' || $2
+				END,
+				settled_at = NULL,
+				updated_at = NOW()
+			WHERE id = $1
+		`
+		_, err := Conn(ctx).ExecContext(ctx, updateQuery, id, note)
+		return err
+	})
+}
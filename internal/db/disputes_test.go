@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestDisputeTransitionAllowed(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{"open", "under_review"}:     true,
+		{"open", "resolved"}:         true,
+		{"open", "closed"}:           true,
+		{"under_review", "open"}:     true,
+		{"under_review", "resolved"}: true,
+		{"under_review", "closed"}:   true,
+		{"resolved", "closed"}:       true,
+	}
+	for _, from := range disputeStatuses {
+		for _, to := range disputeStatuses {
+			want := allowed[[2]string{from, to}]
+			if got := disputeTransitionAllowed(from, to); got != want {
+				t.Errorf("disputeTransitionAllowed(%q, %q) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestDisputeTransitionsMatchCheckConstraint(t *testing.T) {
+	raw, err := os.ReadFile("../../db/migrations/000043_dispute_status_check.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`CHECK \(status IN \(([^)]*)\)\)`).FindSubmatch(raw)
+	if m == nil {
+		t.Fatal("disputes status CHECK not found")
+	}
+	check := map[string]bool{}
+	for _, v := range strings.Split(string(m[1]), ",") {
+		check[strings.Trim(strings.TrimSpace(v), "'")] = true
+	}
+
+	if len(check) != len(disputeStatuses) {
+		t.Errorf("CHECK allows %d statuses, disputeStatuses has %d", len(check), len(disputeStatuses))
+	}
+	for _, s := range disputeStatuses {
+		if !check[s] {
+			t.Errorf("status %q is not allowed by the CHECK constraint", s)
+		}
+	}
+	for from, targets := range disputeTransitions {
+		if !check[from] {
+			t.Errorf("transition source %q is not allowed by the CHECK constraint", from)
+		}
+		for _, to := range targets {
+			if !check[to] {
+				t.Errorf("transition %q -> %q targets a status not allowed by the CHECK constraint", from, to)
+			}
+		}
+	}
+}
+
+func TestDisputeReopen(t *testing.T) {
+	at := time.Date(2026, 5, 4, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		current    string // "" for an unknown dispute
+		wantErr    error
+		wantUpdate bool
+	}{
+		{"resolved", "resolved", nil, true},
+		{"closed", "closed", nil, true},
+		{"already open", "open", ErrInvalidTransition, false},
+		{"under review", "under_review", ErrInvalidTransition, false},
+		{"unknown dispute", "", sql.ErrNoRows, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freezeClock(t, at)
+			fake := newFakeDB(t)
+			lock := dbtest.Rows([]string{"status"})
+			if tt.current != "" {
+				lock = dbtest.Rows([]string{"status"}, []any{tt.current})
+			}
+			fake.Return("SELECT status FROM shipman.disputes WHERE id = $1 FOR UPDATE", lock)
+			fake.Return("UPDATE shipman.disputes SET status = 'open'", dbtest.Affected(1))
+
+			err := NewDisputeRepository().Reopen(context.Background(), uuid.New(), "settlement fell through")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			updates := fake.Calls("UPDATE shipman.disputes SET status = 'open'")
+			if (len(updates) == 1) != tt.wantUpdate {
+				t.Fatalf("updates = %d, want update %v", len(updates), tt.wantUpdate)
+			}
+			if !tt.wantUpdate {
+				if fake.Commits() != 0 {
+					t.Error("a rejected reopen committed")
+				}
+				return
+			}
+			if got, want := updates[0].Arg(2), "Reopened 2026-05-04T09:30:00Z: settlement fell through"; got != want {
+				t.Errorf("note = %q, want %q", got, want)
+			}
+			for _, want := range []string{"settled_at = NULL", "updated_at = NOW()", "resolution_notes || E'\\n' || $2"} {
+				if !strings.Contains(updates[0].Query, want) {
+					t.Errorf("update lacks %q: %s", want, updates[0].Query)
+				}
+			}
+			if fake.Commits() != 1 {
+				t.Errorf("commits = %d, want 1", fake.Commits())
+			}
+		})
+	}
+}
+
+func TestDisputeReopenRejectsLongReason(t *testing.T) {
+	fake := newFakeDB(t)
+	err := NewDisputeRepository().Reopen(context.Background(), uuid.New(), strings.Repeat("x", MaxNotesLength+1))
+	if !errors.Is(err, ErrNotesTooLong) {
+		t.Errorf("err = %v, want ErrNotesTooLong", err)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("a rejected reason reached the database")
+	}
+}
+
+func TestDisputeListByPayment(t *testing.T) {
+	fake := newFakeDB(t)
+	// Dispute rows as Create stores them, with the legacy payment_id apart
+	// from voyage_payment_id.
+	var stored [][]any
+	fake.On("INSERT INTO shipman.disputes", func(call dbtest.Call) dbtest.Result {
+		id, at := uuid.New(), time.Date(2026, 6, 1+len(stored), 0, 0, 0, 0, time.UTC)
+		stored = append(stored, []any{id, call.Arg(1), call.Arg(3), call.Arg(13), call.Arg(7), "open", at})
+		return dbtest.Rows([]string{"id", "status", "created_at", "updated_at"}, []any{id, "open", at, at})
+	})
+	fake.On("FROM shipman.disputes WHERE voyage_payment_id = $1", func(call dbtest.Call) dbtest.Result {
+		var out [][]any
+		for i := len(stored) - 1; i >= 0; i-- {
+			if r := stored[i]; r[3] == call.Arg(1) {
+				out = append(out, []any{r[0], r[1], r[4], r[5], nil, nil, r[6], r[6]})
+			}
+		}
+		return dbtest.Rows([]string{"id", "charter_detail_id", "subject", "status", "claimed_amount", "currency", "created_at", "updated_at"}, out...)
+	})
+
+	repo := NewDisputeRepository()
+	ctx := context.Background()
+	charterID, paymentID, otherPayment := uuid.New(), uuid.New(), uuid.New()
+	raise := func(subject string, voyagePayment, legacyPayment *uuid.UUID) {
+		t.Helper()
+		d := &Dispute{CharterDetailID: charterID, Subject: subject, VoyagePaymentID: voyagePayment, PaymentID: legacyPayment}
+		if err := repo.Create(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raise("Short hire", &paymentID, nil)
+	raise("Other invoice", &otherPayment, nil)
+	raise("Legacy link", nil, &paymentID)
+	raise("Late hire", &paymentID, nil)
+
+	got, err := repo.ListByPayment(ctx, paymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subjects := make([]string, len(got))
+	for i, d := range got {
+		subjects[i] = d.Subject
+	}
+	if want := []string{"Late hire", "Short hire"}; strings.Join(subjects, ",") != strings.Join(want, ",") {
+		t.Errorf("disputes = %v, want %v newest first", subjects, want)
+	}
+
+	none, err := repo.ListByPayment(ctx, uuid.New())
+	if err != nil || len(none) != 0 {
+		t.Errorf("unlinked payment: %v, %v; want no disputes", none, err)
+	}
+}
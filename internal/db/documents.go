@@ -43,6 +43,7 @@ func NewDocumentRepository() *DocumentRepository {
 }
 
 func (repo *DocumentRepository) Create(ctx context.Context, d *Document) error {
+	clearServerFields(&d.ID, &d.CreatedAt, &d.UpdatedAt)
 	const query = `
 		INSERT INTO shipman.documents (
 			charter_detail_id, uploaded_by, filename, original_filename,
@@ -96,6 +97,10 @@ func (repo *DocumentRepository) Retrieve(ctx context.Context, id uuid.UUID) (Doc
 }
 
 func (repo *DocumentRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Document, error) {
+	if err := checkOffset(offset); err != nil {
+		return nil, err
+	}
+
 	const query = `
 		SELECT id, charter_detail_id, uploaded_by, filename, original_filename,
 			   content_type, file_size, storage_path, status, extracted_text,
@@ -168,20 +173,17 @@ func scanDocuments(rows *sql.Rows) ([]Document, error) {
 
 func (repo *DocumentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	const query = `UPDATE shipman.documents SET status = $2 WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, status)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id, status))
 }
 
 func (repo *DocumentRepository) UpdateExtractedText(ctx context.Context, id uuid.UUID, text string) error {
 	const query = `UPDATE shipman.documents SET extracted_text = $2 WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, text)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id, text))
 }
 
 func (repo *DocumentRepository) UpdateAIAnalysis(ctx context.Context, id uuid.UUID, analysis json.RawMessage) error {
 	const query = `UPDATE shipman.documents SET ai_analysis = $2 WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, analysis)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id, analysis))
 }
 
 func (repo *DocumentRepository) Delete(ctx context.Context, id uuid.UUID) error {
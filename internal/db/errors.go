@@ -0,0 +1,61 @@
+package db
+
+import "errors"
+
+// ErrNotFound is returned when an update targets a row that does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidTransition is returned when a status change is not allowed from
+// the row's current status.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrTooManyPorts is returned when adding ports would take a voyage past
+// MaxVoyagePorts.
+var ErrTooManyPorts = errors.New("too many ports for voyage")
+
+// ErrInvalidUNLocode is returned when a port's UN/LOCODE is malformed.
+var ErrInvalidUNLocode = errors.New("invalid UN/LOCODE")
+
+// ErrInvalidStatus is returned when a requested status is not one the
+// operation can set.
+var ErrInvalidStatus = errors.New("invalid status")
+
+// ErrEndBeforeStart is returned when an end time precedes the start time of
+// an entry it would close.
+var ErrEndBeforeStart = errors.New("end time is before start time")
+
+// ErrCharterNotClosed is returned by operations that only apply once a
+// charter has closed.
+var ErrCharterNotClosed = errors.New("charter is not closed")
+
+// ErrInvalidCharterDates is returned when a charter's end date is not after
+// its start date or the span exceeds the configured maximum duration.
+var ErrInvalidCharterDates = errors.New("invalid charter dates")
+
+// ErrInvalidJSON is returned when a value bound for a jsonb column is not
+// valid JSON.
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+// ErrInvalidExclusion is returned when a laytime entry's excluded hours are
+// negative or exceed its gross interval.
+var ErrInvalidExclusion = errors.New("excluded hours exceed entry duration")
+
+// ErrNotesTooLong is returned when a notes, remarks or description field
+// exceeds MaxNotesLength.
+var ErrNotesTooLong = errors.New("text field too long")
+
+// ErrHasActiveDependents is returned when a charter would go back to draft or
+// be cancelled while voyages or payments under it are still in progress.
+var ErrHasActiveDependents = errors.New("charter has active voyages or payments")
+
+// ErrHasOpenDisputes is returned when a charter would be closed while a
+// dispute on it is still unresolved.
+var ErrHasOpenDisputes = errors.New("charter has open disputes")
+
+// ErrNonMonotonicBatch is returned by a strict position batch whose
+// recorded_at timestamps go backwards.
+var ErrNonMonotonicBatch = errors.New("positions are not in time order")
+
+// ErrInvalidPassword is returned when a user is given an empty password, or
+// is written with a password hash that is blank or not a bcrypt hash.
+var ErrInvalidPassword = errors.New("password missing or not hashed")
@@ -0,0 +1,32 @@
+package db
+
+import "fmt"
+
+// conditions accumulates AND-ed WHERE clauses and their positional args for
+// dynamically filtered queries. Each cond holds a single %d verb that is
+// replaced with the arg's $n placeholder.
+type conditions struct {
+	sql  string
+	args []any
+}
+
+func (c *conditions) add(cond string, arg any) {
+	c.args = append(c.args, arg)
+	c.sql += fmt.Sprintf(" AND "+cond, len(c.args))
+}
+
+// addBool filters a nullable boolean column three ways: nil means any value
+// (no clause), otherwise col must equal *v. Rows where col is NULL match
+// neither true nor false.
+func (c *conditions) addBool(col string, v *bool) {
+	if v == nil {
+		return
+	}
+	c.add(col+" = $%d", *v)
+}
+
+// page appends ORDER BY orderBy with LIMIT/OFFSET placeholders.
+func (c *conditions) page(orderBy string, limit, offset int) {
+	c.args = append(c.args, limit, offset)
+	c.sql += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, len(c.args)-1, len(c.args))
+}
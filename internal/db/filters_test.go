@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestConditionsAddBool(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		v        *bool
+		wantSQL  string
+		wantArgs []any
+	}{
+		{"true", &yes, " AND hazardous = $2", []any{"x", true}},
+		{"false", &no, " AND hazardous = $2", []any{"x", false}},
+		{"unspecified", nil, "", []any{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var where conditions
+			where.add("name = $%d", "x")
+			where.addBool("hazardous", tt.v)
+			if got := where.sql; got != " AND name = $1"+tt.wantSQL {
+				t.Errorf("sql = %q, want %q", got, " AND name = $1"+tt.wantSQL)
+			}
+			if !slices.Equal(where.args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", where.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestConditionsPage(t *testing.T) {
+	var where conditions
+	where.page("created_at DESC", 10, 20)
+	if want := " ORDER BY created_at DESC LIMIT $1 OFFSET $2"; where.sql != want {
+		t.Errorf("sql = %q, want %q", where.sql, want)
+	}
+
+	yes := true
+	where = conditions{}
+	where.add("voyage_id = $%d", "v")
+	where.addBool("hazardous", &yes)
+	where.page("id", 5, 0)
+	if want := " AND voyage_id = $1 AND hazardous = $2 ORDER BY id LIMIT $3 OFFSET $4"; where.sql != want {
+		t.Errorf("sql = %q, want %q", where.sql, want)
+	}
+	if want := []any{"v", true, 5, 0}; !slices.Equal(where.args, want) {
+		t.Errorf("args = %v, want %v", where.args, want)
+	}
+}
+
+var hazardousFilter = regexp.MustCompile(`hazardous = \$(\d+)`)
+
+// stubCargoTable answers Search from loads, applying the hazardous filter
+// when the statement carries one.
+func stubCargoTable(fake *dbtest.Fake, loads []CargoLoad) {
+	fake.On("FROM shipman.cargo_loads WHERE TRUE", func(call dbtest.Call) dbtest.Result {
+		var want *bool
+		if m := hazardousFilter.FindStringSubmatch(call.Query); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			v := call.Arg(n).(bool)
+			want = &v
+		}
+		var rows [][]any
+		for _, l := range loads {
+			if want != nil && (l.Hazardous == nil || *l.Hazardous != *want) {
+				continue
+			}
+			var hazardous any
+			if l.Hazardous != nil {
+				hazardous = *l.Hazardous
+			}
+			rows = append(rows, []any{l.ID, l.VoyageID, nil, nil, nil, hazardous, time.Now(), time.Now()})
+		}
+		return dbtest.Rows([]string{"id", "voyage_id", "commodity", "quantity", "unit", "hazardous", "created_at", "updated_at"}, rows...)
+	})
+}
+
+func TestCargoLoadSearchHazardous(t *testing.T) {
+	yes, no := true, false
+	loads := []CargoLoad{
+		{ID: uuid.New(), VoyageID: uuid.New(), Hazardous: &yes},
+		{ID: uuid.New(), VoyageID: uuid.New(), Hazardous: &no},
+		{ID: uuid.New(), VoyageID: uuid.New()},
+	}
+	tests := []struct {
+		name   string
+		filter *bool
+		want   []uuid.UUID
+	}{
+		{"true", &yes, []uuid.UUID{loads[0].ID}},
+		{"false excludes unknown", &no, []uuid.UUID{loads[1].ID}},
+		{"unspecified", nil, []uuid.UUID{loads[0].ID, loads[1].ID, loads[2].ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCargoTable(fake, loads)
+
+			got, err := NewCargoLoadRepository().Search(context.Background(), CargoLoadFilter{Hazardous: tt.filter}, Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]uuid.UUID, len(got))
+			for i, l := range got {
+				ids[i] = l.ID
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("loads = %v, want %v", ids, tt.want)
+			}
+			if got[len(got)-1].Hazardous != nil && tt.filter == nil {
+				t.Error("unknown hazardous flag was scanned as set")
+			}
+		})
+	}
+}
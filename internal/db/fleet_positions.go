@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FleetPosition is the most recent position of an in-progress voyage,
+// tagged with the vessel it belongs to.
+type FleetPosition struct {
+	VoyageID     uuid.UUID `json:"voyage_id"`
+	VoyageNumber *string   `json:"voyage_number,omitempty"`
+	VesselName   *string   `json:"vessel_name,omitempty"`
+	IMONumber    *string   `json:"imo_number,omitempty"`
+	PositionID   uuid.UUID `json:"position_id"`
+	RecordedAt   time.Time `json:"recorded_at"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	SpeedKnots   *float64  `json:"speed_knots,omitempty"`
+	Heading      *float64  `json:"heading,omitempty"`
+	Source       string    `json:"source"`
+}
+
+// FleetPositionsSince returns the latest position of every voyage that has
+// departed but not arrived, skipping archived voyages and any whose latest
+// position was recorded at or before since. Results are ordered by vessel
+// name.
+func (repo *ShipPositionRepository) FleetPositionsSince(ctx context.Context, since time.Time) ([]FleetPosition, error) {
+	query := `
+		SELECT v.id, v.voyage_number, v.vessel_name, v.imo_number,
+		       p.id, p.recorded_at, p.latitude, p.longitude, p.speed_knots, p.heading, p.source
+		FROM shipman.voyages v
+		JOIN LATERAL (
+			SELECT id, recorded_at, latitude, longitude, speed_knots, heading, source
+			FROM shipman.ship_positions
+			WHERE voyage_id = v.id
+			ORDER BY recorded_at DESC, id DESC
+			LIMIT 1
+		) p ON TRUE
+		WHERE v.actual_departure_at IS NOT NULL
+		  AND v.actual_arrival_at IS NULL
+		  AND v.archived_at IS NULL
+		  AND p.recorded_at > $1
+		ORDER BY ` + orderBy(asc("v.vessel_name"), asc("v.id"))
+
+	rows, err := Pool.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FleetPosition
+	for rows.Next() {
+		var (
+			fp           FleetPosition
+			voyageNumber sql.NullString
+			vesselName   sql.NullString
+			imo          sql.NullString
+			speed        sql.NullFloat64
+			heading      sql.NullFloat64
+			source       sql.NullString
+		)
+		if err := rows.Scan(
+			&fp.VoyageID,
+			&voyageNumber,
+			&vesselName,
+			&imo,
+			&fp.PositionID,
+			&fp.RecordedAt,
+			&fp.Latitude,
+			&fp.Longitude,
+			&speed,
+			&heading,
+			&source,
+		); err != nil {
+			return nil, err
+		}
+		fp.VoyageNumber = stringPtr(voyageNumber)
+		fp.VesselName = stringPtr(vesselName)
+		fp.IMONumber = stringPtr(imo)
+		fp.SpeedKnots = floatPtr(speed)
+		fp.Heading = floatPtr(heading)
+		fp.Source = defaultString(source, "manual")
+		out = append(out, fp)
+	}
+	return out, rows.Err()
+}
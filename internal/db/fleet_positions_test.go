@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var fleetPositionColumns = []string{
+	"id", "voyage_number", "vessel_name", "imo_number",
+	"position_id", "recorded_at", "latitude", "longitude", "speed_knots", "heading", "source",
+}
+
+type fleetVoyage struct {
+	id                uuid.UUID
+	vessel            string
+	departed, arrived bool
+	archived          bool
+	positions         []time.Time
+}
+
+// stubFleetPositions answers FleetPositionsSince from voyages: the latest
+// position of each departed, unarrived, unarchived voyage newer than $1,
+// ordered by vessel name.
+func stubFleetPositions(fake *dbtest.Fake, voyages []fleetVoyage) {
+	fake.On("JOIN LATERAL", func(call dbtest.Call) dbtest.Result {
+		since := call.Arg(1).(time.Time)
+		var rows [][]any
+		for _, v := range voyages {
+			if !v.departed || v.arrived || v.archived || len(v.positions) == 0 {
+				continue
+			}
+			latest := slices.MaxFunc(v.positions, func(a, b time.Time) int { return a.Compare(b) })
+			if !latest.After(since) {
+				continue
+			}
+			rows = append(rows, []any{v.id, nil, v.vessel, nil, uuid.New(), latest, 1.29, 103.85, nil, nil, nil})
+		}
+		slices.SortFunc(rows, func(a, b []any) int { return strings.Compare(a[2].(string), b[2].(string)) })
+		return dbtest.Rows(fleetPositionColumns, rows...)
+	})
+}
+
+func TestFleetPositionsSince(t *testing.T) {
+	now := time.Date(2026, 6, 2, 8, 0, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+	fresh := fleetVoyage{id: uuid.New(), vessel: "Nordic Star", departed: true,
+		positions: []time.Time{now.Add(-30 * time.Hour), now.Add(-2 * time.Hour)}}
+	alsoFresh := fleetVoyage{id: uuid.New(), vessel: "Atlantic Dawn", departed: true,
+		positions: []time.Time{now.Add(-time.Hour)}}
+	stale := fleetVoyage{id: uuid.New(), vessel: "Baltic Trader", departed: true,
+		positions: []time.Time{now.Add(-48 * time.Hour), now.Add(-25 * time.Hour)}}
+	onBoundary := fleetVoyage{id: uuid.New(), vessel: "Cape Horn", departed: true,
+		positions: []time.Time{since}}
+	arrived := fleetVoyage{id: uuid.New(), vessel: "Delta Queen", departed: true, arrived: true,
+		positions: []time.Time{now.Add(-time.Hour)}}
+	archived := fleetVoyage{id: uuid.New(), vessel: "Eastern Sun", departed: true, archived: true,
+		positions: []time.Time{now.Add(-time.Hour)}}
+	notSailed := fleetVoyage{id: uuid.New(), vessel: "Fjord Runner"}
+
+	fake := newFakeDB(t)
+	stubFleetPositions(fake, []fleetVoyage{fresh, alsoFresh, stale, onBoundary, arrived, archived, notSailed})
+
+	got, err := NewShipPositionRepository().FleetPositionsSince(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uuid.UUID, len(got))
+	for i, fp := range got {
+		ids[i] = fp.VoyageID
+	}
+	if want := []uuid.UUID{alsoFresh.id, fresh.id}; !slices.Equal(ids, want) {
+		t.Fatalf("voyages = %v, want only the fresh ones %v", ids, want)
+	}
+	if !got[1].RecordedAt.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("recorded_at = %s, want the latest position", got[1].RecordedAt)
+	}
+	if got[0].VesselName == nil || *got[0].VesselName != "Atlantic Dawn" {
+		t.Errorf("vessel name = %v, want Atlantic Dawn", got[0].VesselName)
+	}
+	if got[0].Source != "manual" || got[0].SpeedKnots != nil {
+		t.Errorf("position = %+v, want a manual source and no speed", got[0])
+	}
+
+	q := fake.Calls("JOIN LATERAL")[0].Query
+	for _, want := range []string{
+		"v.actual_departure_at IS NOT NULL", "v.actual_arrival_at IS NULL",
+		"v.archived_at IS NULL", "p.recorded_at > $1", "ORDER BY recorded_at DESC, id DESC LIMIT 1",
+	} {
+		if !strings.Contains(q, want) {
+			t.Errorf("query is missing %q: %s", want, q)
+		}
+	}
+}
+
+func TestFleetPositionsSinceEmpty(t *testing.T) {
+	fake := newFakeDB(t)
+	stubFleetPositions(fake, nil)
+	got, err := NewShipPositionRepository().FleetPositionsSince(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("positions = %+v, want none", got)
+	}
+}
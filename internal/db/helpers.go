@@ -2,6 +2,10 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -53,6 +57,31 @@ func nullableBytes(b []byte) any {
 	return b
 }
 
+// nullableJSON passes a jsonb value through as its text, or NULL when empty.
+func nullableJSON(b json.RawMessage) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return []byte(b)
+}
+
+// jsonOrNil copies a scanned jsonb value, mapping SQL NULL to nil.
+func jsonOrNil(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.RawMessage(bytesOrNil(b))
+}
+
+// checkJSON rejects a non-empty jsonb value that is not valid JSON before it
+// is sent to the database.
+func checkJSON(field string, b json.RawMessage) error {
+	if len(b) > 0 && !json.Valid(b) {
+		return fmt.Errorf("%w: %s", ErrInvalidJSON, field)
+	}
+	return nil
+}
+
 func nullableInt16(i *int16) any {
 	if i == nil {
 		return nil
@@ -114,3 +143,45 @@ func int16Ptr(ni sql.NullInt16) *int16 {
 	v := ni.Int16
 	return &v
 }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes LIKE/ILIKE metacharacters so s matches literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// clearServerFields zeroes the id and timestamp fields of a record about to
+// be inserted. Create methods call it first so values bound from a client
+// request never survive: the database assigns them and RETURNING writes the
+// real values back.
+func clearServerFields(id *uuid.UUID, stamps ...*time.Time) {
+	*id = uuid.Nil
+	for _, t := range stamps {
+		*t = time.Time{}
+	}
+}
+
+// notFound maps the sql.ErrNoRows from an UPDATE ... RETURNING that matched
+// nothing to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// requireRow turns an Exec result that touched no rows into ErrNotFound.
+func requireRow(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+
+	"shipman/internal/db/dbtest"
+)
+
+// newFakeDB installs a dbtest.Fake as Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := Pool
+	SetPool(pool)
+	t.Cleanup(func() {
+		SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+// charterColumns are the columns CharterDetailRepository.Retrieve scans, in
+// order.
+var charterColumns = []string{
+	"id", "org_id", "created_by_user_id", "title", "charter_reference_code",
+	"vessel_name", "counterparty_name", "status", "start_date", "end_date",
+	"laytime_allowance_hours", "demurrage_rate", "demurrage_currency",
+	"fuel_clause", "payment_terms", "ai_status", "ai_document_path",
+	"ai_extracted_terms", "last_reviewed_at", "notes", "laytime_reversible",
+	"default_currency", "despatch_rate", "archived_at", "created_at", "updated_at",
+}
+
+// voyageColumns are the columns VoyageRepository.Retrieve scans, in order.
+var voyageColumns = []string{
+	"id", "org_id", "charter_detail_id", "deal_id", "owner_user_id",
+	"voyage_number", "vessel_name", "imo_number", "vessel_type", "dwt", "flag_state",
+	"departure_port", "arrival_port",
+	"planned_departure_at", "planned_arrival_at", "actual_departure_at", "actual_arrival_at",
+	"distance_nm", "time_at_sea_hours", "fuel_consumed_mt", "fuel_type", "weather_summary",
+	"hire_rate", "freight_rate", "cargo_quantity", "cargo_type",
+	"laytime_allowed_hours", "demurrage_rate", "despatch_rate", "demurrage_currency",
+	"payment_frequency", "first_payment_date", "total_contract_value",
+	"commission_rate", "bunker_cost", "port_costs", "insurance_cost",
+	"counterparty_name", "counterparty_email", "counterparty_user_id", "broker_user_id",
+	"document_id", "charter_type", "status", "notes", "archived_at", "created_at", "updated_at",
+}
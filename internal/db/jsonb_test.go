@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// jsonbField drives one jsonb column through its repository: create stores
+// the value, retrieve reads it back, and arg is the insert placeholder that
+// carries it. returning answers the insert.
+type jsonbField struct {
+	name      string
+	insert    string
+	arg       int
+	returning func() dbtest.Result
+	retrieve  string
+	columns   []string
+	column    string
+	create    func(ctx context.Context, v json.RawMessage) error
+	read      func(ctx context.Context) (any, error)
+}
+
+func jsonbFields() []jsonbField {
+	vessels, cargo, charters := NewVesselRepository(), NewCargoLoadRepository(), NewCharterDetailRepository()
+	vesselID, loadID, charterID := uuid.New(), uuid.New(), uuid.New()
+	created := func() dbtest.Result {
+		return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{uuid.New(), time.Now(), time.Now()})
+	}
+	return []jsonbField{
+		{
+			name: "vessel capacity", insert: "INSERT INTO shipman.vessels", arg: 9, returning: created,
+			retrieve: "FROM shipman.vessels WHERE id = $1", column: "capacity",
+			columns: []string{"id", "name", "imo_number", "flag_state", "vessel_type", "call_sign",
+				"deadweight_tonnage", "gross_tonnage", "net_tonnage", "capacity", "build_year",
+				"class_society", "owner", "manager", "documentation_uri", "notes", "created_at", "updated_at"},
+			create: func(ctx context.Context, v json.RawMessage) error {
+				return vessels.Create(ctx, &Vessel{Name: "Nordic Star", Capacity: v})
+			},
+			read: func(ctx context.Context) (any, error) { return vessels.Retrieve(ctx, vesselID) },
+		},
+		{
+			name: "cargo stowage plan", insert: "INSERT INTO shipman.cargo_loads", arg: 7, returning: created,
+			retrieve: "FROM shipman.cargo_loads WHERE id = $1", column: "stowage_plan",
+			columns: []string{"id", "voyage_id", "load_port", "discharge_port", "commodity", "quantity",
+				"unit", "stowage_plan", "hazardous", "notes", "created_at", "updated_at"},
+			create: func(ctx context.Context, v json.RawMessage) error {
+				return cargo.Create(ctx, &CargoLoad{VoyageID: uuid.New(), StowagePlan: v})
+			},
+			read: func(ctx context.Context) (any, error) { return cargo.Retrieve(ctx, loadID) },
+		},
+		{
+			name: "charter extracted terms", insert: "INSERT INTO shipman.charter_details", arg: 16,
+			returning: func() dbtest.Result {
+				return dbtest.Rows([]string{"id", "status", "ai_status", "created_at", "updated_at"},
+					[]any{uuid.New(), "draft", "pending", time.Now(), time.Now()})
+			},
+			retrieve: "FROM shipman.charter_details WHERE id = $1", column: "ai_extracted_terms",
+			columns: charterColumns,
+			create: func(ctx context.Context, v json.RawMessage) error {
+				return charters.Create(ctx, &CharterDetail{Title: "Grain", AIExtractedTerms: v})
+			},
+			read: func(ctx context.Context) (any, error) { return charters.Retrieve(ctx, charterID) },
+		},
+	}
+}
+
+func TestJSONBRoundTrip(t *testing.T) {
+	const value = `{"holds": [{"no": 1, "teu": 400}], "reefer": true}`
+	for _, f := range jsonbFields() {
+		t.Run(f.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			var stored any
+			fake.On(f.insert, func(call dbtest.Call) dbtest.Result {
+				stored = call.Arg(f.arg)
+				return f.returning()
+			})
+			// A jsonb column comes back from the driver as text.
+			fake.On(f.retrieve, func(dbtest.Call) dbtest.Result {
+				values := map[string]any{
+					"id": uuid.New(), "voyage_id": uuid.New(), "org_id": DefaultOrgID, "name": "Nordic Star",
+					"title": "Grain", "laytime_reversible": false, "created_at": time.Now(), "updated_at": time.Now(),
+					f.column: string(stored.([]byte)),
+				}
+				return dbtest.Rows(f.columns, dbtest.Row(f.columns, values))
+			})
+			ctx := context.Background()
+
+			if err := f.create(ctx, json.RawMessage(value)); err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := stored.([]byte); !ok || string(got) != value {
+				t.Fatalf("stored %T %v, want the JSON text", stored, stored)
+			}
+			record, err := f.read(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := json.Marshal(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded map[string]json.RawMessage
+			if err := json.Unmarshal(out, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			var want, got any
+			_ = json.Unmarshal([]byte(value), &want)
+			if err := json.Unmarshal(decoded[f.column], &got); err != nil {
+				t.Fatalf("%s = %s, want embedded JSON: %v", f.column, decoded[f.column], err)
+			}
+			if gotJSON, _ := json.Marshal(got); string(gotJSON) != mustMarshal(t, want) {
+				t.Errorf("%s = %s, want %s", f.column, gotJSON, mustMarshal(t, want))
+			}
+		})
+	}
+}
+
+func TestJSONBRejectsInvalid(t *testing.T) {
+	for _, f := range jsonbFields() {
+		t.Run(f.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			err := f.create(context.Background(), json.RawMessage(`{"holds": [`))
+			if !errors.Is(err, ErrInvalidJSON) {
+				t.Errorf("err = %v, want ErrInvalidJSON", err)
+			}
+			if len(fake.Calls("")) != 0 {
+				t.Error("invalid JSON reached the database")
+			}
+		})
+	}
+}
+
+func TestJSONBEmptyIsNull(t *testing.T) {
+	for _, f := range jsonbFields() {
+		t.Run(f.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(f.insert, f.returning())
+			if err := f.create(context.Background(), nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := fake.Calls(f.insert)[0].Arg(f.arg); got != nil {
+				t.Errorf("empty value bound as %v, want NULL", got)
+			}
+		})
+	}
+}
+
+func TestJSONBColumnTypes(t *testing.T) {
+	for file, column := range map[string]string{
+		"000001_init.sql":            "ai_extracted_terms",
+		"000004_add_vessels.sql":     "capacity",
+		"000005_add_cargo_loads.sql": "stowage_plan",
+	} {
+		raw, err := os.ReadFile("../../db/migrations/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !regexp.MustCompile(`(?m)^\s*` + column + `\s+JSONB\b`).Match(raw) {
+			t.Errorf("%s is not declared JSONB in %s", column, file)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
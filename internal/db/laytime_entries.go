@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,17 +19,55 @@ type LaytimeEntry struct {
 	StartedAt       time.Time  `json:"started_at"`
 	EndedAt         *time.Time `json:"ended_at,omitempty"`
 	HoursCounted    *float64   `json:"hours_counted,omitempty"`
+	ExcludedHours   float64    `json:"excluded_hours"`
 	Remarks         *string    `json:"remarks,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// CountedHours is the laytime an interval uses: its gross duration less
+// excluded hours such as weather-working time.
+func CountedHours(start, end time.Time, excluded float64) float64 {
+	return end.Sub(start).Hours() - excluded
+}
+
+// checkExclusions rejects negative exclusions and, once the entry has ended,
+// exclusions longer than its gross interval.
+func checkExclusions(entry *LaytimeEntry) error {
+	if entry.ExcludedHours < 0 {
+		return ErrInvalidExclusion
+	}
+	if entry.EndedAt != nil && entry.ExcludedHours > entry.EndedAt.Sub(entry.StartedAt).Hours() {
+		return ErrInvalidExclusion
+	}
+	return nil
+}
+
+// prepareLaytimeEntry fills hours_counted from the entry's interval when it
+// was not given and runs the checks every insert applies. Preview uses it too
+// so a dry run reports exactly what Create and CreateBatch would.
+func prepareLaytimeEntry(entry *LaytimeEntry) error {
+	if entry.HoursCounted == nil && entry.EndedAt != nil {
+		hrs := CountedHours(entry.StartedAt, *entry.EndedAt, entry.ExcludedHours)
+		entry.HoursCounted = &hrs
+	}
+	if err := sanitizeNotes(notesField{"remarks", entry.Remarks}); err != nil {
+		return err
+	}
+	return checkExclusions(entry)
+}
+
 // LaytimeEntryService describes CRUD behaviour.
 type LaytimeEntryService interface {
 	Create(ctx context.Context, entry *LaytimeEntry) error
+	CreateBatch(ctx context.Context, entries []*LaytimeEntry) (BatchResult, error)
+	Preview(entries []*LaytimeEntry) BatchErrors
 	Retrieve(ctx context.Context, id uuid.UUID) (LaytimeEntry, error)
 	ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]LaytimeEntry, error)
 	ListByCharter(ctx context.Context, charterID uuid.UUID) ([]LaytimeEntry, error)
+	ListOpen(ctx context.Context, charterID uuid.UUID) ([]LaytimeEntry, error)
+	ListAllOpen(ctx context.Context, page Page) ([]LaytimeEntry, error)
+	CloseOpen(ctx context.Context, charterID uuid.UUID, portName string, endedAt time.Time) (int64, error)
 	Update(ctx context.Context, entry *LaytimeEntry) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -41,8 +80,13 @@ func NewLaytimeEntryRepository() *LaytimeEntryRepository {
 	return &LaytimeEntryRepository{}
 }
 
-// Create inserts a laytime entry.
+// Create inserts a laytime entry, deriving hours_counted from its start and
+// end when not given.
 func (repo *LaytimeEntryRepository) Create(ctx context.Context, entry *LaytimeEntry) error {
+	clearServerFields(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
+	if err := prepareLaytimeEntry(entry); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.laytime_entries (
 			charter_detail_id,
@@ -52,15 +96,16 @@ func (repo *LaytimeEntryRepository) Create(ctx context.Context, entry *LaytimeEn
 			started_at,
 			ended_at,
 			hours_counted,
-			remarks
+			remarks,
+			excluded_hours
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
 		)
 		RETURNING id, created_at, updated_at
 	`
 
 	charterID := &entry.CharterDetailID
-	return Pool.QueryRowContext(
+	return Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		nullableUUID(charterID),
@@ -71,9 +116,100 @@ func (repo *LaytimeEntryRepository) Create(ctx context.Context, entry *LaytimeEn
 		nullableTime(entry.EndedAt),
 		nullableFloat(entry.HoursCounted),
 		nullableString(entry.Remarks),
+		entry.ExcludedHours,
 	).Scan(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
 }
 
+// BatchResult counts the rows a batch upsert inserted and updated.
+type BatchResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+}
+
+// CreateBatch upserts entries in one transaction, keyed on charter, port
+// name, activity and start time. An entry matching an existing row replaces
+// its voyage, end time, hours and remarks, so importing the same report twice
+// leaves one row per entry. Each entry gets the id and timestamps of the row
+// it landed on; hours_counted is derived as in Create.
+func (repo *LaytimeEntryRepository) CreateBatch(ctx context.Context, entries []*LaytimeEntry) (BatchResult, error) {
+	const query = `
+		INSERT INTO shipman.laytime_entries (
+			charter_detail_id,
+			voyage_id,
+			port_name,
+			activity,
+			started_at,
+			ended_at,
+			hours_counted,
+			remarks,
+			excluded_hours
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		ON CONFLICT (charter_detail_id, port_name, activity, started_at) DO UPDATE SET
+			voyage_id = EXCLUDED.voyage_id,
+			ended_at = EXCLUDED.ended_at,
+			hours_counted = EXCLUDED.hours_counted,
+			remarks = EXCLUDED.remarks,
+			excluded_hours = EXCLUDED.excluded_hours,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at, (xmax = 0) AS inserted
+	`
+
+	var res BatchResult
+	err := WithTx(ctx, func(ctx context.Context) error {
+		for i, entry := range entries {
+			clearServerFields(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
+			if err := prepareLaytimeEntry(entry); err != nil {
+				return fmt.Errorf("entry %d: %w", i, err)
+			}
+			var inserted bool
+			if err := Conn(ctx).QueryRowContext(
+				ctx,
+				query,
+				entry.CharterDetailID,
+				nullableUUID(entry.VoyageID),
+				entry.PortName,
+				entry.Activity,
+				entry.StartedAt,
+				nullableTime(entry.EndedAt),
+				nullableFloat(entry.HoursCounted),
+				nullableString(entry.Remarks),
+				entry.ExcludedHours,
+			).Scan(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt, &inserted); err != nil {
+				return err
+			}
+			if inserted {
+				res.Inserted++
+			} else {
+				res.Updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return BatchResult{}, err
+	}
+	return res, nil
+}
+
+// Preview applies CreateBatch's auto-fill and checks to entries in place
+// without touching the database, returning the error for each index that
+// would be rejected. Rows that pass hold the values CreateBatch would write.
+func (repo *LaytimeEntryRepository) Preview(entries []*LaytimeEntry) BatchErrors {
+	var errs BatchErrors
+	for i, entry := range entries {
+		clearServerFields(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
+		if err := prepareLaytimeEntry(entry); err != nil {
+			if errs == nil {
+				errs = make(BatchErrors)
+			}
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
 // Retrieve fetches an entry by id.
 func (repo *LaytimeEntryRepository) Retrieve(ctx context.Context, id uuid.UUID) (LaytimeEntry, error) {
 	const query = `
@@ -86,6 +222,7 @@ func (repo *LaytimeEntryRepository) Retrieve(ctx context.Context, id uuid.UUID)
 			started_at,
 			ended_at,
 			hours_counted,
+			excluded_hours,
 			remarks,
 			created_at,
 			updated_at
@@ -111,6 +248,7 @@ func (repo *LaytimeEntryRepository) Retrieve(ctx context.Context, id uuid.UUID)
 		&entry.StartedAt,
 		&end,
 		&hours,
+		&entry.ExcludedHours,
 		&remarks,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
@@ -141,7 +279,7 @@ func (repo *LaytimeEntryRepository) Retrieve(ctx context.Context, id uuid.UUID)
 // ListByVoyage returns entries for a voyage.
 func (repo *LaytimeEntryRepository) ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]LaytimeEntry, error) {
 	const query = `
-		SELECT id, charter_detail_id, voyage_id, port_name, activity, started_at, ended_at, hours_counted, remarks, created_at, updated_at
+		SELECT id, charter_detail_id, voyage_id, port_name, activity, started_at, ended_at, hours_counted, excluded_hours, remarks, created_at, updated_at
 		FROM shipman.laytime_entries
 		WHERE voyage_id = $1
 		ORDER BY started_at
@@ -171,6 +309,7 @@ func (repo *LaytimeEntryRepository) ListByVoyage(ctx context.Context, voyageID u
 			&entry.StartedAt,
 			&end,
 			&hours,
+			&entry.ExcludedHours,
 			&remarks,
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
@@ -195,7 +334,7 @@ func (repo *LaytimeEntryRepository) ListByVoyage(ctx context.Context, voyageID u
 // ListByCharter returns entries for a charter.
 func (repo *LaytimeEntryRepository) ListByCharter(ctx context.Context, charterID uuid.UUID) ([]LaytimeEntry, error) {
 	const query = `
-		SELECT id, charter_detail_id, voyage_id, port_name, activity, started_at, ended_at, hours_counted, remarks, created_at, updated_at
+		SELECT id, charter_detail_id, voyage_id, port_name, activity, started_at, ended_at, hours_counted, excluded_hours, remarks, created_at, updated_at
 		FROM shipman.laytime_entries
 		WHERE charter_detail_id = $1
 		ORDER BY started_at
@@ -225,6 +364,7 @@ func (repo *LaytimeEntryRepository) ListByCharter(ctx context.Context, charterID
 			&entry.StartedAt,
 			&end,
 			&hours,
+			&entry.ExcludedHours,
 			&remarks,
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
@@ -246,8 +386,130 @@ func (repo *LaytimeEntryRepository) ListByCharter(ctx context.Context, charterID
 	return entries, rows.Err()
 }
 
+// ListOpen returns a charter's entries that are still running (no ended_at),
+// oldest first.
+func (repo *LaytimeEntryRepository) ListOpen(ctx context.Context, charterID uuid.UUID) ([]LaytimeEntry, error) {
+	const query = `
+		SELECT id, charter_detail_id, voyage_id, port_name, activity, started_at, ended_at, hours_counted, excluded_hours, remarks, created_at, updated_at
+		FROM shipman.laytime_entries
+		WHERE charter_detail_id = $1 AND ended_at IS NULL
+		ORDER BY started_at ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, charterID)
+	if err != nil {
+		return nil, err
+	}
+	return scanLaytimeEntries(rows)
+}
+
+// ListAllOpen returns running entries across every charter, oldest first,
+// for monitoring.
+func (repo *LaytimeEntryRepository) ListAllOpen(ctx context.Context, page Page) ([]LaytimeEntry, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT id, charter_detail_id, voyage_id, port_name, activity, started_at, ended_at, hours_counted, excluded_hours, remarks, created_at, updated_at
+		FROM shipman.laytime_entries
+		WHERE ended_at IS NULL
+		ORDER BY started_at ASC, id ASC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, page.limit(), page.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanLaytimeEntries(rows)
+}
+
+// CloseOpen ends every open entry for the charter at portName (compared
+// case-insensitively) at endedAt, filling hours_counted from started_at less
+// any excluded hours. It
+// returns the number of entries closed. If endedAt precedes any matching
+// entry's start, nothing is changed and ErrEndBeforeStart is returned.
+func (repo *LaytimeEntryRepository) CloseOpen(ctx context.Context, charterID uuid.UUID, portName string, endedAt time.Time) (int64, error) {
+	const query = `
+		WITH open AS (
+			SELECT id, started_at
+			FROM shipman.laytime_entries
+			WHERE charter_detail_id = $1
+			  AND lower(trim(port_name)) = lower(trim($2))
+			  AND ended_at IS NULL
+		), guard AS (
+			SELECT EXISTS (SELECT 1 FROM open WHERE started_at > $3) AS invalid
+		), closed AS (
+			UPDATE shipman.laytime_entries le
+			SET ended_at = $3,
+				hours_counted = GREATEST(EXTRACT(EPOCH FROM ($3 - le.started_at)) / 3600 - le.excluded_hours, 0),
+				updated_at = NOW()
+			FROM guard
+			WHERE le.id IN (SELECT id FROM open) AND NOT guard.invalid
+			RETURNING le.id
+		)
+		SELECT (SELECT invalid FROM guard), (SELECT COUNT(*) FROM closed)
+	`
+
+	var (
+		invalid bool
+		count   int64
+	)
+	if err := Conn(ctx).QueryRowContext(ctx, query, charterID, portName, endedAt).Scan(&invalid, &count); err != nil {
+		return 0, err
+	}
+	if invalid {
+		return 0, ErrEndBeforeStart
+	}
+	return count, nil
+}
+
+func scanLaytimeEntries(rows *sql.Rows) ([]LaytimeEntry, error) {
+	defer rows.Close()
+
+	var entries []LaytimeEntry
+	for rows.Next() {
+		var (
+			entry   LaytimeEntry
+			rawVoy  sql.NullString
+			end     sql.NullTime
+			hours   sql.NullFloat64
+			remarks sql.NullString
+		)
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.CharterDetailID,
+			&rawVoy,
+			&entry.PortName,
+			&entry.Activity,
+			&entry.StartedAt,
+			&end,
+			&hours,
+			&entry.ExcludedHours,
+			&remarks,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entry.VoyageID = uuidPtrNullable(rawVoy)
+		entry.EndedAt = timePtr(end)
+		entry.HoursCounted = floatPtr(hours)
+		entry.Remarks = stringPtr(remarks)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
 // Update modifies a laytime entry.
 func (repo *LaytimeEntryRepository) Update(ctx context.Context, entry *LaytimeEntry) error {
+	if err := sanitizeNotes(notesField{"remarks", entry.Remarks}); err != nil {
+		return err
+	}
+	if err := checkExclusions(entry); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.laytime_entries
 		SET
@@ -258,12 +520,13 @@ func (repo *LaytimeEntryRepository) Update(ctx context.Context, entry *LaytimeEn
 			ended_at = $6,
 			hours_counted = $7,
 			remarks = $8,
+			excluded_hours = $9,
 			updated_at = NOW()
 		WHERE id = $1
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		entry.ID,
@@ -274,7 +537,9 @@ func (repo *LaytimeEntryRepository) Update(ctx context.Context, entry *LaytimeEn
 		nullableTime(entry.EndedAt),
 		nullableFloat(entry.HoursCounted),
 		nullableString(entry.Remarks),
+		entry.ExcludedHours,
 	).Scan(&entry.UpdatedAt)
+	return notFound(err)
 }
 
 // Delete removes a laytime entry.
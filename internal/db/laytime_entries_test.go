@@ -0,0 +1,491 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var laytimeEntryColumns = []string{
+	"id", "charter_detail_id", "voyage_id", "port_name", "activity", "started_at",
+	"ended_at", "hours_counted", "excluded_hours", "remarks", "created_at", "updated_at",
+}
+
+// stubOpenLaytime answers the open-entry lists from entries, applying the
+// charter and ended_at IS NULL filters and the started_at ordering.
+func stubOpenLaytime(fake *dbtest.Fake, entries []LaytimeEntry) {
+	fake.On("FROM shipman.laytime_entries WHERE", func(call dbtest.Call) dbtest.Result {
+		byCharter := strings.Contains(call.Query, "charter_detail_id = $1")
+		var open []LaytimeEntry
+		for _, e := range entries {
+			if e.EndedAt != nil || (byCharter && e.CharterDetailID.String() != call.Arg(1)) {
+				continue
+			}
+			open = append(open, e)
+		}
+		slices.SortFunc(open, func(a, b LaytimeEntry) int { return a.StartedAt.Compare(b.StartedAt) })
+		var rows [][]any
+		for _, e := range open {
+			rows = append(rows, []any{e.ID, e.CharterDetailID, nil, e.PortName, e.Activity, e.StartedAt, nil, nil, 0.0, nil, e.StartedAt, e.StartedAt})
+		}
+		return dbtest.Rows(laytimeEntryColumns, rows...)
+	})
+}
+
+func TestLaytimeListOpen(t *testing.T) {
+	fake := newFakeDB(t)
+	charter, other := uuid.New(), uuid.New()
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	ended := base.Add(6 * time.Hour)
+	entry := func(charterID uuid.UUID, hoursIn int, closed bool) LaytimeEntry {
+		e := LaytimeEntry{ID: uuid.New(), CharterDetailID: charterID, PortName: "Rotterdam", Activity: "loading", StartedAt: base.Add(time.Duration(hoursIn) * time.Hour)}
+		if closed {
+			e.EndedAt = &ended
+		}
+		return e
+	}
+	entries := []LaytimeEntry{
+		entry(charter, 3, false),
+		entry(charter, 0, true),
+		entry(other, 2, false),
+		entry(charter, 1, false),
+		entry(charter, 2, true),
+	}
+	stubOpenLaytime(fake, entries)
+	repo := NewLaytimeEntryRepository()
+
+	got, err := repo.ListOpen(context.Background(), charter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uuid.UUID{entries[3].ID, entries[0].ID}; !slices.Equal(laytimeIDs(got), want) {
+		t.Errorf("charter open entries = %v, want %v", laytimeIDs(got), want)
+	}
+	for _, e := range got {
+		if e.EndedAt != nil {
+			t.Errorf("entry %s has ended", e.ID)
+		}
+	}
+
+	all, err := repo.ListAllOpen(context.Background(), Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uuid.UUID{entries[3].ID, entries[2].ID, entries[0].ID}; !slices.Equal(laytimeIDs(all), want) {
+		t.Errorf("all open entries = %v, want %v", laytimeIDs(all), want)
+	}
+
+	for _, call := range fake.Calls("FROM shipman.laytime_entries") {
+		if !strings.Contains(call.Query, "ended_at IS NULL") || !strings.Contains(call.Query, "ORDER BY started_at ASC") {
+			t.Errorf("query does not filter open entries oldest first: %s", call.Query)
+		}
+	}
+}
+
+func laytimeIDs(entries []LaytimeEntry) []uuid.UUID {
+	ids := make([]uuid.UUID, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// fakeCloseOpen evaluates the CloseOpen statement against entries, closing
+// them in place unless an open entry at the port starts after the end time.
+func fakeCloseOpen(fake *dbtest.Fake, entries []LaytimeEntry) {
+	fake.On("UPDATE shipman.laytime_entries le", func(call dbtest.Call) dbtest.Result {
+		port := strings.ToLower(strings.TrimSpace(call.Arg(2).(string)))
+		endedAt := call.Arg(3).(time.Time)
+		var open []int
+		for i, e := range entries {
+			if e.EndedAt == nil && e.CharterDetailID.String() == call.Arg(1) &&
+				strings.ToLower(strings.TrimSpace(e.PortName)) == port {
+				open = append(open, i)
+			}
+		}
+		for _, i := range open {
+			if entries[i].StartedAt.After(endedAt) {
+				return dbtest.Rows([]string{"invalid", "count"}, []any{true, int64(0)})
+			}
+		}
+		for _, i := range open {
+			hours := max(endedAt.Sub(entries[i].StartedAt).Hours()-entries[i].ExcludedHours, 0)
+			entries[i].EndedAt = &endedAt
+			entries[i].HoursCounted = &hours
+		}
+		return dbtest.Rows([]string{"invalid", "count"}, []any{false, int64(len(open))})
+	})
+}
+
+func TestLaytimeCloseOpen(t *testing.T) {
+	charter, other := uuid.New(), uuid.New()
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	earlier := base.Add(-time.Hour)
+	newEntries := func() []LaytimeEntry {
+		return []LaytimeEntry{
+			{ID: uuid.New(), CharterDetailID: charter, PortName: "Santos", StartedAt: base},
+			{ID: uuid.New(), CharterDetailID: charter, PortName: " santos ", StartedAt: base.Add(2 * time.Hour), ExcludedHours: 1},
+			{ID: uuid.New(), CharterDetailID: charter, PortName: "Santos", StartedAt: earlier, EndedAt: &earlier},
+			{ID: uuid.New(), CharterDetailID: charter, PortName: "Rotterdam", StartedAt: base},
+			{ID: uuid.New(), CharterDetailID: other, PortName: "Santos", StartedAt: base},
+		}
+	}
+
+	t.Run("closes every open entry at the port", func(t *testing.T) {
+		fake := newFakeDB(t)
+		entries := newEntries()
+		fakeCloseOpen(fake, entries)
+		endedAt := base.Add(10 * time.Hour)
+
+		closed, err := NewLaytimeEntryRepository().CloseOpen(context.Background(), charter, "SANTOS", endedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if closed != 2 {
+			t.Errorf("closed = %d, want 2", closed)
+		}
+		for i, want := range []float64{10, 7} {
+			e := entries[i]
+			if e.EndedAt == nil || !e.EndedAt.Equal(endedAt) || e.HoursCounted == nil || *e.HoursCounted != want {
+				t.Errorf("entry %d ended %v with %v hours, want %s with %v", i, e.EndedAt, e.HoursCounted, endedAt, want)
+			}
+		}
+		if !entries[2].EndedAt.Equal(earlier) {
+			t.Errorf("already closed entry re-ended at %s", entries[2].EndedAt)
+		}
+		for _, i := range []int{3, 4} {
+			if entries[i].EndedAt != nil {
+				t.Errorf("entry %d at %s for another port or charter was closed", i, entries[i].PortName)
+			}
+		}
+
+		calls := fake.Calls("UPDATE shipman.laytime_entries le")
+		if len(calls) != 1 {
+			t.Fatalf("statements = %d, want one", len(calls))
+		}
+		for _, part := range []string{"ended_at IS NULL", "lower(trim(port_name)) = lower(trim($2))", "- le.excluded_hours"} {
+			if !strings.Contains(calls[0].Query, part) {
+				t.Errorf("query is missing %q", part)
+			}
+		}
+	})
+
+	t.Run("rejects an end before an open start", func(t *testing.T) {
+		fake := newFakeDB(t)
+		entries := newEntries()
+		fakeCloseOpen(fake, entries)
+
+		closed, err := NewLaytimeEntryRepository().CloseOpen(context.Background(), charter, "Santos", base.Add(time.Hour))
+		if !errors.Is(err, ErrEndBeforeStart) {
+			t.Fatalf("err = %v, want ErrEndBeforeStart", err)
+		}
+		if closed != 0 {
+			t.Errorf("closed = %d, want 0", closed)
+		}
+		for i, e := range entries {
+			if e.EndedAt != nil && i != 2 {
+				t.Errorf("entry %d was closed despite the rejection", i)
+			}
+		}
+	})
+
+	t.Run("no open entries", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fakeCloseOpen(fake, newEntries())
+
+		closed, err := NewLaytimeEntryRepository().CloseOpen(context.Background(), charter, "Houston", base)
+		if err != nil || closed != 0 {
+			t.Errorf("CloseOpen = %d, %v, want 0, nil", closed, err)
+		}
+	})
+}
+
+// laytimeKeyRow is a laytime_entries row as stored by fakeLaytimeUpsert.
+type laytimeKeyRow struct {
+	id      uuid.UUID
+	key     string
+	hours   any
+	remarks any
+}
+
+// fakeLaytimeUpsert keeps laytime_entries rows keyed on the natural key the
+// CreateBatch ON CONFLICT targets. Writes inside a transaction only land
+// when it commits.
+type fakeLaytimeUpsert struct {
+	mu   sync.Mutex
+	rows []*laytimeKeyRow
+}
+
+func (f *fakeLaytimeUpsert) install(fake *dbtest.Fake) {
+	fake.On("INSERT INTO shipman.laytime_entries", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !strings.Contains(call.Query, "ON CONFLICT (charter_detail_id, port_name, activity, started_at) DO UPDATE") {
+			return dbtest.Fail(errors.New("duplicate key value violates unique constraint"))
+		}
+		key := fmt.Sprint(call.Arg(1), "|", call.Arg(3), "|", call.Arg(4), "|", call.Arg(5).(time.Time).UTC())
+		cols := []string{"id", "created_at", "updated_at", "inserted"}
+		for _, r := range f.rows {
+			if r.key == key {
+				r.hours, r.remarks = call.Arg(7), call.Arg(8)
+				return dbtest.Rows(cols, []any{r.id, time.Now(), time.Now(), false})
+			}
+		}
+		r := &laytimeKeyRow{id: uuid.New(), key: key, hours: call.Arg(7), remarks: call.Arg(8)}
+		f.rows = append(f.rows, r)
+		call.Tx.OnEnd(func(committed bool) {
+			if committed {
+				return
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.rows = slices.DeleteFunc(f.rows, func(x *laytimeKeyRow) bool { return x == r })
+		})
+		return dbtest.Rows(cols, []any{r.id, time.Now(), time.Now(), true})
+	})
+}
+
+func noonReport(charterID uuid.UUID, remarks string) []*LaytimeEntry {
+	start := time.Date(2026, 5, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+	return []*LaytimeEntry{
+		{CharterDetailID: charterID, PortName: "Santos", Activity: "loading", StartedAt: start, EndedAt: &end, Remarks: &remarks},
+		{CharterDetailID: charterID, PortName: "Santos", Activity: "waiting", StartedAt: start, Remarks: &remarks},
+		{CharterDetailID: charterID, PortName: "Santos", Activity: "loading", StartedAt: end},
+	}
+}
+
+func TestLaytimeCreateBatchDeduplicates(t *testing.T) {
+	fake := newFakeDB(t)
+	table := &fakeLaytimeUpsert{}
+	table.install(fake)
+	repo := NewLaytimeEntryRepository()
+	ctx := context.Background()
+	charterID := uuid.New()
+
+	first := noonReport(charterID, "first report")
+	res, err := repo.CreateBatch(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (BatchResult{Inserted: 3}) {
+		t.Errorf("first import = %+v, want 3 inserted", res)
+	}
+	if first[0].HoursCounted == nil || *first[0].HoursCounted != 6 {
+		t.Errorf("hours counted = %v, want 6 filled from the interval", first[0].HoursCounted)
+	}
+
+	again := noonReport(charterID, "corrected report")
+	res, err = repo.CreateBatch(ctx, again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (BatchResult{Updated: 3}) {
+		t.Errorf("re-import = %+v, want 3 updated", res)
+	}
+	if len(table.rows) != 3 {
+		t.Fatalf("rows = %d after importing the same batch twice, want 3", len(table.rows))
+	}
+	for i := range again {
+		if again[i].ID != first[i].ID {
+			t.Errorf("entry %d landed on %s, want the existing row %s", i, again[i].ID, first[i].ID)
+		}
+	}
+	if table.rows[0].remarks != "corrected report" {
+		t.Errorf("remarks = %v, want the re-import to update in place", table.rows[0].remarks)
+	}
+
+	other := noonReport(uuid.New(), "other charter")
+	if res, err = repo.CreateBatch(ctx, other); err != nil || res != (BatchResult{Inserted: 3}) {
+		t.Errorf("other charter = %+v, %v; want 3 inserted", res, err)
+	}
+	if fake.Commits() != 3 {
+		t.Errorf("commits = %d, want one per batch", fake.Commits())
+	}
+}
+
+func TestLaytimeCreateBatchRollsBack(t *testing.T) {
+	fake := newFakeDB(t)
+	table := &fakeLaytimeUpsert{}
+	table.install(fake)
+	entries := noonReport(uuid.New(), "report")
+	bad := entries[0].StartedAt.Add(-time.Hour)
+	entries[2].EndedAt = &bad
+
+	if _, err := NewLaytimeEntryRepository().CreateBatch(context.Background(), entries); err == nil || !strings.Contains(err.Error(), "entry 2") {
+		t.Fatalf("err = %v, want entry 2 rejected", err)
+	}
+	if len(table.rows) != 0 {
+		t.Errorf("rows = %d after a rejected batch, want none", len(table.rows))
+	}
+	if fake.Rollbacks() != 1 {
+		t.Errorf("rollbacks = %d, want 1", fake.Rollbacks())
+	}
+}
+
+func TestLaytimeNaturalKeyMigration(t *testing.T) {
+	raw, err := os.ReadFile("../../db/migrations/000035_laytime_entry_natural_key.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := regexp.MustCompile(`CREATE UNIQUE INDEX IF NOT EXISTS idx_laytime_entries_natural_key\s+ON shipman\.laytime_entries\(charter_detail_id, port_name, activity, started_at\)`)
+	if !index.Match(raw) {
+		t.Error("migration does not create the unique index CreateBatch's ON CONFLICT relies on")
+	}
+}
+
+func TestLaytimeExclusions(t *testing.T) {
+	start := time.Date(2026, 5, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(12 * time.Hour)
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		ended     *time.Time
+		excluded  float64
+		given     *float64
+		wantHours any // the stored hours_counted argument
+		wantErr   error
+	}{
+		{"no exclusions", &end, 0, nil, 12.0, nil},
+		{"weather deducted", &end, 3.5, nil, 8.5, nil},
+		{"whole interval excluded", &end, 12, nil, 0.0, nil},
+		{"explicit hours kept", &end, 3, f(10), 10.0, nil},
+		{"open entry", nil, 4, nil, nil, nil},
+		{"exceeds the interval", &end, 12.5, nil, nil, ErrInvalidExclusion},
+		{"negative", &end, -1, nil, nil, ErrInvalidExclusion},
+	}
+	ops := map[string]struct {
+		match string
+		run   func(*LaytimeEntry) error
+	}{
+		"create": {"INSERT INTO shipman.laytime_entries", func(e *LaytimeEntry) error {
+			return NewLaytimeEntryRepository().Create(context.Background(), e)
+		}},
+		"batch": {"INSERT INTO shipman.laytime_entries", func(e *LaytimeEntry) error {
+			_, err := NewLaytimeEntryRepository().CreateBatch(context.Background(), []*LaytimeEntry{e})
+			return err
+		}},
+	}
+	for opName, op := range ops {
+		for _, tt := range tests {
+			t.Run(opName+"/"+tt.name, func(t *testing.T) {
+				fake := newFakeDB(t)
+				fake.On("INSERT INTO shipman.laytime_entries", func(call dbtest.Call) dbtest.Result {
+					if strings.Contains(call.Query, "AS inserted") {
+						return dbtest.Rows([]string{"id", "created_at", "updated_at", "inserted"}, []any{uuid.New(), time.Now(), time.Now(), true})
+					}
+					return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{uuid.New(), time.Now(), time.Now()})
+				})
+				entry := &LaytimeEntry{CharterDetailID: uuid.New(), PortName: "Santos", Activity: "loading",
+					StartedAt: start, EndedAt: tt.ended, ExcludedHours: tt.excluded, HoursCounted: tt.given}
+
+				err := op.run(entry)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				calls := fake.Calls(op.match)
+				if tt.wantErr != nil {
+					if len(calls) != 0 {
+						t.Error("an invalid exclusion was written")
+					}
+					return
+				}
+				if len(calls) != 1 {
+					t.Fatalf("inserts = %d, want 1", len(calls))
+				}
+				if got := calls[0].Arg(7); got != tt.wantHours {
+					t.Errorf("hours_counted = %v, want %v", got, tt.wantHours)
+				}
+				if got := calls[0].Arg(9); got != tt.excluded {
+					t.Errorf("excluded_hours = %v, want %v", got, tt.excluded)
+				}
+			})
+		}
+	}
+}
+
+func TestLaytimeUpdateRejectsExclusions(t *testing.T) {
+	fake := newFakeDB(t)
+	start := time.Date(2026, 5, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	entry := &LaytimeEntry{ID: uuid.New(), PortName: "Santos", StartedAt: start, EndedAt: &end, ExcludedHours: 3}
+
+	if err := NewLaytimeEntryRepository().Update(context.Background(), entry); !errors.Is(err, ErrInvalidExclusion) {
+		t.Errorf("err = %v, want ErrInvalidExclusion", err)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("an invalid exclusion was written")
+	}
+}
+
+func TestLaytimePreviewMatchesCreateBatch(t *testing.T) {
+	fake := newFakeDB(t)
+	table := &fakeLaytimeUpsert{}
+	table.install(fake)
+	repo := NewLaytimeEntryRepository()
+	charterID := uuid.New()
+
+	previewed := noonReport(charterID, "noon report")
+	previewed[0].ExcludedHours = 1.5
+	if errs := repo.Preview(previewed); errs != nil {
+		t.Fatalf("preview errors = %v, want none", errs)
+	}
+	if n := len(fake.Calls("")); n != 0 {
+		t.Fatalf("preview ran %d statements, want none", n)
+	}
+
+	committed := noonReport(charterID, "noon report")
+	committed[0].ExcludedHours = 1.5
+	if _, err := repo.CreateBatch(context.Background(), committed); err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range table.rows {
+		var want any
+		if h := previewed[i].HoursCounted; h != nil {
+			want = *h
+		}
+		if row.hours != want {
+			t.Errorf("entry %d: previewed hours %v, stored %v", i, want, row.hours)
+		}
+	}
+	if h := previewed[0].HoursCounted; h == nil || *h != 4.5 {
+		t.Errorf("previewed hours = %v, want 4.5 from the interval less exclusions", h)
+	}
+}
+
+func TestLaytimePreviewErrors(t *testing.T) {
+	fake := newFakeDB(t)
+	(&fakeLaytimeUpsert{}).install(fake)
+	start := time.Date(2026, 5, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	long := strings.Repeat("x", MaxNotesLength+1)
+	entries := []*LaytimeEntry{
+		{PortName: "Santos", Activity: "loading", StartedAt: start, EndedAt: &end},
+		{PortName: "Santos", Activity: "loading", StartedAt: start, EndedAt: &end, ExcludedHours: 3},
+		{PortName: "Santos", Activity: "waiting", StartedAt: start, Remarks: &long},
+		{PortName: "Santos", Activity: "waiting", StartedAt: start, ExcludedHours: -1},
+	}
+	errs := NewLaytimeEntryRepository().Preview(entries)
+	if len(errs) != 3 || !errors.Is(errs[1], ErrInvalidExclusion) || !errors.Is(errs[2], ErrNotesTooLong) || !errors.Is(errs[3], ErrInvalidExclusion) {
+		t.Fatalf("errs = %v, want exclusions at 1 and 3, remarks at 2", errs)
+	}
+	if h := entries[0].HoursCounted; h == nil || *h != 2 {
+		t.Errorf("valid entry hours = %v, want 2", h)
+	}
+	_, err := NewLaytimeEntryRepository().CreateBatch(context.Background(), entries)
+	if !errors.Is(err, ErrInvalidExclusion) || !strings.HasPrefix(err.Error(), "entry 1: ") {
+		t.Errorf("CreateBatch err = %v, want the first previewed error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var voyagePortColumns = []string{
+	"id", "voyage_id", "port_name", "port_country", "port_unlocode", "latitude", "longitude",
+	"arrived_at", "departed_at", "laytime_hours", "cargo_operations", "notes", "created_at", "updated_at",
+}
+
+type laytimeAtPort struct {
+	port  string
+	hours any
+}
+
+// stubLongestPortCall answers LongestPortCall from ports and entries the way
+// the query does: entry hours summed per port name, case and whitespace
+// folded, falling back to the port's laytime_hours, earlier calls first on a
+// tie.
+func stubLongestPortCall(fake *dbtest.Fake, ports []map[string]any, entries []laytimeAtPort) {
+	key := func(name any) string { return strings.ToLower(strings.TrimSpace(name.(string))) }
+	fake.On("SELECT vp.id, COALESCE(t.hours, vp.laytime_hours, 0) AS hours", func(call dbtest.Call) dbtest.Result {
+		logged := map[string]float64{}
+		for _, e := range entries {
+			h, _ := e.hours.(float64)
+			logged[key(e.port)] += h
+		}
+		type candidate struct {
+			id      uuid.UUID
+			hours   float64
+			arrived time.Time
+		}
+		var calls []candidate
+		for _, p := range ports {
+			if p["voyage_id"] != call.Arg(1) {
+				continue
+			}
+			hours, ok := logged[key(p["port_name"])]
+			if !ok {
+				hours, _ = p["laytime_hours"].(float64)
+			}
+			calls = append(calls, candidate{p["id"].(uuid.UUID), hours, p["arrived_at"].(time.Time)})
+		}
+		if len(calls) == 0 {
+			return dbtest.Rows([]string{"id", "hours"})
+		}
+		slices.SortStableFunc(calls, func(a, b candidate) int {
+			if a.hours != b.hours {
+				if a.hours > b.hours {
+					return -1
+				}
+				return 1
+			}
+			return a.arrived.Compare(b.arrived)
+		})
+		return dbtest.Rows([]string{"id", "hours"}, []any{calls[0].id, calls[0].hours})
+	})
+	fake.On("FROM shipman.voyage_ports WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		for _, p := range ports {
+			if p["id"].(uuid.UUID).String() == call.Arg(1) {
+				return dbtest.Rows(voyagePortColumns, dbtest.Row(voyagePortColumns, p))
+			}
+		}
+		return dbtest.Rows(voyagePortColumns)
+	})
+}
+
+func TestLongestPortCall(t *testing.T) {
+	voyageID := uuid.New()
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	port := func(day int, name string, laytime any) map[string]any {
+		at := start.AddDate(0, 0, day)
+		return map[string]any{
+			"id": uuid.New(), "voyage_id": voyageID.String(), "port_name": name, "arrived_at": at,
+			"laytime_hours": laytime, "created_at": at, "updated_at": at,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		ports     []map[string]any
+		entries   []laytimeAtPort
+		wantPort  int
+		wantHours float64
+	}{
+		{
+			name:      "entries decide",
+			ports:     []map[string]any{port(0, "Santos", 10.0), port(5, "Rotterdam", 80.0), port(9, "Singapore", nil)},
+			entries:   []laytimeAtPort{{"santos ", 30.0}, {"SANTOS", 25.5}, {"Rotterdam", 40.0}, {"Singapore", nil}},
+			wantPort:  0,
+			wantHours: 55.5,
+		},
+		{
+			name:      "laytime_hours without entries",
+			ports:     []map[string]any{port(0, "Santos", 10.0), port(5, "Rotterdam", 80.0), port(9, "Singapore", 12.0)},
+			entries:   []laytimeAtPort{{"Santos", 20.0}},
+			wantPort:  1,
+			wantHours: 80,
+		},
+		{
+			name:      "tie goes to the earlier call",
+			ports:     []map[string]any{port(5, "Rotterdam", 24.0), port(0, "Santos", 24.0)},
+			wantPort:  1,
+			wantHours: 24,
+		},
+		{
+			name:      "nothing recorded",
+			ports:     []map[string]any{port(0, "Santos", nil)},
+			wantPort:  0,
+			wantHours: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubLongestPortCall(fake, tt.ports, tt.entries)
+
+			got, hours, err := NewVoyagePortRepository().LongestPortCall(context.Background(), voyageID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.ports[tt.wantPort]
+			if got.ID != want["id"] || got.PortName != want["port_name"] {
+				t.Errorf("port = %s %q, want %s %q", got.ID, got.PortName, want["id"], want["port_name"])
+			}
+			if hours != tt.wantHours {
+				t.Errorf("hours = %v, want %v", hours, tt.wantHours)
+			}
+		})
+	}
+}
+
+func TestLongestPortCallWithoutPorts(t *testing.T) {
+	fake := newFakeDB(t)
+	stubLongestPortCall(fake, nil, nil)
+	if _, _, err := NewVoyagePortRepository().LongestPortCall(context.Background(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
@@ -47,6 +47,7 @@ func NewNegotiationRepository() *NegotiationRepository {
 }
 
 func (repo *NegotiationRepository) CreateNegotiation(ctx context.Context, n *ClauseNegotiation) error {
+	clearServerFields(&n.ID, &n.CreatedAt, &n.UpdatedAt)
 	const query = `
 		INSERT INTO shipman.clause_negotiations (deal_id, clause_type, clause_title, original_content, status, sort_order)
 		VALUES ($1, $2, $3, $4, $5, $6)
@@ -103,17 +104,17 @@ func (repo *NegotiationRepository) ListByDeal(ctx context.Context, dealID uuid.U
 
 func (repo *NegotiationRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	const query = `UPDATE shipman.clause_negotiations SET status = $2 WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, status)
-	return err
+	return requireRow(Conn(ctx).ExecContext(ctx, query, id, status))
 }
 
 func (repo *NegotiationRepository) CreateProposal(ctx context.Context, p *ClauseProposal) error {
+	clearServerFields(&p.ID, &p.CreatedAt)
 	const query = `
 		INSERT INTO shipman.clause_proposals (negotiation_id, proposed_by, proposed_content, comment, status)
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at
 	`
-	return Pool.QueryRowContext(ctx, query,
+	return Conn(ctx).QueryRowContext(ctx, query,
 		p.NegotiationID, p.ProposedBy, p.ProposedContent, p.Comment, p.Status,
 	).Scan(&p.ID, &p.CreatedAt)
 }
@@ -167,8 +168,7 @@ func (repo *NegotiationRepository) GetProposals(ctx context.Context, negotiation
 
 func (repo *NegotiationRepository) UpdateProposalStatus(ctx context.Context, id uuid.UUID, status string) error {
 	const query = `UPDATE shipman.clause_proposals SET status = $2 WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, status)
-	return err
+	return requireRow(Conn(ctx).ExecContext(ctx, query, id, status))
 }
 
 func (repo *NegotiationRepository) GetNegotiationWithProposals(ctx context.Context, id uuid.UUID) (ClauseNegotiationWithProposals, error) {
@@ -196,7 +196,7 @@ func (repo *NegotiationRepository) SupersedeOtherProposals(ctx context.Context,
 		SET status = 'superseded'
 		WHERE negotiation_id = $1 AND id != $2 AND status = 'pending'
 	`
-	_, err := Pool.ExecContext(ctx, query, negotiationID, acceptedProposalID)
+	_, err := Conn(ctx).ExecContext(ctx, query, negotiationID, acceptedProposalID)
 	return err
 }
 
@@ -208,7 +208,7 @@ func (repo *NegotiationRepository) AllNegotiationsAccepted(ctx context.Context,
 		WHERE deal_id = $1 AND status != 'accepted'
 	`
 	var allDone bool
-	err := Pool.QueryRowContext(ctx, query, dealID).Scan(&allDone)
+	err := Conn(ctx).QueryRowContext(ctx, query, dealID).Scan(&allDone)
 	return allDone, err
 }
 
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NOREvent mirrors shipman.nor_events: a Notice of Readiness tendered at one
+// port of a voyage.
+type NOREvent struct {
+	ID              uuid.UUID  `json:"id"`
+	VoyageID        uuid.UUID  `json:"voyage_id"`
+	CharterDetailID *uuid.UUID `json:"charter_detail_id,omitempty"`
+	PortName        string     `json:"port_name"`
+	TenderedAt      time.Time  `json:"tendered_at"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	TurnTimeHours   float64    `json:"turn_time_hours"`
+	Remarks         *string    `json:"remarks,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// NOREventService describes CRUD behaviour.
+type NOREventService interface {
+	Create(ctx context.Context, n *NOREvent) error
+	Retrieve(ctx context.Context, id uuid.UUID) (NOREvent, error)
+	ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]NOREvent, error)
+	Update(ctx context.Context, n *NOREvent) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NOREventRepository implements NOREventService using Pool.
+type NOREventRepository struct{}
+
+// NewNOREventRepository returns a repository.
+func NewNOREventRepository() *NOREventRepository {
+	return &NOREventRepository{}
+}
+
+// Create records a notice.
+func (repo *NOREventRepository) Create(ctx context.Context, n *NOREvent) error {
+	clearServerFields(&n.ID, &n.CreatedAt, &n.UpdatedAt)
+	if err := sanitizeNotes(notesField{"remarks", n.Remarks}); err != nil {
+		return err
+	}
+	if n.AcceptedAt != nil && n.AcceptedAt.Before(n.TenderedAt) {
+		return ErrEndBeforeStart
+	}
+	const query = `
+		INSERT INTO shipman.nor_events (
+			voyage_id,
+			charter_detail_id,
+			port_name,
+			tendered_at,
+			accepted_at,
+			turn_time_hours,
+			remarks
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+		RETURNING id, created_at, updated_at
+	`
+
+	return Conn(ctx).QueryRowContext(
+		ctx,
+		query,
+		n.VoyageID,
+		nullableUUID(n.CharterDetailID),
+		n.PortName,
+		n.TenderedAt,
+		nullableTime(n.AcceptedAt),
+		n.TurnTimeHours,
+		nullableString(n.Remarks),
+	).Scan(&n.ID, &n.CreatedAt, &n.UpdatedAt)
+}
+
+// Retrieve fetches a notice by id.
+func (repo *NOREventRepository) Retrieve(ctx context.Context, id uuid.UUID) (NOREvent, error) {
+	const query = `
+		SELECT
+			id,
+			voyage_id,
+			charter_detail_id,
+			port_name,
+			tendered_at,
+			accepted_at,
+			turn_time_hours,
+			remarks,
+			created_at,
+			updated_at
+		FROM shipman.nor_events
+		WHERE id = $1
+	`
+
+	return scanNOREvent(Pool.QueryRowContext(ctx, query, id))
+}
+
+// ListByVoyage returns a voyage's notices in the order they were tendered.
+func (repo *NOREventRepository) ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]NOREvent, error) {
+	const query = `
+		SELECT
+			id,
+			voyage_id,
+			charter_detail_id,
+			port_name,
+			tendered_at,
+			accepted_at,
+			turn_time_hours,
+			remarks,
+			created_at,
+			updated_at
+		FROM shipman.nor_events
+		WHERE voyage_id = $1
+		ORDER BY tendered_at ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, voyageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []NOREvent
+	for rows.Next() {
+		n, err := scanNOREvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// Update modifies a notice.
+func (repo *NOREventRepository) Update(ctx context.Context, n *NOREvent) error {
+	if err := sanitizeNotes(notesField{"remarks", n.Remarks}); err != nil {
+		return err
+	}
+	if n.AcceptedAt != nil && n.AcceptedAt.Before(n.TenderedAt) {
+		return ErrEndBeforeStart
+	}
+	const query = `
+		UPDATE shipman.nor_events
+		SET
+			port_name = $2,
+			tendered_at = $3,
+			accepted_at = $4,
+			turn_time_hours = $5,
+			remarks = $6,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := Conn(ctx).QueryRowContext(
+		ctx,
+		query,
+		n.ID,
+		n.PortName,
+		n.TenderedAt,
+		nullableTime(n.AcceptedAt),
+		n.TurnTimeHours,
+		nullableString(n.Remarks),
+	).Scan(&n.UpdatedAt)
+	return notFound(err)
+}
+
+// Delete removes a notice.
+func (repo *NOREventRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	const query = `DELETE FROM shipman.nor_events WHERE id = $1`
+	return requireRow(Conn(ctx).ExecContext(ctx, query, id))
+}
+
+func scanNOREvent(row rowScanner) (NOREvent, error) {
+	var (
+		n         NOREvent
+		charterID sql.NullString
+		accepted  sql.NullTime
+		remarks   sql.NullString
+	)
+	if err := row.Scan(
+		&n.ID,
+		&n.VoyageID,
+		&charterID,
+		&n.PortName,
+		&n.TenderedAt,
+		&accepted,
+		&n.TurnTimeHours,
+		&remarks,
+		&n.CreatedAt,
+		&n.UpdatedAt,
+	); err != nil {
+		return NOREvent{}, err
+	}
+	n.CharterDetailID = uuidPtrNullable(charterID)
+	n.AcceptedAt = timePtr(accepted)
+	n.Remarks = stringPtr(remarks)
+	return n, nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var norColumns = []string{
+	"id", "voyage_id", "charter_detail_id", "port_name", "tendered_at", "accepted_at",
+	"turn_time_hours", "remarks", "created_at", "updated_at",
+}
+
+func TestNOREventCreate(t *testing.T) {
+	tendered := time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC)
+	accepted := tendered.Add(2 * time.Hour)
+	early := tendered.Add(-time.Minute)
+	long := strings.Repeat("x", MaxNotesLength+1)
+
+	tests := []struct {
+		name     string
+		accepted *time.Time
+		remarks  *string
+		wantErr  error
+	}{
+		{"accepted", &accepted, nil, nil},
+		{"tendered only", nil, nil, nil},
+		{"accepted before tendered", &early, nil, ErrEndBeforeStart},
+		{"remarks too long", &accepted, &long, ErrNotesTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("INSERT INTO shipman.nor_events", dbtest.Rows([]string{"id", "created_at", "updated_at"},
+				[]any{uuid.New(), time.Now(), time.Now()}))
+			n := &NOREvent{VoyageID: uuid.New(), PortName: "Santos", TenderedAt: tendered, AcceptedAt: tt.accepted, TurnTimeHours: 6, Remarks: tt.remarks}
+
+			err := NewNOREventRepository().Create(context.Background(), n)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			calls := fake.Calls("INSERT INTO shipman.nor_events")
+			if tt.wantErr != nil {
+				if len(calls) != 0 {
+					t.Error("an invalid notice was written")
+				}
+				return
+			}
+			if n.ID == uuid.Nil || len(calls) != 1 {
+				t.Fatalf("id = %s, inserts = %d; want one insert returning an id", n.ID, len(calls))
+			}
+			var wantAccepted any
+			if tt.accepted != nil {
+				wantAccepted = *tt.accepted
+			}
+			if got := calls[0].Arg(5); got != wantAccepted {
+				t.Errorf("accepted_at arg = %v, want %v", got, wantAccepted)
+			}
+			if calls[0].Arg(2) != nil || calls[0].Arg(6) != 6.0 {
+				t.Errorf("args = %v, want no charter and 6h turn time", calls[0].Args)
+			}
+		})
+	}
+}
+
+func TestNOREventListByVoyage(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID, charterID := uuid.New(), uuid.New()
+	tendered := time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC)
+	fake.Return("FROM shipman.nor_events WHERE voyage_id = $1 ORDER BY tendered_at ASC", dbtest.Rows(norColumns,
+		[]any{uuid.New(), voyageID, charterID.String(), "Santos", tendered, tendered.Add(time.Hour), 6.0, "berth congested", time.Now(), time.Now()},
+		[]any{uuid.New(), voyageID, nil, "Rotterdam", tendered.AddDate(0, 0, 20), nil, 0.0, nil, time.Now(), time.Now()},
+	))
+
+	events, err := NewNOREventRepository().ListByVoyage(context.Background(), voyageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %d, want 2", len(events))
+	}
+	first, second := events[0], events[1]
+	if first.CharterDetailID == nil || *first.CharterDetailID != charterID || first.AcceptedAt == nil || first.Remarks == nil {
+		t.Errorf("first = %+v, want charter, acceptance and remarks scanned", first)
+	}
+	if second.CharterDetailID != nil || second.AcceptedAt != nil || second.Remarks != nil {
+		t.Errorf("second = %+v, want nulls left nil", second)
+	}
+	if calls := fake.Calls("FROM shipman.nor_events"); calls[0].Arg(1) != voyageID.String() {
+		t.Errorf("voyage arg = %v, want %s", calls[0].Arg(1), voyageID)
+	}
+}
+
+func TestCalcLaytimeFromNOR(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("FROM shipman.voyages WHERE id = $1", dbtest.Rows([]string{"allowed", "dem_rate", "desp_rate", "currency"},
+		[]any{48.0, 24000.0, 12000.0, "USD"}))
+	fake.Return("FROM shipman.laytime_entries le LEFT JOIN LATERAL", dbtest.Rows([]string{"sum"}, []any{54.0}))
+	fake.Return("SELECT COALESCE(SUM(hours_counted), 0) FROM shipman.laytime_entries", dbtest.Rows([]string{"sum"}, []any{60.0}))
+	repo := NewVoyageRepository()
+	voyageID := uuid.New()
+
+	fromNOR, err := repo.CalcLaytimeFromNOR(context.Background(), voyageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := repo.CalcLaytime(context.Background(), voyageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromNOR.TotalHoursUsed != 54 || fromNOR.DemurrageHours != 6 || *fromNOR.DemurrageAmount != 6000 {
+		t.Errorf("from NOR = %+v, want 54h used and 6h demurrage at 24000/day", fromNOR)
+	}
+	if plain.TotalHoursUsed != 60 || plain.DemurrageHours != 12 {
+		t.Errorf("plain = %+v, want 60h used and 12h demurrage", plain)
+	}
+
+	q := fake.Calls("LEFT JOIN LATERAL")[0].Query
+	for _, want := range []string{
+		"n.accepted_at + n.turn_time_hours * INTERVAL '1 hour' AS commences",
+		"n.accepted_at IS NOT NULL",
+		"lower(trim(n.port_name)) = lower(trim(le.port_name))",
+		"ORDER BY n.accepted_at DESC LIMIT 1",
+		"WHEN nor.commences IS NULL OR le.started_at >= nor.commences THEN le.hours_counted",
+	} {
+		if !strings.Contains(q, want) {
+			t.Errorf("NOR laytime query is missing %q", want)
+		}
+	}
+}
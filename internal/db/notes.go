@@ -0,0 +1,48 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxNotesLength is the character limit on free-text fields (notes,
+// remarks, descriptions) used unless overridden.
+const DefaultMaxNotesLength = 4000
+
+// MaxNotesLength caps free-text fields, counted in characters.
+var MaxNotesLength = DefaultMaxNotesLength
+
+// SetMaxNotesLength overrides the free-text cap. Values <= 0 restore the
+// default.
+func SetMaxNotesLength(n int) {
+	if n <= 0 {
+		n = DefaultMaxNotesLength
+	}
+	MaxNotesLength = n
+}
+
+// notesField names a free-text value for sanitizeNotes.
+type notesField struct {
+	name  string
+	value *string
+}
+
+// sanitizeNotes cleans free-text fields in place before they are written:
+// null bytes are stripped (Postgres rejects them in text columns) and
+// surrounding whitespace is trimmed. A field still longer than MaxNotesLength
+// fails with ErrNotesTooLong naming it. Nil values are skipped. Formula
+// characters are left alone here and neutralized when rendering CSV.
+func sanitizeNotes(fields ...notesField) error {
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		s := strings.TrimSpace(strings.ReplaceAll(*f.value, "\x00", ""))
+		if n := utf8.RuneCountInString(s); n > MaxNotesLength {
+			return fmt.Errorf("%w: %s is %d characters, maximum is %d", ErrNotesTooLong, f.name, n, MaxNotesLength)
+		}
+		*f.value = s
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestSanitizeNotes(t *testing.T) {
+	SetMaxNotesLength(10)
+	t.Cleanup(func() { SetMaxNotesLength(0) })
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{"trimmed", "  berth 4 \n", "berth 4", nil},
+		{"null bytes stripped", "ber\x00th\x00", "berth", nil},
+		{"at the limit", "0123456789", "0123456789", nil},
+		{"counted in characters", "über über", "über über", nil},
+		{"limit applies after trimming", "   012345678   ", "012345678", nil},
+		{"too long", "0123456789a", "0123456789a", ErrNotesTooLong},
+		{"formula kept for the csv renderer", "=SUM(A1)", "=SUM(A1)", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.in
+			err := sanitizeNotes(notesField{"notes", &s})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "notes is 11 characters, maximum is 10") {
+				t.Errorf("err = %q, want it to name the field and limit", err)
+			}
+			if s != tt.want {
+				t.Errorf("value = %q, want %q", s, tt.want)
+			}
+		})
+	}
+
+	if err := sanitizeNotes(notesField{"notes", nil}); err != nil {
+		t.Errorf("nil field: err = %v", err)
+	}
+}
+
+func TestSetMaxNotesLength(t *testing.T) {
+	t.Cleanup(func() { SetMaxNotesLength(0) })
+	SetMaxNotesLength(25)
+	if MaxNotesLength != 25 {
+		t.Errorf("max = %d, want 25", MaxNotesLength)
+	}
+	SetMaxNotesLength(-1)
+	if MaxNotesLength != DefaultMaxNotesLength {
+		t.Errorf("max = %d, want the default %d", MaxNotesLength, DefaultMaxNotesLength)
+	}
+}
+
+func TestCargoLoadCreateSanitizesNotes(t *testing.T) {
+	const insert = "INSERT INTO shipman.cargo_loads"
+	fake := newFakeDB(t)
+	fake.Return(insert, dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{uuid.New(), time.Now(), time.Now()}))
+	repo := NewCargoLoadRepository()
+
+	notes := " lashings\x00 checked "
+	if err := repo.Create(context.Background(), &CargoLoad{VoyageID: uuid.New(), Notes: &notes}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.Calls(insert); len(calls) != 1 || calls[0].Arg(9) != "lashings checked" {
+		t.Fatalf("insert calls = %+v, want the cleaned notes", calls)
+	}
+
+	long := strings.Repeat("x", DefaultMaxNotesLength+1)
+	err := repo.Create(context.Background(), &CargoLoad{VoyageID: uuid.New(), Notes: &long})
+	if !errors.Is(err, ErrNotesTooLong) {
+		t.Fatalf("err = %v, want ErrNotesTooLong", err)
+	}
+	if calls := fake.Calls(insert); len(calls) != 1 {
+		t.Errorf("insert ran %d times, want the long notes rejected before the write", len(calls))
+	}
+}
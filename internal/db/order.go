@@ -0,0 +1,42 @@
+package db
+
+import "strings"
+
+// List ordering policy:
+//
+//   - Child rows that form a timeline (ports, laytime entries, NOR events,
+//     documents) sort oldest first; top-level feeds (charters, voyages,
+//     payments, disputes) sort newest first.
+//   - Every key is written with an explicit NULLS LAST whatever its
+//     direction, so rows with no date always trail the dated ones instead of
+//     flipping to the top when a list is reversed.
+//   - Lists end with a unique key (usually id) so pages are stable.
+//
+// Queries with a nullable sort key build their ORDER BY with orderBy rather
+// than relying on Postgres's direction-dependent NULL default.
+
+// orderTerm is one ORDER BY key.
+type orderTerm struct {
+	expr string
+	desc bool
+}
+
+// asc sorts expr smallest first with nulls last.
+func asc(expr string) orderTerm { return orderTerm{expr: expr} }
+
+// desc sorts expr largest first with nulls last.
+func desc(expr string) orderTerm { return orderTerm{expr: expr, desc: true} }
+
+// orderBy renders terms as the body of an ORDER BY clause, each with an
+// explicit direction and NULLS LAST.
+func orderBy(terms ...orderTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		dir := "ASC"
+		if t.desc {
+			dir = "DESC"
+		}
+		parts[i] = t.expr + " " + dir + " NULLS LAST"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		terms []orderTerm
+		want  string
+	}{
+		{[]orderTerm{asc("arrived_at")}, "arrived_at ASC NULLS LAST"},
+		{[]orderTerm{desc("vp.hours"), asc("vp.id")}, "vp.hours DESC NULLS LAST, vp.id ASC NULLS LAST"},
+	}
+	for _, tt := range tests {
+		if got := orderBy(tt.terms...); got != tt.want {
+			t.Errorf("orderBy = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+var (
+	orderClause = regexp.MustCompile(`ORDER BY (.+?)(?: LIMIT .*)?$`)
+	orderKey    = regexp.MustCompile(`^(?:\w+\.)?(\w+)(?: (ASC|DESC))?(?: NULLS (FIRST|LAST))?$`)
+)
+
+// outerOrder returns the keys of the statement's last ORDER BY, which for
+// the lists here is the outer one; nil when there is none.
+func outerOrder(query string) []string {
+	i := strings.LastIndex(query, "ORDER BY")
+	if i < 0 {
+		return nil
+	}
+	m := orderClause.FindStringSubmatch(query[i:])
+	if m == nil {
+		return nil
+	}
+	return strings.Split(m[1], ", ")
+}
+
+// sortLikeQuery orders rows the way Postgres would for the statement's
+// ORDER BY, applying its defaults (nulls sort as larger than any value) to
+// keys that do not spell out their NULLS placement.
+func sortLikeQuery(t *testing.T, query string, columns []string, rows [][]any) {
+	t.Helper()
+	terms := outerOrder(query)
+	if terms == nil {
+		t.Fatalf("no ORDER BY in %s", query)
+	}
+	type key struct {
+		col        int
+		desc       bool
+		nullsFirst bool
+	}
+	var keys []key
+	for _, term := range terms {
+		k := orderKey.FindStringSubmatch(strings.TrimSpace(term))
+		if k == nil {
+			t.Fatalf("unparsed ORDER BY term %q", term)
+		}
+		col := slices.Index(columns, k[1])
+		if col < 0 {
+			t.Fatalf("ORDER BY %s is not a selected column", k[1])
+		}
+		desc := k[2] == "DESC"
+		keys = append(keys, key{col, desc, k[3] == "FIRST" || (k[3] == "" && desc)})
+	}
+	slices.SortStableFunc(rows, func(a, b []any) int {
+		for _, k := range keys {
+			x, y := a[k.col], b[k.col]
+			switch {
+			case x == nil && y == nil:
+				continue
+			case x == nil || y == nil:
+				if (x == nil) == k.nullsFirst {
+					return -1
+				}
+				return 1
+			}
+			c := compareValues(x, y)
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+func compareValues(x, y any) int {
+	switch x := x.(type) {
+	case time.Time:
+		return x.Compare(y.(time.Time))
+	case float64:
+		return cmp.Compare(x, y.(float64))
+	case string:
+		return strings.Compare(x, y.(string))
+	case uuid.UUID:
+		return strings.Compare(x.String(), y.(uuid.UUID).String())
+	}
+	panic("unsupported sort value")
+}
+
+// stubOrderedList answers the list matching match with rows sorted by the
+// statement's own ORDER BY, and records every such statement.
+func stubOrderedList(t *testing.T, fake *dbtest.Fake, match string, columns []string, rows [][]any) {
+	fake.On(match, func(call dbtest.Call) dbtest.Result {
+		out := slices.Clone(rows)
+		sortLikeQuery(t, call.Query, columns, out)
+		return dbtest.Rows(columns, out...)
+	})
+}
+
+// assertNullsLast checks every ORDER BY key of the statements matching match
+// spells out NULLS LAST, so the row order does not hang on the direction.
+func assertNullsLast(t *testing.T, fake *dbtest.Fake, match string) {
+	t.Helper()
+	calls := fake.Calls(match)
+	if len(calls) == 0 {
+		t.Fatalf("no %q statement ran", match)
+	}
+	for _, call := range calls {
+		terms := outerOrder(call.Query)
+		if terms == nil {
+			t.Fatalf("no ORDER BY in %s", call.Query)
+		}
+		for _, term := range terms {
+			if !strings.HasSuffix(term, " NULLS LAST") {
+				t.Errorf("ORDER BY term %q has no explicit NULLS LAST", term)
+			}
+		}
+	}
+}
+
+func TestListOrderNullsLast(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 4, d, 0, 0, 0, 0, time.UTC) }
+	parent := uuid.New()
+
+	t.Run("bills of lading", func(t *testing.T) {
+		columns := []string{"id", "charter_detail_id", "document_number", "issue_date", "created_at", "updated_at"}
+		bill := func(number string, issued any, created time.Time) []any {
+			return []any{uuid.New(), parent, number, issued, created, created}
+		}
+		rows := [][]any{
+			bill("undated-old", nil, day(1)),
+			bill("late", day(20), day(2)),
+			bill("undated-new", nil, day(9)),
+			bill("early", day(5), day(3)),
+		}
+		fake := newFakeDB(t)
+		stubOrderedList(t, fake, "FROM shipman.bills_of_lading WHERE charter_detail_id = $1", columns, rows)
+
+		got, err := NewBillOfLadingRepository().ListByCharter(context.Background(), parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers := make([]string, len(got))
+		for i, b := range got {
+			numbers[i] = b.DocumentNumber
+		}
+		if want := []string{"early", "late", "undated-new", "undated-old"}; !slices.Equal(numbers, want) {
+			t.Errorf("bills = %v, want %v", numbers, want)
+		}
+		assertNullsLast(t, fake, "FROM shipman.bills_of_lading")
+	})
+
+	t.Run("voyage ports", func(t *testing.T) {
+		port := func(name string, arrived any, created time.Time) []any {
+			return []any{uuid.New(), parent, name, nil, nil, nil, nil, arrived, nil, nil, nil, nil, created, created}
+		}
+		rows := [][]any{
+			port("Houston", nil, day(1)),
+			port("Rotterdam", day(12), day(2)),
+			port("Santos", day(3), day(3)),
+			port("Singapore", nil, day(4)),
+		}
+		fake := newFakeDB(t)
+		stubOrderedList(t, fake, "FROM shipman.voyage_ports WHERE voyage_id = $1 ORDER BY", voyagePortColumns, rows)
+
+		got, err := NewVoyagePortRepository().ListByVoyage(context.Background(), parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(got))
+		for i, p := range got {
+			names[i] = p.PortName
+		}
+		if want := []string{"Santos", "Rotterdam", "Houston", "Singapore"}; !slices.Equal(names, want) {
+			t.Errorf("ports = %v, want %v", names, want)
+		}
+		assertNullsLast(t, fake, "FROM shipman.voyage_ports WHERE voyage_id = $1 ORDER BY")
+	})
+
+	t.Run("overdue payments", func(t *testing.T) {
+		freezeClock(t, day(30))
+		fake := newFakeDB(t)
+		fake.Return("FROM shipman.voyage_payments WHERE due_date < $1", dbtest.Rows([]string{"id"}))
+		if _, err := NewPaymentRepository().ListOverdue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		assertNullsLast(t, fake, "FROM shipman.voyage_payments WHERE due_date < $1")
+	})
+
+	t.Run("fleet positions", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return("JOIN LATERAL", dbtest.Rows(fleetPositionColumns))
+		if _, err := NewShipPositionRepository().FleetPositionsSince(context.Background(), day(1)); err != nil {
+			t.Fatal(err)
+		}
+		assertNullsLast(t, fake, "JOIN LATERAL")
+	})
+}
+
+func TestSortLikeQueryDefaults(t *testing.T) {
+	// Without NULLS LAST, Postgres puts nulls first on a descending key; the
+	// helper must model that or the list tests above prove nothing.
+	rows := [][]any{{"a", time.Unix(1, 0)}, {"b", nil}, {"c", time.Unix(2, 0)}}
+	sortLikeQuery(t, "SELECT id, at FROM t ORDER BY at DESC", []string{"id", "at"}, rows)
+	if got := []any{rows[0][0], rows[1][0], rows[2][0]}; !slices.Equal(got, []any{"b", "c", "a"}) {
+		t.Errorf("DESC order = %v, want nulls first", got)
+	}
+	sortLikeQuery(t, "SELECT id, at FROM t ORDER BY at DESC NULLS LAST", []string{"id", "at"}, rows)
+	if got := []any{rows[0][0], rows[1][0], rows[2][0]}; !slices.Equal(got, []any{"c", "a", "b"}) {
+		t.Errorf("DESC NULLS LAST order = %v, want nulls last", got)
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// DefaultOrgID is the organization migration 000040 creates and assigns every
+// pre-existing user, charter and voyage to. Rows created without a scope and
+// without an explicit org also land here.
+var DefaultOrgID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+type orgKey struct{}
+
+// WithOrg returns a context that limits charter and voyage reads to orgID and
+// stamps it on rows created through it. A context without an org, such as
+// one used by an admin or a background job, sees every organization.
+func WithOrg(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, orgKey{}, orgID)
+}
+
+// OrgFromContext returns the organization ctx is scoped to, if any.
+func OrgFromContext(ctx context.Context) (uuid.UUID, bool) {
+	orgID, ok := ctx.Value(orgKey{}).(uuid.UUID)
+	return orgID, ok
+}
+
+// orgFilter is the query argument for an `($n::uuid IS NULL OR org_id = $n)`
+// clause: the scoped org, or NULL when ctx is unscoped.
+func orgFilter(ctx context.Context) any {
+	if orgID, ok := OrgFromContext(ctx); ok {
+		return orgID
+	}
+	return nil
+}
+
+// inOrg reports whether a row owned by orgID is visible through ctx.
+func inOrg(ctx context.Context, orgID uuid.UUID) bool {
+	scoped, ok := OrgFromContext(ctx)
+	return !ok || scoped == orgID
+}
+
+// orgForCreate picks the org a new row belongs to: the scoped org when there
+// is one, otherwise requested, falling back to DefaultOrgID.
+func orgForCreate(ctx context.Context, requested uuid.UUID) uuid.UUID {
+	if orgID, ok := OrgFromContext(ctx); ok {
+		return orgID
+	}
+	if requested != uuid.Nil {
+		return requested
+	}
+	return DefaultOrgID
+}
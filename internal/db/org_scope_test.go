@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestOrgHelpers(t *testing.T) {
+	orgID, requested := uuid.New(), uuid.New()
+	scoped := WithOrg(context.Background(), orgID)
+	unscoped := context.Background()
+
+	if got, ok := OrgFromContext(scoped); !ok || got != orgID {
+		t.Errorf("OrgFromContext(scoped) = %s, %v; want %s, true", got, ok, orgID)
+	}
+	if _, ok := OrgFromContext(unscoped); ok {
+		t.Error("OrgFromContext(unscoped) reported an org")
+	}
+	if got := orgFilter(scoped); got != orgID {
+		t.Errorf("orgFilter(scoped) = %v, want %s", got, orgID)
+	}
+	if got := orgFilter(unscoped); got != nil {
+		t.Errorf("orgFilter(unscoped) = %v, want nil", got)
+	}
+	if !inOrg(scoped, orgID) || inOrg(scoped, uuid.New()) || !inOrg(unscoped, uuid.New()) {
+		t.Error("inOrg should accept only the scoped org, and any org when unscoped")
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested uuid.UUID
+		want      uuid.UUID
+	}{
+		{"scope wins over the request", scoped, requested, orgID},
+		{"unscoped keeps the request", unscoped, requested, requested},
+		{"unscoped without a request", unscoped, uuid.Nil, DefaultOrgID},
+	}
+	for _, tt := range tests {
+		if got := orgForCreate(tt.ctx, tt.requested); got != tt.want {
+			t.Errorf("%s: orgForCreate = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+var orgClause = regexp.MustCompile(`\(\$(\d+)::uuid IS NULL OR`)
+
+// orgArg returns the org a statement filters on, or "" when the caller is
+// unscoped and the clause is disabled.
+func orgArg(call dbtest.Call) string {
+	m := orgClause.FindStringSubmatch(call.Query)
+	if m == nil {
+		return ""
+	}
+	n, _ := strconv.Atoi(m[1])
+	org, _ := call.Arg(n).(string)
+	return org
+}
+
+// fakeTenants holds charters, voyages, users and disputes split across
+// organizations and answers reads honouring the org clause.
+type fakeTenants struct {
+	charters, voyages, users, disputes []map[string]any
+}
+
+func (f *fakeTenants) charterOrg(id any) string {
+	for _, c := range f.charters {
+		if c["id"] == id {
+			return c["org_id"].(string)
+		}
+	}
+	return ""
+}
+
+func newFakeTenants(fake *dbtest.Fake, orgs ...uuid.UUID) *fakeTenants {
+	f := &fakeTenants{}
+	for i, org := range orgs {
+		charterID, voyageID := uuid.NewString(), uuid.NewString()
+		stamp := time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		f.charters = append(f.charters, map[string]any{
+			"id": charterID, "org_id": org.String(), "title": "Charter " + strconv.Itoa(i), "status": "active",
+			"ai_status": "pending", "laytime_reversible": false, "created_at": stamp, "updated_at": stamp,
+		})
+		f.voyages = append(f.voyages, map[string]any{
+			"id": voyageID, "org_id": org.String(), "charter_detail_id": charterID, "status": "planned",
+			"demurrage_currency": "USD", "created_at": stamp, "updated_at": stamp,
+		})
+		f.users = append(f.users, map[string]any{
+			"id": uuid.NewString(), "org_id": org.String(), "email": "ops" + strconv.Itoa(i) + "@example.com",
+			"password_hash": "x", "full_name": "Ops", "role": "user", "created_at": stamp, "updated_at": stamp,
+		})
+		f.disputes = append(f.disputes, map[string]any{
+			"id": uuid.NewString(), "charter_detail_id": charterID, "subject": "Short cargo", "currency": "USD",
+			"status": "open", "created_at": stamp, "updated_at": stamp,
+		})
+	}
+
+	visible := func(org string, rowOrg any) bool { return org == "" || org == rowOrg }
+	byID := func(rows []map[string]any, cols []string, call dbtest.Call, orgOf func(map[string]any) any) dbtest.Result {
+		org := orgArg(call)
+		for _, r := range rows {
+			if r["id"] == call.Arg(1) && visible(org, orgOf(r)) {
+				return dbtest.Rows(cols, dbtest.Row(cols, r))
+			}
+		}
+		return dbtest.Rows(cols)
+	}
+	list := func(rows []map[string]any, cols []string, call dbtest.Call) dbtest.Result {
+		org := orgArg(call)
+		var out [][]any
+		for _, r := range rows {
+			if visible(org, r["org_id"]) {
+				out = append(out, dbtest.Row(cols, r))
+			}
+		}
+		if i := slices.Index(cols, "total"); i >= 0 {
+			for _, row := range out {
+				row[i] = len(out)
+			}
+		}
+		return dbtest.Rows(cols, out...)
+	}
+	ownOrg := func(r map[string]any) any { return r["org_id"] }
+
+	fake.On("updated_at FROM shipman.charter_details WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		// Retrieve checks the org in Go so cached rows are covered too.
+		return byID(f.charters, charterColumns, call, ownOrg)
+	})
+	fake.On("COUNT(*) OVER () AS total FROM shipman.charter_details WHERE ($3::uuid IS NULL", func(call dbtest.Call) dbtest.Result {
+		return list(f.charters, []string{"id", "title", "status", "created_at", "updated_at", "total"}, call)
+	})
+	fake.On("archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		return byID(f.voyages, voyageColumns, call, ownOrg)
+	})
+	fake.On("SELECT id FROM shipman.voyages WHERE charter_detail_id = $1", func(call dbtest.Call) dbtest.Result {
+		org := orgArg(call)
+		var out [][]any
+		for _, v := range f.voyages {
+			if v["charter_detail_id"] == call.Arg(1) && visible(org, v["org_id"]) {
+				out = append(out, []any{v["id"]})
+			}
+		}
+		return dbtest.Rows([]string{"id"}, out...)
+	})
+	fake.On("FROM shipman.users WHERE ($3::uuid IS NULL OR org_id = $3)", func(call dbtest.Call) dbtest.Result {
+		return list(f.users, []string{
+			"id", "org_id", "email", "password_hash", "full_name", "role",
+			"coinsub_merchant_id", "wallet_address", "created_at", "updated_at",
+		}, call)
+	})
+	fake.On("FROM shipman.disputes WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		return byID(f.disputes, []string{
+			"id", "charter_detail_id", "voyage_id", "payment_id", "voyage_payment_id", "laytime_entry_id",
+			"raised_by_org_id", "assigned_to_org_id", "subject", "description", "claimed_amount", "currency",
+			"status", "resolution_notes", "settled_at", "archived_at", "created_at", "updated_at",
+		}, call, func(d map[string]any) any { return f.charterOrg(d["charter_detail_id"]) })
+	})
+	return f
+}
+
+func TestOrgScopedReads(t *testing.T) {
+	orgA, orgB := uuid.New(), uuid.New()
+	fake := newFakeDB(t)
+	tenants := newFakeTenants(fake, orgA, orgB)
+	ownCharter := uuid.MustParse(tenants.charters[0]["id"].(string))
+	otherCharter := uuid.MustParse(tenants.charters[1]["id"].(string))
+	ownVoyage := uuid.MustParse(tenants.voyages[0]["id"].(string))
+	otherVoyage := uuid.MustParse(tenants.voyages[1]["id"].(string))
+	otherDispute := uuid.MustParse(tenants.disputes[1]["id"].(string))
+
+	scoped := WithOrg(context.Background(), orgA)
+	admin := context.Background()
+	charters, voyages := NewCharterDetailRepository(), NewVoyageRepository()
+
+	if c, err := charters.Retrieve(scoped, ownCharter); err != nil || c.OrgID != orgA {
+		t.Errorf("own charter = %s, %v; want it in %s", c.OrgID, err, orgA)
+	}
+	if _, err := charters.Retrieve(scoped, otherCharter); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("other org's charter: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := charters.Retrieve(admin, otherCharter); err != nil {
+		t.Errorf("unscoped charter read: %v", err)
+	}
+
+	if v, err := voyages.Retrieve(scoped, ownVoyage); err != nil || v.OrgID != orgA {
+		t.Errorf("own voyage = %s, %v; want it in %s", v.OrgID, err, orgA)
+	}
+	if _, err := voyages.Retrieve(scoped, otherVoyage); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("other org's voyage: err = %v, want sql.ErrNoRows", err)
+	}
+	if got, err := voyages.ListByCharter(scoped, otherCharter, false); err != nil || len(got) != 0 {
+		t.Errorf("other org's charter voyages = %v, %v; want none", voyageIDs(got), err)
+	}
+	if got, err := voyages.ListByCharter(admin, otherCharter, false); err != nil || !slices.Equal(voyageIDs(got), []uuid.UUID{otherVoyage}) {
+		t.Errorf("unscoped charter voyages = %v, %v; want %s", voyageIDs(got), err, otherVoyage)
+	}
+
+	if _, err := NewDisputeRepository().Retrieve(scoped, otherDispute); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("other org's dispute: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := NewDisputeRepository().Retrieve(admin, otherDispute); err != nil {
+		t.Errorf("unscoped dispute read: %v", err)
+	}
+
+	lists := []struct {
+		name string
+		orgs func(context.Context) ([]uuid.UUID, error)
+	}{
+		{"charters", func(ctx context.Context) ([]uuid.UUID, error) {
+			got, total, err := charters.List(ctx, 10, 0)
+			if err == nil && total != len(got) {
+				t.Errorf("charter total = %d, want %d", total, len(got))
+			}
+			ids := make([]uuid.UUID, len(got))
+			for i, c := range got {
+				ids[i] = c.ID
+			}
+			return ids, err
+		}},
+		{"users", func(ctx context.Context) ([]uuid.UUID, error) {
+			got, err := NewUserRepository().List(ctx, 10, 0)
+			ids := make([]uuid.UUID, len(got))
+			for i, u := range got {
+				ids[i] = u.OrgID
+			}
+			return ids, err
+		}},
+	}
+	for _, l := range lists {
+		own, err := l.orgs(scoped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all, err := l.orgs(admin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(own) != 1 || len(all) != 2 {
+			t.Errorf("%s: scoped list has %d rows, unscoped %d; want 1 and 2", l.name, len(own), len(all))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Page carries limit/offset pagination for list queries.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// DefaultPageSize is the limit used by paginated list methods when the caller
+// passes a Page with no Limit.
+const DefaultPageSize = 50
+
+func (p Page) limit() int {
+	if p.Limit <= 0 {
+		return DefaultPageSize
+	}
+	return p.Limit
+}
+
+// listAll walks every page of a paginated list method. It is for internal
+// callers, such as exports, that need the complete set.
+func listAll[T any](list func(Page) ([]T, error)) ([]T, error) {
+	var out []T
+	page := Page{Limit: 100}
+	for {
+		batch, err := list(page)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, batch...)
+		if len(batch) < page.Limit {
+			return out, nil
+		}
+		page.Offset += page.Limit
+	}
+}
+
+// DefaultMaxListOffset is the largest OFFSET a List call accepts unless
+// overridden with SetMaxListOffset. Deep offsets make Postgres read and
+// discard every skipped row, so past this point callers should page by
+// cursor (e.g. created_at of the last row seen) instead.
+const DefaultMaxListOffset = 10000
+
+// MaxListOffset is the active offset ceiling for List queries.
+var MaxListOffset = DefaultMaxListOffset
+
+// ErrOffsetTooLarge is returned by List methods before any query runs when
+// the requested offset exceeds MaxListOffset.
+var ErrOffsetTooLarge = errors.New("offset too large")
+
+// SetMaxListOffset overrides the offset ceiling. Values <= 0 restore the default.
+func SetMaxListOffset(n int) {
+	if n <= 0 {
+		n = DefaultMaxListOffset
+	}
+	MaxListOffset = n
+}
+
+func checkOffset(offset int) error {
+	if offset > MaxListOffset {
+		return fmt.Errorf("%w: %d exceeds maximum of %d, use cursor pagination", ErrOffsetTooLarge, offset, MaxListOffset)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckOffset(t *testing.T) {
+	t.Cleanup(func() { SetMaxListOffset(0) })
+
+	SetMaxListOffset(100)
+	if err := checkOffset(100); err != nil {
+		t.Errorf("offset at the ceiling: %v", err)
+	}
+	if err := checkOffset(101); !errors.Is(err, ErrOffsetTooLarge) {
+		t.Errorf("offset past the ceiling: err = %v, want ErrOffsetTooLarge", err)
+	}
+
+	SetMaxListOffset(-1)
+	if MaxListOffset != DefaultMaxListOffset {
+		t.Errorf("MaxListOffset = %d after reset, want %d", MaxListOffset, DefaultMaxListOffset)
+	}
+}
+
+func TestListRejectsDeepOffsetBeforeQuerying(t *testing.T) {
+	ctx := context.Background()
+	deep := DefaultMaxListOffset + 1
+	lists := map[string]func() error{
+		"charters": func() error {
+			_, _, err := NewCharterDetailRepository().List(ctx, 10, deep)
+			return err
+		},
+		"charter search": func() error {
+			_, err := NewCharterDetailRepository().Search(ctx, "grain", 10, deep)
+			return err
+		},
+		"documents": func() error {
+			_, err := NewDocumentRepository().ListByUser(ctx, uuid.New(), 10, deep)
+			return err
+		},
+		"users": func() error {
+			_, err := NewUserRepository().List(ctx, 10, deep)
+			return err
+		},
+		"vessels": func() error {
+			_, err := NewVesselRepository().List(ctx, 10, deep)
+			return err
+		},
+		"payments": func() error {
+			_, err := NewPaymentRepository().ListMissingDueDate(ctx, nil, Page{Limit: 10, Offset: deep})
+			return err
+		},
+	}
+	for name, list := range lists {
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			if err := list(); !errors.Is(err, ErrOffsetTooLarge) {
+				t.Fatalf("err = %v, want ErrOffsetTooLarge", err)
+			}
+			if calls := fake.Calls(""); len(calls) != 0 {
+				t.Errorf("ran %d statements, want none: %v", len(calls), calls)
+			}
+		})
+	}
+}
+
+// stubPagedTable answers queries containing match with the rows in ids
+// order, sliced by the LIMIT and OFFSET bound at argument positions limitArg
+// and limitArg+1.
+func stubPagedTable(fake *dbtest.Fake, match string, columns []string, rows [][]any, limitArg int) {
+	fake.On(match, func(call dbtest.Call) dbtest.Result {
+		limit, offset := int(call.Arg(limitArg).(int64)), int(call.Arg(limitArg+1).(int64))
+		start, end := min(offset, len(rows)), min(offset+limit, len(rows))
+		return dbtest.Rows(columns, rows[start:end]...)
+	})
+}
+
+func TestListByCharterPageBoundaries(t *testing.T) {
+	base := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uuid.UUID
+	var disputes, records [][]any
+	for i := range 7 {
+		id := uuid.New()
+		ids = append(ids, id)
+		at := base.Add(-time.Duration(i/2) * time.Hour) // pairs share a created_at
+		disputes = append(disputes, []any{id, uuid.New(), "Claim", "open", nil, "USD", at, at})
+		records = append(records, []any{id, uuid.New(), nil, nil, "draft", at, at})
+	}
+
+	lists := []struct {
+		name  string
+		match string
+		rows  [][]any
+		cols  []string
+		list  func(Page) ([]uuid.UUID, error)
+	}{
+		{
+			"disputes", "FROM shipman.disputes WHERE charter_detail_id = $1", disputes,
+			[]string{"id", "charter_detail_id", "subject", "status", "claimed_amount", "currency", "created_at", "updated_at"},
+			func(p Page) ([]uuid.UUID, error) {
+				got, err := NewDisputeRepository().ListByCharter(context.Background(), uuid.New(), p)
+				var out []uuid.UUID
+				for _, d := range got {
+					out = append(out, d.ID)
+				}
+				return out, err
+			},
+		},
+		{
+			"demurrage", "FROM shipman.demurrage_records WHERE charter_detail_id = $1", records,
+			[]string{"id", "charter_detail_id", "voyage_id", "claimed_amount", "status", "created_at", "updated_at"},
+			func(p Page) ([]uuid.UUID, error) {
+				got, err := NewDemurrageRecordRepository().ListByCharter(context.Background(), uuid.New(), p)
+				var out []uuid.UUID
+				for _, r := range got {
+					out = append(out, r.ID)
+				}
+				return out, err
+			},
+		},
+	}
+	for _, l := range lists {
+		t.Run(l.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubPagedTable(fake, l.match, l.cols, l.rows, 2)
+
+			var walked []uuid.UUID
+			for _, want := range [][]uuid.UUID{ids[0:3], ids[3:6], ids[6:7], nil} {
+				got, err := l.list(Page{Limit: 3, Offset: len(walked)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("page at offset %d = %v, want %v", len(walked), got, want)
+				}
+				walked = append(walked, got...)
+			}
+			if !slices.Equal(walked, ids) {
+				t.Errorf("pages walked %v, want every row once in order", walked)
+			}
+
+			if _, err := l.list(Page{}); err != nil {
+				t.Fatal(err)
+			}
+			calls := fake.Calls(l.match)
+			if got := calls[len(calls)-1].Arg(2); got != int64(DefaultPageSize) {
+				t.Errorf("default limit = %v, want %d", got, DefaultPageSize)
+			}
+			if !strings.Contains(calls[0].Query, "ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3") {
+				t.Errorf("order is not stable: %s", calls[0].Query)
+			}
+		})
+	}
+}
+
+func TestListAll(t *testing.T) {
+	for _, n := range []int{0, 1, 99, 100, 101, 250} {
+		var pages int
+		got, err := listAll(func(p Page) ([]int, error) {
+			pages++
+			var out []int
+			for i := p.Offset; i < min(p.Offset+p.Limit, n); i++ {
+				out = append(out, i)
+			}
+			return out, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != n {
+			t.Errorf("n = %d: listAll returned %d items", n, len(got))
+		}
+		for i, v := range got {
+			if v != i {
+				t.Fatalf("n = %d: item %d = %d", n, i, v)
+			}
+		}
+		if want := n/100 + 1; pages != want {
+			t.Errorf("n = %d: fetched %d pages, want %d", n, pages, want)
+		}
+	}
+}
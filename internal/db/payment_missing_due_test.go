@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var paymentColumns = []string{
+	"id", "voyage_id", "created_by", "payment_type", "description", "amount", "currency",
+	"recipient_email", "recipient_wallet",
+	"coinsub_session_id", "coinsub_payment_id", "coinsub_agreement_id",
+	"coinsub_checkout_url", "coinsub_tx_hash",
+	"status", "paid_at", "due_date", "created_at", "updated_at",
+}
+
+var charterClause = regexp.MustCompile(`v\.charter_detail_id = \$(\d+)`)
+
+// missingDuePayment is a voyage_payments row with the charter and org of its
+// voyage.
+type missingDuePayment struct {
+	row            map[string]any
+	charter, orgID string
+}
+
+// stubMissingDueDate answers ListMissingDueDate from payments: undated,
+// outstanding rows, optionally on one charter, in the caller's org, ordered
+// and paged as the statement asks.
+func stubMissingDueDate(t *testing.T, fake *dbtest.Fake, payments []missingDuePayment) {
+	fake.On("WHERE p.due_date IS NULL AND p.status NOT IN ('completed', 'cancelled')", func(call dbtest.Call) dbtest.Result {
+		var charter any
+		if m := charterClause.FindStringSubmatch(call.Query); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			charter = call.Arg(n)
+		}
+		org := orgArg(call)
+		var out [][]any
+		for _, p := range payments {
+			if p.row["due_date"] != nil || p.row["status"] == "completed" || p.row["status"] == "cancelled" {
+				continue
+			}
+			if (charter != nil && p.charter != charter) || (org != "" && p.orgID != org) {
+				continue
+			}
+			out = append(out, dbtest.Row(paymentColumns, p.row))
+		}
+		sortLikeQuery(t, call.Query, paymentColumns, out)
+		m := limitOffset.FindStringSubmatch(call.Query)
+		ln, _ := strconv.Atoi(m[1])
+		on, _ := strconv.Atoi(m[2])
+		limit, offset := int(call.Arg(ln).(int64)), int(call.Arg(on).(int64))
+		out = out[min(offset, len(out)):]
+		return dbtest.Rows(paymentColumns, out[:min(limit, len(out))]...)
+	})
+}
+
+func TestPaymentListMissingDueDate(t *testing.T) {
+	charterA, charterB, otherOrg := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	day := func(d int) time.Time { return time.Date(2026, 7, d, 0, 0, 0, 0, time.UTC) }
+	payment := func(desc string, charter, org string, status string, due any, created time.Time) missingDuePayment {
+		return missingDuePayment{row: map[string]any{
+			"id": uuid.NewString(), "voyage_id": uuid.NewString(), "created_by": uuid.NewString(),
+			"payment_type": "hire", "description": desc, "amount": 1000.0, "currency": "USD",
+			"status": status, "due_date": due, "created_at": created, "updated_at": created,
+		}, charter: charter, orgID: org}
+	}
+	payments := []missingDuePayment{
+		payment("undated draft", charterA, DefaultOrgID.String(), "draft", nil, day(3)),
+		payment("dated", charterA, DefaultOrgID.String(), "pending", day(20), day(1)),
+		payment("undated pending", charterB, DefaultOrgID.String(), "pending", nil, day(2)),
+		payment("undated paid", charterA, DefaultOrgID.String(), "completed", nil, day(1)),
+		payment("undated cancelled", charterB, DefaultOrgID.String(), "cancelled", nil, day(1)),
+		payment("undated failed", charterA, DefaultOrgID.String(), "failed", nil, day(5)),
+		payment("other org", uuid.NewString(), otherOrg, "draft", nil, day(1)),
+	}
+	fake := newFakeDB(t)
+	stubMissingDueDate(t, fake, payments)
+	repo := NewPaymentRepository()
+	scoped := WithOrg(context.Background(), DefaultOrgID)
+
+	list := func(ctx context.Context, charter *uuid.UUID, page Page) []string {
+		t.Helper()
+		got, err := repo.ListMissingDueDate(ctx, charter, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]string, len(got))
+		for i, p := range got {
+			if p.DueDate != nil {
+				t.Errorf("payment %s has due date %s", p.ID, p.DueDate)
+			}
+			out[i] = *p.Description
+		}
+		return out
+	}
+	a := uuid.MustParse(charterA)
+
+	if got, want := list(scoped, nil, Page{Limit: 10}), []string{"undated pending", "undated draft", "undated failed"}; !slices.Equal(got, want) {
+		t.Errorf("global = %v, want %v oldest first", got, want)
+	}
+	if got, want := list(scoped, &a, Page{Limit: 10}), []string{"undated draft", "undated failed"}; !slices.Equal(got, want) {
+		t.Errorf("charter A = %v, want %v", got, want)
+	}
+	if got, want := list(scoped, nil, Page{Limit: 2, Offset: 2}), []string{"undated failed"}; !slices.Equal(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+	if got := list(context.Background(), nil, Page{Limit: 10}); len(got) != 4 || got[0] != "other org" {
+		t.Errorf("unscoped = %v, want every org's undated payments", got)
+	}
+	if calls := fake.Calls("v.charter_detail_id = $"); len(calls) != 1 {
+		t.Errorf("%d charter-filtered queries, want only the scoped call", len(calls))
+	}
+}
+
+func TestPaymentListMissingDueDateRejectsDeepOffset(t *testing.T) {
+	fake := newFakeDB(t)
+	_, err := NewPaymentRepository().ListMissingDueDate(context.Background(), nil, Page{Limit: 10, Offset: MaxListOffset + 1})
+	if !errors.Is(err, ErrOffsetTooLarge) {
+		t.Errorf("err = %v, want ErrOffsetTooLarge", err)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("queried payments past the offset cap")
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CurrencyTotal sums a charter's payments in one currency. Failed and
+// cancelled payments are excluded; Paid covers the completed ones.
+type CurrencyTotal struct {
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Total    float64 `json:"total"`
+	Paid     float64 `json:"paid"`
+}
+
+// currencyOrder is the preferred ordering applied when a caller passes none.
+var currencyOrder []string
+
+// SetCurrencyOrder sets the default currency ordering for totals. Codes are
+// matched case-insensitively; nil or empty restores plain alphabetical order.
+func SetCurrencyOrder(codes []string) {
+	currencyOrder = normalizeCurrencies(codes)
+}
+
+// TotalsByCharter sums payments across all of a charter's voyages, one row
+// per currency. Currencies in preferred (or the configured default when
+// preferred is empty) come first in that order; the rest follow
+// alphabetically.
+func (repo *PaymentRepository) TotalsByCharter(ctx context.Context, charterID uuid.UUID, preferred []string) ([]CurrencyTotal, error) {
+	const query = `
+		SELECT
+			upper(p.currency),
+			COUNT(*),
+			COALESCE(SUM(p.amount), 0),
+			COALESCE(SUM(p.amount) FILTER (WHERE p.status = 'completed'), 0)
+		FROM shipman.voyage_payments p
+		JOIN shipman.voyages v ON v.id = p.voyage_id
+		WHERE v.charter_detail_id = $1
+		  AND p.status NOT IN ('failed', 'cancelled')
+		GROUP BY upper(p.currency)
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, charterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CurrencyTotal
+	for rows.Next() {
+		var t CurrencyTotal
+		if err := rows.Scan(&t.Currency, &t.Count, &t.Total, &t.Paid); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	order := normalizeCurrencies(preferred)
+	if len(order) == 0 {
+		order = currencyOrder
+	}
+	SortCurrencyTotals(out, order)
+	return out, nil
+}
+
+// SortCurrencyTotals orders totals in place: currencies listed in preferred
+// first, in that order, then the remainder alphabetically.
+func SortCurrencyTotals(totals []CurrencyTotal, preferred []string) {
+	rank := make(map[string]int, len(preferred))
+	for i, code := range preferred {
+		code = strings.ToUpper(code)
+		if _, ok := rank[code]; !ok {
+			rank[code] = i
+		}
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		a, b := strings.ToUpper(totals[i].Currency), strings.ToUpper(totals[j].Currency)
+		ra, aok := rank[a]
+		rb, bok := rank[b]
+		switch {
+		case aok && bok:
+			return ra < rb
+		case aok != bok:
+			return aok
+		}
+		return a < b
+	})
+}
+
+func normalizeCurrencies(codes []string) []string {
+	var out []string
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" {
+			out = append(out, code)
+		}
+	}
+	return out
+}
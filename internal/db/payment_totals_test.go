@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func currencies(totals []CurrencyTotal) []string {
+	out := make([]string, len(totals))
+	for i, t := range totals {
+		out[i] = t.Currency
+	}
+	return out
+}
+
+func TestSortCurrencyTotals(t *testing.T) {
+	tests := []struct {
+		name      string
+		preferred []string
+		want      []string
+	}{
+		{"alphabetical by default", nil, []string{"BRL", "EUR", "GBP", "SGD", "USD"}},
+		{"preferred first", []string{"USD", "EUR"}, []string{"USD", "EUR", "BRL", "GBP", "SGD"}},
+		{"case-insensitive codes", []string{"sgd", "Gbp"}, []string{"SGD", "GBP", "BRL", "EUR", "USD"}},
+		{"unknown and repeated codes", []string{"JPY", "EUR", "USD", "EUR"}, []string{"EUR", "USD", "BRL", "GBP", "SGD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := []CurrencyTotal{{Currency: "SGD"}, {Currency: "EUR"}, {Currency: "USD"}, {Currency: "BRL"}, {Currency: "GBP"}}
+			SortCurrencyTotals(totals, tt.preferred)
+			if got := currencies(totals); !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentTotalsByCharter(t *testing.T) {
+	t.Cleanup(func() { SetCurrencyOrder(nil) })
+	tests := []struct {
+		name       string
+		configured []string
+		preferred  []string
+		want       []string
+	}{
+		{"alphabetical", nil, nil, []string{"EUR", "GBP", "USD"}},
+		{"configured order", []string{" usd ", ""}, nil, []string{"USD", "EUR", "GBP"}},
+		{"caller order wins", []string{"USD"}, []string{"GBP", "EUR"}, []string{"GBP", "EUR", "USD"}},
+		{"blank caller order keeps the default", []string{"EUR"}, []string{" ", ""}, []string{"EUR", "GBP", "USD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetCurrencyOrder(tt.configured)
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.voyage_payments p JOIN shipman.voyages v", dbtest.Rows(
+				[]string{"currency", "count", "total", "paid"},
+				[]any{"USD", 3, 3000.0, 1000.0},
+				[]any{"GBP", 1, 500.0, 0.0},
+				[]any{"EUR", 2, 1200.0, 1200.0},
+			))
+			charterID := uuid.New()
+
+			totals, err := NewPaymentRepository().TotalsByCharter(context.Background(), charterID, tt.preferred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := currencies(totals); !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+			i := slices.IndexFunc(totals, func(c CurrencyTotal) bool { return c.Currency == "USD" })
+			if got := totals[i]; got.Count != 3 || got.Total != 3000 || got.Paid != 1000 {
+				t.Errorf("USD total = %+v, want 3 payments, 3000 total, 1000 paid", got)
+			}
+			if calls := fake.Calls("FROM shipman.voyage_payments"); len(calls) != 1 || calls[0].Arg(1) != charterID.String() {
+				t.Errorf("calls = %+v, want one for the charter", calls)
+			}
+		})
+	}
+}
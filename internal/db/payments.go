@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,6 +26,7 @@ type VoyagePayment struct {
 	CoinsubTxHash       *string    `json:"coinsub_tx_hash,omitempty"`
 	Status              string     `json:"status"`
 	PaidAt              *time.Time `json:"paid_at,omitempty"`
+	DueDate             *time.Time `json:"due_date,omitempty"`
 	CreatedAt           time.Time  `json:"created_at"`
 	UpdatedAt           time.Time  `json:"updated_at"`
 }
@@ -35,42 +37,66 @@ func NewPaymentRepository() *PaymentRepository {
 	return &PaymentRepository{}
 }
 
+// Create inserts a payment. A blank currency is taken from the voyage's
+// charter default_currency, falling back to USD.
 func (repo *PaymentRepository) Create(ctx context.Context, p *VoyagePayment) error {
+	clearServerFields(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	p.Normalize()
+	if err := sanitizeNotes(notesField{"description", p.Description}); err != nil {
+		return err
+	}
+	p.PaidAt = nil
+	p.CoinsubSessionID, p.CoinsubPaymentID, p.CoinsubAgreementID = nil, nil, nil
+	p.CoinsubCheckoutURL, p.CoinsubTxHash = nil, nil
 	const query = `
 		INSERT INTO shipman.voyage_payments
 			(voyage_id, created_by, payment_type, description, amount, currency,
-			 recipient_email, recipient_wallet, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, created_at, updated_at
+			 recipient_email, recipient_wallet, status, due_date)
+		VALUES ($1, $2, $3, $4, $5,
+			COALESCE(
+				NULLIF($6, ''),
+				(SELECT cd.default_currency
+				 FROM shipman.voyages v
+				 JOIN shipman.charter_details cd ON cd.id = v.charter_detail_id
+				 WHERE v.id = $1),
+				'USD'
+			),
+			$7, $8, $9, $10)
+		RETURNING id, currency, created_at, updated_at
 	`
-	return Pool.QueryRowContext(ctx, query,
+	return Conn(ctx).QueryRowContext(ctx, query,
 		p.VoyageID, p.CreatedBy, p.PaymentType, nullableString(p.Description),
 		p.Amount, p.Currency,
 		nullableString(p.RecipientEmail), nullableString(p.RecipientWallet),
-		p.Status,
-	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+		p.Status, nullableTime(p.DueDate),
+	).Scan(&p.ID, &p.Currency, &p.CreatedAt, &p.UpdatedAt)
 }
 
+// Retrieve fetches a payment by id. Payments whose voyage is outside the org
+// ctx is scoped to are reported as sql.ErrNoRows.
 func (repo *PaymentRepository) Retrieve(ctx context.Context, id uuid.UUID) (VoyagePayment, error) {
 	const query = `
 		SELECT id, voyage_id, created_by, payment_type, description, amount, currency,
 		       recipient_email, recipient_wallet,
 		       coinsub_session_id, coinsub_payment_id, coinsub_agreement_id,
 		       coinsub_checkout_url, coinsub_tx_hash,
-		       status, paid_at, created_at, updated_at
+		       status, paid_at, due_date, created_at, updated_at
 		FROM shipman.voyage_payments
 		WHERE id = $1
+		  AND ($2::uuid IS NULL OR EXISTS (
+		      SELECT 1 FROM shipman.voyages v
+		      WHERE v.id = voyage_id AND v.org_id = $2))
 	`
 	var p VoyagePayment
 	var desc, recEmail, recWallet sql.NullString
 	var csSession, csPayment, csAgreement, csCheckout, csTxHash sql.NullString
-	var paidAt sql.NullTime
+	var paidAt, dueDate sql.NullTime
 
-	err := Pool.QueryRowContext(ctx, query, id).Scan(
+	err := Pool.QueryRowContext(ctx, query, id, orgFilter(ctx)).Scan(
 		&p.ID, &p.VoyageID, &p.CreatedBy, &p.PaymentType, &desc, &p.Amount, &p.Currency,
 		&recEmail, &recWallet,
 		&csSession, &csPayment, &csAgreement, &csCheckout, &csTxHash,
-		&p.Status, &paidAt, &p.CreatedAt, &p.UpdatedAt,
+		&p.Status, &paidAt, &dueDate, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err != nil {
 		return p, err
@@ -83,6 +109,7 @@ func (repo *PaymentRepository) Retrieve(ctx context.Context, id uuid.UUID) (Voya
 	p.CoinsubAgreementID = stringPtr(csAgreement)
 	p.CoinsubCheckoutURL = stringPtr(csCheckout)
 	p.CoinsubTxHash = stringPtr(csTxHash)
+	p.DueDate = timePtr(dueDate)
 	if paidAt.Valid {
 		p.PaidAt = &paidAt.Time
 	}
@@ -95,7 +122,7 @@ func (repo *PaymentRepository) ListByVoyage(ctx context.Context, voyageID uuid.U
 		       recipient_email, recipient_wallet,
 		       coinsub_session_id, coinsub_payment_id, coinsub_agreement_id,
 		       coinsub_checkout_url, coinsub_tx_hash,
-		       status, paid_at, created_at, updated_at
+		       status, paid_at, due_date, created_at, updated_at
 		FROM shipman.voyage_payments
 		WHERE voyage_id = $1
 		ORDER BY created_at DESC
@@ -111,13 +138,13 @@ func (repo *PaymentRepository) ListByVoyage(ctx context.Context, voyageID uuid.U
 		var p VoyagePayment
 		var desc, recEmail, recWallet sql.NullString
 		var csSession, csPayment, csAgreement, csCheckout, csTxHash sql.NullString
-		var paidAt sql.NullTime
+		var paidAt, dueDate sql.NullTime
 
 		if err := rows.Scan(
 			&p.ID, &p.VoyageID, &p.CreatedBy, &p.PaymentType, &desc, &p.Amount, &p.Currency,
 			&recEmail, &recWallet,
 			&csSession, &csPayment, &csAgreement, &csCheckout, &csTxHash,
-			&p.Status, &paidAt, &p.CreatedAt, &p.UpdatedAt,
+			&p.Status, &paidAt, &dueDate, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -129,6 +156,175 @@ func (repo *PaymentRepository) ListByVoyage(ctx context.Context, voyageID uuid.U
 		p.CoinsubAgreementID = stringPtr(csAgreement)
 		p.CoinsubCheckoutURL = stringPtr(csCheckout)
 		p.CoinsubTxHash = stringPtr(csTxHash)
+		p.DueDate = timePtr(dueDate)
+		if paidAt.Valid {
+			p.PaidAt = &paidAt.Time
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// PaymentStatus is a value of voyage_payments.status.
+type PaymentStatus string
+
+const (
+	PaymentDraft     PaymentStatus = "draft"
+	PaymentPending   PaymentStatus = "pending"
+	PaymentCompleted PaymentStatus = "completed"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentCancelled PaymentStatus = "cancelled"
+	PaymentDisputed  PaymentStatus = "disputed"
+)
+
+// paymentBulkTransitions lists, for each status UpdateStatusMany may set, the
+// statuses a payment may be moving from.
+var paymentBulkTransitions = map[PaymentStatus][]string{
+	PaymentDisputed:  {"pending", "completed", "failed"},
+	PaymentCancelled: {"draft", "pending", "failed", "disputed"},
+}
+
+// UpdateStatusMany moves the given payments to status in one statement and
+// returns how many changed. Only 'disputed' and 'cancelled' may be set this
+// way (ErrInvalidStatus otherwise); payments whose current status cannot move
+// to the target are left untouched and not counted.
+func (repo *PaymentRepository) UpdateStatusMany(ctx context.Context, ids []uuid.UUID, status PaymentStatus) (int64, error) {
+	from, ok := paymentBulkTransitions[status]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	const query = `
+		UPDATE shipman.voyage_payments
+		SET status = $2, updated_at = NOW()
+		WHERE id = ANY($1::uuid[])
+		  AND status = ANY($3::text[])
+	`
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	res, err := Conn(ctx).ExecContext(ctx, query, idStrings, string(status), from)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListOverdue returns unpaid (draft or pending) payments whose due date is
+// before today according to the package clock, oldest due first.
+func (repo *PaymentRepository) ListOverdue(ctx context.Context) ([]VoyagePayment, error) {
+	query := `
+		SELECT id, voyage_id, created_by, payment_type, description, amount, currency,
+		       recipient_email, recipient_wallet,
+		       coinsub_session_id, coinsub_payment_id, coinsub_agreement_id,
+		       coinsub_checkout_url, coinsub_tx_hash,
+		       status, paid_at, due_date, created_at, updated_at
+		FROM shipman.voyage_payments
+		WHERE due_date < $1
+		  AND status IN ('draft', 'pending')
+		ORDER BY ` + orderBy(asc("due_date"), asc("created_at"), asc("id"))
+	today := now().UTC().Format("2006-01-02")
+	rows, err := Pool.QueryContext(ctx, query, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []VoyagePayment
+	for rows.Next() {
+		var p VoyagePayment
+		var desc, recEmail, recWallet sql.NullString
+		var csSession, csPayment, csAgreement, csCheckout, csTxHash sql.NullString
+		var paidAt, dueDate sql.NullTime
+
+		if err := rows.Scan(
+			&p.ID, &p.VoyageID, &p.CreatedBy, &p.PaymentType, &desc, &p.Amount, &p.Currency,
+			&recEmail, &recWallet,
+			&csSession, &csPayment, &csAgreement, &csCheckout, &csTxHash,
+			&p.Status, &paidAt, &dueDate, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		p.Description = stringPtr(desc)
+		p.RecipientEmail = stringPtr(recEmail)
+		p.RecipientWallet = stringPtr(recWallet)
+		p.CoinsubSessionID = stringPtr(csSession)
+		p.CoinsubPaymentID = stringPtr(csPayment)
+		p.CoinsubAgreementID = stringPtr(csAgreement)
+		p.CoinsubCheckoutURL = stringPtr(csCheckout)
+		p.CoinsubTxHash = stringPtr(csTxHash)
+		p.DueDate = timePtr(dueDate)
+		if paidAt.Valid {
+			p.PaidAt = &paidAt.Time
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// ListMissingDueDate returns outstanding payments entered without a due
+// date, which ListOverdue can never report. Completed (paid) and cancelled
+// payments are left out. With charterID set only payments on that charter's
+// voyages are returned; nil lists across every charter. Oldest first.
+func (repo *PaymentRepository) ListMissingDueDate(ctx context.Context, charterID *uuid.UUID, page Page) ([]VoyagePayment, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	var where conditions
+	where.add("($%[1]d::uuid IS NULL OR v.org_id = $%[1]d)", orgFilter(ctx))
+	if charterID != nil {
+		where.add("v.charter_detail_id = $%d", *charterID)
+	}
+	where.page(orderBy(asc("p.created_at"), asc("p.id")), page.limit(), page.Offset)
+
+	query := `
+		SELECT p.id, p.voyage_id, p.created_by, p.payment_type, p.description, p.amount, p.currency,
+		       p.recipient_email, p.recipient_wallet,
+		       p.coinsub_session_id, p.coinsub_payment_id, p.coinsub_agreement_id,
+		       p.coinsub_checkout_url, p.coinsub_tx_hash,
+		       p.status, p.paid_at, p.due_date, p.created_at, p.updated_at
+		FROM shipman.voyage_payments p
+		JOIN shipman.voyages v ON v.id = p.voyage_id
+		WHERE p.due_date IS NULL
+		  AND p.status NOT IN ('completed', 'cancelled')
+	` + where.sql
+	rows, err := Pool.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []VoyagePayment
+	for rows.Next() {
+		var p VoyagePayment
+		var desc, recEmail, recWallet sql.NullString
+		var csSession, csPayment, csAgreement, csCheckout, csTxHash sql.NullString
+		var paidAt, dueDate sql.NullTime
+
+		if err := rows.Scan(
+			&p.ID, &p.VoyageID, &p.CreatedBy, &p.PaymentType, &desc, &p.Amount, &p.Currency,
+			&recEmail, &recWallet,
+			&csSession, &csPayment, &csAgreement, &csCheckout, &csTxHash,
+			&p.Status, &paidAt, &dueDate, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		p.Description = stringPtr(desc)
+		p.RecipientEmail = stringPtr(recEmail)
+		p.RecipientWallet = stringPtr(recWallet)
+		p.CoinsubSessionID = stringPtr(csSession)
+		p.CoinsubPaymentID = stringPtr(csPayment)
+		p.CoinsubAgreementID = stringPtr(csAgreement)
+		p.CoinsubCheckoutURL = stringPtr(csCheckout)
+		p.CoinsubTxHash = stringPtr(csTxHash)
+		p.DueDate = timePtr(dueDate)
 		if paidAt.Valid {
 			p.PaidAt = &paidAt.Time
 		}
@@ -143,8 +339,7 @@ func (repo *PaymentRepository) UpdateCoinsubSession(ctx context.Context, id uuid
 		SET coinsub_session_id = $2, coinsub_checkout_url = $3, status = 'pending'
 		WHERE id = $1
 	`
-	_, err := Pool.ExecContext(ctx, query, id, sessionID, checkoutURL)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id, sessionID, checkoutURL))
 }
 
 func (repo *PaymentRepository) MarkCompleted(ctx context.Context, sessionID, paymentID, txHash string) error {
@@ -193,8 +388,7 @@ func (repo *PaymentRepository) UpdateTransfer(ctx context.Context, id uuid.UUID,
 		SET coinsub_tx_hash = $2, status = 'completed', paid_at = NOW()
 		WHERE id = $1
 	`
-	_, err := Pool.ExecContext(ctx, query, id, txHash)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id, txHash))
 }
 
 // MarkPaid manually marks a payment as completed (for testing / off-platform payments).
@@ -219,20 +413,20 @@ func (repo *PaymentRepository) FindBySessionID(ctx context.Context, sessionID st
 		       recipient_email, recipient_wallet,
 		       coinsub_session_id, coinsub_payment_id, coinsub_agreement_id,
 		       coinsub_checkout_url, coinsub_tx_hash,
-		       status, paid_at, created_at, updated_at
+		       status, paid_at, due_date, created_at, updated_at
 		FROM shipman.voyage_payments
 		WHERE coinsub_session_id = $1
 	`
 	var p VoyagePayment
 	var desc, recEmail, recWallet sql.NullString
 	var csSession, csPayment, csAgreement, csCheckout, csTxHash sql.NullString
-	var paidAt sql.NullTime
+	var paidAt, dueDate sql.NullTime
 
 	err := Pool.QueryRowContext(ctx, query, sessionID).Scan(
 		&p.ID, &p.VoyageID, &p.CreatedBy, &p.PaymentType, &desc, &p.Amount, &p.Currency,
 		&recEmail, &recWallet,
 		&csSession, &csPayment, &csAgreement, &csCheckout, &csTxHash,
-		&p.Status, &paidAt, &p.CreatedAt, &p.UpdatedAt,
+		&p.Status, &paidAt, &dueDate, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err != nil {
 		return p, err
@@ -245,6 +439,7 @@ func (repo *PaymentRepository) FindBySessionID(ctx context.Context, sessionID st
 	p.CoinsubAgreementID = stringPtr(csAgreement)
 	p.CoinsubCheckoutURL = stringPtr(csCheckout)
 	p.CoinsubTxHash = stringPtr(csTxHash)
+	p.DueDate = timePtr(dueDate)
 	if paidAt.Valid {
 		p.PaidAt = &paidAt.Time
 	}
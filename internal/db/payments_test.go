@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// fakePaymentStatuses applies the bulk status UPDATE to an in-memory
+// id -> status table the way the WHERE clause would.
+type fakePaymentStatuses struct {
+	mu     sync.Mutex
+	status map[string]string
+}
+
+func (f *fakePaymentStatuses) install(fake *dbtest.Fake) {
+	fake.On("UPDATE shipman.voyage_payments SET status = $2, updated_at = NOW() WHERE id = ANY($1::uuid[])", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		to, from := call.Arg(2).(string), call.Arg(3).([]string)
+		var n int64
+		for _, id := range call.Arg(1).([]string) {
+			if cur, ok := f.status[id]; ok && slices.Contains(from, cur) {
+				f.status[id] = to
+				n++
+			}
+		}
+		return dbtest.Affected(n)
+	})
+}
+
+func TestPaymentUpdateStatusMany(t *testing.T) {
+	pending, completed, draft, cancelled, disputed := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	initial := map[string]string{
+		pending.String():   "pending",
+		completed.String(): "completed",
+		draft.String():     "draft",
+		cancelled.String(): "cancelled",
+		disputed.String():  "disputed",
+	}
+	all := []uuid.UUID{pending, completed, draft, cancelled, disputed, uuid.New()}
+
+	tests := []struct {
+		name        string
+		to          PaymentStatus
+		want        int64
+		wantChanged []uuid.UUID
+	}{
+		{"dispute", PaymentDisputed, 2, []uuid.UUID{pending, completed}},
+		{"cancel", PaymentCancelled, 3, []uuid.UUID{pending, draft, disputed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			table := &fakePaymentStatuses{status: maps.Clone(initial)}
+			table.install(fake)
+
+			n, err := NewPaymentRepository().UpdateStatusMany(context.Background(), all, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Errorf("updated = %d, want %d", n, tt.want)
+			}
+			for id, before := range initial {
+				changed := slices.Contains(tt.wantChanged, uuid.MustParse(id))
+				if got := table.status[id]; (changed && got != string(tt.to)) || (!changed && got != before) {
+					t.Errorf("%s payment %s is now %s", before, id, got)
+				}
+			}
+			if len(fake.Calls("UPDATE shipman.voyage_payments")) != 1 {
+				t.Error("want a single UPDATE for the batch")
+			}
+		})
+	}
+}
+
+func TestPaymentUpdateStatusManyRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []uuid.UUID
+		to      PaymentStatus
+		wantErr error
+	}{
+		{"completed", []uuid.UUID{uuid.New()}, PaymentCompleted, ErrInvalidStatus},
+		{"pending", []uuid.UUID{uuid.New()}, PaymentPending, ErrInvalidStatus},
+		{"unknown", []uuid.UUID{uuid.New()}, "refunded", ErrInvalidStatus},
+		{"empty batch", nil, PaymentDisputed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			n, err := NewPaymentRepository().UpdateStatusMany(context.Background(), tt.ids, tt.to)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if n != 0 {
+				t.Errorf("updated = %d, want 0", n)
+			}
+			if calls := fake.Calls(""); len(calls) != 0 {
+				t.Errorf("queried the database: %+v", calls)
+			}
+		})
+	}
+}
+
+// fakeCurrencyDefault answers the insert matching match the way its currency
+// COALESCE would: a non-blank $6, else the charter default looked up by the
+// statement's $1, else USD.
+func fakeCurrencyDefault(fake *dbtest.Fake, match string, defaults map[string]string, columns []string) {
+	fake.On(match, func(call dbtest.Call) dbtest.Result {
+		var currency any = "USD"
+		if d, ok := defaults[call.Arg(1).(string)]; ok {
+			currency = d
+		}
+		if c, _ := call.Arg(6).(string); c != "" {
+			currency = c
+		}
+		values := map[string]any{"id": uuid.New(), "currency": currency, "status": "draft", "created_at": time.Now(), "updated_at": time.Now()}
+		return dbtest.Rows(columns, dbtest.Row(columns, values))
+	})
+}
+
+func TestPaymentCreateInheritsCharterCurrency(t *testing.T) {
+	eurVoyage, bareVoyage, orphanVoyage := uuid.New(), uuid.New(), uuid.New()
+	tests := []struct {
+		name     string
+		voyageID uuid.UUID
+		currency string
+		want     string
+	}{
+		{"charter default", eurVoyage, "", "EUR"},
+		{"explicit currency wins", eurVoyage, "gbp", "GBP"},
+		{"charter without a default", bareVoyage, "", "USD"},
+		{"voyage without a charter", orphanVoyage, "", "USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fakeCurrencyDefault(fake, "INSERT INTO shipman.voyage_payments", map[string]string{eurVoyage.String(): "EUR"},
+				[]string{"id", "currency", "created_at", "updated_at"})
+
+			p := &VoyagePayment{VoyageID: tt.voyageID, CreatedBy: uuid.New(), PaymentType: "hire", Amount: 1000, Currency: tt.currency, Status: "draft"}
+			if err := NewPaymentRepository().Create(context.Background(), p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Currency != tt.want {
+				t.Errorf("currency = %q, want %q", p.Currency, tt.want)
+			}
+			query := fake.Calls("INSERT INTO shipman.voyage_payments")[0].Query
+			for _, part := range []string{
+				"NULLIF($6, '')",
+				"SELECT cd.default_currency FROM shipman.voyages v JOIN shipman.charter_details cd ON cd.id = v.charter_detail_id WHERE v.id = $1",
+				"'USD' )",
+				"RETURNING id, currency",
+			} {
+				if !strings.Contains(query, part) {
+					t.Errorf("insert is missing %q", part)
+				}
+			}
+		})
+	}
+}
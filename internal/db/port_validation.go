@@ -0,0 +1,40 @@
+package db
+
+import "strings"
+
+// ValidationError reports field-level problems that stopped a write. Callers
+// can unwrap it with errors.As to answer with the individual fields.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// checkCoordinates requires latitude in [-90, 90] and longitude in
+// [-180, 180]. Both may be nil for an unknown position, but one may not be
+// set without the other.
+func checkCoordinates(lat, lon *float64) error {
+	var issues []FieldError
+	switch {
+	case lat == nil && lon != nil:
+		issues = append(issues, FieldError{Field: "latitude", Message: "latitude is required when longitude is set"})
+	case lat != nil && (*lat < -90 || *lat > 90):
+		issues = append(issues, FieldError{Field: "latitude", Message: "latitude must be between -90 and 90"})
+	}
+	switch {
+	case lon == nil && lat != nil:
+		issues = append(issues, FieldError{Field: "longitude", Message: "longitude is required when latitude is set"})
+	case lon != nil && (*lon < -180 || *lon > 180):
+		issues = append(issues, FieldError{Field: "longitude", Message: "longitude must be between -180 and 180"})
+	}
+	if len(issues) > 0 {
+		return &ValidationError{Errors: issues}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PositionCursor marks a place in a voyage's track for keyset pagination
+// over (recorded_at, id). A zero RecordedAt and ID starts from the oldest
+// position, or the newest when Backward is set.
+type PositionCursor struct {
+	RecordedAt time.Time
+	ID         uuid.UUID
+	Backward   bool
+}
+
+// ErrInvalidCursor is returned by DecodePositionCursor for malformed input.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c PositionCursor) Encode() string {
+	dir := "f"
+	if c.Backward {
+		dir = "b"
+	}
+	raw := dir + "|" + c.RecordedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodePositionCursor parses a token produced by PositionCursor.Encode.
+func DecodePositionCursor(token string) (PositionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return PositionCursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || (parts[0] != "f" && parts[0] != "b") {
+		return PositionCursor{}, ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return PositionCursor{}, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return PositionCursor{}, ErrInvalidCursor
+	}
+	return PositionCursor{RecordedAt: at, ID: id, Backward: parts[0] == "b"}, nil
+}
+
+func (c *PositionCursor) isStart() bool {
+	return c == nil || (c.RecordedAt.IsZero() && c.ID == uuid.Nil)
+}
+
+// PageByVoyage returns up to limit positions strictly after cursor in its
+// direction: oldest first going forward, newest first going backward. A nil
+// cursor pages forward from the start of the track. The returned cursor
+// points at the last row of the page and keeps the direction; it is nil once
+// the track is exhausted. limit <= 0 uses DefaultPageSize.
+func (repo *ShipPositionRepository) PageByVoyage(ctx context.Context, voyageID uuid.UUID, cursor *PositionCursor, limit int) ([]ShipPosition, *PositionCursor, error) {
+	limit = Page{Limit: limit}.limit()
+	backward := cursor != nil && cursor.Backward
+
+	cmp, order := ">", "ASC"
+	if backward {
+		cmp, order = "<", "DESC"
+	}
+
+	query := `
+		SELECT id, voyage_id, recorded_at, latitude, longitude, speed_knots, heading,
+		       distance_logged_nm, fuel_remaining_mt, source, remarks, created_at, updated_at
+		FROM shipman.ship_positions
+		WHERE voyage_id = $1`
+	args := []any{voyageID}
+	if !cursor.isStart() {
+		query += fmt.Sprintf(" AND (recorded_at, id) %s ($2, $3)", cmp)
+		args = append(args, cursor.RecordedAt, cursor.ID)
+	}
+	// Fetch one extra row to learn whether another page follows.
+	query += fmt.Sprintf(" ORDER BY recorded_at %s, id %s LIMIT %d", order, order, limit+1)
+
+	rows, err := Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var positions []ShipPosition
+	for rows.Next() {
+		pos, err := scanShipPosition(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		positions = append(positions, pos)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(positions) <= limit {
+		return positions, nil, nil
+	}
+	positions = positions[:limit]
+	last := positions[len(positions)-1]
+	return positions, &PositionCursor{RecordedAt: last.RecordedAt, ID: last.ID, Backward: backward}, nil
+}
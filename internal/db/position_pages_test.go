@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var pageLimit = regexp.MustCompile(`LIMIT (\d+)$`)
+
+// stubPositionPages answers PageByVoyage from rows, applying the keyset
+// comparison, order and limit the statement asks for.
+func stubPositionPages(fake *dbtest.Fake, rows [][]any) {
+	key := func(r []any) (time.Time, string) { return r[2].(time.Time), r[0].(uuid.UUID).String() }
+	cmp := func(a, b []any) int {
+		at, aid := key(a)
+		bt, bid := key(b)
+		if c := at.Compare(bt); c != 0 {
+			return c
+		}
+		return strings.Compare(aid, bid)
+	}
+	fake.On("FROM shipman.ship_positions WHERE voyage_id = $1", func(call dbtest.Call) dbtest.Result {
+		backward := strings.Contains(call.Query, "ORDER BY recorded_at DESC, id DESC")
+		sorted := slices.Clone(rows)
+		slices.SortFunc(sorted, cmp)
+		if backward {
+			slices.Reverse(sorted)
+		}
+		var out [][]any
+		for _, r := range sorted {
+			if len(call.Args) == 3 {
+				after := []any{uuid.MustParse(call.Arg(3).(string)), nil, call.Arg(2).(time.Time)}
+				c := cmp(r, after)
+				if (!backward && c <= 0) || (backward && c >= 0) {
+					continue
+				}
+			}
+			out = append(out, r)
+		}
+		n, _ := strconv.Atoi(pageLimit.FindStringSubmatch(call.Query)[1])
+		return dbtest.Rows(streamColumns, out[:min(n, len(out))]...)
+	})
+}
+
+func TestPositionPageByVoyage(t *testing.T) {
+	voyageID := uuid.New()
+	rows := positionRows(voyageID, 23)
+	// Several fixes sharing one timestamp must still page by id.
+	shared := rows[10][2]
+	for _, r := range rows[11:15] {
+		r[2] = shared
+	}
+	want := slices.Clone(rows)
+	slices.SortFunc(want, func(a, b []any) int {
+		if c := a[2].(time.Time).Compare(b[2].(time.Time)); c != 0 {
+			return c
+		}
+		return strings.Compare(a[0].(uuid.UUID).String(), b[0].(uuid.UUID).String())
+	})
+	wantIDs := make([]uuid.UUID, len(want))
+	for i, r := range want {
+		wantIDs[i] = r[0].(uuid.UUID)
+	}
+
+	for _, backward := range []bool{false, true} {
+		t.Run("backward="+strconv.FormatBool(backward), func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubPositionPages(fake, rows)
+			repo := NewShipPositionRepository()
+
+			var (
+				got    []uuid.UUID
+				cursor = &PositionCursor{Backward: backward}
+				pages  int
+			)
+			for cursor != nil {
+				page, next, err := repo.PageByVoyage(context.Background(), voyageID, cursor, 5)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page) > 5 {
+					t.Fatalf("page %d has %d positions, want at most 5", pages, len(page))
+				}
+				if next != nil && next.Backward != backward {
+					t.Fatal("next cursor changed direction")
+				}
+				for _, pos := range page {
+					got = append(got, pos.ID)
+				}
+				pages++
+				if next != nil {
+					// Round-trip through the token a client would send back.
+					decoded, err := DecodePositionCursor(next.Encode())
+					if err != nil {
+						t.Fatal(err)
+					}
+					next = &decoded
+				}
+				cursor = next
+			}
+
+			expect := slices.Clone(wantIDs)
+			if backward {
+				slices.Reverse(expect)
+			}
+			if !slices.Equal(got, expect) {
+				t.Errorf("paged ids = %v\nwant %v", got, expect)
+			}
+			if pages != 5 {
+				t.Errorf("pages = %d, want 5", pages)
+			}
+		})
+	}
+}
+
+func TestPositionPageByVoyageExactFit(t *testing.T) {
+	voyageID := uuid.New()
+	fake := newFakeDB(t)
+	stubPositionPages(fake, positionRows(voyageID, 10))
+	repo := NewShipPositionRepository()
+
+	first, next, err := repo.PageByVoyage(context.Background(), voyageID, nil, 5)
+	if err != nil || len(first) != 5 || next == nil {
+		t.Fatalf("first page = %d positions, next %v, err %v", len(first), next, err)
+	}
+	second, next, err := repo.PageByVoyage(context.Background(), voyageID, next, 5)
+	if err != nil || len(second) != 5 {
+		t.Fatalf("second page = %d positions, err %v", len(second), err)
+	}
+	if next != nil {
+		t.Errorf("next = %+v after the last row, want nil", next)
+	}
+	if first[4].ID == second[0].ID {
+		t.Error("consecutive pages overlap")
+	}
+	if calls := fake.Calls(""); !strings.HasSuffix(calls[0].Query, "LIMIT 6") || len(calls[0].Args) != 1 {
+		t.Errorf("first page query = %s %v, want no keyset and one extra row", calls[0].Query, calls[0].Args)
+	}
+}
+
+func TestDecodePositionCursor(t *testing.T) {
+	c := PositionCursor{RecordedAt: time.Date(2026, 5, 1, 3, 4, 5, 600, time.UTC), ID: uuid.New(), Backward: true}
+	got, err := DecodePositionCursor(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.RecordedAt.Equal(c.RecordedAt) || got.ID != c.ID || !got.Backward {
+		t.Errorf("decoded = %+v, want %+v", got, c)
+	}
+	for _, token := range []string{"", "!!", "eHx5fHo", PositionCursor{}.Encode()[:4]} {
+		if _, err := DecodePositionCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodePositionCursor(%q) err = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
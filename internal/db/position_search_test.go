@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var (
+	sourceFilter = regexp.MustCompile(`source = \$(\d+)`)
+	limitOffset  = regexp.MustCompile(`LIMIT \$(\d+) OFFSET \$(\d+)`)
+)
+
+// stubPositionSearch answers Search from rows, applying the voyage and
+// source filters, the newest-first order and the page the statement asks
+// for.
+func stubPositionSearch(fake *dbtest.Fake, rows [][]any) {
+	fake.On("FROM shipman.ship_positions WHERE TRUE", func(call dbtest.Call) dbtest.Result {
+		var source any
+		if m := sourceFilter.FindStringSubmatch(call.Query); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			source = call.Arg(n)
+		}
+		var out [][]any
+		for _, r := range rows {
+			if r[1].(uuid.UUID).String() == call.Arg(1) && (source == nil || r[9] == source) {
+				out = append(out, r)
+			}
+		}
+		slices.SortFunc(out, func(a, b []any) int {
+			if c := b[2].(time.Time).Compare(a[2].(time.Time)); c != 0 {
+				return c
+			}
+			return strings.Compare(b[0].(uuid.UUID).String(), a[0].(uuid.UUID).String())
+		})
+		m := limitOffset.FindStringSubmatch(call.Query)
+		ln, _ := strconv.Atoi(m[1])
+		on, _ := strconv.Atoi(m[2])
+		limit, offset := int(call.Arg(ln).(int64)), int(call.Arg(on).(int64))
+		out = out[min(offset, len(out)):]
+		return dbtest.Rows(streamColumns, out[:min(limit, len(out))]...)
+	})
+}
+
+func TestShipPositionSearch(t *testing.T) {
+	voyageID := uuid.New()
+	rows := positionRows(voyageID, 12)
+	for i, r := range rows {
+		r[9] = []string{"ais", "manual", "noon_report"}[i%3]
+	}
+	// Two AIS fixes sharing a timestamp must still page in a fixed order.
+	rows[6][2] = rows[3][2]
+	rows = append(rows, positionRows(uuid.New(), 3)...)
+	for _, r := range rows[12:] {
+		r[9] = "ais"
+	}
+
+	fake := newFakeDB(t)
+	stubPositionSearch(fake, rows)
+	repo := NewShipPositionRepository()
+	ais := "ais"
+
+	var paged []uuid.UUID
+	for offset := 0; ; offset += 2 {
+		got, err := repo.Search(context.Background(), voyageID, ShipPositionFilter{Source: &ais}, Page{Limit: 2, Offset: offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range got {
+			if p.VoyageID != voyageID || p.Source != "ais" {
+				t.Fatalf("position %s from voyage %s source %q, want this voyage's AIS fixes", p.ID, p.VoyageID, p.Source)
+			}
+			paged = append(paged, p.ID)
+		}
+		if len(got) < 2 {
+			break
+		}
+	}
+	all, err := repo.Search(context.Background(), voyageID, ShipPositionFilter{Source: &ais}, Page{Limit: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole := make([]uuid.UUID, len(all))
+	for i, p := range all {
+		whole[i] = p.ID
+	}
+	if len(whole) != 4 || !slices.Equal(paged, whole) {
+		t.Errorf("pages = %v, want the 4 AIS fixes in one stable order %v", paged, whole)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].RecordedAt.After(all[i-1].RecordedAt) {
+			t.Errorf("positions not newest first: %s after %s", all[i].RecordedAt, all[i-1].RecordedAt)
+		}
+	}
+
+	unfiltered, err := repo.Search(context.Background(), voyageID, ShipPositionFilter{}, Page{Limit: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfiltered) != 12 {
+		t.Errorf("unfiltered = %d positions, want all 12 of the voyage", len(unfiltered))
+	}
+	if calls := fake.Calls("source = $"); len(calls) != 4 {
+		t.Errorf("%d filtered queries, want the source bound on every filtered page", len(calls))
+	}
+}
+
+func TestShipPositionSearchRejectsDeepOffset(t *testing.T) {
+	fake := newFakeDB(t)
+	_, err := NewShipPositionRepository().Search(context.Background(), uuid.New(), ShipPositionFilter{}, Page{Limit: 10, Offset: MaxListOffset + 1})
+	if !errors.Is(err, ErrOffsetTooLarge) {
+		t.Errorf("err = %v, want ErrOffsetTooLarge", err)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("queried positions past the offset cap")
+	}
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// schemaTables are the tables the API reads and writes. A deploy whose
+// migrations stopped part way will be missing some of them.
+var schemaTables = []string{
+	"audit_log",
+	"bills_of_lading",
+	"blob_index",
+	"cargo_loads",
+	"charter_details",
+	"charter_laytime_terms",
+	"charter_laytime_totals",
+	"clause_negotiations",
+	"clause_proposals",
+	"deal_cargo_details",
+	"deal_invites",
+	"deal_participants",
+	"deal_vessel_details",
+	"deals",
+	"deletions",
+	"demurrage_documents",
+	"demurrage_records",
+	"disputes",
+	"documents",
+	"laytime_entries",
+	"nor_events",
+	"organizations",
+	"payments",
+	"ship_positions",
+	"subscriptions",
+	"users",
+	"vessels",
+	"voyage_invites",
+	"voyage_payments",
+	"voyage_ports",
+	"voyages",
+}
+
+// TableStatus reports whether one table is present and readable.
+type TableStatus struct {
+	Table  string `json:"table"`
+	OK     bool   `json:"ok"`
+	Reason string `json:"reason,omitempty"` // missing or the query error
+}
+
+// SchemaCheck confirms every table in schemaTables exists in the shipman
+// schema and answers a one-row select. The error is only for failures that
+// stop the check itself, such as an unreachable database; problem tables
+// are reported in the statuses.
+func SchemaCheck(ctx context.Context) ([]TableStatus, error) {
+	if err := Pool.PingContext(ctx); err != nil {
+		return nil, err
+	}
+
+	out := make([]TableStatus, 0, len(schemaTables))
+	for _, table := range schemaTables {
+		status := TableStatus{Table: table}
+
+		var exists bool
+		const existsQuery = `SELECT to_regclass('shipman.' || $1) IS NOT NULL`
+		if err := Pool.QueryRowContext(ctx, existsQuery, table).Scan(&exists); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !exists:
+			status.Reason = "missing"
+		default:
+			// table comes from schemaTables, never from input.
+			rows, err := Pool.QueryContext(ctx, fmt.Sprintf("SELECT 1 FROM shipman.%s LIMIT 1", table))
+			if err == nil {
+				err = rows.Close()
+			}
+			if err != nil {
+				status.Reason = err.Error()
+			} else {
+				status.OK = true
+			}
+		}
+		out = append(out, status)
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestSchemaTablesCoverQueriedTables checks that every migrated table the db
+// package queries is in schemaTables, so a partial migration fails /readyz.
+func TestSchemaTablesCoverQueriedTables(t *testing.T) {
+	migrations, err := filepath.Glob("../../db/migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := regexp.MustCompile(`(?i)CREATE TABLE(?: IF NOT EXISTS)?\s+(?:shipman\.)?(\w+)`)
+	migrated := map[string]bool{}
+	for _, f := range migrations {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(raw), "-- +goose Down")
+		for _, m := range created.FindAllStringSubmatch(up, -1) {
+			migrated[m[1]] = true
+		}
+	}
+
+	sources, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	referenced := regexp.MustCompile(`shipman\.(\w+)`)
+	queried := map[string]bool{}
+	for _, f := range sources {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range referenced.FindAllStringSubmatch(string(raw), -1) {
+			if migrated[m[1]] {
+				queried[m[1]] = true
+			}
+		}
+	}
+
+	listed := map[string]bool{}
+	for _, table := range schemaTables {
+		listed[table] = true
+	}
+	var missing []string
+	for table := range queried {
+		if !listed[table] {
+			missing = append(missing, table)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("schemaTables is missing queried tables %v", missing)
+	}
+}
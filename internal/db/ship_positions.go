@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,6 +23,7 @@ type ShipPosition struct {
 	FuelRemainingMT  *float64  `json:"fuel_remaining_mt,omitempty"`
 	Source           string    `json:"source"`
 	Remarks          *string   `json:"remarks,omitempty"`
+	RawPayload       []byte    `json:"-"` // original AIS message; served by the raw endpoint, not inline
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -28,8 +31,11 @@ type ShipPosition struct {
 // ShipPositionService exposes CRUD behaviour.
 type ShipPositionService interface {
 	Create(ctx context.Context, pos *ShipPosition) error
+	CreateBatch(ctx context.Context, positions []*ShipPosition, strict bool) error
+	CreateBatchPartial(ctx context.Context, positions []*ShipPosition) (int, BatchErrors)
 	Retrieve(ctx context.Context, id uuid.UUID) (ShipPosition, error)
 	ListByVoyage(ctx context.Context, voyageID uuid.UUID, limit int) ([]ShipPosition, error)
+	Search(ctx context.Context, voyageID uuid.UUID, filter ShipPositionFilter, page Page) ([]ShipPosition, error)
 	Update(ctx context.Context, pos *ShipPosition) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -42,8 +48,16 @@ func NewShipPositionRepository() *ShipPositionRepository {
 	return &ShipPositionRepository{}
 }
 
-// Create inserts a ship position.
+// Create inserts a ship position. Out-of-range coordinates are rejected with
+// a *ValidationError.
 func (repo *ShipPositionRepository) Create(ctx context.Context, pos *ShipPosition) error {
+	clearServerFields(&pos.ID, &pos.CreatedAt, &pos.UpdatedAt)
+	if err := checkCoordinates(&pos.Latitude, &pos.Longitude); err != nil {
+		return err
+	}
+	if err := sanitizeNotes(notesField{"remarks", pos.Remarks}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.ship_positions (
 			voyage_id,
@@ -55,14 +69,15 @@ func (repo *ShipPositionRepository) Create(ctx context.Context, pos *ShipPositio
 			distance_logged_nm,
 			fuel_remaining_mt,
 			source,
-			remarks
+			remarks,
+			raw_payload
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, 'manual'), $10
+			$1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, 'manual'), $10, $11
 		)
 		RETURNING id, source, created_at, updated_at
 	`
 
-	return Pool.QueryRowContext(
+	return Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		pos.VoyageID,
@@ -75,9 +90,48 @@ func (repo *ShipPositionRepository) Create(ctx context.Context, pos *ShipPositio
 		nullableFloat(pos.FuelRemainingMT),
 		nullableString(&pos.Source),
 		nullableString(pos.Remarks),
+		nullableBytes(pos.RawPayload),
 	).Scan(&pos.ID, &pos.Source, &pos.CreatedAt, &pos.UpdatedAt)
 }
 
+// CreateBatch inserts positions in one transaction; if any is invalid or
+// fails to insert nothing is written and the error names its index.
+//
+// Gap and speed calculations assume positions arrive in time order. By
+// default the batch is sorted by recorded_at in place (stable, so equal
+// timestamps keep their order) before insert; with strict set an
+// out-of-order batch is rejected with ErrNonMonotonicBatch instead.
+func (repo *ShipPositionRepository) CreateBatch(ctx context.Context, positions []*ShipPosition, strict bool) error {
+	if strict {
+		for i := 1; i < len(positions); i++ {
+			if positions[i].RecordedAt.Before(positions[i-1].RecordedAt) {
+				return fmt.Errorf("position %d: %w", i, ErrNonMonotonicBatch)
+			}
+		}
+	} else {
+		sort.SliceStable(positions, func(i, j int) bool {
+			return positions[i].RecordedAt.Before(positions[j].RecordedAt)
+		})
+	}
+
+	return WithTx(ctx, func(ctx context.Context) error {
+		for i, pos := range positions {
+			if err := repo.Create(ctx, pos); err != nil {
+				return fmt.Errorf("position %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateBatchPartial inserts each position on its own, so invalid ones are
+// reported without holding back the rest. It returns how many were written
+// and the error for each index that was not. ctx should not carry a
+// transaction: one failed insert would abort it for the items after.
+func (repo *ShipPositionRepository) CreateBatchPartial(ctx context.Context, positions []*ShipPosition) (int, BatchErrors) {
+	return createEach(ctx, positions, repo.Create)
+}
+
 // Retrieve fetches a position by id.
 func (repo *ShipPositionRepository) Retrieve(ctx context.Context, id uuid.UUID) (ShipPosition, error) {
 	const query = `
@@ -93,6 +147,7 @@ func (repo *ShipPositionRepository) Retrieve(ctx context.Context, id uuid.UUID)
 			fuel_remaining_mt,
 			source,
 			remarks,
+			raw_payload,
 			created_at,
 			updated_at
 		FROM shipman.ship_positions
@@ -107,6 +162,7 @@ func (repo *ShipPositionRepository) Retrieve(ctx context.Context, id uuid.UUID)
 		fuel     sql.NullFloat64
 		source   sql.NullString
 		remarks  sql.NullString
+		raw      []byte
 	)
 
 	err := Pool.QueryRowContext(ctx, query, id).Scan(
@@ -121,6 +177,7 @@ func (repo *ShipPositionRepository) Retrieve(ctx context.Context, id uuid.UUID)
 		&fuel,
 		&source,
 		&remarks,
+		&raw,
 		&pos.CreatedAt,
 		&pos.UpdatedAt,
 	)
@@ -134,6 +191,7 @@ func (repo *ShipPositionRepository) Retrieve(ctx context.Context, id uuid.UUID)
 	pos.FuelRemainingMT = floatPtr(fuel)
 	pos.Source = defaultString(source, "manual")
 	pos.Remarks = stringPtr(remarks)
+	pos.RawPayload = bytesOrNil(raw)
 
 	return pos, nil
 }
@@ -161,45 +219,172 @@ func (repo *ShipPositionRepository) ListByVoyage(ctx context.Context, voyageID u
 
 	var positions []ShipPosition
 	for rows.Next() {
-		var (
-			pos      ShipPosition
-			speed    sql.NullFloat64
-			heading  sql.NullFloat64
-			distance sql.NullFloat64
-			fuel     sql.NullFloat64
-			source   sql.NullString
-			remarks  sql.NullString
-		)
-		if err := rows.Scan(
-			&pos.ID,
-			&pos.VoyageID,
-			&pos.RecordedAt,
-			&pos.Latitude,
-			&pos.Longitude,
-			&speed,
-			&heading,
-			&distance,
-			&fuel,
-			&source,
-			&remarks,
-			&pos.CreatedAt,
-			&pos.UpdatedAt,
-		); err != nil {
+		pos, err := scanShipPosition(rows)
+		if err != nil {
 			return nil, err
 		}
-		pos.SpeedKnots = floatPtr(speed)
-		pos.Heading = floatPtr(heading)
-		pos.DistanceLoggedNM = floatPtr(distance)
-		pos.FuelRemainingMT = floatPtr(fuel)
-		pos.Source = defaultString(source, "manual")
-		pos.Remarks = stringPtr(remarks)
 		positions = append(positions, pos)
 	}
 	return positions, rows.Err()
 }
 
+// ShipPositionFilter narrows Search. Nil fields match everything.
+type ShipPositionFilter struct {
+	Source *string
+}
+
+// Search returns one page of the voyage's positions matching filter, newest
+// first. Ties on recorded_at are broken by id so pages do not overlap.
+func (repo *ShipPositionRepository) Search(ctx context.Context, voyageID uuid.UUID, filter ShipPositionFilter, page Page) ([]ShipPosition, error) {
+	if err := checkOffset(page.Offset); err != nil {
+		return nil, err
+	}
+
+	var where conditions
+	where.add("voyage_id = $%d", voyageID)
+	if filter.Source != nil {
+		where.add("source = $%d", *filter.Source)
+	}
+	where.page("recorded_at DESC, id DESC", page.limit(), page.Offset)
+
+	query := `
+		SELECT id, voyage_id, recorded_at, latitude, longitude, speed_knots, heading,
+		       distance_logged_nm, fuel_remaining_mt, source, remarks, created_at, updated_at
+		FROM shipman.ship_positions
+		WHERE TRUE
+	` + where.sql
+
+	rows, err := Pool.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []ShipPosition
+	for rows.Next() {
+		pos, err := scanShipPosition(rows)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, pos)
+	}
+	return positions, rows.Err()
+}
+
+// StreamByVoyage calls fn for each of the voyage's positions, oldest first,
+// straight from the database cursor so memory use does not grow with the
+// number of rows. It stops at the first error from fn or when ctx is
+// cancelled.
+func (repo *ShipPositionRepository) StreamByVoyage(ctx context.Context, voyageID uuid.UUID, fn func(ShipPosition) error) error {
+	const query = `
+		SELECT id, voyage_id, recorded_at, latitude, longitude, speed_knots, heading,
+		       distance_logged_nm, fuel_remaining_mt, source, remarks, created_at, updated_at
+		FROM shipman.ship_positions
+		WHERE voyage_id = $1
+		ORDER BY recorded_at ASC, id ASC
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, voyageID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pos, err := scanShipPosition(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(pos); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Downsample returns at most maxPoints of the voyage's positions, oldest
+// first, spaced evenly in time across the track. The first and last
+// positions are always kept. maxPoints below 2 is treated as 2.
+func (repo *ShipPositionRepository) Downsample(ctx context.Context, voyageID uuid.UUID, maxPoints int) ([]ShipPosition, error) {
+	if maxPoints < 2 {
+		maxPoints = 2
+	}
+
+	var all []ShipPosition
+	if err := repo.StreamByVoyage(ctx, voyageID, func(pos ShipPosition) error {
+		all = append(all, pos)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if len(all) <= maxPoints {
+		return all, nil
+	}
+
+	first, last := all[0].RecordedAt, all[len(all)-1].RecordedAt
+	span := last.Sub(first)
+	out := make([]ShipPosition, 0, maxPoints)
+	out = append(out, all[0])
+
+	// Walk the track once, taking the first position at or after each of
+	// the maxPoints-2 interior time targets.
+	next := 1
+	for i := 1; i < maxPoints-1; i++ {
+		target := first.Add(span * time.Duration(i) / time.Duration(maxPoints-1))
+		for next < len(all)-1 && all[next].RecordedAt.Before(target) {
+			next++
+		}
+		if next >= len(all)-1 {
+			break
+		}
+		out = append(out, all[next])
+		next++
+	}
+
+	return append(out, all[len(all)-1]), nil
+}
+
+func scanShipPosition(row rowScanner) (ShipPosition, error) {
+	var (
+		pos      ShipPosition
+		speed    sql.NullFloat64
+		heading  sql.NullFloat64
+		distance sql.NullFloat64
+		fuel     sql.NullFloat64
+		source   sql.NullString
+		remarks  sql.NullString
+	)
+	if err := row.Scan(
+		&pos.ID,
+		&pos.VoyageID,
+		&pos.RecordedAt,
+		&pos.Latitude,
+		&pos.Longitude,
+		&speed,
+		&heading,
+		&distance,
+		&fuel,
+		&source,
+		&remarks,
+		&pos.CreatedAt,
+		&pos.UpdatedAt,
+	); err != nil {
+		return ShipPosition{}, err
+	}
+	pos.SpeedKnots = floatPtr(speed)
+	pos.Heading = floatPtr(heading)
+	pos.DistanceLoggedNM = floatPtr(distance)
+	pos.FuelRemainingMT = floatPtr(fuel)
+	pos.Source = defaultString(source, "manual")
+	pos.Remarks = stringPtr(remarks)
+	return pos, nil
+}
+
 // Update modifies a position row.
 func (repo *ShipPositionRepository) Update(ctx context.Context, pos *ShipPosition) error {
+	if err := sanitizeNotes(notesField{"remarks", pos.Remarks}); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.ship_positions
 		SET
@@ -217,7 +402,7 @@ func (repo *ShipPositionRepository) Update(ctx context.Context, pos *ShipPositio
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		pos.ID,
@@ -231,6 +416,7 @@ func (repo *ShipPositionRepository) Update(ctx context.Context, pos *ShipPositio
 		pos.Source,
 		nullableString(pos.Remarks),
 	).Scan(&pos.UpdatedAt)
+	return notFound(err)
 }
 
 // Delete removes a position entry.
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var shipPositionColumns = []string{
+	"id", "voyage_id", "recorded_at", "latitude", "longitude", "speed_knots",
+	"heading", "distance_logged_nm", "fuel_remaining_mt", "source", "remarks",
+	"raw_payload", "created_at", "updated_at",
+}
+
+// fakePositionTable stores inserted ship positions and serves them back to
+// Retrieve, the way the database would.
+type fakePositionTable struct {
+	mu   sync.Mutex
+	rows map[string][]any
+}
+
+func (f *fakePositionTable) install(fake *dbtest.Fake) {
+	f.rows = map[string][]any{}
+	fake.On("INSERT INTO shipman.ship_positions", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id, now := uuid.New(), time.Now()
+		source := call.Arg(9)
+		if source == nil {
+			source = "manual"
+		}
+		row := []any{id}
+		row = append(row, call.Args[:8]...)
+		row = append(row, source, call.Arg(10), call.Arg(11), now, now)
+		f.rows[id.String()] = row
+		return dbtest.Rows([]string{"id", "source", "created_at", "updated_at"}, []any{id, source, now, now})
+	})
+	fake.On("FROM shipman.ship_positions WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		row, ok := f.rows[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows(shipPositionColumns)
+		}
+		return dbtest.Rows(shipPositionColumns, row)
+	})
+}
+
+func TestShipPositionRawPayloadRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		raw    []byte
+	}{
+		{"ais message", "ais", []byte(`{"MessageID":1,"UserID":366123456,"Sog":12.3,"Cog":87.5}`)},
+		{"manual position", "manual", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			(&fakePositionTable{}).install(fake)
+			repo := NewShipPositionRepository()
+
+			pos := &ShipPosition{
+				VoyageID:   uuid.New(),
+				RecordedAt: time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+				Latitude:   51.9,
+				Longitude:  4.1,
+				Source:     tt.source,
+				RawPayload: tt.raw,
+			}
+			if err := repo.Create(context.Background(), pos); err != nil {
+				t.Fatal(err)
+			}
+			if got := fake.Calls("INSERT INTO shipman.ship_positions")[0].Arg(11); (got == nil) != (tt.raw == nil) {
+				t.Errorf("raw_payload bound as %v, want NULL only without a payload", got)
+			}
+
+			got, err := repo.Retrieve(context.Background(), pos.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.RawPayload, tt.raw) || (got.RawPayload == nil) != (tt.raw == nil) {
+				t.Errorf("raw payload = %q, want %q", got.RawPayload, tt.raw)
+			}
+			if got.Source != tt.source || got.Latitude != 51.9 {
+				t.Errorf("position = %+v", got)
+			}
+		})
+	}
+}
+
+// streamColumns are the columns StreamByVoyage scans, in order.
+var streamColumns = []string{
+	"id", "voyage_id", "recorded_at", "latitude", "longitude", "speed_knots",
+	"heading", "distance_logged_nm", "fuel_remaining_mt", "source", "remarks",
+	"created_at", "updated_at",
+}
+
+func positionRows(voyageID uuid.UUID, n int) [][]any {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([][]any, n)
+	for i := range rows {
+		at := start.Add(time.Duration(i) * time.Hour)
+		rows[i] = []any{uuid.New(), voyageID, at, 51.9, 4.1, nil, nil, float64(i), nil, nil, nil, at, at}
+	}
+	return rows
+}
+
+func TestShipPositionStreamByVoyage(t *testing.T) {
+	voyageID := uuid.New()
+	rows := positionRows(voyageID, 5)
+
+	t.Run("yields every row in order", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1", dbtest.Rows(streamColumns, rows...))
+
+		var got []ShipPosition
+		err := NewShipPositionRepository().StreamByVoyage(context.Background(), voyageID, func(pos ShipPosition) error {
+			got = append(got, pos)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(rows) {
+			t.Fatalf("streamed %d positions, want %d", len(got), len(rows))
+		}
+		for i, pos := range got {
+			if pos.ID != rows[i][0] || pos.Source != "manual" || *pos.DistanceLoggedNM != float64(i) {
+				t.Errorf("position %d = %+v", i, pos)
+			}
+		}
+		call := fake.Calls("FROM shipman.ship_positions")[0]
+		if call.Arg(1) != voyageID.String() || !strings.Contains(call.Query, "ORDER BY recorded_at ASC, id ASC") {
+			t.Errorf("query = %s %v, want the voyage's positions oldest first", call.Query, call.Args)
+		}
+	})
+
+	t.Run("stops at the first callback error", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1", dbtest.Rows(streamColumns, rows...))
+		sentinel := errors.New("client gone")
+
+		calls := 0
+		err := NewShipPositionRepository().StreamByVoyage(context.Background(), voyageID, func(ShipPosition) error {
+			calls++
+			if calls == 2 {
+				return sentinel
+			}
+			return nil
+		})
+		if !errors.Is(err, sentinel) {
+			t.Errorf("err = %v, want the callback's error", err)
+		}
+		if calls != 2 {
+			t.Errorf("callback ran %d times, want 2", calls)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1", dbtest.Rows(streamColumns, rows...))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := NewShipPositionRepository().StreamByVoyage(ctx, voyageID, func(ShipPosition) error {
+			t.Error("callback ran after cancellation")
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestShipPositionDownsample(t *testing.T) {
+	voyageID := uuid.New()
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	// A day of minute fixes in port followed by twenty hourly fixes at sea.
+	bursty := make([][]any, 0, 1460)
+	for i := 0; i < 1440; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		bursty = append(bursty, []any{uuid.New(), voyageID, at, 51.9, 4.1, nil, nil, nil, nil, nil, nil, at, at})
+	}
+	for i := 1; i <= 20; i++ {
+		at := start.Add(24*time.Hour + time.Duration(i)*time.Hour)
+		bursty = append(bursty, []any{uuid.New(), voyageID, at, 51.9, 4.1, nil, nil, nil, nil, nil, nil, at, at})
+	}
+
+	tests := []struct {
+		name      string
+		rows      [][]any
+		maxPoints int
+		wantLen   int
+	}{
+		{"even track", positionRows(voyageID, 1000), 50, 50},
+		{"bursty track", bursty, 40, 0},
+		{"fewer than max", positionRows(voyageID, 10), 50, 10},
+		{"max below two", positionRows(voyageID, 10), 1, 2},
+		{"single position", positionRows(voyageID, 1), 5, 1},
+		{"no positions", nil, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1", dbtest.Rows(streamColumns, tt.rows...))
+
+			got, err := NewShipPositionRepository().Downsample(context.Background(), voyageID, tt.maxPoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if limit := max(tt.maxPoints, 2); len(got) > limit {
+				t.Errorf("returned %d positions, want at most %d", len(got), limit)
+			}
+			if tt.wantLen > 0 && len(got) != tt.wantLen {
+				t.Errorf("returned %d positions, want %d", len(got), tt.wantLen)
+			}
+			if len(tt.rows) == 0 {
+				if len(got) != 0 {
+					t.Errorf("returned %d positions for an empty track", len(got))
+				}
+				return
+			}
+			if got[0].ID != tt.rows[0][0] || got[len(got)-1].ID != tt.rows[len(tt.rows)-1][0] {
+				t.Error("first or last position was dropped")
+			}
+			for i := 1; i < len(got); i++ {
+				if !got[i].RecordedAt.After(got[i-1].RecordedAt) {
+					t.Fatalf("position %d at %s is not after %s", i, got[i].RecordedAt, got[i-1].RecordedAt)
+				}
+			}
+		})
+	}
+
+	t.Run("sea leg keeps its share of points", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1", dbtest.Rows(streamColumns, bursty...))
+		got, err := NewShipPositionRepository().Downsample(context.Background(), voyageID, 40)
+		if err != nil {
+			t.Fatal(err)
+		}
+		atSea := 0
+		for _, pos := range got {
+			if pos.RecordedAt.After(start.Add(24 * time.Hour)) {
+				atSea++
+			}
+		}
+		// The sea leg is 20 of 44 hours; time spacing should give it every
+		// one of its 20 fixes rather than the 1-in-73 a count stride would.
+		if atSea < 15 {
+			t.Errorf("sea leg kept %d of 20 fixes, want most of them", atSea)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAfterCommit(t *testing.T) {
+	newFakeDB(t)
+
+	ran := 0
+	AfterCommit(context.Background(), func() { ran++ })
+	if ran != 1 {
+		t.Errorf("without a transaction ran %d times, want 1 right away", ran)
+	}
+
+	ran = 0
+	err := WithTx(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func() { ran++ })
+		// Nested WithTx joins the outer transaction, so it waits for it too.
+		return WithTx(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func() { ran++ })
+			if ran != 0 {
+				t.Errorf("ran %d times before commit", ran)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran != 2 {
+		t.Errorf("after commit ran %d times, want 2", ran)
+	}
+
+	ran = 0
+	errRollback := errors.New("roll back")
+	err = WithTx(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func() { ran++ })
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want %v", err, errRollback)
+	}
+	if ran != 0 {
+		t.Errorf("after rollback ran %d times, want 0", ran)
+	}
+}
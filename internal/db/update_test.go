@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUpdateMissingRowIsNotFound(t *testing.T) {
+	ctx := context.Background()
+	missing := uuid.New()
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		update func() error
+	}{
+		{"charter", func() error {
+			return NewCharterDetailRepository().Update(ctx, &CharterDetail{ID: missing, Title: "Grain", Status: "draft"})
+		}},
+		{"voyage", func() error {
+			return NewVoyageRepository().Update(ctx, &Voyage{ID: missing, Status: "planned", DemurrageCurrency: "USD"})
+		}},
+		{"voyage status", func() error {
+			return NewVoyageRepository().UpdateStatus(ctx, missing, "delayed")
+		}},
+		{"payment checkout session", func() error {
+			return NewPaymentRepository().UpdateCoinsubSession(ctx, missing, "cs_1", "https://pay.example/cs_1")
+		}},
+		{"payment transfer", func() error {
+			return NewPaymentRepository().UpdateTransfer(ctx, missing, "0xabc")
+		}},
+		{"dispute", func() error {
+			return NewDisputeRepository().Update(ctx, &Dispute{ID: missing, Subject: "Claim", Status: "open"})
+		}},
+		{"demurrage record", func() error {
+			return NewDemurrageRecordRepository().Update(ctx, &DemurrageRecord{ID: missing, Currency: "USD", Status: "draft"})
+		}},
+		{"vessel", func() error {
+			return NewVesselRepository().Update(ctx, &Vessel{ID: missing, Name: "Ever Given"})
+		}},
+		{"laytime entry", func() error {
+			return NewLaytimeEntryRepository().Update(ctx, &LaytimeEntry{ID: missing, PortName: "Rotterdam", Activity: "loading", StartedAt: at})
+		}},
+		{"user", func() error {
+			hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+			if err != nil {
+				return err
+			}
+			return NewUserRepository().Update(ctx, &User{ID: missing, Email: "a@example.com", PasswordHash: string(hash), FullName: "A", Role: "broker"})
+		}},
+		{"document status", func() error {
+			return NewDocumentRepository().UpdateStatus(ctx, missing, "processed")
+		}},
+		{"deal status", func() error {
+			return NewDealRepository().UpdateStatus(ctx, missing, "completed")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			// The UPDATE matches nothing: RETURNING yields no row and Exec
+			// reports no rows affected.
+			fake.Return("UPDATE shipman.", dbtest.Rows([]string{"updated_at"}))
+
+			err := tt.update()
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("err = %v, want ErrNotFound", err)
+			}
+			if calls := fake.Calls("UPDATE shipman."); len(calls) == 0 || calls[0].Arg(1) != missing.String() {
+				t.Errorf("update calls = %+v, want one for %s", calls, missing)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepsOtherErrors(t *testing.T) {
+	fake := newFakeDB(t)
+	boom := errors.New("connection reset")
+	fake.Return("UPDATE shipman.", dbtest.Fail(boom))
+
+	err := NewVoyageRepository().UpdateStatus(context.Background(), uuid.New(), "delayed")
+	if !errors.Is(err, boom) || errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want the driver error", err)
+	}
+	err = NewCharterDetailRepository().Update(context.Background(), &CharterDetail{ID: uuid.New(), Title: "Grain"})
+	if !errors.Is(err, boom) || errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want the driver error", err)
+	}
+}
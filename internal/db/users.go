@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"time"
 
+	"shipman/internal/auth"
+
 	"github.com/google/uuid"
 )
 
 // User represents a row in shipman.users.
 type User struct {
 	ID                uuid.UUID `json:"id"`
+	OrgID             uuid.UUID `json:"org_id"`
 	Email             string    `json:"email"`
 	PasswordHash      string    `json:"-"`
 	FullName          string    `json:"full_name"`
@@ -21,6 +24,33 @@ type User struct {
 	UpdatedAt         time.Time `json:"updated_at"`
 }
 
+// SetPassword hashes plaintext with bcrypt into PasswordHash. An empty
+// password is rejected with ErrInvalidPassword.
+func (u *User) SetPassword(plaintext string) error {
+	if plaintext == "" {
+		return ErrInvalidPassword
+	}
+	hash, err := auth.HashPassword(plaintext)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+// CheckPassword reports whether plaintext matches PasswordHash.
+func (u User) CheckPassword(plaintext string) bool {
+	return auth.CheckPassword(plaintext, u.PasswordHash)
+}
+
+// checkPasswordHash keeps blank and plaintext values out of password_hash.
+func checkPasswordHash(u *User) error {
+	if !auth.IsHash(u.PasswordHash) {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
 // UserService exposes CRUD behaviour for users.
 type UserService interface {
 	Create(ctx context.Context, u *User) error
@@ -41,20 +71,25 @@ func NewUserRepository() *UserRepository {
 
 // Create inserts a new user and populates ID/CreatedAt/UpdatedAt on the struct.
 func (repo *UserRepository) Create(ctx context.Context, u *User) error {
+	clearServerFields(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+	if err := checkPasswordHash(u); err != nil {
+		return err
+	}
 	const query = `
-		INSERT INTO shipman.users (email, password_hash, full_name, role)
-		VALUES ($1, $2, $3, COALESCE($4, 'user'))
+		INSERT INTO shipman.users (email, password_hash, full_name, role, org_id)
+		VALUES ($1, $2, $3, COALESCE($4, 'user'), $5)
 		RETURNING id, created_at, updated_at
 	`
 
-	return Pool.QueryRowContext(ctx, query, u.Email, u.PasswordHash, u.FullName, u.Role).
+	u.OrgID = orgForCreate(ctx, u.OrgID)
+	return Pool.QueryRowContext(ctx, query, u.Email, u.PasswordHash, u.FullName, u.Role, u.OrgID).
 		Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
 }
 
 // Retrieve fetches a user by ID.
 func (repo *UserRepository) Retrieve(ctx context.Context, id uuid.UUID) (User, error) {
 	const query = `
-		SELECT id, email, password_hash, full_name, role,
+		SELECT id, org_id, email, password_hash, full_name, role,
 		       coinsub_merchant_id, wallet_address,
 		       created_at, updated_at
 		FROM shipman.users
@@ -63,7 +98,7 @@ func (repo *UserRepository) Retrieve(ctx context.Context, id uuid.UUID) (User, e
 	var u User
 	var coinsubID, wallet sql.NullString
 	err := Pool.QueryRowContext(ctx, query, id).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.FullName, &u.Role,
+		&u.ID, &u.OrgID, &u.Email, &u.PasswordHash, &u.FullName, &u.Role,
 		&coinsubID, &wallet,
 		&u.CreatedAt, &u.UpdatedAt,
 	)
@@ -78,7 +113,7 @@ func (repo *UserRepository) Retrieve(ctx context.Context, id uuid.UUID) (User, e
 // RetrieveByEmail fetches a user by email address.
 func (repo *UserRepository) RetrieveByEmail(ctx context.Context, email string) (User, error) {
 	const query = `
-		SELECT id, email, password_hash, full_name, role,
+		SELECT id, org_id, email, password_hash, full_name, role,
 		       coinsub_merchant_id, wallet_address,
 		       created_at, updated_at
 		FROM shipman.users
@@ -87,7 +122,7 @@ func (repo *UserRepository) RetrieveByEmail(ctx context.Context, email string) (
 	var u User
 	var coinsubID, wallet sql.NullString
 	err := Pool.QueryRowContext(ctx, query, email).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.FullName, &u.Role,
+		&u.ID, &u.OrgID, &u.Email, &u.PasswordHash, &u.FullName, &u.Role,
 		&coinsubID, &wallet,
 		&u.CreatedAt, &u.UpdatedAt,
 	)
@@ -101,16 +136,21 @@ func (repo *UserRepository) RetrieveByEmail(ctx context.Context, email string) (
 
 // List returns users ordered by newest first.
 func (repo *UserRepository) List(ctx context.Context, limit, offset int) ([]User, error) {
+	if err := checkOffset(offset); err != nil {
+		return nil, err
+	}
+
 	const query = `
-		SELECT id, email, password_hash, full_name, role,
+		SELECT id, org_id, email, password_hash, full_name, role,
 		       coinsub_merchant_id, wallet_address,
 		       created_at, updated_at
 		FROM shipman.users
+		WHERE ($3::uuid IS NULL OR org_id = $3)
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := Pool.QueryContext(ctx, query, limit, offset)
+	rows, err := Pool.QueryContext(ctx, query, limit, offset, orgFilter(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +160,7 @@ func (repo *UserRepository) List(ctx context.Context, limit, offset int) ([]User
 	for rows.Next() {
 		var u User
 		var coinsubID, wallet sql.NullString
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.FullName, &u.Role, &coinsubID, &wallet, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.OrgID, &u.Email, &u.PasswordHash, &u.FullName, &u.Role, &coinsubID, &wallet, &u.CreatedAt, &u.UpdatedAt); err != nil {
 			return nil, err
 		}
 		u.CoinsubMerchantID = stringPtr(coinsubID)
@@ -132,6 +172,9 @@ func (repo *UserRepository) List(ctx context.Context, limit, offset int) ([]User
 
 // Update modifies the stored fields for a user.
 func (repo *UserRepository) Update(ctx context.Context, u *User) error {
+	if err := checkPasswordHash(u); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.users
 		SET email = $2,
@@ -143,8 +186,9 @@ func (repo *UserRepository) Update(ctx context.Context, u *User) error {
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(ctx, query, u.ID, u.Email, u.PasswordHash, u.FullName, u.Role).
+	err := Pool.QueryRowContext(ctx, query, u.ID, u.Email, u.PasswordHash, u.FullName, u.Role).
 		Scan(&u.UpdatedAt)
+	return notFound(err)
 }
 
 // SetCoinsubMerchantID stores the Coinsub submerchant ID for a user.
@@ -154,6 +198,18 @@ func (repo *UserRepository) SetCoinsubMerchantID(ctx context.Context, id uuid.UU
 	return err
 }
 
+// UpgradePasswordHash replaces the user's password hash with newHash, but
+// only while the stored hash is still oldHash, so a concurrent password change
+// is never overwritten. It returns ErrNotFound when nothing was replaced.
+func (repo *UserRepository) UpgradePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	const query = `
+		UPDATE shipman.users
+		SET password_hash = $3, updated_at = NOW()
+		WHERE id = $1 AND password_hash = $2
+	`
+	return requireRow(Pool.ExecContext(ctx, query, id, oldHash, newHash))
+}
+
 // SetWalletAddress stores the user's crypto wallet address.
 func (repo *UserRepository) SetWalletAddress(ctx context.Context, id uuid.UUID, addr string) error {
 	const query = `UPDATE shipman.users SET wallet_address = $2 WHERE id = $1`
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// VesselStatus is where a vessel is now: its in-progress voyage and that
+// voyage's latest position. Both are nil when the vessel is idle, and
+// Position is nil for a voyage that has not reported yet.
+type VesselStatus struct {
+	VesselName string        `json:"vessel_name"`
+	Voyage     *Voyage       `json:"voyage"`
+	Position   *ShipPosition `json:"position"`
+}
+
+// currentVoyageForVessel picks the vessel's in-progress voyage (departed, not
+// arrived, not archived), latest departure first. Names are compared after
+// canonicalization, case-insensitively. $1 is the name, $2 the org filter.
+const currentVoyageForVessel = `
+		SELECT id FROM shipman.voyages
+		WHERE lower(vessel_name) = lower($1)
+		  AND actual_departure_at IS NOT NULL
+		  AND actual_arrival_at IS NULL
+		  AND archived_at IS NULL
+		  AND ($2::uuid IS NULL OR org_id = $2)
+		ORDER BY actual_departure_at DESC, id DESC
+		LIMIT 1
+`
+
+// VesselCurrentStatus returns the vessel's in-progress voyage and its latest
+// position. The two lookups run concurrently against the same voyage choice.
+func (repo *VesselRepository) VesselCurrentStatus(ctx context.Context, vesselName string) (VesselStatus, error) {
+	name := collapseSpace(vesselName)
+	status := VesselStatus{VesselName: name}
+	org := orgFilter(ctx)
+
+	var (
+		wg                sync.WaitGroup
+		voyageErr, posErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var id uuid.UUID
+		if err := Pool.QueryRowContext(ctx, currentVoyageForVessel, name, org).Scan(&id); err != nil {
+			voyageErr = err
+			return
+		}
+		v, err := NewVoyageRepository().Retrieve(ctx, id)
+		if err != nil {
+			voyageErr = err
+			return
+		}
+		status.Voyage = &v
+	}()
+	go func() {
+		defer wg.Done()
+		query := `
+		SELECT id, voyage_id, recorded_at, latitude, longitude, speed_knots, heading,
+		       distance_logged_nm, fuel_remaining_mt, source, remarks, created_at, updated_at
+		FROM shipman.ship_positions
+		WHERE voyage_id = (` + currentVoyageForVessel + `)
+		ORDER BY recorded_at DESC, id DESC
+		LIMIT 1
+	`
+		pos, err := scanShipPosition(Pool.QueryRowContext(ctx, query, name, org))
+		if err != nil {
+			posErr = err
+			return
+		}
+		status.Position = &pos
+	}()
+	wg.Wait()
+
+	for _, err := range []error{voyageErr, posErr} {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return VesselStatus{}, err
+		}
+	}
+	// A voyage that arrived between the two queries leaves a stray position.
+	if status.Voyage == nil || (status.Position != nil && status.Position.VoyageID != status.Voyage.ID) {
+		status.Position = nil
+	}
+	return status, nil
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// latestPositionColumns are the ship_positions columns VesselCurrentStatus
+// reads; it leaves out raw_payload.
+var latestPositionColumns = []string{
+	"id", "voyage_id", "recorded_at", "latitude", "longitude", "speed_knots",
+	"heading", "distance_logged_nm", "fuel_remaining_mt", "source", "remarks",
+	"created_at", "updated_at",
+}
+
+// fakeFleet holds voyages and their positions for VesselCurrentStatus. When
+// rendezvous is set, the voyage and position lookups each wait for the other
+// to start, so running them one after the other times out.
+type fakeFleet struct {
+	voyages    []map[string]any
+	positions  []map[string]any
+	rendezvous *sync.WaitGroup
+}
+
+// current answers currentVoyageForVessel: the in-progress voyage of the named
+// vessel in the caller's org, latest departure first.
+func (f *fakeFleet) current(call dbtest.Call) map[string]any {
+	org := orgArg(call)
+	var best map[string]any
+	for _, v := range f.voyages {
+		name, _ := v["vessel_name"].(string)
+		if !strings.EqualFold(name, call.Arg(1).(string)) || v["actual_departure_at"] == nil ||
+			v["actual_arrival_at"] != nil || v["archived_at"] != nil || (org != "" && v["org_id"] != org) {
+			continue
+		}
+		if best == nil || v["actual_departure_at"].(time.Time).After(best["actual_departure_at"].(time.Time)) {
+			best = v
+		}
+	}
+	return best
+}
+
+func (f *fakeFleet) meet(t *testing.T) {
+	if f.rendezvous == nil {
+		return
+	}
+	f.rendezvous.Done()
+	done := make(chan struct{})
+	go func() { f.rendezvous.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Error("voyage and position lookups did not run concurrently")
+	}
+}
+
+func (f *fakeFleet) install(t *testing.T, fake *dbtest.Fake) {
+	fake.On("SELECT id FROM shipman.voyages WHERE lower(vessel_name) = lower($1)", func(call dbtest.Call) dbtest.Result {
+		f.meet(t)
+		if v := f.current(call); v != nil {
+			return dbtest.Rows([]string{"id"}, []any{v["id"]})
+		}
+		return dbtest.Rows([]string{"id"})
+	})
+	fake.On("FROM shipman.ship_positions WHERE voyage_id = (", func(call dbtest.Call) dbtest.Result {
+		f.meet(t)
+		v := f.current(call)
+		if v == nil {
+			return dbtest.Rows(latestPositionColumns)
+		}
+		var latest map[string]any
+		for _, p := range f.positions {
+			if p["voyage_id"] == v["id"] && (latest == nil || p["recorded_at"].(time.Time).After(latest["recorded_at"].(time.Time))) {
+				latest = p
+			}
+		}
+		if latest == nil {
+			return dbtest.Rows(latestPositionColumns)
+		}
+		return dbtest.Rows(latestPositionColumns, dbtest.Row(latestPositionColumns, latest))
+	})
+	fake.On("archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		for _, v := range f.voyages {
+			if v["id"] == call.Arg(1) {
+				return dbtest.Rows(voyageColumns, dbtest.Row(voyageColumns, v))
+			}
+		}
+		return dbtest.Rows(voyageColumns)
+	})
+}
+
+func TestVesselCurrentStatus(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 6, d, 0, 0, 0, 0, time.UTC) }
+	voyage := func(number, vessel string, departed, arrived, archived any, org uuid.UUID) map[string]any {
+		return map[string]any{
+			"id": uuid.NewString(), "org_id": org.String(), "voyage_number": number, "vessel_name": vessel,
+			"status": "in_progress", "demurrage_currency": "USD", "actual_departure_at": departed,
+			"actual_arrival_at": arrived, "archived_at": archived, "created_at": day(1), "updated_at": day(1),
+		}
+	}
+	position := func(voyageID any, recorded time.Time, lat float64) map[string]any {
+		return map[string]any{
+			"id": uuid.NewString(), "voyage_id": voyageID, "recorded_at": recorded, "latitude": lat, "longitude": 4.1,
+			"created_at": recorded, "updated_at": recorded,
+		}
+	}
+	sailing := voyage("V2", "Nordic Star", day(5), nil, nil, DefaultOrgID)
+	earlier := voyage("V1", "Nordic Star", day(1), day(4), nil, DefaultOrgID)
+	silent := voyage("V3", "Pacific Dawn", day(6), nil, nil, DefaultOrgID)
+	fleet := &fakeFleet{
+		voyages: []map[string]any{
+			earlier, sailing, silent,
+			voyage("V4", "Harbour Queen", day(2), day(3), nil, DefaultOrgID),
+			voyage("V5", "Harbour Queen", day(4), nil, day(5), DefaultOrgID),
+			voyage("V6", "Harbour Queen", day(6), nil, nil, uuid.New()),
+		},
+		positions: []map[string]any{
+			position(earlier["id"], day(3), 10),
+			position(sailing["id"], day(6), 20),
+			position(sailing["id"], day(8), 21),
+			position(sailing["id"], day(7), 22),
+		},
+	}
+	ctx := WithOrg(context.Background(), DefaultOrgID)
+
+	tests := []struct {
+		name       string
+		vessel     string
+		wantVoyage string
+		wantLat    float64 // 0 for no position
+	}{
+		{"at sea", "Nordic Star", "V2", 21},
+		{"name matched loosely", "  nordic   STAR ", "V2", 21},
+		{"departed, not reported yet", "Pacific Dawn", "V3", 0},
+		{"idle: arrived, archived or another org's", "Harbour Queen", "", 0},
+		{"unknown vessel", "Flying Dutchman", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fleet.install(t, fake)
+
+			got, err := NewVesselRepository().VesselCurrentStatus(ctx, tt.vessel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VesselName != strings.Join(strings.Fields(tt.vessel), " ") {
+				t.Errorf("vessel name = %q, want %q canonicalized", got.VesselName, tt.vessel)
+			}
+			switch {
+			case tt.wantVoyage == "" && got.Voyage != nil:
+				t.Errorf("voyage = %v, want nil for an idle vessel", *got.Voyage.VoyageNumber)
+			case tt.wantVoyage != "" && (got.Voyage == nil || *got.Voyage.VoyageNumber != tt.wantVoyage):
+				t.Errorf("voyage = %+v, want %s", got.Voyage, tt.wantVoyage)
+			}
+			switch {
+			case tt.wantLat == 0 && got.Position != nil:
+				t.Errorf("position = %+v, want nil", got.Position)
+			case tt.wantLat != 0 && (got.Position == nil || float64(got.Position.Latitude) != tt.wantLat):
+				t.Errorf("position = %+v, want the latest fix at %v", got.Position, tt.wantLat)
+			}
+		})
+	}
+
+	t.Run("lookups run concurrently", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fleet.rendezvous = new(sync.WaitGroup)
+		fleet.rendezvous.Add(2)
+		t.Cleanup(func() { fleet.rendezvous = nil })
+		fleet.install(t, fake)
+		if _, err := NewVesselRepository().VesselCurrentStatus(ctx, "Nordic Star"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestVesselCurrentStatusDiscardsStrayPosition(t *testing.T) {
+	// The voyage arrived between the two lookups: the position query still
+	// saw it in progress, the voyage query did not.
+	fake := newFakeDB(t)
+	stamp := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	fake.Return("SELECT id FROM shipman.voyages WHERE lower(vessel_name) = lower($1)", dbtest.Rows([]string{"id"}))
+	fake.Return("FROM shipman.ship_positions WHERE voyage_id = (", dbtest.Rows(latestPositionColumns,
+		dbtest.Row(latestPositionColumns, map[string]any{
+			"id": uuid.New(), "voyage_id": uuid.New(), "recorded_at": stamp, "latitude": 1.0, "longitude": 1.0,
+			"created_at": stamp, "updated_at": stamp,
+		})))
+
+	got, err := NewVesselRepository().VesselCurrentStatus(context.Background(), "Nordic Star")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Voyage != nil || got.Position != nil {
+		t.Errorf("status = %+v, want idle", got)
+	}
+}
+
+func TestVesselCurrentStatusError(t *testing.T) {
+	fake := newFakeDB(t)
+	boom := errors.New("connection reset")
+	fake.Return("SELECT id FROM shipman.voyages WHERE lower(vessel_name) = lower($1)", dbtest.Rows([]string{"id"}))
+	fake.Return("FROM shipman.ship_positions WHERE voyage_id = (", dbtest.Fail(boom))
+
+	if _, err := NewVesselRepository().VesselCurrentStatus(context.Background(), "Nordic Star"); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want %v", err, boom)
+	}
+	if n := len(fake.Calls("")); n != 2 {
+		t.Errorf("ran %d statements, want both lookups", n)
+	}
+}
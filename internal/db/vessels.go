@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,24 +11,24 @@ import (
 
 // Vessel mirrors shipman.vessels rows.
 type Vessel struct {
-	ID                uuid.UUID `json:"id"`
-	Name              string    `json:"name"`
-	IMONumber         *string   `json:"imo_number,omitempty"`
-	FlagState         *string   `json:"flag_state,omitempty"`
-	VesselType        *string   `json:"vessel_type,omitempty"`
-	CallSign          *string   `json:"call_sign,omitempty"`
-	DeadweightTonnage *float64  `json:"deadweight_tonnage,omitempty"`
-	GrossTonnage      *float64  `json:"gross_tonnage,omitempty"`
-	NetTonnage        *float64  `json:"net_tonnage,omitempty"`
-	Capacity          []byte    `json:"capacity,omitempty"` // JSON blob
-	BuildYear         *int16    `json:"build_year,omitempty"`
-	ClassSociety      *string   `json:"class_society,omitempty"`
-	Owner             *string   `json:"owner,omitempty"`
-	Manager           *string   `json:"manager,omitempty"`
-	DocumentationURI  *string   `json:"documentation_uri,omitempty"`
-	Notes             *string   `json:"notes,omitempty"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                uuid.UUID       `json:"id"`
+	Name              string          `json:"name"`
+	IMONumber         *string         `json:"imo_number,omitempty"`
+	FlagState         *string         `json:"flag_state,omitempty"`
+	VesselType        *string         `json:"vessel_type,omitempty"`
+	CallSign          *string         `json:"call_sign,omitempty"`
+	DeadweightTonnage *float64        `json:"deadweight_tonnage,omitempty"`
+	GrossTonnage      *float64        `json:"gross_tonnage,omitempty"`
+	NetTonnage        *float64        `json:"net_tonnage,omitempty"`
+	Capacity          json.RawMessage `json:"capacity,omitempty"` // jsonb
+	BuildYear         *int16          `json:"build_year,omitempty"`
+	ClassSociety      *string         `json:"class_society,omitempty"`
+	Owner             *string         `json:"owner,omitempty"`
+	Manager           *string         `json:"manager,omitempty"`
+	DocumentationURI  *string         `json:"documentation_uri,omitempty"`
+	Notes             *string         `json:"notes,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
 // VesselService exposes CRUD behaviour.
@@ -37,6 +38,7 @@ type VesselService interface {
 	List(ctx context.Context, limit, offset int) ([]Vessel, error)
 	Update(ctx context.Context, vessel *Vessel) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	VesselCurrentStatus(ctx context.Context, vesselName string) (VesselStatus, error)
 }
 
 // VesselRepository implements VesselService using Pool.
@@ -49,6 +51,14 @@ func NewVesselRepository() *VesselRepository {
 
 // Create inserts a vessel.
 func (repo *VesselRepository) Create(ctx context.Context, vessel *Vessel) error {
+	clearServerFields(&vessel.ID, &vessel.CreatedAt, &vessel.UpdatedAt)
+	vessel.Normalize()
+	if err := sanitizeNotes(notesField{"notes", vessel.Notes}); err != nil {
+		return err
+	}
+	if err := checkJSON("capacity", vessel.Capacity); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.vessels (
 			name,
@@ -83,7 +93,7 @@ func (repo *VesselRepository) Create(ctx context.Context, vessel *Vessel) error
 		nullableFloat(vessel.DeadweightTonnage),
 		nullableFloat(vessel.GrossTonnage),
 		nullableFloat(vessel.NetTonnage),
-		nullableBytes(vessel.Capacity),
+		nullableJSON(vessel.Capacity),
 		nullableInt16(vessel.BuildYear),
 		nullableString(vessel.ClassSociety),
 		nullableString(vessel.Owner),
@@ -95,6 +105,10 @@ func (repo *VesselRepository) Create(ctx context.Context, vessel *Vessel) error
 
 // Retrieve fetches a vessel by id.
 func (repo *VesselRepository) Retrieve(ctx context.Context, id uuid.UUID) (Vessel, error) {
+	if cached, ok := vesselCache.get(id); ok {
+		return cached, nil
+	}
+
 	const query = `
 		SELECT
 			id,
@@ -168,7 +182,7 @@ func (repo *VesselRepository) Retrieve(ctx context.Context, id uuid.UUID) (Vesse
 	vessel.DeadweightTonnage = floatPtr(dwt)
 	vessel.GrossTonnage = floatPtr(gross)
 	vessel.NetTonnage = floatPtr(net)
-	vessel.Capacity = bytesOrNil(capacity)
+	vessel.Capacity = jsonOrNil(capacity)
 	vessel.BuildYear = int16Ptr(buildYear)
 	vessel.ClassSociety = stringPtr(classSoc)
 	vessel.Owner = stringPtr(owner)
@@ -176,11 +190,16 @@ func (repo *VesselRepository) Retrieve(ctx context.Context, id uuid.UUID) (Vesse
 	vessel.DocumentationURI = stringPtr(docURI)
 	vessel.Notes = stringPtr(notes)
 
+	vesselCache.set(id, vessel)
 	return vessel, nil
 }
 
 // List returns vessels ordered by newest first.
 func (repo *VesselRepository) List(ctx context.Context, limit, offset int) ([]Vessel, error) {
+	if err := checkOffset(offset); err != nil {
+		return nil, err
+	}
+
 	const query = `
 		SELECT id, name, imo_number, created_at, updated_at
 		FROM shipman.vessels
@@ -215,8 +234,111 @@ func (repo *VesselRepository) List(ctx context.Context, limit, offset int) ([]Ve
 	return vessels, rows.Err()
 }
 
+// VesselFilter narrows Search results. Nil fields are ignored. Tonnage
+// bounds are inclusive and exclude vessels with no recorded value for that
+// measure; VesselType and FlagState match case-insensitively.
+type VesselFilter struct {
+	MinDWT     *float64
+	MaxDWT     *float64
+	MinGross   *float64
+	MaxGross   *float64
+	MinNet     *float64
+	MaxNet     *float64
+	VesselType *string
+	FlagState  *string
+}
+
+// Search returns vessels matching filter, newest first.
+func (repo *VesselRepository) Search(ctx context.Context, filter VesselFilter, limit, offset int) ([]Vessel, error) {
+	if err := checkOffset(offset); err != nil {
+		return nil, err
+	}
+
+	var where conditions
+	if filter.MinDWT != nil {
+		where.add("deadweight_tonnage >= $%d", *filter.MinDWT)
+	}
+	if filter.MaxDWT != nil {
+		where.add("deadweight_tonnage <= $%d", *filter.MaxDWT)
+	}
+	if filter.MinGross != nil {
+		where.add("gross_tonnage >= $%d", *filter.MinGross)
+	}
+	if filter.MaxGross != nil {
+		where.add("gross_tonnage <= $%d", *filter.MaxGross)
+	}
+	if filter.MinNet != nil {
+		where.add("net_tonnage >= $%d", *filter.MinNet)
+	}
+	if filter.MaxNet != nil {
+		where.add("net_tonnage <= $%d", *filter.MaxNet)
+	}
+	if filter.VesselType != nil {
+		where.add("LOWER(vessel_type) = LOWER($%d)", *filter.VesselType)
+	}
+	if filter.FlagState != nil {
+		where.add("LOWER(flag_state) = LOWER($%d)", *filter.FlagState)
+	}
+	where.page("created_at DESC", limit, offset)
+
+	query := `
+		SELECT id, name, imo_number, flag_state, vessel_type,
+		       deadweight_tonnage, gross_tonnage, net_tonnage, created_at, updated_at
+		FROM shipman.vessels
+		WHERE TRUE
+	` + where.sql
+
+	rows, err := Pool.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vessels []Vessel
+	for rows.Next() {
+		var (
+			vessel Vessel
+			imo    sql.NullString
+			flag   sql.NullString
+			vType  sql.NullString
+			dwt    sql.NullFloat64
+			gross  sql.NullFloat64
+			net    sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&vessel.ID,
+			&vessel.Name,
+			&imo,
+			&flag,
+			&vType,
+			&dwt,
+			&gross,
+			&net,
+			&vessel.CreatedAt,
+			&vessel.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		vessel.IMONumber = stringPtr(imo)
+		vessel.FlagState = stringPtr(flag)
+		vessel.VesselType = stringPtr(vType)
+		vessel.DeadweightTonnage = floatPtr(dwt)
+		vessel.GrossTonnage = floatPtr(gross)
+		vessel.NetTonnage = floatPtr(net)
+		vessels = append(vessels, vessel)
+	}
+	return vessels, rows.Err()
+}
+
 // Update modifies vessel fields.
 func (repo *VesselRepository) Update(ctx context.Context, vessel *Vessel) error {
+	vessel.Normalize()
+	if err := sanitizeNotes(notesField{"notes", vessel.Notes}); err != nil {
+		return err
+	}
+	if err := checkJSON("capacity", vessel.Capacity); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.vessels
 		SET
@@ -240,7 +362,7 @@ func (repo *VesselRepository) Update(ctx context.Context, vessel *Vessel) error
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		vessel.ID,
@@ -252,7 +374,7 @@ func (repo *VesselRepository) Update(ctx context.Context, vessel *Vessel) error
 		nullableFloat(vessel.DeadweightTonnage),
 		nullableFloat(vessel.GrossTonnage),
 		nullableFloat(vessel.NetTonnage),
-		nullableBytes(vessel.Capacity),
+		nullableJSON(vessel.Capacity),
 		nullableInt16(vessel.BuildYear),
 		nullableString(vessel.ClassSociety),
 		nullableString(vessel.Owner),
@@ -260,11 +382,14 @@ func (repo *VesselRepository) Update(ctx context.Context, vessel *Vessel) error
 		nullableString(vessel.DocumentationURI),
 		nullableString(vessel.Notes),
 	).Scan(&vessel.UpdatedAt)
+	vesselCache.invalidate(vessel.ID)
+	return notFound(err)
 }
 
 // Delete removes a vessel.
 func (repo *VesselRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	const query = `DELETE FROM shipman.vessels WHERE id = $1`
 	_, err := Pool.ExecContext(ctx, query, id)
+	vesselCache.invalidate(id)
 	return err
 }
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var vesselSearchColumns = []string{
+	"id", "name", "imo_number", "flag_state", "vessel_type",
+	"deadweight_tonnage", "gross_tonnage", "net_tonnage", "created_at", "updated_at",
+}
+
+var (
+	rangePredicate = regexp.MustCompile(`(\w+) (>=|<=) \$(\d+)`)
+	textPredicate  = regexp.MustCompile(`LOWER\((\w+)\) = LOWER\(\$(\d+)\)`)
+)
+
+// stubVesselTable answers VesselRepository.Search from rows, applying the
+// range and text predicates the query carries with SQL NULL semantics: a
+// comparison against a NULL column never matches.
+func stubVesselTable(fake *dbtest.Fake, rows []map[string]any) {
+	fake.On("FROM shipman.vessels WHERE TRUE", func(call dbtest.Call) dbtest.Result {
+		arg := func(n string) any {
+			i, _ := strconv.Atoi(n)
+			return call.Arg(i)
+		}
+		var out [][]any
+	rows:
+		for _, row := range rows {
+			for _, m := range rangePredicate.FindAllStringSubmatch(call.Query, -1) {
+				v, ok := row[m[1]].(float64)
+				bound := arg(m[3]).(float64)
+				if !ok || (m[2] == ">=" && v < bound) || (m[2] == "<=" && v > bound) {
+					continue rows
+				}
+			}
+			for _, m := range textPredicate.FindAllStringSubmatch(call.Query, -1) {
+				v, ok := row[m[1]].(string)
+				if !ok || !strings.EqualFold(v, arg(m[2]).(string)) {
+					continue rows
+				}
+			}
+			out = append(out, dbtest.Row(vesselSearchColumns, row))
+		}
+		return dbtest.Rows(vesselSearchColumns, out...)
+	})
+}
+
+func TestVesselSearchFilters(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	s := func(v string) *string { return &v }
+	vessel := func(name string, dwt, gross, net any, vType, flag any) map[string]any {
+		return map[string]any{
+			"id": uuid.New(), "name": name, "deadweight_tonnage": dwt, "gross_tonnage": gross,
+			"net_tonnage": net, "vessel_type": vType, "flag_state": flag,
+			"created_at": time.Now(), "updated_at": time.Now(),
+		}
+	}
+	table := []map[string]any{
+		vessel("Small", 20000.0, 12000.0, 7000.0, "Bulk Carrier", "PA"),
+		vessel("Medium", 55000.0, 31000.0, 18000.0, "bulk carrier", "LR"),
+		vessel("Large", 180000.0, 90000.0, 55000.0, "Tanker", "MH"),
+		vessel("Unmeasured", nil, nil, nil, nil, nil),
+	}
+
+	tests := []struct {
+		name   string
+		filter VesselFilter
+		want   []string
+	}{
+		{"no filter keeps NULLs", VesselFilter{}, []string{"Small", "Medium", "Large", "Unmeasured"}},
+		{"min dwt", VesselFilter{MinDWT: f(55000)}, []string{"Medium", "Large"}},
+		{"max dwt", VesselFilter{MaxDWT: f(55000)}, []string{"Small", "Medium"}},
+		{"dwt band", VesselFilter{MinDWT: f(30000), MaxDWT: f(100000)}, []string{"Medium"}},
+		{"min gross", VesselFilter{MinGross: f(31000)}, []string{"Medium", "Large"}},
+		{"max gross", VesselFilter{MaxGross: f(12000)}, []string{"Small"}},
+		{"min net", VesselFilter{MinNet: f(50000)}, []string{"Large"}},
+		{"max net", VesselFilter{MaxNet: f(18000)}, []string{"Small", "Medium"}},
+		{"zero bound excludes NULL", VesselFilter{MinDWT: f(0)}, []string{"Small", "Medium", "Large"}},
+		{"vessel type ignores case", VesselFilter{VesselType: s("BULK CARRIER")}, []string{"Small", "Medium"}},
+		{"flag state", VesselFilter{FlagState: s("mh")}, []string{"Large"}},
+		{"combined", VesselFilter{MinDWT: f(10000), VesselType: s("bulk carrier"), FlagState: s("PA")}, []string{"Small"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVesselTable(fake, table)
+
+			vessels, err := NewVesselRepository().Search(context.Background(), tt.filter, 20, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range vessels {
+				got = append(got, v.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("vessels = %v, want %v", got, tt.want)
+			}
+			if q := fake.Calls("")[0].Query; !strings.Contains(q, "ORDER BY created_at DESC") {
+				t.Errorf("query is not newest first: %s", q)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// fakeArchiveTable holds voyages keyed by id and answers the archive
+// statements and the charter and user lists against them.
+type fakeArchiveTable struct {
+	mu       sync.Mutex
+	order    []uuid.UUID
+	voyages  map[uuid.UUID]map[string]any
+	charters map[string]string // charter id -> status
+}
+
+func newFakeArchiveTable(fake *dbtest.Fake) *fakeArchiveTable {
+	f := &fakeArchiveTable{voyages: map[uuid.UUID]map[string]any{}, charters: map[string]string{}}
+	fake.On("archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		v, ok := f.voyages[uuid.MustParse(call.Arg(1).(string))]
+		if !ok {
+			return dbtest.Rows(voyageColumns)
+		}
+		return dbtest.Rows(voyageColumns, dbtest.Row(voyageColumns, v))
+	})
+	fake.On("SELECT id FROM shipman.voyages WHERE charter_detail_id = $1 AND ($2 OR archived_at IS NULL)", func(call dbtest.Call) dbtest.Result {
+		return f.ids(call.Arg(2).(bool), func(v map[string]any) bool { return v["charter_detail_id"] == call.Arg(1) })
+	})
+	fake.On("FROM shipman.voyages WHERE (owner_user_id = $1", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		cols := []string{
+			"id", "deal_id", "voyage_number", "vessel_name", "imo_number", "departure_port", "arrival_port",
+			"planned_departure_at", "planned_arrival_at", "actual_departure_at", "actual_arrival_at",
+			"cargo_type", "cargo_quantity", "counterparty_user_id", "broker_user_id", "owner_user_id",
+			"status", "archived_at", "created_at", "updated_at",
+		}
+		var rows [][]any
+		for _, id := range f.order {
+			v := f.voyages[id]
+			if v["owner_user_id"] == call.Arg(1) && (call.Arg(2).(bool) || v["archived_at"] == nil) {
+				rows = append(rows, dbtest.Row(cols, v))
+			}
+		}
+		return dbtest.Rows(cols, rows...)
+	})
+	fake.On("SET archived_at = COALESCE(archived_at, NOW())", func(call dbtest.Call) dbtest.Result {
+		return f.set(call.Arg(1), func(v map[string]any) {
+			if v["archived_at"] == nil {
+				v["archived_at"] = time.Now()
+			}
+		})
+	})
+	fake.On("SET archived_at = NULL", func(call dbtest.Call) dbtest.Result {
+		return f.set(call.Arg(1), func(v map[string]any) { v["archived_at"] = nil })
+	})
+	fake.On("SELECT status FROM shipman.charter_details WHERE id = $1 FOR UPDATE", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		status, ok := f.charters[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows([]string{"status"})
+		}
+		return dbtest.Rows([]string{"status"}, []any{status})
+	})
+	fake.On("WHERE charter_detail_id = $1 AND status = 'completed' AND archived_at IS NULL", func(call dbtest.Call) dbtest.Result {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var n int64
+		for _, v := range f.voyages {
+			if v["charter_detail_id"] == call.Arg(1) && v["status"] == "completed" && v["archived_at"] == nil {
+				v["archived_at"] = time.Now()
+				n++
+			}
+		}
+		return dbtest.Affected(n)
+	})
+	return f
+}
+
+// add stores a voyage built from values over the defaults and returns its id.
+func (f *fakeArchiveTable) add(values map[string]any) uuid.UUID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uuid.New()
+	v := map[string]any{
+		"id": id, "org_id": DefaultOrgID, "status": "planned", "demurrage_currency": "USD",
+		"created_at": time.Now(), "updated_at": time.Now(),
+	}
+	for k, val := range values {
+		v[k] = val
+	}
+	f.voyages[id] = v
+	f.order = append(f.order, id)
+	return id
+}
+
+func (f *fakeArchiveTable) ids(includeArchived bool, match func(map[string]any) bool) dbtest.Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows [][]any
+	for _, id := range f.order {
+		v := f.voyages[id]
+		if match(v) && (includeArchived || v["archived_at"] == nil) {
+			rows = append(rows, []any{id})
+		}
+	}
+	return dbtest.Rows([]string{"id"}, rows...)
+}
+
+func (f *fakeArchiveTable) set(id any, fn func(map[string]any)) dbtest.Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.voyages[uuid.MustParse(id.(string))]
+	if !ok {
+		return dbtest.Affected(0)
+	}
+	fn(v)
+	return dbtest.Affected(1)
+}
+
+func voyageIDs(voyages []Voyage) []uuid.UUID {
+	ids := make([]uuid.UUID, len(voyages))
+	for i, v := range voyages {
+		ids[i] = v.ID
+	}
+	return ids
+}
+
+func TestVoyageArchiveExcludedFromLists(t *testing.T) {
+	fake := newFakeDB(t)
+	table := newFakeArchiveTable(fake)
+	charterID, ownerID := uuid.New(), uuid.New()
+	voyage := map[string]any{"charter_detail_id": charterID.String(), "owner_user_id": ownerID.String()}
+	first, second := table.add(voyage), table.add(voyage)
+	ctx := context.Background()
+	repo := NewVoyageRepository()
+
+	if err := repo.Archive(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := repo.Retrieve(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived.ArchivedAt == nil {
+		t.Fatal("archived voyage has no archived_at")
+	}
+
+	lists := map[string]func(bool) ([]Voyage, error){
+		"by charter": func(include bool) ([]Voyage, error) { return repo.ListByCharter(ctx, charterID, include) },
+		"by user":    func(include bool) ([]Voyage, error) { return repo.ListByUser(ctx, ownerID, include) },
+	}
+	for name, list := range lists {
+		active, err := list(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := voyageIDs(active); !slices.Equal(got, []uuid.UUID{second}) {
+			t.Errorf("%s: default list = %v, want only %s", name, got, second)
+		}
+		all, err := list(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := voyageIDs(all); !slices.Equal(got, []uuid.UUID{first, second}) {
+			t.Errorf("%s: include_archived list = %v, want both", name, got)
+		}
+		if i := slices.IndexFunc(all, func(v Voyage) bool { return v.ID == first }); all[i].ArchivedAt == nil {
+			t.Errorf("%s: archived_at not scanned", name)
+		}
+	}
+
+	if err := repo.Archive(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := repo.Retrieve(ctx, first)
+	if !again.ArchivedAt.Equal(*archived.ArchivedAt) {
+		t.Errorf("re-archiving moved archived_at from %s to %s", archived.ArchivedAt, again.ArchivedAt)
+	}
+
+	if err := repo.Unarchive(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	active, err := repo.ListByCharter(ctx, charterID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := voyageIDs(active); !slices.Equal(got, []uuid.UUID{first, second}) {
+		t.Errorf("after unarchive = %v, want both", got)
+	}
+}
+
+func TestVoyageArchiveMissing(t *testing.T) {
+	fake := newFakeDB(t)
+	newFakeArchiveTable(fake)
+	repo := NewVoyageRepository()
+	if err := repo.Archive(context.Background(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Archive err = %v, want ErrNotFound", err)
+	}
+	if err := repo.Unarchive(context.Background(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Unarchive err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestArchiveCompletedByCharter(t *testing.T) {
+	tests := []struct {
+		status       string
+		wantErr      error
+		wantArchived int64
+	}{
+		{"closed", nil, 2},
+		{"completed", nil, 2},
+		{"active", ErrCharterNotClosed, 0},
+		{"", sql.ErrNoRows, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			fake := newFakeDB(t)
+			table := newFakeArchiveTable(fake)
+			charterID := uuid.New()
+			if tt.status != "" {
+				table.charters[charterID.String()] = tt.status
+			}
+			earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			done := map[string]any{"charter_detail_id": charterID.String(), "status": "completed"}
+			table.add(done)
+			table.add(done)
+			table.add(map[string]any{"charter_detail_id": charterID.String(), "status": "completed", "archived_at": earlier})
+			sailing := table.add(map[string]any{"charter_detail_id": charterID.String(), "status": "in_progress"})
+			table.add(map[string]any{"charter_detail_id": uuid.NewString(), "status": "completed"})
+
+			archived, err := NewVoyageRepository().ArchiveCompletedByCharter(context.Background(), charterID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if archived != tt.wantArchived {
+				t.Errorf("archived = %d, want %d", archived, tt.wantArchived)
+			}
+			if table.voyages[sailing]["archived_at"] != nil {
+				t.Error("a voyage still in progress was archived")
+			}
+			if tt.wantErr != nil && len(fake.Calls("status = 'completed' AND archived_at IS NULL")) != 0 {
+				t.Error("voyages were archived for a charter that is not closed")
+			}
+		})
+	}
+}
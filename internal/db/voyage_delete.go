@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// voyageChildTables lists tables whose rows are removed with a voyage
+// (ON DELETE CASCADE).
+var voyageChildTables = []string{
+	"voyage_ports",
+	"ship_positions",
+	"cargo_loads",
+	"voyage_payments",
+	"voyage_invites",
+	"nor_events",
+}
+
+// voyageDetachedTables lists tables whose rows survive a voyage delete with
+// voyage_id set to NULL (ON DELETE SET NULL).
+var voyageDetachedTables = []string{
+	"laytime_entries",
+	"payments",
+	"disputes",
+	"bills_of_lading",
+	"demurrage_records",
+}
+
+// DeletePlan describes the effect of deleting a voyage: row counts per table
+// that would be removed, and per table that would lose their voyage link.
+type DeletePlan struct {
+	VoyageID uuid.UUID        `json:"voyage_id"`
+	Deleted  map[string]int64 `json:"deleted"`
+	Detached map[string]int64 `json:"detached"`
+	DryRun   bool             `json:"dry_run"`
+}
+
+// DeleteCascadePreview reports what DeleteCascade would remove without
+// changing anything.
+func (repo *VoyageRepository) DeleteCascadePreview(ctx context.Context, voyageID uuid.UUID) (DeletePlan, error) {
+	return repo.DeleteCascade(ctx, voyageID, true)
+}
+
+// DeleteCascade deletes a voyage together with its child rows and returns the
+// plan it carried out. The counts and the delete run in one transaction with
+// the voyage row locked, so the plan matches what was removed. With dryRun
+// set nothing is deleted. It returns sql.ErrNoRows when the voyage does not
+// exist.
+func (repo *VoyageRepository) DeleteCascade(ctx context.Context, voyageID uuid.UUID, dryRun bool) (DeletePlan, error) {
+	plan := DeletePlan{
+		VoyageID: voyageID,
+		Deleted:  map[string]int64{"voyages": 1},
+		Detached: map[string]int64{},
+		DryRun:   dryRun,
+	}
+
+	err := WithTx(ctx, func(ctx context.Context) error {
+		q := Conn(ctx)
+
+		var id uuid.UUID
+		if err := q.QueryRowContext(ctx,
+			`SELECT id FROM shipman.voyages WHERE id = $1 FOR UPDATE`, voyageID,
+		).Scan(&id); err != nil {
+			return err
+		}
+
+		count := func(table string) (int64, error) {
+			var n int64
+			err := q.QueryRowContext(ctx,
+				fmt.Sprintf(`SELECT COUNT(*) FROM shipman.%s WHERE voyage_id = $1`, table), voyageID,
+			).Scan(&n)
+			return n, err
+		}
+		for _, table := range voyageChildTables {
+			n, err := count(table)
+			if err != nil {
+				return err
+			}
+			plan.Deleted[table] = n
+		}
+		for _, table := range voyageDetachedTables {
+			n, err := count(table)
+			if err != nil {
+				return err
+			}
+			plan.Detached[table] = n
+		}
+
+		if dryRun {
+			return nil
+		}
+		_, err := q.ExecContext(ctx, `DELETE FROM shipman.voyages WHERE id = $1`, voyageID)
+		return err
+	})
+	if err != nil {
+		return DeletePlan{}, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// voyageForeignKeysFromMigrations maps each table with a voyage_id foreign
+// key declared in the migrations to its ON DELETE action.
+func voyageForeignKeysFromMigrations(t *testing.T) map[string]string {
+	t.Helper()
+	files, err := filepath.Glob("../../db/migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := regexp.MustCompile(`(?i)CREATE TABLE(?: IF NOT EXISTS)?\s+(?:shipman\.)?(\w+)`)
+	fk := regexp.MustCompile(`(?i)voyage_id\s+UUID[^,]*REFERENCES\s+(?:shipman\.)?voyages\(id\)\s+ON DELETE (CASCADE|SET NULL)`)
+
+	out := map[string]string{}
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(raw), "-- +goose Down")
+		current := ""
+		for _, line := range strings.Split(up, "\n") {
+			if m := table.FindStringSubmatch(line); m != nil {
+				current = m[1]
+			}
+			if m := fk.FindStringSubmatch(line); m != nil && current != "" {
+				out[current] = strings.ToUpper(m[1])
+			}
+		}
+	}
+	return out
+}
+
+// voyageForeignKeysFromSchema reads the same map from information_schema.
+func voyageForeignKeysFromSchema(t *testing.T, conn *sql.DB) map[string]string {
+	t.Helper()
+	const query = `
+		SELECT kcu.table_name, rc.delete_rule
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.key_column_usage kcu
+		  ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.constraint_column_usage ccu
+		  ON ccu.constraint_schema = rc.constraint_schema AND ccu.constraint_name = rc.constraint_name
+		WHERE ccu.table_schema = 'shipman' AND ccu.table_name = 'voyages'
+	`
+	rows, err := conn.QueryContext(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var table, rule string
+		if err := rows.Scan(&table, &rule); err != nil {
+			t.Fatal(err)
+		}
+		out[table] = rule
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func sortedTables(fks map[string]string, rule string) []string {
+	var out []string
+	for table, r := range fks {
+		if r == rule {
+			out = append(out, table)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedCopy(in []string) []string {
+	out := append([]string(nil), in...)
+	sort.Strings(out)
+	return out
+}
+
+func TestVoyageChildTablesMatchForeignKeys(t *testing.T) {
+	sources := map[string]func(t *testing.T) map[string]string{
+		"migrations": voyageForeignKeysFromMigrations,
+		"information_schema": func(t *testing.T) map[string]string {
+			dsn := os.Getenv("TEST_DATABASE_URL")
+			if dsn == "" {
+				t.Skip("TEST_DATABASE_URL not set")
+			}
+			conn, err := Open(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			return voyageForeignKeysFromSchema(t, conn)
+		},
+	}
+	for name, load := range sources {
+		t.Run(name, func(t *testing.T) {
+			fks := load(t)
+			tests := []struct {
+				rule   string
+				listed []string
+			}{
+				{"CASCADE", voyageChildTables},
+				{"SET NULL", voyageDetachedTables},
+			}
+			for _, tt := range tests {
+				want := sortedTables(fks, tt.rule)
+				got := sortedCopy(tt.listed)
+				if strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("ON DELETE %s tables = %v, foreign keys say %v", tt.rule, got, want)
+				}
+			}
+		})
+	}
+}
+
+// fakeVoyageDelete is a voyage and its per-table voyage_id row counts. The
+// voyage DELETE applies ON DELETE CASCADE / SET NULL to the counts and
+// records what it removed.
+type fakeVoyageDelete struct {
+	id       uuid.UUID
+	exists   bool
+	counts   map[string]int64
+	removed  map[string]int64
+	detached map[string]int64
+}
+
+func (f *fakeVoyageDelete) install(fake *dbtest.Fake) {
+	fake.On("SELECT id FROM shipman.voyages WHERE id = $1 FOR UPDATE", func(call dbtest.Call) dbtest.Result {
+		if !f.exists || call.Arg(1) != f.id.String() {
+			return dbtest.Rows([]string{"id"})
+		}
+		return dbtest.Rows([]string{"id"}, []any{f.id})
+	})
+	table := regexp.MustCompile(`FROM shipman\.(\w+) WHERE voyage_id = \$1`)
+	fake.On("SELECT COUNT(*) FROM shipman.", func(call dbtest.Call) dbtest.Result {
+		return dbtest.Rows([]string{"count"}, []any{f.counts[table.FindStringSubmatch(call.Query)[1]]})
+	})
+	fake.On("DELETE FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		f.exists = false
+		f.removed = map[string]int64{"voyages": 1}
+		f.detached = map[string]int64{}
+		for _, t := range voyageChildTables {
+			f.removed[t], f.counts[t] = f.counts[t], 0
+		}
+		for _, t := range voyageDetachedTables {
+			f.detached[t], f.counts[t] = f.counts[t], 0
+		}
+		return dbtest.Affected(1)
+	})
+}
+
+func TestDeleteCascadePreviewMatchesDeletion(t *testing.T) {
+	fake := newFakeDB(t)
+	f := &fakeVoyageDelete{id: uuid.New(), exists: true, counts: map[string]int64{
+		"voyage_ports": 3, "ship_positions": 40, "voyage_payments": 2, "nor_events": 1,
+		"laytime_entries": 5, "disputes": 1,
+	}}
+	f.install(fake)
+	repo := NewVoyageRepository()
+
+	preview, err := repo.DeleteCascadePreview(context.Background(), f.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.DryRun || preview.VoyageID != f.id {
+		t.Errorf("preview = %+v, want a dry run for %s", preview, f.id)
+	}
+	if n := len(fake.Calls("DELETE FROM shipman.voyages")); n != 0 {
+		t.Fatalf("preview issued %d deletes", n)
+	}
+	if !f.exists || f.counts["ship_positions"] != 40 {
+		t.Fatal("preview changed the voyage's rows")
+	}
+
+	plan, err := repo.DeleteCascade(context.Background(), f.id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.DryRun {
+		t.Error("plan.DryRun = true for a real delete")
+	}
+	if !maps.Equal(preview.Deleted, plan.Deleted) || !maps.Equal(preview.Detached, plan.Detached) {
+		t.Errorf("preview %+v differs from the executed plan %+v", preview, plan)
+	}
+	if !maps.Equal(preview.Deleted, f.removed) || !maps.Equal(preview.Detached, f.detached) {
+		t.Errorf("preview deleted %v / detached %v, delete removed %v / detached %v",
+			preview.Deleted, preview.Detached, f.removed, f.detached)
+	}
+	if fake.Commits() != 2 {
+		t.Errorf("commits = %d, want one per call", fake.Commits())
+	}
+}
+
+func TestDeleteCascadeMissingVoyage(t *testing.T) {
+	fake := newFakeDB(t)
+	(&fakeVoyageDelete{id: uuid.New(), counts: map[string]int64{}}).install(fake)
+
+	for _, dryRun := range []bool{true, false} {
+		if _, err := NewVoyageRepository().DeleteCascade(context.Background(), uuid.New(), dryRun); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("dryRun %v: err = %v, want sql.ErrNoRows", dryRun, err)
+		}
+	}
+	if n := len(fake.Calls("DELETE FROM shipman.voyages")); n != 0 {
+		t.Errorf("issued %d deletes for a missing voyage", n)
+	}
+}
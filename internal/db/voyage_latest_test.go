@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// stubLatestByCharter answers LatestByCharter from voyages the way the query
+// does: unarchived voyages of the charter in the caller's org, in-progress
+// first, then by planned departure or creation, newest first.
+func stubLatestByCharter(fake *dbtest.Fake, voyages []map[string]any) {
+	const order = "ORDER BY (actual_departure_at IS NOT NULL AND actual_arrival_at IS NULL) DESC NULLS LAST, " +
+		"COALESCE(planned_departure_at, created_at) DESC NULLS LAST, id DESC NULLS LAST LIMIT 1"
+	fake.On("SELECT id FROM shipman.voyages WHERE charter_detail_id = $1 AND archived_at IS NULL", func(call dbtest.Call) dbtest.Result {
+		if !strings.Contains(call.Query, order) {
+			return dbtest.Fail(errors.New("unexpected ordering: " + call.Query))
+		}
+		org := orgArg(call)
+		inProgress := func(v map[string]any) bool { return v["actual_departure_at"] != nil && v["actual_arrival_at"] == nil }
+		departure := func(v map[string]any) time.Time {
+			if at, ok := v["planned_departure_at"].(time.Time); ok {
+				return at
+			}
+			return v["created_at"].(time.Time)
+		}
+		var candidates []map[string]any
+		for _, v := range voyages {
+			if v["charter_detail_id"] == call.Arg(1) && v["archived_at"] == nil && (org == "" || v["org_id"] == org) {
+				candidates = append(candidates, v)
+			}
+		}
+		if len(candidates) == 0 {
+			return dbtest.Rows([]string{"id"})
+		}
+		best := slices.MaxFunc(candidates, func(a, b map[string]any) int {
+			if inProgress(a) != inProgress(b) {
+				if inProgress(a) {
+					return 1
+				}
+				return -1
+			}
+			if c := departure(a).Compare(departure(b)); c != 0 {
+				return c
+			}
+			return strings.Compare(a["id"].(string), b["id"].(string))
+		})
+		return dbtest.Rows([]string{"id"}, []any{best["id"]})
+	})
+	fake.On("archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		for _, v := range voyages {
+			if v["id"] == call.Arg(1) {
+				return dbtest.Rows(voyageColumns, dbtest.Row(voyageColumns, v))
+			}
+		}
+		return dbtest.Rows(voyageColumns)
+	})
+}
+
+func TestVoyageLatestByCharter(t *testing.T) {
+	charterID := uuid.NewString()
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	voyage := func(number string, planned, departed, arrived, archived any, created time.Time) map[string]any {
+		return map[string]any{
+			"id": uuid.NewString(), "org_id": DefaultOrgID.String(), "charter_detail_id": charterID,
+			"voyage_number": number, "status": "planned", "demurrage_currency": "USD",
+			"planned_departure_at": planned, "actual_departure_at": departed, "actual_arrival_at": arrived,
+			"archived_at": archived, "created_at": created, "updated_at": created,
+		}
+	}
+	done := voyage("V1", day(1), day(1), day(9), nil, day(1))
+	sailing := voyage("V2", day(5), day(6), nil, nil, day(2))
+	next := voyage("V3", day(20), nil, nil, nil, day(3))
+	unplanned := voyage("V4", nil, nil, nil, nil, day(25))
+	shelved := voyage("V5", day(28), nil, nil, day(4), day(4))
+
+	tests := []struct {
+		name    string
+		voyages []map[string]any
+		want    string
+	}{
+		{"in progress wins over later plans", []map[string]any{done, sailing, next, unplanned, shelved}, "V2"},
+		{"created_at stands in for a missing plan", []map[string]any{done, next, unplanned, shelved}, "V4"},
+		{"latest planned departure", []map[string]any{done, next, shelved}, "V3"},
+		{"only finished voyages", []map[string]any{done}, "V1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubLatestByCharter(fake, tt.voyages)
+
+			got, err := NewVoyageRepository().LatestByCharter(context.Background(), uuid.MustParse(charterID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VoyageNumber == nil || *got.VoyageNumber != tt.want {
+				t.Errorf("latest voyage = %v, want %s", got.VoyageNumber, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoyageLatestByCharterNotFound(t *testing.T) {
+	charterID := uuid.New()
+	stamp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	voyages := []map[string]any{
+		// Only visible to another org, or archived.
+		{"id": uuid.NewString(), "org_id": uuid.NewString(), "charter_detail_id": charterID.String(),
+			"status": "planned", "demurrage_currency": "USD", "created_at": stamp, "updated_at": stamp},
+		{"id": uuid.NewString(), "org_id": DefaultOrgID.String(), "charter_detail_id": charterID.String(),
+			"status": "planned", "demurrage_currency": "USD", "archived_at": stamp, "created_at": stamp, "updated_at": stamp},
+	}
+	fake := newFakeDB(t)
+	stubLatestByCharter(fake, voyages)
+
+	_, err := NewVoyageRepository().LatestByCharter(WithOrg(context.Background(), DefaultOrgID), charterID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if _, err := NewVoyageRepository().LatestByCharter(context.Background(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("charter without voyages: err = %v, want ErrNotFound", err)
+	}
+}
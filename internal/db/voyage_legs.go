@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// earthRadiusNM is the mean Earth radius in nautical miles.
+const earthRadiusNM = 3440.065
+
+// VoyageLeg is the passage between two consecutive port calls. DepartedAt is
+// when the ship left the from port and ArrivedAt when it reached the to port.
+// Pointer fields are nil when the ports lack the coordinates or times needed;
+// DurationHours is also nil when the recorded times run backwards.
+type VoyageLeg struct {
+	Sequence      int        `json:"sequence"` // 1-based
+	FromPortID    uuid.UUID  `json:"from_port_id"`
+	FromPortName  string     `json:"from_port_name"`
+	ToPortID      uuid.UUID  `json:"to_port_id"`
+	ToPortName    string     `json:"to_port_name"`
+	DepartedAt    *time.Time `json:"departed_at,omitempty"`
+	ArrivedAt     *time.Time `json:"arrived_at,omitempty"`
+	DistanceNM    *float64   `json:"distance_nm,omitempty"` // great-circle
+	DurationHours *float64   `json:"duration_hours,omitempty"`
+}
+
+// VoyageLegs derives the voyage's legs from its ports in ListByVoyage order.
+// A voyage with fewer than two ports has no legs.
+func (repo *VoyagePortRepository) VoyageLegs(ctx context.Context, voyageID uuid.UUID) ([]VoyageLeg, error) {
+	ports, err := repo.ListByVoyage(ctx, voyageID)
+	if err != nil {
+		return nil, err
+	}
+	return legsFromPorts(ports), nil
+}
+
+// legsFromPorts pairs each port with the next one.
+func legsFromPorts(ports []VoyagePort) []VoyageLeg {
+	legs := []VoyageLeg{}
+	for i := 1; i < len(ports); i++ {
+		from, to := ports[i-1], ports[i]
+		leg := VoyageLeg{
+			Sequence:     i,
+			FromPortID:   from.ID,
+			FromPortName: from.PortName,
+			ToPortID:     to.ID,
+			ToPortName:   to.PortName,
+			DepartedAt:   from.DepartedAt,
+			ArrivedAt:    to.ArrivedAt,
+		}
+		if from.Latitude != nil && from.Longitude != nil && to.Latitude != nil && to.Longitude != nil {
+			d := greatCircleNM(*from.Latitude, *from.Longitude, *to.Latitude, *to.Longitude)
+			leg.DistanceNM = &d
+		}
+		if leg.DepartedAt != nil && leg.ArrivedAt != nil && !leg.ArrivedAt.Before(*leg.DepartedAt) {
+			h := leg.ArrivedAt.Sub(*leg.DepartedAt).Hours()
+			leg.DurationHours = &h
+		}
+		legs = append(legs, leg)
+	}
+	return legs
+}
+
+// greatCircleNM returns the haversine distance between two points given in
+// degrees, in nautical miles.
+func greatCircleNM(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusNM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
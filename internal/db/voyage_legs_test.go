@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGreatCircleNM(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 51.9, 4.1, 51.9, 4.1, 0},
+		{"one degree of the equator", 0, 0, 0, 1, math.Pi * earthRadiusNM / 180},
+		{"quarter meridian", 0, 0, 90, 0, math.Pi * earthRadiusNM / 2},
+		{"pole to pole", 90, 0, -90, 0, math.Pi * earthRadiusNM},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, math.Pi * earthRadiusNM / 180},
+	}
+	for _, tt := range tests {
+		if got := greatCircleNM(tt.lat1, tt.lon1, tt.lat2, tt.lon2); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: %f NM, want %f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVoyageLegs(t *testing.T) {
+	voyageID := uuid.New()
+	at := func(day, hour int) time.Time { return time.Date(2026, 5, day, hour, 0, 0, 0, time.UTC) }
+	port := func(name string, lat, lon, arrived, departed any) []any {
+		return []any{uuid.New(), voyageID, name, nil, nil, lat, lon, arrived, departed, nil, nil, nil, at(1, 0), at(1, 0)}
+	}
+	// Stored out of order: legs follow the ListByVoyage ordering, not insert
+	// order.
+	fixtures := [][]any{
+		port("Lagos", 1.0, 1.0, at(6, 12), nil),
+		port("Santos", 0.0, 0.0, at(1, 0), at(1, 6)),
+		port("Dakar", 0.0, 1.0, at(3, 6), at(4, 0)),
+	}
+
+	fake := newFakeDB(t)
+	stubOrderedList(t, fake, "FROM shipman.voyage_ports WHERE voyage_id = $1 ORDER BY", voyagePortColumns, fixtures)
+
+	legs, err := NewVoyagePortRepository().VoyageLegs(context.Background(), voyageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legs) != 2 {
+		t.Fatalf("legs = %d, want 2 from three ports", len(legs))
+	}
+	degree := math.Pi * earthRadiusNM / 180
+	want := []struct {
+		from, to string
+		distance float64
+		hours    float64
+	}{
+		{"Santos", "Dakar", degree, 48},
+		{"Dakar", "Lagos", degree, 60},
+	}
+	for i, w := range want {
+		leg := legs[i]
+		if leg.Sequence != i+1 || leg.FromPortName != w.from || leg.ToPortName != w.to {
+			t.Errorf("leg %d = %d %s → %s, want %d %s → %s", i, leg.Sequence, leg.FromPortName, leg.ToPortName, i+1, w.from, w.to)
+		}
+		if leg.DistanceNM == nil || math.Abs(*leg.DistanceNM-w.distance) > 1e-6 {
+			t.Errorf("leg %d distance = %v, want %f", i, leg.DistanceNM, w.distance)
+		}
+		if leg.DurationHours == nil || *leg.DurationHours != w.hours {
+			t.Errorf("leg %d duration = %v, want %v hours", i, leg.DurationHours, w.hours)
+		}
+	}
+	if legs[0].ToPortID != legs[1].FromPortID {
+		t.Error("second leg does not start where the first ended")
+	}
+}
+
+func TestLegsFromPortsGaps(t *testing.T) {
+	at := func(day int) *time.Time { ts := time.Date(2026, 5, day, 0, 0, 0, 0, time.UTC); return &ts }
+	coord := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name         string
+		from, to     VoyagePort
+		wantDistance bool
+		wantDuration bool
+	}{
+		{"complete", VoyagePort{Latitude: coord(0), Longitude: coord(0), DepartedAt: at(1)},
+			VoyagePort{Latitude: coord(0), Longitude: coord(1), ArrivedAt: at(2)}, true, true},
+		{"missing coordinate", VoyagePort{Latitude: coord(0), DepartedAt: at(1)},
+			VoyagePort{Latitude: coord(0), Longitude: coord(1), ArrivedAt: at(2)}, false, true},
+		{"not yet arrived", VoyagePort{Latitude: coord(0), Longitude: coord(0), DepartedAt: at(1)},
+			VoyagePort{Latitude: coord(0), Longitude: coord(1)}, true, false},
+		{"times run backwards", VoyagePort{DepartedAt: at(3)}, VoyagePort{ArrivedAt: at(2)}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legs := legsFromPorts([]VoyagePort{tt.from, tt.to})
+			if len(legs) != 1 {
+				t.Fatalf("legs = %d, want 1", len(legs))
+			}
+			if got := legs[0].DistanceNM != nil; got != tt.wantDistance {
+				t.Errorf("distance set = %v, want %v", got, tt.wantDistance)
+			}
+			if got := legs[0].DurationHours != nil; got != tt.wantDuration {
+				t.Errorf("duration set = %v, want %v", got, tt.wantDuration)
+			}
+		})
+	}
+
+	for _, ports := range [][]VoyagePort{nil, {{PortName: "Santos"}}} {
+		if legs := legsFromPorts(ports); legs == nil || len(legs) != 0 {
+			t.Errorf("%d ports gave legs %v, want an empty list", len(ports), legs)
+		}
+	}
+}
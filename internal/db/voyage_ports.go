@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,8 +31,15 @@ type VoyagePort struct {
 // VoyagePortService exposes CRUD behaviour.
 type VoyagePortService interface {
 	Create(ctx context.Context, vp *VoyagePort) error
+	CreateBatch(ctx context.Context, ports []*VoyagePort) error
+	CreateBatchPartial(ctx context.Context, ports []*VoyagePort) (int, BatchErrors)
+	Upsert(ctx context.Context, vp *VoyagePort) error
 	Retrieve(ctx context.Context, id uuid.UUID) (VoyagePort, error)
 	ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]VoyagePort, error)
+	DistinctPortNames(ctx context.Context, prefix string, limit int) ([]string, error)
+	SyncLaytimeForCharter(ctx context.Context, charterID uuid.UUID) (int64, error)
+	VoyageLegs(ctx context.Context, voyageID uuid.UUID) ([]VoyageLeg, error)
+	LongestPortCall(ctx context.Context, voyageID uuid.UUID) (VoyagePort, float64, error)
 	Update(ctx context.Context, vp *VoyagePort) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -43,8 +52,222 @@ func NewVoyagePortRepository() *VoyagePortRepository {
 	return &VoyagePortRepository{}
 }
 
-// Create inserts a voyage port record.
+// DefaultMaxVoyagePorts is the per-voyage port cap used unless overridden.
+const DefaultMaxVoyagePorts = 100
+
+// MaxVoyagePorts caps how many ports a single voyage may hold.
+var MaxVoyagePorts = DefaultMaxVoyagePorts
+
+// SetMaxVoyagePorts overrides the port cap. Values <= 0 restore the default.
+func SetMaxVoyagePorts(n int) {
+	if n <= 0 {
+		n = DefaultMaxVoyagePorts
+	}
+	MaxVoyagePorts = n
+}
+
+// Create inserts a voyage port record, enforcing MaxVoyagePorts. Out-of-range
+// coordinates are rejected with a *ValidationError.
 func (repo *VoyagePortRepository) Create(ctx context.Context, vp *VoyagePort) error {
+	if err := sanitizeNotes(notesField{"notes", vp.Notes}); err != nil {
+		return err
+	}
+	if err := checkCoordinates(vp.Latitude, vp.Longitude); err != nil {
+		return err
+	}
+	return WithTx(ctx, func(ctx context.Context) error {
+		if err := reservePorts(ctx, vp.VoyageID, 1); err != nil {
+			return err
+		}
+		return repo.insert(ctx, vp)
+	})
+}
+
+// CreateBatch inserts several ports in one transaction. Nothing is written
+// if any voyage would end up over MaxVoyagePorts or any port has invalid
+// coordinates; the latter error names the port's index in the batch.
+func (repo *VoyagePortRepository) CreateBatch(ctx context.Context, ports []*VoyagePort) error {
+	perVoyage := make(map[uuid.UUID]int)
+	for i, vp := range ports {
+		if err := checkCoordinates(vp.Latitude, vp.Longitude); err != nil {
+			return fmt.Errorf("port %d: %w", i, err)
+		}
+		if err := sanitizeNotes(notesField{"notes", vp.Notes}); err != nil {
+			return fmt.Errorf("port %d: %w", i, err)
+		}
+		perVoyage[vp.VoyageID]++
+	}
+
+	return WithTx(ctx, func(ctx context.Context) error {
+		for voyageID, n := range perVoyage {
+			if err := reservePorts(ctx, voyageID, n); err != nil {
+				return err
+			}
+		}
+		for _, vp := range ports {
+			if err := repo.insert(ctx, vp); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateBatchPartial creates each port on its own, each with the checks
+// Create applies, so a port that is invalid or would exceed MaxVoyagePorts is
+// reported without holding back the rest. It returns how many were written
+// and the error for each index that was not. ctx should not carry a
+// transaction: one failed insert would abort it for the items after.
+func (repo *VoyagePortRepository) CreateBatchPartial(ctx context.Context, ports []*VoyagePort) (int, BatchErrors) {
+	return createEach(ctx, ports, repo.Create)
+}
+
+// Upsert inserts a port or, when the voyage already has a port with the same
+// UN/LOCODE, overwrites that row's details and timings in place. Ports
+// without a UN/LOCODE are always created. MaxVoyagePorts applies only when a
+// new row would be added.
+func (repo *VoyagePortRepository) Upsert(ctx context.Context, vp *VoyagePort) error {
+	clearServerFields(&vp.ID, &vp.CreatedAt, &vp.UpdatedAt)
+	if err := sanitizeNotes(notesField{"notes", vp.Notes}); err != nil {
+		return err
+	}
+	if err := checkCoordinates(vp.Latitude, vp.Longitude); err != nil {
+		return err
+	}
+	vp.Normalize()
+	if err := normalizeUNLocode(vp); err != nil {
+		return err
+	}
+	if vp.PortUNLocode == nil {
+		return repo.Create(ctx, vp)
+	}
+
+	return WithTx(ctx, func(ctx context.Context) error {
+		var exists bool
+		if err := Conn(ctx).QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM shipman.voyage_ports WHERE voyage_id = $1 AND port_unlocode = $2)`,
+			vp.VoyageID, *vp.PortUNLocode,
+		).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			if err := reservePorts(ctx, vp.VoyageID, 1); err != nil {
+				return err
+			}
+		}
+
+		const query = `
+			INSERT INTO shipman.voyage_ports (
+				voyage_id,
+				port_name,
+				port_country,
+				port_unlocode,
+				latitude,
+				longitude,
+				arrived_at,
+				departed_at,
+				laytime_hours,
+				cargo_operations,
+				notes
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			)
+			ON CONFLICT (voyage_id, port_unlocode) DO UPDATE SET
+				port_name = EXCLUDED.port_name,
+				port_country = EXCLUDED.port_country,
+				latitude = EXCLUDED.latitude,
+				longitude = EXCLUDED.longitude,
+				arrived_at = EXCLUDED.arrived_at,
+				departed_at = EXCLUDED.departed_at,
+				laytime_hours = EXCLUDED.laytime_hours,
+				cargo_operations = EXCLUDED.cargo_operations,
+				notes = EXCLUDED.notes,
+				updated_at = NOW()
+			RETURNING id, created_at, updated_at
+		`
+
+		return Conn(ctx).QueryRowContext(
+			ctx,
+			query,
+			vp.VoyageID,
+			vp.PortName,
+			nullableString(vp.PortCountry),
+			nullableString(vp.PortUNLocode),
+			nullableFloat(vp.Latitude),
+			nullableFloat(vp.Longitude),
+			nullableTime(vp.ArrivedAt),
+			nullableTime(vp.DepartedAt),
+			nullableFloat(vp.LaytimeHours),
+			nullableString(vp.CargoOperations),
+			nullableString(vp.Notes),
+		).Scan(&vp.ID, &vp.CreatedAt, &vp.UpdatedAt)
+	})
+}
+
+// reservePorts locks the voyage row so concurrent inserts are counted one at
+// a time, then checks that adding n ports stays within MaxVoyagePorts.
+func reservePorts(ctx context.Context, voyageID uuid.UUID, n int) error {
+	var locked uuid.UUID
+	if err := Conn(ctx).QueryRowContext(ctx, `SELECT id FROM shipman.voyages WHERE id = $1 FOR UPDATE`, voyageID).Scan(&locked); err != nil {
+		return err
+	}
+
+	var existing int
+	if err := Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM shipman.voyage_ports WHERE voyage_id = $1`, voyageID).Scan(&existing); err != nil {
+		return err
+	}
+	if existing+n > MaxVoyagePorts {
+		return fmt.Errorf("%w: voyage has %d, limit is %d", ErrTooManyPorts, existing, MaxVoyagePorts)
+	}
+	return nil
+}
+
+// IsValidUNLocode reports whether code is a canonical UN/LOCODE: a two-letter
+// ISO country code followed by a three-character location code (letters or
+// the digits 2-9).
+func IsValidUNLocode(code string) bool {
+	if len(code) != 5 {
+		return false
+	}
+	for i := 0; i < 2; i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return false
+		}
+	}
+	for i := 2; i < 5; i++ {
+		ch := code[i]
+		if !(ch >= 'A' && ch <= 'Z') && !(ch >= '2' && ch <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeUNLocode uppercases and strips whitespace from the port's
+// UN/LOCODE in place. A nil or blank code is stored as NULL.
+func normalizeUNLocode(vp *VoyagePort) error {
+	if vp.PortUNLocode == nil {
+		return nil
+	}
+	code := strings.ToUpper(strings.Join(strings.Fields(*vp.PortUNLocode), ""))
+	if code == "" {
+		vp.PortUNLocode = nil
+		return nil
+	}
+	if !IsValidUNLocode(code) {
+		return fmt.Errorf("%w: %q", ErrInvalidUNLocode, *vp.PortUNLocode)
+	}
+	vp.PortUNLocode = &code
+	return nil
+}
+
+func (repo *VoyagePortRepository) insert(ctx context.Context, vp *VoyagePort) error {
+	clearServerFields(&vp.ID, &vp.CreatedAt, &vp.UpdatedAt)
+	vp.Normalize()
+	if err := normalizeUNLocode(vp); err != nil {
+		return err
+	}
+
 	const query = `
 		INSERT INTO shipman.voyage_ports (
 			voyage_id,
@@ -64,7 +287,7 @@ func (repo *VoyagePortRepository) Create(ctx context.Context, vp *VoyagePort) er
 		RETURNING id, created_at, updated_at
 	`
 
-	return Pool.QueryRowContext(
+	return Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		vp.VoyageID,
@@ -149,15 +372,52 @@ func (repo *VoyagePortRepository) Retrieve(ctx context.Context, id uuid.UUID) (V
 	return vp, nil
 }
 
-// ListByVoyage returns all ports in order visited.
-func (repo *VoyagePortRepository) ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]VoyagePort, error) {
+// DistinctPortNames returns previously used port names (from voyage ports
+// and laytime entries) starting with prefix, case-insensitively, in
+// alphabetical order.
+func (repo *VoyagePortRepository) DistinctPortNames(ctx context.Context, prefix string, limit int) ([]string, error) {
 	const query = `
+		SELECT port_name FROM (
+			SELECT DISTINCT ON (lower(port_name)) port_name
+			FROM (
+				SELECT trim(port_name) AS port_name FROM shipman.voyage_ports
+				UNION
+				SELECT trim(port_name) AS port_name FROM shipman.laytime_entries
+			) names
+			WHERE port_name <> ''
+			  AND port_name ILIKE $1 ESCAPE '\'
+			ORDER BY lower(port_name), port_name
+		) deduped
+		ORDER BY lower(port_name)
+		LIMIT $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, escapeLike(prefix)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// ListByVoyage returns all ports in order visited. Ports not yet reached
+// come last, in the order they were added.
+func (repo *VoyagePortRepository) ListByVoyage(ctx context.Context, voyageID uuid.UUID) ([]VoyagePort, error) {
+	query := `
 		SELECT id, voyage_id, port_name, port_country, port_unlocode, latitude, longitude,
 		       arrived_at, departed_at, laytime_hours, cargo_operations, notes, created_at, updated_at
 		FROM shipman.voyage_ports
 		WHERE voyage_id = $1
-		ORDER BY arrived_at NULLS LAST, created_at
-	`
+		ORDER BY ` + orderBy(asc("arrived_at"), asc("created_at"), asc("id"))
 
 	rows, err := Pool.QueryContext(ctx, query, voyageID)
 	if err != nil {
@@ -211,8 +471,83 @@ func (repo *VoyagePortRepository) ListByVoyage(ctx context.Context, voyageID uui
 	return ports, rows.Err()
 }
 
-// Update modifies a port record.
+// LongestPortCall returns the voyage's port that used the most laytime and
+// its hours. A port's laytime is the hours counted by the voyage's entries at
+// that port, falling back to its own laytime_hours when none were logged.
+// Ties go to the earlier call. It returns ErrNotFound when the voyage has no
+// ports.
+func (repo *VoyagePortRepository) LongestPortCall(ctx context.Context, voyageID uuid.UUID) (VoyagePort, float64, error) {
+	query := `
+		SELECT vp.id, COALESCE(t.hours, vp.laytime_hours, 0) AS hours
+		FROM shipman.voyage_ports vp
+		LEFT JOIN (
+			SELECT lower(trim(port_name)) AS port_key, SUM(COALESCE(hours_counted, 0)) AS hours
+			FROM shipman.laytime_entries
+			WHERE voyage_id = $1
+			GROUP BY lower(trim(port_name))
+		) t ON t.port_key = lower(trim(vp.port_name))
+		WHERE vp.voyage_id = $1
+		ORDER BY ` + orderBy(desc("hours"), asc("vp.arrived_at"), asc("vp.created_at"), asc("vp.id")) + `
+		LIMIT 1
+	`
+
+	var (
+		id    uuid.UUID
+		hours float64
+	)
+	if err := Pool.QueryRowContext(ctx, query, voyageID).Scan(&id, &hours); err != nil {
+		return VoyagePort{}, 0, notFound(err)
+	}
+	port, err := repo.Retrieve(ctx, id)
+	if err != nil {
+		return VoyagePort{}, 0, notFound(err)
+	}
+	return port, hours, nil
+}
+
+// SyncLaytimeForCharter sets laytime_hours on every port of the charter's
+// voyages to the hours counted by the laytime entries logged for that voyage
+// and port, matching port names case-insensitively. Ports with no entries
+// keep their value. All voyages are updated in a single statement; it
+// returns how many ports changed.
+func (repo *VoyagePortRepository) SyncLaytimeForCharter(ctx context.Context, charterID uuid.UUID) (int64, error) {
+	const query = `
+		WITH totals AS (
+			SELECT le.voyage_id, lower(trim(le.port_name)) AS port_key,
+			       SUM(COALESCE(le.hours_counted, 0)) AS hours
+			FROM shipman.laytime_entries le
+			JOIN shipman.voyages v ON v.id = le.voyage_id
+			WHERE v.charter_detail_id = $1
+			GROUP BY le.voyage_id, lower(trim(le.port_name))
+		)
+		UPDATE shipman.voyage_ports vp
+		SET laytime_hours = t.hours, updated_at = NOW()
+		FROM totals t
+		WHERE vp.voyage_id = t.voyage_id
+		  AND lower(trim(vp.port_name)) = t.port_key
+		  AND vp.laytime_hours IS DISTINCT FROM t.hours
+	`
+	res, err := Conn(ctx).ExecContext(ctx, query, charterID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Update modifies a port record. Out-of-range coordinates are rejected with
+// a *ValidationError.
 func (repo *VoyagePortRepository) Update(ctx context.Context, vp *VoyagePort) error {
+	if err := sanitizeNotes(notesField{"notes", vp.Notes}); err != nil {
+		return err
+	}
+	if err := checkCoordinates(vp.Latitude, vp.Longitude); err != nil {
+		return err
+	}
+	vp.Normalize()
+	if err := normalizeUNLocode(vp); err != nil {
+		return err
+	}
+
 	const query = `
 		UPDATE shipman.voyage_ports
 		SET
@@ -231,7 +566,7 @@ func (repo *VoyagePortRepository) Update(ctx context.Context, vp *VoyagePort) er
 		RETURNING updated_at
 	`
 
-	return Pool.QueryRowContext(
+	err := Pool.QueryRowContext(
 		ctx,
 		query,
 		vp.ID,
@@ -246,11 +581,11 @@ func (repo *VoyagePortRepository) Update(ctx context.Context, vp *VoyagePort) er
 		nullableString(vp.CargoOperations),
 		nullableString(vp.Notes),
 	).Scan(&vp.UpdatedAt)
+	return notFound(err)
 }
 
-// Delete removes a voyage port.
+// Delete removes a voyage port. It returns ErrNotFound when there is none.
 func (repo *VoyagePortRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	const query = `DELETE FROM shipman.voyage_ports WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id))
 }
@@ -0,0 +1,510 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// fakePortTable counts the ports inserted per voyage so reservePorts sees
+// earlier inserts. Inserts made in a rolled-back transaction are undone.
+type fakePortTable struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (p *fakePortTable) install(fake *dbtest.Fake) {
+	p.counts = make(map[string]int)
+	fake.On("SELECT id FROM shipman.voyages WHERE id = $1 FOR UPDATE", func(call dbtest.Call) dbtest.Result {
+		return dbtest.Rows([]string{"id"}, []any{call.Arg(1)})
+	})
+	fake.On("SELECT COUNT(*) FROM shipman.voyage_ports WHERE voyage_id = $1", func(call dbtest.Call) dbtest.Result {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return dbtest.Rows([]string{"count"}, []any{p.counts[call.Arg(1).(string)]})
+	})
+	fake.On("INSERT INTO shipman.voyage_ports", func(call dbtest.Call) dbtest.Result {
+		voyageID := call.Arg(1).(string)
+		p.mu.Lock()
+		p.counts[voyageID]++
+		p.mu.Unlock()
+		call.Tx.OnEnd(func(committed bool) {
+			if !committed {
+				p.mu.Lock()
+				p.counts[voyageID]--
+				p.mu.Unlock()
+			}
+		})
+		return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{uuid.New(), time.Now(), time.Now()})
+	})
+}
+
+func (p *fakePortTable) count(voyageID uuid.UUID) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts[voyageID.String()]
+}
+
+func newPort(voyageID uuid.UUID, name string) *VoyagePort {
+	return &VoyagePort{VoyageID: voyageID, PortName: name}
+}
+
+func TestVoyagePortCreateEnforcesMax(t *testing.T) {
+	SetMaxVoyagePorts(3)
+	t.Cleanup(func() { SetMaxVoyagePorts(0) })
+	fake := newFakeDB(t)
+	var table fakePortTable
+	table.install(fake)
+
+	ctx := context.Background()
+	repo := NewVoyagePortRepository()
+	voyageID := uuid.New()
+	for i, name := range []string{"Santos", "Rotterdam", "Singapore"} {
+		if err := repo.Create(ctx, newPort(voyageID, name)); err != nil {
+			t.Fatalf("port %d: %v", i+1, err)
+		}
+	}
+	if err := repo.Create(ctx, newPort(voyageID, "Houston")); !errors.Is(err, ErrTooManyPorts) {
+		t.Fatalf("port past the limit: err = %v, want ErrTooManyPorts", err)
+	}
+	if got := table.count(voyageID); got != 3 {
+		t.Errorf("ports = %d, want 3", got)
+	}
+	if err := repo.Create(ctx, newPort(uuid.New(), "Houston")); err != nil {
+		t.Errorf("another voyage's first port: %v", err)
+	}
+}
+
+func TestVoyagePortCreateBatchEnforcesMax(t *testing.T) {
+	SetMaxVoyagePorts(3)
+	t.Cleanup(func() { SetMaxVoyagePorts(0) })
+
+	tests := []struct {
+		name     string
+		existing int
+		batch    int
+		wantErr  error
+		wantPort int
+	}{
+		{"fills to the limit", 1, 2, nil, 3},
+		{"one past the limit", 2, 2, ErrTooManyPorts, 2},
+		{"batch alone too large", 0, 4, ErrTooManyPorts, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			var table fakePortTable
+			table.install(fake)
+			voyageID := uuid.New()
+			table.counts[voyageID.String()] = tt.existing
+
+			ports := make([]*VoyagePort, tt.batch)
+			for i := range ports {
+				ports[i] = newPort(voyageID, "Port")
+			}
+			err := NewVoyagePortRepository().CreateBatch(context.Background(), ports)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := table.count(voyageID); got != tt.wantPort {
+				t.Errorf("ports = %d, want %d", got, tt.wantPort)
+			}
+			if tt.wantErr != nil && len(fake.Calls("INSERT INTO shipman.voyage_ports")) != 0 {
+				t.Error("a rejected batch inserted ports")
+			}
+		})
+	}
+}
+
+func TestSetMaxVoyagePorts(t *testing.T) {
+	t.Cleanup(func() { SetMaxVoyagePorts(0) })
+	SetMaxVoyagePorts(7)
+	if MaxVoyagePorts != 7 {
+		t.Errorf("MaxVoyagePorts = %d, want 7", MaxVoyagePorts)
+	}
+	SetMaxVoyagePorts(0)
+	if MaxVoyagePorts != DefaultMaxVoyagePorts {
+		t.Errorf("MaxVoyagePorts = %d after reset, want %d", MaxVoyagePorts, DefaultMaxVoyagePorts)
+	}
+}
+
+func TestVoyagePortDeleteMissing(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("DELETE FROM shipman.voyage_ports WHERE id = $1", dbtest.Affected(0))
+	if err := NewVoyagePortRepository().Delete(context.Background(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDistinctPortNamesSearchesEveryPortTable(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("SELECT DISTINCT ON (lower(port_name)) port_name", dbtest.Rows([]string{"port_name"}))
+
+	names, err := NewVoyagePortRepository().DistinctPortNames(context.Background(), "Ro", 5)
+	if err != nil || names != nil {
+		t.Fatalf("names, err = %v, %v; want nil, nil", names, err)
+	}
+	q := fake.Calls("")[0].Query
+	for _, want := range []string{"FROM shipman.voyage_ports", "UNION", "FROM shipman.laytime_entries", `ILIKE $1 ESCAPE '\'`, "ORDER BY lower(port_name) LIMIT $2"} {
+		if !strings.Contains(q, want) {
+			t.Errorf("query lacks %q: %s", want, q)
+		}
+	}
+}
+
+func TestIsValidUNLocode(t *testing.T) {
+	tests := map[string]bool{
+		"NLRTM":  true,
+		"USHOU":  true,
+		"GB2LN":  true,
+		"nlrtm":  false,
+		"NLRT":   false,
+		"NLRTMX": false,
+		"N1RTM":  false,
+		"NLRT1":  false,
+		"NL RT":  false,
+		"":       false,
+	}
+	for code, want := range tests {
+		if got := IsValidUNLocode(code); got != want {
+			t.Errorf("IsValidUNLocode(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestVoyagePortUNLocodeCanonicalized(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		code    *string
+		want    any // the stored port_unlocode argument
+		wantErr error
+	}{
+		{"valid", str("NLRTM"), "NLRTM", nil},
+		{"lowercase", str("nlrtm"), "NLRTM", nil},
+		{"spaced", str(" nl rtm "), "NLRTM", nil},
+		{"nil", nil, nil, nil},
+		{"blank", str("  "), nil, nil},
+		{"malformed", str("NL-RTM"), nil, ErrInvalidUNLocode},
+		{"too short", str("NLR"), nil, ErrInvalidUNLocode},
+	}
+	ops := map[string]struct {
+		match string
+		run   func(*VoyagePort) error
+	}{
+		"create": {"INSERT INTO shipman.voyage_ports", func(vp *VoyagePort) error {
+			return NewVoyagePortRepository().Create(context.Background(), vp)
+		}},
+		"update": {"UPDATE shipman.voyage_ports", func(vp *VoyagePort) error {
+			return NewVoyagePortRepository().Update(context.Background(), vp)
+		}},
+	}
+	for opName, op := range ops {
+		for _, tt := range tests {
+			t.Run(opName+"/"+tt.name, func(t *testing.T) {
+				fake := newFakeDB(t)
+				var table fakePortTable
+				table.install(fake)
+				fake.Return("UPDATE shipman.voyage_ports", dbtest.Rows([]string{"updated_at"}, []any{time.Now()}))
+
+				vp := newPort(uuid.New(), "Rotterdam")
+				vp.ID = uuid.New()
+				vp.PortUNLocode = tt.code
+				err := op.run(vp)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				calls := fake.Calls(op.match)
+				if tt.wantErr != nil {
+					if len(calls) != 0 {
+						t.Error("a malformed code was written")
+					}
+					return
+				}
+				if len(calls) != 1 {
+					t.Fatalf("ran %d writes, want 1", len(calls))
+				}
+				if got := calls[0].Arg(4); got != tt.want {
+					t.Errorf("stored port_unlocode = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// upsertRow is a voyage_ports row as stored by fakeUpsertTable.
+type upsertRow struct {
+	id         uuid.UUID
+	voyageID   string
+	unlocode   any
+	portName   string
+	departedAt any
+}
+
+// fakeUpsertTable keeps voyage_ports rows so Upsert's existence check, port
+// count and ON CONFLICT target resolve against earlier writes.
+type fakeUpsertTable struct {
+	mu   sync.Mutex
+	rows []*upsertRow
+}
+
+func (p *fakeUpsertTable) install(fake *dbtest.Fake) {
+	fake.On("SELECT id FROM shipman.voyages WHERE id = $1 FOR UPDATE", func(call dbtest.Call) dbtest.Result {
+		return dbtest.Rows([]string{"id"}, []any{call.Arg(1)})
+	})
+	fake.On("SELECT COUNT(*) FROM shipman.voyage_ports WHERE voyage_id = $1", func(call dbtest.Call) dbtest.Result {
+		return dbtest.Rows([]string{"count"}, []any{len(p.find(call.Arg(1), nil))})
+	})
+	fake.On("WHERE voyage_id = $1 AND port_unlocode = $2", func(call dbtest.Call) dbtest.Result {
+		return dbtest.Rows([]string{"exists"}, []any{len(p.find(call.Arg(1), call.Arg(2))) > 0})
+	})
+	fake.On("INSERT INTO shipman.voyage_ports", func(call dbtest.Call) dbtest.Result {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if strings.Contains(call.Query, "ON CONFLICT (voyage_id, port_unlocode) DO UPDATE") {
+			for _, r := range p.rows {
+				if r.voyageID == call.Arg(1) && call.Arg(4) != nil && r.unlocode == call.Arg(4) {
+					r.portName, r.departedAt = call.Arg(2).(string), call.Arg(8)
+					return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{r.id, time.Now(), time.Now()})
+				}
+			}
+		}
+		r := &upsertRow{id: uuid.New(), voyageID: call.Arg(1).(string), unlocode: call.Arg(4), portName: call.Arg(2).(string), departedAt: call.Arg(8)}
+		p.rows = append(p.rows, r)
+		return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{r.id, time.Now(), time.Now()})
+	})
+}
+
+// find returns the rows for voyageID, restricted to unlocode unless it is nil.
+func (p *fakeUpsertTable) find(voyageID, unlocode any) []*upsertRow {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []*upsertRow
+	for _, r := range p.rows {
+		if r.voyageID == voyageID && (unlocode == nil || r.unlocode == unlocode) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestVoyagePortUpsert(t *testing.T) {
+	str := func(s string) *string { return &s }
+	ctx := context.Background()
+	repo := NewVoyagePortRepository()
+
+	t.Run("re-importing a port updates it in place", func(t *testing.T) {
+		fake := newFakeDB(t)
+		var table fakeUpsertTable
+		table.install(fake)
+		voyageID := uuid.New()
+		first, second := time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 3, 6, 0, 0, 0, time.UTC)
+
+		vp := newPort(voyageID, "Rotterdam")
+		vp.PortUNLocode, vp.DepartedAt = str("NLRTM"), &first
+		if err := repo.Upsert(ctx, vp); err != nil {
+			t.Fatal(err)
+		}
+		again := newPort(voyageID, "Port of Rotterdam")
+		again.PortUNLocode, again.DepartedAt = str(" nl rtm"), &second
+		if err := repo.Upsert(ctx, again); err != nil {
+			t.Fatal(err)
+		}
+
+		rows := table.find(voyageID.String(), nil)
+		if len(rows) != 1 {
+			t.Fatalf("rows = %d, want 1", len(rows))
+		}
+		if again.ID != vp.ID {
+			t.Errorf("second import got id %s, want the existing %s", again.ID, vp.ID)
+		}
+		if got := rows[0]; got.portName != "Port of Rotterdam" || got.departedAt != second {
+			t.Errorf("row = %+v, want the re-imported name and departure", got)
+		}
+
+		other := newPort(uuid.New(), "Rotterdam")
+		other.PortUNLocode = str("NLRTM")
+		if err := repo.Upsert(ctx, other); err != nil {
+			t.Fatal(err)
+		}
+		if other.ID == vp.ID {
+			t.Error("the same code on another voyage reused the first voyage's row")
+		}
+	})
+
+	t.Run("ports without a code are always created", func(t *testing.T) {
+		fake := newFakeDB(t)
+		var table fakeUpsertTable
+		table.install(fake)
+		voyageID := uuid.New()
+
+		for _, code := range []*string{nil, str("  ")} {
+			if err := repo.Upsert(ctx, newPort(voyageID, "Anchorage")); err != nil {
+				t.Fatal(err)
+			}
+			vp := newPort(voyageID, "Anchorage")
+			vp.PortUNLocode = code
+			if err := repo.Upsert(ctx, vp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := len(table.find(voyageID.String(), nil)); got != 4 {
+			t.Errorf("rows = %d, want 4", got)
+		}
+		for _, call := range fake.Calls("INSERT INTO shipman.voyage_ports") {
+			if strings.Contains(call.Query, "ON CONFLICT") {
+				t.Error("a port without a code went through the upsert statement")
+			}
+		}
+	})
+
+	t.Run("the port limit applies only to new rows", func(t *testing.T) {
+		SetMaxVoyagePorts(1)
+		t.Cleanup(func() { SetMaxVoyagePorts(0) })
+		fake := newFakeDB(t)
+		var table fakeUpsertTable
+		table.install(fake)
+		voyageID := uuid.New()
+
+		vp := newPort(voyageID, "Santos")
+		vp.PortUNLocode = str("BRSSZ")
+		if err := repo.Upsert(ctx, vp); err != nil {
+			t.Fatal(err)
+		}
+		again := newPort(voyageID, "Santos")
+		again.PortUNLocode = str("BRSSZ")
+		if err := repo.Upsert(ctx, again); err != nil {
+			t.Errorf("updating an existing port at the limit: %v", err)
+		}
+		extra := newPort(voyageID, "Rotterdam")
+		extra.PortUNLocode = str("NLRTM")
+		if err := repo.Upsert(ctx, extra); !errors.Is(err, ErrTooManyPorts) {
+			t.Errorf("new port past the limit: err = %v, want ErrTooManyPorts", err)
+		}
+	})
+
+	t.Run("malformed code", func(t *testing.T) {
+		fake := newFakeDB(t)
+		vp := newPort(uuid.New(), "Rotterdam")
+		vp.PortUNLocode = str("NL-RTM")
+		if err := repo.Upsert(ctx, vp); !errors.Is(err, ErrInvalidUNLocode) {
+			t.Errorf("err = %v, want ErrInvalidUNLocode", err)
+		}
+		if len(fake.Calls("")) != 0 {
+			t.Error("a malformed code reached the database")
+		}
+	})
+}
+
+// TestVoyagePortUpsertMatchesUniqueIndex keeps Upsert's ON CONFLICT target in
+// step with the unique index, without which Postgres rejects the statement.
+func TestVoyagePortUpsertMatchesUniqueIndex(t *testing.T) {
+	raw, err := os.ReadFile("../../db/migrations/000030_voyage_ports_unlocode_unique.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`CREATE UNIQUE INDEX[^;]*ON shipman\.voyage_ports\(([^)]*)\)`).FindSubmatch(raw)
+	if m == nil {
+		t.Fatal("unique index definition not found")
+	}
+
+	fake := newFakeDB(t)
+	var table fakeUpsertTable
+	table.install(fake)
+	vp := newPort(uuid.New(), "Rotterdam")
+	code := "NLRTM"
+	vp.PortUNLocode = &code
+	if err := NewVoyagePortRepository().Upsert(context.Background(), vp); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ON CONFLICT (" + string(m[1]) + ")"; !strings.Contains(fake.Calls("INSERT INTO shipman.voyage_ports")[0].Query, want) {
+		t.Errorf("upsert does not use %s", want)
+	}
+}
+
+func TestVoyagePortCoordinates(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name       string
+		lat, lon   *float64
+		wantFields []string
+	}{
+		{"valid", f(51.9), f(4.1), nil},
+		{"bounds", f(-90), f(180), nil},
+		{"unknown position", nil, nil, nil},
+		{"latitude out of range", f(90.5), f(4.1), []string{"latitude"}},
+		{"longitude out of range", f(51.9), f(-180.01), []string{"longitude"}},
+		{"both out of range", f(-91), f(200), []string{"latitude", "longitude"}},
+		{"latitude without longitude", f(51.9), nil, []string{"longitude"}},
+		{"longitude without latitude", nil, f(4.1), []string{"latitude"}},
+	}
+	ops := map[string]struct {
+		match string
+		run   func(*VoyagePort) error
+	}{
+		"create": {"INSERT INTO shipman.voyage_ports", func(vp *VoyagePort) error {
+			return NewVoyagePortRepository().Create(context.Background(), vp)
+		}},
+		"update": {"UPDATE shipman.voyage_ports", func(vp *VoyagePort) error {
+			return NewVoyagePortRepository().Update(context.Background(), vp)
+		}},
+		"upsert": {"INSERT INTO shipman.voyage_ports", func(vp *VoyagePort) error {
+			return NewVoyagePortRepository().Upsert(context.Background(), vp)
+		}},
+		"batch": {"INSERT INTO shipman.voyage_ports", func(vp *VoyagePort) error {
+			return NewVoyagePortRepository().CreateBatch(context.Background(), []*VoyagePort{newPort(vp.VoyageID, "Antwerp"), vp})
+		}},
+	}
+	for opName, op := range ops {
+		for _, tt := range tests {
+			t.Run(opName+"/"+tt.name, func(t *testing.T) {
+				fake := newFakeDB(t)
+				var table fakePortTable
+				table.install(fake)
+				fake.Return("UPDATE shipman.voyage_ports", dbtest.Rows([]string{"updated_at"}, []any{time.Now()}))
+
+				vp := newPort(uuid.New(), "Rotterdam")
+				vp.ID = uuid.New()
+				vp.Latitude, vp.Longitude = tt.lat, tt.lon
+				err := op.run(vp)
+				calls := fake.Calls(op.match)
+				if tt.wantFields == nil {
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(calls) == 0 {
+						t.Error("valid coordinates were not written")
+					}
+					return
+				}
+
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("err = %v, want a *ValidationError", err)
+				}
+				var fields []string
+				for _, fe := range verr.Errors {
+					fields = append(fields, fe.Field)
+				}
+				if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+					t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+				}
+				if opName == "batch" && !strings.HasPrefix(err.Error(), "port 1: ") {
+					t.Errorf("err = %q, want it to name the port's index", err)
+				}
+				if len(calls) != 0 {
+					t.Error("invalid coordinates were written")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// Schedule variance values reported by VoyageProgress.
+const (
+	ScheduleAhead   = "ahead"
+	ScheduleOn      = "on"
+	ScheduleBehind  = "behind"
+	ScheduleUnknown = "unknown"
+)
+
+// scheduleTolerancePct is how far distance progress may trail or lead time
+// progress, in percentage points, and still count as on schedule.
+const scheduleTolerancePct = 5.0
+
+// Progress compares how far a voyage has sailed with how much of its planned
+// duration has elapsed. Fields are nil when the data to compute them is
+// missing, in which case Schedule is "unknown".
+type Progress struct {
+	VoyageID         uuid.UUID `json:"voyage_id"`
+	DistanceLoggedNM *float64  `json:"distance_logged_nm,omitempty"`
+	DistanceNM       *float64  `json:"distance_nm,omitempty"`
+	ElapsedHours     *float64  `json:"elapsed_hours,omitempty"`
+	PlannedHours     *float64  `json:"planned_hours,omitempty"`
+	PercentComplete  *float64  `json:"percent_complete,omitempty"`
+	PercentElapsed   *float64  `json:"percent_elapsed,omitempty"`
+	Schedule         string    `json:"schedule"`
+}
+
+// VoyageProgress reports percent complete from the latest position's logged
+// distance against the voyage's distance_nm, and percent elapsed from the
+// departure (actual, else planned) to arrival or now against the planned
+// duration. Schedule is ahead or behind when the two differ by more than
+// scheduleTolerancePct. It returns sql.ErrNoRows when the voyage is missing.
+func (repo *VoyageRepository) VoyageProgress(ctx context.Context, voyageID uuid.UUID) (Progress, error) {
+	const query = `
+		SELECT
+			v.distance_nm,
+			v.planned_departure_at,
+			v.planned_arrival_at,
+			v.actual_departure_at,
+			v.actual_arrival_at,
+			(
+				SELECT sp.distance_logged_nm
+				FROM shipman.ship_positions sp
+				WHERE sp.voyage_id = v.id
+				ORDER BY sp.recorded_at DESC
+				LIMIT 1
+			)
+		FROM shipman.voyages v
+		WHERE v.id = $1
+	`
+
+	var (
+		distance, logged                       sql.NullFloat64
+		plannedDep, plannedArr, actDep, actArr sql.NullTime
+	)
+	if err := Pool.QueryRowContext(ctx, query, voyageID).Scan(
+		&distance, &plannedDep, &plannedArr, &actDep, &actArr, &logged,
+	); err != nil {
+		return Progress{}, err
+	}
+
+	p := Progress{
+		VoyageID:         voyageID,
+		DistanceLoggedNM: floatPtr(logged),
+		DistanceNM:       floatPtr(distance),
+		Schedule:         ScheduleUnknown,
+	}
+
+	if p.DistanceLoggedNM != nil && p.DistanceNM != nil && *p.DistanceNM > 0 {
+		pct := *p.DistanceLoggedNM / *p.DistanceNM * 100
+		p.PercentComplete = &pct
+	}
+
+	if plannedDep.Valid && plannedArr.Valid && plannedArr.Time.After(plannedDep.Time) {
+		planned := plannedArr.Time.Sub(plannedDep.Time).Hours()
+		p.PlannedHours = &planned
+	}
+
+	start := timePtr(actDep)
+	if start == nil {
+		start = timePtr(plannedDep)
+	}
+	if start != nil {
+		end := now()
+		if actArr.Valid {
+			end = actArr.Time
+		}
+		elapsed := end.Sub(*start).Hours()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		p.ElapsedHours = &elapsed
+	}
+
+	if p.ElapsedHours != nil && p.PlannedHours != nil {
+		pct := *p.ElapsedHours / *p.PlannedHours * 100
+		p.PercentElapsed = &pct
+	}
+
+	if p.PercentComplete != nil && p.PercentElapsed != nil {
+		p.Schedule = scheduleVariance(*p.PercentComplete, *p.PercentElapsed)
+	}
+	return p, nil
+}
+
+func scheduleVariance(complete, elapsed float64) string {
+	switch diff := complete - elapsed; {
+	case diff > scheduleTolerancePct:
+		return ScheduleAhead
+	case diff < -scheduleTolerancePct:
+		return ScheduleBehind
+	default:
+		return ScheduleOn
+	}
+}
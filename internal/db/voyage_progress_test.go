@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var progressColumns = []string{
+	"distance_nm", "planned_departure_at", "planned_arrival_at",
+	"actual_departure_at", "actual_arrival_at", "distance_logged_nm",
+}
+
+func TestVoyageProgress(t *testing.T) {
+	dep := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	arr := dep.Add(100 * time.Hour)
+	SetClock(FixedClock(dep.Add(50 * time.Hour)))
+	t.Cleanup(func() { SetClock(nil) })
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name         string
+		row          map[string]any
+		wantSchedule string
+		wantComplete *float64
+		wantElapsed  *float64
+	}{
+		{
+			name:         "ahead",
+			row:          map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 700.0, "planned_departure_at": dep, "planned_arrival_at": arr},
+			wantSchedule: ScheduleAhead, wantComplete: f(70), wantElapsed: f(50),
+		},
+		{
+			name:         "behind",
+			row:          map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 300.0, "planned_departure_at": dep, "planned_arrival_at": arr},
+			wantSchedule: ScheduleBehind, wantComplete: f(30), wantElapsed: f(50),
+		},
+		{
+			name:         "on schedule within tolerance",
+			row:          map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 540.0, "planned_departure_at": dep, "planned_arrival_at": arr},
+			wantSchedule: ScheduleOn, wantComplete: f(54), wantElapsed: f(50),
+		},
+		{
+			name: "late actual departure counts from the actual time",
+			row: map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 300.0, "planned_departure_at": dep, "planned_arrival_at": arr,
+				"actual_departure_at": dep.Add(20 * time.Hour)},
+			wantSchedule: ScheduleOn, wantComplete: f(30), wantElapsed: f(30),
+		},
+		{
+			name: "arrived voyage stops the clock",
+			row: map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 1000.0, "planned_departure_at": dep, "planned_arrival_at": arr,
+				"actual_departure_at": dep, "actual_arrival_at": dep.Add(40 * time.Hour)},
+			wantSchedule: ScheduleAhead, wantComplete: f(100), wantElapsed: f(40),
+		},
+		{
+			name:         "not yet departed",
+			row:          map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 0.0, "planned_departure_at": dep.Add(80 * time.Hour), "planned_arrival_at": arr.Add(80 * time.Hour)},
+			wantSchedule: ScheduleOn, wantComplete: f(0), wantElapsed: f(0),
+		},
+		{
+			name:         "no positions",
+			row:          map[string]any{"distance_nm": 1000.0, "planned_departure_at": dep, "planned_arrival_at": arr},
+			wantSchedule: ScheduleUnknown, wantElapsed: f(50),
+		},
+		{
+			name:         "zero distance",
+			row:          map[string]any{"distance_nm": 0.0, "distance_logged_nm": 10.0, "planned_departure_at": dep, "planned_arrival_at": arr},
+			wantSchedule: ScheduleUnknown, wantElapsed: f(50),
+		},
+		{
+			name:         "no planned arrival",
+			row:          map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 500.0, "planned_departure_at": dep},
+			wantSchedule: ScheduleUnknown, wantComplete: f(50),
+		},
+		{
+			name:         "planned arrival before departure",
+			row:          map[string]any{"distance_nm": 1000.0, "distance_logged_nm": 500.0, "planned_departure_at": arr, "planned_arrival_at": dep},
+			wantSchedule: ScheduleUnknown, wantComplete: f(50),
+		},
+		{
+			name:         "nothing recorded",
+			row:          map[string]any{},
+			wantSchedule: ScheduleUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.voyages v WHERE v.id = $1", dbtest.Rows(progressColumns, dbtest.Row(progressColumns, tt.row)))
+			voyageID := uuid.New()
+
+			got, err := NewVoyageRepository().VoyageProgress(context.Background(), voyageID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VoyageID != voyageID {
+				t.Errorf("voyage id = %s, want %s", got.VoyageID, voyageID)
+			}
+			if got.Schedule != tt.wantSchedule {
+				t.Errorf("schedule = %q, want %q", got.Schedule, tt.wantSchedule)
+			}
+			checkPct(t, "percent complete", got.PercentComplete, tt.wantComplete)
+			checkPct(t, "percent elapsed", got.PercentElapsed, tt.wantElapsed)
+		})
+	}
+}
+
+func TestVoyageProgressMissingVoyage(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("FROM shipman.voyages v WHERE v.id = $1", dbtest.Rows(progressColumns))
+	if _, err := NewVoyageRepository().VoyageProgress(context.Background(), uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func checkPct(t *testing.T, name string, got, want *float64) {
+	t.Helper()
+	switch {
+	case want == nil && got != nil:
+		t.Errorf("%s = %v, want nil", name, *got)
+	case want != nil && got == nil:
+		t.Errorf("%s = nil, want %v", name, *want)
+	case want != nil && math.Abs(*got-*want) > 1e-9:
+		t.Errorf("%s = %v, want %v", name, *got, *want)
+	}
+}
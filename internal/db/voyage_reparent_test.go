@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// voyageCharterTablesFromMigrations lists the tables, other than voyages,
+// whose CREATE TABLE declares both a voyage_id and a charter_detail_id.
+func voyageCharterTablesFromMigrations(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("../../db/migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := regexp.MustCompile(`(?i)CREATE TABLE(?: IF NOT EXISTS)?\s+(?:shipman\.)?(\w+)`)
+	column := regexp.MustCompile(`^\s*(voyage_id|charter_detail_id)\s+UUID`)
+
+	columns := map[string]map[string]bool{}
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(raw), "-- +goose Down")
+		current := ""
+		for _, line := range strings.Split(up, "\n") {
+			if m := table.FindStringSubmatch(line); m != nil {
+				current = m[1]
+				columns[current] = map[string]bool{}
+			}
+			if m := column.FindStringSubmatch(line); m != nil && current != "" {
+				columns[current][m[1]] = true
+			}
+		}
+	}
+	var out []string
+	for name, cols := range columns {
+		if name != "voyages" && cols["voyage_id"] && cols["charter_detail_id"] {
+			out = append(out, name)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestVoyageCharterTablesMatchMigrations(t *testing.T) {
+	want := voyageCharterTablesFromMigrations(t)
+	got := slices.Sorted(slices.Values(voyageCharterTables))
+	if !slices.Equal(got, want) {
+		t.Errorf("voyageCharterTables = %v, migrations declare %v", got, want)
+	}
+}
+
+func TestVoyageReparent(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	target := uuid.New()
+
+	tests := []struct {
+		name      string
+		ids       []uuid.UUID
+		charter   bool
+		moved     int64
+		wantErr   error
+		wantMoved int64
+	}{
+		{"moves voyages and their rows", []uuid.UUID{a, b}, true, 2, nil, 2},
+		{"duplicate ids counted once", []uuid.UUID{a, a, b}, true, 2, nil, 2},
+		{"unknown voyage writes nothing", []uuid.UUID{a, b}, true, 1, ErrNotFound, 0},
+		{"unknown charter", []uuid.UUID{a}, false, 0, sql.ErrNoRows, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			lock := dbtest.Rows([]string{"exists"})
+			if tt.charter {
+				lock = dbtest.Rows([]string{"exists"}, []any{true})
+			}
+			fake.Return("FROM shipman.charter_details WHERE id = $1 FOR SHARE", lock)
+			fake.Return("UPDATE shipman.voyages SET charter_detail_id = $2", dbtest.Affected(tt.moved))
+			for _, table := range voyageCharterTables {
+				fake.Return("UPDATE shipman."+table+" SET charter_detail_id = $2", dbtest.Affected(3))
+			}
+
+			moved, err := NewVoyageRepository().Reparent(context.Background(), tt.ids, target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if moved != tt.wantMoved {
+				t.Errorf("moved = %d, want %d", moved, tt.wantMoved)
+			}
+
+			voyages := fake.Calls("UPDATE shipman.voyages SET charter_detail_id = $2")
+			for _, table := range voyageCharterTables {
+				calls := fake.Calls("UPDATE shipman." + table + " SET charter_detail_id = $2")
+				if tt.wantErr != nil {
+					if len(calls) != 0 {
+						t.Errorf("%s updated after a failed reparent", table)
+					}
+					continue
+				}
+				if len(calls) != 1 {
+					t.Fatalf("%s updated %d times, want 1", table, len(calls))
+				}
+				if calls[0].Tx == nil || calls[0].Tx != voyages[0].Tx {
+					t.Errorf("%s not updated in the voyages transaction", table)
+				}
+				if ids := calls[0].Arg(1).([]uuid.UUID); len(ids) != len(voyages[0].Arg(1).([]uuid.UUID)) {
+					t.Errorf("%s ids = %v, want the voyage ids", table, ids)
+				}
+				if got := calls[0].Arg(2); got != target.String() {
+					t.Errorf("%s charter = %v, want %s", table, got, target)
+				}
+			}
+			if tt.wantErr == nil {
+				if ids := voyages[0].Arg(1).([]uuid.UUID); len(ids) != 2 {
+					t.Errorf("voyage ids = %v, want 2 unique ids", ids)
+				}
+				if fake.Commits() != 1 {
+					t.Errorf("commits = %d, want 1", fake.Commits())
+				}
+			} else if fake.Rollbacks() != 1 {
+				t.Errorf("rollbacks = %d, want 1", fake.Rollbacks())
+			}
+		})
+	}
+}
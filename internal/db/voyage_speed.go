@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// SpeedReport reconciles a voyage's logged distance against its plan.
+// Pointer fields are nil when the data to compute them is missing.
+type SpeedReport struct {
+	VoyageID            uuid.UUID `json:"voyage_id"`
+	PositionCount       int       `json:"position_count"`
+	SpeedSamples        int       `json:"speed_samples"` // positions reporting a speed
+	AverageSpeedKnots   *float64  `json:"average_speed_knots,omitempty"`
+	DistanceLoggedNM    *float64  `json:"distance_logged_nm,omitempty"`
+	DistanceNM          *float64  `json:"distance_nm,omitempty"`
+	DistanceVariancePct *float64  `json:"distance_variance_pct,omitempty"` // positive = sailed further than planned
+}
+
+// VoyageSpeedReport averages speed over ground across the voyage's positions
+// that report one, and compares the logged distance at the latest position
+// with the voyage's planned distance_nm. It returns sql.ErrNoRows when the
+// voyage is missing.
+func (repo *VoyageRepository) VoyageSpeedReport(ctx context.Context, voyageID uuid.UUID) (SpeedReport, error) {
+	const query = `
+		SELECT
+			v.distance_nm,
+			(SELECT COUNT(*) FROM shipman.ship_positions sp WHERE sp.voyage_id = v.id),
+			(SELECT COUNT(sp.speed_knots) FROM shipman.ship_positions sp WHERE sp.voyage_id = v.id),
+			(SELECT AVG(sp.speed_knots) FROM shipman.ship_positions sp WHERE sp.voyage_id = v.id),
+			(
+				SELECT sp.distance_logged_nm
+				FROM shipman.ship_positions sp
+				WHERE sp.voyage_id = v.id
+				  AND sp.distance_logged_nm IS NOT NULL
+				ORDER BY sp.recorded_at DESC
+				LIMIT 1
+			)
+		FROM shipman.voyages v
+		WHERE v.id = $1
+	`
+
+	var distance, avgSpeed, logged sql.NullFloat64
+	r := SpeedReport{VoyageID: voyageID}
+	if err := Pool.QueryRowContext(ctx, query, voyageID).Scan(
+		&distance, &r.PositionCount, &r.SpeedSamples, &avgSpeed, &logged,
+	); err != nil {
+		return SpeedReport{}, err
+	}
+
+	r.AverageSpeedKnots = floatPtr(avgSpeed)
+	r.DistanceLoggedNM = floatPtr(logged)
+	r.DistanceNM = floatPtr(distance)
+	if r.DistanceLoggedNM != nil && r.DistanceNM != nil && *r.DistanceNM > 0 {
+		pct := (*r.DistanceLoggedNM - *r.DistanceNM) / *r.DistanceNM * 100
+		r.DistanceVariancePct = &pct
+	}
+	return r, nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var speedColumns = []string{"distance_nm", "position_count", "speed_samples", "avg_speed", "distance_logged_nm"}
+
+func TestVoyageSpeedReport(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name         string
+		row          []any
+		wantSamples  int
+		wantSpeed    *float64
+		wantLogged   *float64
+		wantPlanned  *float64
+		wantVariance *float64
+	}{
+		{
+			name:        "track with speeds",
+			row:         []any{1000.0, 48, 48, 12.5, 1100.0},
+			wantSamples: 48, wantSpeed: f(12.5), wantLogged: f(1100), wantPlanned: f(1000), wantVariance: f(10),
+		},
+		{
+			name:        "short of plan",
+			row:         []any{1000.0, 10, 6, 9.0, 950.0},
+			wantSamples: 6, wantSpeed: f(9), wantLogged: f(950), wantPlanned: f(1000), wantVariance: f(-5),
+		},
+		{
+			name:       "missing speed data",
+			row:        []any{1000.0, 12, 0, nil, 400.0},
+			wantLogged: f(400), wantPlanned: f(1000), wantVariance: f(-60),
+		},
+		{
+			name:        "no planned distance",
+			row:         []any{nil, 5, 5, 11.0, 300.0},
+			wantSamples: 5, wantSpeed: f(11), wantLogged: f(300),
+		},
+		{
+			name:        "zero planned distance",
+			row:         []any{0.0, 5, 5, 11.0, 300.0},
+			wantSamples: 5, wantSpeed: f(11), wantLogged: f(300), wantPlanned: f(0),
+		},
+		{
+			name:        "no positions",
+			row:         []any{1000.0, 0, 0, nil, nil},
+			wantPlanned: f(1000),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.voyages v WHERE v.id = $1", dbtest.Rows(speedColumns, tt.row))
+			voyageID := uuid.New()
+
+			got, err := NewVoyageRepository().VoyageSpeedReport(context.Background(), voyageID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VoyageID != voyageID {
+				t.Errorf("voyage id = %s, want %s", got.VoyageID, voyageID)
+			}
+			if got.SpeedSamples != tt.wantSamples {
+				t.Errorf("speed samples = %d, want %d", got.SpeedSamples, tt.wantSamples)
+			}
+			checkPct(t, "average speed", got.AverageSpeedKnots, tt.wantSpeed)
+			checkPct(t, "logged distance", got.DistanceLoggedNM, tt.wantLogged)
+			checkPct(t, "planned distance", got.DistanceNM, tt.wantPlanned)
+			checkPct(t, "variance", got.DistanceVariancePct, tt.wantVariance)
+			if calls := fake.Calls("FROM shipman.voyages v"); len(calls) != 1 || calls[0].Arg(1) != voyageID.String() {
+				t.Errorf("calls = %+v, want one for the voyage", calls)
+			}
+		})
+	}
+}
+
+func TestVoyageSpeedReportMissingVoyage(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("FROM shipman.voyages v WHERE v.id = $1", dbtest.Rows(speedColumns))
+	if _, err := NewVoyageRepository().VoyageSpeedReport(context.Background(), uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("err = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// staleVoyage is a voyages row and the times of its positions.
+type staleVoyage struct {
+	row       map[string]any
+	positions []time.Time
+}
+
+// stubListStale answers ListStale from voyages: departed, unarrived,
+// unarchived voyages in the caller's org without a position at or after $1,
+// in the statement's order.
+func stubListStale(t *testing.T, fake *dbtest.Fake, voyages []staleVoyage) {
+	fake.On("SELECT v.id FROM shipman.voyages v WHERE v.actual_departure_at IS NOT NULL", func(call dbtest.Call) dbtest.Result {
+		cutoff, org := call.Arg(1).(time.Time), orgArg(call)
+		var out [][]any
+		for _, v := range voyages {
+			if v.row["actual_departure_at"] == nil || v.row["actual_arrival_at"] != nil || v.row["archived_at"] != nil || (org != "" && v.row["org_id"] != org) {
+				continue
+			}
+			if slices.ContainsFunc(v.positions, func(at time.Time) bool { return !at.Before(cutoff) }) {
+				continue
+			}
+			out = append(out, []any{v.row["id"], v.row["actual_departure_at"]})
+		}
+		sortLikeQuery(t, call.Query, []string{"id", "actual_departure_at"}, out)
+		for i, r := range out {
+			out[i] = r[:1]
+		}
+		return dbtest.Rows([]string{"id"}, out...)
+	})
+	fake.On("archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		for _, v := range voyages {
+			if v.row["id"] == call.Arg(1) {
+				return dbtest.Rows(voyageColumns, dbtest.Row(voyageColumns, v.row))
+			}
+		}
+		return dbtest.Rows(voyageColumns)
+	})
+}
+
+func TestVoyageListStale(t *testing.T) {
+	cutoff := time.Date(2026, 6, 10, 0, 0, 0, 0, time.UTC)
+	voyage := func(number string, departed, arrived, archived any, org uuid.UUID, positions ...time.Time) staleVoyage {
+		return staleVoyage{row: map[string]any{
+			"id": uuid.NewString(), "org_id": org.String(), "voyage_number": number, "status": "in_progress",
+			"demurrage_currency": "USD", "actual_departure_at": departed, "actual_arrival_at": arrived,
+			"archived_at": archived, "created_at": cutoff, "updated_at": cutoff,
+		}, positions: positions}
+	}
+	otherOrg := uuid.New()
+	voyages := []staleVoyage{
+		voyage("reporting", cutoff.AddDate(0, 0, -9), nil, nil, DefaultOrgID, cutoff.AddDate(0, 0, -5), cutoff.Add(2*time.Hour)),
+		voyage("quiet", cutoff.AddDate(0, 0, -4), nil, nil, DefaultOrgID, cutoff.AddDate(0, 0, -3), cutoff.Add(-time.Minute)),
+		voyage("silent", cutoff.AddDate(0, 0, -8), nil, nil, DefaultOrgID),
+		voyage("on the cutoff", cutoff.AddDate(0, 0, -6), nil, nil, DefaultOrgID, cutoff),
+		voyage("arrived", cutoff.AddDate(0, 0, -20), cutoff.AddDate(0, 0, -2), nil, DefaultOrgID),
+		voyage("archived", cutoff.AddDate(0, 0, -20), nil, cutoff.AddDate(0, 0, -1), DefaultOrgID),
+		voyage("not sailed", nil, nil, nil, DefaultOrgID),
+		voyage("other org", cutoff.AddDate(0, 0, -30), nil, nil, otherOrg),
+	}
+	fake := newFakeDB(t)
+	stubListStale(t, fake, voyages)
+	repo := NewVoyageRepository()
+
+	numbers := func(ctx context.Context) []string {
+		t.Helper()
+		got, err := repo.ListStale(ctx, cutoff)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]string, len(got))
+		for i, v := range got {
+			out[i] = *v.VoyageNumber
+		}
+		return out
+	}
+	if got, want := numbers(WithOrg(context.Background(), DefaultOrgID)), []string{"silent", "quiet"}; !slices.Equal(got, want) {
+		t.Errorf("stale voyages = %v, want %v longest running first", got, want)
+	}
+	if got, want := numbers(context.Background()), []string{"other org", "silent", "quiet"}; !slices.Equal(got, want) {
+		t.Errorf("unscoped stale voyages = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+	"shipman/internal/metrics"
+
+	"github.com/google/uuid"
+)
+
+// fakeVoyageRow is a voyage status row behind a simulated FOR UPDATE lock:
+// the lock query blocks until the transaction holding the row ends.
+type fakeVoyageRow struct {
+	lock      sync.Mutex
+	mu        sync.Mutex
+	status    string
+	departed  *time.Time
+	updatedAt time.Time
+}
+
+func (r *fakeVoyageRow) install(fake *dbtest.Fake) {
+	cols := []string{"status", "actual_departure_at", "actual_arrival_at", "updated_at"}
+	fake.On("FROM shipman.voyages WHERE id = $1 FOR UPDATE", func(call dbtest.Call) dbtest.Result {
+		r.lock.Lock()
+		call.Tx.OnEnd(func(bool) { r.lock.Unlock() })
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return dbtest.Rows(cols, []any{r.status, r.departed, nil, r.updatedAt})
+	})
+	fake.On("SET status = 'sailing', actual_departure_at = $2", func(call dbtest.Call) dbtest.Result {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		at := call.Arg(2).(time.Time)
+		r.status, r.departed, r.updatedAt = "sailing", &at, at
+		return dbtest.Rows(cols, []any{r.status, r.departed, nil, r.updatedAt})
+	})
+}
+
+func TestVoyageDepartConcurrent(t *testing.T) {
+	fake := newFakeDB(t)
+	row := &fakeVoyageRow{status: "planned", updatedAt: time.Now()}
+	row.install(fake)
+
+	id := uuid.New()
+	times := []time.Time{
+		time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC),
+	}
+	states := make([]VoyageState, len(times))
+	errs := make([]error, len(times))
+	var wg sync.WaitGroup
+	for i, at := range times {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			states[i], errs[i] = NewVoyageRepository().Depart(context.Background(), id, at)
+		}()
+	}
+	wg.Wait()
+
+	var won, lost int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+			if states[i].ActualDeparture == nil || !states[i].ActualDeparture.Equal(times[i]) {
+				t.Errorf("winner departure = %v, want %v", states[i].ActualDeparture, times[i])
+			}
+		case errors.Is(err, ErrInvalidTransition):
+			lost++
+			// The loser sees what the winner wrote.
+			if states[i].Status != "sailing" || states[i].ActualDeparture == nil || !states[i].ActualDeparture.Equal(*row.departed) {
+				t.Errorf("loser state = %+v, want the winner's departure %v", states[i], *row.departed)
+			}
+		default:
+			t.Fatalf("Depart: %v", err)
+		}
+	}
+	if won != 1 || lost != 1 {
+		t.Errorf("won %d, lost %d; want exactly one departure", won, lost)
+	}
+	if n := len(fake.Calls("SET status = 'sailing'")); n != 1 {
+		t.Errorf("departure written %d times, want 1", n)
+	}
+}
+
+func TestVoyageTransitionJoinsOuterTx(t *testing.T) {
+	fake := newFakeDB(t)
+	row := &fakeVoyageRow{status: "planned", updatedAt: time.Now()}
+	row.install(fake)
+
+	errRollback := errors.New("roll back")
+	err := WithTx(context.Background(), func(ctx context.Context) error {
+		if _, err := NewVoyageRepository().Depart(ctx, uuid.New(), time.Now()); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want the outer error", err)
+	}
+	if fake.Commits() != 0 || fake.Rollbacks() != 1 {
+		t.Errorf("commits = %d, rollbacks = %d; want the outer transaction rolled back only", fake.Commits(), fake.Rollbacks())
+	}
+	calls := fake.Calls("shipman.voyages")
+	if len(calls) != 2 || calls[0].Tx == nil || calls[0].Tx != calls[1].Tx {
+		t.Errorf("transition did not run in the outer transaction: %v", calls)
+	}
+}
+
+// departedCount scrapes the voyages departed counter.
+func departedCount(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if v, ok := strings.CutPrefix(line, "shipman_voyages_departed_total "); ok {
+			return v
+		}
+	}
+	t.Fatalf("voyages departed counter not exported: %s", w.Body.String())
+	return ""
+}
+
+func TestVoyageDepartCountsMetric(t *testing.T) {
+	metrics.Enable()
+	fake := newFakeDB(t)
+	row := &fakeVoyageRow{status: "planned", updatedAt: time.Now()}
+	row.install(fake)
+	repo := NewVoyageRepository()
+	id := uuid.New()
+
+	if _, err := repo.Depart(context.Background(), id, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := departedCount(t); got != "1" {
+		t.Errorf("departed = %s after departing, want 1", got)
+	}
+
+	// A rejected transition is not a departure.
+	if _, err := repo.Depart(context.Background(), id, time.Now()); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("second Depart = %v, want ErrInvalidTransition", err)
+	}
+	if got := departedCount(t); got != "1" {
+		t.Errorf("departed = %s after a rejected departure, want 1", got)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Utilization summarises how much of a window a vessel spent on voyages.
+type Utilization struct {
+	VesselName     string    `json:"vessel_name"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	VoyageCount    int       `json:"voyage_count"`
+	DaysAtSea      float64   `json:"days_at_sea"`
+	UtilizationPct float64   `json:"utilization_pct"`
+}
+
+// UtilizationByVessel reports the time between from and to that the named
+// vessel (matched case-insensitively) spent on voyages. Each voyage spans its
+// actual departure and arrival, falling back to the planned times; a voyage
+// that has departed but not arrived is treated as at sea until to. Voyages
+// with no usable span are skipped, and overlapping voyages are merged so no
+// day is counted twice.
+func (repo *VoyageRepository) UtilizationByVessel(ctx context.Context, vesselName string, from, to time.Time) (Utilization, error) {
+	out := Utilization{VesselName: vesselName, From: from, To: to}
+	if !to.After(from) {
+		return out, nil
+	}
+
+	const query = `
+		SELECT
+			COALESCE(actual_departure_at, planned_departure_at),
+			CASE
+				WHEN actual_arrival_at IS NOT NULL THEN actual_arrival_at
+				WHEN actual_departure_at IS NOT NULL THEN $3
+				ELSE planned_arrival_at
+			END
+		FROM shipman.voyages
+		WHERE lower(trim(vessel_name)) = lower(trim($1))
+		  AND COALESCE(actual_departure_at, planned_departure_at) < $3
+		  AND COALESCE(actual_arrival_at, CASE WHEN actual_departure_at IS NOT NULL THEN $3 END, planned_arrival_at) > $2
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, vesselName, from, to)
+	if err != nil {
+		return Utilization{}, err
+	}
+	defer rows.Close()
+
+	type span struct{ start, end time.Time }
+	var spans []span
+	for rows.Next() {
+		var start, end sql.NullTime
+		if err := rows.Scan(&start, &end); err != nil {
+			return Utilization{}, err
+		}
+		if !start.Valid || !end.Valid {
+			continue
+		}
+		s, e := start.Time, end.Time
+		if s.Before(from) {
+			s = from
+		}
+		if e.After(to) {
+			e = to
+		}
+		if !e.After(s) {
+			continue
+		}
+		spans = append(spans, span{s, e})
+	}
+	if err := rows.Err(); err != nil {
+		return Utilization{}, err
+	}
+
+	out.VoyageCount = len(spans)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	var (
+		atSea time.Duration
+		cur   span
+	)
+	for i, sp := range spans {
+		switch {
+		case i == 0:
+			cur = sp
+		case !sp.start.After(cur.end):
+			if sp.end.After(cur.end) {
+				cur.end = sp.end
+			}
+		default:
+			atSea += cur.end.Sub(cur.start)
+			cur = sp
+		}
+	}
+	if len(spans) > 0 {
+		atSea += cur.end.Sub(cur.start)
+	}
+
+	out.DaysAtSea = atSea.Hours() / 24
+	out.UtilizationPct = atSea.Hours() / to.Sub(from).Hours() * 100
+	return out, nil
+}
+
+// VesselBusy reports whether any voyage of the named vessel (matched
+// case-insensitively) overlaps [from, to), returning the conflicting voyages
+// ordered by departure. A voyage occupies the vessel from its actual, else
+// planned, departure until its actual arrival; one that has departed but not
+// arrived, or has no arrival at all, is busy from departure onward. A voyage
+// ending exactly at from does not conflict, and voyages with no departure
+// time are ignored.
+func (repo *VoyageRepository) VesselBusy(ctx context.Context, vesselName string, from, to time.Time) (bool, []Voyage, error) {
+	if !to.After(from) {
+		return false, nil, nil
+	}
+
+	const query = `
+		SELECT id
+		FROM shipman.voyages
+		WHERE lower(trim(vessel_name)) = lower(trim($1))
+		  AND COALESCE(actual_departure_at, planned_departure_at) < $3
+		  AND (
+			(actual_arrival_at IS NULL AND (actual_departure_at IS NOT NULL OR planned_arrival_at IS NULL))
+			OR COALESCE(actual_arrival_at, planned_arrival_at) > $2
+		  )
+		ORDER BY COALESCE(actual_departure_at, planned_departure_at), id
+	`
+
+	rows, err := Pool.QueryContext(ctx, query, vesselName, from, to)
+	if err != nil {
+		return false, nil, err
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return false, nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, nil, err
+	}
+
+	conflicts := make([]Voyage, 0, len(ids))
+	for _, id := range ids {
+		v, err := repo.Retrieve(ctx, id)
+		if err != nil {
+			return false, nil, err
+		}
+		conflicts = append(conflicts, v)
+	}
+	return len(conflicts) > 0, conflicts, nil
+}
@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// utilVoyage is the slice of a voyages row UtilizationByVessel reads.
+type utilVoyage struct {
+	vessel                           string
+	plannedDeparture, plannedArrival *time.Time
+	actualDeparture, actualArrival   *time.Time
+}
+
+// fakeUtilization answers the utilization query from voyages, applying the
+// statement's vessel match, span rules and window filter.
+func fakeUtilization(fake *dbtest.Fake, voyages []utilVoyage) {
+	fake.On("FROM shipman.voyages WHERE lower(trim(vessel_name)) = lower(trim($1))", func(call dbtest.Call) dbtest.Result {
+		vessel := strings.ToLower(strings.TrimSpace(call.Arg(1).(string)))
+		from, to := call.Arg(2).(time.Time), call.Arg(3).(time.Time)
+		var rows [][]any
+		for _, v := range voyages {
+			if strings.ToLower(strings.TrimSpace(v.vessel)) != vessel {
+				continue
+			}
+			start := v.actualDeparture
+			if start == nil {
+				start = v.plannedDeparture
+			}
+			end := v.plannedArrival
+			switch {
+			case v.actualArrival != nil:
+				end = v.actualArrival
+			case v.actualDeparture != nil:
+				end = &to
+			}
+			if start == nil || !start.Before(to) || end == nil || !end.After(from) {
+				continue
+			}
+			rows = append(rows, []any{*start, *end})
+		}
+		return dbtest.Rows([]string{"start", "end"}, rows...)
+	})
+}
+
+func TestVoyageUtilizationByVessel(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	day := func(d int) *time.Time {
+		t := from.AddDate(0, 0, d)
+		return &t
+	}
+
+	tests := []struct {
+		name       string
+		voyages    []utilVoyage
+		wantCount  int
+		wantDays   float64
+		wantQuery  bool
+		windowFrom time.Time
+	}{
+		{
+			name: "voyages outside the window",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(-20), actualArrival: day(-5)},
+				{vessel: "Ocean Star", plannedDeparture: day(35), plannedArrival: day(40)},
+			},
+			wantQuery: true,
+		},
+		{
+			name: "separate voyages add up",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(1), actualArrival: day(4)},
+				{vessel: "ocean star ", plannedDeparture: day(10), plannedArrival: day(12)},
+				{vessel: "Sea Breeze", actualDeparture: day(1), actualArrival: day(20)},
+			},
+			wantCount: 2, wantDays: 5, wantQuery: true,
+		},
+		{
+			name: "overlapping voyages are counted once",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(2), actualArrival: day(8)},
+				{vessel: "Ocean Star", actualDeparture: day(5), actualArrival: day(12)},
+				{vessel: "Ocean Star", actualDeparture: day(6), actualArrival: day(7)},
+			},
+			wantCount: 3, wantDays: 10, wantQuery: true,
+		},
+		{
+			name: "voyages straddling the window are clamped",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(-3), actualArrival: day(2)},
+				{vessel: "Ocean Star", plannedDeparture: day(28), plannedArrival: day(33)},
+			},
+			wantCount: 2, wantDays: 4, wantQuery: true,
+		},
+		{
+			name: "open voyage runs to the end of the window",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(20), plannedArrival: day(22)},
+			},
+			wantCount: 1, wantDays: 10, wantQuery: true,
+		},
+		{
+			name: "actual times win over planned",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", plannedDeparture: day(1), plannedArrival: day(3), actualDeparture: day(2), actualArrival: day(6)},
+			},
+			wantCount: 1, wantDays: 4, wantQuery: true,
+		},
+		{
+			name: "voyages without a span are skipped",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", plannedArrival: day(3)},
+				{vessel: "Ocean Star", plannedDeparture: day(3)},
+			},
+			wantQuery: true,
+		},
+		{
+			name:       "empty window",
+			voyages:    []utilVoyage{{vessel: "Ocean Star", actualDeparture: day(1), actualArrival: day(4)}},
+			windowFrom: to,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fakeUtilization(fake, tt.voyages)
+			windowFrom := from
+			if !tt.windowFrom.IsZero() {
+				windowFrom = tt.windowFrom
+			}
+
+			got, err := NewVoyageRepository().UtilizationByVessel(context.Background(), "Ocean Star", windowFrom, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VesselName != "Ocean Star" || !got.From.Equal(windowFrom) || !got.To.Equal(to) {
+				t.Errorf("window = %s %s..%s, want Ocean Star %s..%s", got.VesselName, got.From, got.To, windowFrom, to)
+			}
+			if got.VoyageCount != tt.wantCount {
+				t.Errorf("voyage count = %d, want %d", got.VoyageCount, tt.wantCount)
+			}
+			if math.Abs(got.DaysAtSea-tt.wantDays) > 1e-9 {
+				t.Errorf("days at sea = %v, want %v", got.DaysAtSea, tt.wantDays)
+			}
+			if tt.wantQuery {
+				if want := tt.wantDays / 30 * 100; math.Abs(got.UtilizationPct-want) > 1e-9 {
+					t.Errorf("utilization = %v%%, want %v%%", got.UtilizationPct, want)
+				}
+			}
+			if n := len(fake.Calls("FROM shipman.voyages")); (n == 1) != tt.wantQuery {
+				t.Errorf("queries = %d, want query %v", n, tt.wantQuery)
+			}
+		})
+	}
+}
+
+// fakeVesselBusy answers VesselBusy's overlap query and the follow-up
+// Retrieve calls from voyages, keyed by id.
+func fakeVesselBusy(fake *dbtest.Fake, voyages map[uuid.UUID]utilVoyage) {
+	fake.On("SELECT id FROM shipman.voyages WHERE lower(trim(vessel_name)) = lower(trim($1))", func(call dbtest.Call) dbtest.Result {
+		vessel := strings.ToLower(strings.TrimSpace(call.Arg(1).(string)))
+		from, to := call.Arg(2).(time.Time), call.Arg(3).(time.Time)
+		type match struct {
+			id    uuid.UUID
+			start time.Time
+		}
+		var matches []match
+		for id, v := range voyages {
+			if strings.ToLower(strings.TrimSpace(v.vessel)) != vessel {
+				continue
+			}
+			start := v.actualDeparture
+			if start == nil {
+				start = v.plannedDeparture
+			}
+			if start == nil || !start.Before(to) {
+				continue
+			}
+			end := v.actualArrival
+			if end == nil {
+				end = v.plannedArrival
+			}
+			open := v.actualArrival == nil && (v.actualDeparture != nil || v.plannedArrival == nil)
+			if !open && (end == nil || !end.After(from)) {
+				continue
+			}
+			matches = append(matches, match{id, *start})
+		}
+		sort.Slice(matches, func(i, j int) bool { return matches[i].start.Before(matches[j].start) })
+		var rows [][]any
+		for _, m := range matches {
+			rows = append(rows, []any{m.id})
+		}
+		return dbtest.Rows([]string{"id"}, rows...)
+	})
+	fake.On("archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		id := uuid.MustParse(call.Arg(1).(string))
+		v := voyages[id]
+		return dbtest.Rows(voyageColumns, dbtest.Row(voyageColumns, map[string]any{
+			"id": id, "org_id": DefaultOrgID, "vessel_name": v.vessel, "status": "planned", "demurrage_currency": "USD",
+			"created_at": time.Now(), "updated_at": time.Now(),
+		}))
+	})
+}
+
+func TestVesselBusy(t *testing.T) {
+	from := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC)
+	day := func(d int) *time.Time {
+		t := from.AddDate(0, 0, d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		voyages   []utilVoyage
+		wantBusy  []int // indexes into voyages, in departure order
+		wantQuery bool
+		windowTo  time.Time
+	}{
+		{
+			name: "overlapping",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(-5), actualArrival: day(2)},
+				{vessel: "ocean star ", plannedDeparture: day(8), plannedArrival: day(15)},
+				{vessel: "Ocean Star", plannedDeparture: day(3), plannedArrival: day(4)},
+			},
+			wantBusy: []int{0, 2, 1}, wantQuery: true,
+		},
+		{
+			name: "adjacent",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(-5), actualArrival: day(0)},
+				{vessel: "Ocean Star", plannedDeparture: day(10), plannedArrival: day(15)},
+			},
+			wantQuery: true,
+		},
+		{
+			name: "free",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(-20), actualArrival: day(-10)},
+				{vessel: "Sea Breeze", actualDeparture: day(1), actualArrival: day(5)},
+				{vessel: "Ocean Star", plannedArrival: day(5)},
+			},
+			wantQuery: true,
+		},
+		{
+			name: "open voyage is busy from departure onward",
+			voyages: []utilVoyage{
+				{vessel: "Ocean Star", actualDeparture: day(-30), plannedArrival: day(-20)},
+				{vessel: "Ocean Star", plannedDeparture: day(-40)},
+			},
+			wantBusy: []int{1, 0}, wantQuery: true,
+		},
+		{
+			name:     "empty window",
+			voyages:  []utilVoyage{{vessel: "Ocean Star", actualDeparture: day(1), actualArrival: day(4)}},
+			windowTo: from,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			ids := make([]uuid.UUID, len(tt.voyages))
+			byID := map[uuid.UUID]utilVoyage{}
+			for i, v := range tt.voyages {
+				ids[i] = uuid.New()
+				byID[ids[i]] = v
+			}
+			fakeVesselBusy(fake, byID)
+			windowTo := to
+			if !tt.windowTo.IsZero() {
+				windowTo = tt.windowTo
+			}
+
+			busy, conflicts, err := NewVoyageRepository().VesselBusy(context.Background(), "Ocean Star", from, windowTo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([]uuid.UUID, len(tt.wantBusy))
+			for i, n := range tt.wantBusy {
+				want[i] = ids[n]
+			}
+			if got := voyageIDs(conflicts); !slices.Equal(got, want) {
+				t.Errorf("conflicts = %v, want %v", got, want)
+			}
+			if busy != (len(want) > 0) {
+				t.Errorf("busy = %v with %d conflicts", busy, len(want))
+			}
+			if n := len(fake.Calls("SELECT id FROM shipman.voyages")); (n == 1) != tt.wantQuery {
+				t.Errorf("queries = %d, want query %v", n, tt.wantQuery)
+			}
+		})
+	}
+}
@@ -5,14 +5,18 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"time"
 
+	"shipman/internal/metrics"
+
 	"github.com/google/uuid"
 )
 
 // Voyage mirrors shipman.voyages.
 type Voyage struct {
 	ID                  uuid.UUID  `json:"id"`
+	OrgID               uuid.UUID  `json:"org_id"`
 	CharterDetailID     *uuid.UUID `json:"charter_detail_id,omitempty"`
 	DealID              *uuid.UUID `json:"deal_id,omitempty"`
 	OwnerUserID         *uuid.UUID `json:"owner_user_id,omitempty"`
@@ -62,6 +66,7 @@ type Voyage struct {
 	CharterType         *string    `json:"charter_type,omitempty"`
 	Status              string     `json:"status"`
 	Notes               *string    `json:"notes,omitempty"`
+	ArchivedAt          *time.Time `json:"archived_at,omitempty"`
 	CreatedAt           time.Time  `json:"created_at"`
 	UpdatedAt           time.Time  `json:"updated_at"`
 }
@@ -86,6 +91,11 @@ func NewVoyageRepository() *VoyageRepository {
 }
 
 func (repo *VoyageRepository) Create(ctx context.Context, v *Voyage) error {
+	clearServerFields(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	v.Normalize()
+	if err := sanitizeNotes(notesField{"notes", v.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		INSERT INTO shipman.voyages (
 			charter_detail_id, deal_id, owner_user_id,
@@ -97,17 +107,23 @@ func (repo *VoyageRepository) Create(ctx context.Context, v *Voyage) error {
 			payment_frequency, first_payment_date, total_contract_value,
 			commission_rate, bunker_cost, port_costs, insurance_cost,
 			counterparty_name, counterparty_email,
-			charter_type, status, notes
+			charter_type, status, notes, org_id
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
 			$12, $13, $14, $15, $16, $17, $18, $19, $20,
 			COALESCE($21, 'USD'),
 			$22, $23, $24, $25, $26, $27, $28, $29, $30,
-			$31, COALESCE($32, 'planned'), $33
+			$31, COALESCE($32, 'planned'), $33,
+			COALESCE($34::uuid, (SELECT org_id FROM shipman.charter_details WHERE id = $1), $35)
 		)
-		RETURNING id, status, demurrage_currency, created_at, updated_at
+		RETURNING id, org_id, status, demurrage_currency, created_at, updated_at
 	`
-	return Pool.QueryRowContext(ctx, query,
+	// Unscoped callers without an explicit org inherit the charter's.
+	org := orgFilter(ctx)
+	if org == nil && v.OrgID != uuid.Nil {
+		org = v.OrgID
+	}
+	return Conn(ctx).QueryRowContext(ctx, query,
 		nullableUUID(v.CharterDetailID),
 		nullableUUID(v.DealID),
 		nullableUUID(v.OwnerUserID),
@@ -141,7 +157,9 @@ func (repo *VoyageRepository) Create(ctx context.Context, v *Voyage) error {
 		nullableString(v.CharterType),
 		nullableString(&v.Status),
 		nullableString(v.Notes),
-	).Scan(&v.ID, &v.Status, &v.DemurrageCurrency, &v.CreatedAt, &v.UpdatedAt)
+		org,
+		DefaultOrgID,
+	).Scan(&v.ID, &v.OrgID, &v.Status, &v.DemurrageCurrency, &v.CreatedAt, &v.UpdatedAt)
 }
 
 func (repo *VoyageRepository) AttachDocument(ctx context.Context, voyageID, documentID uuid.UUID) error {
@@ -150,10 +168,12 @@ func (repo *VoyageRepository) AttachDocument(ctx context.Context, voyageID, docu
 	return err
 }
 
+// Retrieve fetches a voyage. A voyage outside the org ctx is scoped to is
+// reported as sql.ErrNoRows.
 func (repo *VoyageRepository) Retrieve(ctx context.Context, id uuid.UUID) (Voyage, error) {
 	const query = `
 		SELECT
-			id, charter_detail_id, deal_id, owner_user_id,
+			id, org_id, charter_detail_id, deal_id, owner_user_id,
 			voyage_number, vessel_name, imo_number, vessel_type, dwt, flag_state,
 			departure_port, arrival_port,
 			planned_departure_at, planned_arrival_at,
@@ -168,9 +188,10 @@ func (repo *VoyageRepository) Retrieve(ctx context.Context, id uuid.UUID) (Voyag
 			counterparty_name, counterparty_email,
 			counterparty_user_id, broker_user_id,
 			document_id, charter_type,
-			status, notes, created_at, updated_at
+			status, notes, archived_at, created_at, updated_at
 		FROM shipman.voyages
 		WHERE id = $1
+		  AND ($2::uuid IS NULL OR org_id = $2)
 	`
 	var (
 		v               Voyage
@@ -215,9 +236,10 @@ func (repo *VoyageRepository) Retrieve(ctx context.Context, id uuid.UUID) (Voyag
 		documentID      sql.NullString
 		charterType     sql.NullString
 		notes           sql.NullString
+		archivedAt      sql.NullTime
 	)
-	err := Pool.QueryRowContext(ctx, query, id).Scan(
-		&v.ID, &charterID, &dealID, &ownerID,
+	err := Pool.QueryRowContext(ctx, query, id, orgFilter(ctx)).Scan(
+		&v.ID, &v.OrgID, &charterID, &dealID, &ownerID,
 		&vNumber, &vesselName, &imo, &vType, &dwt, &flag,
 		&departPort, &arrivePort,
 		&planDep, &planArr, &actDep, &actArr,
@@ -230,7 +252,7 @@ func (repo *VoyageRepository) Retrieve(ctx context.Context, id uuid.UUID) (Voyag
 		&counterName, &counterEmail,
 		&counterUserID, &brokerUserID,
 		&documentID, &charterType,
-		&v.Status, &notes, &v.CreatedAt, &v.UpdatedAt,
+		&v.Status, &notes, &archivedAt, &v.CreatedAt, &v.UpdatedAt,
 	)
 	if err != nil {
 		return Voyage{}, err
@@ -276,13 +298,31 @@ func (repo *VoyageRepository) Retrieve(ctx context.Context, id uuid.UUID) (Voyag
 	v.DocumentID = uuidPtrNullable(documentID)
 	v.CharterType = stringPtr(charterType)
 	v.Notes = stringPtr(notes)
+	v.ArchivedAt = timePtr(archivedAt)
 	return v, nil
 }
 
-func (repo *VoyageRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]Voyage, error) {
+// IsCharterParticipant reports whether userID owns, brokers or is the
+// counterparty on any voyage of the charter, archived ones included.
+func (repo *VoyageRepository) IsCharterParticipant(ctx context.Context, charterID, userID uuid.UUID) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM shipman.voyages
+			WHERE charter_detail_id = $1
+			  AND (owner_user_id = $2 OR counterparty_user_id = $2 OR broker_user_id = $2)
+			  AND ($3::uuid IS NULL OR org_id = $3)
+		)
+	`
+	var ok bool
+	err := Pool.QueryRowContext(ctx, query, charterID, userID, orgFilter(ctx)).Scan(&ok)
+	return ok, err
+}
+
+func (repo *VoyageRepository) ListByUser(ctx context.Context, userID uuid.UUID, includeArchived bool) ([]Voyage, error) {
 	// Return every voyage the user is involved in — owner, counterparty
 	// (the joined-via-invite side), or broker. Without this any invited user
-	// would see an empty /voyages page after accepting.
+	// would see an empty /voyages page after accepting. Archived voyages are
+	// left out unless includeArchived is set.
 	const query = `
 		SELECT id, deal_id, voyage_number, vessel_name, imo_number,
 		       departure_port, arrival_port,
@@ -290,14 +330,16 @@ func (repo *VoyageRepository) ListByUser(ctx context.Context, userID uuid.UUID)
 		       actual_departure_at, actual_arrival_at,
 		       cargo_type, cargo_quantity,
 		       counterparty_user_id, broker_user_id, owner_user_id,
-		       status, created_at, updated_at
+		       status, archived_at, created_at, updated_at
 		FROM shipman.voyages
-		WHERE owner_user_id = $1
-		   OR counterparty_user_id = $1
-		   OR broker_user_id = $1
+		WHERE (owner_user_id = $1
+		    OR counterparty_user_id = $1
+		    OR broker_user_id = $1)
+		  AND ($2 OR archived_at IS NULL)
+		  AND ($3::uuid IS NULL OR org_id = $3)
 		ORDER BY COALESCE(planned_departure_at, created_at) DESC
 	`
-	rows, err := Pool.QueryContext(ctx, query, userID)
+	rows, err := Pool.QueryContext(ctx, query, userID, includeArchived, orgFilter(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -322,6 +364,7 @@ func (repo *VoyageRepository) ListByUser(ctx context.Context, userID uuid.UUID)
 			counterUserID sql.NullString
 			brokerUserID  sql.NullString
 			ownerUserID   sql.NullString
+			archivedAt    sql.NullTime
 		)
 		if err := rows.Scan(
 			&v.ID, &dealID, &vNumber, &vessel, &imo,
@@ -329,7 +372,7 @@ func (repo *VoyageRepository) ListByUser(ctx context.Context, userID uuid.UUID)
 			&planDep, &planArr, &actDep, &actArr,
 			&cargoType, &cargoQty,
 			&counterUserID, &brokerUserID, &ownerUserID,
-			&v.Status, &v.CreatedAt, &v.UpdatedAt,
+			&v.Status, &archivedAt, &v.CreatedAt, &v.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -348,11 +391,216 @@ func (repo *VoyageRepository) ListByUser(ctx context.Context, userID uuid.UUID)
 		v.CounterpartyUserID = uuidPtrNullable(counterUserID)
 		v.BrokerUserID = uuidPtrNullable(brokerUserID)
 		v.OwnerUserID = uuidPtrNullable(ownerUserID)
+		v.ArchivedAt = timePtr(archivedAt)
 		voyages = append(voyages, v)
 	}
 	return voyages, rows.Err()
 }
 
+// ListByCharter returns the full voyages attached to a charter. Archived
+// voyages are left out unless includeArchived is set.
+func (repo *VoyageRepository) ListByCharter(ctx context.Context, charterID uuid.UUID, includeArchived bool) ([]Voyage, error) {
+	const query = `
+		SELECT id FROM shipman.voyages
+		WHERE charter_detail_id = $1
+		  AND ($2 OR archived_at IS NULL)
+		  AND ($3::uuid IS NULL OR org_id = $3)
+		ORDER BY created_at
+	`
+	return repo.retrieveAll(ctx, query, charterID, includeArchived, orgFilter(ctx))
+}
+
+// ListStale returns in-progress voyages (departed, not arrived, not archived)
+// with no position recorded at or after noPositionSince, including those that
+// never reported one. Longest-running voyages come first.
+func (repo *VoyageRepository) ListStale(ctx context.Context, noPositionSince time.Time) ([]Voyage, error) {
+	query := `
+		SELECT v.id FROM shipman.voyages v
+		WHERE v.actual_departure_at IS NOT NULL
+		  AND v.actual_arrival_at IS NULL
+		  AND v.archived_at IS NULL
+		  AND ($2::uuid IS NULL OR v.org_id = $2)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM shipman.ship_positions p
+		      WHERE p.voyage_id = v.id AND p.recorded_at >= $1
+		  )
+		ORDER BY ` + orderBy(asc("v.actual_departure_at"), asc("v.id"))
+	return repo.retrieveAll(ctx, query, noPositionSince, orgFilter(ctx))
+}
+
+// retrieveAll runs an id-only query and loads each matching voyage in full.
+func (repo *VoyageRepository) retrieveAll(ctx context.Context, query string, args ...any) ([]Voyage, error) {
+	rows, err := Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	voyages := make([]Voyage, 0, len(ids))
+	for _, id := range ids {
+		v, err := repo.Retrieve(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		voyages = append(voyages, v)
+	}
+	return voyages, nil
+}
+
+// LatestByCharter returns the charter's current voyage: an in-progress one
+// (departed, not arrived) when there is one, otherwise the voyage with the
+// latest planned departure, falling back to when it was created. Archived
+// voyages are ignored. It returns ErrNotFound when the charter has none.
+func (repo *VoyageRepository) LatestByCharter(ctx context.Context, charterID uuid.UUID) (Voyage, error) {
+	query := `
+		SELECT id FROM shipman.voyages
+		WHERE charter_detail_id = $1
+		  AND archived_at IS NULL
+		  AND ($2::uuid IS NULL OR org_id = $2)
+		ORDER BY ` + orderBy(
+		desc("(actual_departure_at IS NOT NULL AND actual_arrival_at IS NULL)"),
+		desc("COALESCE(planned_departure_at, created_at)"),
+		desc("id"),
+	) + `
+		LIMIT 1
+	`
+
+	var id uuid.UUID
+	if err := Pool.QueryRowContext(ctx, query, charterID, orgFilter(ctx)).Scan(&id); err != nil {
+		return Voyage{}, notFound(err)
+	}
+	v, err := repo.Retrieve(ctx, id)
+	if err != nil {
+		return Voyage{}, notFound(err)
+	}
+	return v, nil
+}
+
+// Archive hides a voyage from default lists. Archiving an archived voyage
+// keeps its original archived_at.
+func (repo *VoyageRepository) Archive(ctx context.Context, id uuid.UUID) error {
+	const query = `UPDATE shipman.voyages SET archived_at = COALESCE(archived_at, NOW()), updated_at = NOW() WHERE id = $1`
+	return requireRow(Pool.ExecContext(ctx, query, id))
+}
+
+// Unarchive returns an archived voyage to default lists.
+func (repo *VoyageRepository) Unarchive(ctx context.Context, id uuid.UUID) error {
+	const query = `UPDATE shipman.voyages SET archived_at = NULL, updated_at = NOW() WHERE id = $1`
+	return requireRow(Pool.ExecContext(ctx, query, id))
+}
+
+// ArchiveCompletedByCharter archives every completed, unarchived voyage of a
+// closed charter (status 'closed' or 'completed') and returns how many were
+// archived. It returns ErrCharterNotClosed for a charter still in progress
+// and sql.ErrNoRows when the charter does not exist.
+func (repo *VoyageRepository) ArchiveCompletedByCharter(ctx context.Context, charterID uuid.UUID) (int64, error) {
+	var archived int64
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var status string
+		if err := Conn(ctx).QueryRowContext(ctx,
+			`SELECT status FROM shipman.charter_details WHERE id = $1 FOR UPDATE`, charterID,
+		).Scan(&status); err != nil {
+			return err
+		}
+		if status != "closed" && status != "completed" {
+			return ErrCharterNotClosed
+		}
+
+		res, err := Conn(ctx).ExecContext(ctx, `
+			UPDATE shipman.voyages
+			SET archived_at = NOW(), updated_at = NOW()
+			WHERE charter_detail_id = $1 AND status = 'completed' AND archived_at IS NULL
+		`, charterID)
+		if err != nil {
+			return err
+		}
+		archived, err = res.RowsAffected()
+		return err
+	})
+	return archived, err
+}
+
+// voyageCharterTables lists tables whose rows carry both a voyage_id and a
+// charter_detail_id. Reparent moves them with their voyage so they do not
+// stay attached to the old charter.
+var voyageCharterTables = []string{
+	"laytime_entries",
+	"disputes",
+	"bills_of_lading",
+	"demurrage_records",
+	"payments",
+	"nor_events",
+}
+
+// Reparent moves the given voyages, and the rows in voyageCharterTables
+// recorded against them, to newCharterID in one transaction. It returns
+// sql.ErrNoRows when the target charter does not exist and ErrNotFound,
+// writing nothing, when any voyage id is unknown. Duplicate ids are counted
+// once.
+func (repo *VoyageRepository) Reparent(ctx context.Context, voyageIDs []uuid.UUID, newCharterID uuid.UUID) (int64, error) {
+	unique := make(map[uuid.UUID]bool, len(voyageIDs))
+	ids := make([]uuid.UUID, 0, len(voyageIDs))
+	for _, id := range voyageIDs {
+		if !unique[id] {
+			unique[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var moved int64
+	err := WithTx(ctx, func(ctx context.Context) error {
+		var exists bool
+		if err := Conn(ctx).QueryRowContext(ctx,
+			`SELECT true FROM shipman.charter_details WHERE id = $1 FOR SHARE`, newCharterID,
+		).Scan(&exists); err != nil {
+			return err
+		}
+
+		res, err := Conn(ctx).ExecContext(ctx, `
+			UPDATE shipman.voyages
+			SET charter_detail_id = $2, updated_at = NOW()
+			WHERE id = ANY($1)
+		`, ids, newCharterID)
+		if err != nil {
+			return err
+		}
+		if moved, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if moved != int64(len(ids)) {
+			return ErrNotFound
+		}
+
+		for _, table := range voyageCharterTables {
+			query := `
+				UPDATE shipman.` + table + `
+				SET charter_detail_id = $2, updated_at = NOW()
+				WHERE voyage_id = ANY($1)
+			`
+			if _, err := Conn(ctx).ExecContext(ctx, query, ids, newCharterID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
 // IsParticipant returns true when the user is owner, counterparty, or broker
 // on the voyage. Used by all read/write access checks in the voyage handlers.
 func (repo *VoyageRepository) IsParticipant(ctx context.Context, voyageID, userID uuid.UUID) (bool, error) {
@@ -388,11 +636,15 @@ func (repo *VoyageRepository) SetParty(ctx context.Context, voyageID uuid.UUID,
 		col = "counterparty_user_id"
 	}
 	q := "UPDATE shipman.voyages SET " + col + " = $2 WHERE id = $1"
-	_, err := Pool.ExecContext(ctx, q, voyageID, userID)
+	_, err := Conn(ctx).ExecContext(ctx, q, voyageID, userID)
 	return err
 }
 
 func (repo *VoyageRepository) Update(ctx context.Context, v *Voyage) error {
+	v.Normalize()
+	if err := sanitizeNotes(notesField{"notes", v.Notes}); err != nil {
+		return err
+	}
 	const query = `
 		UPDATE shipman.voyages
 		SET
@@ -416,7 +668,7 @@ func (repo *VoyageRepository) Update(ctx context.Context, v *Voyage) error {
 		WHERE id = $1
 		RETURNING updated_at
 	`
-	return Pool.QueryRowContext(ctx, query,
+	err := Conn(ctx).QueryRowContext(ctx, query,
 		v.ID,
 		nullableString(v.VoyageNumber), nullableString(v.VesselName), nullableString(v.IMONumber),
 		nullableString(v.VesselType), nullableFloat(v.DWT), nullableString(v.FlagState),
@@ -435,12 +687,90 @@ func (repo *VoyageRepository) Update(ctx context.Context, v *Voyage) error {
 		nullableString(v.CounterpartyName), nullableString(v.CounterpartyEmail),
 		v.Status, nullableString(v.Notes),
 	).Scan(&v.UpdatedAt)
+	return notFound(err)
 }
 
 func (repo *VoyageRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	const query = `UPDATE shipman.voyages SET status = $2, updated_at = NOW() WHERE id = $1`
-	_, err := Pool.ExecContext(ctx, query, id, status)
-	return err
+	return requireRow(Pool.ExecContext(ctx, query, id, status))
+}
+
+// VoyageState is the status snapshot returned by voyage status transitions.
+type VoyageState struct {
+	ID              uuid.UUID  `json:"id"`
+	Status          string     `json:"status"`
+	ActualDeparture *time.Time `json:"actual_departure_at,omitempty"`
+	ActualArrival   *time.Time `json:"actual_arrival_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Depart marks a planned or delayed voyage as sailing. The row is locked for
+// the duration of the check so concurrent calls are serialized; the loser gets
+// ErrInvalidTransition together with the state written by the winner.
+func (repo *VoyageRepository) Depart(ctx context.Context, id uuid.UUID, at time.Time) (VoyageState, error) {
+	state, err := repo.transition(ctx, id, func(st VoyageState) bool {
+		return (st.Status == "planned" || st.Status == "delayed") && st.ActualDeparture == nil
+	}, `UPDATE shipman.voyages
+		SET status = 'sailing', actual_departure_at = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING status, actual_departure_at, actual_arrival_at, updated_at`, at)
+	if err == nil {
+		AfterCommit(ctx, metrics.VoyageDeparted)
+	}
+	return state, err
+}
+
+// Arrive marks a sailing (or delayed, already departed) voyage as completed.
+func (repo *VoyageRepository) Arrive(ctx context.Context, id uuid.UUID, at time.Time) (VoyageState, error) {
+	state, err := repo.transition(ctx, id, func(st VoyageState) bool {
+		return (st.Status == "sailing" || st.Status == "delayed") && st.ActualDeparture != nil
+	}, `UPDATE shipman.voyages
+		SET status = 'completed', actual_arrival_at = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING status, actual_departure_at, actual_arrival_at, updated_at`, at)
+	if err == nil {
+		AfterCommit(ctx, metrics.VoyageArrived)
+	}
+	return state, err
+}
+
+// transition locks the voyage row, checks allowed against its current state
+// and applies update, all in one transaction. It joins a transaction already
+// on ctx.
+func (repo *VoyageRepository) transition(ctx context.Context, id uuid.UUID, allowed func(VoyageState) bool, update string, at time.Time) (VoyageState, error) {
+	st := VoyageState{ID: id}
+	err := WithTx(ctx, func(ctx context.Context) error {
+		const lockQuery = `
+			SELECT status, actual_departure_at, actual_arrival_at, updated_at
+			FROM shipman.voyages
+			WHERE id = $1
+			FOR UPDATE
+		`
+		var dep, arr sql.NullTime
+		if err := Conn(ctx).QueryRowContext(ctx, lockQuery, id).Scan(&st.Status, &dep, &arr, &st.UpdatedAt); err != nil {
+			return err
+		}
+		st.ActualDeparture = timePtr(dep)
+		st.ActualArrival = timePtr(arr)
+
+		if !allowed(st) {
+			return ErrInvalidTransition
+		}
+
+		if err := Conn(ctx).QueryRowContext(ctx, update, id, at).Scan(&st.Status, &dep, &arr, &st.UpdatedAt); err != nil {
+			return err
+		}
+		st.ActualDeparture = timePtr(dep)
+		st.ActualArrival = timePtr(arr)
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrInvalidTransition):
+		return st, err
+	case err != nil:
+		return VoyageState{}, err
+	}
+	return st, nil
 }
 
 func (repo *VoyageRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -461,12 +791,50 @@ func (repo *VoyageRepository) CalcLaytime(ctx context.Context, voyageID uuid.UUI
 	if err := Pool.QueryRowContext(ctx, sumQuery, voyageID).Scan(&totalUsed); err != nil {
 		return LaytimeSummary{}, err
 	}
+	return repo.laytimeSummary(ctx, voyageID, totalUsed)
+}
+
+// CalcLaytimeFromNOR is CalcLaytime with laytime commencing at each port's
+// latest accepted Notice of Readiness plus its turn time. Hours an entry
+// spent before commencement are dropped; ports without an accepted notice
+// count in full.
+func (repo *VoyageRepository) CalcLaytimeFromNOR(ctx context.Context, voyageID uuid.UUID) (LaytimeSummary, error) {
+	const sumQuery = `
+		SELECT COALESCE(SUM(
+			CASE
+				WHEN nor.commences IS NULL OR le.started_at >= nor.commences THEN le.hours_counted
+				ELSE GREATEST(le.hours_counted - EXTRACT(EPOCH FROM (
+					LEAST(nor.commences, COALESCE(le.ended_at, nor.commences)) - le.started_at
+				)) / 3600, 0)
+			END
+		), 0)
+		FROM shipman.laytime_entries le
+		LEFT JOIN LATERAL (
+			SELECT n.accepted_at + n.turn_time_hours * INTERVAL '1 hour' AS commences
+			FROM shipman.nor_events n
+			WHERE n.voyage_id = le.voyage_id
+			  AND n.accepted_at IS NOT NULL
+			  AND lower(trim(n.port_name)) = lower(trim(le.port_name))
+			ORDER BY n.accepted_at DESC
+			LIMIT 1
+		) nor ON true
+		WHERE le.voyage_id = $1
+		  AND le.hours_counted IS NOT NULL
+	`
+	var totalUsed float64
+	if err := Pool.QueryRowContext(ctx, sumQuery, voyageID).Scan(&totalUsed); err != nil {
+		return LaytimeSummary{}, err
+	}
+	return repo.laytimeSummary(ctx, voyageID, totalUsed)
+}
 
-	// Get voyage terms
+// laytimeSummary prices totalUsed hours against the voyage's laytime terms.
+func (repo *VoyageRepository) laytimeSummary(ctx context.Context, voyageID uuid.UUID, totalUsed float64) (LaytimeSummary, error) {
+	// Get voyage terms; despatch defaults to half the demurrage rate
 	const termsQuery = `
 		SELECT COALESCE(laytime_allowed_hours, 0),
 		       COALESCE(demurrage_rate, 0),
-		       COALESCE(despatch_rate, 0),
+		       COALESCE(despatch_rate, COALESCE(demurrage_rate, 0) / 2),
 		       COALESCE(demurrage_currency, 'USD')
 		FROM shipman.voyages WHERE id = $1
 	`
@@ -519,6 +887,7 @@ type VoyageInvite struct {
 }
 
 func (repo *VoyageRepository) CreateInvite(ctx context.Context, i *VoyageInvite) error {
+	clearServerFields(&i.ID, &i.CreatedAt)
 	b := make([]byte, 32)
 	_, _ = rand.Read(b)
 	i.Token = hex.EncodeToString(b)
@@ -569,6 +938,6 @@ func (repo *VoyageRepository) UseInvite(ctx context.Context, token string, usedB
 		SET used_at = NOW(), used_by = $2
 		WHERE token = $1
 	`
-	_, err := Pool.ExecContext(ctx, query, token, usedBy)
+	_, err := Conn(ctx).ExecContext(ctx, query, token, usedBy)
 	return err
 }
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+)
+
+func TestWarmup(t *testing.T) {
+	tests := []struct {
+		name       string
+		connErr    error
+		wantWarmed int
+		wantIdle   int
+	}{
+		{"fills the idle pool", nil, DBMaxIdleConns, DBMaxIdleConns},
+		{"unreachable database", errors.New("connection refused"), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := dbtest.New()
+			fake.FailConnects(tt.connErr)
+			pool := fake.Open()
+			defer pool.Close()
+			pool.SetMaxIdleConns(DBMaxIdleConns)
+
+			warmed, err := Warmup(context.Background(), pool, DBMaxIdleConns)
+			if !errors.Is(err, tt.connErr) {
+				t.Errorf("err = %v, want %v", err, tt.connErr)
+			}
+			if warmed != tt.wantWarmed {
+				t.Errorf("warmed = %d, want %d", warmed, tt.wantWarmed)
+			}
+			stats := pool.Stats()
+			if stats.Idle != tt.wantIdle || stats.InUse != 0 {
+				t.Errorf("idle/in use = %d/%d, want %d/0", stats.Idle, stats.InUse, tt.wantIdle)
+			}
+		})
+	}
+}
+
+func TestWarmupCanceled(t *testing.T) {
+	pool := dbtest.New().Open()
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	warmed, err := Warmup(ctx, pool, DBMaxIdleConns)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if warmed != 0 {
+		t.Errorf("warmed = %d, want 0", warmed)
+	}
+}
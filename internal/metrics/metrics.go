@@ -0,0 +1,138 @@
+// Package metrics exposes Prometheus counters for business events. Until
+// Enable is called every recording function is a no-op, so callers can
+// record unconditionally.
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type domainMetrics struct {
+	registry         *prometheus.Registry
+	chartersCreated  prometheus.Counter
+	voyagesDeparted  prometheus.Counter
+	voyagesArrived   prometheus.Counter
+	demurrageClaims  *prometheus.CounterVec
+	demurrageAmounts *prometheus.HistogramVec
+	disputesOpened   prometheus.Counter
+}
+
+var active atomic.Pointer[domainMetrics]
+
+// Enable creates and registers the domain metrics. Calling it again resets
+// them.
+func Enable() {
+	m := &domainMetrics{
+		registry: prometheus.NewRegistry(),
+		chartersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shipman_charters_created_total",
+			Help: "Charters created.",
+		}),
+		voyagesDeparted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shipman_voyages_departed_total",
+			Help: "Voyages marked as departed.",
+		}),
+		voyagesArrived: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shipman_voyages_arrived_total",
+			Help: "Voyages marked as arrived.",
+		}),
+		demurrageClaims: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shipman_demurrage_claims_total",
+			Help: "Demurrage records created, by currency.",
+		}, []string{"currency"}),
+		demurrageAmounts: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shipman_demurrage_claimed_amount",
+			Help:    "Claimed amount of new demurrage records, by currency.",
+			Buckets: prometheus.ExponentialBuckets(1000, 4, 8), // 1k .. ~16M
+		}, []string{"currency"}),
+		disputesOpened: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shipman_disputes_opened_total",
+			Help: "Disputes raised.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.chartersCreated,
+		m.voyagesDeparted,
+		m.voyagesArrived,
+		m.demurrageClaims,
+		m.demurrageAmounts,
+		m.disputesOpened,
+	)
+	active.Store(m)
+}
+
+// Enabled reports whether Enable has been called.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format.
+// It responds 404 while metrics are disabled.
+func Handler() http.Handler {
+	m := active.Load()
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func CharterCreated() {
+	if m := active.Load(); m != nil {
+		m.chartersCreated.Inc()
+	}
+}
+
+func VoyageDeparted() {
+	if m := active.Load(); m != nil {
+		m.voyagesDeparted.Inc()
+	}
+}
+
+func VoyageArrived() {
+	if m := active.Load(); m != nil {
+		m.voyagesArrived.Inc()
+	}
+}
+
+// DemurrageClaimed records a new demurrage claim. amount may be nil when the
+// claim has no figure yet; it is then counted but not observed.
+func DemurrageClaimed(currency string, amount *float64) {
+	m := active.Load()
+	if m == nil {
+		return
+	}
+	label := currencyLabel(currency)
+	m.demurrageClaims.WithLabelValues(label).Inc()
+	if amount != nil {
+		m.demurrageAmounts.WithLabelValues(label).Observe(*amount)
+	}
+}
+
+func DisputeOpened() {
+	if m := active.Load(); m != nil {
+		m.disputesOpened.Inc()
+	}
+}
+
+// currencyLabel keeps the currency label to well-formed ISO codes so a bad
+// input cannot mint new series.
+func currencyLabel(currency string) string {
+	c := strings.ToUpper(strings.TrimSpace(currency))
+	if len(c) != 3 {
+		return "other"
+	}
+	for i := 0; i < len(c); i++ {
+		if c[i] < 'A' || c[i] > 'Z' {
+			return "other"
+		}
+	}
+	return c
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrape returns the exposition line for series, or "" when it is absent.
+func scrape(t *testing.T, series string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d", w.Code)
+	}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestDisabledIsNoop(t *testing.T) {
+	active.Store(nil)
+
+	CharterCreated()
+	VoyageDeparted()
+	VoyageArrived()
+	amount := 5000.0
+	DemurrageClaimed("USD", &amount)
+	DisputeOpened()
+
+	if Enabled() {
+		t.Error("Enabled() = true before Enable")
+	}
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 while disabled", w.Code)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	Enable()
+	t.Cleanup(func() { active.Store(nil) })
+
+	CharterCreated()
+	VoyageDeparted()
+	VoyageDeparted()
+	VoyageArrived()
+	DisputeOpened()
+	amount := 5000.0
+	DemurrageClaimed("usd", &amount)
+	DemurrageClaimed("USD", nil)
+	DemurrageClaimed("dollars", nil)
+
+	tests := []struct {
+		series string
+		want   string
+	}{
+		{"shipman_charters_created_total", "1"},
+		{"shipman_voyages_departed_total", "2"},
+		{"shipman_voyages_arrived_total", "1"},
+		{"shipman_disputes_opened_total", "1"},
+		{`shipman_demurrage_claims_total{currency="USD"}`, "2"},
+		{`shipman_demurrage_claims_total{currency="other"}`, "1"},
+		{`shipman_demurrage_claimed_amount_count{currency="USD"}`, "1"},
+		{`shipman_demurrage_claimed_amount_sum{currency="USD"}`, "5000"},
+	}
+	for _, tt := range tests {
+		if got := scrape(t, tt.series); got != tt.series+" "+tt.want {
+			t.Errorf("%s: got %q, want %s", tt.series, got, tt.want)
+		}
+	}
+	if got := scrape(t, `shipman_demurrage_claimed_amount_count{currency="other"}`); got != "" {
+		t.Errorf("claims without an amount were observed: %s", got)
+	}
+
+	Enable()
+	if got := scrape(t, "shipman_voyages_departed_total"); got != "shipman_voyages_departed_total 0" {
+		t.Errorf("after re-Enable: %q, want the counter reset", got)
+	}
+}
+
+func TestCurrencyLabel(t *testing.T) {
+	tests := map[string]string{
+		"USD":   "USD",
+		" eur ": "EUR",
+		"":      "other",
+		"US":    "other",
+		"USDT":  "other",
+		"U$D":   "other",
+		"ÜSD":   "other",
+	}
+	for in, want := range tests {
+		if got := currencyLabel(in); got != want {
+			t.Errorf("currencyLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package activity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/router/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the admin activity feed mounted at /activity.
+type Handler struct {
+	activityRepo *db.ActivityRepository
+	laytimeRepo  *db.LaytimeEntryRepository
+	disputeRepo  *db.DisputeRepository
+	charterRepo  *db.CharterDetailRepository
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		activityRepo: db.NewActivityRepository(),
+		laytimeRepo:  db.NewLaytimeEntryRepository(),
+		disputeRepo:  db.NewDisputeRepository(),
+		charterRepo:  db.NewCharterDetailRepository(),
+	}
+}
+
+func (h *Handler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("", h.handleRecent)
+	r.GET("/laytime/open", h.handleOpenLaytime)
+	r.POST("/archive", h.handleArchive)
+}
+
+func (h *Handler) handleRecent(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	items, err := h.activityRepo.Recent(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load activity"})
+		return
+	}
+
+	if items == nil {
+		items = []db.ActivityItem{}
+	}
+
+	render.Data(c, items)
+}
+
+func (h *Handler) handleOpenLaytime(c *gin.Context) {
+	page := db.Page{Limit: 20}
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			page.Limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			page.Offset = parsed
+		}
+	}
+
+	entries, err := h.laytimeRepo.ListAllOpen(c.Request.Context(), page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list open laytime entries"})
+		return
+	}
+
+	if entries == nil {
+		entries = []db.LaytimeEntry{}
+	}
+
+	render.Paged(c, entries, page.Limit, page.Offset)
+}
+
+// handleArchive archives disputes resolved and charters closed before
+// ?before= (YYYY-MM-DD). The date must be in the past.
+func (h *Handler) handleArchive(c *gin.Context) {
+	before, err := time.Parse("2006-01-02", c.Query("before"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before must be YYYY-MM-DD"})
+		return
+	}
+	if !before.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before must be in the past"})
+		return
+	}
+
+	disputes, err := h.disputeRepo.ArchiveResolvedBefore(c.Request.Context(), before)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to archive disputes"})
+		return
+	}
+	charters, err := h.charterRepo.ArchiveClosedBefore(c.Request.Context(), before)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to archive charters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"disputes_archived": disputes, "charters_archived": charters})
+}
@@ -0,0 +1,203 @@
+package activity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const activityQuery = "SELECT kind, id, charter_id, updated_at, summary FROM ("
+
+var activityColumns = []string{"kind", "id", "charter_id", "updated_at", "summary"}
+
+func TestRecentActivity(t *testing.T) {
+	fake := newFakeDB(t)
+	charterID := uuid.New()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	seeded := []struct {
+		kind string
+		id   uuid.UUID
+		at   time.Time
+	}{
+		{"payment", uuid.New(), base},
+		{"dispute", uuid.New(), base.Add(-time.Second)},
+		{"voyage", uuid.New(), base.Add(-time.Minute)},
+		{"charter", charterID, base.Add(-time.Hour)},
+	}
+	var rows [][]any
+	for _, s := range seeded {
+		rows = append(rows, []any{s.kind, s.id, charterID.String(), s.at, s.kind + " summary"})
+	}
+	fake.Return(activityQuery, dbtest.Rows(activityColumns, rows...))
+
+	w := do(t, newTestRouter(), http.MethodGet, "/activity")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []db.ActivityItem `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != len(seeded) {
+		t.Fatalf("items = %d, want %d", len(body.Data), len(seeded))
+	}
+	for i, s := range seeded {
+		got := body.Data[i]
+		if got.Kind != s.kind || got.ID != s.id || !got.UpdatedAt.Equal(s.at) {
+			t.Errorf("item %d = %+v, want %s %s at %s", i, got, s.kind, s.id, s.at)
+		}
+		if got.CharterID == nil || *got.CharterID != charterID {
+			t.Errorf("item %d charter = %v, want %s", i, got.CharterID, charterID)
+		}
+		if i > 0 && got.UpdatedAt.After(body.Data[i-1].UpdatedAt) {
+			t.Errorf("item %d is newer than item %d", i, i-1)
+		}
+	}
+}
+
+func TestRecentActivityLimit(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{"", 20},
+		{"?limit=5", 5},
+		{"?limit=100", 100},
+		{"?limit=101", 20},
+		{"?limit=0", 20},
+		{"?limit=abc", 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(activityQuery, dbtest.Rows(activityColumns))
+
+			w := do(t, newTestRouter(), http.MethodGet, "/activity"+tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if w.Body.String() != `{"data":[]}` {
+				t.Errorf("body = %s, want an empty data list", w.Body.String())
+			}
+			calls := fake.Calls(activityQuery)
+			if len(calls) != 1 || calls[0].Arg(1) != tt.want {
+				t.Errorf("feed calls = %+v, want one with limit %d", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenLaytime(t *testing.T) {
+	tests := []struct {
+		query                 string
+		wantStatus            int
+		wantLimit, wantOffset int64
+	}{
+		{"", http.StatusOK, 20, 0},
+		{"?limit=5&offset=10", http.StatusOK, 5, 10},
+		{"?offset=20000", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			started := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+			fake.Return("FROM shipman.laytime_entries WHERE ended_at IS NULL", dbtest.Rows([]string{
+				"id", "charter_detail_id", "voyage_id", "port_name", "activity", "started_at",
+				"ended_at", "hours_counted", "excluded_hours", "remarks", "created_at", "updated_at",
+			}, []any{uuid.New(), uuid.New(), nil, "Santos", "discharging", started, nil, nil, 0.0, nil, started, started}))
+
+			w := do(t, newTestRouter(), http.MethodGet, "/activity/laytime/open"+tt.query)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls("FROM shipman.laytime_entries")
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("listed despite the rejected offset")
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0].Arg(1) != tt.wantLimit || calls[0].Arg(2) != tt.wantOffset {
+				t.Fatalf("list calls = %+v, want limit %d offset %d", calls, tt.wantLimit, tt.wantOffset)
+			}
+			var body struct {
+				Data []db.LaytimeEntry `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 1 || body.Data[0].PortName != "Santos" || body.Data[0].EndedAt != nil {
+				t.Errorf("data = %+v, want the open entry", body.Data)
+			}
+		})
+	}
+}
+
+func TestArchiveEndpoint(t *testing.T) {
+	const (
+		disputeQuery = "UPDATE shipman.disputes SET archived_at = NOW()"
+		charterQuery = "UPDATE shipman.charter_details cd SET archived_at = NOW()"
+	)
+	future := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"past date", "?before=2025-01-01", http.StatusOK},
+		{"missing date", "", http.StatusBadRequest},
+		{"malformed date", "?before=01/01/2025", http.StatusBadRequest},
+		{"future date", "?before=" + future, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(disputeQuery, dbtest.Affected(4))
+			fake.Return(charterQuery, dbtest.Affected(2))
+
+			w := do(t, newTestRouter(), http.MethodPost, "/activity/archive"+tt.query)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			disputes, charters := fake.Calls(disputeQuery), fake.Calls(charterQuery)
+			if tt.wantStatus != http.StatusOK {
+				if len(disputes)+len(charters) != 0 {
+					t.Error("archived despite the rejected date")
+				}
+				return
+			}
+			cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			if len(disputes) != 1 || !disputes[0].Arg(1).(time.Time).Equal(cutoff) ||
+				len(charters) != 1 || !charters[0].Arg(1).(time.Time).Equal(cutoff) {
+				t.Fatalf("archive calls = %+v %+v, want one each before %s", disputes, charters, cutoff)
+			}
+			if w.Body.String() != `{"charters_archived":2,"disputes_archived":4}` {
+				t.Errorf("body = %s, want both counts", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestArchiveEndpointDisputeFailure(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("UPDATE shipman.disputes", dbtest.Fail(errors.New("connection reset")))
+	fake.Return("UPDATE shipman.charter_details", dbtest.Affected(1))
+
+	w := do(t, newTestRouter(), http.MethodPost, "/activity/archive?before=2025-01-01")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if len(fake.Calls("UPDATE shipman.charter_details")) != 0 {
+		t.Error("archived charters after the dispute archive failed")
+	}
+}
@@ -0,0 +1,56 @@
+package activity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/router/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var testJWT = auth.NewJWTManager("test-secret", time.Hour)
+
+// newFakeDB installs a dbtest.Fake as db.Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+// newTestRouter mounts the activity routes behind the real bearer-token
+// middleware. Role checks live in the router, so none are applied here.
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/activity")
+	g.Use(middleware.Auth(testJWT))
+	NewHandler().AddRoutes(g)
+	return r
+}
+
+func do(t *testing.T, r http.Handler, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := testJWT.Generate(uuid.New(), db.DefaultOrgID, "admin@example.com", "admin", "Test Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
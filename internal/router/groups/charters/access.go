@@ -0,0 +1,34 @@
+package charters
+
+import (
+	"net/http"
+
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requireCharterAccess checks that the caller may act on charter: admins, the
+// user who created it, and participants of any of its voyages. Otherwise it
+// writes a 403 and returns false. The charter must already have been loaded
+// through the org-scoped Retrieve.
+func requireCharterAccess(c *gin.Context, charter db.CharterDetail) bool {
+	if c.GetString("userRole") == "admin" {
+		return true
+	}
+	userID := c.MustGet("userID").(uuid.UUID)
+	if charter.CreatedByUserID != nil && *charter.CreatedByUserID == userID {
+		return true
+	}
+	ok, err := db.NewVoyageRepository().IsCharterParticipant(c.Request.Context(), charter.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check charter access"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return false
+	}
+	return true
+}
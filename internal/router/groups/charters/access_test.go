@@ -0,0 +1,42 @@
+package charters
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestParticipantOnlyRoutes checks that routes which change a charter, or
+// expose more than its own fields, refuse org members who neither created
+// the charter nor take part in one of its voyages.
+func TestParticipantOnlyRoutes(t *testing.T) {
+	owner := newTestUser("shipowner")
+	stranger := newTestUser("charterer")
+	charter := newCharter(owner.ID)
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/ai-status", `{"status":"processing"}`},
+		{http.MethodPut, "/laytime/mode", `{"reversible":true}`},
+		{http.MethodPost, "/laytime/close", `{"port_name":"Santos","ended_at":"2026-01-05T00:00:00Z"}`},
+		{http.MethodPost, "/ports/sync-laytime", ""},
+		{http.MethodGet, "/history?field=title", ""},
+	}
+	for _, rt := range routes {
+		t.Run(rt.method+" "+rt.path, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, stranger, rt.method, "/"+charter.ID.String()+rt.path, rt.body)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+			}
+			if writes := fake.Calls("UPDATE "); len(writes) != 0 {
+				t.Errorf("stranger request wrote %v", writes)
+			}
+		})
+	}
+}
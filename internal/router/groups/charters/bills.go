@@ -0,0 +1,139 @@
+package charters
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/router/middleware"
+	"shipman/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BillHandler serves bill of lading endpoints mounted at /bills.
+type BillHandler struct {
+	billRepo    *db.BillOfLadingRepository
+	charterRepo *db.CharterDetailRepository
+	storage     storage.Storage
+}
+
+func NewBillHandler(store storage.Storage) *BillHandler {
+	return &BillHandler{
+		billRepo:    db.NewBillOfLadingRepository(),
+		charterRepo: db.NewCharterDetailRepository(),
+		storage:     store,
+	}
+}
+
+func (h *BillHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/:id/document", middleware.LongRunning(), h.handleDocument)
+}
+
+// handleDocument streams the scanned bill from storage. Range and If-Range
+// requests are honoured so large scans can be resumed. When the bill carries
+// a SHA-256 checksum (hex, optionally prefixed "sha256:") the file is checked
+// against it before anything is sent, and the checksum is returned as the
+// ETag and X-Checksum-SHA256 headers.
+func (h *BillHandler) handleDocument(c *gin.Context) {
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bill ID"})
+		return
+	}
+
+	bill, err := h.billRepo.Retrieve(c.Request.Context(), billID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "bill of lading not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve bill of lading"})
+		return
+	}
+	charter, err := h.charterRepo.Retrieve(c.Request.Context(), bill.CharterDetailID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "bill of lading not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve charter"})
+		return
+	}
+	if !requireCharterAccess(c, charter) {
+		return
+	}
+	if bill.StorageURI == nil || strings.TrimSpace(*bill.StorageURI) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no document attached"})
+		return
+	}
+	path := strings.TrimSpace(*bill.StorageURI)
+
+	f, err := h.storage.Get(path)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found on disk"})
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "inline; filename=\""+filepath.Base(path)+"\"")
+
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		// Without seeking neither ranges nor up-front verification are possible.
+		c.DataFromReader(http.StatusOK, -1, contentType, f, nil)
+		return
+	}
+
+	if sum, ok := sha256Checksum(bill.Checksum); ok {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, rs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read document"})
+			return
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != sum {
+			log.Printf("bill %s: stored document does not match checksum", bill.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "document checksum mismatch"})
+			return
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read document"})
+			return
+		}
+		c.Header("ETag", `"`+sum+`"`)
+		c.Header("X-Checksum-SHA256", sum)
+	}
+
+	http.ServeContent(c.Writer, c.Request, "", time.Time{}, rs)
+}
+
+// sha256Checksum returns the lowercase hex digest in a stored checksum, if it
+// is a SHA-256 one.
+func sha256Checksum(checksum *string) (string, bool) {
+	if checksum == nil {
+		return "", false
+	}
+	sum := strings.ToLower(strings.TrimSpace(*checksum))
+	sum = strings.TrimPrefix(sum, "sha256:")
+	if len(sum) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", false
+	}
+	return sum, true
+}
@@ -0,0 +1,179 @@
+package charters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+	"shipman/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var billColumns = []string{
+	"id", "charter_detail_id", "voyage_id", "document_number", "issue_date", "issuer",
+	"consignee", "notify_party", "cargo_description", "quantity", "quantity_unit",
+	"storage_uri", "checksum", "encrypted_key", "notes", "created_at", "updated_at",
+}
+
+// billFixture stores doc in a temporary LocalStorage and answers the bill
+// lookup for billID with a row pointing at it.
+func billFixture(t *testing.T, fake *dbtest.Fake, billID, charterID uuid.UUID, doc []byte, checksum any) *BillHandler {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uri any
+	if doc != nil {
+		path, err := store.Save("scan.pdf", bytes.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		uri = path
+	}
+	fake.On("FROM shipman.bills_of_lading WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		if call.Arg(1) != billID.String() {
+			return dbtest.Rows(billColumns)
+		}
+		return dbtest.Rows(billColumns, dbtest.Row(billColumns, map[string]any{
+			"id": billID, "charter_detail_id": charterID, "document_number": "BL-001", "storage_uri": uri, "checksum": checksum,
+			"created_at": time.Now(), "updated_at": time.Now(),
+		}))
+	})
+	return NewBillHandler(store)
+}
+
+// getBill requests the bill document as u with the given extra headers.
+func getBill(t *testing.T, h *BillHandler, u testUser, billID uuid.UUID, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := testJWT.Generate(u.ID, u.OrgID, "user@example.com", u.Role, "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/"+billID.String()+"/document", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	newTestRouter(h.AddRoutes).ServeHTTP(w, req)
+	return w
+}
+
+func TestBillDocument(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	doc := []byte("%PDF-1.7 " + strings.Repeat("scanned bill of lading ", 200))
+	digest := sha256.Sum256(doc)
+	sum := hex.EncodeToString(digest[:])
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   []byte
+		wantRange  string
+	}{
+		{"full", nil, http.StatusOK, doc, ""},
+		{"range", map[string]string{"Range": "bytes=0-8"}, http.StatusPartialContent, doc[:9], "bytes 0-8/" + strconv.Itoa(len(doc))},
+		{"open range", map[string]string{"Range": "bytes=100-"}, http.StatusPartialContent, doc[100:], "bytes 100-" + strconv.Itoa(len(doc)-1) + "/" + strconv.Itoa(len(doc))},
+		{"unsatisfiable", map[string]string{"Range": "bytes=999999-"}, http.StatusRequestedRangeNotSatisfiable, nil, ""},
+		{"stale if-range", map[string]string{"Range": "bytes=0-8", "If-Range": `"other"`}, http.StatusOK, doc, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			billID := uuid.New()
+			h := billFixture(t, fake, billID, charter.ID, doc, "sha256:"+strings.ToUpper(sum))
+
+			w := getBill(t, h, owner, billID, tt.headers)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("X-Checksum-SHA256"); got != sum {
+				t.Errorf("X-Checksum-SHA256 = %q, want %s", got, sum)
+			}
+			if tt.wantBody == nil {
+				return
+			}
+			if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+				t.Errorf("Accept-Ranges = %q, want bytes", got)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/pdf" {
+				t.Errorf("Content-Type = %q, want application/pdf", got)
+			}
+			if !bytes.Equal(w.Body.Bytes(), tt.wantBody) {
+				t.Errorf("body = %d bytes, want %d", w.Body.Len(), len(tt.wantBody))
+			}
+			if got := w.Header().Get("Content-Range"); got != tt.wantRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestBillDocumentRejects(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	doc := []byte("%PDF-1.7 scan")
+	wrong := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name       string
+		user       testUser
+		doc        []byte
+		checksum   any
+		missing    bool
+		wantStatus int
+	}{
+		{"no document attached", owner, nil, nil, false, http.StatusNotFound},
+		{"missing bill", owner, doc, nil, true, http.StatusNotFound},
+		{"stranger", newTestUser("charterer"), doc, nil, false, http.StatusForbidden},
+		{"checksum mismatch", owner, doc, wrong, false, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			billID := uuid.New()
+			h := billFixture(t, fake, billID, charter.ID, tt.doc, tt.checksum)
+			if tt.missing {
+				billID = uuid.New()
+			}
+
+			w := getBill(t, h, tt.user, billID, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if bytes.Contains(w.Body.Bytes(), doc) {
+				t.Error("document bytes were sent")
+			}
+		})
+	}
+}
+
+func TestBillDocumentWithoutChecksum(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	fake := newFakeDB(t)
+	stubCharters(fake, charter)
+	billID := uuid.New()
+	h := billFixture(t, fake, billID, charter.ID, []byte("%PDF-1.7 scan"), "md5:abc")
+
+	w := getBill(t, h, newTestUser("admin"), billID, map[string]string{"Range": "bytes=1-3"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "PDF" {
+		t.Fatalf("status = %d, body %q; want 206 PDF", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Checksum-SHA256") != "" || w.Header().Get("ETag") != "" {
+		t.Error("a non-SHA-256 checksum was advertised")
+	}
+}
@@ -0,0 +1,94 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const changesQuery = "SELECT deleted, entity_id, change_xid::text"
+
+var changeColumns = []string{"deleted", "entity_id", "change_xid", "title", "status", "created_at", "updated_at", "deleted_at"}
+
+func TestDeleteThenSyncChanges(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	kept := uuid.New()
+	fake := newFakeDB(t)
+	stubCharters(fake, charter)
+	fake.Return("DELETE FROM shipman.charter_details", dbtest.Rows([]string{"org_id"}, []any{charter.OrgID}))
+	fake.Return("INSERT INTO shipman.deletions", dbtest.Affected(1))
+	deletedAt := time.Date(2026, 8, 1, 12, 0, 0, 0, time.UTC)
+	fake.Return(changesQuery, dbtest.Rows(changeColumns,
+		[]any{false, kept, "41", "Kept", "draft", charter.CreatedAt, charter.UpdatedAt, nil},
+		[]any{true, charter.ID, "42", nil, nil, nil, nil, deletedAt}))
+	r := newTestRouter(NewHandler().AddRoutes)
+
+	if w := do(t, r, owner, http.MethodDelete, "/"+charter.ID.String(), ""); w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", w.Code, w.Body.String())
+	}
+	inserts := fake.Calls("INSERT INTO shipman.deletions")
+	if len(inserts) != 1 || inserts[0].Arg(2) != charter.ID.String() {
+		t.Fatalf("tombstones = %v, want one for %s", inserts, charter.ID)
+	}
+	if fake.Commits() != 1 {
+		t.Errorf("commits = %d, want the delete and tombstone committed together", fake.Commits())
+	}
+
+	cursor := db.ChangeCursor{XID: 40, ID: uuid.New()}
+	w := do(t, r, owner, http.MethodGet, "/changes?limit=10&cursor="+cursor.Encode(), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("changes status = %d: %s", w.Code, w.Body.String())
+	}
+	call := fake.Calls(changesQuery)[0]
+	if call.Arg(1) != "40" || call.Arg(2) != cursor.ID.String() || call.Arg(5) != int64(11) {
+		t.Errorf("feed args = %v, want the cursor and limit+1", call.Args)
+	}
+	var got db.ChangeSet
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Upserts) != 1 || got.Upserts[0].ID != kept {
+		t.Errorf("upserts = %+v, want only %s", got.Upserts, kept)
+	}
+	if len(got.Deletions) != 1 || got.Deletions[0].EntityID != charter.ID || !got.Deletions[0].DeletedAt.Equal(deletedAt) {
+		t.Errorf("deletions = %+v, want the deleted charter", got.Deletions)
+	}
+	if next, err := db.DecodeChangeCursor(got.Next); err != nil || next != (db.ChangeCursor{XID: 42, ID: charter.ID}) {
+		t.Errorf("next = %+v, %v; want the tombstone's position", next, err)
+	}
+	if got.More {
+		t.Error("more = true for a short page")
+	}
+}
+
+func TestChangesRejectsBadParams(t *testing.T) {
+	tests := []struct {
+		name, query string
+	}{
+		{"garbage cursor", "cursor=not-a-cursor"},
+		{"zero limit", "limit=0"},
+		{"limit over the cap", "limit=" + strconv.Itoa(db.MaxChangesPage+1)},
+		{"non-numeric limit", "limit=all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("shipowner"), http.MethodGet, "/changes?"+tt.query, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			if len(fake.Calls(changesQuery)) != 0 {
+				t.Error("queried the feed for a rejected request")
+			}
+		})
+	}
+}
@@ -0,0 +1,1284 @@
+package charters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/router/middleware"
+	"shipman/internal/router/render"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	charterRepo   *db.CharterDetailRepository
+	termRepo      *db.CharterLaytimeTermRepository
+	disputeRepo   *db.DisputeRepository
+	demurrageRepo *db.DemurrageRecordRepository
+	laytimeRepo   *db.LaytimeEntryRepository
+	paymentRepo   *db.PaymentRepository
+	auditRepo     *db.AuditLogRepository
+	voyageRepo    *db.VoyageRepository
+	portRepo      *db.VoyagePortRepository
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		charterRepo:   db.NewCharterDetailRepository(),
+		termRepo:      db.NewCharterLaytimeTermRepository(),
+		disputeRepo:   db.NewDisputeRepository(),
+		demurrageRepo: db.NewDemurrageRecordRepository(),
+		laytimeRepo:   db.NewLaytimeEntryRepository(),
+		paymentRepo:   db.NewPaymentRepository(),
+		auditRepo:     db.NewAuditLogRepository(),
+		voyageRepo:    db.NewVoyageRepository(),
+		portRepo:      db.NewVoyagePortRepository(),
+	}
+}
+
+func (h *Handler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("", middleware.ListLimitGuard("/api/v1/charters/stream"), h.handleList)
+	r.POST("", h.handleCreate)
+	r.GET("/stream", middleware.LongRunning(), h.handleStream)
+	r.GET("/expiring", h.handleListExpiring)
+	r.GET("/changes", h.handleChanges)
+	r.GET("/:id", h.handleGet)
+	r.PUT("/:id", h.handleUpdate)
+	r.DELETE("/:id", h.handleDelete)
+	r.GET("/:id/disputes", h.handleListDisputes)
+	r.GET("/:id/demurrage", h.handleListDemurrage)
+	r.GET("/:id/demurrage/reconcile", h.handleReconcileDemurrage)
+	r.GET("/:id/payments/totals", h.handlePaymentTotals)
+	r.GET("/:id/history", h.handleFieldHistory)
+	r.GET("/:id/voyages/latest", h.handleLatestVoyage)
+	r.POST("/:id/voyages/archive", h.handleArchiveVoyages)
+	r.POST("/:id/voyages/reparent", h.handleReparentVoyages)
+	r.POST("/:id/ports/sync-laytime", h.handleSyncPortLaytime)
+	r.GET("/:id/terms", h.handleEffectiveTerms)
+	r.GET("/:id/laytime-terms", h.handleListLaytimeTerms)
+	r.POST("/:id/laytime-terms", h.handleCreateLaytimeTerm)
+	r.PUT("/:id/laytime-terms/:termId", h.handleUpdateLaytimeTerm)
+	r.DELETE("/:id/laytime-terms/:termId", h.handleDeleteLaytimeTerm)
+	r.GET("/:id/laytime/summary", h.handleLaytimeSummary)
+	r.GET("/:id/laytime/totals", h.handleLaytimeTotals)
+	r.GET("/:id/laytime/open", h.handleListOpenLaytime)
+	r.POST("/:id/laytime/close", h.handleCloseOpenLaytime)
+	r.POST("/:id/laytime/recompute", h.handleRecomputeLaytime)
+	r.PUT("/:id/laytime/mode", h.handleSetLaytimeMode)
+	r.POST("/:id/ai-status", h.handleSetAIStatus)
+	r.POST("/:id/status", h.handleSetStatus)
+	r.POST("/:id/close", h.handleClose)
+	r.POST("/with-voyage", h.handleCreateWithVoyage)
+	r.POST("/validate", h.handleValidate)
+}
+
+// AddAdminRoutes mounts the whole-charter export and import, which move every
+// child row at once. The group must be restricted to admins.
+func (h *Handler) AddAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/:id/export", middleware.LongRunning(), h.handleExport)
+	r.POST("/import", middleware.Transactional(), h.handleImport)
+}
+
+// parsePage reads limit (default 20, at most 100) and offset query params.
+// Out-of-range values fall back to the defaults.
+func parsePage(c *gin.Context) db.Page {
+	page := db.Page{Limit: 20}
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			page.Limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			page.Offset = parsed
+		}
+	}
+	return page
+}
+
+func (h *Handler) handleList(c *gin.Context) {
+	page := parsePage(c)
+	filter, ok := parseListFilter(c)
+	if !ok {
+		return
+	}
+	filtered := filter != (db.CharterListFilter{})
+
+	if include := c.Query("include"); include != "" {
+		if include != "voyage_counts" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "include must be voyage_counts"})
+			return
+		}
+		if filtered || c.Query("without_voyages") != "" || c.Query("q") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "include=voyage_counts cannot be combined with status, start dates, without_voyages or q"})
+			return
+		}
+		h.listWithVoyageCounts(c, page)
+		return
+	}
+
+	withoutVoyages := false
+	if wv := c.Query("without_voyages"); wv != "" {
+		parsed, err := strconv.ParseBool(wv)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "without_voyages must be true or false"})
+			return
+		}
+		if parsed && filtered {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "without_voyages cannot be combined with status or start dates"})
+			return
+		}
+		withoutVoyages = parsed
+	}
+	q := strings.TrimSpace(c.Query("q"))
+	if q != "" && (withoutVoyages || filtered) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q cannot be combined with status, start dates or without_voyages"})
+		return
+	}
+
+	var (
+		charters []db.CharterDetail
+		err      error
+	)
+	switch {
+	case q != "":
+		charters, err = h.charterRepo.Search(c.Request.Context(), q, page.Limit, page.Offset)
+	case withoutVoyages:
+		charters, err = h.charterRepo.ListWithoutVoyages(c.Request.Context(), page)
+	case filter.Status != nil && *filter.Status == "active" && filter.StartAfter == nil && filter.StartBefore == nil:
+		charters, err = h.charterRepo.ListActive(c.Request.Context(), page)
+	case filtered:
+		charters, err = h.charterRepo.ListFiltered(c.Request.Context(), filter, page.Limit, page.Offset)
+	default:
+		var total int
+		charters, total, err = h.charterRepo.List(c.Request.Context(), page.Limit, page.Offset)
+		if err == nil {
+			render.TotalHeader(c, total)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list charters"})
+		return
+	}
+
+	if charters == nil {
+		charters = []db.CharterDetail{}
+	}
+
+	render.PageHeaders(c, page.Limit, page.Offset)
+	render.List(c, "charters.csv", render.Envelope(c, charters), charters)
+}
+
+// parseListFilter reads ?status=, ?start_after= and ?start_before= (both
+// YYYY-MM-DD; start_after inclusive, start_before exclusive). It writes a 400
+// and returns false when a date is malformed.
+func parseListFilter(c *gin.Context) (db.CharterListFilter, bool) {
+	var filter db.CharterListFilter
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		filter.Status = &status
+	}
+	for _, bound := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"start_after", &filter.StartAfter},
+		{"start_before", &filter.StartBefore},
+	} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bound.param + " must be YYYY-MM-DD"})
+			return db.CharterListFilter{}, false
+		}
+		*bound.dst = &t
+	}
+	return filter, true
+}
+
+// handleStream serves every charter as NDJSON or CSV without paging, for
+// clients that need the full set.
+func (h *Handler) handleStream(c *gin.Context) {
+	render.Stream(c, "charters.csv", func(yield func(db.CharterDetail) error) error {
+		return h.charterRepo.Each(c.Request.Context(), yield)
+	})
+}
+
+func (h *Handler) listWithVoyageCounts(c *gin.Context, page db.Page) {
+	charters, err := h.charterRepo.ListWithVoyageCounts(c.Request.Context(), page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list charters"})
+		return
+	}
+
+	if charters == nil {
+		charters = []db.CharterWithCounts{}
+	}
+
+	render.PageHeaders(c, page.Limit, page.Offset)
+	render.List(c, "charters.csv", render.Envelope(c, charters), charters)
+}
+
+func (h *Handler) handleListExpiring(c *gin.Context) {
+	days := 30
+	if d := c.Query("within_days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be between 1 and 365"})
+			return
+		}
+		days = parsed
+	}
+
+	charters, err := h.charterRepo.ListExpiring(c.Request.Context(), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list expiring charters"})
+		return
+	}
+
+	if charters == nil {
+		charters = []db.CharterDetail{}
+	}
+
+	render.Data(c, charters)
+}
+
+// loadCharter parses the :id param and ensures the charter exists. It writes
+// the error response and returns false when the request should stop.
+func (h *Handler) loadCharter(c *gin.Context) (db.CharterDetail, bool) {
+	charterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid charter ID"})
+		return db.CharterDetail{}, false
+	}
+
+	charter, err := h.charterRepo.Retrieve(c.Request.Context(), charterID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+			return db.CharterDetail{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve charter"})
+		return db.CharterDetail{}, false
+	}
+
+	return charter, true
+}
+
+// loadParticipantCharter is loadCharter restricted to callers that pass
+// requireCharterAccess.
+func (h *Handler) loadParticipantCharter(c *gin.Context) (db.CharterDetail, bool) {
+	charter, ok := h.loadCharter(c)
+	if !ok || !requireCharterAccess(c, charter) {
+		return db.CharterDetail{}, false
+	}
+	return charter, true
+}
+
+// handleCreate creates a charter owned by the caller. It always starts as a
+// draft with ai_status pending; later moves go through /status and /ai-status.
+func (h *Handler) handleCreate(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var charter db.CharterDetail
+	if err := c.ShouldBindJSON(&charter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if issues := charter.Validate(); len(issues) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"valid": false, "errors": issues})
+		return
+	}
+
+	charter.CreatedByUserID = &userID
+	charter.OrgID = uuid.Nil
+	charter.Status = ""
+	charter.AIStatus = ""
+	charter.AIDocumentPath = nil
+	charter.ArchivedAt = nil
+
+	if err := h.charterRepo.Create(c.Request.Context(), &charter); err != nil {
+		if errors.Is(err, db.ErrInvalidCharterDates) || errors.Is(err, db.ErrInvalidJSON) || errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create charter"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, charter)
+}
+
+func (h *Handler) handleGet(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, charter)
+}
+
+// handleUpdate replaces the charter's editable fields with the body. Status,
+// ai_status and the AI document are kept as stored: they only change through
+// /status and /ai-status, which enforce their transitions.
+func (h *Handler) handleUpdate(c *gin.Context) {
+	existing, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var charter db.CharterDetail
+	if err := c.ShouldBindJSON(&charter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if issues := charter.Validate(); len(issues) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"valid": false, "errors": issues})
+		return
+	}
+
+	charter.ID = existing.ID
+	charter.OrgID = existing.OrgID
+	charter.CreatedByUserID = existing.CreatedByUserID
+	charter.Status = existing.Status
+	charter.AIStatus = existing.AIStatus
+	charter.AIDocumentPath = existing.AIDocumentPath
+	charter.ArchivedAt = existing.ArchivedAt
+	charter.CreatedAt = existing.CreatedAt
+
+	if err := h.charterRepo.Update(c.Request.Context(), &charter); err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+		case errors.Is(err, db.ErrInvalidCharterDates), errors.Is(err, db.ErrInvalidJSON), errors.Is(err, db.ErrNotesTooLong):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update charter"})
+		}
+		return
+	}
+	h.recordChange(c, existing, charter)
+
+	c.JSON(http.StatusOK, charter)
+}
+
+func (h *Handler) handleDelete(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	if err := h.charterRepo.Delete(c.Request.Context(), charter.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete charter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "charter deleted"})
+}
+
+func (h *Handler) handleListDisputes(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+	page := parsePage(c)
+
+	disputes, err := h.disputeRepo.ListByCharter(c.Request.Context(), charter.ID, page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list disputes"})
+		return
+	}
+
+	if disputes == nil {
+		disputes = []db.Dispute{}
+	}
+
+	render.Paged(c, disputes, page.Limit, page.Offset)
+}
+
+func (h *Handler) handleListDemurrage(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+	page := parsePage(c)
+
+	records, err := h.demurrageRepo.ListByCharter(c.Request.Context(), charter.ID, page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list demurrage records"})
+		return
+	}
+
+	if records == nil {
+		records = []db.DemurrageRecord{}
+	}
+
+	render.Paged(c, records, page.Limit, page.Offset)
+}
+
+// handleReconcileDemurrage lists demurrage claims that do not match the
+// laytime entries they reference.
+func (h *Handler) handleReconcileDemurrage(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	discrepancies, err := h.demurrageRepo.ReconcileWithLaytime(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reconcile demurrage"})
+		return
+	}
+
+	if discrepancies == nil {
+		discrepancies = []db.Discrepancy{}
+	}
+
+	render.Data(c, discrepancies)
+}
+
+// handlePaymentTotals sums the charter's payments per currency. An optional
+// currency_order (comma-separated) overrides the configured ordering.
+func (h *Handler) handlePaymentTotals(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	var preferred []string
+	if raw := c.Query("currency_order"); raw != "" {
+		preferred = strings.Split(raw, ",")
+	}
+
+	totals, err := h.paymentRepo.TotalsByCharter(c.Request.Context(), charter.ID, preferred)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to total payments"})
+		return
+	}
+
+	if totals == nil {
+		totals = []db.CurrencyTotal{}
+	}
+
+	render.Data(c, totals)
+}
+
+// handleArchiveVoyages archives the completed voyages of a closed charter.
+func (h *Handler) handleArchiveVoyages(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	archived, err := h.voyageRepo.ArchiveCompletedByCharter(c.Request.Context(), charter.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrCharterNotClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "charter must be closed before its voyages are archived"})
+			return
+		}
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to archive voyages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"archived": archived})
+}
+
+// handleChanges serves incremental sync: up to ?limit= (default 50, at most
+// db.MaxChangesPage) charters updated and deleted after ?cursor= (omit it for
+// a full sync). Pass the returned next as cursor on the following call; more
+// means another page is ready now.
+func (h *Handler) handleChanges(c *gin.Context) {
+	var cursor db.ChangeCursor
+	if raw := c.Query("cursor"); raw != "" {
+		parsed, err := db.DecodeChangeCursor(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		cursor = parsed
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > db.MaxChangesPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", db.MaxChangesPage)})
+			return
+		}
+		limit = parsed
+	}
+
+	changes, err := h.charterRepo.Changes(c.Request.Context(), cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list charter changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// handleLatestVoyage returns the charter's current voyage.
+func (h *Handler) handleLatestVoyage(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	v, err := h.voyageRepo.LatestByCharter(c.Request.Context(), charter.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter has no voyages"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load latest voyage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// handleSyncPortLaytime copies laytime totals from entries onto the ports of
+// every voyage under the charter.
+func (h *Handler) handleSyncPortLaytime(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.portRepo.SyncLaytimeForCharter(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sync port laytime"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+type ReparentVoyagesRequest struct {
+	VoyageIDs []uuid.UUID `json:"voyage_ids" binding:"required,min=1"`
+}
+
+// handleReparentVoyages moves voyages owned by the caller onto this charter,
+// taking their laytime entries, disputes, bills and demurrage with them. The
+// caller must have access both to this charter and to the charter each voyage
+// leaves.
+func (h *Handler) handleReparentVoyages(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req ReparentVoyagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checked := map[uuid.UUID]bool{charter.ID: true}
+	for _, id := range req.VoyageIDs {
+		v, err := h.voyageRepo.Retrieve(c.Request.Context(), id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "voyage " + id.String() + " not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve voyage"})
+			return
+		}
+		if v.OwnerUserID == nil || *v.OwnerUserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			return
+		}
+		if v.CharterDetailID == nil || checked[*v.CharterDetailID] {
+			continue
+		}
+		source, err := h.charterRepo.Retrieve(c.Request.Context(), *v.CharterDetailID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve charter"})
+			return
+		}
+		if !requireCharterAccess(c, source) {
+			return
+		}
+		checked[source.ID] = true
+	}
+
+	moved, err := h.voyageRepo.Reparent(c.Request.Context(), req.VoyageIDs, charter.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to move voyages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"moved": moved})
+}
+
+func (h *Handler) handleListLaytimeTerms(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	terms, err := h.termRepo.ListByCharter(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list laytime terms"})
+		return
+	}
+
+	if terms == nil {
+		terms = []db.CharterLaytimeTerm{}
+	}
+
+	render.Data(c, terms)
+}
+
+// handleEffectiveTerms returns the charter's commercial terms with per-port
+// allowances merged in and defaults filled.
+func (h *Handler) handleEffectiveTerms(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	terms, err := h.termRepo.ListByCharter(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list laytime terms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, db.ResolveCharterTerms(charter, terms))
+}
+
+type LaytimeTermRequest struct {
+	PortRole       *string  `json:"port_role"`
+	PortName       string   `json:"port_name" binding:"required"`
+	AllowanceHours *float64 `json:"allowance_hours" binding:"required"`
+	Reversible     bool     `json:"reversible"`
+	Notes          *string  `json:"notes"`
+}
+
+func (req LaytimeTermRequest) validate() string {
+	if strings.TrimSpace(req.PortName) == "" {
+		return "port_name is required"
+	}
+	if req.PortRole != nil && *req.PortRole != "load" && *req.PortRole != "discharge" {
+		return "port_role must be load or discharge"
+	}
+	if *req.AllowanceHours < 0 {
+		return "allowance_hours must not be negative"
+	}
+	return ""
+}
+
+func (h *Handler) handleCreateLaytimeTerm(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req LaytimeTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	term := &db.CharterLaytimeTerm{
+		CharterDetailID: charter.ID,
+		PortRole:        req.PortRole,
+		PortName:        strings.TrimSpace(req.PortName),
+		AllowanceHours:  *req.AllowanceHours,
+		Reversible:      req.Reversible,
+		Notes:           req.Notes,
+	}
+
+	if err := h.termRepo.Create(c.Request.Context(), term); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create laytime term"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+func (h *Handler) handleUpdateLaytimeTerm(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	termID, err := uuid.Parse(c.Param("termId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid laytime term ID"})
+		return
+	}
+
+	existing, err := h.termRepo.Retrieve(c.Request.Context(), termID)
+	if err != nil || existing.CharterDetailID != charter.ID {
+		if err == nil || err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "laytime term not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve laytime term"})
+		return
+	}
+
+	var req LaytimeTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	existing.PortRole = req.PortRole
+	existing.PortName = strings.TrimSpace(req.PortName)
+	existing.AllowanceHours = *req.AllowanceHours
+	existing.Reversible = req.Reversible
+	if req.Notes != nil {
+		existing.Notes = req.Notes
+	}
+
+	if err := h.termRepo.Update(c.Request.Context(), &existing); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "laytime term not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update laytime term"})
+		return
+	}
+
+	c.JSON(http.StatusOK, existing)
+}
+
+func (h *Handler) handleDeleteLaytimeTerm(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	termID, err := uuid.Parse(c.Param("termId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid laytime term ID"})
+		return
+	}
+
+	existing, err := h.termRepo.Retrieve(c.Request.Context(), termID)
+	if err != nil || existing.CharterDetailID != charter.ID {
+		if err == nil || err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "laytime term not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve laytime term"})
+		return
+	}
+
+	if err := h.termRepo.Delete(c.Request.Context(), termID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete laytime term"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "laytime term deleted"})
+}
+
+func (h *Handler) handleLaytimeSummary(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.charterRepo.CalcLaytime(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate laytime"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func (h *Handler) handleLaytimeTotals(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	totals, err := h.charterRepo.LaytimeTotals(c.Request.Context(), charter.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "laytime totals have not been computed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve laytime totals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, totals)
+}
+
+func (h *Handler) handleListOpenLaytime(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.laytimeRepo.ListOpen(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list open laytime entries"})
+		return
+	}
+
+	if entries == nil {
+		entries = []db.LaytimeEntry{}
+	}
+
+	render.Data(c, entries)
+}
+
+type CloseLaytimeRequest struct {
+	PortName string     `json:"port_name" binding:"required"`
+	EndedAt  *time.Time `json:"ended_at" binding:"required"`
+}
+
+func (h *Handler) handleCloseOpenLaytime(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req CloseLaytimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.PortName) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "port_name is required"})
+		return
+	}
+
+	closed, err := h.laytimeRepo.CloseOpen(c.Request.Context(), charter.ID, req.PortName, *req.EndedAt)
+	if err != nil {
+		if errors.Is(err, db.ErrEndBeforeStart) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ended_at is before the start of an open entry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to close laytime entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"closed": closed})
+}
+
+func (h *Handler) handleRecomputeLaytime(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	totals, err := h.charterRepo.RecomputeLaytime(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to recompute laytime"})
+		return
+	}
+
+	c.JSON(http.StatusOK, totals)
+}
+
+type LaytimeModeRequest struct {
+	Reversible *bool `json:"reversible" binding:"required"`
+}
+
+func (h *Handler) handleSetLaytimeMode(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req LaytimeModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := charter
+	charter.LaytimeReversible = *req.Reversible
+	if err := h.charterRepo.Update(c.Request.Context(), &charter); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+			return
+		}
+		if errors.Is(err, db.ErrInvalidCharterDates) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update laytime mode"})
+		return
+	}
+	h.recordChange(c, before, charter)
+
+	c.JSON(http.StatusOK, charter)
+}
+
+type AIStatusRequest struct {
+	Status       string  `json:"status" binding:"required"`
+	DocumentPath *string `json:"document_path"`
+}
+
+// handleSetAIStatus advances the charter's AI extraction workflow. Moves the
+// workflow does not allow get a 409.
+func (h *Handler) handleSetAIStatus(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req AIStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.charterRepo.SetAIStatus(c.Request.Context(), charter.ID, req.Status, req.DocumentPath)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+		case errors.Is(err, db.ErrInvalidStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, processing, applied or failed"})
+		case errors.Is(err, db.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot move ai_status from " + charter.AIStatus + " to " + req.Status})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update ai status"})
+		}
+		return
+	}
+
+	updated, err := h.charterRepo.Retrieve(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve charter"})
+		return
+	}
+	h.recordChange(c, charter, updated)
+
+	c.JSON(http.StatusOK, updated)
+}
+
+type StatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Force  bool   `json:"force"`
+}
+
+// handleSetStatus changes the charter's status. Moves the status graph does
+// not allow get a 409. Sending it back to draft or cancelling it while
+// voyages or payments are still open gets a 409 listing them, unless force is
+// set.
+func (h *Handler) handleSetStatus(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req StatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dependents, err := h.charterRepo.SetStatus(c.Request.Context(), charter.ID, req.Status, req.Force)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+		case errors.Is(err, db.ErrInvalidStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft, active, completed, closed or cancelled"})
+		case errors.Is(err, db.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot move status from " + charter.Status + " to " + req.Status})
+		case errors.Is(err, db.ErrHasActiveDependents):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "dependents": dependents})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
+		}
+		return
+	}
+
+	updated, err := h.charterRepo.Retrieve(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve charter"})
+		return
+	}
+	h.recordChange(c, charter, updated)
+
+	c.JSON(http.StatusOK, updated)
+}
+
+type CloseRequest struct {
+	Force bool `json:"force"`
+}
+
+// handleClose closes the charter and archives its completed voyages. Open
+// disputes get a 409 listing them unless force is set; outstanding payments
+// are returned alongside a successful close.
+func (h *Handler) handleClose(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	var req CloseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	res, err := h.charterRepo.Close(c.Request.Context(), charter.ID, req.Force)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			c.JSON(http.StatusNotFound, gin.H{"error": "charter not found"})
+		case errors.Is(err, db.ErrHasOpenDisputes):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "result": res})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to close charter"})
+		}
+		return
+	}
+
+	if updated, err := h.charterRepo.Retrieve(c.Request.Context(), charter.ID); err == nil {
+		h.recordChange(c, charter, updated)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// recordChange writes an audit entry for the fields that differ between
+// before and after. Failures are logged; the edit itself has already been
+// saved.
+func (h *Handler) recordChange(c *gin.Context, before, after db.CharterDetail) {
+	changes, err := db.DiffFields(before, after)
+	if err == nil {
+		entry := &db.AuditEntry{
+			EntityType: db.AuditEntityCharter,
+			EntityID:   after.ID,
+			Changes:    changes,
+		}
+		if userID, ok := c.Get("userID"); ok {
+			if id, ok := userID.(uuid.UUID); ok {
+				entry.UserID = &id
+			}
+		}
+		err = h.auditRepo.Record(c.Request.Context(), entry)
+	}
+	if err != nil {
+		log.Printf("audit charter %s: %v", after.ID, err)
+	}
+}
+
+func (h *Handler) handleFieldHistory(c *gin.Context) {
+	charter, ok := h.loadParticipantCharter(c)
+	if !ok {
+		return
+	}
+
+	field := strings.TrimSpace(c.Query("field"))
+	if field == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field is required"})
+		return
+	}
+
+	history, err := h.auditRepo.FieldHistory(c.Request.Context(), db.AuditEntityCharter, charter.ID, field)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load field history"})
+		return
+	}
+
+	if history == nil {
+		history = []db.FieldChange{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"field": field, "data": history})
+}
+
+func (h *Handler) handleExport(c *gin.Context) {
+	charter, ok := h.loadCharter(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.charterRepo.CharterExport(c.Request.Context(), charter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export charter"})
+		return
+	}
+
+	name := charter.ID.String()
+	if charter.CharterReferenceCode != nil {
+		if ref := attachmentName(*charter.CharterReferenceCode); ref != "" {
+			name = ref
+		}
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "charter-"+name+".json"))
+	c.JSON(http.StatusOK, export)
+}
+
+// attachmentName reduces a user-supplied value to something safe inside a
+// Content-Disposition filename: its last path element, without quotes,
+// backslashes or control characters.
+func attachmentName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, filepath.Base(strings.TrimSpace(s)))
+	if s == "." || s == "/" || s == ".." {
+		return ""
+	}
+	return s
+}
+
+func (h *Handler) handleImport(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var export db.CharterExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(export.Charter.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "charter.title is required"})
+		return
+	}
+
+	charter, err := h.charterRepo.CharterImport(c.Request.Context(), export, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidCharterDates) || errors.Is(err, db.ErrInvalidJSON) || errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrInvalidUNLocode) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrTooManyPorts) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import charter"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, charter)
+}
+
+// CharterWithVoyageRequest is the body of POST /charters/with-voyage.
+type CharterWithVoyageRequest struct {
+	Charter db.CharterDetail `json:"charter"`
+	Voyage  db.Voyage        `json:"voyage"`
+}
+
+// handleCreateWithVoyage creates a charter together with its first voyage;
+// either both are created or neither is.
+func (h *Handler) handleCreateWithVoyage(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req CharterWithVoyageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if issues := req.Charter.Validate(); len(issues) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"valid": false, "errors": issues})
+		return
+	}
+
+	charter, voyage := req.Charter, req.Voyage
+	charter.CreatedByUserID = &userID
+	charter.OrgID = uuid.Nil
+	voyage.OwnerUserID = &userID
+	voyage.DealID = nil
+	voyage.DocumentID = nil
+	voyage.CounterpartyUserID = nil
+	voyage.BrokerUserID = nil
+
+	if err := h.charterRepo.CreateCharterWithVoyage(c.Request.Context(), &charter, &voyage); err != nil {
+		if errors.Is(err, db.ErrInvalidCharterDates) || errors.Is(err, db.ErrInvalidJSON) || errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create charter with voyage"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"charter": charter, "voyage": voyage})
+}
+
+func (h *Handler) handleValidate(c *gin.Context) {
+	var charter db.CharterDetail
+	if err := c.ShouldBindJSON(&charter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issues := charter.Validate()
+	if len(issues) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"valid": false, "errors": issues})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": true, "errors": []db.FieldError{}})
+}
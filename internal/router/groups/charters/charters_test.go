@@ -0,0 +1,707 @@
+package charters
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/router/render"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterCRUD(t *testing.T) {
+	owner := newTestUser("shipowner")
+	stranger := newTestUser("charterer")
+	otherOrg := newTestUser("shipowner")
+	otherOrg.OrgID = uuid.New()
+	admin := newTestUser("admin")
+	charter := newCharter(owner.ID)
+	missing := uuid.New()
+	longNotes := strings.Repeat("x", db.MaxNotesLength+1)
+
+	tests := []struct {
+		name       string
+		user       testUser
+		method     string
+		path       string
+		body       string
+		setup      func(*dbtest.Fake)
+		wantStatus int
+	}{
+		{"get", stranger, http.MethodGet, "/" + charter.ID.String(), "", nil, http.StatusOK},
+		{"get invalid id", owner, http.MethodGet, "/not-a-uuid", "", nil, http.StatusBadRequest},
+		{"get missing", owner, http.MethodGet, "/" + missing.String(), "", nil, http.StatusNotFound},
+		{"get other org", otherOrg, http.MethodGet, "/" + charter.ID.String(), "", nil, http.StatusNotFound},
+		{"get db error", owner, http.MethodGet, "/" + charter.ID.String(), "", func(f *dbtest.Fake) {
+			f.Return(charterRetrieveQuery, dbtest.Fail(errors.New("connection reset")))
+		}, http.StatusInternalServerError},
+
+		{"create", owner, http.MethodPost, "/", `{"title":"New charter","status":"closed"}`, nil, http.StatusCreated},
+		{"create malformed", owner, http.MethodPost, "/", `{"title":`, nil, http.StatusBadRequest},
+		{"create without title", owner, http.MethodPost, "/", `{"title":"  "}`, nil, http.StatusUnprocessableEntity},
+		{"create end before start", owner, http.MethodPost, "/",
+			`{"title":"x","start_date":"2026-05-01T00:00:00Z","end_date":"2026-04-01T00:00:00Z"}`, nil, http.StatusUnprocessableEntity},
+		{"create spanning a century", owner, http.MethodPost, "/",
+			`{"title":"x","start_date":"2026-05-01T00:00:00Z","end_date":"2126-05-01T00:00:00Z"}`, nil, http.StatusUnprocessableEntity},
+		{"create with dates", owner, http.MethodPost, "/",
+			`{"title":"x","start_date":"2026-04-01T00:00:00Z","end_date":"2026-05-01T00:00:00Z"}`, nil, http.StatusCreated},
+		{"create notes too long", owner, http.MethodPost, "/", `{"title":"x","notes":"` + longNotes + `"}`, nil, http.StatusBadRequest},
+		{"create db error", owner, http.MethodPost, "/", `{"title":"New charter"}`, func(f *dbtest.Fake) {
+			f.Return("INSERT INTO shipman.charter_details", dbtest.Fail(errors.New("connection reset")))
+		}, http.StatusInternalServerError},
+
+		{"update by creator", owner, http.MethodPut, "/" + charter.ID.String(), `{"title":"Renamed","status":"closed"}`, nil, http.StatusOK},
+		{"update by admin", admin, http.MethodPut, "/" + charter.ID.String(), `{"title":"Renamed"}`, nil, http.StatusOK},
+		{"update by participant", stranger, http.MethodPut, "/" + charter.ID.String(), `{"title":"Renamed"}`, stubParticipant, http.StatusOK},
+		{"update by stranger", stranger, http.MethodPut, "/" + charter.ID.String(), `{"title":"Renamed"}`, nil, http.StatusForbidden},
+		{"update missing", owner, http.MethodPut, "/" + missing.String(), `{"title":"Renamed"}`, nil, http.StatusNotFound},
+		{"update end before start", owner, http.MethodPut, "/" + charter.ID.String(),
+			`{"title":"x","start_date":"2026-05-01T00:00:00Z","end_date":"2026-04-01T00:00:00Z"}`, nil, http.StatusUnprocessableEntity},
+		{"update without title", owner, http.MethodPut, "/" + charter.ID.String(), `{"title":""}`, nil, http.StatusUnprocessableEntity},
+		{"update notes too long", owner, http.MethodPut, "/" + charter.ID.String(), `{"title":"x","notes":"` + longNotes + `"}`, nil, http.StatusBadRequest},
+		{"update malformed", owner, http.MethodPut, "/" + charter.ID.String(), `[`, nil, http.StatusBadRequest},
+		{"update deleted meanwhile", owner, http.MethodPut, "/" + charter.ID.String(), `{"title":"Renamed"}`, func(f *dbtest.Fake) {
+			f.Return("UPDATE shipman.charter_details SET title = $2", dbtest.Rows([]string{"updated_at"}))
+		}, http.StatusNotFound},
+		{"update db error", owner, http.MethodPut, "/" + charter.ID.String(), `{"title":"Renamed"}`, func(f *dbtest.Fake) {
+			f.Return("UPDATE shipman.charter_details SET title = $2", dbtest.Fail(errors.New("connection reset")))
+		}, http.StatusInternalServerError},
+
+		{"delete by creator", owner, http.MethodDelete, "/" + charter.ID.String(), "", nil, http.StatusOK},
+		{"delete by stranger", stranger, http.MethodDelete, "/" + charter.ID.String(), "", nil, http.StatusForbidden},
+		{"delete missing", owner, http.MethodDelete, "/" + missing.String(), "", nil, http.StatusNotFound},
+		{"delete db error", owner, http.MethodDelete, "/" + charter.ID.String(), "", func(f *dbtest.Fake) {
+			f.Return("DELETE FROM shipman.charter_details", dbtest.Fail(errors.New("connection reset")))
+		}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			fake.Return("INSERT INTO shipman.charter_details", dbtest.Rows(
+				[]string{"id", "status", "ai_status", "created_at", "updated_at"},
+				[]any{uuid.New(), "draft", "pending", time.Now(), time.Now()}))
+			fake.Return("UPDATE shipman.charter_details SET title = $2", dbtest.Rows([]string{"updated_at"}, []any{time.Now()}))
+			fake.Return("DELETE FROM shipman.charter_details", dbtest.Rows([]string{"org_id"}, []any{charter.OrgID}))
+			fake.Return("INSERT INTO shipman.deletions", dbtest.Affected(1))
+			if tt.setup != nil {
+				tt.setup(fake)
+			}
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, tt.user, tt.method, tt.path, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCharterCreateDespatchRate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantArg    any
+	}{
+		{"set", `{"title":"Grain","despatch_rate":9600}`, http.StatusCreated, 9600.0},
+		{"unset defaults later", `{"title":"Grain"}`, http.StatusCreated, nil},
+		{"negative", `{"title":"Grain","despatch_rate":-1}`, http.StatusUnprocessableEntity, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("INSERT INTO shipman.charter_details", dbtest.Rows(
+				[]string{"id", "status", "ai_status", "created_at", "updated_at"},
+				[]any{uuid.New(), "draft", "pending", time.Now(), time.Now()}))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("shipowner"), http.MethodPost, "/", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls("INSERT INTO shipman.charter_details")
+			if tt.wantStatus != http.StatusCreated {
+				if len(calls) != 0 {
+					t.Error("inserted a charter with a negative despatch rate")
+				}
+				return
+			}
+			if got := calls[0].Arg(21); got != tt.wantArg {
+				t.Errorf("despatch_rate = %v, want %v", got, tt.wantArg)
+			}
+		})
+	}
+}
+
+func TestCharterCreateStartsAsDraft(t *testing.T) {
+	owner := newTestUser("shipowner")
+	fake := newFakeDB(t)
+	fake.Return("INSERT INTO shipman.charter_details", dbtest.Rows(
+		[]string{"id", "status", "ai_status", "created_at", "updated_at"},
+		[]any{uuid.New(), "draft", "pending", time.Now(), time.Now()}))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, owner, http.MethodPost, "/", `{"title":"New charter","status":"closed","ai_status":"applied"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	insert := fake.Calls("INSERT INTO shipman.charter_details")[0]
+	if got := insert.Arg(1); got != owner.ID.String() {
+		t.Errorf("created_by_user_id = %v, want %s", got, owner.ID)
+	}
+	if got := insert.Arg(6); got != "draft" {
+		t.Errorf("status = %v, want draft", got)
+	}
+	if got := insert.Arg(14); got != "pending" {
+		t.Errorf("ai_status = %v, want pending", got)
+	}
+}
+
+func TestCharterUpdateKeepsGuardedFields(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	charter.Status = "active"
+	charter.AIStatus = "applied"
+	fake := newFakeDB(t)
+	stubCharters(fake, charter)
+	fake.Return("UPDATE shipman.charter_details SET title = $2", dbtest.Rows([]string{"updated_at"}, []any{time.Now()}))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, owner, http.MethodPut, "/"+charter.ID.String(), `{"title":"Renamed","status":"draft","ai_status":"pending"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	update := fake.Calls("UPDATE shipman.charter_details SET title = $2")[0]
+	if got := update.Arg(2); got != "Renamed" {
+		t.Errorf("title = %v, want Renamed", got)
+	}
+	if got := update.Arg(6); got != "active" {
+		t.Errorf("status = %v, want the stored active", got)
+	}
+	if got := update.Arg(14); got != "applied" {
+		t.Errorf("ai_status = %v, want the stored applied", got)
+	}
+	if len(fake.Calls("INSERT INTO shipman.audit_log")) != 1 {
+		t.Error("update was not audited")
+	}
+}
+
+func TestCharterListDefaults(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantLimit  int64
+		wantOffset int64
+	}{
+		{"", 20, 0},
+		{"?limit=5&offset=10", 5, 10},
+		{"?limit=0&offset=-1", 20, 0},
+		{"?limit=abc", 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("COUNT(*) OVER () AS total FROM shipman.charter_details", dbtest.Rows(
+				[]string{"id", "title", "status", "created_at", "updated_at", "total"},
+				[]any{uuid.New(), "Grain charter", "draft", time.Now(), time.Now(), 41}))
+			fake.Return("SELECT COUNT(*) FROM shipman.charter_details", dbtest.Rows([]string{"count"}, []any{41}))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("shipowner"), http.MethodGet, "/"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			list := fake.Calls("COUNT(*) OVER () AS total")[0]
+			if list.Arg(1) != tt.wantLimit || list.Arg(2) != tt.wantOffset {
+				t.Errorf("limit, offset = %v, %v; want %d, %d", list.Arg(1), list.Arg(2), tt.wantLimit, tt.wantOffset)
+			}
+			var body struct {
+				Data []db.CharterDetail `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 1 {
+				t.Errorf("data has %d rows, want 1", len(body.Data))
+			}
+			if got := w.Header().Get("X-Total-Count"); got != "41" {
+				t.Errorf("X-Total-Count = %q, want 41", got)
+			}
+		})
+	}
+}
+
+func TestCharterSetStatusEndpoint(t *testing.T) {
+	owner := newTestUser("shipowner")
+	stranger := newTestUser("charterer")
+	charter := newCharter(owner.ID)
+	charter.Status = "active"
+	dependents := dbtest.Rows([]string{"kind", "id", "status"}, []any{"voyage", uuid.New(), "sailing"})
+
+	tests := []struct {
+		name       string
+		user       testUser
+		body       string
+		current    string
+		wantStatus int
+		wantUpdate bool
+	}{
+		{"stranger", stranger, `{"status":"draft","force":true}`, "active", http.StatusForbidden, false},
+		{"blocked revert", owner, `{"status":"draft"}`, "active", http.StatusConflict, false},
+		{"forced revert", owner, `{"status":"draft","force":true}`, "active", http.StatusOK, true},
+		{"closed is final", owner, `{"status":"active","force":true}`, "closed", http.StatusConflict, false},
+		{"unknown status", owner, `{"status":"archived"}`, "active", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			fake.Return("FROM shipman.charter_details WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"status"}, []any{tt.current}))
+			fake.Return("SELECT kind, id, status FROM", dependents)
+			fake.Return("UPDATE shipman.charter_details SET status = $2", dbtest.Affected(1))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, tt.user, http.MethodPost, "/"+charter.ID.String()+"/status", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if updated := len(fake.Calls("UPDATE shipman.charter_details SET status = $2")) == 1; updated != tt.wantUpdate {
+				t.Errorf("updated = %v, want %v", updated, tt.wantUpdate)
+			}
+			if tt.name == "blocked revert" {
+				var body struct {
+					Dependents []db.ActiveDependent `json:"dependents"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if len(body.Dependents) != 1 {
+					t.Errorf("dependents = %v, want the sailing voyage", body.Dependents)
+				}
+			}
+		})
+	}
+}
+
+func TestCharterListRejectsDeepOffset(t *testing.T) {
+	fake := newFakeDB(t)
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, newTestUser("shipowner"), http.MethodGet, "/?offset=50000000", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "cursor pagination") {
+		t.Errorf("body = %s, want a pointer to cursor pagination", w.Body.String())
+	}
+	if calls := fake.Calls("FROM shipman.charter_details"); len(calls) != 0 {
+		t.Errorf("ran %d charter queries, want none", len(calls))
+	}
+}
+
+func TestCharterListNegotiatesFormat(t *testing.T) {
+	tests := []struct {
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"application/json", http.StatusOK, `"title":"Grain charter"`},
+		{"text/csv", http.StatusOK, "Grain charter"},
+		{"application/pdf", http.StatusNotAcceptable, "supported formats"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("COUNT(*) OVER () AS total FROM shipman.charter_details", dbtest.Rows(
+				[]string{"id", "title", "status", "created_at", "updated_at", "total"},
+				[]any{uuid.New(), "Grain charter", "draft", time.Now(), time.Now(), 1}))
+
+			u := newTestUser("shipowner")
+			token, err := testJWT.Generate(u.ID, u.OrgID, "user@example.com", u.Role, "Test User")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			newTestRouter(NewHandler().AddRoutes).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if tt.accept == "text/csv" && !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+				t.Errorf("Content-Type = %q, want text/csv", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCharterListEnvelope(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", `{"data":[{`},
+		{"data", `{"data":[{`},
+		{"none", `[{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("COUNT(*) OVER () AS total FROM shipman.charter_details", dbtest.Rows(
+				[]string{"id", "title", "status", "created_at", "updated_at", "total"},
+				[]any{uuid.New(), "Grain charter", "draft", time.Now(), time.Now(), 1}))
+
+			u := newTestUser("shipowner")
+			token, err := testJWT.Generate(u.ID, u.OrgID, "user@example.com", u.Role, "Test User")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/?limit=5&offset=10", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.header != "" {
+				req.Header.Set(render.HeaderEnvelope, tt.header)
+			}
+			w := httptest.NewRecorder()
+			newTestRouter(NewHandler().AddRoutes).ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if !strings.HasPrefix(w.Body.String(), tt.want) || !strings.Contains(w.Body.String(), `"title":"Grain charter"`) {
+				t.Errorf("body = %s, want it to start with %s", w.Body.String(), tt.want)
+			}
+			if w.Header().Get("X-Page-Limit") != "5" || w.Header().Get("X-Page-Offset") != "10" {
+				t.Errorf("page headers = %q/%q, want 5/10", w.Header().Get("X-Page-Limit"), w.Header().Get("X-Page-Offset"))
+			}
+		})
+	}
+}
+
+func TestCharterChildListsArePaged(t *testing.T) {
+	lists := []struct {
+		path  string
+		match string
+	}{
+		{"disputes", "FROM shipman.disputes WHERE charter_detail_id = $1"},
+		{"demurrage", "FROM shipman.demurrage_records WHERE charter_detail_id = $1"},
+	}
+	tests := []struct {
+		query                 string
+		wantLimit, wantOffset int64
+		wantStatus            int
+	}{
+		{"", 20, 0, http.StatusOK},
+		{"?limit=5&offset=10", 5, 10, http.StatusOK},
+		{"?limit=500", 20, 0, http.StatusOK},
+		{"?offset=-3", 20, 0, http.StatusOK},
+		{"?offset=20000", 0, 0, http.StatusBadRequest},
+	}
+	for _, l := range lists {
+		for _, tt := range tests {
+			t.Run(l.path+tt.query, func(t *testing.T) {
+				fake := newFakeDB(t)
+				admin := newTestUser("admin")
+				charter := newCharter(admin.ID)
+				stubCharters(fake, charter)
+				fake.Return(l.match, dbtest.Rows([]string{"id"}))
+
+				r := newTestRouter(NewHandler().AddRoutes)
+				w := do(t, r, admin, http.MethodGet, "/"+charter.ID.String()+"/"+l.path+tt.query, "")
+				if w.Code != tt.wantStatus {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+				}
+				calls := fake.Calls(l.match)
+				if tt.wantStatus != http.StatusOK {
+					if len(calls) != 0 {
+						t.Errorf("listed despite the rejected offset")
+					}
+					return
+				}
+				if len(calls) != 1 {
+					t.Fatalf("list queries = %d, want 1", len(calls))
+				}
+				if got := calls[0].Arg(2); got != tt.wantLimit {
+					t.Errorf("limit = %v, want %d", got, tt.wantLimit)
+				}
+				if got := calls[0].Arg(3); got != tt.wantOffset {
+					t.Errorf("offset = %v, want %d", got, tt.wantOffset)
+				}
+				if got := w.Header().Get("X-Page-Limit"); got != strconv.FormatInt(tt.wantLimit, 10) {
+					t.Errorf("X-Page-Limit = %q, want %d", got, tt.wantLimit)
+				}
+				var body struct {
+					Data   []json.RawMessage `json:"data"`
+					Limit  int64             `json:"limit"`
+					Offset int64             `json:"offset"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Data == nil || body.Limit != tt.wantLimit || body.Offset != tt.wantOffset {
+					t.Errorf("body = %s, want an empty page at %d/%d", w.Body.String(), tt.wantLimit, tt.wantOffset)
+				}
+			})
+		}
+	}
+}
+
+func TestCharterListActiveFilter(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantActive bool
+	}{
+		{"?status=active", true},
+		{"", false},
+		{"?status=draft", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.charter_details", dbtest.Rows([]string{"id", "title", "status", "created_at", "updated_at", "total"}))
+			fake.Return("FROM shipman.charter_details WHERE status = 'active'", dbtest.Rows([]string{"id", "title", "status", "created_at", "updated_at"}))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodGet, "/"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			active := len(fake.Calls("WHERE status = 'active'")) > 0
+			if active != tt.wantActive {
+				t.Errorf("used the active list = %v, want %v", active, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestCharterListWithoutVoyagesFilter(t *testing.T) {
+	const withoutQuery = "WHERE NOT EXISTS"
+	tests := []struct {
+		query       string
+		wantStatus  int
+		wantWithout bool
+	}{
+		{"?without_voyages=true", http.StatusOK, true},
+		{"?without_voyages=false", http.StatusOK, false},
+		{"?without_voyages=false&status=active", http.StatusOK, false},
+		{"?without_voyages=maybe", http.StatusBadRequest, false},
+		{"?without_voyages=true&status=active", http.StatusBadRequest, false},
+		{"?without_voyages=true&include=voyage_counts", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			summary := []string{"id", "title", "status", "created_at", "updated_at"}
+			fake.Return("FROM shipman.charter_details", dbtest.Rows(append(summary, "total")))
+			fake.Return("FROM shipman.charter_details WHERE status = 'active'", dbtest.Rows(summary))
+			fake.Return(withoutQuery, dbtest.Rows(summary, []any{uuid.New(), "Spot fixture", "draft", time.Now(), time.Now()}))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodGet, "/"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(withoutQuery)
+			if without := len(calls) > 0; without != tt.wantWithout {
+				t.Errorf("used the without-voyages list = %v, want %v", without, tt.wantWithout)
+			}
+			if tt.wantWithout && !strings.Contains(w.Body.String(), "Spot fixture") {
+				t.Errorf("body = %s, want the charter without voyages", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCharterListLimitGuard(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return("FROM shipman.charter_details", dbtest.Rows([]string{"id", "title", "status", "created_at", "updated_at", "total"}))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, newTestUser("broker"), http.MethodGet, "/?limit=50000", "")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"stream":"/api/v1/charters/stream"`) {
+		t.Errorf("body = %s, want a pointer to the stream endpoint", w.Body.String())
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("queried charters for an oversized limit")
+	}
+}
+
+func TestCharterStream(t *testing.T) {
+	fake := newFakeDB(t)
+	var rows [][]any
+	for i := 0; i < 3; i++ {
+		rows = append(rows, []any{uuid.New(), "Charter " + strconv.Itoa(i), "active", time.Now(), time.Now()})
+	}
+	fake.Return("OR org_id = $1) ORDER BY created_at DESC", dbtest.Rows([]string{"id", "title", "status", "created_at", "updated_at"}, rows...))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, newTestUser("broker"), http.MethodGet, "/stream", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], `"title":"Charter 2"`) {
+		t.Errorf("body = %s, want three charters in order", w.Body.String())
+	}
+	if call := fake.Calls("FROM shipman.charter_details")[0]; strings.Contains(call.Query, "LIMIT") {
+		t.Errorf("stream query = %q, want it unpaged", call.Query)
+	}
+}
+
+func TestCharterListIncludeVoyageCounts(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+	}{
+		{"?include=voyage_counts", http.StatusOK},
+		{"?include=voyages", http.StatusBadRequest},
+		{"?include=voyage_counts&status=active", http.StatusBadRequest},
+		{"?include=voyage_counts&q=grain", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+			fake.Return("LEFT JOIN shipman.voyages v ON v.charter_detail_id = cd.id", dbtest.Rows(
+				[]string{"id", "title", "status", "voyage_count", "in_progress", "created_at", "updated_at"},
+				[]any{uuid.New(), "Grain charter", "active", int64(3), int64(1), at, at},
+			))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodGet, "/"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(fake.Calls("FROM shipman.charter_details")) != 0 {
+					t.Error("listed charters despite the bad include")
+				}
+				return
+			}
+			var body struct {
+				Data []db.CharterWithCounts `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 1 || body.Data[0].VoyageCount != 3 || body.Data[0].InProgressVoyageCount != 1 {
+				t.Errorf("data = %+v, want one charter with 3 voyages, 1 in progress", body.Data)
+			}
+		})
+	}
+}
+
+func TestCharterUpdateMissingRow(t *testing.T) {
+	fake := newFakeDB(t)
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	stubCharters(fake, charter)
+	// The charter is deleted between the lookup and the write.
+	fake.Return("UPDATE shipman.charter_details", dbtest.Rows([]string{"updated_at"}))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, owner, http.MethodPut, "/"+charter.ID.String(), `{"title":"Renamed"}`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404: %s", w.Code, w.Body.String())
+	}
+	if len(fake.Calls("UPDATE shipman.charter_details")) != 1 {
+		t.Error("want the update attempted once")
+	}
+}
+
+func TestCharterPaymentTotalsCurrencyOrder(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	fake := newFakeDB(t)
+	stubCharters(fake, charter)
+	fake.Return("FROM shipman.voyage_payments p JOIN shipman.voyages v", dbtest.Rows(
+		[]string{"currency", "count", "total", "paid"},
+		[]any{"USD", 1, 100.0, 100.0},
+		[]any{"BRL", 1, 50.0, 0.0},
+		[]any{"EUR", 1, 75.0, 0.0},
+	))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, owner, http.MethodGet, "/"+charter.ID.String()+"/payments/totals?currency_order=eur,usd", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []db.CurrencyTotal `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, total := range body.Data {
+		got = append(got, total.Currency)
+	}
+	if want := []string{"EUR", "USD", "BRL"}; !slices.Equal(got, want) {
+		t.Errorf("currencies = %v, want %v", got, want)
+	}
+}
+
+func TestCharterReconcileDemurrage(t *testing.T) {
+	const reconcile = "JOIN shipman.laytime_entries le ON le.id = dr.laytime_entry_id"
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	recordID := uuid.New()
+
+	tests := []struct {
+		name       string
+		path       string
+		rows       dbtest.Result
+		wantStatus int
+		wantBody   string
+	}{
+		{"mismatch", "/" + charter.ID.String() + "/demurrage/reconcile", dbtest.Rows(
+			[]string{"id", "laytime_id", "claimed_hours", "hours_counted"},
+			[]any{uuid.New(), uuid.New(), 10.0, 10.0},
+			[]any{recordID, uuid.New(), 30.0, 20.0},
+		), http.StatusOK, `"demurrage_record_id":"` + recordID.String() + `"`},
+		{"all matching", "/" + charter.ID.String() + "/demurrage/reconcile", dbtest.Rows(
+			[]string{"id", "laytime_id", "claimed_hours", "hours_counted"},
+			[]any{uuid.New(), uuid.New(), 10.0, 10.0},
+		), http.StatusOK, `{"data":[]}`},
+		{"missing charter", "/" + uuid.NewString() + "/demurrage/reconcile", dbtest.Result{}, http.StatusNotFound, ""},
+		{"db error", "/" + charter.ID.String() + "/demurrage/reconcile", dbtest.Fail(errors.New("connection reset")), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			fake.Return(reconcile, tt.rows)
+
+			w := do(t, newTestRouter(NewHandler().AddRoutes), owner, http.MethodGet, tt.path, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if tt.name == "mismatch" && !strings.Contains(w.Body.String(), `"difference_hours":10,"reason":"mismatch"`) {
+				t.Errorf("body = %s, want a 10 hour mismatch", w.Body.String())
+			}
+		})
+	}
+}
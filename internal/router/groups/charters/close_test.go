@@ -0,0 +1,113 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// stubClose answers the statements of CharterDetailRepository.Close and
+// VoyageRepository.ArchiveCompletedByCharter; disputes lists the open ones.
+func stubClose(fake *dbtest.Fake, disputes ...[]any) {
+	fake.Return("SELECT true FROM shipman.charter_details WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"locked"}, []any{true}))
+	fake.Return("SELECT 'dispute', id, status FROM shipman.disputes", dbtest.Rows([]string{"kind", "id", "status"}, disputes...))
+	fake.Return("SELECT 'payment', p.id, p.status", dbtest.Rows([]string{"kind", "id", "status"}))
+	fake.Return("UPDATE shipman.charter_details SET status = 'closed'", dbtest.Affected(1))
+	fake.Return("SELECT status FROM shipman.charter_details WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"status"}, []any{"closed"}))
+	fake.Return("SET archived_at = NOW(), updated_at = NOW() WHERE charter_detail_id = $1", dbtest.Affected(1))
+}
+
+func TestCharterCloseEndpoint(t *testing.T) {
+	owner := newTestUser("shipowner")
+	stranger := newTestUser("charterer")
+	charter := newCharter(owner.ID)
+	charter.Status = "active"
+	open := []any{"dispute", uuid.New(), "open"}
+
+	tests := []struct {
+		name        string
+		user        testUser
+		body        string
+		disputes    [][]any
+		participant bool
+		wantStatus  int
+		wantClosed  bool
+	}{
+		{"clean close", owner, "", nil, false, http.StatusOK, true},
+		{"participant close", stranger, "", nil, true, http.StatusOK, true},
+		{"blocked by open dispute", owner, "", [][]any{open}, false, http.StatusConflict, false},
+		{"forced past open dispute", owner, `{"force":true}`, [][]any{open}, false, http.StatusOK, true},
+		{"stranger", stranger, `{"force":true}`, nil, false, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			if tt.participant {
+				stubParticipant(fake)
+			}
+			stubClose(fake, tt.disputes...)
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, tt.user, http.MethodPost, "/"+charter.ID.String()+"/close", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			closed := len(fake.Calls("UPDATE shipman.charter_details SET status = 'closed'")) == 1
+			if closed != tt.wantClosed {
+				t.Errorf("closed = %v, want %v", closed, tt.wantClosed)
+			}
+			if tt.wantStatus == http.StatusConflict {
+				var body struct {
+					Result db.CloseResult `json:"result"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if len(body.Result.OpenDisputes) != 1 {
+					t.Errorf("open disputes = %v, want the blocking dispute", body.Result.OpenDisputes)
+				}
+			}
+		})
+	}
+}
+
+func TestCharterArchiveVoyagesEndpoint(t *testing.T) {
+	owner := newTestUser("shipowner")
+	stranger := newTestUser("charterer")
+	charter := newCharter(owner.ID)
+
+	tests := []struct {
+		name       string
+		user       testUser
+		status     string
+		wantStatus int
+	}{
+		{"closed charter", owner, "closed", http.StatusOK},
+		{"charter in progress", owner, "active", http.StatusConflict},
+		{"stranger", stranger, "closed", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			fake.Return("SELECT status FROM shipman.charter_details WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"status"}, []any{tt.status}))
+			fake.Return("SET archived_at = NOW(), updated_at = NOW() WHERE charter_detail_id = $1", dbtest.Affected(3))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, tt.user, http.MethodPost, "/"+charter.ID.String()+"/voyages/archive", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			archived := len(fake.Calls("SET archived_at = NOW(), updated_at = NOW() WHERE charter_detail_id = $1")) == 1
+			if archived != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("archived = %v with status %d", archived, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package charters
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shipman/internal/db"
+	"shipman/internal/router/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CounterpartyHandler serves counterparty lookups across charters.
+type CounterpartyHandler struct {
+	charterRepo *db.CharterDetailRepository
+}
+
+func NewCounterpartyHandler() *CounterpartyHandler {
+	return &CounterpartyHandler{
+		charterRepo: db.NewCharterDetailRepository(),
+	}
+}
+
+func (h *CounterpartyHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/suggest", h.handleSuggest)
+}
+
+// handleSuggest returns counterparty names seen on charters that start with
+// ?q=.
+func (h *CounterpartyHandler) handleSuggest(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		render.Data(c, []string{})
+		return
+	}
+
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 50 {
+			limit = parsed
+		}
+	}
+
+	names, err := h.charterRepo.DistinctCounterparties(c.Request.Context(), q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to suggest counterparties"})
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	render.Data(c, names)
+}
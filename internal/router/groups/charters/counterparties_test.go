@@ -0,0 +1,87 @@
+package charters
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+)
+
+func TestCounterpartySuggest(t *testing.T) {
+	const suggest = "SELECT DISTINCT ON (lower(name)) name"
+	user := newTestUser("charterer")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantPrefix string
+		wantLimit  int64
+	}{
+		{"default limit", "/suggest?q=ma", "ma%", 10},
+		{"trimmed query", "/suggest?q=%20ma%20", "ma%", 10},
+		{"like characters escaped", "/suggest?q=100%25_", `100\%\_%`, 10},
+		{"explicit limit", "/suggest?q=ma&limit=3", "ma%", 3},
+		{"limit over the cap ignored", "/suggest?q=ma&limit=500", "ma%", 10},
+		{"invalid limit ignored", "/suggest?q=ma&limit=x", "ma%", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(suggest, dbtest.Rows([]string{"name"}, []any{"Maersk Line"}, []any{"Marubeni"}))
+
+			r := newTestRouter(NewCounterpartyHandler().AddRoutes)
+			w := do(t, r, user, http.MethodGet, tt.path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var got struct {
+				Data []string `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"Maersk Line", "Marubeni"}; !slices.Equal(got.Data, want) {
+				t.Errorf("data = %q, want %q", got.Data, want)
+			}
+			calls := fake.Calls(suggest)
+			if len(calls) != 1 || calls[0].Arg(1) != tt.wantPrefix || calls[0].Arg(2) != tt.wantLimit {
+				t.Errorf("calls = %+v, want pattern %q limit %d", calls, tt.wantPrefix, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestCounterpartySuggestEmpty(t *testing.T) {
+	const suggest = "SELECT DISTINCT ON (lower(name)) name"
+	user := newTestUser("charterer")
+
+	t.Run("blank query skips the lookup", func(t *testing.T) {
+		fake := newFakeDB(t)
+		w := do(t, newTestRouter(NewCounterpartyHandler().AddRoutes), user, http.MethodGet, "/suggest?q=%20", "")
+		if w.Code != http.StatusOK || w.Body.String() != `{"data":[]}` {
+			t.Errorf("response = %d %s, want an empty list", w.Code, w.Body.String())
+		}
+		if calls := fake.Calls(suggest); len(calls) != 0 {
+			t.Errorf("lookup ran for a blank query: %+v", calls)
+		}
+	})
+	t.Run("no matches", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return(suggest, dbtest.Rows([]string{"name"}))
+		w := do(t, newTestRouter(NewCounterpartyHandler().AddRoutes), user, http.MethodGet, "/suggest?q=zim", "")
+		if w.Code != http.StatusOK || w.Body.String() != `{"data":[]}` {
+			t.Errorf("response = %d %s, want an empty list", w.Code, w.Body.String())
+		}
+	})
+	t.Run("db error", func(t *testing.T) {
+		fake := newFakeDB(t)
+		fake.Return(suggest, dbtest.Fail(errors.New("connection reset")))
+		w := do(t, newTestRouter(NewCounterpartyHandler().AddRoutes), user, http.MethodGet, "/suggest?q=ma", "")
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", w.Code)
+		}
+	})
+}
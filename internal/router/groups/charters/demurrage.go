@@ -0,0 +1,160 @@
+package charters
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"shipman/internal/db"
+	"shipman/internal/router/render"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DemurrageHandler serves cross-charter demurrage endpoints mounted at /demurrage.
+type DemurrageHandler struct {
+	demurrageRepo *db.DemurrageRecordRepository
+	documentRepo  *db.DemurrageDocumentRepository
+}
+
+func NewDemurrageHandler() *DemurrageHandler {
+	return &DemurrageHandler{
+		demurrageRepo: db.NewDemurrageRecordRepository(),
+		documentRepo:  db.NewDemurrageDocumentRepository(),
+	}
+}
+
+func (h *DemurrageHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/:id/documents", h.handleListDocuments)
+	r.POST("/:id/documents", h.handleCreateDocument)
+	r.DELETE("/:id/documents/:docId", h.handleDeleteDocument)
+}
+
+// AddAdminRoutes mounts the cross-charter demurrage reports. The group must be
+// restricted to admins.
+func (h *DemurrageHandler) AddAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/uncollected", h.handleUncollected)
+}
+
+func (h *DemurrageHandler) handleUncollected(c *gin.Context) {
+	records, err := h.demurrageRepo.UnpaidForClosedCharters(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list uncollected demurrage"})
+		return
+	}
+
+	if records == nil {
+		records = []db.UncollectedDemurrage{}
+	}
+
+	render.Data(c, records)
+}
+
+// loadRecord parses the :id param and ensures the demurrage record exists. It
+// writes the error response and returns false when the request should stop.
+func (h *DemurrageHandler) loadRecord(c *gin.Context) (db.DemurrageRecord, bool) {
+	recordID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid demurrage record ID"})
+		return db.DemurrageRecord{}, false
+	}
+
+	record, err := h.demurrageRepo.Retrieve(c.Request.Context(), recordID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "demurrage record not found"})
+			return db.DemurrageRecord{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve demurrage record"})
+		return db.DemurrageRecord{}, false
+	}
+
+	return record, true
+}
+
+func (h *DemurrageHandler) handleListDocuments(c *gin.Context) {
+	record, ok := h.loadRecord(c)
+	if !ok {
+		return
+	}
+
+	docs, err := h.documentRepo.ListByRecord(c.Request.Context(), record.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list demurrage documents"})
+		return
+	}
+
+	if docs == nil {
+		docs = []db.DemurrageDocument{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"primary": record.SupportingDocURI, "data": docs})
+}
+
+type DemurrageDocumentRequest struct {
+	URI      string  `json:"uri" binding:"required"`
+	Kind     string  `json:"kind"`
+	Checksum *string `json:"checksum"`
+}
+
+func (h *DemurrageHandler) handleCreateDocument(c *gin.Context) {
+	record, ok := h.loadRecord(c)
+	if !ok {
+		return
+	}
+
+	var req DemurrageDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.URI) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "uri is required"})
+		return
+	}
+
+	doc := &db.DemurrageDocument{
+		DemurrageRecordID: record.ID,
+		URI:               strings.TrimSpace(req.URI),
+		Kind:              strings.ToLower(strings.TrimSpace(req.Kind)),
+		Checksum:          req.Checksum,
+	}
+
+	if err := h.documentRepo.Create(c.Request.Context(), doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to attach demurrage document"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
+
+func (h *DemurrageHandler) handleDeleteDocument(c *gin.Context) {
+	record, ok := h.loadRecord(c)
+	if !ok {
+		return
+	}
+
+	docID, err := uuid.Parse(c.Param("docId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid document ID"})
+		return
+	}
+
+	existing, err := h.documentRepo.Retrieve(c.Request.Context(), docID)
+	if err != nil || existing.DemurrageRecordID != record.ID {
+		if err == nil || err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "demurrage document not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve demurrage document"})
+		return
+	}
+
+	if err := h.documentRepo.Delete(c.Request.Context(), docID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete demurrage document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "demurrage document deleted"})
+}
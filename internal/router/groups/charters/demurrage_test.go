@@ -0,0 +1,116 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const demurrageRetrieveQuery = "supporting_doc_uri, notes, created_at, updated_at FROM shipman.demurrage_records WHERE id = $1"
+
+var demurrageColumns = []string{
+	"id", "charter_detail_id", "voyage_id", "laytime_entry_id", "claimed_hours",
+	"claimed_amount", "currency", "status", "reference", "supporting_doc_uri",
+	"notes", "created_at", "updated_at",
+}
+
+// stubDemurrageRecord answers demurrage record lookups for id with a record
+// whose primary document is primary.
+func stubDemurrageRecord(fake *dbtest.Fake, id uuid.UUID, primary string) {
+	fake.On(demurrageRetrieveQuery, func(call dbtest.Call) dbtest.Result {
+		if call.Arg(1) != id.String() {
+			return dbtest.Rows(demurrageColumns)
+		}
+		return dbtest.Rows(demurrageColumns, dbtest.Row(demurrageColumns, map[string]any{
+			"id": id, "charter_detail_id": uuid.New(), "currency": "USD", "status": "draft",
+			"supporting_doc_uri": primary, "created_at": time.Now(), "updated_at": time.Now(),
+		}))
+	})
+}
+
+func TestDemurrageDocumentCreateEndpoint(t *testing.T) {
+	recordID := uuid.New()
+	tests := []struct {
+		name       string
+		record     uuid.UUID
+		body       string
+		wantStatus int
+		wantURI    string
+		wantKind   string
+	}{
+		{"created", recordID, `{"uri":" s3://claims/sof.pdf ","kind":"SOF","checksum":"sha256:abc"}`, http.StatusCreated, "s3://claims/sof.pdf", "sof"},
+		{"kind defaults", recordID, `{"uri":"s3://claims/misc.pdf"}`, http.StatusCreated, "s3://claims/misc.pdf", "other"},
+		{"blank uri", recordID, `{"uri":"  "}`, http.StatusBadRequest, "", ""},
+		{"missing uri", recordID, `{"kind":"nor"}`, http.StatusBadRequest, "", ""},
+		{"unknown record", uuid.New(), `{"uri":"s3://claims/sof.pdf"}`, http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubDemurrageRecord(fake, recordID, "s3://claims/primary.pdf")
+			fake.On("INSERT INTO shipman.demurrage_documents", func(call dbtest.Call) dbtest.Result {
+				return dbtest.Rows([]string{"id", "kind", "created_at"}, []any{uuid.New(), call.Arg(3), time.Now()})
+			})
+
+			r := newTestRouter(NewDemurrageHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodPost, "/"+tt.record.String()+"/documents", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			inserts := fake.Calls("INSERT INTO shipman.demurrage_documents")
+			if tt.wantStatus != http.StatusCreated {
+				if len(inserts) != 0 {
+					t.Error("document was inserted")
+				}
+				return
+			}
+
+			var doc db.DemurrageDocument
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.ID == uuid.Nil || doc.DemurrageRecordID != recordID || doc.Kind != tt.wantKind {
+				t.Errorf("doc = %+v", doc)
+			}
+			if inserts[0].Arg(2) != tt.wantURI {
+				t.Errorf("inserted uri %q, want %q", inserts[0].Arg(2), tt.wantURI)
+			}
+		})
+	}
+}
+
+func TestDemurrageDocumentListEndpoint(t *testing.T) {
+	fake := newFakeDB(t)
+	recordID := uuid.New()
+	stubDemurrageRecord(fake, recordID, "s3://claims/primary.pdf")
+	fake.Return("FROM shipman.demurrage_documents WHERE demurrage_record_id = $1", dbtest.Rows(
+		[]string{"id", "demurrage_record_id", "uri", "kind", "checksum", "created_at"},
+		[]any{uuid.New(), recordID, "s3://claims/sof.pdf", "sof", nil, time.Now().Add(-time.Hour)},
+		[]any{uuid.New(), recordID, "s3://claims/nor.pdf", "nor", nil, time.Now()},
+	))
+
+	r := newTestRouter(NewDemurrageHandler().AddRoutes)
+	w := do(t, r, newTestUser("broker"), http.MethodGet, "/"+recordID.String()+"/documents", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Primary *string                `json:"primary"`
+		Data    []db.DemurrageDocument `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Primary == nil || *body.Primary != "s3://claims/primary.pdf" {
+		t.Errorf("primary = %v, want the record's supporting_doc_uri", body.Primary)
+	}
+	if len(body.Data) != 2 || body.Data[0].Kind != "sof" || body.Data[1].Kind != "nor" {
+		t.Errorf("data = %+v, want both documents in order", body.Data)
+	}
+}
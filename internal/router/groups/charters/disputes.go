@@ -0,0 +1,76 @@
+package charters
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DisputeHandler serves dispute endpoints mounted at /disputes.
+type DisputeHandler struct {
+	disputeRepo *db.DisputeRepository
+}
+
+func NewDisputeHandler() *DisputeHandler {
+	return &DisputeHandler{
+		disputeRepo: db.NewDisputeRepository(),
+	}
+}
+
+func (h *DisputeHandler) AddRoutes(r *gin.RouterGroup) {
+	r.POST("/:id/reopen", h.handleReopen)
+}
+
+type ReopenDisputeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (h *DisputeHandler) handleReopen(c *gin.Context) {
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dispute ID"})
+		return
+	}
+
+	var req ReopenDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	if err := h.disputeRepo.Reopen(c.Request.Context(), disputeID, reason); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dispute not found"})
+			return
+		}
+		if errors.Is(err, db.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "only resolved or closed disputes can be reopened"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reopen dispute"})
+		return
+	}
+
+	dispute, err := h.disputeRepo.Retrieve(c.Request.Context(), disputeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve dispute"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
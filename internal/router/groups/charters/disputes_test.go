@@ -0,0 +1,43 @@
+package charters
+
+import (
+	"net/http"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestDisputeReopenEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string // "" for an unknown dispute
+		body       string
+		wantStatus int
+	}{
+		{"already open", "open", `{"reason":"again"}`, http.StatusConflict},
+		{"unknown dispute", "", `{"reason":"again"}`, http.StatusNotFound},
+		{"blank reason", "resolved", `{"reason":"  "}`, http.StatusBadRequest},
+		{"missing reason", "resolved", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			lock := dbtest.Rows([]string{"status"})
+			if tt.current != "" {
+				lock = dbtest.Rows([]string{"status"}, []any{tt.current})
+			}
+			fake.Return("SELECT status FROM shipman.disputes WHERE id = $1 FOR UPDATE", lock)
+
+			r := newTestRouter(NewDisputeHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodPost, "/"+uuid.NewString()+"/reopen", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if len(fake.Calls("UPDATE shipman.disputes")) != 0 {
+				t.Error("dispute was updated")
+			}
+		})
+	}
+}
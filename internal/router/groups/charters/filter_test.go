@@ -0,0 +1,87 @@
+package charters
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+)
+
+const (
+	filteredQuery = "FROM shipman.charter_details WHERE TRUE"
+	activeQuery   = "FROM shipman.charter_details WHERE status = 'active'"
+	listQuery     = "COUNT(*) OVER () AS total FROM shipman.charter_details"
+)
+
+func TestListFilterParams(t *testing.T) {
+	q1, q2 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		query     string
+		wantQuery string
+		wantArgs  []any // after the org filter, before the page
+	}{
+		{"no filter lists everything", "", listQuery, nil},
+		{"active alone uses the active index", "?status=active", activeQuery, nil},
+		{"status", "?status=draft", filteredQuery, []any{"draft"}},
+		{"start after", "?start_after=2026-01-01", filteredQuery, []any{q1}},
+		{"start before", "?start_before=2026-04-01", filteredQuery, []any{q2}},
+		{"active in a quarter", "?status=active&start_after=2026-01-01&start_before=2026-04-01", filteredQuery, []any{"active", q1, q2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(filteredQuery, dbtest.Rows([]string{"id"}))
+			fake.Return(activeQuery, dbtest.Rows([]string{"id"}))
+			fake.Return(listQuery, dbtest.Rows([]string{"id"}))
+
+			w := do(t, newTestRouter(NewHandler().AddRoutes), newTestUser("shipowner"), http.MethodGet, "/"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			calls := fake.Calls("FROM shipman.charter_details")
+			if len(calls) != 1 || len(fake.Calls(tt.wantQuery)) != 1 {
+				t.Fatalf("ran %d charter queries, want one matching %q", len(calls), tt.wantQuery)
+			}
+			if tt.wantArgs == nil {
+				return
+			}
+			args := calls[0].Args
+			if got := args[1 : len(args)-2]; len(got) != len(tt.wantArgs) {
+				t.Fatalf("filter args = %v, want %v", got, tt.wantArgs)
+			}
+			for i, want := range tt.wantArgs {
+				got := args[1+i]
+				if at, ok := want.(time.Time); ok {
+					if !got.(time.Time).Equal(at) {
+						t.Errorf("arg %d = %v, want %v", i+2, got, want)
+					}
+				} else if got != want {
+					t.Errorf("arg %d = %v, want %v", i+2, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestListFilterRejects(t *testing.T) {
+	for _, query := range []string{
+		"?start_after=01/02/2026",
+		"?start_before=2026-13-01",
+		"?start_after=2026-01-01&q=star",
+		"?start_before=2026-04-01&without_voyages=true",
+		"?status=draft&include=voyage_counts",
+	} {
+		t.Run(query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			w := do(t, newTestRouter(NewHandler().AddRoutes), newTestUser("shipowner"), http.MethodGet, "/"+query, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			if len(fake.Calls("")) != 0 {
+				t.Error("queried charters for a rejected request")
+			}
+		})
+	}
+}
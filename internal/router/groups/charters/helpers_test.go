@@ -0,0 +1,176 @@
+package charters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/router/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var testJWT = auth.NewJWTManager("test-secret", time.Hour)
+
+// testUser is the caller a test request is authenticated as.
+type testUser struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+	Role  string
+}
+
+func newTestUser(role string) testUser {
+	return testUser{ID: uuid.New(), OrgID: db.DefaultOrgID, Role: role}
+}
+
+// newFakeDB installs a dbtest.Fake as db.Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+// newTestRouter mounts routes behind the real bearer-token middleware.
+func newTestRouter(mount func(*gin.RouterGroup)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/")
+	g.Use(middleware.Auth(testJWT))
+	mount(g)
+	return r
+}
+
+func do(t *testing.T, r http.Handler, u testUser, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := testJWT.Generate(u.ID, u.OrgID, "user@example.com", u.Role, "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// charterRetrieveQuery matches CharterDetailRepository.Retrieve.
+const charterRetrieveQuery = "archived_at, created_at, updated_at FROM shipman.charter_details WHERE id = $1"
+
+var charterColumns = []string{
+	"id", "org_id", "created_by_user_id", "title", "charter_reference_code",
+	"vessel_name", "counterparty_name", "status", "start_date", "end_date",
+	"laytime_allowance_hours", "demurrage_rate", "demurrage_currency",
+	"fuel_clause", "payment_terms", "ai_status", "ai_document_path",
+	"ai_extracted_terms", "last_reviewed_at", "notes", "laytime_reversible",
+	"default_currency", "despatch_rate", "archived_at", "created_at", "updated_at",
+}
+
+func charterRow(d db.CharterDetail) []any {
+	return []any{
+		d.ID, d.OrgID, d.CreatedByUserID, d.Title, d.CharterReferenceCode,
+		d.VesselName, d.CounterpartyName, d.Status, d.StartDate, d.EndDate,
+		d.LaytimeAllowanceHours, d.DemurrageRate, d.DemurrageCurrency,
+		d.FuelClause, d.PaymentTerms, d.AIStatus, d.AIDocumentPath,
+		[]byte(d.AIExtractedTerms), d.LastReviewedAt, d.Notes, d.LaytimeReversible,
+		d.DefaultCurrency, d.DespatchRate, d.ArchivedAt, d.CreatedAt, d.UpdatedAt,
+	}
+}
+
+// newCharter returns a draft charter in the default org created by owner.
+func newCharter(owner uuid.UUID) db.CharterDetail {
+	return db.CharterDetail{
+		ID:              uuid.New(),
+		OrgID:           db.DefaultOrgID,
+		CreatedByUserID: &owner,
+		Title:           "Grain charter",
+		Status:          "draft",
+		AIStatus:        "pending",
+		CreatedAt:       time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:       time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// stubCharters answers charter lookups with the given rows and the
+// participant check with false, so only admins and creators get access.
+func stubCharters(fake *dbtest.Fake, charters ...db.CharterDetail) {
+	byID := make(map[uuid.UUID]db.CharterDetail, len(charters))
+	for _, c := range charters {
+		byID[c.ID] = c
+	}
+	fake.On(charterRetrieveQuery, func(call dbtest.Call) dbtest.Result {
+		c, ok := byID[uuid.MustParse(call.Arg(1).(string))]
+		if !ok {
+			return dbtest.Rows(charterColumns)
+		}
+		return dbtest.Rows(charterColumns, charterRow(c))
+	})
+	fake.Return("SELECT 1 FROM shipman.voyages WHERE charter_detail_id = $1", dbtest.Rows([]string{"exists"}, []any{false}))
+	fake.Return("INSERT INTO shipman.audit_log", dbtest.Rows([]string{"id", "created_at"}, []any{uuid.New(), time.Now()}))
+}
+
+// stubParticipant makes the participant check pass for every charter.
+func stubParticipant(fake *dbtest.Fake) {
+	fake.Return("SELECT 1 FROM shipman.voyages WHERE charter_detail_id = $1", dbtest.Rows([]string{"exists"}, []any{true}))
+}
+
+// voyageRetrieveQuery matches VoyageRepository.Retrieve.
+const voyageRetrieveQuery = "archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1"
+
+var voyageColumns = []string{
+	"id", "org_id", "charter_detail_id", "deal_id", "owner_user_id",
+	"voyage_number", "vessel_name", "imo_number", "vessel_type", "dwt", "flag_state",
+	"departure_port", "arrival_port",
+	"planned_departure_at", "planned_arrival_at", "actual_departure_at", "actual_arrival_at",
+	"distance_nm", "time_at_sea_hours", "fuel_consumed_mt", "fuel_type", "weather_summary",
+	"hire_rate", "freight_rate", "cargo_quantity", "cargo_type",
+	"laytime_allowed_hours", "demurrage_rate", "despatch_rate", "demurrage_currency",
+	"payment_frequency", "first_payment_date", "total_contract_value",
+	"commission_rate", "bunker_cost", "port_costs", "insurance_cost",
+	"counterparty_name", "counterparty_email", "counterparty_user_id", "broker_user_id",
+	"document_id", "charter_type", "status", "notes", "archived_at", "created_at", "updated_at",
+}
+
+// stubVoyages answers voyage lookups with rows built from the given values
+// keyed by voyages column name; each must include "id".
+func stubVoyages(fake *dbtest.Fake, voyages ...map[string]any) {
+	byID := make(map[string][]any, len(voyages))
+	for _, v := range voyages {
+		values := map[string]any{
+			"org_id":             db.DefaultOrgID,
+			"demurrage_currency": "USD",
+			"status":             "planned",
+			"created_at":         time.Now(),
+			"updated_at":         time.Now(),
+		}
+		for k, val := range v {
+			values[k] = val
+		}
+		byID[values["id"].(uuid.UUID).String()] = dbtest.Row(voyageColumns, values)
+	}
+	fake.On(voyageRetrieveQuery, func(call dbtest.Call) dbtest.Result {
+		row, ok := byID[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows(voyageColumns)
+		}
+		return dbtest.Rows(voyageColumns, row)
+	})
+}
@@ -0,0 +1,62 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestLatestVoyageEndpoint(t *testing.T) {
+	const latest = "SELECT id FROM shipman.voyages WHERE charter_detail_id = $1 AND archived_at IS NULL"
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	sailing := uuid.New()
+
+	tests := []struct {
+		name       string
+		charterID  uuid.UUID
+		rows       [][]any
+		wantStatus int
+	}{
+		{"current voyage", charter.ID, [][]any{{sailing}}, http.StatusOK},
+		{"no voyages", charter.ID, nil, http.StatusNotFound},
+		{"unknown charter", uuid.New(), [][]any{{sailing}}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			stubVoyages(fake, map[string]any{"id": sailing, "charter_detail_id": charter.ID.String(), "status": "in_progress"})
+			fake.Return(latest, dbtest.Rows([]string{"id"}, tt.rows...))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, owner, http.MethodGet, "/"+tt.charterID.String()+"/voyages/latest", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.charterID != charter.ID && len(fake.Calls(latest)) != 0 {
+				t.Error("looked up voyages for a missing charter")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got struct {
+				ID     uuid.UUID `json:"id"`
+				Status string    `json:"status"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != sailing || got.Status != "in_progress" {
+				t.Errorf("voyage = %+v, want %s in progress", got, sailing)
+			}
+			if calls := fake.Calls(latest); len(calls) != 1 || calls[0].Arg(1) != charter.ID.String() {
+				t.Errorf("latest calls = %+v, want one for the charter", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// stubLaytime answers the queries behind CharterDetailRepository.CalcLaytime
+// with a 48 hour, 24000 USD/day charter, the given hours per port and no
+// per-port terms.
+func stubLaytime(fake *dbtest.Fake, usage ...[]any) {
+	fake.Return("COALESCE(demurrage_currency, 'USD'), laytime_reversible FROM shipman.charter_details",
+		dbtest.Rows([]string{"allowed", "dem_rate", "desp_rate", "currency", "reversible"},
+			[]any{48.0, 24000.0, 12000.0, "USD", false}))
+	fake.Return("SELECT port_name, COALESCE(SUM(hours_counted), 0) FROM shipman.laytime_entries",
+		dbtest.Rows([]string{"port_name", "hours"}, usage...))
+	fake.Return("FROM shipman.charter_laytime_terms WHERE charter_detail_id = $1",
+		dbtest.Rows([]string{"id", "charter_detail_id", "port_role", "port_name", "allowance_hours",
+			"reversible", "notes", "created_at", "updated_at"}))
+}
+
+func TestCharterRecomputeLaytime(t *testing.T) {
+	owner := newTestUser("shipowner")
+	stranger := newTestUser("charterer")
+	charter := newCharter(owner.ID)
+	computedAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	const insert = "INSERT INTO shipman.charter_laytime_totals"
+
+	t.Run("stores fresh totals", func(t *testing.T) {
+		fake := newFakeDB(t)
+		stubCharters(fake, charter)
+		stubLaytime(fake, []any{"Santos", 40.0}, []any{"Rotterdam", 20.0})
+		fake.Return(insert, dbtest.Rows([]string{"computed_at"}, []any{computedAt}))
+
+		r := newTestRouter(NewHandler().AddRoutes)
+		w := do(t, r, owner, http.MethodPost, "/"+charter.ID.String()+"/laytime/recompute", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+		}
+		calls := fake.Calls(insert)
+		if len(calls) != 1 {
+			t.Fatalf("totals written %d times, want 1", len(calls))
+		}
+		// $2 used, $3 allowed, $5 demurrage hours, $7 demurrage amount, $8 currency.
+		args := calls[0].Args
+		if args[1] != 60.0 || args[2] != 48.0 || args[4] != 12.0 || args[6] != 12000.0 || args[7] != "USD" {
+			t.Errorf("insert args = %v", args)
+		}
+
+		var got db.CharterLaytimeTotals
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.CharterDetailID != charter.ID || got.DemurrageHours != 12 || !got.ComputedAt.Equal(computedAt) {
+			t.Errorf("totals = %+v", got)
+		}
+	})
+
+	t.Run("stranger", func(t *testing.T) {
+		fake := newFakeDB(t)
+		stubCharters(fake, charter)
+		stubLaytime(fake)
+
+		r := newTestRouter(NewHandler().AddRoutes)
+		w := do(t, r, stranger, http.MethodPost, "/"+charter.ID.String()+"/laytime/recompute", "")
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403: %s", w.Code, w.Body.String())
+		}
+		if calls := fake.Calls(insert); len(calls) != 0 {
+			t.Errorf("stranger request wrote totals")
+		}
+	})
+}
+
+var laytimeEntryColumns = []string{
+	"id", "charter_detail_id", "voyage_id", "port_name", "activity", "started_at",
+	"ended_at", "hours_counted", "excluded_hours", "remarks", "created_at", "updated_at",
+}
+
+func TestListOpenLaytimeEndpoint(t *testing.T) {
+	fake := newFakeDB(t)
+	admin := newTestUser("admin")
+	charter := newCharter(admin.ID)
+	stubCharters(fake, charter)
+	started := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	fake.Return("WHERE charter_detail_id = $1 AND ended_at IS NULL", dbtest.Rows(laytimeEntryColumns,
+		[]any{uuid.New(), charter.ID, nil, "Rotterdam", "loading", started, nil, nil, 0.0, nil, started, started},
+	))
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, admin, http.MethodGet, "/"+charter.ID.String()+"/laytime/open", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []db.LaytimeEntry `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 1 || body.Data[0].EndedAt != nil || body.Data[0].PortName != "Rotterdam" {
+		t.Errorf("data = %+v, want the one open entry", body.Data)
+	}
+	if call := fake.Calls("AND ended_at IS NULL")[0]; call.Arg(1) != charter.ID.String() {
+		t.Errorf("listed charter %v, want %s", call.Arg(1), charter.ID)
+	}
+
+	if w := do(t, r, admin, http.MethodGet, "/"+uuid.NewString()+"/laytime/open", ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing charter status = %d, want 404", w.Code)
+	}
+}
+
+func TestCloseOpenLaytimeEndpoint(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	const closeQuery = "UPDATE shipman.laytime_entries le"
+
+	tests := []struct {
+		name       string
+		body       string
+		result     *dbtest.Result
+		wantStatus int
+		wantClosed int64
+	}{
+		{"closes entries", `{"port_name":"Santos","ended_at":"2026-01-05T12:00:00Z"}`,
+			closeResult(false, 3), http.StatusOK, 3},
+		{"end before start", `{"port_name":"Santos","ended_at":"2026-01-05T12:00:00Z"}`,
+			closeResult(true, 0), http.StatusBadRequest, 0},
+		{"blank port", `{"port_name":"  ","ended_at":"2026-01-05T12:00:00Z"}`, nil, http.StatusBadRequest, 0},
+		{"missing end", `{"port_name":"Santos"}`, nil, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			if tt.result != nil {
+				fake.Return(closeQuery, *tt.result)
+			}
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, owner, http.MethodPost, "/"+charter.ID.String()+"/laytime/close", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(closeQuery)
+			if tt.result == nil {
+				if len(calls) != 0 {
+					t.Error("closed entries for an invalid request")
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0].Arg(1) != charter.ID.String() || calls[0].Arg(2) != "Santos" ||
+				!calls[0].Arg(3).(time.Time).Equal(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)) {
+				t.Fatalf("close calls = %+v", calls)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var body struct {
+				Closed int64 `json:"closed"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Closed != tt.wantClosed {
+				t.Errorf("closed = %d, want %d", body.Closed, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func closeResult(invalid bool, count int64) *dbtest.Result {
+	r := dbtest.Rows([]string{"invalid", "count"}, []any{invalid, count})
+	return &r
+}
+
+func TestCharterLaytimeSummaryDespatch(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	fake := newFakeDB(t)
+	stubCharters(fake, charter)
+	stubLaytime(fake, []any{"Santos", 36.0})
+
+	r := newTestRouter(NewHandler().AddRoutes)
+	w := do(t, r, owner, http.MethodGet, "/"+charter.ID.String()+"/laytime/summary", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var got db.CharterLaytimeSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.DespatchHours != 12 || got.DemurrageAmount != nil {
+		t.Errorf("summary = %+v, want 12 despatch hours and no demurrage", got)
+	}
+	if got.DespatchRate == nil || *got.DespatchRate != 12000 || got.DespatchAmount == nil || *got.DespatchAmount != 6000 {
+		t.Errorf("despatch rate/amount = %v/%v, want 12000/6000", got.DespatchRate, got.DespatchAmount)
+	}
+}
+
+func TestCharterEffectiveTermsEndpoint(t *testing.T) {
+	owner := newTestUser("shipowner")
+	charter := newCharter(owner.ID)
+	allowance, rate := 48.0, 24000.0
+	charter.LaytimeAllowanceHours, charter.DemurrageRate = &allowance, &rate
+	termColumns := []string{"id", "charter_detail_id", "port_role", "port_name", "allowance_hours",
+		"reversible", "notes", "created_at", "updated_at"}
+
+	tests := []struct {
+		name      string
+		terms     [][]any
+		wantPorts map[string]float64
+	}{
+		{"without port terms", nil, map[string]float64{}},
+		{"with port terms", [][]any{
+			{uuid.New(), charter.ID, "load", "Santos", 72.0, false, nil, time.Now(), time.Now()},
+			{uuid.New(), charter.ID, "discharge", "Rotterdam", 36.0, false, nil, time.Now(), time.Now()},
+		}, map[string]float64{"Santos": 72, "Rotterdam": 36}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, charter)
+			fake.Return("FROM shipman.charter_laytime_terms WHERE charter_detail_id = $1", dbtest.Rows(termColumns, tt.terms...))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, owner, http.MethodGet, "/"+charter.ID.String()+"/terms", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var got db.CharterTerms
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.CharterDetailID != charter.ID {
+				t.Errorf("charter id = %s, want %s", got.CharterDetailID, charter.ID)
+			}
+			if got.LaytimeAllowanceHours.Value != 48 || got.LaytimeAllowanceHours.Source != db.TermSourceSet {
+				t.Errorf("allowance = %+v, want 48 set on the charter", got.LaytimeAllowanceHours)
+			}
+			if got.DespatchRate.Value != 12000 || got.DespatchRate.Source != db.TermSourceDefault {
+				t.Errorf("despatch rate = %+v, want the 12000 default", got.DespatchRate)
+			}
+			if got.DemurrageCurrency.Value != "USD" || got.DemurrageCurrency.Source != db.TermSourceDefault {
+				t.Errorf("demurrage currency = %+v, want the USD default", got.DemurrageCurrency)
+			}
+			if got.Ports == nil || len(got.Ports) != len(tt.wantPorts) {
+				t.Fatalf("ports = %+v, want %v", got.Ports, tt.wantPorts)
+			}
+			for _, p := range got.Ports {
+				if p.AllowanceHours.Value != tt.wantPorts[p.PortName] || p.AllowanceHours.Source != db.TermSourcePort {
+					t.Errorf("%s allowance = %+v, want %v from the port term", p.PortName, p.AllowanceHours, tt.wantPorts[p.PortName])
+				}
+			}
+		})
+	}
+
+	t.Run("missing charter", func(t *testing.T) {
+		fake := newFakeDB(t)
+		stubCharters(fake)
+		r := newTestRouter(NewHandler().AddRoutes)
+		w := do(t, r, owner, http.MethodGet, "/"+uuid.NewString()+"/terms", "")
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", w.Code)
+		}
+		if len(fake.Calls("FROM shipman.charter_laytime_terms")) != 0 {
+			t.Error("listed laytime terms for a missing charter")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package charters
+
+import (
+	"net/http"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCharterReparentVoyages(t *testing.T) {
+	owner := newTestUser("shipowner")
+	target := newCharter(owner.ID)
+	ownSource := newCharter(owner.ID)
+	foreignSource := newCharter(uuid.New())
+	fromOwn, fromForeign, unowned := uuid.New(), uuid.New(), uuid.New()
+	stranger := newTestUser("shipowner")
+
+	tests := []struct {
+		name       string
+		user       testUser
+		voyages    []uuid.UUID
+		wantStatus int
+	}{
+		{"both charters accessible", owner, []uuid.UUID{fromOwn}, http.StatusOK},
+		{"target not accessible", stranger, []uuid.UUID{fromOwn}, http.StatusForbidden},
+		{"source not accessible", owner, []uuid.UUID{fromOwn, fromForeign}, http.StatusForbidden},
+		{"voyage not owned", owner, []uuid.UUID{unowned}, http.StatusForbidden},
+		{"voyage missing", owner, []uuid.UUID{uuid.New()}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubCharters(fake, target, ownSource, foreignSource)
+			stubVoyages(fake,
+				map[string]any{"id": fromOwn, "owner_user_id": owner.ID, "charter_detail_id": ownSource.ID},
+				map[string]any{"id": fromForeign, "owner_user_id": owner.ID, "charter_detail_id": foreignSource.ID},
+				map[string]any{"id": unowned, "owner_user_id": uuid.New(), "charter_detail_id": ownSource.ID},
+			)
+			fake.Return("FROM shipman.charter_details WHERE id = $1 FOR SHARE", dbtest.Rows([]string{"exists"}, []any{true}))
+			fake.Return("UPDATE shipman.voyages SET charter_detail_id = $2", dbtest.Affected(int64(len(tt.voyages))))
+			fake.Return("SET charter_detail_id = $2, updated_at = NOW() WHERE voyage_id = ANY($1)", dbtest.Affected(0))
+
+			body := `{"voyage_ids":[`
+			for i, id := range tt.voyages {
+				if i > 0 {
+					body += ","
+				}
+				body += `"` + id.String() + `"`
+			}
+			body += `]}`
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, tt.user, http.MethodPost, "/"+target.ID.String()+"/voyages/reparent", body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			moved := len(fake.Calls("UPDATE shipman.voyages SET charter_detail_id = $2")) == 1
+			if moved != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("moved = %v with status %d", moved, w.Code)
+			}
+		})
+	}
+}
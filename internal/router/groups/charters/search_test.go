@@ -0,0 +1,67 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const searchQuery = "FROM shipman.charter_details WHERE (title ILIKE $1"
+
+func TestListSearch(t *testing.T) {
+	user := newTestUser("shipowner")
+	fake := newFakeDB(t)
+	hit := uuid.New()
+	fake.Return(searchQuery, dbtest.Rows(
+		[]string{"id", "title", "vessel_name", "counterparty_name", "status", "created_at", "updated_at"},
+		[]any{hit, "Grain to Santos", "MV Nordic Star", nil, "active", time.Now(), time.Now()}))
+
+	w := do(t, newTestRouter(NewHandler().AddRoutes), user, http.MethodGet, "/?q=%20nordic%25%20&limit=5&offset=10", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	call := fake.Calls(searchQuery)[0]
+	if call.Arg(1) != `%nordic\%%` || call.Arg(2) != `nordic\%%` {
+		t.Errorf("patterns = %v, %v; want the trimmed, escaped query", call.Arg(1), call.Arg(2))
+	}
+	if call.Arg(3) != int64(5) || call.Arg(4) != int64(10) {
+		t.Errorf("page = %v/%v, want 5/10", call.Arg(3), call.Arg(4))
+	}
+	if call.Arg(5) != db.DefaultOrgID.String() {
+		t.Errorf("org = %v, want the caller's", call.Arg(5))
+	}
+	var got struct {
+		Data []db.CharterDetail `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Data) != 1 || got.Data[0].ID != hit {
+		t.Errorf("charters = %+v, want the match", got.Data)
+	}
+}
+
+func TestListSearchConflicts(t *testing.T) {
+	for _, path := range []string{
+		"/?q=star&status=active",
+		"/?q=star&without_voyages=true",
+		"/?q=star&include=voyage_counts",
+	} {
+		t.Run(path, func(t *testing.T) {
+			fake := newFakeDB(t)
+			w := do(t, newTestRouter(NewHandler().AddRoutes), newTestUser("shipowner"), http.MethodGet, path, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			if len(fake.Calls("")) != 0 {
+				t.Error("queried charters for a rejected request")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"shipman/internal/db"
+)
+
+func TestCharterValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		{
+			"valid",
+			`{"title":"Grain","start_date":"2026-04-01T00:00:00Z","end_date":"2026-05-01T00:00:00Z","demurrage_rate":12000,"demurrage_currency":"USD"}`,
+			http.StatusOK, nil,
+		},
+		{
+			"several problems",
+			`{"title":"","start_date":"2026-05-01T00:00:00Z","end_date":"2026-04-01T00:00:00Z","demurrage_rate":12000}`,
+			http.StatusUnprocessableEntity, []string{"title", "end_date", "demurrage_currency"},
+		},
+		{"malformed", `{"title":`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodPost, "/validate", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if calls := fake.Calls(""); len(calls) != 0 {
+				t.Errorf("validation queried the database: %+v", calls)
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				return
+			}
+
+			var body struct {
+				Valid  bool            `json:"valid"`
+				Errors []db.FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Valid != (tt.wantStatus == http.StatusOK) || body.Errors == nil {
+				t.Errorf("body = %s", w.Body.String())
+			}
+			if len(body.Errors) != len(tt.wantFields) {
+				t.Fatalf("errors = %+v, want fields %v", body.Errors, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if body.Errors[i].Field != field {
+					t.Errorf("error %d field = %q, want %q", i, body.Errors[i].Field, field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package charters
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateCharterWithVoyageEndpoint(t *testing.T) {
+	const (
+		charterInsert = "INSERT INTO shipman.charter_details"
+		voyageInsert  = "INSERT INTO shipman.voyages"
+	)
+	owner := newTestUser("shipowner")
+	longNotes := strings.Repeat("x", db.MaxNotesLength+1)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantCharter  bool
+		wantRollback bool
+	}{
+		{"both created", `{"charter":{"title":"Grain charter"},"voyage":{"voyage_number":"V-1","owner_user_id":"` + uuid.NewString() + `"}}`,
+			http.StatusCreated, true, false},
+		{"invalid voyage", `{"charter":{"title":"Grain charter"},"voyage":{"voyage_number":"V-1","notes":"` + longNotes + `"}}`,
+			http.StatusBadRequest, true, true},
+		{"invalid charter", `{"charter":{"title":" "},"voyage":{"voyage_number":"V-1"}}`,
+			http.StatusUnprocessableEntity, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charterID, voyageID := uuid.New(), uuid.New()
+			fake := newFakeDB(t)
+			fake.Return(charterInsert, dbtest.Rows([]string{"id", "status", "ai_status", "created_at", "updated_at"},
+				[]any{charterID, "draft", "pending", time.Now(), time.Now()}))
+			fake.Return(voyageInsert, dbtest.Rows([]string{"id", "org_id", "status", "demurrage_currency", "created_at", "updated_at"},
+				[]any{voyageID, db.DefaultOrgID, "planned", "USD", time.Now(), time.Now()}))
+
+			r := newTestRouter(NewHandler().AddRoutes)
+			w := do(t, r, owner, http.MethodPost, "/with-voyage", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if inserted := len(fake.Calls(charterInsert)) == 1; inserted != tt.wantCharter {
+				t.Errorf("charter inserted = %v, want %v", inserted, tt.wantCharter)
+			}
+			if tt.wantRollback && (fake.Rollbacks() != 1 || fake.Commits() != 0) {
+				t.Errorf("commits %d, rollbacks %d; want the charter rolled back", fake.Commits(), fake.Rollbacks())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if len(fake.Calls(voyageInsert)) != 0 {
+					t.Error("inserted a voyage for a rejected request")
+				}
+				return
+			}
+
+			if fake.Commits() != 1 {
+				t.Errorf("commits = %d, want 1", fake.Commits())
+			}
+			insert := fake.Calls(voyageInsert)[0]
+			if insert.Arg(1) != charterID.String() || insert.Arg(3) != owner.ID.String() {
+				t.Errorf("voyage charter/owner = %v/%v, want %s/%s", insert.Arg(1), insert.Arg(3), charterID, owner.ID)
+			}
+			var got struct {
+				Charter db.CharterDetail `json:"charter"`
+				Voyage  db.Voyage        `json:"voyage"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Charter.ID != charterID || got.Voyage.ID != voyageID || got.Voyage.CharterDetailID == nil || *got.Voyage.CharterDetailID != charterID {
+				t.Errorf("response = charter %s, voyage %s on %v; want %s and %s on it", got.Charter.ID, got.Voyage.ID, got.Voyage.CharterDetailID, charterID, voyageID)
+			}
+		})
+	}
+}
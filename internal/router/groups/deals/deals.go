@@ -2,12 +2,15 @@ package deals
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"shipman/internal/db"
 	"shipman/internal/email"
+	"shipman/internal/router/middleware"
+	"shipman/internal/router/render"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -49,9 +52,9 @@ func (h *Handler) AddRoutes(r *gin.RouterGroup) {
 	r.PUT("/:id/cargo", h.handleUpsertCargoDetails)
 	r.POST("/:id/negotiations", h.handleCreateNegotiation)
 	r.GET("/:id/negotiations", h.handleListNegotiations)
-	r.POST("/:id/negotiations/:negotiationId/proposals", h.handleCreateProposal)
+	r.POST("/:id/negotiations/:negotiationId/proposals", middleware.Transactional(), h.handleCreateProposal)
 	r.GET("/:id/negotiations/:negotiationId", h.handleGetNegotiation)
-	r.PATCH("/:id/negotiations/:negotiationId/proposals/:proposalId", h.handleUpdateProposalStatus)
+	r.PATCH("/:id/negotiations/:negotiationId/proposals/:proposalId", middleware.Transactional(), h.handleUpdateProposalStatus)
 }
 
 type CreateDealRequest struct {
@@ -84,6 +87,10 @@ func (h *Handler) handleCreate(c *gin.Context) {
 	}
 
 	if err := h.dealRepo.Create(c.Request.Context(), deal); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create deal"})
 		return
 	}
@@ -114,7 +121,7 @@ func (h *Handler) handleList(c *gin.Context) {
 		deals = []db.Deal{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": deals})
+	render.Data(c, deals)
 }
 
 func (h *Handler) handleGet(c *gin.Context) {
@@ -268,6 +275,10 @@ func (h *Handler) handleUpsertVesselDetails(c *gin.Context) {
 	}
 
 	if err := h.detailsRepo.UpsertVesselDetails(c.Request.Context(), d); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save vessel details"})
 		return
 	}
@@ -348,6 +359,10 @@ func (h *Handler) handleUpsertCargoDetails(c *gin.Context) {
 	}
 
 	if err := h.detailsRepo.UpsertCargoDetails(c.Request.Context(), d); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save cargo details"})
 		return
 	}
@@ -623,7 +638,7 @@ func (h *Handler) handleListNegotiations(c *gin.Context) {
 		negotiations = []db.ClauseNegotiation{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": negotiations})
+	render.Data(c, negotiations)
 }
 
 func (h *Handler) handleGetNegotiation(c *gin.Context) {
@@ -705,7 +720,10 @@ func (h *Handler) handleCreateProposal(c *gin.Context) {
 		return
 	}
 
-	h.negRepo.UpdateStatus(c.Request.Context(), negotiationID, "countered")
+	if err := h.negRepo.UpdateStatus(c.Request.Context(), negotiationID, "countered"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update negotiation"})
+		return
+	}
 
 	c.JSON(http.StatusCreated, proposal)
 }
@@ -758,19 +776,35 @@ func (h *Handler) handleUpdateProposalStatus(c *gin.Context) {
 
 	if req.Status == "accepted" {
 		// Supersede all other pending proposals on this negotiation
-		h.negRepo.SupersedeOtherProposals(ctx, negotiationID, proposalID)
+		if err := h.negRepo.SupersedeOtherProposals(ctx, negotiationID, proposalID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to supersede proposals"})
+			return
+		}
 		// Mark the negotiation itself as accepted
-		h.negRepo.UpdateStatus(ctx, negotiationID, "accepted")
+		if err := h.negRepo.UpdateStatus(ctx, negotiationID, "accepted"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update negotiation"})
+			return
+		}
 
 		// Check if ALL negotiations on the deal are now accepted
 		allDone, err := h.negRepo.AllNegotiationsAccepted(ctx, dealID)
-		if err == nil && allDone {
-			h.dealRepo.UpdateStatus(ctx, dealID, "completed")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check negotiations"})
+			return
+		}
+		if allDone {
+			if err := h.dealRepo.UpdateStatus(ctx, dealID, "completed"); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to complete deal"})
+				return
+			}
 			dealCompleted = true
 		}
 	} else if req.Status == "rejected" {
 		// Negotiation stays open for further proposals
-		h.negRepo.UpdateStatus(ctx, negotiationID, "open")
+		if err := h.negRepo.UpdateStatus(ctx, negotiationID, "open"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update negotiation"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
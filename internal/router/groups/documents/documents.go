@@ -1,8 +1,12 @@
 package documents
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"mime"
 	"net/http"
@@ -12,6 +16,8 @@ import (
 	"shipman/internal/ai"
 	"shipman/internal/db"
 	"shipman/internal/processor"
+	"shipman/internal/router/middleware"
+	"shipman/internal/router/render"
 	"shipman/internal/storage"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +26,7 @@ import (
 
 type Handler struct {
 	docRepo   *db.DocumentRepository
+	blobRepo  *db.BlobIndexRepository
 	storage   storage.Storage
 	processor *processor.Processor
 	aiService ai.ClauseExtractor
@@ -39,6 +46,7 @@ func NewHandler(store storage.Storage, aiProvider, apiKey, aiModel, aiBaseURL st
 
 	return &Handler{
 		docRepo:   db.NewDocumentRepository(),
+		blobRepo:  db.NewBlobIndexRepository(),
 		storage:   store,
 		processor: processor.NewProcessor(),
 		aiService: aiService,
@@ -46,12 +54,12 @@ func NewHandler(store storage.Storage, aiProvider, apiKey, aiModel, aiBaseURL st
 }
 
 func (h *Handler) AddRoutes(r *gin.RouterGroup) {
-	r.POST("", h.handleUpload)
+	r.POST("", middleware.LongRunning(), h.handleUpload)
 	r.GET("", h.handleList)
 	r.GET("/:id", h.handleGet)
 	// GET /:id/view is registered once in router.go (with query-token middleware for iframes)
 	r.POST("/:id/process", h.handleProcess)
-	r.POST("/:id/analyze", h.handleAnalyze)
+	r.POST("/:id/analyze", middleware.LongRunning(), h.handleAnalyze)
 	r.DELETE("/:id", h.handleDelete)
 }
 
@@ -92,7 +100,7 @@ func (h *Handler) handleUpload(c *gin.Context) {
 		return
 	}
 
-	storagePath, err := h.storage.Save(header.Filename, file)
+	storagePath, err := h.storeBlob(c, header.Filename, file)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
 		return
@@ -117,7 +125,7 @@ func (h *Handler) handleUpload(c *gin.Context) {
 	}
 
 	if err := h.docRepo.Create(c.Request.Context(), doc); err != nil {
-		h.storage.Delete(storagePath)
+		h.releaseBlob(c, storagePath)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save document record"})
 		return
 	}
@@ -125,6 +133,56 @@ func (h *Handler) handleUpload(c *gin.Context) {
 	c.JSON(http.StatusCreated, doc)
 }
 
+// storeBlob saves the upload unless a blob with the same SHA-256 is already
+// indexed, in which case the existing object is reused. It returns the storage
+// path the document should point at.
+func (h *Handler) storeBlob(c *gin.Context, filename string, file io.ReadSeeker) (string, error) {
+	ctx := c.Request.Context()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(sum.Sum(nil))
+
+	if uri, ok, err := h.blobRepo.Acquire(ctx, checksum); err != nil {
+		return "", err
+	} else if ok {
+		return uri, nil
+	}
+
+	path, err := h.storage.Save(filename, file)
+	if err != nil {
+		return "", err
+	}
+	uri, err := h.blobRepo.Register(ctx, checksum, path)
+	if err != nil {
+		h.storage.Delete(path)
+		return "", err
+	}
+	if uri != path {
+		// Lost a race with an identical upload; keep theirs.
+		h.storage.Delete(path)
+	}
+	return uri, nil
+}
+
+// releaseBlob drops this document's reference on its blob and removes the
+// object once nothing else points at it.
+func (h *Handler) releaseBlob(c *gin.Context, path string) {
+	last, err := h.blobRepo.Release(c.Request.Context(), path)
+	if err != nil {
+		log.Printf("documents: release blob %s: %v", path, err)
+		return
+	}
+	if last {
+		h.storage.Delete(path)
+	}
+}
+
 func (h *Handler) handleList(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -149,6 +207,10 @@ func (h *Handler) handleList(c *gin.Context) {
 
 	docs, err := h.docRepo.ListByUser(c.Request.Context(), userID.(uuid.UUID), limit, offset)
 	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list documents"})
 		return
 	}
@@ -157,7 +219,7 @@ func (h *Handler) handleList(c *gin.Context) {
 		docs = []db.Document{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": docs})
+	render.Paged(c, docs, limit, offset)
 }
 
 func (h *Handler) handleGet(c *gin.Context) {
@@ -370,12 +432,11 @@ func (h *Handler) handleDelete(c *gin.Context) {
 		return
 	}
 
-	h.storage.Delete(doc.StoragePath)
-
 	if err := h.docRepo.Delete(c.Request.Context(), docID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete document"})
 		return
 	}
+	h.releaseBlob(c, doc.StoragePath)
 
 	c.JSON(http.StatusOK, gin.H{"message": "document deleted"})
 }
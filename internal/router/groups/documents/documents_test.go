@@ -0,0 +1,189 @@
+package documents
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var documentColumns = []string{
+	"id", "charter_detail_id", "uploaded_by", "filename", "original_filename",
+	"content_type", "file_size", "storage_path", "status", "extracted_text",
+	"ai_analysis", "created_at", "updated_at",
+}
+
+// fakeDocumentStore keeps documents rows and blob_index entries so uploads
+// and deletes see each other's writes.
+type fakeDocumentStore struct {
+	mu   sync.Mutex
+	docs map[string][]any // id -> documents row
+	uris map[string]string
+	refs map[string]int // uri -> ref_count
+}
+
+func newFakeDocumentStore(fake *dbtest.Fake) *fakeDocumentStore {
+	s := &fakeDocumentStore{docs: map[string][]any{}, uris: map[string]string{}, refs: map[string]int{}}
+	fake.On("INSERT INTO shipman.documents", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := uuid.New()
+		s.docs[id.String()] = []any{id, call.Arg(1), call.Arg(2), call.Arg(3), call.Arg(4),
+			call.Arg(5), call.Arg(6), call.Arg(7), call.Arg(8), nil, nil, time.Now(), time.Now()}
+		return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{id, time.Now(), time.Now()})
+	})
+	fake.On("FROM shipman.documents WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		row, ok := s.docs[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows(documentColumns)
+		}
+		return dbtest.Rows(documentColumns, row)
+	})
+	fake.On("DELETE FROM shipman.documents WHERE id = $1", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.docs, call.Arg(1).(string))
+		return dbtest.Affected(1)
+	})
+	fake.On("SET ref_count = ref_count + 1, updated_at = NOW() WHERE checksum = $1", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		uri, ok := s.uris[call.Arg(1).(string)]
+		if !ok {
+			return dbtest.Rows([]string{"uri"})
+		}
+		s.refs[uri]++
+		return dbtest.Rows([]string{"uri"}, []any{uri})
+	})
+	fake.On("INSERT INTO shipman.blob_index", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		uri := call.Arg(2).(string)
+		s.uris[call.Arg(1).(string)] = uri
+		s.refs[uri] = 1
+		return dbtest.Rows([]string{"uri"}, []any{uri})
+	})
+	fake.On("SET ref_count = ref_count - 1", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		uri := call.Arg(1).(string)
+		if _, ok := s.refs[uri]; !ok {
+			return dbtest.Rows([]string{"ref_count"})
+		}
+		s.refs[uri]--
+		return dbtest.Rows([]string{"ref_count"}, []any{s.refs[uri]})
+	})
+	fake.On("DELETE FROM shipman.blob_index WHERE uri = $1", func(call dbtest.Call) dbtest.Result {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.refs, call.Arg(1).(string))
+		return dbtest.Affected(1)
+	})
+	return s
+}
+
+func uploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="file"; filename="` + filename + `"`},
+		"Content-Type":        {"application/pdf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/documents", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestUploadDeduplicatesContent(t *testing.T) {
+	fake := newFakeDB(t)
+	table := newFakeDocumentStore(fake)
+	dir := t.TempDir()
+	store, err := storage.NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(NewHandler(store, "", "", "", ""))
+	userID := uuid.New()
+	bol := []byte("%PDF-1.7 bill of lading BL-001")
+
+	upload := func(name string, content []byte) db.Document {
+		t.Helper()
+		w := send(t, r, userID, uploadRequest(t, name, content))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload %s: status = %d: %s", name, w.Code, w.Body.String())
+		}
+		var doc db.Document
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	stored := func() int {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	first := upload("bol.pdf", bol)
+	second := upload("bol-copy.pdf", bol)
+	other := upload("other.pdf", []byte("%PDF-1.7 a different bill"))
+	if second.Filename != first.Filename {
+		t.Errorf("duplicate stored at %s, want the existing %s", second.Filename, first.Filename)
+	}
+	if second.ID == first.ID || second.OriginalFilename != "bol-copy.pdf" {
+		t.Errorf("duplicate = %+v, want its own record", second)
+	}
+	if other.Filename == first.Filename {
+		t.Error("different content shared a blob")
+	}
+	if n := stored(); n != 2 {
+		t.Fatalf("stored objects = %d, want 2", n)
+	}
+	if got := table.refs[first.Filename]; got != 2 {
+		t.Errorf("ref_count = %d, want 2", got)
+	}
+
+	remove := func(doc db.Document) {
+		t.Helper()
+		w := send(t, r, userID, httptest.NewRequest(http.MethodDelete, "/documents/"+doc.ID.String(), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("delete: status = %d: %s", w.Code, w.Body.String())
+		}
+	}
+	remove(first)
+	if n := stored(); n != 2 {
+		t.Fatalf("after deleting one copy: stored objects = %d, want the shared blob kept", n)
+	}
+	rc, err := store.Get(second.Filename)
+	if err != nil {
+		t.Fatalf("remaining document lost its blob: %v", err)
+	}
+	rc.Close()
+	remove(second)
+	if n := stored(); n != 1 {
+		t.Errorf("after deleting both copies: stored objects = %d, want 1", n)
+	}
+}
@@ -0,0 +1,56 @@
+package documents
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/router/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var testJWT = auth.NewJWTManager("test-secret", time.Hour)
+
+// newFakeDB installs a dbtest.Fake as db.Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+// newTestRouter mounts the document routes behind the real bearer-token
+// middleware.
+func newTestRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/documents")
+	g.Use(middleware.Auth(testJWT))
+	h.AddRoutes(g)
+	return r
+}
+
+// send authenticates req as userID and serves it.
+func send(t *testing.T, r http.Handler, userID uuid.UUID, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := testJWT.Generate(userID, db.DefaultOrgID, "user@example.com", "shipowner", "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
@@ -2,22 +2,30 @@ package marketplace
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"shipman/internal/db"
+	"shipman/internal/router/render"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type Handler struct {
-	vesselRepo *db.VesselRepository
+	vesselRepo  *db.VesselRepository
+	charterRepo *db.CharterDetailRepository
+	voyageRepo  *db.VoyageRepository
 }
 
 func NewHandler() *Handler {
 	return &Handler{
-		vesselRepo: db.NewVesselRepository(),
+		vesselRepo:  db.NewVesselRepository(),
+		charterRepo: db.NewCharterDetailRepository(),
+		voyageRepo:  db.NewVoyageRepository(),
 	}
 }
 
@@ -27,6 +35,9 @@ func (h *Handler) AddRoutes(r *gin.RouterGroup) {
 	r.POST("/vessels", h.handleCreateVessel)
 	r.PUT("/vessels/:id", h.handleUpdateVessel)
 	r.DELETE("/vessels/:id", h.handleDeleteVessel)
+	r.GET("/vessels/:id/charters", h.handleListVesselCharters)
+	r.GET("/vessels/:id/utilization", h.handleVesselUtilization)
+	r.GET("/vessels/:id/availability", h.handleVesselAvailability)
 }
 
 func (h *Handler) handleListVessels(c *gin.Context) {
@@ -45,8 +56,18 @@ func (h *Handler) handleListVessels(c *gin.Context) {
 		}
 	}
 
-	vessels, err := h.vesselRepo.List(c.Request.Context(), limit, offset)
+	filter, msg := parseVesselFilter(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	vessels, err := h.vesselRepo.Search(c.Request.Context(), filter, limit, offset)
 	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list vessels"})
 		return
 	}
@@ -55,7 +76,58 @@ func (h *Handler) handleListVessels(c *gin.Context) {
 		vessels = []db.Vessel{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": vessels})
+	render.Paged(c, vesselViews(c, vessels), limit, offset)
+}
+
+// parseVesselFilter reads the tonnage range, vessel_type and flag_state query
+// params. It returns a non-empty message when a param is malformed.
+func parseVesselFilter(c *gin.Context) (db.VesselFilter, string) {
+	var filter db.VesselFilter
+
+	bounds := []struct {
+		param string
+		dst   **float64
+	}{
+		{"min_dwt", &filter.MinDWT},
+		{"max_dwt", &filter.MaxDWT},
+		{"min_gross", &filter.MinGross},
+		{"max_gross", &filter.MaxGross},
+		{"min_net", &filter.MinNet},
+		{"max_net", &filter.MaxNet},
+	}
+	for _, b := range bounds {
+		raw := c.Query(b.param)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 {
+			return db.VesselFilter{}, b.param + " must be a non-negative number"
+		}
+		*b.dst = &v
+	}
+
+	ranges := []struct {
+		name     string
+		min, max *float64
+	}{
+		{"dwt", filter.MinDWT, filter.MaxDWT},
+		{"gross", filter.MinGross, filter.MaxGross},
+		{"net", filter.MinNet, filter.MaxNet},
+	}
+	for _, r := range ranges {
+		if r.min != nil && r.max != nil && *r.min > *r.max {
+			return db.VesselFilter{}, "min_" + r.name + " must not exceed max_" + r.name
+		}
+	}
+
+	if v := strings.TrimSpace(c.Query("vessel_type")); v != "" {
+		filter.VesselType = &v
+	}
+	if v := strings.TrimSpace(c.Query("flag_state")); v != "" {
+		filter.FlagState = &v
+	}
+	return filter, ""
 }
 
 func (h *Handler) handleGetVessel(c *gin.Context) {
@@ -75,7 +147,7 @@ func (h *Handler) handleGetVessel(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, vessel)
+	c.JSON(http.StatusOK, vesselView(c, vessel))
 }
 
 type CreateVesselRequest struct {
@@ -112,11 +184,15 @@ func (h *Handler) handleCreateVessel(c *gin.Context) {
 	}
 
 	if err := h.vesselRepo.Create(c.Request.Context(), vessel); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create vessel"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, vessel)
+	c.JSON(http.StatusCreated, vesselView(c, *vessel))
 }
 
 func (h *Handler) handleUpdateVessel(c *gin.Context) {
@@ -169,11 +245,19 @@ func (h *Handler) handleUpdateVessel(c *gin.Context) {
 	}
 
 	if err := h.vesselRepo.Update(c.Request.Context(), &existing); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "vessel not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update vessel"})
 		return
 	}
 
-	c.JSON(http.StatusOK, existing)
+	c.JSON(http.StatusOK, vesselView(c, existing))
 }
 
 func (h *Handler) handleDeleteVessel(c *gin.Context) {
@@ -190,3 +274,144 @@ func (h *Handler) handleDeleteVessel(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "vessel deleted"})
 }
+
+func (h *Handler) handleListVesselCharters(c *gin.Context) {
+	vesselID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vessel ID"})
+		return
+	}
+
+	vessel, err := h.vesselRepo.Retrieve(c.Request.Context(), vesselID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "vessel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve vessel"})
+		return
+	}
+
+	page := db.Page{Limit: 20}
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			page.Limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			page.Offset = parsed
+		}
+	}
+
+	charters, err := h.charterRepo.ListByVesselName(c.Request.Context(), vessel.Name, page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list charters"})
+		return
+	}
+
+	if charters == nil {
+		charters = []db.CharterDetail{}
+	}
+
+	render.Data(c, charters)
+}
+
+// handleVesselUtilization reports days at sea between from and to
+// (YYYY-MM-DD, both inclusive).
+func (h *Handler) handleVesselUtilization(c *gin.Context) {
+	vesselID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vessel ID"})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+		return
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	vessel, err := h.vesselRepo.Retrieve(c.Request.Context(), vesselID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "vessel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve vessel"})
+		return
+	}
+
+	util, err := h.voyageRepo.UtilizationByVessel(c.Request.Context(), vessel.Name, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute utilization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, util)
+}
+
+// handleVesselAvailability reports whether the vessel is free between from
+// and to (YYYY-MM-DD, both inclusive) and lists any conflicting voyages.
+func (h *Handler) handleVesselAvailability(c *gin.Context) {
+	vesselID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vessel ID"})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+		return
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	vessel, err := h.vesselRepo.Retrieve(c.Request.Context(), vesselID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "vessel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve vessel"})
+		return
+	}
+
+	busy, conflicts, err := h.voyageRepo.VesselBusy(c.Request.Context(), vessel.Name, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check availability"})
+		return
+	}
+	if conflicts == nil {
+		conflicts = []db.Voyage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vessel_name": vessel.Name,
+		"from":        from,
+		"to":          to,
+		"available":   !busy,
+		"conflicts":   conflicts,
+	})
+}
@@ -0,0 +1,219 @@
+package marketplace
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestListVesselCharters(t *testing.T) {
+	vesselID := uuid.New()
+	charterID := uuid.New()
+
+	tests := []struct {
+		name       string
+		vessel     uuid.UUID
+		query      string
+		wantStatus int
+		wantList   bool
+	}{
+		{"by vessel name", vesselID, "", http.StatusOK, true},
+		{"unknown vessel", uuid.New(), "", http.StatusNotFound, false},
+		{"deep offset", vesselID, "?offset=50000000", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVessels(fake, map[string]any{"id": vesselID, "name": "Ocean Star"})
+			fake.Return("FROM shipman.charter_details cd", dbtest.Rows(
+				[]string{"id", "title", "vessel_name", "status", "created_at", "updated_at"},
+				[]any{charterID, "Grain charter", "Ocean Star", "active", time.Now(), time.Now()},
+			))
+
+			w := do(t, newTestRouter(), newTestUser("broker"), http.MethodGet, "/vessels/"+tt.vessel.String()+"/charters"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls("FROM shipman.charter_details cd")
+			if !tt.wantList {
+				if len(calls) != 0 {
+					t.Errorf("listed charters %d times, want none", len(calls))
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0].Arg(1) != "Ocean Star" {
+				t.Fatalf("list calls = %v, want one for the vessel's name", calls)
+			}
+			var body struct {
+				Data []db.CharterDetail `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 1 || body.Data[0].ID != charterID {
+				t.Errorf("data = %+v, want the one charter", body.Data)
+			}
+		})
+	}
+}
+
+func TestListVesselsFilterParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantArgs   []any // filter args before limit and offset
+	}{
+		{"none", "", http.StatusOK, nil},
+		{"dwt band", "?min_dwt=30000&max_dwt=80000.5", http.StatusOK, []any{30000.0, 80000.5}},
+		{"gross and net", "?max_gross=9000&min_net=100", http.StatusOK, []any{9000.0, 100.0}},
+		{"type and flag", "?vessel_type=%20Tanker%20&flag_state=MH", http.StatusOK, []any{"Tanker", "MH"}},
+		{"not a number", "?min_dwt=big", http.StatusBadRequest, nil},
+		{"negative", "?max_net=-1", http.StatusBadRequest, nil},
+		{"inverted band", "?min_gross=10&max_gross=5", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.vessels WHERE TRUE", dbtest.Rows([]string{
+				"id", "name", "imo_number", "flag_state", "vessel_type",
+				"deadweight_tonnage", "gross_tonnage", "net_tonnage", "created_at", "updated_at",
+			}))
+
+			w := do(t, newTestRouter(), newTestUser("broker"), http.MethodGet, "/vessels"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls("FROM shipman.vessels WHERE TRUE")
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("a rejected filter still queried vessels")
+				}
+				return
+			}
+			args := calls[0].Args
+			if got := args[:len(args)-2]; !slices.Equal(got, tt.wantArgs) {
+				t.Errorf("filter args = %v, want %v", got, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestVesselUtilization(t *testing.T) {
+	vesselID := uuid.New()
+	const utilQuery = "FROM shipman.voyages WHERE lower(trim(vessel_name))"
+
+	tests := []struct {
+		name       string
+		vessel     uuid.UUID
+		query      string
+		wantStatus int
+	}{
+		{"inclusive window", vesselID, "?from=2026-03-01&to=2026-03-10", http.StatusOK},
+		{"unknown vessel", uuid.New(), "?from=2026-03-01&to=2026-03-10", http.StatusNotFound},
+		{"missing from", vesselID, "?to=2026-03-10", http.StatusBadRequest},
+		{"bad to", vesselID, "?from=2026-03-01&to=10/03/2026", http.StatusBadRequest},
+		{"reversed window", vesselID, "?from=2026-03-10&to=2026-03-01", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVessels(fake, map[string]any{"id": vesselID, "name": "Ocean Star"})
+			fake.Return(utilQuery, dbtest.Rows([]string{"start", "end"},
+				[]any{time.Date(2026, 2, 27, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)},
+				[]any{time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)},
+			))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/vessels/"+tt.vessel.String()+"/utilization"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(utilQuery)
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("computed utilization for a rejected request")
+				}
+				return
+			}
+			from, end := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+			if len(calls) != 1 || calls[0].Arg(1) != "Ocean Star" ||
+				!calls[0].Arg(2).(time.Time).Equal(from) || !calls[0].Arg(3).(time.Time).Equal(end) {
+				t.Fatalf("utilization calls = %+v, want Ocean Star over %s..%s", calls, from, end)
+			}
+			var got db.Utilization
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.VesselName != "Ocean Star" || got.VoyageCount != 2 || got.DaysAtSea != 4.5 || got.UtilizationPct != 45 {
+				t.Errorf("utilization = %+v, want 2 voyages, 4.5 days, 45%%", got)
+			}
+		})
+	}
+}
+
+func TestVesselAvailability(t *testing.T) {
+	vesselID := uuid.New()
+	conflict := uuid.New()
+	const busyQuery = "SELECT id FROM shipman.voyages WHERE lower(trim(vessel_name))"
+
+	tests := []struct {
+		name          string
+		vessel        uuid.UUID
+		query         string
+		busy          [][]any
+		wantStatus    int
+		wantAvailable bool
+	}{
+		{"free", vesselID, "?from=2026-03-01&to=2026-03-10", nil, http.StatusOK, true},
+		{"overlapping", vesselID, "?from=2026-03-01&to=2026-03-10", [][]any{{conflict}}, http.StatusOK, false},
+		{"unknown vessel", uuid.New(), "?from=2026-03-01&to=2026-03-10", nil, http.StatusNotFound, false},
+		{"missing to", vesselID, "?from=2026-03-01", nil, http.StatusBadRequest, false},
+		{"bad from", vesselID, "?from=01/03/2026&to=2026-03-10", nil, http.StatusBadRequest, false},
+		{"reversed window", vesselID, "?from=2026-03-10&to=2026-03-01", nil, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVessels(fake, map[string]any{"id": vesselID, "name": "Ocean Star"})
+			stubVoyages(fake, map[string]any{"id": conflict, "vessel_name": "Ocean Star", "voyage_number": "V-7"})
+			fake.Return(busyQuery, dbtest.Rows([]string{"id"}, tt.busy...))
+
+			w := do(t, newTestRouter(), newTestUser("broker"), http.MethodGet, "/vessels/"+tt.vessel.String()+"/availability"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(busyQuery)
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("checked availability for a rejected request")
+				}
+				return
+			}
+			from, end := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+			if len(calls) != 1 || calls[0].Arg(1) != "Ocean Star" ||
+				!calls[0].Arg(2).(time.Time).Equal(from) || !calls[0].Arg(3).(time.Time).Equal(end) {
+				t.Fatalf("availability calls = %+v, want Ocean Star over %s..%s", calls, from, end)
+			}
+			var got struct {
+				Available bool        `json:"available"`
+				Conflicts []db.Voyage `json:"conflicts"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Available != tt.wantAvailable || got.Conflicts == nil {
+				t.Errorf("availability = %s, want available %v and a conflicts list", w.Body.String(), tt.wantAvailable)
+			}
+			if !tt.wantAvailable && (len(got.Conflicts) != 1 || got.Conflicts[0].ID != conflict) {
+				t.Errorf("conflicts = %+v, want voyage %s", got.Conflicts, conflict)
+			}
+		})
+	}
+}
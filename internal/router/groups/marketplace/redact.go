@@ -0,0 +1,62 @@
+package marketplace
+
+import (
+	"encoding/json"
+	"strings"
+
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveVesselFields holds the JSON names of vessel fields hidden from
+// non-admin requesters. Empty means every field is shown.
+var sensitiveVesselFields []string
+
+// SetSensitiveVesselFields sets the vessel fields, by JSON name (e.g. "owner",
+// "documentation_uri"), that only admins may see.
+func SetSensitiveVesselFields(fields []string) {
+	sensitiveVesselFields = nil
+	for _, f := range fields {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			sensitiveVesselFields = append(sensitiveVesselFields, f)
+		}
+	}
+}
+
+// vesselView returns the vessel as it should be serialised for the
+// requester: unchanged for admins, otherwise with the sensitive fields
+// removed.
+func vesselView(c *gin.Context, v db.Vessel) any {
+	if len(sensitiveVesselFields) == 0 || c.GetString("userRole") == "admin" {
+		return v
+	}
+	return redactVessel(v)
+}
+
+// vesselViews applies vesselView to a list.
+func vesselViews(c *gin.Context, vessels []db.Vessel) any {
+	if len(sensitiveVesselFields) == 0 || c.GetString("userRole") == "admin" {
+		return vessels
+	}
+	out := make([]any, len(vessels))
+	for i, v := range vessels {
+		out[i] = redactVessel(v)
+	}
+	return out
+}
+
+func redactVessel(v db.Vessel) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return v
+	}
+	for _, name := range sensitiveVesselFields {
+		delete(fields, name)
+	}
+	return fields
+}
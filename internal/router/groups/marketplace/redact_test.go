@@ -0,0 +1,93 @@
+package marketplace
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestVesselSensitiveFields(t *testing.T) {
+	t.Cleanup(func() { SetSensitiveVesselFields(nil) })
+	vesselID := uuid.New()
+	vessel := map[string]any{
+		"id": vesselID, "name": "Nordic Star", "flag_state": "NO",
+		"owner": "Nordic Shipping AS", "manager": "Fjord Management", "documentation_uri": "s3://docs/nordic-star",
+	}
+	sensitive := []string{"owner", "manager", "documentation_uri"}
+
+	tests := []struct {
+		name       string
+		configured []string
+		role       string
+		wantHidden bool
+	}{
+		{"admin sees everything", []string{" Owner", "manager ", "documentation_uri", ""}, "admin", false},
+		{"broker has fields hidden", []string{" Owner", "manager ", "documentation_uri", ""}, "broker", true},
+		{"nothing configured", nil, "broker", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetSensitiveVesselFields(tt.configured)
+			fake := newFakeDB(t)
+			stubVessels(fake, vessel)
+
+			w := do(t, newTestRouter(), newTestUser(tt.role), http.MethodGet, "/vessels/"+vesselID.String(), "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var got map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range sensitive {
+				if _, ok := got[field]; ok == tt.wantHidden {
+					t.Errorf("%s present = %v, want %v", field, ok, !tt.wantHidden)
+				}
+			}
+			if got["name"] != "Nordic Star" || got["flag_state"] != "NO" || got["id"] != vesselID.String() {
+				t.Errorf("body = %v, want the other fields kept", got)
+			}
+		})
+	}
+}
+
+func TestVesselListSensitiveFields(t *testing.T) {
+	SetSensitiveVesselFields([]string{"flag_state"})
+	t.Cleanup(func() { SetSensitiveVesselFields(nil) })
+
+	for _, role := range []string{"admin", "charterer"} {
+		t.Run(role, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.vessels WHERE TRUE", dbtest.Rows([]string{
+				"id", "name", "imo_number", "flag_state", "vessel_type",
+				"deadweight_tonnage", "gross_tonnage", "net_tonnage", "created_at", "updated_at",
+			}, []any{uuid.New(), "Nordic Star", nil, "NO", "bulker", nil, nil, nil, time.Now(), time.Now()}))
+
+			w := do(t, newTestRouter(), newTestUser(role), http.MethodGet, "/vessels", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var body struct {
+				Data []map[string]any `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 1 {
+				t.Fatalf("data = %v, want one vessel", body.Data)
+			}
+			_, shown := body.Data[0]["flag_state"]
+			if want := role == "admin"; shown != want {
+				t.Errorf("flag_state shown = %v, want %v", shown, want)
+			}
+			if body.Data[0]["vessel_type"] != "bulker" {
+				t.Errorf("vessel = %v, want vessel_type kept", body.Data[0])
+			}
+		})
+	}
+}
@@ -10,23 +10,27 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"shipman/internal/db"
 	"shipman/internal/rocketramp"
 )
 
-// Handler is the embed-code endpoint group.
+// Handler is the cross-cutting payments endpoint group.
 type Handler struct {
-	rocket *rocketramp.Client
+	rocket      *rocketramp.Client
+	paymentRepo *db.PaymentRepository
 }
 
-// NewHandler constructs the embed-code handler.
+// NewHandler constructs the payments handler.
 func NewHandler(rocket *rocketramp.Client) *Handler {
-	return &Handler{rocket: rocket}
+	return &Handler{rocket: rocket, paymentRepo: db.NewPaymentRepository()}
 }
 
 // AddRoutes wires the routes under an already-authenticated group.
 func (h *Handler) AddRoutes(r *gin.RouterGroup) {
 	r.POST("/embed-code", h.handleCreateEmbedCode)
 	r.GET("/embed-config", h.handleEmbedConfig)
+	r.POST("/status", h.handleBulkStatus)
 }
 
 type createEmbedCodeRequest struct {
@@ -92,3 +96,50 @@ func (h *Handler) handleEmbedConfig(c *gin.Context) {
 		"embed_base_url": h.rocket.EmbedBaseURL(),
 	})
 }
+
+// maxBulkStatusIDs bounds the id list accepted by POST /payments/status.
+const maxBulkStatusIDs = 500
+
+type bulkStatusRequest struct {
+	IDs    []uuid.UUID `json:"ids" binding:"required"`
+	Status string      `json:"status" binding:"required"`
+}
+
+// POST /api/v1/payments/status
+//
+//	{ "ids": ["…", "…"], "status": "disputed" }
+//
+// Admin only. Moves every listed payment that can make the transition and
+// returns how many were updated; payments that cannot move are skipped.
+func (h *Handler) handleBulkStatus(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req bulkStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+	if len(req.IDs) > maxBulkStatusIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many ids"})
+		return
+	}
+
+	updated, err := h.paymentRepo.UpdateStatusMany(c.Request.Context(), req.IDs, db.PaymentStatus(req.Status))
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be disputed or cancelled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated, "requested": len(req.IDs)})
+}
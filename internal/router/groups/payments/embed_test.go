@@ -0,0 +1,52 @@
+package payments
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const bulkStatusUpdate = "UPDATE shipman.voyage_payments SET status = $2"
+
+func TestBulkPaymentStatus(t *testing.T) {
+	ids := fmt.Sprintf(`["%s","%s","%s"]`, uuid.New(), uuid.New(), uuid.New())
+	tests := []struct {
+		name       string
+		role       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid batch", "admin", `{"ids":` + ids + `,"status":"disputed"}`, http.StatusOK, `{"requested":3,"updated":2}`},
+		{"invalid status", "admin", `{"ids":` + ids + `,"status":"completed"}`, http.StatusBadRequest, `{"error":"status must be disputed or cancelled"}`},
+		{"empty ids", "admin", `{"ids":[],"status":"cancelled"}`, http.StatusBadRequest, `{"error":"ids must not be empty"}`},
+		{"too many ids", "admin", `{"ids":[` + strings.Repeat(`"`+uuid.NewString()+`",`, maxBulkStatusIDs) + `"` + uuid.NewString() + `"],"status":"cancelled"}`, http.StatusBadRequest, `{"error":"too many ids"}`},
+		{"not admin", "broker", `{"ids":` + ids + `,"status":"disputed"}`, http.StatusForbidden, `{"error":"insufficient permissions"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(bulkStatusUpdate, dbtest.Affected(2))
+
+			w := do(t, newTestRouter(), tt.role, http.MethodPost, "/status", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+			calls := fake.Calls(bulkStatusUpdate)
+			if ran := len(calls) > 0; ran != (tt.wantStatus == http.StatusOK) {
+				t.Fatalf("update ran = %v", ran)
+			}
+			if len(calls) == 1 && (calls[0].Arg(2) != "disputed" || len(calls[0].Arg(1).([]string)) != 3) {
+				t.Errorf("update args = %v, want 3 ids to disputed", calls[0].Args)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package payments
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/router/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var testJWT = auth.NewJWTManager("test-secret", time.Hour)
+
+// newFakeDB installs a dbtest.Fake as db.Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+// newTestRouter mounts the payments routes behind the real bearer-token
+// middleware.
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/")
+	g.Use(middleware.Auth(testJWT))
+	NewHandler(nil).AddRoutes(g)
+	return r
+}
+
+func do(t *testing.T, r http.Handler, role, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := testJWT.Generate(uuid.New(), db.DefaultOrgID, "user@example.com", role, "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
@@ -1,7 +1,10 @@
 package users
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
@@ -46,6 +49,11 @@ func (h *Handler) AddPublicRoutes(r *gin.RouterGroup) {
 	r.POST("/signin", h.handleSignin)
 }
 
+// AddAuthRoutes mounts the sign-in endpoint under its /auth name.
+func (h *Handler) AddAuthRoutes(r *gin.RouterGroup) {
+	r.POST("/login", h.handleSignin)
+}
+
 func (h *Handler) AddProtectedRoutes(r *gin.RouterGroup) {
 	r.GET("/me", h.handleMe)
 }
@@ -67,25 +75,22 @@ func (h *Handler) handleSignup(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(req.Password)
-	if err != nil {
+	user := &db.User{
+		Email:    strings.ToLower(req.Email),
+		FullName: req.FullName,
+		Role:     req.Role,
+	}
+	if err := user.SetPassword(req.Password); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
 		return
 	}
 
-	user := &db.User{
-		Email:        strings.ToLower(req.Email),
-		PasswordHash: hashedPassword,
-		FullName:     req.FullName,
-		Role:         req.Role,
-	}
-
 	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
 	}
 
-	token, err := h.jwtManager.Generate(user.ID, user.Email, user.Role, user.FullName)
+	token, err := h.jwtManager.Generate(user.ID, user.OrgID, user.Email, user.Role, user.FullName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
@@ -107,6 +112,7 @@ func (h *Handler) handleSignin(c *gin.Context) {
 	user, err := h.userRepo.RetrieveByEmail(c.Request.Context(), strings.ToLower(req.Email))
 	if err != nil {
 		if err == sql.ErrNoRows {
+			auth.CompareDummy(req.Password)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
 			return
 		}
@@ -114,12 +120,13 @@ func (h *Handler) handleSignin(c *gin.Context) {
 		return
 	}
 
-	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+	if !user.CheckPassword(req.Password) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
 		return
 	}
+	h.upgradePasswordHash(c.Request.Context(), user, req.Password)
 
-	token, err := h.jwtManager.Generate(user.ID, user.Email, user.Role, user.FullName)
+	token, err := h.jwtManager.Generate(user.ID, user.OrgID, user.Email, user.Role, user.FullName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
@@ -131,6 +138,23 @@ func (h *Handler) handleSignin(c *gin.Context) {
 	})
 }
 
+// upgradePasswordHash re-hashes a just-verified password when the stored hash
+// uses a lower bcrypt cost than current. Failures are logged and never block
+// the sign-in.
+func (h *Handler) upgradePasswordHash(ctx context.Context, user db.User, password string) {
+	if !auth.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("rehash password for user %s: %v", user.ID, err)
+		return
+	}
+	if err := h.userRepo.UpgradePasswordHash(ctx, user.ID, user.PasswordHash, hash); err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Printf("store upgraded password hash for user %s: %v", user.ID, err)
+	}
+}
+
 func (h *Handler) handleMe(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
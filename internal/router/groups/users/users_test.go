@@ -0,0 +1,156 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// userDriver serves the RetrieveByEmail query from an in-memory set of
+// users keyed by email. Any other statement fails.
+type userDriver struct {
+	users map[string]db.User
+}
+
+func (d userDriver) Open(string) (driver.Conn, error)             { return userConn(d), nil }
+func (d userDriver) Connect(context.Context) (driver.Conn, error) { return userConn(d), nil }
+func (d userDriver) Driver() driver.Driver                        { return d }
+
+type userConn userDriver
+
+func (c userConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c userConn) Close() error              { return nil }
+func (c userConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+func (c userConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "WHERE email = $1") || len(args) != 1 {
+		return nil, errors.New("unexpected query")
+	}
+	rows := &userRows{}
+	if u, ok := c.users[args[0].Value.(string)]; ok {
+		rows.values = [][]driver.Value{{
+			u.ID.String(), u.OrgID.String(), u.Email, u.PasswordHash, u.FullName, u.Role,
+			nil, nil, u.CreatedAt, u.UpdatedAt,
+		}}
+	}
+	return rows, nil
+}
+
+type userRows struct {
+	values [][]driver.Value
+}
+
+func (r *userRows) Columns() []string {
+	return []string{"id", "org_id", "email", "password_hash", "full_name", "role",
+		"coinsub_merchant_id", "wallet_address", "created_at", "updated_at"}
+}
+
+func (r *userRows) Close() error { return nil }
+
+func (r *userRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSignin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.SetBcryptCost(bcrypt.MinCost)
+	defer auth.SetBcryptCost(auth.DefaultBcryptCost)
+
+	hash, err := auth.HashPassword("s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := db.User{
+		ID:           uuid.New(),
+		OrgID:        uuid.New(),
+		Email:        "captain@example.com",
+		PasswordHash: hash,
+		FullName:     "Ada Captain",
+		Role:         "shipowner",
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}
+	pool := sql.OpenDB(userDriver{users: map[string]db.User{user.Email: user}})
+	defer pool.Close()
+	prev := db.Pool
+	db.SetPool(pool)
+	defer db.SetPool(prev)
+
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	r := gin.New()
+	NewHandler(jwtManager).AddAuthRoutes(r.Group("/auth"))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"success", `{"email":"captain@example.com","password":"s3cret-pass"}`, http.StatusOK},
+		{"email is case-insensitive", `{"email":"Captain@Example.com","password":"s3cret-pass"}`, http.StatusOK},
+		{"wrong password", `{"email":"captain@example.com","password":"wrong-pass"}`, http.StatusUnauthorized},
+		{"unknown email", `{"email":"nobody@example.com","password":"s3cret-pass"}`, http.StatusUnauthorized},
+		{"missing password", `{"email":"captain@example.com"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			switch tt.wantStatus {
+			case http.StatusOK:
+				var resp AuthResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				claims, err := jwtManager.Verify(resp.Token)
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if claims.UserID != user.ID || claims.OrgID != user.OrgID || claims.Role != user.Role {
+					t.Errorf("claims = %+v, want user %s org %s role %s", claims, user.ID, user.OrgID, user.Role)
+				}
+				if resp.User.ID != user.ID {
+					t.Errorf("user id = %s, want %s", resp.User.ID, user.ID)
+				}
+				if strings.Contains(w.Body.String(), hash) {
+					t.Error("response leaks the password hash")
+				}
+			case http.StatusUnauthorized:
+				var resp map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp["error"] != "invalid email or password" {
+					t.Errorf("error = %q, want %q", resp["error"], "invalid email or password")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package voyages
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"shipman/internal/db"
+)
+
+// defaultFleetWindow is how far back /fleet/positions looks without ?since=.
+const defaultFleetWindow = 24 * time.Hour
+
+// FleetHandler serves operational views across every active voyage.
+type FleetHandler struct {
+	positionRepo *db.ShipPositionRepository
+	voyageRepo   *db.VoyageRepository
+}
+
+func NewFleetHandler() *FleetHandler {
+	return &FleetHandler{
+		positionRepo: db.NewShipPositionRepository(),
+		voyageRepo:   db.NewVoyageRepository(),
+	}
+}
+
+func (h *FleetHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/positions", h.handlePositions)
+}
+
+// AddVoyageRoutes mounts the fleet-wide voyage views on the voyages group.
+func (h *FleetHandler) AddVoyageRoutes(r *gin.RouterGroup) {
+	r.GET("/stale", h.handleStaleVoyages)
+}
+
+// parseSince reads ?since= (RFC 3339), defaulting to defaultFleetWindow ago.
+// It writes a 400 and returns false when the value is malformed.
+func parseSince(c *gin.Context) (time.Time, bool) {
+	raw := c.Query("since")
+	if raw == "" {
+		return time.Now().Add(-defaultFleetWindow), true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// handlePositions returns the last-known position of each in-progress voyage
+// recorded after ?since= (RFC 3339, defaults to 24 hours ago).
+func (h *FleetHandler) handlePositions(c *gin.Context) {
+	since, ok := parseSince(c)
+	if !ok {
+		return
+	}
+
+	positions, err := h.positionRepo.FleetPositionsSince(c.Request.Context(), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load fleet positions"})
+		return
+	}
+	if positions == nil {
+		positions = []db.FleetPosition{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": positions, "since": since})
+}
+
+// handleStaleVoyages lists in-progress voyages with no position reported
+// since ?since= (RFC 3339, defaults to 24 hours ago).
+func (h *FleetHandler) handleStaleVoyages(c *gin.Context) {
+	since, ok := parseSince(c)
+	if !ok {
+		return
+	}
+
+	voyages, err := h.voyageRepo.ListStale(c.Request.Context(), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list stale voyages"})
+		return
+	}
+	if voyages == nil {
+		voyages = []db.Voyage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": voyages, "since": since})
+}
@@ -0,0 +1,151 @@
+package voyages
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var fleetPositionColumns = []string{
+	"id", "voyage_number", "vessel_name", "imo_number",
+	"position_id", "recorded_at", "latitude", "longitude", "speed_knots", "heading", "source",
+}
+
+func TestFleetPositionsEndpoint(t *testing.T) {
+	since := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+	voyageID := uuid.New()
+
+	tests := []struct {
+		name       string
+		query      string
+		rows       [][]any
+		wantStatus int
+		wantSince  func(time.Time) bool
+		wantCount  int
+	}{
+		{
+			name: "explicit since", query: "?since=" + url.QueryEscape(since.Format(time.RFC3339)),
+			rows:       [][]any{{voyageID, "V-1", "Nordic Star", nil, uuid.New(), since.Add(time.Hour), 1.29, 103.85, 12.5, nil, "ais"}},
+			wantStatus: http.StatusOK, wantCount: 1,
+			wantSince: func(got time.Time) bool { return got.Equal(since) },
+		},
+		{
+			name: "defaults to the past day", wantStatus: http.StatusOK,
+			wantSince: func(got time.Time) bool {
+				d := time.Since(got)
+				return d > 24*time.Hour-time.Minute && d < 24*time.Hour+time.Minute
+			},
+		},
+		{name: "malformed since", query: "?since=yesterday", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("JOIN LATERAL", dbtest.Rows(fleetPositionColumns, tt.rows...))
+
+			r := newGroupRouter(NewFleetHandler().AddRoutes)
+			w := do(t, r, newTestUser("admin"), http.MethodGet, "/positions"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls("JOIN LATERAL")
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("queried positions for a malformed since")
+				}
+				return
+			}
+			if len(calls) != 1 || !tt.wantSince(calls[0].Arg(1).(time.Time)) {
+				t.Fatalf("calls = %+v, want one with the expected since", calls)
+			}
+
+			var body struct {
+				Data  []db.FleetPosition `json:"data"`
+				Since time.Time          `json:"since"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Data == nil || len(body.Data) != tt.wantCount {
+				t.Fatalf("data = %s, want %d positions", w.Body.String(), tt.wantCount)
+			}
+			if tt.wantCount > 0 && (body.Data[0].VoyageID != voyageID || *body.Data[0].VesselName != "Nordic Star") {
+				t.Errorf("position = %+v", body.Data[0])
+			}
+			if !tt.wantSince(body.Since) {
+				t.Errorf("since = %s", body.Since)
+			}
+		})
+	}
+}
+
+func TestStaleVoyagesEndpoint(t *testing.T) {
+	const stale = "SELECT v.id FROM shipman.voyages v WHERE v.actual_departure_at IS NOT NULL"
+	since := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+	quiet := uuid.New()
+
+	tests := []struct {
+		name       string
+		query      string
+		rows       [][]any
+		wantStatus int
+		wantSince  func(time.Time) bool
+	}{
+		{
+			name: "explicit since", query: "?since=" + url.QueryEscape(since.Format(time.RFC3339)),
+			rows: [][]any{{quiet}}, wantStatus: http.StatusOK,
+			wantSince: func(got time.Time) bool { return got.Equal(since) },
+		},
+		{
+			name: "defaults to the past day", wantStatus: http.StatusOK,
+			wantSince: func(got time.Time) bool {
+				d := time.Since(got)
+				return d > 24*time.Hour-time.Minute && d < 24*time.Hour+time.Minute
+			},
+		},
+		{name: "malformed since", query: "?since=last-week", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": quiet, "status": "in_progress", "actual_departure_at": since.AddDate(0, 0, -3)})
+			fake.Return(stale, dbtest.Rows([]string{"id"}, tt.rows...))
+
+			r := newGroupRouter(NewFleetHandler().AddVoyageRoutes)
+			w := do(t, r, newTestUser("admin"), http.MethodGet, "/stale"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(stale)
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("listed voyages for a malformed since")
+				}
+				return
+			}
+			if len(calls) != 1 || !tt.wantSince(calls[0].Arg(1).(time.Time)) {
+				t.Fatalf("calls = %+v, want one with the expected since", calls)
+			}
+
+			var body struct {
+				Data []db.Voyage `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Data == nil || len(body.Data) != len(tt.rows) {
+				t.Fatalf("data = %s, want %d voyages", w.Body.String(), len(tt.rows))
+			}
+			if len(body.Data) > 0 && body.Data[0].ID != quiet {
+				t.Errorf("voyage = %s, want %s", body.Data[0].ID, quiet)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package voyages
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/router/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var testJWT = auth.NewJWTManager("test-secret", time.Hour)
+
+// testUser is the caller a test request is authenticated as.
+type testUser struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+	Role  string
+}
+
+func newTestUser(role string) testUser {
+	return testUser{ID: uuid.New(), OrgID: db.DefaultOrgID, Role: role}
+}
+
+// newFakeDB installs a dbtest.Fake as db.Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+// freezeClock sets the db package clock to at for the rest of the test.
+func freezeClock(t *testing.T, at time.Time) {
+	t.Helper()
+	db.SetClock(db.FixedClock(at))
+	t.Cleanup(func() { db.SetClock(nil) })
+}
+
+// newTestRouter mounts the voyage routes behind the real bearer-token
+// middleware.
+func newTestRouter() *gin.Engine {
+	return newGroupRouter(NewHandler("", "", "", "", "", nil, "").AddRoutes)
+}
+
+// newGroupRouter mounts routes behind the real bearer-token middleware.
+func newGroupRouter(mount func(*gin.RouterGroup)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/")
+	g.Use(middleware.Auth(testJWT))
+	mount(g)
+	return r
+}
+
+func do(t *testing.T, r http.Handler, u testUser, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := testJWT.Generate(u.ID, u.OrgID, "user@example.com", u.Role, "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// voyageRetrieveQuery matches VoyageRepository.Retrieve.
+const voyageRetrieveQuery = "archived_at, created_at, updated_at FROM shipman.voyages WHERE id = $1"
+
+var voyageColumns = []string{
+	"id", "org_id", "charter_detail_id", "deal_id", "owner_user_id",
+	"voyage_number", "vessel_name", "imo_number", "vessel_type", "dwt", "flag_state",
+	"departure_port", "arrival_port",
+	"planned_departure_at", "planned_arrival_at", "actual_departure_at", "actual_arrival_at",
+	"distance_nm", "time_at_sea_hours", "fuel_consumed_mt", "fuel_type", "weather_summary",
+	"hire_rate", "freight_rate", "cargo_quantity", "cargo_type",
+	"laytime_allowed_hours", "demurrage_rate", "despatch_rate", "demurrage_currency",
+	"payment_frequency", "first_payment_date", "total_contract_value",
+	"commission_rate", "bunker_cost", "port_costs", "insurance_cost",
+	"counterparty_name", "counterparty_email", "counterparty_user_id", "broker_user_id",
+	"document_id", "charter_type", "status", "notes", "archived_at", "created_at", "updated_at",
+}
+
+// stubVoyages answers voyage lookups with rows built from the given values
+// keyed by voyages column name; each must include "id". Voyages outside the
+// org a lookup is scoped to are not found.
+func stubVoyages(fake *dbtest.Fake, voyages ...map[string]any) {
+	byID := make(map[string][]any, len(voyages))
+	orgs := make(map[string]string, len(voyages))
+	for _, v := range voyages {
+		values := map[string]any{
+			"org_id":             db.DefaultOrgID,
+			"demurrage_currency": "USD",
+			"status":             "planned",
+			"created_at":         time.Now(),
+			"updated_at":         time.Now(),
+		}
+		for k, val := range v {
+			values[k] = val
+		}
+		id := values["id"].(uuid.UUID).String()
+		byID[id] = dbtest.Row(voyageColumns, values)
+		orgs[id] = fmt.Sprint(values["org_id"])
+	}
+	fake.On(voyageRetrieveQuery, func(call dbtest.Call) dbtest.Result {
+		id := call.Arg(1).(string)
+		row, ok := byID[id]
+		if org, scoped := call.Arg(2).(string); scoped && org != orgs[id] {
+			ok = false
+		}
+		if !ok {
+			return dbtest.Rows(voyageColumns)
+		}
+		return dbtest.Rows(voyageColumns, row)
+	})
+}
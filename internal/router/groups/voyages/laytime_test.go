@@ -0,0 +1,217 @@
+package voyages
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestAddLaytimeBatchReimport(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID, charterID := uuid.New(), uuid.New()
+	fake := newFakeDB(t)
+	stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID.String(), "charter_detail_id": charterID.String()})
+	// Rows keyed on charter, port, activity and start, as the unique index is.
+	stored := map[string]uuid.UUID{}
+	fake.On("INSERT INTO shipman.laytime_entries", func(call dbtest.Call) dbtest.Result {
+		key := fmt.Sprint(call.Arg(1), call.Arg(3), call.Arg(4), call.Arg(5))
+		id, ok := stored[key]
+		if !ok {
+			id = uuid.New()
+			stored[key] = id
+		}
+		return dbtest.Rows([]string{"id", "created_at", "updated_at", "inserted"}, []any{id, time.Now(), time.Now(), !ok})
+	})
+	body := `{"entries":[
+		{"port_name":"Santos","activity":"loading","started_at":"2026-05-01T06:00:00Z","ended_at":"2026-05-01T12:00:00Z"},
+		{"port_name":"Santos","activity":"waiting","started_at":"2026-05-01T06:00:00Z"}
+	]}`
+	r := newTestRouter()
+
+	for _, want := range []struct{ inserted, updated int }{{2, 0}, {0, 2}} {
+		w := do(t, r, owner, http.MethodPost, "/"+voyageID.String()+"/laytime/batch", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+		var got struct {
+			Inserted int `json:"inserted"`
+			Updated  int `json:"updated"`
+			Entries  []struct {
+				ID              uuid.UUID `json:"id"`
+				CharterDetailID uuid.UUID `json:"charter_detail_id"`
+			} `json:"entries"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Inserted != want.inserted || got.Updated != want.updated {
+			t.Errorf("inserted/updated = %d/%d, want %d/%d", got.Inserted, got.Updated, want.inserted, want.updated)
+		}
+		if len(got.Entries) != 2 || got.Entries[0].CharterDetailID != charterID {
+			t.Errorf("entries = %+v, want two on the voyage's charter", got.Entries)
+		}
+	}
+	if len(stored) != 2 {
+		t.Errorf("stored %d rows after importing twice, want 2", len(stored))
+	}
+}
+
+func TestAddLaytimeBatchRejects(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	tests := []struct {
+		name       string
+		user       testUser
+		body       string
+		wantStatus int
+	}{
+		{"stranger", newTestUser("charterer"), `{"entries":[{"port_name":"Santos","activity":"loading","started_at":"2026-05-01T06:00:00Z"}]}`, http.StatusForbidden},
+		{"empty batch", owner, `{"entries":[]}`, http.StatusBadRequest},
+		{"ends before it starts", owner, `{"entries":[{"port_name":"Santos","activity":"loading","started_at":"2026-05-01T06:00:00Z","ended_at":"2026-05-01T05:00:00Z"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID.String(), "charter_detail_id": uuid.NewString()})
+
+			w := do(t, newTestRouter(), tt.user, http.MethodPost, "/"+voyageID.String()+"/laytime/batch", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if len(fake.Calls("INSERT INTO shipman.laytime_entries")) != 0 {
+				t.Error("a rejected batch was written")
+			}
+		})
+	}
+}
+
+func TestAddLaytimeWeatherExclusions(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	const entry = `"port_name":"Santos","activity":"loading","started_at":"2026-05-01T06:00:00Z","ended_at":"2026-05-01T18:00:00Z"`
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantHours  float64
+	}{
+		{"without exclusions", `{` + entry + `}`, http.StatusCreated, 12},
+		{"with weather exclusions", `{` + entry + `,"excluded_hours":4.5}`, http.StatusCreated, 7.5},
+		{"exclusions exceed the interval", `{` + entry + `,"excluded_hours":13}`, http.StatusBadRequest, 0},
+		{"negative exclusions", `{` + entry + `,"excluded_hours":-1}`, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID.String()})
+			fake.Return("INSERT INTO shipman.laytime_entries", dbtest.Rows([]string{"id", "created_at", "updated_at"},
+				[]any{uuid.New(), time.Now(), time.Now()}))
+
+			w := do(t, newTestRouter(), owner, http.MethodPost, "/"+voyageID.String()+"/laytime", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls("INSERT INTO shipman.laytime_entries")
+			if tt.wantStatus != http.StatusCreated {
+				if len(calls) != 0 {
+					t.Error("a rejected entry was written")
+				}
+				return
+			}
+			var got struct {
+				HoursCounted  float64 `json:"hours_counted"`
+				ExcludedHours float64 `json:"excluded_hours"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.HoursCounted != tt.wantHours || got.HoursCounted+got.ExcludedHours != 12 {
+				t.Errorf("entry = %+v, want %v counted of 12 gross", got, tt.wantHours)
+			}
+			if len(calls) != 1 || calls[0].Arg(7) != tt.wantHours {
+				t.Errorf("insert calls = %+v, want hours_counted %v stored", calls, tt.wantHours)
+			}
+		})
+	}
+}
+
+func TestPreviewLaytime(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	const insert = "INSERT INTO shipman.laytime_entries"
+	body := `{"entries":[
+		{"port_name":"Santos","activity":"loading","started_at":"2026-05-01T06:00:00Z","ended_at":"2026-05-01T18:00:00Z","excluded_hours":4.5},
+		{"port_name":"Santos","activity":"waiting","started_at":"2026-05-01T06:00:00Z","ended_at":"2026-05-01T08:00:00Z","excluded_hours":3},
+		{"port_name":"Santos","activity":"shifting","started_at":"2026-05-01T18:00:00Z"}
+	]}`
+	type entry struct {
+		ID           uuid.UUID `json:"id"`
+		HoursCounted *float64  `json:"hours_counted"`
+	}
+
+	fake := newFakeDB(t)
+	stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID.String(), "charter_detail_id": uuid.NewString()})
+	fake.Return(insert, dbtest.Rows([]string{"id", "created_at", "updated_at", "inserted"}, []any{uuid.New(), time.Now(), time.Now(), true}))
+	r := newTestRouter()
+
+	w := do(t, r, owner, http.MethodPost, "/"+voyageID.String()+"/laytime/preview", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var preview struct {
+		Valid   bool    `json:"valid"`
+		Entries []entry `json:"entries"`
+		Errors  []struct {
+			Index int    `json:"index"`
+			Error string `json:"error"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if preview.Valid || len(preview.Errors) != 1 || preview.Errors[0].Index != 1 {
+		t.Fatalf("preview = %+v, want only entry 1 rejected", preview)
+	}
+	if h := preview.Entries[0].HoursCounted; h == nil || *h != 7.5 {
+		t.Errorf("previewed hours = %v, want 7.5", h)
+	}
+	if preview.Entries[2].HoursCounted != nil || preview.Entries[0].ID != uuid.Nil {
+		t.Errorf("preview entries = %+v, want no hours for the open entry and no ids", preview.Entries)
+	}
+	if len(fake.Calls(insert)) != 0 {
+		t.Fatal("preview wrote entries")
+	}
+
+	// Committing the valid rows stores exactly what the preview showed.
+	valid := `{"entries":[
+		{"port_name":"Santos","activity":"loading","started_at":"2026-05-01T06:00:00Z","ended_at":"2026-05-01T18:00:00Z","excluded_hours":4.5},
+		{"port_name":"Santos","activity":"shifting","started_at":"2026-05-01T18:00:00Z"}
+	]}`
+	w = do(t, r, owner, http.MethodPost, "/"+voyageID.String()+"/laytime/batch", valid)
+	if w.Code != http.StatusOK {
+		t.Fatalf("commit status = %d: %s", w.Code, w.Body.String())
+	}
+	var committed struct {
+		Entries []entry `json:"entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &committed); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls(insert)
+	if len(calls) != 2 || calls[0].Arg(7) != 7.5 || calls[1].Arg(7) != nil {
+		t.Fatalf("inserts = %+v, want the previewed hours stored", calls)
+	}
+	for i, want := range []entry{preview.Entries[0], preview.Entries[2]} {
+		got := committed.Entries[i].HoursCounted
+		if (got == nil) != (want.HoursCounted == nil) || (got != nil && *got != *want.HoursCounted) {
+			t.Errorf("entry %d: committed hours %v, previewed %v", i, got, want.HoursCounted)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package voyages
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"shipman/internal/db"
+	"shipman/internal/router/render"
+)
+
+// loadParticipantVoyage parses :id and loads the voyage, requiring the caller
+// to be one of its parties. It writes the error response and returns false
+// when the request should stop.
+func (h *Handler) loadParticipantVoyage(c *gin.Context) (db.Voyage, bool) {
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return db.Voyage{}, false
+	}
+	v, err := h.voyageRepo.Retrieve(c.Request.Context(), voyageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return db.Voyage{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get voyage"})
+		return db.Voyage{}, false
+	}
+	if !isVoyageParticipant(v, c.MustGet("userID").(uuid.UUID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return db.Voyage{}, false
+	}
+	return v, true
+}
+
+// loadVoyageNOR loads :norId and checks that it belongs to voyageID.
+func (h *Handler) loadVoyageNOR(c *gin.Context, voyageID uuid.UUID) (db.NOREvent, bool) {
+	norID, err := uuid.Parse(c.Param("norId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid NOR ID"})
+		return db.NOREvent{}, false
+	}
+	n, err := h.norRepo.Retrieve(c.Request.Context(), norID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "NOR not found"})
+			return db.NOREvent{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve NOR"})
+		return db.NOREvent{}, false
+	}
+	if n.VoyageID != voyageID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOR not found"})
+		return db.NOREvent{}, false
+	}
+	return n, true
+}
+
+func (h *Handler) handleListNOR(c *gin.Context) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return
+	}
+	events, err := h.norRepo.ListByVoyage(c.Request.Context(), v.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list NOR events"})
+		return
+	}
+	if events == nil {
+		events = []db.NOREvent{}
+	}
+	render.Data(c, events)
+}
+
+type NORRequest struct {
+	PortName      string     `json:"port_name" binding:"required"`
+	TenderedAt    time.Time  `json:"tendered_at" binding:"required"`
+	AcceptedAt    *time.Time `json:"accepted_at"`
+	TurnTimeHours float64    `json:"turn_time_hours" binding:"gte=0"`
+	Remarks       *string    `json:"remarks"`
+}
+
+func (h *Handler) handleCreateNOR(c *gin.Context) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return
+	}
+	var req NORRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	n := &db.NOREvent{
+		VoyageID:        v.ID,
+		CharterDetailID: v.CharterDetailID,
+		PortName:        req.PortName,
+		TenderedAt:      req.TenderedAt,
+		AcceptedAt:      req.AcceptedAt,
+		TurnTimeHours:   req.TurnTimeHours,
+		Remarks:         req.Remarks,
+	}
+	if err := h.norRepo.Create(c.Request.Context(), n); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrEndBeforeStart) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "accepted_at must not be before tendered_at"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record NOR"})
+		return
+	}
+	c.JSON(http.StatusCreated, n)
+}
+
+func (h *Handler) handleUpdateNOR(c *gin.Context) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return
+	}
+	existing, ok := h.loadVoyageNOR(c, v.ID)
+	if !ok {
+		return
+	}
+	var req NORRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing.PortName = req.PortName
+	existing.TenderedAt = req.TenderedAt
+	existing.AcceptedAt = req.AcceptedAt
+	existing.TurnTimeHours = req.TurnTimeHours
+	existing.Remarks = req.Remarks
+	if err := h.norRepo.Update(c.Request.Context(), &existing); err != nil {
+		switch {
+		case errors.Is(err, db.ErrEndBeforeStart):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "accepted_at must not be before tendered_at"})
+		case errors.Is(err, db.ErrNotesTooLong):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, db.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "NOR not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update NOR"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, existing)
+}
+
+func (h *Handler) handleDeleteNOR(c *gin.Context) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return
+	}
+	n, ok := h.loadVoyageNOR(c, v.ID)
+	if !ok {
+		return
+	}
+	if err := h.norRepo.Delete(c.Request.Context(), n.ID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "NOR not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete NOR"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
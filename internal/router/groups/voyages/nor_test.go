@@ -0,0 +1,139 @@
+package voyages
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var norColumns = []string{
+	"id", "voyage_id", "charter_detail_id", "port_name", "tendered_at", "accepted_at",
+	"turn_time_hours", "remarks", "created_at", "updated_at",
+}
+
+func TestCreateAndListNOR(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID, charterID := uuid.New(), uuid.New()
+	tendered := time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC)
+	fake := newFakeDB(t)
+	stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID, "charter_detail_id": charterID})
+
+	var stored [][]any
+	fake.On("INSERT INTO shipman.nor_events", func(call dbtest.Call) dbtest.Result {
+		id := uuid.New()
+		stored = append(stored, []any{id, call.Arg(1), call.Arg(2), call.Arg(3), call.Arg(4), call.Arg(5),
+			call.Arg(6), call.Arg(7), time.Now(), time.Now()})
+		return dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{id, time.Now(), time.Now()})
+	})
+	fake.On("FROM shipman.nor_events WHERE voyage_id = $1", func(call dbtest.Call) dbtest.Result {
+		return dbtest.Rows(norColumns, stored...)
+	})
+	r := newTestRouter()
+
+	w := do(t, r, owner, http.MethodPost, "/"+voyageID.String()+"/nor",
+		`{"port_name":"Santos","tendered_at":"2026-04-02T08:00:00Z","accepted_at":"2026-04-02T10:00:00Z","turn_time_hours":6}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body.String())
+	}
+	var created db.NOREvent
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == uuid.Nil || created.CharterDetailID == nil || *created.CharterDetailID != charterID {
+		t.Errorf("created = %+v, want an id and the voyage's charter", created)
+	}
+
+	w = do(t, r, owner, http.MethodGet, "/"+voyageID.String()+"/nor", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []db.NOREvent `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 1 || body.Data[0].ID != created.ID || !body.Data[0].TenderedAt.Equal(tendered) ||
+		body.Data[0].AcceptedAt == nil || body.Data[0].TurnTimeHours != 6 {
+		t.Errorf("list = %+v, want the recorded notice", body.Data)
+	}
+}
+
+func TestCreateNORRejects(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	tests := []struct {
+		name       string
+		user       testUser
+		body       string
+		wantStatus int
+	}{
+		{"stranger", newTestUser("charterer"), `{"port_name":"Santos","tendered_at":"2026-04-02T08:00:00Z"}`, http.StatusForbidden},
+		{"missing port", owner, `{"tendered_at":"2026-04-02T08:00:00Z"}`, http.StatusBadRequest},
+		{"negative turn time", owner, `{"port_name":"Santos","tendered_at":"2026-04-02T08:00:00Z","turn_time_hours":-1}`, http.StatusBadRequest},
+		{"accepted before tendered", owner, `{"port_name":"Santos","tendered_at":"2026-04-02T08:00:00Z","accepted_at":"2026-04-01T08:00:00Z"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+			fake.Return("INSERT INTO shipman.nor_events", dbtest.Rows([]string{"id", "created_at", "updated_at"}, []any{uuid.New(), time.Now(), time.Now()}))
+
+			w := do(t, newTestRouter(), tt.user, http.MethodPost, "/"+voyageID.String()+"/nor", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if len(fake.Calls("INSERT INTO shipman.nor_events")) != 0 {
+				t.Error("a rejected notice was written")
+			}
+		})
+	}
+}
+
+func TestLaytimeSummaryFromNOR(t *testing.T) {
+	voyageID := uuid.New()
+	tests := []struct {
+		query    string
+		wantNOR  bool
+		wantUsed float64
+	}{
+		{"", false, 60},
+		{"?from_nor=true", true, 54},
+		{"?from_nor=false", false, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("COALESCE(demurrage_currency, 'USD') FROM shipman.voyages WHERE id = $1",
+				dbtest.Rows([]string{"allowed", "dem_rate", "desp_rate", "currency"}, []any{48.0, 24000.0, 12000.0, "USD"}))
+			fake.Return("SELECT COALESCE(SUM(hours_counted), 0) FROM shipman.laytime_entries", dbtest.Rows([]string{"sum"}, []any{60.0}))
+			fake.Return("LEFT JOIN LATERAL", dbtest.Rows([]string{"sum"}, []any{54.0}))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/laytime/summary"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var got db.LaytimeSummary
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.TotalHoursUsed != tt.wantUsed {
+				t.Errorf("hours used = %v, want %v", got.TotalHoursUsed, tt.wantUsed)
+			}
+			usedNOR := false
+			for _, call := range fake.Calls("FROM shipman.laytime_entries") {
+				usedNOR = usedNOR || strings.Contains(call.Query, "nor_events")
+			}
+			if usedNOR != tt.wantNOR {
+				t.Errorf("counted from NOR = %v, want %v", usedNOR, tt.wantNOR)
+			}
+		})
+	}
+}
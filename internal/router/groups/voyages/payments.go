@@ -3,19 +3,23 @@ package voyages
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"shipman/internal/coinsub"
 	"shipman/internal/db"
+	"shipman/internal/router/render"
 )
 
 type PaymentHandler struct {
 	paymentRepo *db.PaymentRepository
 	voyageRepo  *db.VoyageRepository
+	disputeRepo *db.DisputeRepository
 	userRepo    *db.UserRepository
 	coinsub     *coinsub.Client
 	appURL      string
@@ -25,6 +29,7 @@ func NewPaymentHandler(coinsubClient *coinsub.Client, appURL string) *PaymentHan
 	return &PaymentHandler{
 		paymentRepo: db.NewPaymentRepository(),
 		voyageRepo:  db.NewVoyageRepository(),
+		disputeRepo: db.NewDisputeRepository(),
 		userRepo:    db.NewUserRepository(),
 		coinsub:     coinsubClient,
 		appURL:      appURL,
@@ -40,6 +45,11 @@ func (h *PaymentHandler) AddRoutes(r *gin.RouterGroup) {
 	r.DELETE("/:id/payments/:paymentId", h.handleDelete)
 }
 
+// AddPaymentRoutes mounts routes addressing a payment by its own id.
+func (h *PaymentHandler) AddPaymentRoutes(r *gin.RouterGroup) {
+	r.GET("/:id", h.handleGet)
+}
+
 func (h *PaymentHandler) AddUserRoutes(r *gin.RouterGroup) {
 	// User routes for future use
 }
@@ -47,6 +57,8 @@ func (h *PaymentHandler) AddUserRoutes(r *gin.RouterGroup) {
 func (h *PaymentHandler) AddAdminRoutes(r *gin.RouterGroup) {
 	r.POST("/coinsub/register-webhook", h.handleRegisterWebhook)
 	r.GET("/coinsub/status", h.handleCoinsubStatus)
+	r.GET("/payments/overdue", h.handleListOverdue)
+	r.GET("/payments/missing-due-date", h.handleListMissingDueDate)
 }
 
 func (h *PaymentHandler) AddPublicRoutes(r *gin.RouterGroup) {
@@ -85,6 +97,7 @@ type CreatePaymentRequest struct {
 	Recurring   bool    `json:"recurring"`
 	Interval    string  `json:"interval"`
 	Frequency   string  `json:"frequency"`
+	DueDate     *string `json:"due_date"`
 }
 
 func (h *PaymentHandler) handleList(c *gin.Context) {
@@ -101,7 +114,7 @@ func (h *PaymentHandler) handleList(c *gin.Context) {
 	if payments == nil {
 		payments = []db.VoyagePayment{}
 	}
-	c.JSON(http.StatusOK, payments)
+	render.List(c, "payments-"+voyageID.String()+".csv", payments, payments)
 }
 
 func (h *PaymentHandler) handleCreate(c *gin.Context) {
@@ -128,17 +141,12 @@ func (h *PaymentHandler) handleCreate(c *gin.Context) {
 		return
 	}
 
-	currency := req.Currency
-	if currency == "" {
-		currency = "USD"
-	}
-
 	payment := &db.VoyagePayment{
 		VoyageID:    voyageID,
 		CreatedBy:   userID,
 		PaymentType: req.PaymentType,
 		Amount:      req.Amount,
-		Currency:    currency,
+		Currency:    req.Currency, // blank inherits the charter default
 		Status:      "draft",
 	}
 	name := req.Name
@@ -147,12 +155,24 @@ func (h *PaymentHandler) handleCreate(c *gin.Context) {
 	} else if req.Description != "" {
 		payment.Description = &req.Description
 	}
+	if req.DueDate != nil && *req.DueDate != "" {
+		due, err := time.Parse("2006-01-02", *req.DueDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due_date must be YYYY-MM-DD"})
+			return
+		}
+		payment.DueDate = &due
+	}
 	// Pre-fill recipient with the voyage counterparty so invoice cards can show who it's sent to
 	if v.CounterpartyEmail != nil && *v.CounterpartyEmail != "" {
 		payment.RecipientEmail = v.CounterpartyEmail
 	}
 
 	if err := h.paymentRepo.Create(c.Request.Context(), payment); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create payment"})
 		return
 	}
@@ -186,7 +206,7 @@ func (h *PaymentHandler) handleCreate(c *gin.Context) {
 			Name:           sessionName,
 			Details:        details,
 			Amount:         payment.Amount,
-			Currency:       currency,
+			Currency:       payment.Currency,
 			SuccessURL:     h.appURL + "/voyages/" + voyageID.String() + "?tab=payments&status=success",
 			CancelURL:      h.appURL + "/voyages/" + voyageID.String() + "?tab=payments&status=cancelled",
 			ExpiresInHours: 72,
@@ -322,6 +342,10 @@ func (h *PaymentHandler) handleCheckout(c *gin.Context) {
 	}
 
 	if err := h.paymentRepo.UpdateCoinsubSession(c.Request.Context(), paymentID, result.Data.PurchaseSessionID, result.Data.URL); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save session"})
 		return
 	}
@@ -361,7 +385,7 @@ func (h *PaymentHandler) handleTransfer(c *gin.Context) {
 	}
 
 	payment, err := h.paymentRepo.Retrieve(c.Request.Context(), paymentID)
-	if err != nil {
+	if err != nil || payment.VoyageID != voyageID {
 		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
 		return
 	}
@@ -428,6 +452,12 @@ func (h *PaymentHandler) handleMarkPaid(c *gin.Context) {
 		return
 	}
 
+	existing, err := h.paymentRepo.Retrieve(c.Request.Context(), paymentID)
+	if err != nil || existing.VoyageID != voyageID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		return
+	}
+
 	if err := h.paymentRepo.MarkPaid(c.Request.Context(), paymentID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark payment as paid"})
 		return
@@ -441,6 +471,51 @@ func (h *PaymentHandler) handleMarkPaid(c *gin.Context) {
 	c.JSON(http.StatusOK, payment)
 }
 
+// handleGet returns a payment the caller can see through its voyage. With
+// ?include=disputes the disputes raised against it are added.
+func (h *PaymentHandler) handleGet(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		return
+	}
+	include := c.Query("include")
+	if include != "" && include != "disputes" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "include must be disputes"})
+		return
+	}
+
+	p, err := h.paymentRepo.Retrieve(c.Request.Context(), paymentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		return
+	}
+	v, err := h.voyageRepo.Retrieve(c.Request.Context(), p.VoyageID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		return
+	}
+	if !h.canAccessVoyage(c.Request.Context(), v, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	if include != "disputes" {
+		c.JSON(http.StatusOK, p)
+		return
+	}
+	disputes, err := h.disputeRepo.ListByPayment(c.Request.Context(), p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list disputes"})
+		return
+	}
+	if disputes == nil {
+		disputes = []db.Dispute{}
+	}
+	c.JSON(http.StatusOK, gin.H{"payment": p, "disputes": disputes})
+}
+
 func (h *PaymentHandler) handleDelete(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 	voyageID, err := uuid.Parse(c.Param("id"))
@@ -574,6 +649,47 @@ func (h *PaymentHandler) handleCoinsubStatus(c *gin.Context) {
 	})
 }
 
+func (h *PaymentHandler) handleListOverdue(c *gin.Context) {
+	payments, err := h.paymentRepo.ListOverdue(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list overdue payments"})
+		return
+	}
+	if payments == nil {
+		payments = []db.VoyagePayment{}
+	}
+	render.Data(c, payments)
+}
+
+// handleListMissingDueDate lists outstanding payments with no due date,
+// optionally limited to one charter with ?charter_id=.
+func (h *PaymentHandler) handleListMissingDueDate(c *gin.Context) {
+	var charterID *uuid.UUID
+	if raw := c.Query("charter_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid charter ID"})
+			return
+		}
+		charterID = &id
+	}
+	page := parsePage(c)
+
+	payments, err := h.paymentRepo.ListMissingDueDate(c.Request.Context(), charterID, page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list payments"})
+		return
+	}
+	if payments == nil {
+		payments = []db.VoyagePayment{}
+	}
+	render.Paged(c, payments, page.Limit, page.Offset)
+}
+
 func splitName(full string) [2]string {
 	parts := [2]string{full, ""}
 	for i, ch := range full {
@@ -0,0 +1,197 @@
+package voyages
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/coinsub"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestCreatePaymentInheritsCharterCurrency(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	const insert = "INSERT INTO shipman.voyage_payments"
+
+	tests := []struct {
+		name      string
+		body      string
+		wantArg   string
+		returning string
+	}{
+		{"blank currency", `{"payment_type":"hire","amount":1000}`, "", "EUR"},
+		{"explicit currency", `{"payment_type":"hire","amount":1000,"currency":"gbp"}`, "GBP", "GBP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+			fake.Return(insert, dbtest.Rows([]string{"id", "currency", "created_at", "updated_at"},
+				[]any{uuid.New(), tt.returning, time.Now(), time.Now()}))
+
+			r := newGroupRouter(NewPaymentHandler(coinsub.NewClient("", "", ""), "").AddRoutes)
+			w := do(t, r, owner, http.MethodPost, "/"+voyageID.String()+"/payments", tt.body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if calls := fake.Calls(insert); len(calls) != 1 || calls[0].Arg(6) != tt.wantArg {
+				t.Fatalf("insert calls = %+v, want currency %q passed through", calls, tt.wantArg)
+			}
+			var got db.VoyagePayment
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Currency != tt.returning {
+				t.Errorf("currency = %q, want %q", got.Currency, tt.returning)
+			}
+		})
+	}
+}
+
+func TestGetPaymentWithDisputes(t *testing.T) {
+	const (
+		retrieve = "FROM shipman.voyage_payments WHERE id = $1"
+		disputes = "FROM shipman.disputes WHERE voyage_payment_id = $1"
+	)
+	owner := newTestUser("shipowner")
+	voyageID, paymentID, disputeID := uuid.New(), uuid.New(), uuid.New()
+	paymentColumns := []string{
+		"id", "voyage_id", "created_by", "payment_type", "description", "amount", "currency",
+		"recipient_email", "recipient_wallet",
+		"coinsub_session_id", "coinsub_payment_id", "coinsub_agreement_id",
+		"coinsub_checkout_url", "coinsub_tx_hash",
+		"status", "paid_at", "due_date", "created_at", "updated_at",
+	}
+
+	tests := []struct {
+		name         string
+		user         testUser
+		paymentID    uuid.UUID
+		query        string
+		wantStatus   int
+		wantDisputes bool
+	}{
+		{"with disputes", owner, paymentID, "?include=disputes", http.StatusOK, true},
+		{"payment only", owner, paymentID, "", http.StatusOK, false},
+		{"unknown include", owner, paymentID, "?include=voyage", http.StatusBadRequest, false},
+		{"unknown payment", owner, uuid.New(), "?include=disputes", http.StatusNotFound, false},
+		{"stranger", newTestUser("charterer"), paymentID, "?include=disputes", http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID.String()})
+			fake.On(retrieve, func(call dbtest.Call) dbtest.Result {
+				if call.Arg(1) != paymentID.String() {
+					return dbtest.Rows(paymentColumns)
+				}
+				return dbtest.Rows(paymentColumns, dbtest.Row(paymentColumns, map[string]any{
+					"id": paymentID, "voyage_id": voyageID, "created_by": owner.ID, "payment_type": "hire",
+					"amount": 1000.0, "currency": "USD", "status": "pending", "created_at": time.Now(), "updated_at": time.Now(),
+				}))
+			})
+			fake.On(disputes, func(call dbtest.Call) dbtest.Result {
+				cols := []string{"id", "charter_detail_id", "subject", "status", "claimed_amount", "currency", "created_at", "updated_at"}
+				if call.Arg(1) != paymentID.String() {
+					return dbtest.Rows(cols)
+				}
+				return dbtest.Rows(cols, []any{disputeID, uuid.New(), "Short hire", "open", 250.0, "USD", time.Now(), time.Now()})
+			})
+
+			r := newGroupRouter(NewPaymentHandler(coinsub.NewClient("", "", ""), "").AddPaymentRoutes)
+			w := do(t, r, tt.user, http.MethodGet, "/"+tt.paymentID.String()+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if listed := len(fake.Calls(disputes)) == 1; listed != tt.wantDisputes {
+				t.Errorf("disputes listed = %v, want %v", listed, tt.wantDisputes)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if !tt.wantDisputes {
+				var got db.VoyagePayment
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.ID != paymentID {
+					t.Errorf("payment = %s, %v; want %s", got.ID, err, paymentID)
+				}
+				return
+			}
+			var got struct {
+				Payment  db.VoyagePayment `json:"payment"`
+				Disputes []db.Dispute     `json:"disputes"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Payment.ID != paymentID || len(got.Disputes) != 1 || got.Disputes[0].ID != disputeID {
+				t.Errorf("response = %+v, want the payment with dispute %s", got, disputeID)
+			}
+		})
+	}
+}
+
+func TestListMissingDueDateEndpoint(t *testing.T) {
+	const report = "WHERE p.due_date IS NULL"
+	charterID := uuid.New()
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantCharter any
+		wantPage    [2]int64
+	}{
+		{"global", "", http.StatusOK, nil, [2]int64{20, 0}},
+		{"one charter", "?charter_id=" + charterID.String() + "&limit=5&offset=10", http.StatusOK, charterID.String(), [2]int64{5, 10}},
+		{"bad charter", "?charter_id=nope", http.StatusBadRequest, nil, [2]int64{}},
+		{"offset past the cap", "?offset=" + strconv.Itoa(db.MaxListOffset+1), http.StatusBadRequest, nil, [2]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(report, dbtest.Rows([]string{"id", "voyage_id", "created_by", "payment_type", "description", "amount", "currency",
+				"recipient_email", "recipient_wallet", "coinsub_session_id", "coinsub_payment_id", "coinsub_agreement_id",
+				"coinsub_checkout_url", "coinsub_tx_hash", "status", "paid_at", "due_date", "created_at", "updated_at"},
+				[]any{uuid.New(), uuid.New(), uuid.New(), "hire", nil, 1000.0, "USD", nil, nil, nil, nil, nil, nil, nil,
+					"pending", nil, nil, time.Now(), time.Now()}))
+
+			r := newGroupRouter(NewPaymentHandler(coinsub.NewClient("", "", ""), "").AddAdminRoutes)
+			w := do(t, r, newTestUser("admin"), http.MethodGet, "/payments/missing-due-date"+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(report)
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("ran the report for a rejected request")
+				}
+				return
+			}
+			if len(calls) != 1 {
+				t.Fatalf("report calls = %d, want 1", len(calls))
+			}
+			args := calls[0].Args
+			if filtered := strings.Contains(calls[0].Query, "v.charter_detail_id = $2"); filtered != (tt.wantCharter != nil) {
+				t.Errorf("charter filter = %v, want %v", filtered, tt.wantCharter != nil)
+			}
+			if tt.wantCharter != nil && args[1] != tt.wantCharter {
+				t.Errorf("charter arg = %v, want %v", args[1], tt.wantCharter)
+			}
+			if got := [2]int64{args[len(args)-2].(int64), args[len(args)-1].(int64)}; got != tt.wantPage {
+				t.Errorf("limit/offset = %v, want %v", got, tt.wantPage)
+			}
+			var body struct {
+				Data []db.VoyagePayment `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Data) != 1 {
+				t.Errorf("body = %s, want one payment", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package voyages
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"shipman/internal/db"
+	"shipman/internal/router/render"
+)
+
+// handleLongestPortCall reports the voyage's port call that used the most
+// laytime.
+func (h *Handler) handleLongestPortCall(c *gin.Context) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return
+	}
+
+	port, hours, err := h.portRepo.LongestPortCall(c.Request.Context(), v.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage has no ports"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute longest port call"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"port": port, "laytime_hours": hours})
+}
+
+// handleLegs lists the voyage's legs between consecutive port calls.
+func (h *Handler) handleLegs(c *gin.Context) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return
+	}
+
+	legs, err := h.portRepo.VoyageLegs(c.Request.Context(), v.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build voyage legs"})
+		return
+	}
+
+	render.Data(c, legs)
+}
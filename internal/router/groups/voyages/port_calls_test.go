@@ -0,0 +1,147 @@
+package voyages
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestLongestPortCallEndpoint(t *testing.T) {
+	const longest = "SELECT vp.id, COALESCE(t.hours, vp.laytime_hours, 0) AS hours"
+	owner := newTestUser("shipowner")
+	voyageID, portID := uuid.New(), uuid.New()
+	portColumns := []string{
+		"id", "voyage_id", "port_name", "port_country", "port_unlocode", "latitude", "longitude",
+		"arrived_at", "departed_at", "laytime_hours", "cargo_operations", "notes", "created_at", "updated_at",
+	}
+
+	tests := []struct {
+		name       string
+		user       testUser
+		result     dbtest.Result
+		wantStatus int
+	}{
+		{"longest call", owner, dbtest.Rows([]string{"id", "hours"}, []any{portID, 55.5}), http.StatusOK},
+		{"no ports", owner, dbtest.Rows([]string{"id", "hours"}), http.StatusNotFound},
+		{"db error", owner, dbtest.Fail(errors.New("connection reset")), http.StatusInternalServerError},
+		{"stranger", newTestUser("charterer"), dbtest.Rows([]string{"id", "hours"}, []any{portID, 55.5}), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+			fake.Return(longest, tt.result)
+			fake.Return("FROM shipman.voyage_ports WHERE id = $1", dbtest.Rows(portColumns, dbtest.Row(portColumns, map[string]any{
+				"id": portID, "voyage_id": voyageID, "port_name": "Santos", "created_at": time.Now(), "updated_at": time.Now(),
+			})))
+
+			w := do(t, newTestRouter(), tt.user, http.MethodGet, "/"+voyageID.String()+"/longest-port-call", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusForbidden && len(fake.Calls(longest)) != 0 {
+				t.Error("computed laytime for a non-participant")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got struct {
+				Port struct {
+					ID       uuid.UUID `json:"id"`
+					PortName string    `json:"port_name"`
+				} `json:"port"`
+				LaytimeHours float64 `json:"laytime_hours"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Port.ID != portID || got.Port.PortName != "Santos" || got.LaytimeHours != 55.5 {
+				t.Errorf("response = %+v, want Santos with 55.5 hours", got)
+			}
+			if calls := fake.Calls(longest); len(calls) != 1 || calls[0].Arg(1) != voyageID.String() {
+				t.Errorf("calls = %+v, want one for the voyage", calls)
+			}
+		})
+	}
+}
+
+func TestLegsEndpoint(t *testing.T) {
+	const ports = "FROM shipman.voyage_ports WHERE voyage_id = $1 ORDER BY"
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	portColumns := []string{
+		"id", "voyage_id", "port_name", "port_country", "port_unlocode", "latitude", "longitude",
+		"arrived_at", "departed_at", "laytime_hours", "cargo_operations", "notes", "created_at", "updated_at",
+	}
+	at := func(day int) time.Time { return time.Date(2026, 5, day, 0, 0, 0, 0, time.UTC) }
+	port := func(name string, lat, lon any, arrived, departed any) []any {
+		return dbtest.Row(portColumns, map[string]any{
+			"id": uuid.New(), "voyage_id": voyageID, "port_name": name, "latitude": lat, "longitude": lon,
+			"arrived_at": arrived, "departed_at": departed, "created_at": at(1), "updated_at": at(1),
+		})
+	}
+	itinerary := dbtest.Rows(portColumns,
+		port("Santos", 0.0, 0.0, at(1), at(2)),
+		port("Dakar", 0.0, 1.0, at(4), at(5)),
+		port("Lagos", nil, nil, at(7), nil),
+	)
+
+	tests := []struct {
+		name       string
+		user       testUser
+		result     dbtest.Result
+		wantStatus int
+		wantLegs   int
+	}{
+		{"three-port itinerary", owner, itinerary, http.StatusOK, 2},
+		{"single port", owner, dbtest.Rows(portColumns, port("Santos", 0.0, 0.0, at(1), nil)), http.StatusOK, 0},
+		{"db error", owner, dbtest.Fail(errors.New("connection reset")), http.StatusInternalServerError, 0},
+		{"stranger", newTestUser("charterer"), itinerary, http.StatusForbidden, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+			fake.Return(ports, tt.result)
+
+			w := do(t, newTestRouter(), tt.user, http.MethodGet, "/"+voyageID.String()+"/legs", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusForbidden && len(fake.Calls(ports)) != 0 {
+				t.Error("listed ports for a non-participant")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got struct {
+				Data []map[string]any `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Data == nil || len(got.Data) != tt.wantLegs {
+				t.Fatalf("legs = %v, want %d", got.Data, tt.wantLegs)
+			}
+			if tt.wantLegs == 0 {
+				return
+			}
+			first, last := got.Data[0], got.Data[1]
+			if first["from_port_name"] != "Santos" || first["to_port_name"] != "Dakar" || first["duration_hours"] != 48.0 {
+				t.Errorf("first leg = %v, want Santos → Dakar over 48 hours", first)
+			}
+			if d, ok := first["distance_nm"].(float64); !ok || d < 60 || d > 60.1 {
+				t.Errorf("first leg distance = %v, want about 60 NM", first["distance_nm"])
+			}
+			if _, ok := last["distance_nm"]; ok {
+				t.Errorf("last leg = %v, want no distance without Lagos coordinates", last)
+			}
+		})
+	}
+}
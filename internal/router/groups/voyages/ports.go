@@ -0,0 +1,52 @@
+package voyages
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"shipman/internal/db"
+	"shipman/internal/router/render"
+)
+
+// PortHandler serves port lookups shared across voyages.
+type PortHandler struct {
+	portRepo *db.VoyagePortRepository
+}
+
+func NewPortHandler() *PortHandler {
+	return &PortHandler{
+		portRepo: db.NewVoyagePortRepository(),
+	}
+}
+
+func (h *PortHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/suggest", h.handleSuggest)
+}
+
+// handleSuggest returns previously used port names matching ?q= as a prefix.
+func (h *PortHandler) handleSuggest(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		render.Data(c, []string{})
+		return
+	}
+
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 50 {
+			limit = parsed
+		}
+	}
+
+	names, err := h.portRepo.DistinctPortNames(c.Request.Context(), q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to suggest ports"})
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	render.Data(c, names)
+}
@@ -0,0 +1,62 @@
+package voyages
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"shipman/internal/db/dbtest"
+)
+
+func TestPortSuggest(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantQuery  bool
+		wantPrefix string
+		wantLimit  int64
+	}{
+		{"prefix", "?q=ro", true, "ro%", 10},
+		{"trimmed with limit", "?q=%20Ro%20&limit=3", true, "Ro%", 3},
+		{"limit out of range", "?q=ro&limit=500", true, "ro%", 10},
+		{"like metacharacters", "?q=50%25_off", true, `50\%\_off%`, 10},
+		{"blank", "?q=%20", false, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("SELECT DISTINCT ON (lower(port_name)) port_name", dbtest.Rows([]string{"port_name"},
+				[]any{"Rotterdam"}, []any{"Rouen"}))
+
+			r := newGroupRouter(NewPortHandler().AddRoutes)
+			w := do(t, r, newTestUser("broker"), http.MethodGet, "/suggest"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var body struct {
+				Data []string `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+
+			calls := fake.Calls("SELECT DISTINCT ON (lower(port_name))")
+			if !tt.wantQuery {
+				if len(calls) != 0 || body.Data == nil || len(body.Data) != 0 {
+					t.Errorf("calls = %d, data = %v; want no query and []", len(calls), body.Data)
+				}
+				return
+			}
+			if len(calls) != 1 {
+				t.Fatalf("ran %d suggest queries, want 1", len(calls))
+			}
+			if calls[0].Arg(1) != tt.wantPrefix || calls[0].Arg(2) != tt.wantLimit {
+				t.Errorf("args = %v, want %q, %d", calls[0].Args, tt.wantPrefix, tt.wantLimit)
+			}
+			if !slices.Equal(body.Data, []string{"Rotterdam", "Rouen"}) {
+				t.Errorf("data = %v, want Rotterdam, Rouen", body.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package voyages
+
+import (
+	"database/sql"
+	"net/http"
+
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PositionHandler serves single-position endpoints mounted at /positions.
+type PositionHandler struct {
+	positionRepo *db.ShipPositionRepository
+	voyageRepo   *db.VoyageRepository
+}
+
+func NewPositionHandler() *PositionHandler {
+	return &PositionHandler{
+		positionRepo: db.NewShipPositionRepository(),
+		voyageRepo:   db.NewVoyageRepository(),
+	}
+}
+
+func (h *PositionHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/:id/raw", h.handleRaw)
+}
+
+// handleRaw returns the AIS payload stored with a position, byte for byte.
+func (h *PositionHandler) handleRaw(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	positionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position ID"})
+		return
+	}
+
+	pos, err := h.positionRepo.Retrieve(c.Request.Context(), positionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "position not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve position"})
+		return
+	}
+
+	v, err := h.voyageRepo.Retrieve(c.Request.Context(), pos.VoyageID)
+	if err != nil || !isVoyageParticipant(v, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	if len(pos.RawPayload) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "position has no raw payload"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", pos.RawPayload)
+}
@@ -0,0 +1,632 @@
+package voyages
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+var shipPositionColumns = []string{
+	"id", "voyage_id", "recorded_at", "latitude", "longitude", "speed_knots",
+	"heading", "distance_logged_nm", "fuel_remaining_mt", "source", "remarks",
+	"raw_payload", "created_at", "updated_at",
+}
+
+func TestPositionRaw(t *testing.T) {
+	owner := newTestUser("shipowner")
+	raw := `{"MessageID":1,"UserID":366123456,"Sog":12.3}`
+	tests := []struct {
+		name       string
+		caller     testUser
+		raw        any
+		missing    bool
+		wantStatus int
+	}{
+		{"stored payload", owner, []byte(raw), false, http.StatusOK},
+		{"manual position", owner, nil, false, http.StatusNotFound},
+		{"stranger", newTestUser("shipowner"), []byte(raw), false, http.StatusForbidden},
+		{"unknown position", owner, nil, true, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			voyageID, positionID := uuid.New(), uuid.New()
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+			rows := dbtest.Rows(shipPositionColumns)
+			if !tt.missing {
+				rows = dbtest.Rows(shipPositionColumns, dbtest.Row(shipPositionColumns, map[string]any{
+					"id": positionID, "voyage_id": voyageID, "recorded_at": time.Now(),
+					"latitude": 51.9, "longitude": 4.1, "source": "ais", "raw_payload": tt.raw,
+					"created_at": time.Now(), "updated_at": time.Now(),
+				}))
+			}
+			fake.Return("FROM shipman.ship_positions WHERE id = $1", rows)
+
+			r := newGroupRouter(NewPositionHandler().AddRoutes)
+			w := do(t, r, tt.caller, http.MethodGet, "/"+positionID.String()+"/raw", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("content type = %q, want application/json", ct)
+			}
+			if w.Body.String() != raw {
+				t.Errorf("body = %s, want the stored payload %s", w.Body.String(), raw)
+			}
+		})
+	}
+}
+
+func TestVoyageProgressEndpoint(t *testing.T) {
+	dep := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	freezeClock(t, dep.Add(25*time.Hour))
+	voyageID := uuid.New()
+	const progressQuery = "FROM shipman.voyages v WHERE v.id = $1"
+	columns := []string{"distance_nm", "planned_departure_at", "planned_arrival_at", "actual_departure_at", "actual_arrival_at", "distance_logged_nm"}
+
+	tests := []struct {
+		name       string
+		id         string
+		rows       [][]any
+		wantStatus int
+		wantBody   db.Progress
+	}{
+		{
+			name:       "behind schedule",
+			id:         voyageID.String(),
+			rows:       [][]any{{800.0, dep, dep.Add(100 * time.Hour), nil, nil, 80.0}},
+			wantStatus: http.StatusOK,
+			wantBody:   db.Progress{VoyageID: voyageID, Schedule: db.ScheduleBehind},
+		},
+		{
+			name:       "insufficient data",
+			id:         voyageID.String(),
+			rows:       [][]any{{nil, dep, nil, nil, nil, nil}},
+			wantStatus: http.StatusOK,
+			wantBody:   db.Progress{VoyageID: voyageID, Schedule: db.ScheduleUnknown},
+		},
+		{name: "missing voyage", id: voyageID.String(), wantStatus: http.StatusNotFound},
+		{name: "invalid id", id: "not-a-uuid", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(progressQuery, dbtest.Rows(columns, tt.rows...))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+tt.id+"/progress", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got db.Progress
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.VoyageID != tt.wantBody.VoyageID || got.Schedule != tt.wantBody.Schedule {
+				t.Errorf("progress = %+v, want %+v", got, tt.wantBody)
+			}
+			if tt.wantBody.Schedule == db.ScheduleUnknown && (got.PercentComplete != nil || got.PercentElapsed != nil) {
+				t.Errorf("progress = %s, want no percentages without the data", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestVoyageSpeedReportEndpoint(t *testing.T) {
+	voyageID := uuid.New()
+	columns := []string{"distance_nm", "position_count", "speed_samples", "avg_speed", "distance_logged_nm"}
+
+	tests := []struct {
+		name       string
+		id         string
+		rows       [][]any
+		wantStatus int
+		wantKeys   []string
+		absentKeys []string
+	}{
+		{
+			name:       "track with speeds",
+			id:         voyageID.String(),
+			rows:       [][]any{{1000.0, 24, 24, 12.0, 1050.0}},
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"average_speed_knots", "distance_logged_nm", "distance_nm", "distance_variance_pct"},
+		},
+		{
+			name:       "missing speed data",
+			id:         voyageID.String(),
+			rows:       [][]any{{nil, 3, 0, nil, 120.0}},
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"distance_logged_nm"},
+			absentKeys: []string{"average_speed_knots", "distance_nm", "distance_variance_pct"},
+		},
+		{name: "missing voyage", id: voyageID.String(), wantStatus: http.StatusNotFound},
+		{name: "invalid id", id: "not-a-uuid", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.voyages v WHERE v.id = $1", dbtest.Rows(columns, tt.rows...))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+tt.id+"/speed-report", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got["voyage_id"] != voyageID.String() {
+				t.Errorf("voyage_id = %v, want %s", got["voyage_id"], voyageID)
+			}
+			for _, k := range tt.wantKeys {
+				if _, ok := got[k]; !ok {
+					t.Errorf("report = %s, missing %s", w.Body.String(), k)
+				}
+			}
+			for _, k := range tt.absentKeys {
+				if _, ok := got[k]; ok {
+					t.Errorf("report = %s, want no %s without the data", w.Body.String(), k)
+				}
+			}
+		})
+	}
+}
+
+var streamPositionColumns = []string{
+	"id", "voyage_id", "recorded_at", "latitude", "longitude", "speed_knots",
+	"heading", "distance_logged_nm", "fuel_remaining_mt", "source", "remarks",
+	"created_at", "updated_at",
+}
+
+// positionStreamRows builds n minute-spaced positions for voyageID.
+func positionStreamRows(voyageID uuid.UUID, n int) ([]uuid.UUID, dbtest.Result) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	ids := make([]uuid.UUID, n)
+	rows := make([][]any, n)
+	for i := range rows {
+		ids[i] = uuid.New()
+		at := start.Add(time.Duration(i) * time.Minute)
+		rows[i] = []any{ids[i], voyageID, at, 1.29, 103.85, 12.5, nil, nil, nil, "ais", nil, at, at}
+	}
+	return ids, dbtest.Rows(streamPositionColumns, rows...)
+}
+
+// stubPositionStream answers StreamByVoyage with n positions for voyageID.
+func stubPositionStream(fake *dbtest.Fake, voyageID uuid.UUID, n int) []uuid.UUID {
+	ids, rows := positionStreamRows(voyageID, n)
+	fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1 ORDER BY recorded_at ASC", rows)
+	return ids
+}
+
+func TestStreamPositionsNDJSON(t *testing.T) {
+	for _, n := range []int{0, 1, 250} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			fake := newFakeDB(t)
+			voyageID := uuid.New()
+			ids := stubPositionStream(fake, voyageID, n)
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/positions.ndjson", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+			}
+			if !w.Flushed {
+				t.Error("stream was never flushed")
+			}
+			body := w.Body.String()
+			if n > 0 && !strings.HasSuffix(body, "\n") {
+				t.Error("body does not end with a newline")
+			}
+			lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+			if n == 0 {
+				lines = nil
+				if body != "" {
+					t.Errorf("body = %q, want empty", body)
+				}
+			}
+			if len(lines) != n {
+				t.Fatalf("lines = %d, want %d", len(lines), n)
+			}
+			for i, line := range lines {
+				var pos db.ShipPosition
+				if err := json.Unmarshal([]byte(line), &pos); err != nil {
+					t.Fatalf("line %d is not JSON: %v", i, err)
+				}
+				if pos.ID != ids[i] || pos.VoyageID != voyageID {
+					t.Errorf("line %d = %+v, want position %s", i, pos, ids[i])
+				}
+			}
+		})
+	}
+}
+
+// brokenWriter fails every write after the first, as a connection the client
+// has dropped does.
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *brokenWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, errors.New("broken pipe")
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStreamPositionsStopsOnDisconnect(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID := uuid.New()
+	stubPositionStream(fake, voyageID, 50)
+	u := newTestUser("shipowner")
+	token, err := testJWT.Generate(u.ID, u.OrgID, "user@example.com", u.Role, "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/"+voyageID.String()+"/positions.ndjson", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := &brokenWriter{ResponseRecorder: httptest.NewRecorder()}
+	newTestRouter().ServeHTTP(w, req)
+
+	if w.writes != 2 {
+		t.Errorf("writes = %d, want the stream to stop at the first failed write", w.writes)
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 1 {
+		t.Errorf("delivered %d lines, want 1", lines)
+	}
+}
+
+func TestStreamPositionsInvalidID(t *testing.T) {
+	fake := newFakeDB(t)
+	w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/not-a-uuid/positions.ndjson", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("queried positions for an invalid ID")
+	}
+}
+
+func TestListPositionsDownsample(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID := uuid.New()
+	ids := stubPositionStream(fake, voyageID, 100)
+
+	w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/positions?max_points=10", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var got []db.ShipPosition
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || len(got) > 10 {
+		t.Fatalf("returned %d positions, want 1 to 10", len(got))
+	}
+	if got[0].ID != ids[0] || got[len(got)-1].ID != ids[len(ids)-1] {
+		t.Error("downsampled track does not keep the first and last positions")
+	}
+	if len(fake.Calls("LIMIT")) != 0 {
+		t.Error("downsampling read a page instead of the whole track")
+	}
+}
+
+func TestListPositionsMaxPointsInvalid(t *testing.T) {
+	for _, mp := range []string{"1", "5001", "abc", "-3"} {
+		t.Run(mp, func(t *testing.T) {
+			fake := newFakeDB(t)
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+uuid.NewString()+"/positions?max_points="+mp, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "between 2 and 5000") {
+				t.Errorf("body = %s", w.Body.String())
+			}
+			if len(fake.Calls("")) != 0 {
+				t.Error("queried positions for an invalid max_points")
+			}
+		})
+	}
+}
+
+func TestListPositionsDefaultsToLatest(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID := uuid.New()
+	fake.Return("ORDER BY recorded_at DESC LIMIT $2", dbtest.Rows(shipPositionColumns))
+
+	w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/positions", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Errorf("body = %s, want []", body)
+	}
+	calls := fake.Calls("ORDER BY recorded_at DESC LIMIT $2")
+	if len(calls) != 1 || calls[0].Arg(1) != voyageID.String() || calls[0].Arg(2) != int64(100) {
+		t.Errorf("calls = %+v, want the latest 100 positions", calls)
+	}
+}
+
+func TestPagePositionsEndpoint(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID := uuid.New()
+	// Every page query gets all five rows, so the handler always sees one
+	// more than a limit of four or less and must hand back a cursor.
+	ids, rows := positionStreamRows(voyageID, 5)
+	fake.Return("FROM shipman.ship_positions WHERE voyage_id = $1", rows)
+	r := newTestRouter()
+	owner := newTestUser("shipowner")
+
+	type page struct {
+		Data       []db.ShipPosition `json:"data"`
+		NextCursor *string           `json:"next_cursor"`
+	}
+	w := do(t, r, owner, http.MethodGet, "/"+voyageID.String()+"/positions/page?limit=4", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var first page
+	if err := json.Unmarshal(w.Body.Bytes(), &first); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Data) != 4 || first.Data[3].ID != ids[3] || first.NextCursor == nil {
+		t.Fatalf("first page = %s", w.Body.String())
+	}
+	cursor, err := db.DecodePositionCursor(*first.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.ID != ids[3] || cursor.Backward {
+		t.Errorf("cursor = %+v, want forward from %s", cursor, ids[3])
+	}
+	calls := fake.Calls("FROM shipman.ship_positions")
+	if q := calls[0].Query; !strings.HasSuffix(q, "ORDER BY recorded_at ASC, id ASC LIMIT 5") || len(calls[0].Args) != 1 {
+		t.Errorf("first page query = %s", q)
+	}
+
+	w = do(t, r, owner, http.MethodGet, "/"+voyageID.String()+"/positions/page?limit=2&cursor="+*first.NextCursor, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	calls = fake.Calls("FROM shipman.ship_positions")
+	last := calls[len(calls)-1]
+	if !strings.Contains(last.Query, "AND (recorded_at, id) > ($2, $3)") || last.Arg(3) != ids[3].String() {
+		t.Errorf("next page query = %s %v, want keyset after the cursor", last.Query, last.Args)
+	}
+
+	w = do(t, r, owner, http.MethodGet, "/"+voyageID.String()+"/positions/page?direction=backward&limit=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	calls = fake.Calls("FROM shipman.ship_positions")
+	if q := calls[len(calls)-1].Query; !strings.HasSuffix(q, "ORDER BY recorded_at DESC, id DESC LIMIT 3") {
+		t.Errorf("backward query = %s", q)
+	}
+	var back page
+	if err := json.Unmarshal(w.Body.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.NextCursor == nil {
+		t.Fatal("backward page has no next cursor")
+	}
+	if c, _ := db.DecodePositionCursor(*back.NextCursor); !c.Backward {
+		t.Error("backward page cursor lost its direction")
+	}
+}
+
+func TestPagePositionsEndpointLastPage(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID := uuid.New()
+	stubPositionStream(fake, voyageID, 3)
+
+	w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/positions/page", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"next_cursor":null`) {
+		t.Errorf("body = %s, want a null next_cursor", w.Body.String())
+	}
+}
+
+func TestPagePositionsEndpointRejects(t *testing.T) {
+	for name, query := range map[string]string{
+		"malformed cursor":  "?cursor=not-a-cursor",
+		"unknown direction": "?direction=sideways",
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+uuid.NewString()+"/positions/page"+query, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			if len(fake.Calls("")) != 0 {
+				t.Error("queried positions for a rejected request")
+			}
+		})
+	}
+}
+
+func TestListPositionsBySource(t *testing.T) {
+	const search = "FROM shipman.ship_positions WHERE TRUE"
+	tests := []struct {
+		name       string
+		query      string
+		wantSource any
+		wantLimit  int64
+		wantOffset int64
+	}{
+		{"source", "?source=ais", "ais", 20, 0},
+		{"source trimmed", "?source=%20ais%20", "ais", 20, 0},
+		{"source and page", "?source=ais&limit=5&offset=10", "ais", 5, 10},
+		{"page only", "?limit=5", nil, 5, 0},
+		{"limit over the cap", "?source=ais&limit=500", "ais", 20, 0},
+		{"negative offset ignored", "?source=ais&offset=-4", "ais", 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			voyageID := uuid.New()
+			fake.Return(search, dbtest.Rows(shipPositionColumns))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/positions"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+				t.Errorf("body = %s, want []", body)
+			}
+			if w.Header().Get("X-Page-Limit") != strconv.FormatInt(tt.wantLimit, 10) || w.Header().Get("X-Page-Offset") != strconv.FormatInt(tt.wantOffset, 10) {
+				t.Errorf("page headers = %q/%q, want %d/%d", w.Header().Get("X-Page-Limit"), w.Header().Get("X-Page-Offset"), tt.wantLimit, tt.wantOffset)
+			}
+			calls := fake.Calls(search)
+			if len(calls) != 1 {
+				t.Fatalf("search calls = %d, want 1", len(calls))
+			}
+			want := []any{voyageID.String()}
+			if tt.wantSource != nil {
+				want = append(want, tt.wantSource)
+			}
+			want = append(want, tt.wantLimit, tt.wantOffset)
+			if !slices.Equal(calls[0].Args, want) {
+				t.Errorf("args = %v, want %v", calls[0].Args, want)
+			}
+		})
+	}
+}
+
+func TestListPositionsBySourceRejectsDeepOffset(t *testing.T) {
+	fake := newFakeDB(t)
+	w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+uuid.NewString()+"/positions?source=ais&offset=50000000", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+	}
+	if len(fake.Calls("")) != 0 {
+		t.Error("queried positions past the offset cap")
+	}
+}
+
+func TestAddPositionsBatch(t *testing.T) {
+	const insert = "INSERT INTO shipman.ship_positions"
+	valid := `{"recorded_at":"2026-05-01T00:00:00Z","latitude":51.9,"longitude":4.1}`
+	later := `{"recorded_at":"2026-05-01T06:00:00Z","latitude":52.1,"longitude":3.9}`
+	offMap := `{"recorded_at":"2026-05-01T03:00:00Z","latitude":91,"longitude":4.1}`
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantBody    string
+		wantInserts int
+	}{
+		{"partial keeps the valid positions", `{"positions":[` + valid + `,` + offMap + `,` + later + `]}`,
+			http.StatusOK, `"created":2,"errors":[{"error":"validation failed: latitude must be between -90 and 90","index":1}]`, 2},
+		{"partial all valid", `{"positions":[` + valid + `,` + later + `]}`, http.StatusOK, `{"created":2,"errors":[]}`, 2},
+		{"atomic all valid", `{"atomic":true,"positions":[` + valid + `,` + later + `]}`, http.StatusCreated, `{"created":2}`, 2},
+		{"atomic rejects the batch", `{"atomic":true,"positions":[` + valid + `,` + offMap + `]}`, http.StatusBadRequest, `position 1: `, 1},
+		{"empty batch", `{"positions":[]}`, http.StatusBadRequest, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(insert, dbtest.Rows([]string{"id", "source", "created_at", "updated_at"}, []any{uuid.New(), "manual", time.Now(), time.Now()}))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodPost, "/"+uuid.NewString()+"/positions/batch", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if n := len(fake.Calls(insert)); n != tt.wantInserts {
+				t.Errorf("inserts = %d, want %d", n, tt.wantInserts)
+			}
+			if strings.Contains(tt.body, `"atomic":true`) && tt.wantStatus != http.StatusCreated && fake.Commits() != 0 {
+				t.Error("a rejected atomic batch was committed")
+			}
+		})
+	}
+}
+
+func TestAddPositionsBatchOrdering(t *testing.T) {
+	const insert = "INSERT INTO shipman.ship_positions"
+	late := `{"recorded_at":"2026-05-01T06:00:00Z","latitude":52.1,"longitude":3.9}`
+	early := `{"recorded_at":"2026-05-01T00:00:00Z","latitude":51.9,"longitude":4.1}`
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLats   []float64
+	}{
+		{"sorted by default", `{"atomic":true,"positions":[` + late + `,` + early + `]}`, http.StatusCreated, []float64{51.9, 52.1}},
+		{"strict rejects", `{"atomic":true,"strict":true,"positions":[` + late + `,` + early + `]}`, http.StatusBadRequest, nil},
+		{"strict in order", `{"atomic":true,"strict":true,"positions":[` + early + `,` + late + `]}`, http.StatusCreated, []float64{51.9, 52.1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(insert, dbtest.Rows([]string{"id", "source", "created_at", "updated_at"}, []any{uuid.New(), "manual", time.Now(), time.Now()}))
+
+			w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodPost, "/"+uuid.NewString()+"/positions/batch", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(w.Body.String(), db.ErrNonMonotonicBatch.Error()) {
+				t.Errorf("body = %s, want the ordering error", w.Body.String())
+			}
+			var lats []float64
+			for _, call := range fake.Calls(insert) {
+				lats = append(lats, call.Arg(3).(float64))
+			}
+			if !slices.Equal(lats, tt.wantLats) {
+				t.Errorf("insert order = %v, want %v", lats, tt.wantLats)
+			}
+		})
+	}
+}
+
+func TestListPositionsRoundsCoordinates(t *testing.T) {
+	fake := newFakeDB(t)
+	voyageID := uuid.New()
+	// The list leaves raw_payload out.
+	columns := slices.DeleteFunc(slices.Clone(shipPositionColumns), func(c string) bool { return c == "raw_payload" })
+	fake.Return("ORDER BY recorded_at DESC LIMIT $2", dbtest.Rows(columns, dbtest.Row(columns, map[string]any{
+		"id": uuid.New(), "voyage_id": voyageID, "recorded_at": time.Now(), "latitude": 1.2300000000001,
+		"longitude": -4.12345678, "source": "ais", "created_at": time.Now(), "updated_at": time.Now(),
+	})))
+
+	w := do(t, newTestRouter(), newTestUser("shipowner"), http.MethodGet, "/"+voyageID.String()+"/positions", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var got []map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got) != 1 {
+		t.Fatalf("body = %s, %v; want one position", w.Body.String(), err)
+	}
+	if lat, lon := string(got[0]["latitude"]), string(got[0]["longitude"]); lat != "1.23" || lon != "-4.123457" {
+		t.Errorf("coordinates = %s, %s; want 1.23, -4.123457", lat, lon)
+	}
+}
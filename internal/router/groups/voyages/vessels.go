@@ -0,0 +1,53 @@
+package voyages
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"shipman/internal/db"
+)
+
+// VesselHandler serves per-vessel views built from its voyages.
+type VesselHandler struct {
+	vesselRepo *db.VesselRepository
+}
+
+func NewVesselHandler() *VesselHandler {
+	return &VesselHandler{
+		vesselRepo: db.NewVesselRepository(),
+	}
+}
+
+func (h *VesselHandler) AddRoutes(r *gin.RouterGroup) {
+	r.GET("/:id/status", h.handleStatus)
+}
+
+// handleStatus returns the vessel's in-progress voyage and latest position,
+// with null for both when it is idle.
+func (h *VesselHandler) handleStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vessel ID"})
+		return
+	}
+
+	vessel, err := h.vesselRepo.Retrieve(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "vessel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve vessel"})
+		return
+	}
+
+	status, err := h.vesselRepo.VesselCurrentStatus(c.Request.Context(), vessel.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load vessel status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,130 @@
+package voyages
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+const (
+	vesselRetrieveQuery = "created_at, updated_at FROM shipman.vessels WHERE id = $1"
+	currentVoyageQuery  = "SELECT id FROM shipman.voyages WHERE lower(vessel_name) = lower($1)"
+	latestPositionQuery = "FROM shipman.ship_positions WHERE voyage_id = ("
+)
+
+var vesselColumns = []string{
+	"id", "name", "imo_number", "flag_state", "vessel_type", "call_sign",
+	"deadweight_tonnage", "gross_tonnage", "net_tonnage", "capacity", "build_year",
+	"class_society", "owner", "manager", "documentation_uri", "notes",
+	"created_at", "updated_at",
+}
+
+// latestPositionColumns are the ship_positions columns read for a vessel's
+// status, without raw_payload.
+var latestPositionColumns = []string{
+	"id", "voyage_id", "recorded_at", "latitude", "longitude", "speed_knots",
+	"heading", "distance_logged_nm", "fuel_remaining_mt", "source", "remarks",
+	"created_at", "updated_at",
+}
+
+func TestVesselStatusEndpoint(t *testing.T) {
+	user := newTestUser("charterer")
+	voyageID := uuid.New()
+	recorded := time.Date(2026, 6, 8, 12, 0, 0, 0, time.UTC)
+	atSea := func(f *dbtest.Fake) {
+		stubVoyages(f, map[string]any{"id": voyageID, "voyage_number": "V-7", "vessel_name": "Nordic Star", "status": "in_progress"})
+		f.Return(currentVoyageQuery, dbtest.Rows([]string{"id"}, []any{voyageID}))
+		f.Return(latestPositionQuery, dbtest.Rows(latestPositionColumns, dbtest.Row(latestPositionColumns, map[string]any{
+			"id": uuid.New(), "voyage_id": voyageID, "recorded_at": recorded, "latitude": 51.9, "longitude": 4.1,
+			"created_at": recorded, "updated_at": recorded,
+		})))
+	}
+	idle := func(f *dbtest.Fake) {
+		f.Return(currentVoyageQuery, dbtest.Rows([]string{"id"}))
+		f.Return(latestPositionQuery, dbtest.Rows(latestPositionColumns))
+	}
+
+	tests := []struct {
+		name       string
+		vessel     string // path id; "" for a known vessel
+		known      bool
+		setup      func(*dbtest.Fake)
+		wantStatus int
+		wantBody   string
+	}{
+		{"at sea", "", true, atSea, http.StatusOK, ""},
+		{"idle", "", true, idle, http.StatusOK, `{"vessel_name":"Nordic Star","voyage":null,"position":null}`},
+		{"unknown vessel", uuid.NewString(), false, idle, http.StatusNotFound, ""},
+		{"invalid id", "not-a-uuid", false, idle, http.StatusBadRequest, ""},
+		{"db error", "", true, func(f *dbtest.Fake) {
+			idle(f)
+			f.Return(latestPositionQuery, dbtest.Fail(errors.New("connection reset")))
+		}, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh id per case keeps the vessel cache from answering.
+			vesselID := uuid.New()
+			fake := newFakeDB(t)
+			fake.On(vesselRetrieveQuery, func(call dbtest.Call) dbtest.Result {
+				if call.Arg(1) != vesselID.String() {
+					return dbtest.Rows(vesselColumns)
+				}
+				return dbtest.Rows(vesselColumns, dbtest.Row(vesselColumns, map[string]any{
+					"id": vesselID, "name": "Nordic Star", "created_at": recorded, "updated_at": recorded,
+				}))
+			})
+			tt.setup(fake)
+			path := tt.vessel
+			if tt.known {
+				path = vesselID.String()
+			}
+
+			w := do(t, newGroupRouter(NewVesselHandler().AddRoutes), user, http.MethodGet, "/"+path+"/status", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if tt.wantStatus != http.StatusInternalServerError && len(fake.Calls(currentVoyageQuery)) != 0 {
+					t.Error("looked up voyages for a vessel that was not found")
+				}
+				return
+			}
+			if calls := fake.Calls(currentVoyageQuery); len(calls) == 0 || calls[0].Arg(1) != "Nordic Star" {
+				t.Errorf("voyage lookups = %v, want them by the vessel's name", calls)
+			}
+			if tt.wantBody != "" {
+				return
+			}
+			var got struct {
+				Voyage *struct {
+					ID           uuid.UUID `json:"id"`
+					VoyageNumber string    `json:"voyage_number"`
+				} `json:"voyage"`
+				Position *struct {
+					VoyageID   uuid.UUID `json:"voyage_id"`
+					RecordedAt time.Time `json:"recorded_at"`
+					Latitude   float64   `json:"latitude"`
+				} `json:"position"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Voyage == nil || got.Voyage.ID != voyageID || got.Voyage.VoyageNumber != "V-7" {
+				t.Errorf("voyage = %+v, want V-7", got.Voyage)
+			}
+			if got.Position == nil || got.Position.VoyageID != voyageID || !got.Position.RecordedAt.Equal(recorded) || got.Position.Latitude != 51.9 {
+				t.Errorf("position = %+v, want the latest fix of V-7", got.Position)
+			}
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +17,8 @@ import (
 	"shipman/internal/ai"
 	"shipman/internal/db"
 	"shipman/internal/email"
+	"shipman/internal/router/middleware"
+	"shipman/internal/router/render"
 )
 
 // isVoyageParticipant reports whether userID is owner, counterparty, or
@@ -39,6 +43,8 @@ type Handler struct {
 	voyageRepo   *db.VoyageRepository
 	positionRepo *db.ShipPositionRepository
 	laytimeRepo  *db.LaytimeEntryRepository
+	norRepo      *db.NOREventRepository
+	portRepo     *db.VoyagePortRepository
 	docRepo      *db.DocumentRepository
 	userRepo     *db.UserRepository
 	marineAPIKey string
@@ -59,6 +65,8 @@ func NewHandler(marineAPIKey, aiProvider, aiAPIKey, aiModel, aiBaseURL string, e
 		voyageRepo:   db.NewVoyageRepository(),
 		positionRepo: db.NewShipPositionRepository(),
 		laytimeRepo:  db.NewLaytimeEntryRepository(),
+		norRepo:      db.NewNOREventRepository(),
+		portRepo:     db.NewVoyagePortRepository(),
 		docRepo:      db.NewDocumentRepository(),
 		userRepo:     db.NewUserRepository(),
 		marineAPIKey: marineAPIKey,
@@ -72,20 +80,29 @@ func (h *Handler) AddRoutes(r *gin.RouterGroup) {
 	// Voyages (fixtures)
 	r.GET("", h.handleList)
 	r.POST("", h.handleCreate)
-	r.POST("/extract-terms-preview", h.handleExtractTermsPreview)
-	r.POST("/join", h.handleJoinVoyage)
+	r.POST("/extract-terms-preview", middleware.LongRunning(), h.handleExtractTermsPreview)
+	r.POST("/join", middleware.Transactional(), h.handleJoinVoyage)
 	r.GET("/:id", h.handleGet)
 	r.PATCH("/:id", h.handleUpdate)
 	r.DELETE("/:id", h.handleDelete)
+	r.POST("/:id/depart", h.handleDepart)
+	r.POST("/:id/arrive", h.handleArrive)
+	r.POST("/:id/archive", h.handleArchive)
+	r.POST("/:id/unarchive", h.handleUnarchive)
 
 	// Positions / tracking
 	r.GET("/:id/positions", h.handleListPositions)
+	r.GET("/:id/positions.ndjson", middleware.LongRunning(), h.handleStreamPositions)
+	r.GET("/:id/positions/page", h.handlePagePositions)
 	r.POST("/:id/positions", h.handleAddPosition)
+	r.POST("/:id/positions/batch", h.handleAddPositions)
 	r.GET("/:id/position/live", h.handleLivePosition)
+	r.GET("/:id/progress", h.handleProgress)
+	r.GET("/:id/speed-report", h.handleSpeedReport)
 
 	// Charter party document
 	r.POST("/:id/attach-document", h.handleAttachDocument)
-	r.POST("/:id/extract-terms", h.handleExtractTerms)
+	r.POST("/:id/extract-terms", middleware.LongRunning(), h.handleExtractTerms)
 
 	// Invites
 	r.POST("/:id/invite", h.handleCreateInvite)
@@ -93,9 +110,19 @@ func (h *Handler) AddRoutes(r *gin.RouterGroup) {
 	// Laytime
 	r.GET("/:id/laytime", h.handleListLaytime)
 	r.POST("/:id/laytime", h.handleAddLaytime)
+	r.POST("/:id/laytime/batch", h.handleAddLaytimeBatch)
+	r.POST("/:id/laytime/preview", h.handlePreviewLaytime)
 	r.PATCH("/:id/laytime/:entryId", h.handleUpdateLaytime)
 	r.DELETE("/:id/laytime/:entryId", h.handleDeleteLaytime)
 	r.GET("/:id/laytime/summary", h.handleLaytimeSummary)
+
+	// Notice of Readiness
+	r.GET("/:id/nor", h.handleListNOR)
+	r.GET("/:id/longest-port-call", h.handleLongestPortCall)
+	r.GET("/:id/legs", h.handleLegs)
+	r.POST("/:id/nor", h.handleCreateNOR)
+	r.PATCH("/:id/nor/:norId", h.handleUpdateNOR)
+	r.DELETE("/:id/nor/:norId", h.handleDeleteNOR)
 }
 
 // AddPublicRoutes registers unauthenticated routes (invite preview).
@@ -107,7 +134,7 @@ func (h *Handler) AddPublicRoutes(r *gin.RouterGroup) {
 
 func (h *Handler) handleList(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
-	voyages, err := h.voyageRepo.ListByUser(c.Request.Context(), userID)
+	voyages, err := h.voyageRepo.ListByUser(c.Request.Context(), userID, c.Query("include_archived") == "true")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list voyages"})
 		return
@@ -220,6 +247,10 @@ func (h *Handler) handleCreate(c *gin.Context) {
 	}
 
 	if err := h.voyageRepo.Create(c.Request.Context(), v); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("voyage create failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create voyage", "details": err.Error()})
 		return
@@ -371,6 +402,14 @@ func (h *Handler) handleUpdate(c *gin.Context) {
 	if req.ClearDocument { existing.DocumentID = nil }
 
 	if err := h.voyageRepo.Update(c.Request.Context(), &existing); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update voyage"})
 		return
 	}
@@ -389,22 +428,150 @@ func (h *Handler) handleDelete(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
 		return
 	}
-	if err := h.voyageRepo.Delete(c.Request.Context(), voyageID); err != nil {
+	if c.Query("dry_run") == "true" {
+		plan, err := h.voyageRepo.DeleteCascadePreview(c.Request.Context(), voyageID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to preview voyage delete"})
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+	plan, err := h.voyageRepo.DeleteCascade(c.Request.Context(), voyageID, false)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete voyage"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "deleted", "plan": plan})
+}
+
+func (h *Handler) handleArchive(c *gin.Context) {
+	h.handleSetArchived(c, h.voyageRepo.Archive)
+}
+
+func (h *Handler) handleUnarchive(c *gin.Context) {
+	h.handleSetArchived(c, h.voyageRepo.Unarchive)
+}
+
+// handleSetArchived applies Archive or Unarchive for the voyage owner and
+// returns the updated voyage.
+func (h *Handler) handleSetArchived(c *gin.Context, apply func(context.Context, uuid.UUID) error) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return
+	}
+	existing, err := h.voyageRepo.Retrieve(c.Request.Context(), voyageID)
+	if err != nil || existing.OwnerUserID == nil || *existing.OwnerUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	if err := apply(c.Request.Context(), voyageID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update voyage"})
+		return
+	}
+	updated, err := h.voyageRepo.Retrieve(c.Request.Context(), voyageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve voyage"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// ---------- Status transitions ----------
+
+type VoyageTransitionRequest struct {
+	At *time.Time `json:"at"`
+}
+
+func (h *Handler) handleDepart(c *gin.Context) {
+	h.handleTransition(c, h.voyageRepo.Depart)
+}
+
+func (h *Handler) handleArrive(c *gin.Context) {
+	h.handleTransition(c, h.voyageRepo.Arrive)
+}
+
+func (h *Handler) handleTransition(c *gin.Context, apply func(context.Context, uuid.UUID, time.Time) (db.VoyageState, error)) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return
+	}
+
+	existing, err := h.voyageRepo.Retrieve(c.Request.Context(), voyageID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+		return
+	}
+	if !isVoyageParticipant(existing, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	var req VoyageTransitionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	at := db.Now().UTC()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	state, err := apply(c.Request.Context(), voyageID, at)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "voyage cannot move to that status", "current": state})
+			return
+		}
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update voyage status"})
+		return
+	}
+	c.JSON(http.StatusOK, state)
 }
 
 // ---------- Position / Tracking ----------
 
+// maxDownsamplePoints caps max_points on the positions list.
+const maxDownsamplePoints = 5000
+
+// handleListPositions returns the latest 100 positions, newest first, or with
+// max_points a time-evenly downsampled track of the whole voyage, oldest
+// first. ?source=, ?limit= or ?offset= switch to a filtered page instead.
 func (h *Handler) handleListPositions(c *gin.Context) {
 	voyageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
 		return
 	}
-	positions, err := h.positionRepo.ListByVoyage(c.Request.Context(), voyageID, 100)
+	if c.Query("source") != "" || c.Query("limit") != "" || c.Query("offset") != "" {
+		h.searchPositions(c, voyageID)
+		return
+	}
+	var positions []db.ShipPosition
+	if mp := c.Query("max_points"); mp != "" {
+		maxPoints, convErr := strconv.Atoi(mp)
+		if convErr != nil || maxPoints < 2 || maxPoints > maxDownsamplePoints {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("max_points must be between 2 and %d", maxDownsamplePoints)})
+			return
+		}
+		positions, err = h.positionRepo.Downsample(c.Request.Context(), voyageID, maxPoints)
+	} else {
+		positions, err = h.positionRepo.ListByVoyage(c.Request.Context(), voyageID, 100)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list positions"})
 		return
@@ -415,29 +582,178 @@ func (h *Handler) handleListPositions(c *gin.Context) {
 	c.JSON(http.StatusOK, positions)
 }
 
-type AddPositionRequest struct {
-	RecordedAt       time.Time `json:"recorded_at" binding:"required"`
-	Latitude         float64   `json:"latitude" binding:"required"`
-	Longitude        float64   `json:"longitude" binding:"required"`
-	SpeedKnots       *float64  `json:"speed_knots"`
-	Heading          *float64  `json:"heading"`
-	DistanceLoggedNM *float64  `json:"distance_logged_nm"`
-	FuelRemainingMT  *float64  `json:"fuel_remaining_mt"`
-	Remarks          *string   `json:"remarks"`
+// parsePage reads limit (default 20, at most 100) and offset query params.
+// Out-of-range values fall back to the defaults.
+func parsePage(c *gin.Context) db.Page {
+	page := db.Page{Limit: 20}
+	if l, convErr := strconv.Atoi(c.Query("limit")); convErr == nil && l > 0 && l <= 100 {
+		page.Limit = l
+	}
+	if o, convErr := strconv.Atoi(c.Query("offset")); convErr == nil && o >= 0 {
+		page.Offset = o
+	}
+	return page
 }
 
-func (h *Handler) handleAddPosition(c *gin.Context) {
+// searchPositions serves a page of positions, optionally from one source.
+// ?limit= defaults to 20, capped at 100.
+func (h *Handler) searchPositions(c *gin.Context, voyageID uuid.UUID) {
+	page := parsePage(c)
+
+	var filter db.ShipPositionFilter
+	if source := strings.TrimSpace(c.Query("source")); source != "" {
+		filter.Source = &source
+	}
+
+	positions, err := h.positionRepo.Search(c.Request.Context(), voyageID, filter, page)
+	if err != nil {
+		if errors.Is(err, db.ErrOffsetTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list positions"})
+		return
+	}
+	if positions == nil {
+		positions = []db.ShipPosition{}
+	}
+	render.PageHeaders(c, page.Limit, page.Offset)
+	c.JSON(http.StatusOK, positions)
+}
+
+// handlePagePositions returns one keyset page of the track. ?cursor= is the
+// next_cursor from a previous page; without it ?direction=backward starts
+// from the newest position. ?limit= defaults to 20, capped at 100.
+func (h *Handler) handlePagePositions(c *gin.Context) {
 	voyageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
 		return
 	}
-	var req AddPositionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	limit := 20
+	if l, convErr := strconv.Atoi(c.Query("limit")); convErr == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	cursor := &db.PositionCursor{}
+	if token := c.Query("cursor"); token != "" {
+		*cursor, err = db.DecodePositionCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+	} else {
+		switch c.DefaultQuery("direction", "forward") {
+		case "forward":
+		case "backward":
+			cursor.Backward = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be forward or backward"})
+			return
+		}
+	}
+
+	positions, next, err := h.positionRepo.PageByVoyage(c.Request.Context(), voyageID, cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list positions"})
+		return
+	}
+	if positions == nil {
+		positions = []db.ShipPosition{}
+	}
+	resp := gin.H{"data": positions, "next_cursor": nil}
+	if next != nil {
+		resp["next_cursor"] = next.Encode()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) handleProgress(c *gin.Context) {
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return
+	}
+	progress, err := h.voyageRepo.VoyageProgress(c.Request.Context(), voyageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute progress"})
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+func (h *Handler) handleSpeedReport(c *gin.Context) {
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return
+	}
+	report, err := h.voyageRepo.VoyageSpeedReport(c.Request.Context(), voyageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "voyage not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute speed report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ndjsonFlushEvery is how many positions are written between flushes when
+// streaming NDJSON.
+const ndjsonFlushEvery = 100
+
+// handleStreamPositions writes every position for the voyage as one JSON
+// object per line, flushing as it goes. A client disconnect cancels the
+// request context, which ends the database scan.
+func (h *Handler) handleStreamPositions(c *gin.Context) {
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
 		return
 	}
-	pos := &db.ShipPosition{
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	n := 0
+	err = h.positionRepo.StreamByVoyage(c.Request.Context(), voyageID, func(pos db.ShipPosition) error {
+		if err := enc.Encode(pos); err != nil {
+			return err
+		}
+		n++
+		if n%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	c.Writer.Flush()
+	if err != nil && c.Request.Context().Err() == nil {
+		log.Printf("stream positions for voyage %s: %v", voyageID, err)
+		_ = c.Error(err)
+	}
+}
+
+type AddPositionRequest struct {
+	RecordedAt       time.Time       `json:"recorded_at" binding:"required"`
+	Latitude         float64         `json:"latitude" binding:"required"`
+	Longitude        float64         `json:"longitude" binding:"required"`
+	SpeedKnots       *float64        `json:"speed_knots"`
+	Heading          *float64        `json:"heading"`
+	DistanceLoggedNM *float64        `json:"distance_logged_nm"`
+	FuelRemainingMT  *float64        `json:"fuel_remaining_mt"`
+	Remarks          *string         `json:"remarks"`
+	RawPayload       json.RawMessage `json:"raw_payload"` // optional source AIS message, stored verbatim
+}
+
+func (req AddPositionRequest) position(voyageID uuid.UUID) *db.ShipPosition {
+	return &db.ShipPosition{
 		VoyageID:         voyageID,
 		RecordedAt:       req.RecordedAt,
 		Latitude:         req.Latitude,
@@ -448,14 +764,94 @@ func (h *Handler) handleAddPosition(c *gin.Context) {
 		FuelRemainingMT:  req.FuelRemainingMT,
 		Source:           "manual",
 		Remarks:          req.Remarks,
+		RawPayload:       req.RawPayload,
+	}
+}
+
+// isPositionInputError reports whether err from a position write is the
+// client's fault.
+func isPositionInputError(err error) bool {
+	var verr *db.ValidationError
+	return errors.Is(err, db.ErrNotesTooLong) || errors.Is(err, db.ErrNonMonotonicBatch) || errors.As(err, &verr)
+}
+
+func (h *Handler) handleAddPosition(c *gin.Context) {
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return
+	}
+	var req AddPositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	pos := req.position(voyageID)
 	if err := h.positionRepo.Create(c.Request.Context(), pos); err != nil {
+		if isPositionInputError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save position"})
 		return
 	}
 	c.JSON(http.StatusCreated, pos)
 }
 
+type AddPositionsRequest struct {
+	Positions []AddPositionRequest `json:"positions" binding:"required,min=1,dive"`
+	Atomic    bool                 `json:"atomic"`
+	Strict    bool                 `json:"strict"`
+}
+
+// handleAddPositions records several positions. With atomic set either all
+// are saved or none are; the batch is put in time order first, or rejected
+// when out of order if strict is also set. Otherwise each is saved
+// independently and failures are listed by index alongside the count that
+// went in.
+func (h *Handler) handleAddPositions(c *gin.Context) {
+	voyageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
+		return
+	}
+	var req AddPositionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	positions := make([]*db.ShipPosition, len(req.Positions))
+	for i, p := range req.Positions {
+		positions[i] = p.position(voyageID)
+	}
+
+	if req.Atomic {
+		if err := h.positionRepo.CreateBatch(c.Request.Context(), positions, req.Strict); err != nil {
+			if isPositionInputError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save positions"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"created": len(positions)})
+		return
+	}
+
+	created, batchErrs := h.positionRepo.CreateBatchPartial(c.Request.Context(), positions)
+	failures := make([]gin.H, 0, len(batchErrs))
+	for i := range positions {
+		if err, failed := batchErrs[i]; failed {
+			msg := "failed to save position"
+			if isPositionInputError(err) {
+				msg = err.Error()
+			}
+			failures = append(failures, gin.H{"index": i, "error": msg})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"created": created, "errors": failures})
+}
+
 // handleLivePosition fetches from MarineTraffic if configured, else returns latest manual position.
 func (h *Handler) handleLivePosition(c *gin.Context) {
 	voyageID, err := uuid.Parse(c.Param("id"))
@@ -692,12 +1088,13 @@ func (h *Handler) handleListLaytime(c *gin.Context) {
 }
 
 type LaytimeEntryRequest struct {
-	PortName     string     `json:"port_name" binding:"required"`
-	Activity     string     `json:"activity" binding:"required"`
-	StartedAt    time.Time  `json:"started_at" binding:"required"`
-	EndedAt      *time.Time `json:"ended_at"`
-	HoursCounted *float64   `json:"hours_counted"`
-	Remarks      *string    `json:"remarks"`
+	PortName      string     `json:"port_name" binding:"required"`
+	Activity      string     `json:"activity" binding:"required"`
+	StartedAt     time.Time  `json:"started_at" binding:"required"`
+	EndedAt       *time.Time `json:"ended_at"`
+	HoursCounted  *float64   `json:"hours_counted"`
+	ExcludedHours float64    `json:"excluded_hours" binding:"gte=0"`
+	Remarks       *string    `json:"remarks"`
 }
 
 func (h *Handler) handleAddLaytime(c *gin.Context) {
@@ -720,13 +1117,21 @@ func (h *Handler) handleAddLaytime(c *gin.Context) {
 		return
 	}
 
-	// Auto-calculate hours if start+end provided and hours not set
-	hoursCounted := req.HoursCounted
-	if hoursCounted == nil && req.EndedAt != nil {
-		hrs := req.EndedAt.Sub(req.StartedAt).Hours()
-		hoursCounted = &hrs
+	// Hours are auto-calculated from start+end by the repository when unset.
+	entry := req.entry(v)
+	if err := h.laytimeRepo.Create(c.Request.Context(), entry); err != nil {
+		if isLaytimeInputError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add laytime entry"})
+		return
 	}
+	c.JSON(http.StatusCreated, entry)
+}
 
+// entry builds the laytime entry req describes for voyage v.
+func (req LaytimeEntryRequest) entry(v db.Voyage) *db.LaytimeEntry {
 	// Use a placeholder charter_detail_id if none (laytime_entries requires it due to old schema)
 	var charterDetailID uuid.UUID
 	if v.CharterDetailID != nil {
@@ -734,24 +1139,86 @@ func (h *Handler) handleAddLaytime(c *gin.Context) {
 	} else {
 		// Use voyage ID as a stand-in UUID (same table, just needs a non-null value)
 		// We'll relax this constraint in a future migration
-		charterDetailID = voyageID
+		charterDetailID = v.ID
 	}
 
-	entry := &db.LaytimeEntry{
+	voyageID := v.ID
+	return &db.LaytimeEntry{
 		CharterDetailID: charterDetailID,
 		VoyageID:        &voyageID,
 		PortName:        req.PortName,
 		Activity:        req.Activity,
 		StartedAt:       req.StartedAt,
 		EndedAt:         req.EndedAt,
-		HoursCounted:    hoursCounted,
+		HoursCounted:    req.HoursCounted,
+		ExcludedHours:   req.ExcludedHours,
 		Remarks:         req.Remarks,
 	}
-	if err := h.laytimeRepo.Create(c.Request.Context(), entry); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add laytime entry"})
+}
+
+// isLaytimeInputError reports whether err from a laytime write is the
+// client's fault.
+func isLaytimeInputError(err error) bool {
+	return errors.Is(err, db.ErrNotesTooLong) || errors.Is(err, db.ErrInvalidExclusion)
+}
+
+type LaytimeBatchRequest struct {
+	Entries []LaytimeEntryRequest `json:"entries" binding:"required,min=1,dive"`
+}
+
+// bindLaytimeBatch loads the voyage and binds a batch of entries for it.
+func (h *Handler) bindLaytimeBatch(c *gin.Context) ([]*db.LaytimeEntry, bool) {
+	v, ok := h.loadParticipantVoyage(c)
+	if !ok {
+		return nil, false
+	}
+	var req LaytimeBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	entries := make([]*db.LaytimeEntry, len(req.Entries))
+	for i, e := range req.Entries {
+		entries[i] = e.entry(v)
+	}
+	return entries, true
+}
+
+// handleAddLaytimeBatch upserts a noon-report batch of entries in one
+// transaction.
+func (h *Handler) handleAddLaytimeBatch(c *gin.Context) {
+	entries, ok := h.bindLaytimeBatch(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusCreated, entry)
+	res, err := h.laytimeRepo.CreateBatch(c.Request.Context(), entries)
+	if err != nil {
+		if isLaytimeInputError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add laytime entries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"inserted": res.Inserted, "updated": res.Updated, "entries": entries})
+}
+
+// handlePreviewLaytime is a dry run of handleAddLaytimeBatch: it returns the
+// entries as they would be written, with computed hours, and the errors that
+// would reject any of them. Nothing is saved.
+func (h *Handler) handlePreviewLaytime(c *gin.Context) {
+	entries, ok := h.bindLaytimeBatch(c)
+	if !ok {
+		return
+	}
+	batchErrs := h.laytimeRepo.Preview(entries)
+	failures := make([]gin.H, 0, len(batchErrs))
+	for i := range entries {
+		if err, failed := batchErrs[i]; failed {
+			failures = append(failures, gin.H{"index": i, "error": err.Error()})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": len(failures) == 0, "entries": entries, "errors": failures})
 }
 
 func (h *Handler) handleUpdateLaytime(c *gin.Context) {
@@ -775,13 +1242,26 @@ func (h *Handler) handleUpdateLaytime(c *gin.Context) {
 	existing.StartedAt = req.StartedAt
 	existing.EndedAt = req.EndedAt
 	existing.Remarks = req.Remarks
+	existing.ExcludedHours = req.ExcludedHours
 	if req.HoursCounted != nil {
 		existing.HoursCounted = req.HoursCounted
 	} else if req.EndedAt != nil {
-		hrs := req.EndedAt.Sub(req.StartedAt).Hours()
+		hrs := db.CountedHours(req.StartedAt, *req.EndedAt, req.ExcludedHours)
 		existing.HoursCounted = &hrs
 	}
 	if err := h.laytimeRepo.Update(c.Request.Context(), &existing); err != nil {
+		if errors.Is(err, db.ErrNotesTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrInvalidExclusion) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update entry"})
 		return
 	}
@@ -807,7 +1287,12 @@ func (h *Handler) handleLaytimeSummary(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid voyage ID"})
 		return
 	}
-	summary, err := h.voyageRepo.CalcLaytime(c.Request.Context(), voyageID)
+	var summary db.LaytimeSummary
+	if c.Query("from_nor") == "true" {
+		summary, err = h.voyageRepo.CalcLaytimeFromNOR(c.Request.Context(), voyageID)
+	} else {
+		summary, err = h.voyageRepo.CalcLaytime(c.Request.Context(), voyageID)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate laytime"})
 		return
@@ -977,9 +1462,14 @@ func (h *Handler) handleJoinVoyage(c *gin.Context) {
 	// them access to /voyages/:id.
 	if err := h.voyageRepo.SetParty(c.Request.Context(), invite.VoyageID, invite.Role, userID); err != nil {
 		log.Printf("handleJoinVoyage: failed to link party: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join voyage"})
+		return
 	}
 
-	_ = h.voyageRepo.UseInvite(c.Request.Context(), req.Token, userID)
+	if err := h.voyageRepo.UseInvite(c.Request.Context(), req.Token, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to use invite"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "joined voyage",
@@ -0,0 +1,240 @@
+package voyages
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestVoyageTransitionDefaultsToClock(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	frozen := time.Date(2026, 4, 1, 6, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	given := time.Date(2026, 4, 2, 10, 0, 0, 0, time.UTC)
+	cols := []string{"status", "actual_departure_at", "actual_arrival_at", "updated_at"}
+
+	tests := []struct {
+		name   string
+		path   string
+		status string
+		body   string
+		want   time.Time
+	}{
+		{"depart now", "/depart", "planned", "", frozen.UTC()},
+		{"depart at given time", "/depart", "planned", `{"at":"2026-04-02T10:00:00Z"}`, given},
+		{"arrive now", "/arrive", "sailing", "", frozen.UTC()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			freezeClock(t, frozen)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID, "status": tt.status})
+			var departed any
+			if tt.status == "sailing" {
+				departed = frozen.Add(-48 * time.Hour)
+			}
+			fake.Return("FROM shipman.voyages WHERE id = $1 FOR UPDATE", dbtest.Rows(cols,
+				[]any{tt.status, departed, nil, time.Now()}))
+			fake.On("UPDATE shipman.voyages SET status = ", func(call dbtest.Call) dbtest.Result {
+				return dbtest.Rows(cols, []any{"sailing", call.Arg(2), nil, time.Now()})
+			})
+
+			w := do(t, newTestRouter(), owner, http.MethodPost, "/"+voyageID.String()+tt.path, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			calls := fake.Calls("UPDATE shipman.voyages SET status = ")
+			if len(calls) != 1 {
+				t.Fatalf("status written %d times, want 1", len(calls))
+			}
+			if at, ok := calls[0].Arg(2).(time.Time); !ok || !at.Equal(tt.want) {
+				t.Errorf("transition time = %v, want %v", calls[0].Arg(2), tt.want)
+			}
+		})
+	}
+}
+
+func TestVoyageDeleteDryRun(t *testing.T) {
+	owner := newTestUser("shipowner")
+	tests := []struct {
+		name       string
+		caller     testUser
+		query      string
+		wantStatus int
+		wantDelete bool
+	}{
+		{"dry run", owner, "?dry_run=true", http.StatusOK, false},
+		{"delete", owner, "", http.StatusOK, true},
+		{"dry run for a stranger", newTestUser("shipowner"), "?dry_run=true", http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			voyageID := uuid.New()
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+			fake.Return("SELECT id FROM shipman.voyages WHERE id = $1 FOR UPDATE", dbtest.Rows([]string{"id"}, []any{voyageID}))
+			fake.Return("SELECT COUNT(*) FROM shipman.", dbtest.Rows([]string{"count"}, []any{int64(0)}))
+			fake.Return("SELECT COUNT(*) FROM shipman.voyage_ports", dbtest.Rows([]string{"count"}, []any{int64(4)}))
+			fake.Return("DELETE FROM shipman.voyages WHERE id = $1", dbtest.Affected(1))
+
+			w := do(t, newTestRouter(), tt.caller, http.MethodDelete, "/"+voyageID.String()+tt.query, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if deleted := len(fake.Calls("DELETE FROM shipman.voyages")) > 0; deleted != tt.wantDelete {
+				t.Fatalf("deleted = %v, want %v", deleted, tt.wantDelete)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var plan db.DeletePlan
+			if tt.wantDelete {
+				var body struct {
+					Plan db.DeletePlan `json:"plan"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				plan = body.Plan
+			} else if err := json.Unmarshal(w.Body.Bytes(), &plan); err != nil {
+				t.Fatal(err)
+			}
+			if plan.VoyageID != voyageID || plan.DryRun == tt.wantDelete {
+				t.Errorf("plan = %+v", plan)
+			}
+			if plan.Deleted["voyages"] != 1 || plan.Deleted["voyage_ports"] != 4 {
+				t.Errorf("deleted = %v, want the voyage and its 4 ports", plan.Deleted)
+			}
+		})
+	}
+}
+
+func TestVoyageUpdateMissingRow(t *testing.T) {
+	fake := newFakeDB(t)
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+	// The voyage is deleted between the lookup and the write.
+	fake.Return("UPDATE shipman.voyages", dbtest.Rows([]string{"updated_at"}))
+
+	w := do(t, newTestRouter(), owner, http.MethodPatch, "/"+voyageID.String(), `{"notes":"late"}`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404: %s", w.Code, w.Body.String())
+	}
+	if len(fake.Calls("UPDATE shipman.voyages")) != 1 {
+		t.Error("want the update attempted once")
+	}
+}
+
+func TestVoyageArchiveEndpoints(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	archivedAt := time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		path       string
+		user       testUser
+		match      string
+		wantStatus int
+	}{
+		{"owner archives", "/archive", owner, "SET archived_at = COALESCE(archived_at, NOW())", http.StatusOK},
+		{"owner unarchives", "/unarchive", owner, "SET archived_at = NULL", http.StatusOK},
+		{"non-owner archives", "/archive", newTestUser("broker"), "SET archived_at = COALESCE(archived_at, NOW())", http.StatusForbidden},
+		{"non-owner unarchives", "/unarchive", newTestUser("admin"), "SET archived_at = NULL", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID, "archived_at": archivedAt})
+			fake.Return(tt.match, dbtest.Affected(1))
+
+			w := do(t, newTestRouter(), tt.user, http.MethodPost, "/"+voyageID.String()+tt.path, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(tt.match)
+			if tt.wantStatus != http.StatusOK {
+				if len(calls) != 0 {
+					t.Error("a non-owner changed the archive state")
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0].Arg(1) != voyageID.String() {
+				t.Fatalf("update calls = %+v, want one for the voyage", calls)
+			}
+			var got db.Voyage
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != voyageID {
+				t.Errorf("returned voyage %s, want %s", got.ID, voyageID)
+			}
+		})
+	}
+}
+
+func TestVoyageListIncludeArchived(t *testing.T) {
+	for query, want := range map[string]bool{"": false, "?include_archived=true": true, "?include_archived=1": false} {
+		t.Run(query, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return("FROM shipman.voyages WHERE (owner_user_id = $1", dbtest.Rows([]string{"id"}))
+			user := newTestUser("shipowner")
+
+			w := do(t, newTestRouter(), user, http.MethodGet, "/"+query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			calls := fake.Calls("FROM shipman.voyages WHERE (owner_user_id = $1")
+			if len(calls) != 1 || calls[0].Arg(1) != user.ID.String() || calls[0].Arg(2) != want {
+				t.Errorf("list calls = %+v, want include_archived %v", calls, want)
+			}
+		})
+	}
+}
+
+func TestVoyageGetOtherOrg(t *testing.T) {
+	owner := newTestUser("shipowner")
+	voyageID := uuid.New()
+	elsewhere := owner
+	elsewhere.OrgID = uuid.New()
+	admin := elsewhere
+	admin.Role = "admin"
+
+	tests := []struct {
+		name       string
+		user       testUser
+		wantStatus int
+	}{
+		{"same org", owner, http.StatusOK},
+		{"other org", elsewhere, http.StatusNotFound},
+		{"admin in another org", admin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			stubVoyages(fake, map[string]any{"id": voyageID, "owner_user_id": owner.ID})
+
+			w := do(t, newTestRouter(), tt.user, http.MethodGet, "/"+voyageID.String(), "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			calls := fake.Calls(voyageRetrieveQuery)
+			if len(calls) == 0 {
+				t.Fatal("voyage was not looked up")
+			}
+			if org := calls[0].Arg(2); tt.user.Role == "admin" && org != nil {
+				t.Errorf("admin lookup scoped to %v, want unscoped", org)
+			} else if tt.user.Role != "admin" && org != tt.user.OrgID.String() {
+				t.Errorf("lookup scoped to %v, want %s", org, tt.user.OrgID)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// User is the authenticated caller as read from the request's JWT.
+type User struct {
+	ID       uuid.UUID
+	OrgID    uuid.UUID
+	Email    string
+	Role     string
+	FullName string
+}
+
+// Auth validates the "Authorization: Bearer" token with jwt and stores the
+// caller's claims on the context for CurrentUser. Missing, malformed,
+// expired and tampered tokens are rejected with 401.
+func Auth(jwt *auth.JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing Authorization header"})
+			return
+		}
+
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid Authorization header format"})
+			return
+		}
+
+		claims, err := jwt.Verify(parts[1])
+		if err != nil {
+			if errors.Is(err, auth.ErrExpiredToken) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has expired"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		c.Set("userID", claims.UserID)
+		c.Set("userEmail", claims.Email)
+		c.Set("userRole", claims.Role)
+		c.Set("userFullName", claims.FullName)
+		ScopeToOrg(c, claims)
+
+		c.Next()
+	}
+}
+
+// ScopeToOrg limits the request's repository reads to the caller's
+// organization. Admins are left unscoped and see every tenant. Tokens issued
+// before organizations existed carry no org and fall into the default one.
+func ScopeToOrg(c *gin.Context, claims *auth.Claims) {
+	orgID := claims.OrgID
+	if orgID == uuid.Nil {
+		orgID = db.DefaultOrgID
+	}
+	c.Set("orgID", orgID)
+	if claims.Role == "admin" {
+		return
+	}
+	c.Request = c.Request.WithContext(db.WithOrg(c.Request.Context(), orgID))
+}
+
+// CurrentUser returns the caller stored by Auth. It reports false on routes
+// the middleware did not run on.
+func CurrentUser(c *gin.Context) (User, bool) {
+	id, ok := c.Get("userID")
+	if !ok {
+		return User{}, false
+	}
+	u := User{
+		Email:    c.GetString("userEmail"),
+		Role:     c.GetString("userRole"),
+		FullName: c.GetString("userFullName"),
+	}
+	u.ID, _ = id.(uuid.UUID)
+	if org, ok := c.Get("orgID"); ok {
+		u.OrgID, _ = org.(uuid.UUID)
+	}
+	return u, true
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwt := auth.NewJWTManager("test-secret", time.Hour)
+
+	userID, orgID := uuid.New(), uuid.New()
+	valid, err := jwt.Generate(userID, orgID, "ops@example.com", "broker", "Ops Desk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noOrg, err := jwt.Generate(userID, uuid.Nil, "ops@example.com", "broker", "Ops Desk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSecret, err := auth.NewJWTManager("other-secret", time.Hour).
+		Generate(userID, orgID, "ops@example.com", "admin", "Ops Desk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := auth.NewJWTManager("test-secret", -time.Minute).
+		Generate(userID, orgID, "ops@example.com", "broker", "Ops Desk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Swap the payload for one claiming admin while keeping the signature.
+	parts := strings.Split(valid, ".")
+	adminClaims, err := jwt.Generate(userID, orgID, "ops@example.com", "admin", "Ops Desk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := parts[0] + "." + strings.Split(adminClaims, ".")[1] + "." + parts[2]
+
+	r := gin.New()
+	r.GET("/me", Auth(jwt), func(c *gin.Context) {
+		u, ok := CurrentUser(c)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "no current user"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": u.ID, "org_id": u.OrgID, "email": u.Email, "role": u.Role, "full_name": u.FullName})
+	})
+
+	tests := []struct {
+		name      string
+		header    string
+		wantCode  int
+		wantError string
+		wantOrg   uuid.UUID
+	}{
+		{"valid token", "Bearer " + valid, http.StatusOK, "", orgID},
+		{"lowercase scheme", "bearer " + valid, http.StatusOK, "", orgID},
+		{"token without org", "Bearer " + noOrg, http.StatusOK, "", db.DefaultOrgID},
+		{"missing header", "", http.StatusUnauthorized, "missing Authorization header", uuid.Nil},
+		{"missing scheme", valid, http.StatusUnauthorized, "invalid Authorization header format", uuid.Nil},
+		{"tampered payload", "Bearer " + tampered, http.StatusUnauthorized, "invalid token", uuid.Nil},
+		{"wrong secret", "Bearer " + otherSecret, http.StatusUnauthorized, "invalid token", uuid.Nil},
+		{"expired token", "Bearer " + expired, http.StatusUnauthorized, "token has expired", uuid.Nil},
+		{"garbage token", "Bearer not-a-jwt", http.StatusUnauthorized, "invalid token", uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != http.StatusOK {
+				if body["error"] != tt.wantError {
+					t.Errorf("error = %q, want %q", body["error"], tt.wantError)
+				}
+				return
+			}
+			if body["id"] != userID.String() || body["role"] != "broker" || body["email"] != "ops@example.com" || body["full_name"] != "Ops Desk" {
+				t.Errorf("current user = %v", body)
+			}
+			if body["org_id"] != tt.wantOrg.String() {
+				t.Errorf("org_id = %s, want %s", body["org_id"], tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestCurrentUserWithoutAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if u, ok := CurrentUser(c); ok {
+		t.Errorf("CurrentUser = %+v, true; want false without Auth", u)
+	}
+}
+
+func TestScopeToOrg(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orgID := uuid.New()
+	tests := []struct {
+		name       string
+		claims     auth.Claims
+		wantOrg    uuid.UUID
+		wantScoped bool
+	}{
+		{"member", auth.Claims{OrgID: orgID, Role: "broker"}, orgID, true},
+		{"member without org", auth.Claims{Role: "broker"}, db.DefaultOrgID, true},
+		{"admin bypass", auth.Claims{OrgID: orgID, Role: "admin"}, orgID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ScopeToOrg(c, &tt.claims)
+
+			if got, _ := c.Get("orgID"); got != tt.wantOrg {
+				t.Errorf("orgID = %v, want %s", got, tt.wantOrg)
+			}
+			scoped, ok := db.OrgFromContext(c.Request.Context())
+			if ok != tt.wantScoped || (ok && scoped != tt.wantOrg) {
+				t.Errorf("request scope = %s, %v; want %s, %v", scoped, ok, tt.wantOrg, tt.wantScoped)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxListLimit is the largest limit a guarded list endpoint accepts
+// unless overridden with SetMaxListLimit. It sits above the per-page maximum
+// on purpose: values between the two fall back to the default page size as
+// before, while anything past it is treated as an attempt to fetch
+// everything in one response.
+const DefaultMaxListLimit = 1000
+
+var maxListLimit = DefaultMaxListLimit
+
+// SetMaxListLimit overrides the cap used by ListLimitGuard. Values <= 0
+// restore the default.
+func SetMaxListLimit(n int) {
+	if n <= 0 {
+		n = DefaultMaxListLimit
+	}
+	maxListLimit = n
+}
+
+// ListLimitGuard rejects a list request whose limit query param exceeds the
+// configured cap with a 413 naming streamPath, the endpoint that serves the
+// full set without buffering it.
+func ListLimitGuard(streamPath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > maxListLimit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":  fmt.Sprintf("limit %d exceeds maximum of %d; use the streaming export for full result sets", l, maxListLimit),
+				"stream": streamPath,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListLimitGuard(t *testing.T) {
+	t.Cleanup(func() { SetMaxListLimit(0) })
+	tests := []struct {
+		name       string
+		cap        int
+		query      string
+		wantStatus int
+	}{
+		{"no limit", 0, "", http.StatusOK},
+		{"at the cap", 0, "?limit=1000", http.StatusOK},
+		{"past the cap", 0, "?limit=1001", http.StatusRequestEntityTooLarge},
+		{"huge limit", 0, "?limit=100000000", http.StatusRequestEntityTooLarge},
+		{"non-numeric limit is left to the handler", 0, "?limit=all", http.StatusOK},
+		{"configured cap", 50, "?limit=51", http.StatusRequestEntityTooLarge},
+		{"under configured cap", 50, "?limit=50", http.StatusOK},
+		{"non-positive cap restores the default", -1, "?limit=1000", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetMaxListLimit(tt.cap)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			reached := false
+			r.GET("/charters", ListLimitGuard("/api/v1/charters/stream"), func(c *gin.Context) {
+				reached = true
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/charters"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler reached = %v", reached)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			var body struct {
+				Error  string `json:"error"`
+				Stream string `json:"stream"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Stream != "/api/v1/charters/stream" || body.Error == "" {
+				t.Errorf("body = %s, want an error pointing at the stream endpoint", w.Body.String())
+			}
+		})
+	}
+}
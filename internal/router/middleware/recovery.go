@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Recovery turns a handler panic into a 500 carrying a correlation ID. The
+// ID is the request's X-Request-ID when the client sent one, or a fresh UUID
+// otherwise; it is logged alongside the panic and stack, returned in the
+// body and echoed in the X-Request-ID header so users can quote it to
+// support. The stack never reaches the client.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+
+			correlationID := c.GetString("requestID")
+			if correlationID == "" {
+				correlationID = c.GetHeader("X-Request-ID")
+			}
+			if correlationID == "" {
+				correlationID = uuid.NewString()
+			}
+			log.Printf("panic recovered request_id=%s %s %s: %v\n%s", correlationID, c.Request.Method, c.Request.URL.Path, p, debug.Stack())
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.Header("X-Request-ID", correlationID)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":          "internal server error",
+				"correlation_id": correlationID,
+			})
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// captureLog redirects the standard logger for the rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(prev)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		contextID string
+		wantID    string
+	}{
+		{name: "request ID from the logging middleware", header: "client-id", contextID: "ctx-id", wantID: "ctx-id"},
+		{name: "client request ID", header: "client-id", wantID: "client-id"},
+		{name: "fresh ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.contextID != "" {
+					c.Set("requestID", tt.contextID)
+				}
+				c.Next()
+			}, Recovery())
+			r.GET("/boom", func(c *gin.Context) { panic("secret database password") })
+
+			req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", w.Code)
+			}
+			var body struct {
+				Error         string `json:"error"`
+				CorrelationID string `json:"correlation_id"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %s: %v", w.Body.String(), err)
+			}
+			if body.Error != "internal server error" {
+				t.Errorf("error = %q, want internal server error", body.Error)
+			}
+			wantID := tt.wantID
+			if wantID == "" {
+				if _, err := uuid.Parse(body.CorrelationID); err != nil {
+					t.Errorf("correlation_id = %q, want a fresh UUID", body.CorrelationID)
+				}
+				wantID = body.CorrelationID
+			}
+			if body.CorrelationID != wantID {
+				t.Errorf("correlation_id = %q, want %q", body.CorrelationID, wantID)
+			}
+			if got := w.Header().Get("X-Request-ID"); got != wantID {
+				t.Errorf("X-Request-ID = %q, want %q", got, wantID)
+			}
+			for _, leak := range []string{"secret database password", "goroutine", "recovery.go"} {
+				if strings.Contains(w.Body.String(), leak) {
+					t.Errorf("body leaks %q: %s", leak, w.Body.String())
+				}
+			}
+			if !strings.Contains(logs.String(), "request_id="+wantID) || !strings.Contains(logs.String(), "secret database password") {
+				t.Errorf("log = %q, want the panic and request ID", logs.String())
+			}
+		})
+	}
+}
+
+func TestRecoveryAfterWrite(t *testing.T) {
+	captureLog(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	r.GET("/partial", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("late")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/partial", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want the partial write left alone", w.Code, w.Body.String())
+	}
+}
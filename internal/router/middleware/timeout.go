@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLongRunningTimeout is the deadline LongRunning grants unless
+// overridden with SetLongRunningTimeout.
+const DefaultLongRunningTimeout = 5 * time.Minute
+
+var longRunningTimeout = DefaultLongRunningTimeout
+
+// SetLongRunningTimeout overrides the deadline used by LongRunning. Values
+// <= 0 restore the default.
+func SetLongRunningTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultLongRunningTimeout
+	}
+	longRunningTimeout = d
+}
+
+// LongRunning extends the connection's read and write deadlines past the
+// server-wide timeouts for routes that move large bodies (uploads, exports,
+// PDFs) or wait on slow upstreams such as AI extraction.
+func LongRunning() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deadline := time.Now().Add(longRunningTimeout)
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.SetReadDeadline(deadline); err != nil {
+			log.Printf("extend read deadline for %s: %v", c.FullPath(), err)
+		}
+		if err := rc.SetWriteDeadline(deadline); err != nil {
+			log.Printf("extend write deadline for %s: %v", c.FullPath(), err)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+// Package middleware holds gin middleware that route groups attach to
+// individual routes.
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"shipman/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Transactional wraps a route in a database transaction. The request context
+// carries the transaction, so repository writes and db.Conn(ctx) inside the
+// handler join it. The transaction is committed when the handler finishes
+// with a 2xx status and no gin errors, and rolled back otherwise or on panic.
+//
+// The handler's response is buffered and only sent once the commit has
+// succeeded; a failed commit, or a 2xx answer alongside gin errors, is
+// answered with a 500 instead, so the client is never told that rolled-back
+// work succeeded.
+func Transactional() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, tx, err := db.BeginTx(c.Request.Context())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to start transaction"})
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		orig := c.Writer
+		buf := &bufferedWriter{ResponseWriter: orig, status: http.StatusOK}
+		c.Writer = buf
+
+		defer func() {
+			if p := recover(); p != nil {
+				c.Writer = orig
+				_ = tx.Rollback()
+				panic(p)
+			}
+		}()
+
+		c.Next()
+		c.Writer = orig
+
+		status := buf.Status()
+		if status < 200 || status >= 300 {
+			_ = tx.Rollback()
+			buf.flush()
+			return
+		}
+		if len(c.Errors) > 0 {
+			// The handler reported success but recorded an error; its
+			// response would claim work that is being rolled back.
+			_ = tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "request failed"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			log.Printf("transaction commit failed for %s %s: %v", c.Request.Method, c.FullPath(), err)
+			_ = c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+			return
+		}
+		buf.flush()
+	}
+}
+
+// bufferedWriter holds a handler's status and body until flush, letting
+// Transactional decide what reaches the client after the commit. Headers go
+// straight to the underlying writer's map; they are not sent before flush.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush is a no-op: flushing early would send the response before commit.
+func (w *bufferedWriter) Flush() {}
+
+// flush sends the buffered status and body to the underlying writer.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
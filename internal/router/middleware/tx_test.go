@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+const txWrite = "UPDATE shipman.deals SET status = $2 WHERE id = $1"
+
+func TestTransactional(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       gin.HandlerFunc
+		failCommit    bool
+		wantStatus    int
+		wantBody      string
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{
+			name: "success commits and sends the response",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusCreated, gin.H{"ok": true})
+			},
+			wantStatus:  http.StatusCreated,
+			wantBody:    `{"ok":true}`,
+			wantCommits: 1,
+		},
+		{
+			name: "handler error rolls back",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "second write failed"})
+			},
+			wantStatus:    http.StatusInternalServerError,
+			wantBody:      `{"error":"second write failed"}`,
+			wantRollbacks: 1,
+		},
+		{
+			name: "client error rolls back",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusConflict, gin.H{"error": "conflict"})
+			},
+			wantStatus:    http.StatusConflict,
+			wantBody:      `{"error":"conflict"}`,
+			wantRollbacks: 1,
+		},
+		{
+			name: "gin error after a 2xx discards the buffered response",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"ok": true})
+				_ = c.Error(errors.New("late failure"))
+			},
+			wantStatus:    http.StatusInternalServerError,
+			wantBody:      `{"error":"request failed"}`,
+			wantRollbacks: 1,
+		},
+		{
+			name: "failed commit discards the buffered response",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"ok": true})
+			},
+			failCommit:    true,
+			wantStatus:    http.StatusInternalServerError,
+			wantBody:      `{"error":"failed to commit transaction"}`,
+			wantRollbacks: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(t)
+			fake.Return(txWrite, dbtest.Affected(1))
+			if tt.failCommit {
+				fake.FailCommits(errors.New("serialization failure"))
+			}
+
+			w := serveTransactional(t, func(c *gin.Context) {
+				if _, err := db.Conn(c.Request.Context()).ExecContext(c.Request.Context(), txWrite, "id", "completed"); err != nil {
+					t.Fatal(err)
+				}
+				tt.handler(c)
+			})
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			calls := fake.Calls(txWrite)
+			if len(calls) != 1 || calls[0].Tx == nil {
+				t.Fatalf("write calls = %+v, want one inside the transaction", calls)
+			}
+			if fake.Commits() != tt.wantCommits || fake.Rollbacks() != tt.wantRollbacks {
+				t.Errorf("commits/rollbacks = %d/%d, want %d/%d",
+					fake.Commits(), fake.Rollbacks(), tt.wantCommits, tt.wantRollbacks)
+			}
+		})
+	}
+}
+
+func TestTransactionalPanicRollsBack(t *testing.T) {
+	fake := newFakeDB(t)
+	fake.Return(txWrite, dbtest.Affected(1))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	r.POST("/", Transactional(), func(c *gin.Context) {
+		_, _ = db.Conn(c.Request.Context()).ExecContext(c.Request.Context(), txWrite, "id", "completed")
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "ok") {
+		t.Errorf("body = %s, want the buffered response dropped", w.Body.String())
+	}
+	if fake.Commits() != 0 || fake.Rollbacks() != 1 {
+		t.Errorf("commits/rollbacks = %d/%d, want 0/1", fake.Commits(), fake.Rollbacks())
+	}
+}
+
+// newFakeDB installs a dbtest.Fake as db.Pool for the rest of the test.
+func newFakeDB(t *testing.T) *dbtest.Fake {
+	t.Helper()
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+	return fake
+}
+
+func serveTransactional(t *testing.T, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", Transactional(), handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	return w
+}
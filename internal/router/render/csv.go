@@ -0,0 +1,170 @@
+// Package render holds response helpers shared by the route groups.
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MIMECSV is the media type served for CSV responses.
+const MIMECSV = "text/csv"
+
+// List writes rows as CSV when the client asks for text/csv and jsonBody as
+// JSON otherwise (including when no Accept header is sent). Any other Accept
+// value gets a 406.
+func List(c *gin.Context, filename string, jsonBody, rows any) {
+	switch c.NegotiateFormat(gin.MIMEJSON, MIMECSV) {
+	case gin.MIMEJSON:
+		c.JSON(http.StatusOK, jsonBody)
+	case MIMECSV:
+		c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		if err := WriteCSV(c.Writer, rows); err != nil {
+			_ = c.Error(err)
+		}
+	default:
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "supported formats are application/json and text/csv"})
+	}
+}
+
+// WriteCSV encodes a slice of structs as CSV. The header row comes from the
+// fields' json tag names; fields tagged "-" are skipped. Nil pointers become
+// empty cells, times are RFC 3339, and nested values are JSON encoded. Text
+// that a spreadsheet would read as a formula is prefixed with a quote.
+func WriteCSV(w io.Writer, rows any) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("render: WriteCSV needs a slice, got %s", v.Kind())
+	}
+
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("render: WriteCSV needs a slice of structs, got %s", elem.Kind())
+	}
+
+	header, fields := csvColumns(elem)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(fields))
+	for i := 0; i < v.Len(); i++ {
+		if err := writeCSVRow(cw, v.Index(i), fields, record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvColumns returns the header names and field indexes WriteCSV emits for a
+// struct type.
+func csvColumns(elem reflect.Type) (header []string, fields []int) {
+	for i := 0; i < elem.NumField(); i++ {
+		f := elem.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+	return header, fields
+}
+
+// writeCSVRow writes one struct value, reusing record as scratch space. Nil
+// pointers and non-struct values are skipped.
+func writeCSVRow(cw *csv.Writer, row reflect.Value, fields []int, record []string) error {
+	for row.Kind() == reflect.Pointer {
+		if row.IsNil() {
+			return nil
+		}
+		row = row.Elem()
+	}
+	if row.Kind() != reflect.Struct {
+		return nil
+	}
+	for j, idx := range fields {
+		cell, err := csvCell(row.Field(idx))
+		if err != nil {
+			return err
+		}
+		record[j] = cell
+	}
+	return cw.Write(record)
+}
+
+func csvCell(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch val := v.Interface().(type) {
+	case time.Time:
+		return val.Format(time.RFC3339), nil
+	case []byte:
+		return neutralizeFormula(string(val)), nil
+	case fmt.Stringer:
+		return val.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return neutralizeFormula(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// neutralizeFormula prefixes s with a single quote when it starts with a
+// character spreadsheets treat as the start of a formula, so exported text
+// cannot run as one (CSV injection).
+func neutralizeFormula(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type csvRow struct {
+	Name    string    `json:"name"`
+	Count   int       `json:"count"`
+	Rate    *float64  `json:"rate,omitempty"`
+	At      time.Time `json:"at"`
+	Secret  string    `json:"-"`
+	Tags    []string  `json:"tags"`
+	Untyped string
+	hidden  string
+	Nil     *time.Time `json:"nil"`
+}
+
+func TestWriteCSV(t *testing.T) {
+	rate := 1.5
+	rows := []csvRow{
+		{Name: "Ocean Star", Count: 3, Rate: &rate, At: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Secret: "x", Tags: []string{"a", "b"}, Untyped: "u", hidden: "h"},
+		{Name: "=SUM(A1)", Count: -1},
+	}
+	var b strings.Builder
+	if err := WriteCSV(&b, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "name,count,rate,at,tags,Untyped,nil\n" +
+		`Ocean Star,3,1.5,2026-03-01T12:00:00Z,"[""a"",""b""]",u,` + "\n" +
+		`'=SUM(A1),-1,,0001-01-01T00:00:00Z,null,,` + "\n"
+	if b.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestNeutralizeFormula(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"+1", "'+1"},
+		{"-1+1", "'-1+1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"berth 4 = ok", "berth 4 = ok"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := neutralizeFormula(tt.in); got != tt.want {
+			t.Errorf("neutralizeFormula(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCSVRejectsNonStructSlices(t *testing.T) {
+	for _, rows := range []any{csvRow{}, []string{"a"}, nil} {
+		if err := WriteCSV(&strings.Builder{}, rows); err == nil {
+			t.Errorf("WriteCSV(%T) succeeded, want an error", rows)
+		}
+	}
+}
+
+func TestWriteCSVEmptyWritesHeader(t *testing.T) {
+	var b strings.Builder
+	if err := WriteCSV(&b, []*csvRow{nil}); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "name,count,rate,at,tags,Untyped,nil\n" {
+		t.Errorf("csv = %q, want only the header", b.String())
+	}
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		accept     string
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"", http.StatusOK, "application/json", `{"data":[{"name":"Ocean Star"`},
+		{"application/json", http.StatusOK, "application/json", `{"data":[{"name":"Ocean Star"`},
+		{"text/csv", http.StatusOK, "text/csv", "name,count,rate,at,tags,Untyped,nil\nOcean Star,"},
+		{"application/xml", http.StatusNotAcceptable, "application/json", "supported formats"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			rows := []csvRow{{Name: "Ocean Star"}}
+			r.GET("/", func(c *gin.Context) { List(c, "rows.csv", gin.H{"data": rows}, rows) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %s", got, tt.wantType)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantType == "text/csv" && w.Header().Get("Content-Disposition") != `attachment; filename="rows.csv"` {
+				t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
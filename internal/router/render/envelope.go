@@ -0,0 +1,71 @@
+package render
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderEnvelope lets a client pick the list response shape: "data" for
+// {"data": [...]} or "none" for a bare array. Other values, or no header,
+// get the server default.
+const HeaderEnvelope = "X-Response-Envelope"
+
+var envelopeDefault = true
+
+// SetDefaultEnvelope chooses the list response shape for clients that do not
+// send HeaderEnvelope. It exists for consumers still migrating off bare
+// arrays.
+func SetDefaultEnvelope(on bool) {
+	envelopeDefault = on
+}
+
+func wantsEnvelope(c *gin.Context) bool {
+	switch c.GetHeader(HeaderEnvelope) {
+	case "none":
+		return false
+	case "data":
+		return true
+	}
+	return envelopeDefault
+}
+
+// Envelope returns rows wrapped as {"data": rows}, or rows unchanged when the
+// client opted out of the envelope.
+func Envelope(c *gin.Context, rows any) any {
+	c.Header("Vary", HeaderEnvelope)
+	if wantsEnvelope(c) {
+		return gin.H{"data": rows}
+	}
+	return rows
+}
+
+// Data writes rows as a 200 list response in the shape the client asked for.
+func Data(c *gin.Context, rows any) {
+	c.JSON(http.StatusOK, Envelope(c, rows))
+}
+
+// PageHeaders reports offset pagination in X-Page-Limit and X-Page-Offset so
+// clients reading bare arrays still see it.
+func PageHeaders(c *gin.Context, limit, offset int) {
+	c.Header("X-Page-Limit", strconv.Itoa(limit))
+	c.Header("X-Page-Offset", strconv.Itoa(offset))
+}
+
+// TotalHeader reports the size of the full result set in X-Total-Count.
+func TotalHeader(c *gin.Context, total int) {
+	c.Header("X-Total-Count", strconv.Itoa(total))
+}
+
+// Paged writes one page of rows. Pagination goes in the headers either way
+// and is repeated in the body when the response is enveloped.
+func Paged(c *gin.Context, rows any, limit, offset int) {
+	PageHeaders(c, limit, offset)
+	c.Header("Vary", HeaderEnvelope)
+	if !wantsEnvelope(c) {
+		c.JSON(http.StatusOK, rows)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rows, "limit": limit, "offset": offset})
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPaged(t *testing.T) {
+	t.Cleanup(func() { SetDefaultEnvelope(true) })
+	tests := []struct {
+		name     string
+		envelope bool
+		header   string
+		want     string
+	}{
+		{"default", true, "", `{"data":["a","b"],"limit":2,"offset":4}`},
+		{"opt out", true, "none", `["a","b"]`},
+		{"bare default", false, "", `["a","b"]`},
+		{"opt in", false, "data", `{"data":["a","b"],"limit":2,"offset":4}`},
+		{"unknown value gets the default", false, "wrapped", `["a","b"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetDefaultEnvelope(tt.envelope)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) { Paged(c, []string{"a", "b"}, 2, 4) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderEnvelope, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.want)
+			}
+			if w.Header().Get("X-Page-Limit") != "2" || w.Header().Get("X-Page-Offset") != "4" {
+				t.Errorf("page headers = %q/%q, want 2/4", w.Header().Get("X-Page-Limit"), w.Header().Get("X-Page-Offset"))
+			}
+			if w.Header().Get("Vary") != HeaderEnvelope {
+				t.Errorf("Vary = %q, want %s", w.Header().Get("Vary"), HeaderEnvelope)
+			}
+		})
+	}
+}
+
+func TestData(t *testing.T) {
+	for header, want := range map[string]string{"": `{"data":[1,2]}`, "none": `[1,2]`} {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) { Data(c, []int{1, 2}) })
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(HeaderEnvelope, header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("header %q: response = %d %s, want 200 %s", header, w.Code, w.Body.String(), want)
+		}
+	}
+}
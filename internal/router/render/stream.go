@@ -0,0 +1,84 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MIMENDJSON is the media type served for newline-delimited JSON streams.
+const MIMENDJSON = "application/x-ndjson"
+
+// flushEvery is how many rows Stream writes between flushes.
+const flushEvery = 100
+
+// Stream writes every row produced by each as NDJSON (the default) or, when
+// the client asks for text/csv, as CSV with the same columns as List. Rows go
+// out as they are produced so the full set is never held in memory. Errors
+// after the first row can only be logged on the context, since the status
+// line has already been sent.
+func Stream[T any](c *gin.Context, filename string, each func(yield func(T) error) error) {
+	var write func(T) error
+	var flush func() error
+
+	switch c.NegotiateFormat(MIMENDJSON, MIMECSV, gin.MIMEJSON) {
+	case MIMENDJSON, gin.MIMEJSON:
+		c.Header("Content-Type", MIMENDJSON)
+		enc := json.NewEncoder(c.Writer)
+		write = func(row T) error { return enc.Encode(row) }
+		flush = func() error { return nil }
+	case MIMECSV:
+		elem := reflect.TypeFor[T]()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("cannot stream %s as CSV", elem)})
+			return
+		}
+		header, fields := csvColumns(elem)
+		record := make([]string, len(fields))
+		cw := csv.NewWriter(c.Writer)
+		c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := cw.Write(header); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		write = func(row T) error { return writeCSVRow(cw, reflect.ValueOf(row), fields, record) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "supported formats are application/x-ndjson and text/csv"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+	n := 0
+	err := each(func(row T) error {
+		if err := write(row); err != nil {
+			return err
+		}
+		n++
+		if n%flushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if ferr := flush(); err == nil {
+		err = ferr
+	}
+	c.Writer.Flush()
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
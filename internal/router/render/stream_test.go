@@ -0,0 +1,97 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		accept     string
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"", http.StatusOK, MIMENDJSON, "{\"name\":\"Ocean Star\",\"count\":1,"},
+		{"application/json", http.StatusOK, MIMENDJSON, "{\"name\":\"Ocean Star\""},
+		{"text/csv", http.StatusOK, "text/csv", "name,count,rate,at,tags,Untyped,nil\nOcean Star,1,"},
+		{"application/xml", http.StatusNotAcceptable, "application/json", "supported formats"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			yielded := 0
+			r.GET("/", func(c *gin.Context) {
+				Stream(c, "rows.csv", func(yield func(csvRow) error) error {
+					for i := 1; i <= 250; i++ {
+						if err := yield(csvRow{Name: "Ocean Star", Count: i}); err != nil {
+							return err
+						}
+						yielded++
+					}
+					return nil
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %s", got, tt.wantType)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if yielded != 0 {
+					t.Errorf("produced %d rows for an unacceptable format", yielded)
+				}
+				return
+			}
+			lines := strings.Count(w.Body.String(), "\n")
+			if tt.wantType == "text/csv" {
+				lines-- // header
+			}
+			if yielded != 250 || lines != 250 {
+				t.Errorf("yielded %d rows and wrote %d lines, want 250 each", yielded, lines)
+			}
+		})
+	}
+}
+
+func TestStreamStopsOnError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var errs []*gin.Error
+	r.GET("/", func(c *gin.Context) {
+		Stream(c, "rows.csv", func(yield func(csvRow) error) error {
+			if err := yield(csvRow{Name: "first"}); err != nil {
+				return err
+			}
+			return errors.New("connection reset")
+		})
+		errs = c.Errors
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), "\n") != 1 {
+		t.Errorf("status = %d, body %q; want the row sent before the failure", w.Code, w.Body.String())
+	}
+	if len(errs) != 1 || errs[0].Error() != "connection reset" {
+		t.Errorf("context errors = %v, want the scan error recorded", errs)
+	}
+}
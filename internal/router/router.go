@@ -7,13 +7,18 @@ import (
 
 	"shipman/internal/auth"
 	"shipman/internal/coinsub"
+	"shipman/internal/db"
 	"shipman/internal/email"
+	"shipman/internal/metrics"
+	"shipman/internal/router/groups/activity"
+	"shipman/internal/router/groups/charters"
 	"shipman/internal/router/groups/deals"
 	"shipman/internal/router/groups/documents"
 	"shipman/internal/router/groups/marketplace"
 	pmt "shipman/internal/router/groups/payments"
 	"shipman/internal/router/groups/users"
 	"shipman/internal/router/groups/voyages"
+	"shipman/internal/router/middleware"
 	"shipman/internal/rocketramp"
 	"shipman/internal/storage"
 
@@ -42,10 +47,15 @@ type RocketRampConfig struct {
 	TestMode   bool
 }
 
-func Setup(jwtSecret string, store storage.Storage, aiProvider, aiAPIKey, aiModel, aiBaseURL string, emailCfg email.Config, appURL, marineAPIKey string, coinsubKey, coinsubMerchantID, coinsubSecret string, rr RocketRampConfig) *gin.Engine {
+// Setup builds the engine. A tokenDuration of zero issues tokens valid for
+// 24 hours.
+func Setup(jwtSecret string, tokenDuration time.Duration, store storage.Storage, aiProvider, aiAPIKey, aiModel, aiBaseURL string, emailCfg email.Config, appURL, marineAPIKey string, coinsubKey, coinsubMerchantID, coinsubSecret string, rr RocketRampConfig) *gin.Engine {
+	if tokenDuration <= 0 {
+		tokenDuration = 24 * time.Hour
+	}
 	r := &Router{
 		engine:        gin.New(),
-		jwtManager:    auth.NewJWTManager(jwtSecret, 24*time.Hour),
+		jwtManager:    auth.NewJWTManager(jwtSecret, tokenDuration),
 		storage:       store,
 		aiProvider:    aiProvider,
 		aiAPIKey:      aiAPIKey,
@@ -59,7 +69,7 @@ func Setup(jwtSecret string, store storage.Storage, aiProvider, aiAPIKey, aiMode
 	}
 
 	r.engine.Use(gin.Logger())
-	r.engine.Use(gin.Recovery())
+	r.engine.Use(middleware.Recovery())
 
 	r.addDefaultRoutes()
 	r.registerAPIRoutes()
@@ -81,9 +91,43 @@ func (r *Router) addDefaultRoutes() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// /readyz checks the database answers; ?deep=true also checks that every
+	// table the API uses is present and readable.
+	r.engine.GET("/readyz", func(c *gin.Context) {
+		if c.Query("deep") != "true" {
+			if err := db.Pool.PingContext(c.Request.Context()); err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			return
+		}
+
+		tables, err := db.SchemaCheck(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
+			return
+		}
+		var failed []db.TableStatus
+		for _, t := range tables {
+			if !t.OK {
+				failed = append(failed, t)
+			}
+		}
+		if len(failed) > 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "degraded", "failed": failed, "tables": tables})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "tables": tables})
+	})
+
 	r.engine.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+
+	if metrics.Enabled() {
+		r.engine.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
 }
 
 func (r *Router) registerAPIRoutes() {
@@ -98,13 +142,16 @@ func (r *Router) registerAPIRoutes() {
 	publicUsers := v1.Group("/users")
 	userHandler.AddPublicRoutes(publicUsers)
 
+	authGroup := v1.Group("/auth")
+	userHandler.AddAuthRoutes(authGroup)
+
 	protectedUsers := v1.Group("/users")
 	protectedUsers.Use(r.authMiddleware())
 	userHandler.AddProtectedRoutes(protectedUsers)
 
 	docHandler := documents.NewHandler(r.storage, r.aiProvider, r.aiAPIKey, r.aiModel, r.aiBaseURL)
 	// Public route: serve PDF for iframe preview (token passed as query param)
-	v1.GET("/documents/:id/view", r.tokenFromQueryMiddleware(), docHandler.HandleView)
+	v1.GET("/documents/:id/view", r.tokenFromQueryMiddleware(), middleware.LongRunning(), docHandler.HandleView)
 	docsGroup := v1.Group("/documents")
 	docsGroup.Use(r.authMiddleware())
 	docHandler.AddRoutes(docsGroup)
@@ -122,6 +169,39 @@ func (r *Router) registerAPIRoutes() {
 	marketplaceGroup.Use(r.authMiddleware())
 	marketplaceHandler.AddRoutes(marketplaceGroup)
 
+	charterHandler := charters.NewHandler()
+	chartersGroup := v1.Group("/charters")
+	chartersGroup.Use(r.authMiddleware())
+	charterHandler.AddRoutes(chartersGroup)
+
+	chartersAdminGroup := v1.Group("/charters")
+	chartersAdminGroup.Use(r.authMiddleware(), requireRole("admin"))
+	charterHandler.AddAdminRoutes(chartersAdminGroup)
+
+	disputeHandler := charters.NewDisputeHandler()
+	disputesGroup := v1.Group("/disputes")
+	disputesGroup.Use(r.authMiddleware())
+	disputeHandler.AddRoutes(disputesGroup)
+
+	demurrageHandler := charters.NewDemurrageHandler()
+	demurrageGroup := v1.Group("/demurrage")
+	demurrageGroup.Use(r.authMiddleware())
+	demurrageHandler.AddRoutes(demurrageGroup)
+
+	demurrageAdminGroup := v1.Group("/demurrage")
+	demurrageAdminGroup.Use(r.authMiddleware(), requireRole("admin"))
+	demurrageHandler.AddAdminRoutes(demurrageAdminGroup)
+
+	billHandler := charters.NewBillHandler(r.storage)
+	billsGroup := v1.Group("/bills")
+	billsGroup.Use(r.authMiddleware())
+	billHandler.AddRoutes(billsGroup)
+
+	counterpartyHandler := charters.NewCounterpartyHandler()
+	counterpartiesGroup := v1.Group("/counterparties")
+	counterpartiesGroup.Use(r.authMiddleware())
+	counterpartyHandler.AddRoutes(counterpartiesGroup)
+
 	voyageHandler := voyages.NewHandler(r.marineAPIKey, r.aiProvider, r.aiAPIKey, r.aiModel, r.aiBaseURL, r.emailSvc, r.appURL)
 	publicVoyages := v1.Group("/voyages")
 	voyageHandler.AddPublicRoutes(publicVoyages)
@@ -130,26 +210,57 @@ func (r *Router) registerAPIRoutes() {
 	voyagesGroup.Use(r.authMiddleware())
 	voyageHandler.AddRoutes(voyagesGroup)
 
+	portHandler := voyages.NewPortHandler()
+	portsGroup := v1.Group("/ports")
+	portsGroup.Use(r.authMiddleware())
+	portHandler.AddRoutes(portsGroup)
+
+	positionHandler := voyages.NewPositionHandler()
+	positionsGroup := v1.Group("/positions")
+	positionsGroup.Use(r.authMiddleware())
+	positionHandler.AddRoutes(positionsGroup)
+
+	vesselHandler := voyages.NewVesselHandler()
+	vesselsGroup := v1.Group("/vessels")
+	vesselsGroup.Use(r.authMiddleware())
+	vesselHandler.AddRoutes(vesselsGroup)
+
+	fleetHandler := voyages.NewFleetHandler()
+	fleetGroup := v1.Group("/fleet")
+	fleetGroup.Use(r.authMiddleware(), requireRole("admin"))
+	fleetHandler.AddRoutes(fleetGroup)
+
+	fleetVoyagesGroup := v1.Group("/voyages")
+	fleetVoyagesGroup.Use(r.authMiddleware(), requireRole("admin"))
+	fleetHandler.AddVoyageRoutes(fleetVoyagesGroup)
+
 	paymentHandler := voyages.NewPaymentHandler(r.coinsubClient, r.appURL)
 	paymentHandler.AddRoutes(voyagesGroup)
 	paymentHandler.AddPublicRoutes(v1)
 	paymentHandler.AddUserRoutes(protectedUsers)
 
 	adminGroup := v1.Group("/admin")
-	adminGroup.Use(r.authMiddleware())
+	adminGroup.Use(r.authMiddleware(), requireRole("admin"))
 	paymentHandler.AddAdminRoutes(adminGroup)
 
+	activityHandler := activity.NewHandler()
+	activityGroup := v1.Group("/activity")
+	activityGroup.Use(r.authMiddleware(), requireRole("admin"))
+	activityHandler.AddRoutes(activityGroup)
+
 	rrHandler := pmt.NewHandler(r.rocketRampClient)
 	paymentsGroup := v1.Group("/payments")
 	paymentsGroup.Use(r.authMiddleware())
 	rrHandler.AddRoutes(paymentsGroup)
+	paymentHandler.AddPaymentRoutes(paymentsGroup)
 }
 
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Response-Envelope")
+		c.Header("Access-Control-Expose-Headers", "X-Page-Limit, X-Page-Offset")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == http.MethodOptions {
@@ -162,34 +273,17 @@ func corsMiddleware() gin.HandlerFunc {
 }
 
 func (r *Router) authMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing Authorization header"})
-			return
-		}
-
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid Authorization header format"})
-			return
-		}
+	return middleware.Auth(r.jwtManager)
+}
 
-		claims, err := r.jwtManager.Verify(parts[1])
-		if err != nil {
-			if err == auth.ErrExpiredToken {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has expired"})
-				return
-			}
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+// requireRole rejects requests whose authenticated user does not have role.
+// It must run after authMiddleware.
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("userRole") != role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
 			return
 		}
-
-		c.Set("userID", claims.UserID)
-		c.Set("userEmail", claims.Email)
-		c.Set("userRole", claims.Role)
-		c.Set("userFullName", claims.FullName)
-
 		c.Next()
 	}
 }
@@ -217,6 +311,7 @@ func (r *Router) tokenFromQueryMiddleware() gin.HandlerFunc {
 		}
 		c.Set("userID", claims.UserID)
 		c.Set("userEmail", claims.Email)
+		middleware.ScopeToOrg(c, claims)
 		c.Next()
 	}
 }
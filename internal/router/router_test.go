@@ -0,0 +1,165 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shipman/internal/auth"
+	"shipman/internal/db"
+	"shipman/internal/db/dbtest"
+	"shipman/internal/email"
+	"shipman/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret"
+
+// newTestEngine builds the full API against a dbtest.Fake installed as
+// db.Pool for the rest of the test.
+func newTestEngine(t *testing.T) (*gin.Engine, *dbtest.Fake) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	fake := dbtest.New()
+	pool := fake.Open()
+	prev := db.Pool
+	db.SetPool(pool)
+	t.Cleanup(func() {
+		db.SetPool(prev)
+		pool.Close()
+	})
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := Setup(testSecret, time.Hour, store, "openai", "", "", "", email.Config{}, "", "", "", "", "", RocketRampConfig{})
+	return engine, fake
+}
+
+func get(t *testing.T, engine http.Handler, role, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := auth.NewJWTManager(testSecret, time.Hour).Generate(uuid.New(), db.DefaultOrgID, "user@example.com", role, "Test User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestUncollectedDemurrageIsAdminOnly(t *testing.T) {
+	tests := []struct {
+		role       string
+		wantStatus int
+	}{
+		{"admin", http.StatusOK},
+		{"broker", http.StatusForbidden},
+		{"shipowner", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			engine, fake := newTestEngine(t)
+			fake.Return("JOIN shipman.charter_details cd ON cd.id = dr.charter_detail_id", dbtest.Rows([]string{
+				"id", "charter_detail_id", "voyage_id", "laytime_entry_id", "claimed_hours",
+				"claimed_amount", "currency", "status", "reference", "supporting_doc_uri",
+				"notes", "created_at", "updated_at", "title",
+			}))
+
+			w := get(t, engine, tt.role, "/api/v1/demurrage/uncollected")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if ran := len(fake.Calls("FROM shipman.demurrage_records")) > 0; ran != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("report ran = %v for %s", ran, tt.role)
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != `{"data":[]}` {
+				t.Errorf("body = %s, want an empty data list", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestActivityFeedIsAdminOnly(t *testing.T) {
+	tests := []struct {
+		role       string
+		wantStatus int
+	}{
+		{"admin", http.StatusOK},
+		{"broker", http.StatusForbidden},
+		{"charterer", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			engine, fake := newTestEngine(t)
+			fake.Return("SELECT kind, id, charter_id, updated_at, summary", dbtest.Rows([]string{"kind", "id", "charter_id", "updated_at", "summary"}))
+
+			w := get(t, engine, tt.role, "/api/v1/activity")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if ran := len(fake.Calls("SELECT kind, id, charter_id")) > 0; ran != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("feed ran = %v for %s", ran, tt.role)
+			}
+		})
+	}
+}
+
+func TestFleetPositionsIsAdminOnly(t *testing.T) {
+	tests := []struct {
+		role       string
+		wantStatus int
+	}{
+		{"admin", http.StatusOK},
+		{"broker", http.StatusForbidden},
+		{"shipowner", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			engine, fake := newTestEngine(t)
+			fake.Return("JOIN LATERAL", dbtest.Rows([]string{
+				"id", "voyage_number", "vessel_name", "imo_number",
+				"position_id", "recorded_at", "latitude", "longitude", "speed_knots", "heading", "source",
+			}))
+
+			w := get(t, engine, tt.role, "/api/v1/fleet/positions")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if ran := len(fake.Calls("JOIN LATERAL")) > 0; ran != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("fleet view ran = %v for %s", ran, tt.role)
+			}
+		})
+	}
+}
+
+func TestMissingDueDateIsAdminOnly(t *testing.T) {
+	const report = "WHERE p.due_date IS NULL"
+	tests := []struct {
+		role       string
+		wantStatus int
+	}{
+		{"admin", http.StatusOK},
+		{"broker", http.StatusForbidden},
+		{"shipowner", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			engine, fake := newTestEngine(t)
+			fake.Return(report, dbtest.Rows([]string{"id"}))
+
+			w := get(t, engine, tt.role, "/api/v1/admin/payments/missing-due-date")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if ran := len(fake.Calls(report)) > 0; ran != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("report ran = %v for %s", ran, tt.role)
+			}
+		})
+	}
+}
@@ -2,53 +2,118 @@ package routes
 
 import (
 	"context"
-	"database/sql"
+	"crypto/tls"
+	"fmt"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	engine *gin.Engine
-	http   *http.Server
+	engine   *gin.Engine
+	http     *http.Server
+	inFlight atomic.Int64
+	tls      bool
 }
 
-func New(engine *gin.Engine, addr string) *Server {
-	return &Server{
-		engine: engine,
-		http: &http.Server{
-			Addr:              addr,
-			Handler:           engine,
-			ReadHeaderTimeout: 10 * time.Second,
-			ReadTimeout:       30 * time.Second,
-			WriteTimeout:      30 * time.Second,
-			IdleTimeout:       60 * time.Second,
-		},
-	}
+// Timeouts configures the underlying http.Server. Zero fields take the
+// matching DefaultTimeouts value.
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
 }
 
-func (s *Server) Start() error {
-	return s.http.ListenAndServe()
+// DefaultTimeouts are applied for any Timeouts field left at zero.
+var DefaultTimeouts = Timeouts{
+	ReadHeader: 10 * time.Second,
+	Read:       30 * time.Second,
+	Write:      30 * time.Second,
+	Idle:       60 * time.Second,
 }
 
-func (s *Server) Stop(ctx context.Context) error {
-	return s.http.Shutdown(ctx)
+func (t Timeouts) withDefaults() Timeouts {
+	if t.ReadHeader <= 0 {
+		t.ReadHeader = DefaultTimeouts.ReadHeader
+	}
+	if t.Read <= 0 {
+		t.Read = DefaultTimeouts.Read
+	}
+	if t.Write <= 0 {
+		t.Write = DefaultTimeouts.Write
+	}
+	if t.Idle <= 0 {
+		t.Idle = DefaultTimeouts.Idle
+	}
+	return t
+}
+
+func New(engine *gin.Engine, addr string, timeouts Timeouts) *Server {
+	t := timeouts.withDefaults()
+	s := &Server{engine: engine}
+	s.http = &http.Server{
+		Addr:              addr,
+		Handler:           s.track(engine),
+		ReadHeaderTimeout: t.ReadHeader,
+		ReadTimeout:       t.Read,
+		WriteTimeout:      t.Write,
+		IdleTimeout:       t.Idle,
+	}
+	return s
 }
 
-func RegisterRoutes(r *gin.Engine, db *sql.DB) {
-	r.GET("/healthz", func(c *gin.Context) {
-		if err := db.PingContext(c.Request.Context()); err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+// track counts requests while they are being served.
+func (s *Server) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
 	})
+}
+
+// InFlight reports how many requests are currently being served.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
 
-	api := r.Group("/api")
-	{
-		api.GET("/charters", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"data": []interface{}{}})
-		})
+// EnableTLS makes Start serve HTTPS with the given PEM certificate and key,
+// accepting TLS 1.2 and newer. The pair is loaded now so a missing or
+// mismatched file fails at startup rather than on the first listen.
+func (s *Server) EnableTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	s.http.TLSConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
 	}
+	s.tls = true
+	return nil
+}
+
+// Start listens on the configured address and serves until Stop.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve serves on ln, over HTTPS when EnableTLS was called and plain HTTP
+// otherwise.
+func (s *Server) Serve(ln net.Listener) error {
+	if s.tls {
+		return s.http.ServeTLS(ln, "", "")
+	}
+	return s.http.Serve(ln)
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
 }
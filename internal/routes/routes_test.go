@@ -0,0 +1,228 @@
+package routes
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shipman/internal/router/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return r
+}
+
+// serve starts s on a loopback port and stops it when the test ends.
+func serve(t *testing.T, s *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ln) }()
+	t.Cleanup(func() {
+		_ = s.Stop(context.Background())
+		if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("Serve = %v, want http.ErrServerClosed", err)
+		}
+	})
+	return ln.Addr().String()
+}
+
+// writeSelfSignedCert writes a PEM certificate and key for 127.0.0.1 and
+// returns their paths and the certificate.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "shipman test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestServerTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t)
+	s := New(newEngine(), "", Timeouts{})
+	if err := s.EnableTLS(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, s)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get("https://" + addr + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("TLS state = %+v, want TLS 1.2 or newer", resp.TLS)
+	}
+
+	old := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:    roots,
+		MaxVersion: tls.VersionTLS11,
+	}}}
+	if resp, err := old.Get("https://" + addr + "/healthz"); err == nil {
+		resp.Body.Close()
+		t.Error("TLS 1.1 handshake succeeded, want it refused")
+	}
+}
+
+func TestServerPlainHTTP(t *testing.T) {
+	addr := serve(t, New(newEngine(), "", Timeouts{}))
+
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestEnableTLSMissingFiles(t *testing.T) {
+	certFile, keyFile, _ := writeSelfSignedCert(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name         string
+		cert, key    string
+		wantNotExist bool
+	}{
+		{"missing cert", missing, keyFile, true},
+		{"missing key", certFile, missing, true},
+		{"cert as key", certFile, certFile, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(newEngine(), "", Timeouts{})
+			err := s.EnableTLS(tt.cert, tt.key)
+			if err == nil {
+				t.Fatal("EnableTLS succeeded, want an error")
+			}
+			if errors.Is(err, os.ErrNotExist) != tt.wantNotExist {
+				t.Errorf("err = %v, want os.ErrNotExist %v", err, tt.wantNotExist)
+			}
+			if s.tls || s.http.TLSConfig != nil {
+				t.Error("server was switched to TLS despite the error")
+			}
+		})
+	}
+}
+
+func TestNewAppliesTimeouts(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Timeouts
+		want Timeouts
+	}{
+		{"zero takes defaults", Timeouts{}, DefaultTimeouts},
+		{
+			"configured values kept",
+			Timeouts{ReadHeader: time.Second, Read: 2 * time.Second, Write: 5 * time.Minute, Idle: 3 * time.Second},
+			Timeouts{ReadHeader: time.Second, Read: 2 * time.Second, Write: 5 * time.Minute, Idle: 3 * time.Second},
+		},
+		{
+			"partial fills the rest",
+			Timeouts{Write: 5 * time.Minute},
+			Timeouts{ReadHeader: DefaultTimeouts.ReadHeader, Read: DefaultTimeouts.Read, Write: 5 * time.Minute, Idle: DefaultTimeouts.Idle},
+		},
+		{
+			"negative takes defaults",
+			Timeouts{Read: -time.Second},
+			DefaultTimeouts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(newEngine(), ":0", tt.in).http
+			got := Timeouts{ReadHeader: h.ReadHeaderTimeout, Read: h.ReadTimeout, Write: h.WriteTimeout, Idle: h.IdleTimeout}
+			if got != tt.want {
+				t.Errorf("timeouts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLongRunningOutlastsWriteTimeout(t *testing.T) {
+	middleware.SetLongRunningTimeout(5 * time.Second)
+	t.Cleanup(func() { middleware.SetLongRunningTimeout(0) })
+
+	slow := func(c *gin.Context) {
+		time.Sleep(300 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	}
+	r := newEngine()
+	r.GET("/slow", slow)
+	r.GET("/export", middleware.LongRunning(), slow)
+	addr := serve(t, New(r, "", Timeouts{Write: 100 * time.Millisecond}))
+
+	if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+		resp.Body.Close()
+		t.Error("/slow outlived the write timeout, want the connection cut")
+	}
+	resp, err := http.Get("http://" + addr + "/export")
+	if err != nil {
+		t.Fatalf("/export: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/export status = %d, want 200", resp.StatusCode)
+	}
+}